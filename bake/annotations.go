@@ -0,0 +1,93 @@
+package bake
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/buildx/build"
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/docker/buildx/util/gitutil"
+	bkgitutil "github.com/moby/buildkit/util/gitutil"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// annotations returns the annotations of the target. If annotations-auto is
+// set, the source and revision annotations are added at the given levels
+// from the git repository of the context of the target, unless they are
+// already set by the target.
+func (t *Target) annotations(levels ...string) ([]string, error) {
+	if t.AnnotationsAuto == nil || !*t.AnnotationsAuto {
+		return t.Annotations, nil
+	}
+	set, err := buildflags.ParseAnnotations(t.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]struct{}, len(set))
+	for k := range set {
+		keys[k.Key] = struct{}{}
+	}
+
+	source, revision, err := gitSource(t.Context)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.Join(levels, ",") + ":"
+	var res []string
+	for _, kv := range [][2]string{
+		{ocispecs.AnnotationSource, source},
+		{ocispecs.AnnotationRevision, revision},
+	} {
+		if _, ok := keys[kv[0]]; ok || kv[1] == "" {
+			continue
+		}
+		res = append(res, prefix+kv[0]+"="+kv[1])
+	}
+	return append(res, t.Annotations...), nil
+}
+
+// gitSource returns the remote url and the commit of the git repository of a
+// build context. They are empty if the context is not in a git repository,
+// or if the commit of a remote context is not pinned.
+func gitSource(contextPath *string) (string, string, error) {
+	p := "."
+	if contextPath != nil {
+		p = strings.TrimPrefix(*contextPath, "cwd://")
+	}
+	if build.IsRemoteURL(p) {
+		gitRef, err := bkgitutil.ParseGitRef(p)
+		if err != nil {
+			return "", "", nil
+		}
+		var revision string
+		if bkgitutil.IsCommitSHA(gitRef.Commit) {
+			revision = gitRef.Commit
+		}
+		return gitRef.Remote, revision, nil
+	}
+
+	wd, err := filepath.Abs(p)
+	if err != nil {
+		return "", "", err
+	}
+	gitc, err := gitutil.New(gitutil.WithWorkingDir(wd))
+	if err != nil || !gitc.IsInsideWorkTree() {
+		return "", "", nil
+	}
+	source, _ := gitc.RemoteURL()
+	revision, err := gitc.FullCommit()
+	if err != nil && !gitutil.IsUnknownRevision(err) {
+		return "", "", errors.Wrap(err, "failed to get git commit")
+	}
+	if revision != "" {
+		if v, ok := os.LookupEnv("BUILDX_GIT_CHECK_DIRTY"); ok {
+			if dirty, err := strconv.ParseBool(v); err == nil && dirty && gitc.IsDirty() {
+				revision += "-dirty"
+			}
+		}
+	}
+	return source, revision, nil
+}
@@ -3,7 +3,9 @@ package bake
 import (
 	"context"
 	"encoding"
+	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
@@ -19,6 +21,8 @@ import (
 	"github.com/docker/buildx/build"
 	controllerapi "github.com/docker/buildx/controller/pb"
 	"github.com/docker/buildx/util/buildflags"
+	"github.com/docker/buildx/util/confutil"
+	"github.com/docker/buildx/util/credplugin"
 	"github.com/docker/buildx/util/platformutil"
 	"github.com/docker/buildx/util/progress"
 	"github.com/docker/cli/cli/config"
@@ -26,7 +30,7 @@ import (
 	hcl "github.com/hashicorp/hcl/v2"
 	"github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/client/llb"
-	"github.com/moby/buildkit/session/auth/authprovider"
+	"github.com/moby/buildkit/exporter/containerimage/exptypes"
 	"github.com/moby/buildkit/util/entitlements"
 	"github.com/pkg/errors"
 	"github.com/tonistiigi/go-csvvalue"
@@ -47,9 +51,15 @@ type File struct {
 type Override struct {
 	Value    string
 	ArrValue []string
+	// MapValue is the value of a map field, such as args, set as a whole
+	// with a JSON override.
+	MapValue map[string]string
+	// Replace is set by JSON overrides, which replace the whole field instead
+	// of adding to it.
+	Replace bool
 }
 
-func defaultFilenames() []string {
+func defaultFilenames(profile string) []string {
 	names := []string{}
 	names = append(names, composecli.DefaultFileNames...)
 	names = append(names, []string{
@@ -58,14 +68,58 @@ func defaultFilenames() []string {
 		"docker-bake.hcl",
 		"docker-bake.override.hcl",
 	}...)
+	names = append(names, profileFilenames(profile)...)
 	return names
 }
 
-func ReadLocalFiles(names []string, stdin io.Reader, l progress.SubLogger) ([]File, error) {
+// profileFilenames returns the override files of the given environment
+// profile, in the order they are applied after the default files.
+func profileFilenames(profile string) []string {
+	if profile == "" {
+		return nil
+	}
+	return []string{
+		"docker-bake." + profile + ".json",
+		"docker-bake." + profile + ".hcl",
+	}
+}
+
+func validateProfile(profile string) error {
+	if profile == "" {
+		return nil
+	}
+	if profile == "override" || strings.ContainsAny(profile, `/\`) || strings.HasPrefix(profile, ".") {
+		return errors.Errorf("invalid bake profile %q", profile)
+	}
+	return nil
+}
+
+// checkProfileFiles returns an error if none of the override files of the
+// profile were found.
+func checkProfileFiles(profile string, files []File) error {
+	names := profileFilenames(profile)
+	if len(names) == 0 {
+		return nil
+	}
+	for _, f := range files {
+		if slices.Contains(names, path.Base(f.Name)) {
+			return nil
+		}
+	}
+	return errors.Errorf("no bake definition found for profile %q, expected %s", profile, strings.Join(names, " or "))
+}
+
+// ReadLocalFiles reads the bake definition files from the local filesystem.
+// If names is empty, the default files are read along with the override
+// files of profile, if set.
+func ReadLocalFiles(names []string, profile string, stdin io.Reader, l progress.SubLogger) ([]File, error) {
 	isDefault := false
 	if len(names) == 0 {
+		if err := validateProfile(profile); err != nil {
+			return nil, err
+		}
 		isDefault = true
-		names = defaultFilenames()
+		names = defaultFilenames(profile)
 	}
 	out := make([]File, 0, len(names))
 
@@ -94,6 +148,11 @@ func ReadLocalFiles(names []string, stdin io.Reader, l progress.SubLogger) ([]Fi
 		}
 		out = append(out, File{Name: n, Data: dt})
 	}
+	if isDefault {
+		if err := checkProfileFiles(profile, out); err != nil {
+			return nil, err
+		}
+	}
 	return out, nil
 }
 
@@ -195,7 +254,7 @@ func ListTargets(files []File) ([]string, error) {
 }
 
 func ReadTargets(ctx context.Context, files []File, targets, overrides []string, defaults map[string]string, ent *EntitlementConf) (map[string]*Target, map[string]*Group, error) {
-	c, _, err := ParseFiles(files, defaults)
+	c, _, err := parseFilesCached(files, defaults, templateFileReader(ent))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -231,6 +290,10 @@ func ReadTargets(ctx context.Context, files []File, targets, overrides []string,
 		}
 	}
 
+	if err := c.loadIndexSources(m, o, ent); err != nil {
+		return nil, nil, err
+	}
+
 	for _, target := range targets {
 		if target == "default" {
 			continue
@@ -244,15 +307,98 @@ func ReadTargets(ctx context.Context, files []File, targets, overrides []string,
 		g.Targets = dedupSlice(g.Targets)
 	}
 
-	for name, t := range m {
-		if err := c.loadLinks(name, t, m, o, nil, ent); err != nil {
+	// linked targets are added to m, so they are collected once all the
+	// links are loaded
+	for _, name := range sortedKeys(m) {
+		if err := c.loadLinks(name, m[name], m, o, nil, ent); err != nil {
 			return nil, nil, err
 		}
 	}
+	if ent != nil {
+		for _, name := range sortedKeys(m) {
+			if h := m[name].Hooks; h != nil && (len(h.Pre) > 0 || len(h.Post) > 0) {
+				ent.hookTargets = append(ent.hookTargets, name)
+			}
+		}
+	}
+
+	if err := checkTestSources(m); err != nil {
+		return nil, nil, err
+	}
+
+	if err := expandStages(m, n, targets); err != nil {
+		return nil, nil, err
+	}
+
+	if err := validateTargets(m); err != nil {
+		return nil, nil, formatHCLError(err, files)
+	}
 
 	return m, n, nil
 }
 
+// validateTargets evaluates the validation blocks of the resolved targets and
+// returns the failures of all of them.
+func validateTargets(m map[string]*Target) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var diags hcl.Diagnostics
+	for _, name := range names {
+		t := m[name]
+		if len(t.Validations) == 0 {
+			continue
+		}
+		v, err := targetValue(name, t)
+		if err != nil {
+			return err
+		}
+		for _, validation := range t.Validations {
+			for _, d := range validation.Validate("target", v) {
+				if d.Summary == "Validation failed" {
+					d.Detail = fmt.Sprintf("target %q: %s", name, d.Detail)
+				}
+				diags = append(diags, d)
+			}
+		}
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+	return nil
+}
+
+// targetValue returns the value of a resolved target visible to its
+// validations. Unset lists and maps are empty, so that conditions such as
+// length(target.tags) > 0 don't fail on targets that don't set them.
+func targetValue(name string, t *Target) (cty.Value, error) {
+	ty, err := hclparser.ImpliedType(t)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	v, err := hclparser.ToCtyValue(t, ty)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	attrs := v.AsValueMap()
+	for k, a := range attrs {
+		if !a.IsNull() {
+			continue
+		}
+		switch ty := a.Type(); {
+		case ty.IsListType():
+			attrs[k] = cty.ListValEmpty(ty.ElementType())
+		case ty.IsMapType():
+			attrs[k] = cty.MapValEmpty(ty.ElementType())
+		}
+	}
+	attrs["name"] = cty.StringVal(name)
+	return cty.ObjectVal(attrs), nil
+}
+
 func dedupSlice(s []string) []string {
 	if len(s) == 0 {
 		return s
@@ -301,11 +447,44 @@ func sliceToMap(env []string) (res map[string]string) {
 	return
 }
 
-func ParseFiles(files []File, defaults map[string]string) (_ *Config, _ *hclparser.ParseMeta, err error) {
+func ParseFiles(files []File, defaults map[string]string) (*Config, *hclparser.ParseMeta, error) {
+	return parseFilesCached(files, defaults, templateFileReader(nil))
+}
+
+func parseFilesCached(files []File, defaults map[string]string, readFile func(string) ([]byte, error)) (_ *Config, _ *hclparser.ParseMeta, err error) {
 	defer func() {
 		err = formatHCLError(err, files)
 	}()
 
+	pc := newParseCache(ParseCacheDir, files, defaults)
+	if c, pm, ok := pc.load(); ok {
+		return c, pm, nil
+	}
+	c, pm, err := parseFiles(files, defaults, pc.lookupVar, readFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the files of the modules are not part of the cache key
+	if len(c.Modules) > 0 {
+		return c, pm, nil
+	}
+	// validations are evaluated with the parser context that is not cached
+	for _, t := range c.Targets {
+		if len(t.Validations) > 0 {
+			return c, pm, nil
+		}
+	}
+	pc.store(c, pm)
+	return c, pm, nil
+}
+
+func parseFiles(files []File, defaults map[string]string, lookupVar func(string) (string, bool), readFile func(string) ([]byte, error)) (*Config, *hclparser.ParseMeta, error) {
+	return parseFilesIn("", nil, files, defaults, lookupVar, readFile)
+}
+
+// parseFilesIn parses the files of the definition, or of a module when dir
+// is the directory of the module.
+func parseFilesIn(dir string, visited []string, files []File, defaults map[string]string, lookupVar func(string) (string, bool), readFile func(string) ([]byte, error)) (*Config, *hclparser.ParseMeta, error) {
 	var c Config
 	var composeFiles []File
 	var hclFiles []*hcl.File
@@ -344,9 +523,10 @@ func ParseFiles(files []File, defaults map[string]string) (_ *Config, _ *hclpars
 	var pm hclparser.ParseMeta
 	if len(hclFiles) > 0 {
 		res, err := hclparser.Parse(hclparser.MergeFiles(hclFiles), hclparser.Opt{
-			LookupVar:     os.LookupEnv,
+			LookupVar:     lookupVar,
 			Vars:          defaults,
 			ValidateLabel: validateTargetName,
+			ReadFile:      readFile,
 		}, &c)
 		if err.HasErrors() {
 			return nil, nil, err
@@ -368,6 +548,13 @@ func ParseFiles(files []File, defaults map[string]string) (_ *Config, _ *hclpars
 		pm = *res
 	}
 
+	if dir != "" {
+		c.resolveModulePaths(dir)
+	}
+	if err := c.loadModules(dir, visited, defaults, readFile); err != nil {
+		return nil, nil, err
+	}
+
 	return &c, &pm, nil
 }
 
@@ -400,6 +587,7 @@ func ParseFile(dt []byte, fn string) (*Config, error) {
 type Config struct {
 	Groups  []*Group  `json:"group" hcl:"group,block" cty:"group"`
 	Targets []*Target `json:"target" hcl:"target,block" cty:"target"`
+	Modules []*Module `json:"-" hcl:"module,block" cty:"module"`
 }
 
 func mergeConfig(c1, c2 Config) Config {
@@ -479,8 +667,8 @@ func (c Config) expandTargets(pattern string) ([]string, error) {
 
 func (c Config) loadLinks(name string, t *Target, m map[string]*Target, o map[string]map[string]Override, visited []string, ent *EntitlementConf) error {
 	visited = append(visited, name)
-	for _, v := range t.Contexts {
-		if strings.HasPrefix(v, "target:") {
+	for _, k := range sortedKeys(t.Contexts) {
+		if v := t.Contexts[k]; strings.HasPrefix(v, "target:") {
 			target := strings.TrimPrefix(v, "target:")
 			if target == name {
 				return errors.Errorf("target %s cannot link to itself", target)
@@ -527,6 +715,7 @@ func (c Config) loadLinks(name string, t *Target, m map[string]*Target, o map[st
 func (c Config) newOverrides(v []string) (map[string]map[string]Override, error) {
 	m := map[string]map[string]Override{}
 	for _, v := range v {
+		v, isJSON := strings.CutPrefix(v, jsonOverridePrefix)
 		parts := strings.SplitN(v, "=", 2)
 		keys := strings.SplitN(parts[0], ".", 3)
 		if len(keys) < 2 {
@@ -534,7 +723,7 @@ func (c Config) newOverrides(v []string) (map[string]map[string]Override, error)
 		}
 
 		pattern := keys[0]
-		if len(parts) != 2 && keys[1] != "args" {
+		if len(parts) != 2 && (keys[1] != "args" || isJSON) {
 			return nil, errors.Errorf("invalid override %s, expected target.name=value", v)
 		}
 
@@ -554,8 +743,24 @@ func (c Config) newOverrides(v []string) (map[string]map[string]Override, error)
 
 			o := t[kk[1]]
 
+			if isJSON {
+				if err := o.setJSON(keys[1], len(keys) == 3, parts[1]); err != nil {
+					return nil, errors.Wrapf(err, "invalid JSON override %s", parts[0])
+				}
+				if o.MapValue != nil {
+					// previous overrides of single entries are replaced too
+					for k := range t {
+						if strings.HasPrefix(k, keys[1]+".") {
+							delete(t, k)
+						}
+					}
+				}
+				t[kk[1]] = o
+				continue
+			}
+
 			switch keys[1] {
-			case "output", "cache-to", "cache-from", "tags", "platform", "secrets", "ssh", "attest", "entitlements", "network":
+			case "output", "cache-to", "cache-from", "tags", "platform", "secrets", "ssh", "attest", "entitlements", "network", "sources", "no-cache-filter":
 				if len(parts) == 2 {
 					o.ArrValue = append(o.ArrValue, parts[1])
 				}
@@ -636,6 +841,16 @@ func (c Config) ResolveTarget(name string, overrides map[string]map[string]Overr
 		return nil, err
 	}
 	t.Inherits = nil
+	if t.IsIndex() {
+		// index targets don't build anything
+		return t, nil
+	}
+	if t.IsTest() {
+		if err := t.prepareTest(name); err != nil {
+			return nil, err
+		}
+	}
+	t.applyProxy()
 	if t.Context == nil {
 		s := "."
 		t.Context = &s
@@ -698,7 +913,8 @@ type Target struct {
 	// Inherits is the only field that cannot be overridden with --set
 	Inherits []string `json:"inherits,omitempty" hcl:"inherits,optional" cty:"inherits"`
 
-	Annotations      []string                `json:"annotations,omitempty" hcl:"annotations,optional" cty:"annotations"`
+	Annotations      buildflags.Annotations  `json:"annotations,omitempty" hcl:"annotations,optional" cty:"annotations"`
+	AnnotationsAuto  *bool                   `json:"annotations-auto,omitempty" hcl:"annotations-auto,optional" cty:"annotations-auto"`
 	Attest           buildflags.Attests      `json:"attest,omitempty" hcl:"attest,optional" cty:"attest"`
 	Context          *string                 `json:"context,omitempty" hcl:"context,optional" cty:"context"`
 	Contexts         map[string]string       `json:"contexts,omitempty" hcl:"contexts,optional" cty:"contexts"`
@@ -709,7 +925,7 @@ type Target struct {
 	Tags             []string                `json:"tags,omitempty" hcl:"tags,optional" cty:"tags"`
 	CacheFrom        buildflags.CacheOptions `json:"cache-from,omitempty" hcl:"cache-from,optional" cty:"cache-from"`
 	CacheTo          buildflags.CacheOptions `json:"cache-to,omitempty" hcl:"cache-to,optional" cty:"cache-to"`
-	Target           *string                 `json:"target,omitempty" hcl:"target,optional" cty:"target"`
+	Target           Stages                  `json:"target,omitempty" hcl:"target,optional" cty:"target"`
 	Secrets          buildflags.Secrets      `json:"secret,omitempty" hcl:"secret,optional" cty:"secret"`
 	SSH              buildflags.SSHKeys      `json:"ssh,omitempty" hcl:"ssh,optional" cty:"ssh"`
 	Platforms        []string                `json:"platforms,omitempty" hcl:"platforms,optional" cty:"platforms"`
@@ -721,7 +937,16 @@ type Target struct {
 	ShmSize          *string                 `json:"shm-size,omitempty" hcl:"shm-size,optional" cty:"shm-size"`
 	Ulimits          []string                `json:"ulimits,omitempty" hcl:"ulimits,optional" cty:"ulimits"`
 	Call             *string                 `json:"call,omitempty" hcl:"call,optional" cty:"call"`
+	Builder          *string                 `json:"builder,omitempty" hcl:"builder,optional" cty:"builder"`
+	FrontendImage    *string                 `json:"frontend-image,omitempty" hcl:"frontend-image,optional" cty:"frontend-image"`
 	Entitlements     []string                `json:"entitlements,omitempty" hcl:"entitlements,optional" cty:"entitlements"`
+	Type             *string                 `json:"type,omitempty" hcl:"type,optional" cty:"type"`
+	Sources          []string                `json:"sources,omitempty" hcl:"sources,optional" cty:"sources"`
+	Hooks            *TargetHooks            `json:"hooks,omitempty" hcl:"hooks,block" cty:"hooks"`
+	Proxy            *TargetProxy            `json:"proxy,omitempty" hcl:"proxy,block" cty:"proxy"`
+	Test             *TargetTest             `json:"test,omitempty" hcl:"test,block" cty:"test"`
+	Watch            []*TargetWatch          `json:"watch,omitempty" hcl:"watch,block" cty:"watch"`
+	Validations      []*hclparser.Validation `json:"-" hcl:"validation,block"`
 	// IMPORTANT: if you add more fields here, do not forget to update newOverrides/AddOverrides and docs/bake-reference.md.
 
 	// linked is a private field to mark a target used as a linked one
@@ -807,9 +1032,18 @@ func (t *Target) Merge(t2 *Target) {
 	if t2.Call != nil {
 		t.Call = t2.Call
 	}
+	if t2.Builder != nil {
+		t.Builder = t2.Builder
+	}
+	if t2.FrontendImage != nil {
+		t.FrontendImage = t2.FrontendImage
+	}
 	if t2.Annotations != nil { // merge
 		t.Annotations = append(t.Annotations, t2.Annotations...)
 	}
+	if t2.AnnotationsAuto != nil {
+		t.AnnotationsAuto = t2.AnnotationsAuto
+	}
 	if t2.Attest != nil { // merge
 		t.Attest = t.Attest.Merge(t2.Attest)
 	}
@@ -855,11 +1089,39 @@ func (t *Target) Merge(t2 *Target) {
 	if t2.Entitlements != nil { // merge
 		t.Entitlements = append(t.Entitlements, t2.Entitlements...)
 	}
+	if t2.Type != nil {
+		t.Type = t2.Type
+	}
+	if t2.Sources != nil { // no merge
+		t.Sources = t2.Sources
+	}
+	if t2.Hooks != nil { // no merge
+		t.Hooks = t2.Hooks
+	}
+	if t2.Test != nil { // no merge
+		t.Test = t2.Test
+	}
+	if t2.Proxy != nil { // merge
+		t.Proxy = t.Proxy.merge(t2.Proxy)
+	}
+	if t2.Watch != nil { // merge
+		t.Watch = append(t.Watch, t2.Watch...)
+	}
+	if t2.Validations != nil { // merge
+		t.Validations = append(t.Validations, t2.Validations...)
+	}
 	t.Inherits = append(t.Inherits, t2.Inherits...)
 }
 
 func (t *Target) AddOverrides(overrides map[string]Override, ent *EntitlementConf) error {
-	for key, o := range overrides {
+	// map fields replaced as a whole are applied before their single entries
+	names := make([]string, 0, len(overrides))
+	for key := range overrides {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	for _, key := range names {
+		o := overrides[key]
 		value := o.Value
 		keys := strings.SplitN(key, ".", 2)
 		switch keys[0] {
@@ -869,7 +1131,14 @@ func (t *Target) AddOverrides(overrides map[string]Override, ent *EntitlementCon
 			t.Dockerfile = &value
 		case "args":
 			if len(keys) != 2 {
-				return errors.Errorf("invalid format for args, expecting args.<name>=<value>")
+				if o.MapValue == nil {
+					return errors.Errorf("invalid format for args, expecting args.<name>=<value>")
+				}
+				t.Args = map[string]*string{}
+				for k, v := range o.MapValue {
+					t.Args[k] = &v
+				}
+				continue
 			}
 			if t.Args == nil {
 				t.Args = map[string]*string{}
@@ -877,7 +1146,11 @@ func (t *Target) AddOverrides(overrides map[string]Override, ent *EntitlementCon
 			t.Args[keys[1]] = &value
 		case "contexts":
 			if len(keys) != 2 {
-				return errors.Errorf("invalid format for contexts, expecting contexts.<name>=<value>")
+				if o.MapValue == nil {
+					return errors.Errorf("invalid format for contexts, expecting contexts.<name>=<value>")
+				}
+				t.Contexts = maps.Clone(o.MapValue)
+				continue
 			}
 			if t.Contexts == nil {
 				t.Contexts = map[string]string{}
@@ -885,7 +1158,14 @@ func (t *Target) AddOverrides(overrides map[string]Override, ent *EntitlementCon
 			t.Contexts[keys[1]] = value
 		case "labels":
 			if len(keys) != 2 {
-				return errors.Errorf("invalid format for labels, expecting labels.<name>=<value>")
+				if o.MapValue == nil {
+					return errors.Errorf("invalid format for labels, expecting labels.<name>=<value>")
+				}
+				t.Labels = map[string]*string{}
+				for k, v := range o.MapValue {
+					t.Labels[k] = &v
+				}
+				continue
 			}
 			if t.Labels == nil {
 				t.Labels = map[string]*string{}
@@ -920,9 +1200,23 @@ func (t *Target) AddOverrides(overrides map[string]Override, ent *EntitlementCon
 				}
 			}
 		case "target":
-			t.Target = &value
+			t.Target = Stages{value}
 		case "call":
 			t.Call = &value
+		case "builder":
+			t.Builder = &value
+		case "proxy":
+			if len(keys) != 2 {
+				return errors.Errorf("invalid format for proxy, expecting proxy.<key>=<value>")
+			}
+			if t.Proxy == nil {
+				t.Proxy = &TargetProxy{}
+			}
+			if err := t.Proxy.set(keys[1], value); err != nil {
+				return err
+			}
+		case "frontend-image":
+			t.FrontendImage = &value
 		case "secrets":
 			secrets, err := parseArrValue[buildflags.Secret](o.ArrValue)
 			if err != nil {
@@ -945,6 +1239,10 @@ func (t *Target) AddOverrides(overrides map[string]Override, ent *EntitlementCon
 			}
 		case "platform":
 			t.Platforms = o.ArrValue
+		case "type":
+			t.Type = &value
+		case "sources":
+			t.Sources = o.ArrValue
 		case "output":
 			outputs, err := parseArrValue[buildflags.ExportEntry](o.ArrValue)
 			if err != nil {
@@ -957,6 +1255,9 @@ func (t *Target) AddOverrides(overrides map[string]Override, ent *EntitlementCon
 				}
 			}
 		case "entitlements":
+			if o.Replace {
+				t.Entitlements = nil
+			}
 			t.Entitlements = append(t.Entitlements, o.ArrValue...)
 			for _, v := range o.ArrValue {
 				if v == string(EntitlementKeyNetworkHost) {
@@ -966,12 +1267,24 @@ func (t *Target) AddOverrides(overrides map[string]Override, ent *EntitlementCon
 				}
 			}
 		case "annotations":
+			if o.Replace {
+				t.Annotations = nil
+			}
 			t.Annotations = append(t.Annotations, o.ArrValue...)
+		case "annotations-auto":
+			auto, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.Errorf("invalid value %s for boolean key annotations-auto", value)
+			}
+			t.AnnotationsAuto = &auto
 		case "attest":
 			attest, err := parseArrValue[buildflags.Attest](o.ArrValue)
 			if err != nil {
 				return errors.Wrap(err, "invalid value for attest")
 			}
+			if o.Replace {
+				t.Attest = nil
+			}
 			t.Attest = t.Attest.Merge(attest)
 		case "no-cache":
 			noCache, err := strconv.ParseBool(value)
@@ -1125,10 +1438,19 @@ func (t *Target) GetName(ectx *hcl.EvalContext, block *hcl.Block, loadDeps func(
 func TargetsToBuildOpt(m map[string]*Target, inp *Input) (map[string]build.Options, error) {
 	// make sure local credentials are loaded multiple times for different targets
 	dockerConfig := config.LoadDefaultConfigFile(os.Stderr)
-	authProvider := authprovider.NewDockerAuthProvider(dockerConfig, nil)
+	credPlugins, err := credplugin.LoadConfig(confutil.DefaultDir(dockerConfig.Filename))
+	if err != nil {
+		return nil, err
+	}
+	authProvider := credplugin.NewAuthProvider(dockerConfig, credPlugins)
 
 	m2 := make(map[string]build.Options, len(m))
-	for k, v := range m {
+	for _, k := range sortedKeys(m) {
+		v := m[k]
+		if v.IsIndex() {
+			// assembled from the images of its sources after the build
+			continue
+		}
 		bo, err := toBuildOpt(v, inp)
 		if err != nil {
 			return nil, err
@@ -1140,6 +1462,10 @@ func TargetsToBuildOpt(m map[string]*Target, inp *Input) (map[string]build.Optio
 }
 
 func updateContext(t *build.Inputs, inp *Input) {
+	if inp != nil && inp.Dir != "" {
+		updateClonedContext(t, inp.Dir)
+		return
+	}
 	if inp == nil || inp.State == nil {
 		return
 	}
@@ -1177,24 +1503,49 @@ func updateContext(t *build.Inputs, inp *Input) {
 	t.ContextState = &st
 }
 
-func collectLocalPaths(t build.Inputs) []string {
-	var out []string
+// updateClonedContext resolves the relative context and named contexts of a
+// target against the local checkout of a cloned remote definition.
+func updateClonedContext(t *build.Inputs, dir string) {
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, filepath.FromSlash(p))
+	}
+	for k, v := range t.NamedContexts {
+		if v.State != nil || strings.HasPrefix(v.Path, "cwd://") || strings.HasPrefix(v.Path, "target:") || strings.HasPrefix(v.Path, "docker-image:") || build.IsRemoteURL(v.Path) {
+			continue
+		}
+		t.NamedContexts[k] = build.NamedContext{Path: resolve(v.Path)}
+	}
+
+	if strings.HasPrefix(t.ContextPath, "cwd://") || build.IsRemoteURL(t.ContextPath) {
+		return
+	}
+	t.ContextPath = resolve(t.ContextPath)
+}
+
+// localInputPaths returns the local paths of the inputs of a build along with
+// the fields of the target setting them.
+func localInputPaths(t build.Inputs) map[string][]string {
+	out := map[string][]string{}
 	if t.ContextState == nil {
 		if v, ok := isLocalPath(t.ContextPath); ok {
-			out = append(out, v)
+			out[v] = append(out[v], "context")
 		}
 		if v, ok := isLocalPath(t.DockerfilePath); ok {
-			out = append(out, v)
+			out[v] = append(out[v], "dockerfile")
 		}
 	} else if strings.HasPrefix(t.ContextPath, "cwd://") {
-		out = append(out, strings.TrimPrefix(t.ContextPath, "cwd://"))
+		v := strings.TrimPrefix(t.ContextPath, "cwd://")
+		out[v] = append(out[v], "context")
 	}
-	for _, v := range t.NamedContexts {
+	for name, v := range t.NamedContexts {
 		if v.State != nil {
 			continue
 		}
 		if v, ok := isLocalPath(v.Path); ok {
-			out = append(out, v)
+			out[v] = append(out[v], "contexts."+name)
 		}
 	}
 	return out
@@ -1289,6 +1640,13 @@ func toBuildOpt(t *Target, inp *Input) (*build.Options, error) {
 		}
 		args[k] = *v
 	}
+	if t.FrontendImage != nil {
+		img, err := build.ParseFrontendImage(*t.FrontendImage)
+		if err != nil {
+			return nil, err
+		}
+		args[build.FrontendImageArg] = img
+	}
 
 	labels := map[string]string{}
 	for k, v := range t.Labels {
@@ -1354,8 +1712,10 @@ func toBuildOpt(t *Target, inp *Input) (*build.Options, error) {
 	}
 	bo.Session = append(bo.Session, sshAttachment)
 
-	if t.Target != nil {
-		bo.Target = *t.Target
+	if len(t.Target) > 1 {
+		return nil, errors.Errorf("target %s builds several stages", t.Name)
+	} else if len(t.Target) == 1 {
+		bo.Target = t.Target[0]
 	}
 
 	if t.Call != nil {
@@ -1365,18 +1725,38 @@ func toBuildOpt(t *Target, inp *Input) (*build.Options, error) {
 	}
 
 	if t.CacheFrom != nil {
-		bo.CacheFrom = controllerapi.CreateCaches(t.CacheFrom.ToPB())
+		cacheFrom, err := t.CacheFrom.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		bo.CacheFrom = controllerapi.CreateCaches(cacheFrom.ToPB())
 	}
 	if t.CacheTo != nil {
-		bo.CacheTo = controllerapi.CreateCaches(t.CacheTo.ToPB())
+		cacheTo, err := t.CacheTo.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		bo.CacheTo = controllerapi.CreateCaches(cacheTo.ToPB())
 	}
 
-	bo.Exports, bo.ExportsLocalPathsTemporary, err = controllerapi.CreateExports(t.Outputs.ToPB())
+	outputs, err := t.Outputs.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	bo.Exports, bo.ExportsLocalPathsTemporary, err = controllerapi.CreateExports(outputs.ToPB())
 	if err != nil {
 		return nil, err
 	}
 
-	annotations, err := buildflags.ParseAnnotations(t.Annotations)
+	levels := []string{exptypes.AnnotationManifest}
+	if len(bo.Platforms) > 1 {
+		levels = append(levels, exptypes.AnnotationIndex)
+	}
+	annotationsList, err := t.annotations(levels...)
+	if err != nil {
+		return nil, err
+	}
+	annotations, err := buildflags.ParseAnnotations(annotationsList)
 	if err != nil {
 		return nil, err
 	}
@@ -1412,6 +1792,17 @@ func defaultTarget() *Target {
 	return &Target{}
 }
 
+// sortedKeys returns the keys of a map in order, to process its entries
+// deterministically.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func removeDupesStr(s []string) []string {
 	i := 0
 	seen := make(map[string]struct{}, len(s))
@@ -2,6 +2,7 @@ package bake
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/docker/buildx/util/buildflags"
 	"github.com/moby/buildkit/util/entitlements"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -135,6 +137,13 @@ target "webapp" {
 		require.Equal(t, []string{"webapp"}, g["default"].Targets)
 	})
 
+	t.Run("NoCacheFilterOverride", func(t *testing.T) {
+		t.Parallel()
+		m, _, err := ReadTargets(ctx, []File{fp}, []string{"webapp"}, []string{"webapp.no-cache-filter=base", "webapp.no-cache-filter=deps"}, nil, &EntitlementConf{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"base", "deps"}, m["webapp"].NoCacheFilter)
+	})
+
 	t.Run("ShmSizeOverride", func(t *testing.T) {
 		m, _, err := ReadTargets(ctx, []File{fp}, []string{"webapp"}, []string{"webapp.shm-size=256m"}, nil, &EntitlementConf{})
 		require.NoError(t, err)
@@ -1657,7 +1666,7 @@ func TestReadLocalFilesDefault(t *testing.T) {
 			for _, tf := range tt.filenames {
 				require.NoError(t, os.WriteFile(tf, []byte(tf), 0644))
 			}
-			files, err := ReadLocalFiles(nil, nil, nil)
+			files, err := ReadLocalFiles(nil, "", nil, nil)
 			require.NoError(t, err)
 			if len(files) == 0 {
 				require.Equal(t, len(tt.expected), len(files))
@@ -1677,6 +1686,42 @@ func TestReadLocalFilesDefault(t *testing.T) {
 	}
 }
 
+func TestReadLocalFilesProfile(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	t.Cleanup(func() { _ = os.Chdir(pwd) })
+	require.NoError(t, os.Chdir(dir))
+
+	for _, f := range []string{"docker-bake.hcl", "docker-bake.override.hcl", "docker-bake.staging.hcl", "docker-bake.prod.json"} {
+		require.NoError(t, os.WriteFile(f, []byte(f), 0644))
+	}
+
+	files, err := ReadLocalFiles(nil, "staging", nil, nil)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	require.Equal(t, []string{"docker-bake.hcl", "docker-bake.override.hcl", "docker-bake.staging.hcl"}, names)
+
+	files, err = ReadLocalFiles(nil, "prod", nil, nil)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	require.Equal(t, "docker-bake.prod.json", files[2].Name)
+
+	_, err = ReadLocalFiles(nil, "dev", nil, nil)
+	require.ErrorContains(t, err, `no bake definition found for profile "dev"`)
+
+	_, err = ReadLocalFiles(nil, "../staging", nil, nil)
+	require.ErrorContains(t, err, `invalid bake profile "../staging"`)
+
+	// profile only applies to the default files
+	files, err = ReadLocalFiles([]string{"docker-bake.hcl"}, "dev", nil, nil)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
 func TestAttestDuplicates(t *testing.T) {
 	fp := File{
 		Name: "docker-bake.hcl",
@@ -1710,6 +1755,67 @@ func TestAttestDuplicates(t *testing.T) {
 	}, opts["default"].Attests)
 }
 
+func TestReadTargetsDeterministic(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+group "default" {
+  targets = ["app", "lint", "unit"]
+}
+target "app" {
+  contexts = {
+    deps = "target:deps"
+    lib = "target:lib"
+  }
+  hooks {
+    pre = ["echo app"]
+  }
+}
+target "deps" {
+  entitlements = ["network.host"]
+  hooks {
+    pre = ["echo deps"]
+  }
+}
+target "lib" {
+  entitlements = ["security.insecure"]
+  hooks {
+    post = ["echo lib"]
+  }
+}
+target "lint" {
+  name = "lint-${tgt}"
+  matrix = {
+    tgt = ["a", "b", "c", "d"]
+  }
+  target = tgt
+}
+target "unit" {
+  target = ["test", "test-race"]
+}
+`),
+	}
+
+	var first []byte
+	for i := 0; i < 20; i++ {
+		ent := &EntitlementConf{}
+		m, g, err := ReadTargets(context.TODO(), []File{fp}, []string{"default"}, []string{"*.args.A=1"}, nil, ent)
+		require.NoError(t, err)
+
+		// hooks of the linked targets are collected too
+		require.Equal(t, []string{"app", "deps", "lib"}, ent.hookTargets)
+		require.Equal(t, []string{"network.host", "security.insecure"}, m["app"].Entitlements)
+
+		dt, err := json.Marshal(map[string]any{"group": g, "target": m})
+		require.NoError(t, err)
+		if first == nil {
+			first = dt
+			continue
+		}
+		require.Equal(t, string(first), string(dt))
+	}
+}
+
 func TestAnnotations(t *testing.T) {
 	fp := File{
 		Name: "docker-bake.hcl",
@@ -1738,6 +1844,73 @@ func TestAnnotations(t *testing.T) {
 	require.Equal(t, "bar", bo["app"].Exports[0].Attrs["annotation-manifest[linux/amd64].foo"])
 }
 
+func TestAnnotationsMap(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(
+			`target "app" {
+				output = ["type=image,name=foo"]
+				annotations = {
+					"index:org.opencontainers.image.title" = "app"
+					"org.opencontainers.image.authors" = "dvdksn"
+				}
+			}`),
+	}
+	ctx := context.TODO()
+	m, _, err := ReadTargets(ctx, []File{fp}, []string{"app"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, buildflags.Annotations{
+		"index:org.opencontainers.image.title=app",
+		"org.opencontainers.image.authors=dvdksn",
+	}, m["app"].Annotations)
+
+	bo, err := TargetsToBuildOpt(m, &Input{})
+	require.NoError(t, err)
+	require.Equal(t, "app", bo["app"].Exports[0].Attrs["annotation-index.org.opencontainers.image.title"])
+	require.Equal(t, "dvdksn", bo["app"].Exports[0].Attrs["annotation.org.opencontainers.image.authors"])
+
+	fp.Data = []byte(
+		`target "app" {
+			annotations = {
+				"layer:foo" = "bar"
+			}
+		}`)
+	_, _, err = ReadTargets(ctx, []File{fp}, []string{"app"}, nil, nil, &EntitlementConf{})
+	require.ErrorContains(t, err, `unknown annotation type "layer"`)
+}
+
+func TestAnnotationsAuto(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(
+			`target "app" {
+				context = "https://github.com/docker/buildx.git#8e9f4c0c2b6d1ec5a6e9a0bd5b3f4c8d9e0f1a2b"
+				platforms = ["linux/amd64", "linux/arm64"]
+				output = ["type=image,name=foo"]
+				annotations = ["org.opencontainers.image.source=https://example.com/app"]
+				annotations-auto = true
+			}`),
+	}
+	ctx := context.TODO()
+	m, _, err := ReadTargets(ctx, []File{fp}, []string{"app"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+
+	bo, err := TargetsToBuildOpt(m, &Input{})
+	require.NoError(t, err)
+	attrs := bo["app"].Exports[0].Attrs
+	require.Equal(t, "8e9f4c0c2b6d1ec5a6e9a0bd5b3f4c8d9e0f1a2b", attrs["annotation-manifest.org.opencontainers.image.revision"])
+	require.Equal(t, "8e9f4c0c2b6d1ec5a6e9a0bd5b3f4c8d9e0f1a2b", attrs["annotation-index.org.opencontainers.image.revision"])
+	// set by the target
+	require.Equal(t, "https://example.com/app", attrs["annotation.org.opencontainers.image.source"])
+	require.NotContains(t, attrs, "annotation-manifest.org.opencontainers.image.source")
+
+	m, _, err = ReadTargets(ctx, []File{fp}, []string{"app"}, []string{"app.annotations-auto=false"}, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	bo, err = TargetsToBuildOpt(m, &Input{})
+	require.NoError(t, err)
+	require.NotContains(t, bo["app"].Exports[0].Attrs, "annotation-manifest.org.opencontainers.image.revision")
+}
+
 func TestHCLEntitlements(t *testing.T) {
 	fp := File{
 		Name: "docker-bake.hcl",
@@ -2058,6 +2231,33 @@ target "app" {
 	require.Len(t, m["app"].Outputs, 0)
 }
 
+func TestFrontendImage(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+target "app" {
+  frontend-image = "docker/dockerfile:1.7"
+  args = {
+    BUILDKIT_SYNTAX = "docker/dockerfile:1.4"
+  }
+}
+target "other" {
+}
+`),
+	}
+
+	ctx := context.TODO()
+	m, _, err := ReadTargets(ctx, []File{fp}, []string{"app", "other"}, []string{"other.frontend-image=labs"}, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, "docker/dockerfile:1.7", *m["app"].FrontendImage)
+	require.Equal(t, "labs", *m["other"].FrontendImage)
+
+	bo, err := TargetsToBuildOpt(m, &Input{})
+	require.NoError(t, err)
+	require.Equal(t, "docker/dockerfile:1.7", bo["app"].BuildArgs["BUILDKIT_SYNTAX"])
+	require.Equal(t, "docker/dockerfile:labs", bo["other"].BuildArgs["BUILDKIT_SYNTAX"])
+}
+
 func stringify[V fmt.Stringer](values []V) []string {
 	s := make([]string, len(values))
 	for i, v := range values {
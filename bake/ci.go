@@ -0,0 +1,105 @@
+package bake
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CI providers detected by CIVariables.
+const (
+	CIProviderGitHub = "github"
+	CIProviderGitLab = "gitlab"
+)
+
+// ciVariables are the names of the builtin variables set by CIVariables.
+var ciVariables = []string{
+	"CI_PROVIDER",
+	"CI_BRANCH",
+	"CI_TAG",
+	"CI_PR_NUMBER",
+	"CI_COMMIT_SHA",
+	"CI_COMMIT_SHORT_SHA",
+	"CI_REPOSITORY",
+}
+
+// CIVariables returns the builtin variables holding the branch, tag, pull
+// request number and commit of the build, read from the environment of the
+// CI provider. The provider is detected from the environment if it is empty.
+// The variables are empty if no provider is detected, so definitions using
+// them can still be built locally.
+func CIVariables(provider string, lookup func(string) (string, bool)) (map[string]string, error) {
+	env := func(k string) string {
+		v, _ := lookup(k)
+		return v
+	}
+	if provider == "" {
+		switch {
+		case env("GITHUB_ACTIONS") == "true":
+			provider = CIProviderGitHub
+		case env("GITLAB_CI") == "true":
+			provider = CIProviderGitLab
+		}
+	}
+
+	vars := make(map[string]string, len(ciVariables))
+	for _, k := range ciVariables {
+		vars[k] = ""
+	}
+
+	switch provider {
+	case "":
+		return vars, nil
+	case CIProviderGitHub:
+		ref := env("GITHUB_REF")
+		switch {
+		case strings.HasPrefix(ref, "refs/pull/"):
+			// the ref of pull requests is refs/pull/<number>/merge
+			vars["CI_PR_NUMBER"], _, _ = strings.Cut(strings.TrimPrefix(ref, "refs/pull/"), "/")
+			vars["CI_BRANCH"] = env("GITHUB_HEAD_REF")
+		case strings.HasPrefix(ref, "refs/tags/"):
+			vars["CI_TAG"] = strings.TrimPrefix(ref, "refs/tags/")
+		case strings.HasPrefix(ref, "refs/heads/"):
+			vars["CI_BRANCH"] = strings.TrimPrefix(ref, "refs/heads/")
+		}
+		vars["CI_COMMIT_SHA"] = env("GITHUB_SHA")
+		vars["CI_REPOSITORY"] = env("GITHUB_REPOSITORY")
+	case CIProviderGitLab:
+		if iid := env("CI_MERGE_REQUEST_IID"); iid != "" {
+			vars["CI_PR_NUMBER"] = iid
+			vars["CI_BRANCH"] = env("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME")
+		} else {
+			vars["CI_BRANCH"] = env("CI_COMMIT_BRANCH")
+		}
+		vars["CI_TAG"] = env("CI_COMMIT_TAG")
+		vars["CI_COMMIT_SHA"] = env("CI_COMMIT_SHA")
+		vars["CI_REPOSITORY"] = env("CI_PROJECT_PATH")
+	default:
+		return nil, errors.Errorf("invalid CI provider %q, expecting %s or %s", provider, CIProviderGitHub, CIProviderGitLab)
+	}
+	vars["CI_PROVIDER"] = provider
+	if sha := vars["CI_COMMIT_SHA"]; len(sha) > 7 {
+		vars["CI_COMMIT_SHORT_SHA"] = sha[:7]
+	} else {
+		vars["CI_COMMIT_SHORT_SHA"] = sha
+	}
+	return vars, nil
+}
+
+// ParseCIVariablesEnv parses the value of the environment variable enabling
+// the CI builtin variables: a boolean to detect the provider, or the name of
+// the provider. It returns whether the variables are enabled.
+func ParseCIVariablesEnv(v string) (provider string, enabled bool, err error) {
+	if v == "" {
+		return "", false, nil
+	}
+	if b, err := strconv.ParseBool(v); err == nil {
+		return "", b, nil
+	}
+	switch v {
+	case CIProviderGitHub, CIProviderGitLab:
+		return v, true, nil
+	}
+	return "", false, errors.Errorf("invalid value %q, expecting a boolean, %s or %s", v, CIProviderGitHub, CIProviderGitLab)
+}
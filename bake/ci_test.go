@@ -0,0 +1,176 @@
+package bake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIVariables(t *testing.T) {
+	tcs := []struct {
+		name     string
+		provider string
+		env      map[string]string
+		expected map[string]string
+	}{
+		{
+			name: "none",
+			env:  map[string]string{},
+			expected: map[string]string{
+				"CI_PROVIDER":         "",
+				"CI_BRANCH":           "",
+				"CI_TAG":              "",
+				"CI_PR_NUMBER":        "",
+				"CI_COMMIT_SHA":       "",
+				"CI_COMMIT_SHORT_SHA": "",
+				"CI_REPOSITORY":       "",
+			},
+		},
+		{
+			name: "github branch",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_REF":        "refs/heads/main",
+				"GITHUB_SHA":        "4e1f9c1f7d1bbcf7e2b4f2a4a9c4bd5ef4f2f6b0",
+				"GITHUB_REPOSITORY": "docker/buildx",
+			},
+			expected: map[string]string{
+				"CI_PROVIDER":         "github",
+				"CI_BRANCH":           "main",
+				"CI_TAG":              "",
+				"CI_PR_NUMBER":        "",
+				"CI_COMMIT_SHA":       "4e1f9c1f7d1bbcf7e2b4f2a4a9c4bd5ef4f2f6b0",
+				"CI_COMMIT_SHORT_SHA": "4e1f9c1",
+				"CI_REPOSITORY":       "docker/buildx",
+			},
+		},
+		{
+			name: "github pull request",
+			env: map[string]string{
+				"GITHUB_ACTIONS":  "true",
+				"GITHUB_REF":      "refs/pull/1234/merge",
+				"GITHUB_HEAD_REF": "feature/ci",
+			},
+			expected: map[string]string{
+				"CI_PROVIDER":         "github",
+				"CI_BRANCH":           "feature/ci",
+				"CI_TAG":              "",
+				"CI_PR_NUMBER":        "1234",
+				"CI_COMMIT_SHA":       "",
+				"CI_COMMIT_SHORT_SHA": "",
+				"CI_REPOSITORY":       "",
+			},
+		},
+		{
+			name: "github tag",
+			env: map[string]string{
+				"GITHUB_ACTIONS": "true",
+				"GITHUB_REF":     "refs/tags/v1.2.3",
+			},
+			expected: map[string]string{
+				"CI_PROVIDER":         "github",
+				"CI_BRANCH":           "",
+				"CI_TAG":              "v1.2.3",
+				"CI_PR_NUMBER":        "",
+				"CI_COMMIT_SHA":       "",
+				"CI_COMMIT_SHORT_SHA": "",
+				"CI_REPOSITORY":       "",
+			},
+		},
+		{
+			name: "gitlab merge request",
+			env: map[string]string{
+				"GITLAB_CI":                           "true",
+				"CI_MERGE_REQUEST_IID":                "42",
+				"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "feature/ci",
+				"CI_COMMIT_SHA":                       "5f2a0d2a8e2ccd08f3c5a3b5bad5ce6fa5a3a7c1",
+				"CI_PROJECT_PATH":                     "group/project",
+			},
+			expected: map[string]string{
+				"CI_PROVIDER":         "gitlab",
+				"CI_BRANCH":           "feature/ci",
+				"CI_TAG":              "",
+				"CI_PR_NUMBER":        "42",
+				"CI_COMMIT_SHA":       "5f2a0d2a8e2ccd08f3c5a3b5bad5ce6fa5a3a7c1",
+				"CI_COMMIT_SHORT_SHA": "5f2a0d2",
+				"CI_REPOSITORY":       "group/project",
+			},
+		},
+		{
+			name:     "gitlab forced",
+			provider: "gitlab",
+			env: map[string]string{
+				"GITHUB_ACTIONS":  "true",
+				"CI_COMMIT_TAG":   "v1.2.3",
+				"CI_PROJECT_PATH": "group/project",
+			},
+			expected: map[string]string{
+				"CI_PROVIDER":         "gitlab",
+				"CI_BRANCH":           "",
+				"CI_TAG":              "v1.2.3",
+				"CI_PR_NUMBER":        "",
+				"CI_COMMIT_SHA":       "",
+				"CI_COMMIT_SHORT_SHA": "",
+				"CI_REPOSITORY":       "group/project",
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			vars, err := CIVariables(tc.provider, func(k string) (string, bool) {
+				v, ok := tc.env[k]
+				return v, ok
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, vars)
+		})
+	}
+
+	_, err := CIVariables("jenkins", func(string) (string, bool) { return "", false })
+	require.ErrorContains(t, err, `invalid CI provider "jenkins"`)
+}
+
+func TestParseCIVariablesEnv(t *testing.T) {
+	provider, ok, err := ParseCIVariablesEnv("")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Empty(t, provider)
+
+	provider, ok, err = ParseCIVariablesEnv("1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, provider)
+
+	provider, ok, err = ParseCIVariablesEnv("gitlab")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "gitlab", provider)
+
+	_, _, err = ParseCIVariablesEnv("jenkins")
+	require.ErrorContains(t, err, `invalid value "jenkins"`)
+}
+
+func TestReadTargetsCIVariables(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+target "app" {
+  tags = CI_TAG != "" ? ["user/app:${CI_TAG}"] : ["user/app:${CI_COMMIT_SHORT_SHA}"]
+}
+`),
+	}
+	vars, err := CIVariables("", func(k string) (string, bool) {
+		v, ok := map[string]string{
+			"GITHUB_ACTIONS": "true",
+			"GITHUB_REF":     "refs/heads/main",
+			"GITHUB_SHA":     "4e1f9c1f7d1bbcf7e2b4f2a4a9c4bd5ef4f2f6b0",
+		}[k]
+		return v, ok
+	})
+	require.NoError(t, err)
+
+	m, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app"}, nil, vars, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"user/app:4e1f9c1"}, m["app"].Tags)
+}
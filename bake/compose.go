@@ -35,21 +35,7 @@ func ParseComposeFiles(fs []File) (*Config, error) {
 }
 
 func ParseCompose(cfgs []composetypes.ConfigFile, envs map[string]string) (*Config, error) {
-	if envs == nil {
-		envs = make(map[string]string)
-	}
-	cfg, err := loader.LoadWithContext(context.Background(), composetypes.ConfigDetails{
-		ConfigFiles: cfgs,
-		Environment: envs,
-	}, func(options *loader.Options) {
-		projectName := "bake"
-		if v, ok := envs[consts.ComposeProjectName]; ok && v != "" {
-			projectName = v
-		}
-		options.SetProjectName(projectName, false)
-		options.SkipNormalization = true
-		options.Profiles = []string{"*"}
-	})
+	cfg, err := loadCompose(cfgs, envs)
 	if err != nil {
 		return nil, err
 	}
@@ -179,12 +165,14 @@ func ParseCompose(cfgs []composetypes.ConfigFile, envs map[string]string) (*Conf
 				ShmSize:     shmSize,
 				Ulimits:     ulimits,
 			}
+			if s.Develop != nil {
+				t.Watch = composeToBakeWatch(s.Develop.Watch)
+			}
 			if err = t.composeExtTarget(s.Build.Extensions); err != nil {
 				return nil, err
 			}
 			if s.Build.Target != "" {
-				target := s.Build.Target
-				t.Target = &target
+				t.Target = Stages{s.Build.Target}
 			}
 			if len(t.Tags) == 0 && s.Image != "" {
 				t.Tags = []string{s.Image}
@@ -197,6 +185,88 @@ func ParseCompose(cfgs []composetypes.ConfigFile, envs map[string]string) (*Conf
 	return &c, nil
 }
 
+// composeToBakeWatch returns the paths of the watch triggers that rebuild the
+// service. Other actions apply to the running containers and are ignored.
+func composeToBakeWatch(triggers []composetypes.Trigger) []*TargetWatch {
+	var watch []*TargetWatch
+	for _, tr := range triggers {
+		if tr.Action != composetypes.WatchActionRebuild || tr.Path == "" {
+			continue
+		}
+		watch = append(watch, &TargetWatch{
+			Path:   tr.Path,
+			Ignore: tr.Ignore,
+		})
+	}
+	return watch
+}
+
+func loadCompose(cfgs []composetypes.ConfigFile, envs map[string]string) (*composetypes.Project, error) {
+	if envs == nil {
+		envs = make(map[string]string)
+	}
+	return loader.LoadWithContext(context.Background(), composetypes.ConfigDetails{
+		ConfigFiles: cfgs,
+		Environment: envs,
+	}, func(options *loader.Options) {
+		projectName := "bake"
+		if v, ok := envs[consts.ComposeProjectName]; ok && v != "" {
+			projectName = v
+		}
+		options.SetProjectName(projectName, false)
+		options.SkipNormalization = true
+		options.Profiles = []string{"*"}
+	})
+}
+
+// ComposeBuilder is the builder described by the x-builder extension of a
+// Compose file.
+type ComposeBuilder struct {
+	Name            string      `yaml:"name,omitempty"`
+	Driver          string      `yaml:"driver,omitempty"`
+	DriverOpts      stringArray `yaml:"driver-opts,omitempty"`
+	Platforms       stringArray `yaml:"platforms,omitempty"`
+	BuildkitdFlags  string      `yaml:"buildkitd-flags,omitempty"`
+	BuildkitdConfig string      `yaml:"buildkitd-config,omitempty"`
+	// don't forget to update documentation if you add a new field:
+	// docs/reference/buildx_create.md#from-file
+}
+
+// ParseComposeBuilder returns the builder described by the x-builder
+// extension of Compose files, or nil if none of the files have one. The
+// BuildKit daemon config file is relative to the first file.
+func ParseComposeBuilder(fs []File) (*ComposeBuilder, error) {
+	envs, err := composeEnv()
+	if err != nil {
+		return nil, err
+	}
+	var cfgs []composetypes.ConfigFile
+	for _, f := range fs {
+		cfgs = append(cfgs, composetypes.ConfigFile{
+			Filename: f.Name,
+			Content:  f.Data,
+		})
+	}
+	cfg, err := loadCompose(cfgs, envs)
+	if err != nil {
+		return nil, err
+	}
+
+	ext, ok := cfg.Extensions["x-builder"]
+	if !ok || ext == nil {
+		return nil, nil
+	}
+	var b ComposeBuilder
+	yb, _ := yaml.Marshal(ext)
+	if err := yaml.Unmarshal(yb, &b); err != nil {
+		return nil, errors.Wrap(err, "invalid x-builder extension")
+	}
+	if b.BuildkitdConfig != "" && !filepath.IsAbs(b.BuildkitdConfig) && len(fs) > 0 {
+		b.BuildkitdConfig = filepath.Join(filepath.Dir(fs[0].Name), b.BuildkitdConfig)
+	}
+	return &b, nil
+}
+
 func validateComposeFile(dt []byte, fn string) (bool, error) {
 	envs, err := composeEnv()
 	if err != nil {
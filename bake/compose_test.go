@@ -123,9 +123,9 @@ services:
 		return c.Targets[i].Name < c.Targets[j].Name
 	})
 	require.Equal(t, "db", c.Targets[0].Name)
-	require.Equal(t, "db", *c.Targets[0].Target)
+	require.Equal(t, Stages{"db"}, c.Targets[0].Target)
 	require.Equal(t, "webapp", c.Targets[1].Name)
-	require.Equal(t, "webapp", *c.Targets[1].Target)
+	require.Equal(t, Stages{"webapp"}, c.Targets[1].Target)
 }
 
 func TestComposeBuildWithoutContext(t *testing.T) {
@@ -147,9 +147,9 @@ services:
 		return c.Targets[i].Name < c.Targets[j].Name
 	})
 	require.Equal(t, "db", c.Targets[0].Name)
-	require.Equal(t, "db", *c.Targets[0].Target)
+	require.Equal(t, Stages{"db"}, c.Targets[0].Target)
 	require.Equal(t, "webapp", c.Targets[1].Name)
-	require.Equal(t, "webapp", *c.Targets[1].Target)
+	require.Equal(t, Stages{"webapp"}, c.Targets[1].Target)
 }
 
 func TestBuildArgEnvCompose(t *testing.T) {
@@ -734,9 +734,9 @@ services:
 		return c.Targets[i].Name < c.Targets[j].Name
 	})
 	require.Equal(t, "bar", c.Targets[0].Name)
-	require.Equal(t, "buildbar", *c.Targets[0].Target)
+	require.Equal(t, Stages{"buildbar"}, c.Targets[0].Target)
 	require.Equal(t, "foo", c.Targets[1].Name)
-	require.Equal(t, "buildfoo", *c.Targets[1].Target)
+	require.Equal(t, Stages{"buildfoo"}, c.Targets[1].Target)
 }
 
 func TestDevelop(t *testing.T) {
@@ -752,10 +752,21 @@ services:
           target: /var/www
           ignore:
             - node_modules/
+        - path: ./webapp/package.json
+          action: rebuild
+        - path: ./webapp/src
+          action: rebuild
+          ignore:
+            - "*.test.js"
 `)
 
-	_, err := ParseCompose([]composetypes.ConfigFile{{Content: dt}}, nil)
+	c, err := ParseCompose([]composetypes.ConfigFile{{Content: dt}}, nil)
 	require.NoError(t, err)
+	require.Len(t, c.Targets, 1)
+	require.Equal(t, []*TargetWatch{
+		{Path: "webapp/package.json"},
+		{Path: "webapp/src", Ignore: []string{"*.test.js"}},
+	}, c.Targets[0].Watch)
 }
 
 func TestCgroup(t *testing.T) {
@@ -798,6 +809,40 @@ services:
 	})
 }
 
+func TestParseComposeBuilder(t *testing.T) {
+	dt := []byte(`
+x-builder:
+  name: ci
+  driver: docker-container
+  driver-opts:
+    - image=moby/buildkit:v0.16.0
+    - network=host
+  platforms: linux/amd64 linux/arm64
+  buildkitd-config: buildkitd.toml
+
+services:
+  app:
+    build: .
+`)
+	b, err := ParseComposeBuilder([]File{{Name: "ci/compose.yaml", Data: dt}})
+	require.NoError(t, err)
+	require.Equal(t, &ComposeBuilder{
+		Name:            "ci",
+		Driver:          "docker-container",
+		DriverOpts:      stringArray{"image=moby/buildkit:v0.16.0", "network=host"},
+		Platforms:       stringArray{"linux/amd64", "linux/arm64"},
+		BuildkitdConfig: filepath.Join("ci", "buildkitd.toml"),
+	}, b)
+
+	b, err = ParseComposeBuilder([]File{{Name: "compose.yaml", Data: []byte(`
+services:
+  app:
+    build: .
+`)}})
+	require.NoError(t, err)
+	require.Nil(t, b)
+}
+
 // chdir changes the current working directory to the named directory,
 // and then restore the original working directory at the end of the test.
 func chdir(t *testing.T, dir string) {
@@ -0,0 +1,129 @@
+package bake
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/moby/buildkit/solver/errdefs"
+	"github.com/pkg/errors"
+)
+
+// Diagnostic is a parsing or validation error of a bake definition with its
+// position in the source.
+type Diagnostic struct {
+	Severity  string `json:"severity"`
+	Summary   string `json:"summary"`
+	Detail    string `json:"detail,omitempty"`
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+	Snippet   string `json:"snippet,omitempty"`
+}
+
+// Diagnostics returns the diagnostics of an error returned when reading the
+// definition files. Errors without HCL diagnostics are returned as a single
+// diagnostic without position.
+func Diagnostics(err error, files []File) []Diagnostic {
+	if err == nil {
+		return nil
+	}
+	var diags hcl.Diagnostics
+	if !errors.As(err, &diags) {
+		return []Diagnostic{{Severity: "error", Summary: err.Error()}}
+	}
+
+	sources := map[string][]byte{}
+	for _, s := range errdefs.Sources(err) {
+		if s.Info != nil {
+			sources[s.Info.Filename] = s.Info.Data
+		}
+	}
+	for _, f := range files {
+		sources[f.Name] = f.Data
+	}
+
+	out := make([]Diagnostic, 0, len(diags))
+	for _, d := range diags {
+		diag := Diagnostic{
+			Severity: "error",
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+		}
+		if d.Severity == hcl.DiagWarning {
+			diag.Severity = "warning"
+		}
+		if d.Subject != nil {
+			diag.File = d.Subject.Filename
+			diag.Line = d.Subject.Start.Line
+			diag.Column = d.Subject.Start.Column
+			diag.EndLine = d.Subject.End.Line
+			diag.EndColumn = d.Subject.End.Column
+			diag.Snippet = snippet(sources[d.Subject.Filename], d.Subject.Start.Line, d.Subject.End.Line)
+		}
+		out = append(out, diag)
+	}
+	return out
+}
+
+// GitHubAnnotation returns the diagnostic as a GitHub Actions workflow command
+// annotating the position in the source.
+func (d Diagnostic) GitHubAnnotation() string {
+	var props []string
+	if d.File != "" {
+		props = append(props, "file="+escapeAnnotationProperty(d.File))
+	}
+	for _, p := range []struct {
+		key string
+		val int
+	}{
+		{"line", d.Line},
+		{"col", d.Column},
+		{"endLine", d.EndLine},
+		{"endColumn", d.EndColumn},
+	} {
+		if p.val > 0 {
+			props = append(props, fmt.Sprintf("%s=%d", p.key, p.val))
+		}
+	}
+	props = append(props, "title="+escapeAnnotationProperty(d.Summary))
+
+	msg := d.Summary
+	if d.Detail != "" {
+		msg = d.Detail
+	}
+	return fmt.Sprintf("::%s %s::%s", d.Severity, strings.Join(props, ","), escapeAnnotationData(msg))
+}
+
+// snippet returns the lines of the source between start and end, limited to
+// a few lines.
+func snippet(dt []byte, start, end int) string {
+	if len(dt) == 0 || start < 1 {
+		return ""
+	}
+	if end < start {
+		end = start
+	}
+	if end-start > 4 {
+		end = start + 4
+	}
+	lines := bytes.Split(dt, []byte("\n"))
+	if start > len(lines) {
+		return ""
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	return string(bytes.TrimRight(bytes.Join(lines[start-1:end], []byte("\n")), "\r\n"))
+}
+
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
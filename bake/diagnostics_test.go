@@ -0,0 +1,63 @@
+package bake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnostics(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`target "app" {
+  context = "."
+  tags = notafunc("bar")
+}
+`),
+	}
+	_, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app"}, nil, nil, nil)
+	require.Error(t, err)
+
+	diags := Diagnostics(err, []File{fp})
+	require.Len(t, diags, 2)
+	require.Equal(t, Diagnostic{
+		Severity:  "error",
+		Summary:   "Call to unknown function",
+		Detail:    `There is no function named "notafunc".`,
+		File:      "docker-bake.hcl",
+		Line:      3,
+		Column:    10,
+		EndLine:   3,
+		EndColumn: 18,
+		Snippet:   `  tags = notafunc("bar")`,
+	}, diags[0])
+	require.Equal(t, `::error file=docker-bake.hcl,line=3,col=10,endLine=3,endColumn=18,title=Call to unknown function::There is no function named "notafunc".`, diags[0].GitHubAnnotation())
+
+	diags = Diagnostics(errors.New("failed to find target foo"), nil)
+	require.Equal(t, []Diagnostic{{Severity: "error", Summary: "failed to find target foo"}}, diags)
+	require.Equal(t, "::error title=failed to find target foo::failed to find target foo", diags[0].GitHubAnnotation())
+}
+
+func TestDiagnosticsValidation(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`target "app" {
+  validation {
+    condition = length(target.tags) > 0
+    error_message = "tags: required,\nset one"
+  }
+}
+`),
+	}
+	_, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app"}, nil, nil, nil)
+	require.Error(t, err)
+
+	diags := Diagnostics(err, nil)
+	require.Len(t, diags, 1)
+	require.Equal(t, "docker-bake.hcl", diags[0].File)
+	require.Equal(t, 3, diags[0].Line)
+	require.Equal(t, "    condition = length(target.tags) > 0", diags[0].Snippet)
+	require.Contains(t, diags[0].GitHubAnnotation(), "%0Aset one")
+}
@@ -0,0 +1,80 @@
+package bake
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+const (
+	TargetDiffAdded   = "added"
+	TargetDiffRemoved = "removed"
+	TargetDiffChanged = "changed"
+)
+
+// FieldDiff is the previous and current value of a target field. A nil
+// value means the field is not set.
+type FieldDiff struct {
+	Previous any `json:"previous,omitempty"`
+	Current  any `json:"current,omitempty"`
+}
+
+// TargetDiff describes how a target changed between two resolutions of a
+// bake definition.
+type TargetDiff struct {
+	Status string               `json:"status"`
+	Fields map[string]FieldDiff `json:"fields,omitempty"`
+}
+
+// DiffTargets compares two sets of resolved targets and returns the targets
+// that were added, removed or have changed fields. Unchanged targets are
+// omitted.
+func DiffTargets(prev, cur map[string]*Target) (map[string]*TargetDiff, error) {
+	res := map[string]*TargetDiff{}
+	for name, t := range cur {
+		pt, ok := prev[name]
+		if !ok {
+			res[name] = &TargetDiff{Status: TargetDiffAdded}
+			continue
+		}
+		pf, err := targetFields(pt)
+		if err != nil {
+			return nil, err
+		}
+		cf, err := targetFields(t)
+		if err != nil {
+			return nil, err
+		}
+		fields := map[string]FieldDiff{}
+		for k, v := range cf {
+			if pv, ok := pf[k]; !ok || !reflect.DeepEqual(pv, v) {
+				fields[k] = FieldDiff{Previous: pf[k], Current: v}
+			}
+		}
+		for k, pv := range pf {
+			if _, ok := cf[k]; !ok {
+				fields[k] = FieldDiff{Previous: pv}
+			}
+		}
+		if len(fields) > 0 {
+			res[name] = &TargetDiff{Status: TargetDiffChanged, Fields: fields}
+		}
+	}
+	for name := range prev {
+		if _, ok := cur[name]; !ok {
+			res[name] = &TargetDiff{Status: TargetDiffRemoved}
+		}
+	}
+	return res, nil
+}
+
+func targetFields(t *Target) (map[string]any, error) {
+	dt, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(dt, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,60 @@
+package bake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffTargets(t *testing.T) {
+	prev := map[string]*Target{
+		"app": {
+			Name:       "app",
+			Dockerfile: ptrstr("Dockerfile"),
+			Tags:       []string{"app:latest"},
+			Args:       map[string]*string{"GO_VERSION": ptrstr("1.22")},
+		},
+		"docs":  {Name: "docs", Tags: []string{"docs"}},
+		"tests": {Name: "tests", Target: Stages{"test"}},
+	}
+	cur := map[string]*Target{
+		"app": {
+			Name:       "app",
+			Dockerfile: ptrstr("Dockerfile"),
+			Tags:       []string{"app:latest", "app:v1"},
+			Platforms:  []string{"linux/amd64"},
+		},
+		"docs": {Name: "docs", Tags: []string{"docs"}},
+		"lint": {Name: "lint"},
+	}
+
+	diff, err := DiffTargets(prev, cur)
+	require.NoError(t, err)
+	require.Len(t, diff, 3)
+
+	require.Equal(t, TargetDiffChanged, diff["app"].Status)
+	require.Equal(t, map[string]FieldDiff{
+		"tags": {
+			Previous: []any{"app:latest"},
+			Current:  []any{"app:latest", "app:v1"},
+		},
+		"platforms": {
+			Current: []any{"linux/amd64"},
+		},
+		"args": {
+			Previous: map[string]any{"GO_VERSION": "1.22"},
+		},
+	}, diff["app"].Fields)
+
+	require.Equal(t, &TargetDiff{Status: TargetDiffAdded}, diff["lint"])
+	require.Equal(t, &TargetDiff{Status: TargetDiffRemoved}, diff["tests"])
+	require.NotContains(t, diff, "docs")
+
+	// everything is added when there is no previous resolution
+	diff, err = DiffTargets(nil, cur)
+	require.NoError(t, err)
+	require.Len(t, diff, 3)
+	for _, d := range diff {
+		require.Equal(t, TargetDiffAdded, d.Status)
+	}
+}
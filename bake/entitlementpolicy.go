@@ -0,0 +1,194 @@
+package bake
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/docker/buildx/build"
+	"github.com/pkg/errors"
+)
+
+const (
+	entitlementPolicyEnv      = "BUILDX_ENTITLEMENT_POLICY"
+	entitlementPolicyFilename = "entitlement-policy.json"
+)
+
+// EntitlementPolicy is an organization-curated set of entitlements that are
+// always granted or always denied for bake builds.
+type EntitlementPolicy struct {
+	// Allow lists entitlements granted without prompting, using the same
+	// format as the --allow flag. Filesystem paths may be glob patterns.
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists entitlements that are refused even if requested with the
+	// --allow flag. Filesystem paths may be glob patterns.
+	Deny []string `json:"deny,omitempty"`
+
+	path string
+	deny EntitlementConf
+}
+
+// LoadEntitlementPolicy reads the entitlement policy from the file set with
+// BUILDX_ENTITLEMENT_POLICY or from the buildx config dir. A nil policy is
+// returned if no policy file is configured.
+func LoadEntitlementPolicy(configDir string) (*EntitlementPolicy, error) {
+	fp, ok := os.LookupEnv(entitlementPolicyEnv)
+	if !ok || fp == "" {
+		if configDir == "" {
+			return nil, nil
+		}
+		fp = filepath.Join(configDir, entitlementPolicyFilename)
+		if _, err := os.Stat(fp); err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+	dt, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read entitlement policy")
+	}
+	return ParseEntitlementPolicy(dt, fp)
+}
+
+// ParseEntitlementPolicy parses an entitlement policy file.
+func ParseEntitlementPolicy(dt []byte, fp string) (*EntitlementPolicy, error) {
+	var p EntitlementPolicy
+	if err := json.Unmarshal(dt, &p); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse entitlement policy %s", fp)
+	}
+	p.path = fp
+
+	deny, err := ParseEntitlements(p.Deny)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid deny entitlement in policy %s", fp)
+	}
+	for _, v := range slices.Concat(deny.FSRead, deny.FSWrite) {
+		if _, err := filepath.Match(v, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid path pattern %q in policy %s", v, fp)
+		}
+	}
+	p.deny = deny
+
+	if _, err := ParseEntitlements(p.Allow); err != nil {
+		return nil, errors.Wrapf(err, "invalid allow entitlement in policy %s", fp)
+	}
+	return &p, nil
+}
+
+// Apply grants the entitlements allowed by the policy.
+func (p *EntitlementPolicy) Apply(c *EntitlementConf) error {
+	allow, err := ParseEntitlements(p.Allow)
+	if err != nil {
+		return err
+	}
+	c.NetworkHost = c.NetworkHost || allow.NetworkHost
+	c.SecurityInsecure = c.SecurityInsecure || allow.SecurityInsecure
+	c.SSH = c.SSH || allow.SSH
+	c.Hooks = c.Hooks || allow.Hooks
+	c.ImagePush = append(c.ImagePush, allow.ImagePush...)
+	c.ImageLoad = append(c.ImageLoad, allow.ImageLoad...)
+
+	fsRead, err := expandPathPatterns(allow.FSRead)
+	if err != nil {
+		return errors.Wrapf(err, "invalid allow entitlement in policy %s", p.path)
+	}
+	fsWrite, err := expandPathPatterns(allow.FSWrite)
+	if err != nil {
+		return errors.Wrapf(err, "invalid allow entitlement in policy %s", p.path)
+	}
+	c.FSRead = append(c.FSRead, fsRead...)
+	c.FSWrite = append(c.FSWrite, fsWrite...)
+	return nil
+}
+
+// Check returns an error if any of the builds requests an entitlement denied
+// by the policy.
+func (p *EntitlementPolicy) Check(m map[string]build.Options) error {
+	requested, err := EntitlementConf{}.Validate(m)
+	if err != nil {
+		return err
+	}
+
+	var denied []string
+	if requested.NetworkHost && p.deny.NetworkHost {
+		denied = append(denied, string(EntitlementKeyNetworkHost))
+	}
+	if requested.SecurityInsecure && p.deny.SecurityInsecure {
+		denied = append(denied, string(EntitlementKeySecurityInsecure))
+	}
+	if requested.SSH && p.deny.SSH {
+		denied = append(denied, string(EntitlementKeySSH))
+	}
+	for _, r := range requested.FSRead {
+		if matchDeniedPath(r, p.deny.FSRead) {
+			denied = append(denied, string(EntitlementKeyFSRead)+"="+r)
+		}
+	}
+	for _, r := range requested.FSWrite {
+		if matchDeniedPath(r, p.deny.FSWrite) {
+			denied = append(denied, string(EntitlementKeyFSWrite)+"="+r)
+		}
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+	return errors.Errorf("entitlements denied by policy %s: %s", p.path, strings.Join(denied, ", "))
+}
+
+// expandPathPatterns resolves glob patterns to the existing paths they match.
+func expandPathPatterns(in []string) ([]string, error) {
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		if v == "*" || !hasGlobMeta(v) {
+			out = append(out, v)
+			continue
+		}
+		matches, err := filepath.Glob(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid path pattern %q", v)
+		}
+		out = append(out, matches...)
+	}
+	return out, nil
+}
+
+// matchDeniedPath reports whether the requested path p is, contains or is
+// contained in one of the denied paths or patterns.
+func matchDeniedPath(p string, deny []string) bool {
+	for _, d := range deny {
+		if d == "*" {
+			return true
+		}
+		if !hasGlobMeta(d) {
+			if v, err := filepath.Abs(d); err == nil {
+				d = v
+			}
+			if isParentOrEqualPath(p, d) || isParentOrEqualPath(d, p) {
+				return true
+			}
+			continue
+		}
+		if !filepath.IsAbs(d) {
+			if v, err := filepath.Abs(d); err == nil {
+				d = v
+			}
+		}
+		for c := p; ; c = filepath.Dir(c) {
+			if ok, _ := filepath.Match(d, c); ok {
+				return true
+			}
+			if c == filepath.Dir(c) {
+				break
+			}
+		}
+	}
+	return false
+}
+
+func hasGlobMeta(p string) bool {
+	return strings.ContainsAny(p, `*?[`)
+}
@@ -0,0 +1,103 @@
+package bake
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/buildx/build"
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/moby/buildkit/client/llb"
+	"github.com/moby/buildkit/util/entitlements"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEntitlementPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	p, err := LoadEntitlementPolicy(dir)
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, entitlementPolicyFilename), []byte(`{"allow":["network.host"],"deny":["security.insecure"]}`), 0600))
+	p, err = LoadEntitlementPolicy(dir)
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	require.Equal(t, []string{"network.host"}, p.Allow)
+	require.Equal(t, []string{"security.insecure"}, p.Deny)
+
+	fp := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(fp, []byte(`{"deny":["ssh"]}`), 0600))
+	t.Setenv(entitlementPolicyEnv, fp)
+	p, err = LoadEntitlementPolicy(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ssh"}, p.Deny)
+
+	t.Setenv(entitlementPolicyEnv, filepath.Join(t.TempDir(), "missing.json"))
+	_, err = LoadEntitlementPolicy(dir)
+	require.Error(t, err)
+}
+
+func TestParseEntitlementPolicyInvalid(t *testing.T) {
+	_, err := ParseEntitlementPolicy([]byte(`{"allow":["foo"]}`), "policy.json")
+	require.ErrorContains(t, err, `unknown entitlement key "foo"`)
+
+	_, err = ParseEntitlementPolicy([]byte(`{"deny":["fs.read=/tmp/[a"]}`), "policy.json")
+	require.ErrorContains(t, err, "invalid path pattern")
+}
+
+func TestEntitlementPolicyApply(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "cache-a"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "cache-b"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "other"), 0755))
+
+	p, err := ParseEntitlementPolicy([]byte(`{"allow":["network.host","fs.read=`+filepath.ToSlash(dir)+`/cache-*","fs.write=/tmp/out"]}`), "policy.json")
+	require.NoError(t, err)
+
+	var conf EntitlementConf
+	require.NoError(t, p.Apply(&conf))
+	require.True(t, conf.NetworkHost)
+	require.False(t, conf.SecurityInsecure)
+	require.Equal(t, []string{filepath.Join(dir, "cache-a"), filepath.Join(dir, "cache-b")}, conf.FSRead)
+	require.Equal(t, []string{"/tmp/out"}, conf.FSWrite)
+}
+
+func TestEntitlementPolicyCheck(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "secrets", "token")
+	require.NoError(t, os.MkdirAll(filepath.Dir(secretPath), 0755))
+	require.NoError(t, os.WriteFile(secretPath, []byte("x"), 0600))
+	expDir, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+
+	opts := map[string]build.Options{
+		"app": {
+			Inputs: build.Inputs{
+				ContextState: &llb.State{},
+			},
+			Allow: []entitlements.Entitlement{
+				entitlements.EntitlementSecurityInsecure,
+			},
+			SecretSpecs: buildflags.Secrets{
+				{ID: "token", FilePath: secretPath},
+			}.ToPB(),
+		},
+	}
+
+	p, err := ParseEntitlementPolicy([]byte(`{"deny":["network.host"]}`), "policy.json")
+	require.NoError(t, err)
+	require.NoError(t, p.Check(opts))
+
+	p, err = ParseEntitlementPolicy([]byte(`{"deny":["security.insecure"]}`), "policy.json")
+	require.NoError(t, err)
+	require.ErrorContains(t, p.Check(opts), "entitlements denied by policy policy.json: security.insecure")
+
+	p, err = ParseEntitlementPolicy([]byte(`{"deny":["fs.read=`+filepath.ToSlash(expDir)+`/secret*"]}`), "policy.json")
+	require.NoError(t, err)
+	require.ErrorContains(t, p.Check(opts), "fs.read="+filepath.Join(expDir, "secrets", "token"))
+
+	p, err = ParseEntitlementPolicy([]byte(`{"deny":["fs.write=`+filepath.ToSlash(expDir)+`"]}`), "policy.json")
+	require.NoError(t, err)
+	require.NoError(t, p.Check(opts))
+}
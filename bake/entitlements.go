@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 
 	"github.com/containerd/console"
 	"github.com/docker/buildx/build"
@@ -34,6 +35,7 @@ const (
 	EntitlementKeyImageLoad        EntitlementKey = "image.load"
 	EntitlementKeyImage            EntitlementKey = "image"
 	EntitlementKeySSH              EntitlementKey = "ssh"
+	EntitlementKeyHooks            EntitlementKey = "hooks"
 )
 
 type EntitlementConf struct {
@@ -44,6 +46,13 @@ type EntitlementConf struct {
 	ImagePush        []string
 	ImageLoad        []string
 	SSH              bool
+	Hooks            bool
+
+	// templateFiles holds the local files rendered with templatefile while
+	// reading the definition.
+	templateFiles []string
+	// hookTargets holds the targets that define hooks to run on the client.
+	hookTargets []string
 }
 
 func ParseEntitlements(in []string) (EntitlementConf, error) {
@@ -56,6 +65,8 @@ func ParseEntitlements(in []string) (EntitlementConf, error) {
 			conf.SecurityInsecure = true
 		case string(EntitlementKeySSH):
 			conf.SSH = true
+		case string(EntitlementKeyHooks):
+			conf.Hooks = true
 		default:
 			k, v, _ := strings.Cut(e, "=")
 			switch k {
@@ -81,47 +92,119 @@ func ParseEntitlements(in []string) (EntitlementConf, error) {
 	return conf, nil
 }
 
+// EntitlementRequest is an entitlement missing for the build of a target,
+// along with the field of the target requesting it.
+type EntitlementRequest struct {
+	// Target is empty for the entitlements requested by the definition
+	// itself rather than by one of its targets.
+	Target string         `json:"target,omitempty"`
+	Key    EntitlementKey `json:"key"`
+	Path   string         `json:"path,omitempty"`
+	Reason string         `json:"reason"`
+}
+
+// Flag returns the value of the --allow flag granting the entitlement.
+func (r EntitlementRequest) Flag() string {
+	if r.Path != "" {
+		return string(r.Key) + "=" + r.Path
+	}
+	return string(r.Key)
+}
+
 func (c EntitlementConf) Validate(m map[string]build.Options) (EntitlementConf, error) {
+	return c.validate(m, nil)
+}
+
+// Requests returns the entitlements missing for the build of the targets,
+// with the field of each target requesting them, sorted by target.
+func (c EntitlementConf) Requests(m map[string]build.Options) ([]EntitlementRequest, error) {
+	var reqs []EntitlementRequest
+	if _, err := c.validate(m, &reqs); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(reqs, func(a, b EntitlementRequest) int {
+		return cmp.Or(
+			cmp.Compare(a.Target, b.Target),
+			cmp.Compare(a.Key, b.Key),
+			cmp.Compare(a.Path, b.Path),
+			cmp.Compare(a.Reason, b.Reason),
+		)
+	})
+	return slices.Compact(reqs), nil
+}
+
+func (c EntitlementConf) validate(m map[string]build.Options, reqs *[]EntitlementRequest) (EntitlementConf, error) {
 	var expected EntitlementConf
 
-	for _, v := range m {
-		if err := c.check(v, &expected); err != nil {
+	for _, k := range sortedKeys(m) {
+		if err := c.check(k, m[k], &expected, reqs); err != nil {
+			return EntitlementConf{}, err
+		}
+	}
+
+	if len(c.templateFiles) > 0 {
+		roPaths := pathReasons{}
+		for _, p := range c.templateFiles {
+			roPaths.add(p, "templatefile")
+		}
+		fsRead, err := roPaths.missing("", EntitlementKeyFSRead, c.FSRead, reqs)
+		if err != nil {
 			return EntitlementConf{}, err
 		}
+		expected.FSRead = mergePaths(expected.FSRead, fsRead)
+	}
+
+	if len(c.hookTargets) > 0 && !c.Hooks {
+		expected.Hooks = true
+		if reqs != nil {
+			for _, t := range c.hookTargets {
+				*reqs = append(*reqs, EntitlementRequest{Target: t, Key: EntitlementKeyHooks, Reason: "hooks"})
+			}
+		}
 	}
 
 	return expected, nil
 }
 
-func (c EntitlementConf) check(bo build.Options, expected *EntitlementConf) error {
+func (c EntitlementConf) check(name string, bo build.Options, expected *EntitlementConf, reqs *[]EntitlementRequest) error {
+	request := func(key EntitlementKey, reason string) {
+		if reqs != nil {
+			*reqs = append(*reqs, EntitlementRequest{Target: name, Key: key, Reason: reason})
+		}
+	}
+
 	for _, e := range bo.Allow {
 		switch e {
 		case entitlements.EntitlementNetworkHost:
 			if !c.NetworkHost {
 				expected.NetworkHost = true
+				request(EntitlementKeyNetworkHost, "entitlements")
 			}
 		case entitlements.EntitlementSecurityInsecure:
 			if !c.SecurityInsecure {
 				expected.SecurityInsecure = true
+				request(EntitlementKeySecurityInsecure, "entitlements")
 			}
 		}
 	}
 
-	rwPaths := map[string]struct{}{}
-	roPaths := map[string]struct{}{}
+	rwPaths := pathReasons{}
+	roPaths := pathReasons{}
 
-	for _, p := range collectLocalPaths(bo.Inputs) {
-		roPaths[p] = struct{}{}
+	for p, reasons := range localInputPaths(bo.Inputs) {
+		for _, r := range reasons {
+			roPaths.add(p, r)
+		}
 	}
 
 	for _, p := range bo.ExportsLocalPathsTemporary {
-		rwPaths[p] = struct{}{}
+		rwPaths.add(p, "output")
 	}
 
 	for _, ce := range bo.CacheTo {
 		if ce.Type == "local" {
 			if dest, ok := ce.Attrs["dest"]; ok {
-				rwPaths[dest] = struct{}{}
+				rwPaths.add(dest, "cache-to")
 			}
 		}
 	}
@@ -129,41 +212,97 @@ func (c EntitlementConf) check(bo build.Options, expected *EntitlementConf) erro
 	for _, ci := range bo.CacheFrom {
 		if ci.Type == "local" {
 			if src, ok := ci.Attrs["src"]; ok {
-				roPaths[src] = struct{}{}
+				roPaths.add(src, "cache-from")
 			}
 		}
 	}
 
 	for _, secret := range bo.SecretSpecs {
 		if secret.FilePath != "" {
-			roPaths[secret.FilePath] = struct{}{}
+			roPaths.add(secret.FilePath, fmt.Sprintf("secret (id=%s)", secret.ID))
 		}
 	}
 
 	for _, ssh := range bo.SSHSpecs {
 		for _, p := range ssh.Paths {
-			roPaths[p] = struct{}{}
+			roPaths.add(p, fmt.Sprintf("ssh (id=%s)", ssh.ID))
 		}
 		if len(ssh.Paths) == 0 {
 			expected.SSH = true
+			request(EntitlementKeySSH, fmt.Sprintf("ssh (id=%s)", ssh.ID))
 		}
 	}
 
-	var err error
-	expected.FSRead, err = findMissingPaths(c.FSRead, roPaths)
+	fsRead, err := roPaths.missing(name, EntitlementKeyFSRead, c.FSRead, reqs)
 	if err != nil {
 		return err
 	}
+	expected.FSRead = mergePaths(expected.FSRead, fsRead)
 
-	expected.FSWrite, err = findMissingPaths(c.FSWrite, rwPaths)
+	fsWrite, err := rwPaths.missing(name, EntitlementKeyFSWrite, c.FSWrite, reqs)
 	if err != nil {
 		return err
 	}
+	expected.FSWrite = mergePaths(expected.FSWrite, fsWrite)
 
 	return nil
 }
 
-func (c EntitlementConf) Prompt(ctx context.Context, isRemote bool, out io.Writer) error {
+// pathReasons holds the local paths accessed by a build along with the fields
+// of the target accessing them.
+type pathReasons map[string][]string
+
+func (p pathReasons) add(path, reason string) {
+	p[path] = append(p[path], reason)
+}
+
+// missing returns the paths not granted by set. If reqs is not nil, a request
+// is added to it for each field accessing a missing path.
+func (p pathReasons) missing(target string, key EntitlementKey, set []string, reqs *[]EntitlementRequest) ([]string, error) {
+	paths := make(map[string]struct{}, len(p))
+	for k := range p {
+		paths[k] = struct{}{}
+	}
+	out, err := findMissingPaths(set, paths)
+	if err != nil || len(out) == 0 || reqs == nil {
+		return out, err
+	}
+
+	for k, reasons := range p {
+		v, _, err := evaluateToExistingPath(k)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to evaluate path %q", k)
+		}
+		if v, err = osutil.GetLongPathName(v); err != nil {
+			return nil, errors.Wrapf(err, "failed to evaluate path %q", k)
+		}
+		for _, m := range out {
+			if !isParentOrEqualPath(v, m) {
+				continue
+			}
+			for _, r := range reasons {
+				*reqs = append(*reqs, EntitlementRequest{Target: target, Key: key, Path: m, Reason: r})
+			}
+			break
+		}
+	}
+	return out, nil
+}
+
+// mergePaths returns the sorted union of two path lists, keeping nil if both
+// are empty.
+func mergePaths(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	out := slices.Concat(a, b)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// Prompt asks for the confirmation of the entitlements of c, listing the
+// targets and fields requesting them from reqs.
+func (c EntitlementConf) Prompt(ctx context.Context, isRemote bool, reqs []EntitlementRequest, out io.Writer) error {
 	var term bool
 	if _, err := console.ConsoleFromFile(os.Stdin); err == nil {
 		term = true
@@ -184,6 +323,10 @@ func (c EntitlementConf) Prompt(ctx context.Context, isRemote bool, out io.Write
 		msgs = append(msgs, " - Running privileged containers that can make system changes")
 		flags = append(flags, string(EntitlementKeySecurityInsecure))
 	}
+	if c.Hooks {
+		msgs = append(msgs, " - Running hook commands of targets on this machine")
+		flags = append(flags, string(EntitlementKeyHooks))
+	}
 
 	if c.SSH {
 		msgsFS = append(msgsFS, " - Forwarding default SSH agent socket")
@@ -232,6 +375,12 @@ func (c EntitlementConf) Prompt(ctx context.Context, isRemote bool, out io.Write
 	for _, m := range slices.Concat(msgs, msgsFS) {
 		fmt.Fprintf(out, "%s\n", m)
 	}
+	if len(reqs) > 0 {
+		fmt.Fprintf(out, "\nRequested by:\n\n")
+		for _, r := range reqs {
+			fmt.Fprintf(out, " - %s: %s (%s)\n", r.source(), r.relative(wd).Flag(), r.Reason)
+		}
+	}
 
 	for i, f := range flags {
 		flags[i] = "--allow=" + f
@@ -307,6 +456,59 @@ func (c EntitlementConf) Prompt(ctx context.Context, isRemote bool, out io.Write
 	return errors.Errorf("additional privileges requested")
 }
 
+// PrintRequests writes a table of the requested entitlements, followed by the
+// flags granting them.
+func PrintRequests(w io.Writer, reqs []EntitlementRequest) error {
+	if len(reqs) == 0 {
+		_, err := fmt.Fprintln(w, "No additional privileges requested")
+		return err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return errors.Wrap(err, "failed to get current working directory")
+	}
+	wd, err = filepath.EvalSymlinks(wd)
+	if err != nil {
+		return errors.Wrap(err, "failed to evaluate working directory")
+	}
+
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	fmt.Fprintln(tw, "TARGET\tENTITLEMENT\tREASON")
+	var flags []string
+	for _, r := range reqs {
+		flag := r.relative(wd).Flag()
+		target := r.Target
+		if target == "" {
+			target = "(definition)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", target, flag, r.Reason)
+		flags = append(flags, "--allow="+flag)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	slices.Sort(flags)
+	_, err = fmt.Fprintf(w, "\nPass %q to grant requested privileges.\n", strings.Join(slices.Compact(flags), " "))
+	return err
+}
+
+// source describes what requests the entitlement.
+func (r EntitlementRequest) source() string {
+	if r.Target == "" {
+		return "definition"
+	}
+	return fmt.Sprintf("target %q", r.Target)
+}
+
+// relative returns the request with its path relative to wd where possible.
+func (r EntitlementRequest) relative(wd string) EntitlementRequest {
+	if r.Path != "" {
+		r.Path = toRelativePaths([]string{r.Path}, wd)[0]
+	}
+	return r
+}
+
 func isParentOrEqualPath(p, parent string) bool {
 	if p == parent || parent == "/" {
 		return true
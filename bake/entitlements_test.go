@@ -1,6 +1,7 @@
 package bake
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -416,6 +417,70 @@ func TestValidateEntitlements(t *testing.T) {
 	}
 }
 
+func TestEntitlementRequests(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+	expDir1, err := filepath.EvalSymlinks(dir1)
+	require.NoError(t, err)
+
+	conf := EntitlementConf{
+		FSRead:      []string{dir2},
+		hookTargets: []string{"app"},
+	}
+	reqs, err := conf.Requests(map[string]build.Options{
+		"app": {
+			Inputs: build.Inputs{
+				ContextPath:    dir2,
+				DockerfilePath: filepath.Join(dir2, "Dockerfile"),
+				NamedContexts: map[string]build.NamedContext{
+					"shared": {Path: filepath.Join(dir1, "shared")},
+					"base":   {Path: "docker-image://alpine"},
+				},
+			},
+			Allow: []entitlements.Entitlement{entitlements.EntitlementNetworkHost},
+		},
+		"db": {
+			Inputs: build.Inputs{
+				ContextPath:    dir2,
+				DockerfilePath: filepath.Join(dir2, "Dockerfile"),
+			},
+			ExportsLocalPathsTemporary: []string{filepath.Join(dir1, "out")},
+			SecretSpecs:                []*pb.Secret{{ID: "token", FilePath: filepath.Join(dir1, "token")}},
+			SSHSpecs:                   []*pb.SSH{{ID: "default"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []EntitlementRequest{
+		{Target: "app", Key: EntitlementKeyFSRead, Path: expDir1, Reason: "contexts.shared"},
+		{Target: "app", Key: EntitlementKeyHooks, Reason: "hooks"},
+		{Target: "app", Key: EntitlementKeyNetworkHost, Reason: "entitlements"},
+		{Target: "db", Key: EntitlementKeyFSRead, Path: expDir1, Reason: "secret (id=token)"},
+		{Target: "db", Key: EntitlementKeyFSWrite, Path: expDir1, Reason: "output"},
+		{Target: "db", Key: EntitlementKeySSH, Reason: "ssh (id=default)"},
+	}, reqs)
+	require.Equal(t, "fs.read="+expDir1, reqs[0].Flag())
+	require.Equal(t, "hooks", reqs[1].Flag())
+}
+
+func TestPrintRequests(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, PrintRequests(&b, nil))
+	require.Equal(t, "No additional privileges requested\n", b.String())
+
+	b.Reset()
+	require.NoError(t, PrintRequests(&b, []EntitlementRequest{
+		{Key: EntitlementKeyFSRead, Path: "/tmp/values.json", Reason: "templatefile"},
+		{Target: "app", Key: EntitlementKeyNetworkHost, Reason: "entitlements"},
+		{Target: "app", Key: EntitlementKeyFSRead, Path: "/tmp/values.json", Reason: "contexts.shared"},
+	}))
+	require.Equal(t, "TARGET\t\tENTITLEMENT\t\t\tREASON\n"+
+		"(definition)\tfs.read=/tmp/values.json\ttemplatefile\n"+
+		"app\t\tnetwork.host\t\t\tentitlements\n"+
+		"app\t\tfs.read=/tmp/values.json\tcontexts.shared\n"+
+		"\n"+
+		`Pass "--allow=fs.read=/tmp/values.json --allow=network.host" to grant requested privileges.`+"\n", b.String())
+}
+
 func TestGroupSamePaths(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -484,3 +549,47 @@ func TestGroupSamePaths(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateEntitlementsMultipleTargets(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+
+	expDir1, err := filepath.EvalSymlinks(dir1)
+	require.NoError(t, err)
+	expDir2, err := filepath.EvalSymlinks(dir2)
+	require.NoError(t, err)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	conf := EntitlementConf{
+		FSRead: []string{wd},
+	}
+	expected, err := conf.Validate(map[string]build.Options{
+		"app": {
+			ExportsLocalPathsTemporary: []string{dir1},
+		},
+		"docs": {
+			ExportsLocalPathsTemporary: []string{dir2},
+		},
+		"test": {
+			ExportsLocalPathsTemporary: []string{dir1},
+		},
+	})
+	require.NoError(t, err)
+	exp := []string{expDir1, expDir2}
+	slices.Sort(exp)
+	require.Equal(t, EntitlementConf{FSWrite: exp}, expected)
+}
+
+func TestMergePaths(t *testing.T) {
+	require.Nil(t, mergePaths(nil, nil))
+	require.Equal(t, []string{"/a", "/b", "/c"}, mergePaths([]string{"/b"}, []string{"/c", "/a", "/b"}))
+
+	// the first list must not be modified through its spare capacity
+	a := make([]string, 1, 4)
+	a[0] = "/b"
+	out := mergePaths(a, []string{"/a"})
+	require.Equal(t, []string{"/a", "/b"}, out)
+	require.Equal(t, []string{"/b"}, a)
+}
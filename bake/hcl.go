@@ -1,12 +1,14 @@
 package bake
 
 import (
+	"os"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/moby/buildkit/solver/errdefs"
 	"github.com/moby/buildkit/solver/pb"
+	"github.com/pkg/errors"
 )
 
 func ParseHCLFile(dt []byte, fn string) (*hcl.File, bool, error) {
@@ -36,6 +38,22 @@ func ParseHCLFile(dt []byte, fn string) (*hcl.File, bool, error) {
 	return f, true, nil
 }
 
+// templateFileReader returns the function reading the templates rendered by
+// templatefile from the local filesystem. Files read are recorded in ent so
+// they are checked like the other paths requiring the fs.read entitlement.
+func templateFileReader(ent *EntitlementConf) func(string) ([]byte, error) {
+	return func(fn string) ([]byte, error) {
+		dt, err := os.ReadFile(fn)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if ent != nil {
+			ent.templateFiles = append(ent.templateFiles, fn)
+		}
+		return dt, nil
+	}
+}
+
 func formatHCLError(err error, files []File) error {
 	if err == nil {
 		return nil
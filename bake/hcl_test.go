@@ -1,9 +1,14 @@
 package bake
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
+	"github.com/docker/buildx/util/gitutil"
+	hcl "github.com/hashicorp/hcl/v2"
 	"github.com/stretchr/testify/require"
 )
 
@@ -517,11 +522,11 @@ func TestHCLTargetAttrs(t *testing.T) {
 
 	require.Equal(t, "xxx", *c.Targets[0].Dockerfile)
 	require.Equal(t, "yyy", *c.Targets[0].Context)
-	require.Equal(t, "xxx", *c.Targets[0].Target)
+	require.Equal(t, Stages{"xxx"}, c.Targets[0].Target)
 
 	require.Equal(t, "xxx", *c.Targets[1].Dockerfile)
 	require.Equal(t, "yyy", *c.Targets[1].Context)
-	require.Equal(t, "yyy", *c.Targets[1].Target)
+	require.Equal(t, Stages{"yyy"}, c.Targets[1].Target)
 }
 
 func TestHCLTargetGlobal(t *testing.T) {
@@ -960,7 +965,7 @@ func TestHCLRenameMultiFile(t *testing.T) {
 
 	require.Equal(t, "bar", c.Targets[0].Name)
 	require.Equal(t, "x", *c.Targets[0].Dockerfile)
-	require.Equal(t, "z", *c.Targets[0].Target)
+	require.Equal(t, Stages{"z"}, c.Targets[0].Target)
 
 	require.Equal(t, "foo", c.Targets[1].Name)
 	require.Equal(t, "y", *c.Targets[1].Context)
@@ -1390,6 +1395,100 @@ func TestHCLBuiltinVars(t *testing.T) {
 	require.Equal(t, "test", *c.Targets[0].Dockerfile)
 }
 
+func TestHCLLocals(t *testing.T) {
+	dt := []byte(`
+		variable "REPO" {
+			default = "user/app"
+		}
+		variable "VERSION" {
+			default = "1.0.0"
+		}
+		locals {
+			major = split(".", VERSION)[0]
+			tags = ["${REPO}:${VERSION}", "${REPO}:${local.major}"]
+		}
+		target "app" {
+			tags = local.tags
+			args = {
+				MAJOR = local.major
+			}
+		}
+		`)
+
+	c, err := ParseFile(dt, "docker-bake.hcl")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(c.Targets))
+	require.Equal(t, []string{"user/app:1.0.0", "user/app:1"}, c.Targets[0].Tags)
+	require.Equal(t, ptrstr("1"), c.Targets[0].Args["MAJOR"])
+
+	// local values are derived from variables but can't be set from the
+	// environment
+	t.Setenv("VERSION", "2.3.4")
+	t.Setenv("major", "9")
+	c, err = ParseFile(dt, "docker-bake.hcl")
+	require.NoError(t, err)
+	require.Equal(t, []string{"user/app:2.3.4", "user/app:2"}, c.Targets[0].Tags)
+}
+
+func TestHCLLocalsMultiFile(t *testing.T) {
+	c, _, err := ParseFiles([]File{
+		{
+			Data: []byte(`
+			locals {
+				base = "alpine"
+				suffix = "-dev"
+			}
+			target "app" {
+				args = {
+					BASE = "${local.base}${local.suffix}"
+				}
+			}`),
+			Name: "docker-bake.hcl",
+		},
+		{
+			Data: []byte(`
+			locals {
+				suffix = "-prod"
+			}`),
+			Name: "docker-bake.override.hcl",
+		},
+		{
+			Data: []byte(`{"locals": {"base": "debian"}}`),
+			Name: "docker-bake.override.json",
+		},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(c.Targets))
+	require.Equal(t, ptrstr("debian-prod"), c.Targets[0].Args["BASE"])
+}
+
+func TestHCLLocalsErrors(t *testing.T) {
+	_, err := ParseFile([]byte(`
+		locals {
+			a = local.b
+			b = local.a
+		}
+		target "app" {
+			args = {
+				A = local.a
+			}
+		}
+		`), "docker-bake.hcl")
+	require.ErrorContains(t, err, "local value cycle not allowed")
+
+	_, err = ParseFile([]byte(`
+		locals {
+			a = "foo"
+		}
+		target "app" {
+			args = {
+				A = local.b
+			}
+		}
+		`), "docker-bake.hcl")
+	require.ErrorContains(t, err, `local value "b" does not exist`)
+}
+
 func TestCombineHCLAndJSONTargets(t *testing.T) {
 	c, _, err := ParseFiles([]File{
 		{
@@ -1459,11 +1558,11 @@ target "b" {
 
 	require.Equal(t, "a", c.Targets[2].Name)
 	require.Equal(t, ".", *c.Targets[2].Context)
-	require.Equal(t, "a", *c.Targets[2].Target)
+	require.Equal(t, Stages{"a"}, c.Targets[2].Target)
 
 	require.Equal(t, "b", c.Targets[3].Name)
 	require.Equal(t, ".", *c.Targets[3].Context)
-	require.Equal(t, "b", *c.Targets[3].Target)
+	require.Equal(t, Stages{"b"}, c.Targets[3].Target)
 }
 
 func TestCombineHCLAndJSONVars(t *testing.T) {
@@ -1597,6 +1696,128 @@ func TestHCLIndexOfFunc(t *testing.T) {
 	require.Empty(t, c.Targets[1].Tags[1])
 }
 
+func TestHCLTemplateFileFunc(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	t.Cleanup(func() { _ = os.Chdir(pwd) })
+	require.NoError(t, os.Chdir(dir))
+
+	require.NoError(t, os.WriteFile("Dockerfile.tmpl", []byte("FROM alpine:${version}\nRUN echo ${upper(name)} %{ if debug }&& echo debug%{ endif }\n"), 0644))
+	dt := []byte(`
+		variable "VERSION" {
+			default = "3.20"
+		}
+		target "default" {
+			name = "app-${item}"
+			matrix = {
+				item = ["foo", "bar"]
+			}
+			dockerfile-inline = templatefile("Dockerfile.tmpl", {
+				version = VERSION
+				name    = item
+				debug   = item == "bar"
+			})
+		}
+		`)
+
+	c, _, err := ParseFiles([]File{{Name: "docker-bake.hcl", Data: dt}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(c.Targets))
+	require.Equal(t, "app-foo", c.Targets[0].Name)
+	require.Equal(t, "FROM alpine:3.20\nRUN echo FOO \n", *c.Targets[0].DockerfileInline)
+	require.Equal(t, "app-bar", c.Targets[1].Name)
+	require.Equal(t, "FROM alpine:3.20\nRUN echo BAR && echo debug\n", *c.Targets[1].DockerfileInline)
+
+	// only the given variables are visible to the template
+	require.NoError(t, os.WriteFile("Dockerfile.tmpl", []byte("FROM alpine:${VERSION}"), 0644))
+	_, _, err = ParseFiles([]File{{Name: "docker-bake.hcl", Data: dt}}, nil)
+	require.ErrorContains(t, err, `template Dockerfile.tmpl references undefined variable "VERSION"`)
+
+	require.NoError(t, os.WriteFile("Dockerfile.tmpl", []byte(`${templatefile("Dockerfile.tmpl", {})}`), 0644))
+	_, _, err = ParseFiles([]File{{Name: "docker-bake.hcl", Data: dt}}, nil)
+	require.ErrorContains(t, err, "cannot call templatefile from template Dockerfile.tmpl")
+}
+
+func TestHCLTemplateFileEntitlements(t *testing.T) {
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	t.Cleanup(func() { _ = os.Chdir(pwd) })
+	require.NoError(t, os.Chdir(dir))
+
+	require.NoError(t, os.WriteFile("Dockerfile.tmpl", []byte("FROM ${image}"), 0644))
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+		target "default" {
+			dockerfile-inline = templatefile("Dockerfile.tmpl", { image = "alpine" })
+		}
+		`),
+	}
+
+	var ent EntitlementConf
+	m, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"default"}, nil, nil, &ent)
+	require.NoError(t, err)
+	require.Equal(t, "FROM alpine", *m["default"].DockerfileInline)
+
+	exp, err := ent.Validate(nil)
+	require.NoError(t, err)
+	wd, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(wd, "Dockerfile.tmpl")}, exp.FSRead)
+
+	ent = EntitlementConf{FSRead: []string{dir}}
+	_, _, err = ReadTargets(context.TODO(), []File{fp}, []string{"default"}, nil, nil, &ent)
+	require.NoError(t, err)
+	exp, err = ent.Validate(nil)
+	require.NoError(t, err)
+	require.Empty(t, exp.FSRead)
+}
+
+func TestHCLGitBranchFunc(t *testing.T) {
+	t.Setenv("GITHUB_HEAD_REF", "")
+	t.Setenv("GITHUB_REF", "")
+	t.Setenv("CI_COMMIT_BRANCH", "")
+	t.Setenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "")
+
+	gitutil.Mktmp(t)
+	c, err := gitutil.New()
+	require.NoError(t, err)
+	gitutil.GitInit(c, t)
+	gitutil.GitCommit(c, t, "initial")
+
+	dt := []byte(`
+		target "default" {
+			cache-from = ["type=registry,ref=user/app:cache"]
+			cache-to = git_branch() == "main" ? ["type=registry,ref=user/app:cache,mode=max"] : []
+		}
+		`)
+
+	cfg, err := ParseFile(dt, "docker-bake.hcl")
+	require.NoError(t, err)
+	require.Len(t, cfg.Targets, 1)
+	require.Equal(t, []string{"type=registry,mode=max,ref=user/app:cache"}, stringify(cfg.Targets[0].CacheTo))
+
+	gitutil.GitCheckoutBranch(c, t, "feature")
+	cfg, err = ParseFile(dt, "docker-bake.hcl")
+	require.NoError(t, err)
+	require.Empty(t, cfg.Targets[0].CacheTo)
+
+	// detached HEAD in a pull request
+	t.Setenv("GITHUB_HEAD_REF", "fix")
+	gitutil.GitCheckoutDetach(c, t)
+	cfg, err = ParseFile([]byte(`
+		target "default" {
+			args = {
+				BRANCH = git_branch()
+			}
+		}
+		`), "docker-bake.hcl")
+	require.NoError(t, err)
+	require.Equal(t, ptrstr("fix"), cfg.Targets[0].Args["BRANCH"])
+}
+
 func ptrstr(s interface{}) *string {
 	var n *string
 	if reflect.ValueOf(s).Kind() == reflect.String {
@@ -1605,3 +1826,135 @@ func ptrstr(s interface{}) *string {
 	}
 	return n
 }
+
+func TestHCLTargetValidation(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+		variable "REGISTRY" {
+			default = "docker.io/org"
+		}
+		target "_common" {
+			validation {
+				condition = length(target.tags) > 0
+				error_message = "tags required for ${target.name}"
+			}
+			validation {
+				condition = length([for t in target.tags : t if trimprefix(t, "${REGISTRY}/") == t]) == 0
+				error_message = "tags must be pushed to ${REGISTRY}"
+			}
+		}
+		target "app" {
+			inherits = ["_common"]
+			tags = ["docker.io/org/app"]
+		}
+		target "db" {
+			inherits = ["_common"]
+		}
+		target "web" {
+			inherits = ["_common"]
+			tags = ["ghcr.io/org/web"]
+		}
+		`),
+	}
+
+	m, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app"}, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"docker.io/org/app"}, m["app"].Tags)
+
+	_, _, err = ReadTargets(context.TODO(), []File{fp}, []string{"db", "web"}, nil, nil, nil)
+	require.Error(t, err)
+	var diags hcl.Diagnostics
+	require.ErrorAs(t, err, &diags)
+	require.Len(t, diags, 2)
+	require.Equal(t, `target "db": tags required for db`, diags[0].Detail)
+	require.Equal(t, `target "web": tags must be pushed to docker.io/org`, diags[1].Detail)
+	require.Equal(t, 7, diags[0].Subject.Start.Line)
+
+	// validations are evaluated after overrides
+	m, _, err = ReadTargets(context.TODO(), []File{fp}, []string{"db"}, []string{"db.tags=docker.io/org/db"}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"docker.io/org/db"}, m["db"].Tags)
+}
+
+func TestHCLDynamicBlocks(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+		variable "REGISTRIES" {
+			default = {
+				hub = "docker.io/org/app"
+				ghcr = "ghcr.io/org/app"
+			}
+		}
+		variable "SECRETS" {
+			default = ["GH_TOKEN", "NPM_TOKEN"]
+		}
+		variable "REQUIRED" {
+			default = "hub,ghcr"
+		}
+		target "app" {
+			output = ["type=docker"]
+			dynamic "output" {
+				for_each = REGISTRIES
+				content {
+					type = "image"
+					name = output.value
+					push = true
+				}
+			}
+			dynamic "secret" {
+				for_each = SECRETS
+				iterator = s
+				content {
+					id = lower(s.value)
+					env = s.value
+				}
+			}
+			dynamic "validation" {
+				for_each = split(",", REQUIRED)
+				content {
+					condition = contains(keys(REGISTRIES), validation.value)
+					error_message = "missing ${validation.value} registry"
+				}
+			}
+		}
+		`),
+	}
+
+	m, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app"}, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, m["app"].Outputs, 3)
+	require.Equal(t, "type=docker", m["app"].Outputs[0].String())
+	require.Equal(t, "type=image,name=ghcr.io/org/app,push=true", m["app"].Outputs[1].String())
+	require.Equal(t, "type=image,name=docker.io/org/app,push=true", m["app"].Outputs[2].String())
+	require.Len(t, m["app"].Secrets, 2)
+	require.Equal(t, "id=gh_token,env=GH_TOKEN", m["app"].Secrets[0].String())
+	require.Equal(t, "id=npm_token,env=NPM_TOKEN", m["app"].Secrets[1].String())
+
+	_, _, err = ReadTargets(context.TODO(), []File{fp}, []string{"app"}, nil, map[string]string{"REQUIRED": "hub,quay"}, nil)
+	require.ErrorContains(t, err, "missing quay registry")
+}
+
+func TestHCLDynamicBlocksInvalid(t *testing.T) {
+	_, err := ParseFile([]byte(`
+		target "app" {
+			dynamic "output" {
+				for_each = "a"
+				content {
+					type = output.value
+				}
+			}
+		}
+		`), "docker-bake.hcl")
+	require.ErrorContains(t, err, "Invalid dynamic for_each value")
+
+	_, err = ParseFile([]byte(`
+		target "app" {
+			dynamic "output" {
+				for_each = ["a"]
+			}
+		}
+		`), "docker-bake.hcl")
+	require.ErrorContains(t, err, "requires exactly one content block")
+}
@@ -0,0 +1,440 @@
+package hclparser
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// dynamicBlockType is the type of the blocks generating, for each element of
+// a collection, an entry of a list attribute or a nested block named by their
+// label.
+const dynamicBlockType = "dynamic"
+
+var dynamicBlockSchema = hcl.BlockHeaderSchema{
+	Type:       dynamicBlockType,
+	LabelNames: []string{"name"},
+}
+
+var dynamicSpecSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "for_each", Required: true},
+		{Name: "iterator"},
+		{Name: "labels"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "content"},
+	},
+}
+
+// dynamicBody expands the dynamic blocks of a body. The entries generated
+// for a list attribute are appended to the ones of the attribute, if any.
+type dynamicBody struct {
+	body hcl.Body
+	ectx *hcl.EvalContext
+}
+
+func expandDynamicBody(body hcl.Body, ectx *hcl.EvalContext) hcl.Body {
+	return &dynamicBody{body: body, ectx: ectx}
+}
+
+func (b *dynamicBody) Content(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Diagnostics) {
+	content, diags := b.body.Content(extendDynamicSchema(schema))
+	if diags.HasErrors() {
+		return content, diags
+	}
+	content, expandDiags := b.expand(schema, content, false)
+	return content, append(diags, expandDiags...)
+}
+
+func (b *dynamicBody) PartialContent(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Body, hcl.Diagnostics) {
+	content, remain, diags := b.body.PartialContent(extendDynamicSchema(schema))
+	if diags.HasErrors() {
+		return content, remain, diags
+	}
+	content, expandDiags := b.expand(schema, content, true)
+	return content, remain, append(diags, expandDiags...)
+}
+
+func (b *dynamicBody) JustAttributes() (hcl.Attributes, hcl.Diagnostics) {
+	return b.body.JustAttributes()
+}
+
+func (b *dynamicBody) MissingItemRange() hcl.Range {
+	return b.body.MissingItemRange()
+}
+
+func extendDynamicSchema(schema *hcl.BodySchema) *hcl.BodySchema {
+	ext := &hcl.BodySchema{
+		Attributes: schema.Attributes,
+		Blocks:     append(append([]hcl.BlockHeaderSchema{}, schema.Blocks...), dynamicBlockSchema),
+	}
+	return ext
+}
+
+func (b *dynamicBody) expand(schema *hcl.BodySchema, raw *hcl.BodyContent, partial bool) (*hcl.BodyContent, hcl.Diagnostics) {
+	content := &hcl.BodyContent{
+		Attributes:       hcl.Attributes{},
+		MissingItemRange: raw.MissingItemRange,
+	}
+	for name, attr := range raw.Attributes {
+		content.Attributes[name] = attr
+	}
+
+	var diags hcl.Diagnostics
+	entries := map[string][]cty.Value{}
+	ranges := map[string]hcl.Range{}
+	for _, block := range raw.Blocks {
+		if block.Type != dynamicBlockType {
+			content.Blocks = append(content.Blocks, block)
+			continue
+		}
+		name := block.Labels[0]
+		isAttr, isBlock := schemaHas(schema, name)
+		if !isAttr && !isBlock {
+			if !partial {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unsupported dynamic block",
+					Detail:   fmt.Sprintf("There is no list attribute or block named %q here.", name),
+					Subject:  &block.LabelRanges[0],
+				})
+			}
+			continue
+		}
+
+		spec, specDiags := decodeDynamicSpec(block, b.ectx)
+		diags = append(diags, specDiags...)
+		if specDiags.HasErrors() {
+			continue
+		}
+		if _, ok := ranges[name]; !ok {
+			ranges[name] = block.DefRange
+		}
+		for it := spec.forEach.ElementIterator(); it.Next(); {
+			key, value := it.Element()
+			ectx := b.ectx.NewChild()
+			ectx.Variables = map[string]cty.Value{
+				spec.iterator: cty.ObjectVal(map[string]cty.Value{
+					"key":   key,
+					"value": value,
+				}),
+			}
+			if isAttr {
+				entry, entryDiags := dynamicEntry(spec.content, ectx)
+				diags = append(diags, entryDiags...)
+				if !entryDiags.HasErrors() {
+					entries[name] = append(entries[name], entry)
+				}
+				continue
+			}
+			nb, blockDiags := dynamicBlock(name, spec, ectx)
+			diags = append(diags, blockDiags...)
+			if !blockDiags.HasErrors() {
+				content.Blocks = append(content.Blocks, nb)
+			}
+		}
+		// the attribute is set even if no entry was generated
+		if _, ok := entries[name]; !ok && isAttr {
+			entries[name] = nil
+		}
+	}
+
+	for name, values := range entries {
+		// the entries of the attribute come first
+		if attr, ok := raw.Attributes[name]; ok {
+			v, valueDiags := attr.Expr.Value(b.ectx)
+			diags = append(diags, valueDiags...)
+			if valueDiags.HasErrors() {
+				continue
+			}
+			if !v.IsNull() {
+				if !v.CanIterateElements() {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Unsupported dynamic block",
+						Detail:   fmt.Sprintf("Attribute %q must be a list to be extended by a dynamic block.", name),
+						Subject:  attr.Expr.Range().Ptr(),
+					})
+					continue
+				}
+				values = append(v.AsValueSlice(), values...)
+			}
+		} else if len(values) == 0 {
+			continue
+		}
+		rng := ranges[name]
+		content.Attributes[name] = &hcl.Attribute{
+			Name:      name,
+			Expr:      hcl.StaticExpr(cty.TupleVal(values), rng),
+			Range:     rng,
+			NameRange: rng,
+		}
+	}
+	return content, diags
+}
+
+// schemaHas returns whether name is an attribute or a block of the schema.
+func schemaHas(schema *hcl.BodySchema, name string) (isAttr bool, isBlock bool) {
+	for _, a := range schema.Attributes {
+		if a.Name == name {
+			return true, false
+		}
+	}
+	for _, b := range schema.Blocks {
+		if b.Type == name {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+type dynamicSpec struct {
+	forEach  cty.Value
+	iterator string
+	labels   hcl.Expression
+	content  *hcl.Block
+}
+
+func decodeDynamicSpec(block *hcl.Block, ectx *hcl.EvalContext) (*dynamicSpec, hcl.Diagnostics) {
+	content, diags := block.Body.Content(dynamicSpecSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	attr := content.Attributes["for_each"]
+	forEach, diags := attr.Expr.Value(ectx)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	if !forEach.IsKnown() || forEach.IsNull() || !forEach.CanIterateElements() {
+		return nil, hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid dynamic for_each value",
+			Detail:   fmt.Sprintf("Cannot use a %s value in for_each. A list, set, map or object is required.", forEach.Type().FriendlyName()),
+			Subject:  attr.Expr.Range().Ptr(),
+		}}
+	}
+
+	spec := &dynamicSpec{
+		forEach:  forEach,
+		iterator: block.Labels[0],
+	}
+	if attr, ok := content.Attributes["iterator"]; ok {
+		name := hcl.ExprAsKeyword(attr.Expr)
+		if name == "" {
+			return nil, hcl.Diagnostics{&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid dynamic iterator name",
+				Detail:   "Dynamic iterator must be a single variable name.",
+				Subject:  attr.Expr.Range().Ptr(),
+			}}
+		}
+		spec.iterator = name
+	}
+	if attr, ok := content.Attributes["labels"]; ok {
+		spec.labels = attr.Expr
+	}
+	if len(content.Blocks) != 1 {
+		return nil, hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid dynamic block",
+			Detail:   "A dynamic block requires exactly one content block.",
+			Subject:  block.DefRange.Ptr(),
+		}}
+	}
+	spec.content = content.Blocks[0]
+	return spec, nil
+}
+
+// dynamicEntry evaluates the attributes of a content block into an object.
+func dynamicEntry(content *hcl.Block, ectx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	attrs, diags := content.Body.JustAttributes()
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	m := make(map[string]cty.Value, len(attrs))
+	for name, attr := range attrs {
+		v, valueDiags := attr.Expr.Value(ectx)
+		diags = append(diags, valueDiags...)
+		if valueDiags.HasErrors() {
+			continue
+		}
+		m[name] = v
+	}
+	return cty.ObjectVal(m), diags
+}
+
+// dynamicBlock returns the nested block generated by a content block, whose
+// expressions are evaluated with the iterator.
+func dynamicBlock(name string, spec *dynamicSpec, ectx *hcl.EvalContext) (*hcl.Block, hcl.Diagnostics) {
+	var labels []string
+	if spec.labels != nil {
+		v, diags := spec.labels.Value(ectx)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		if err := convertLabels(v, &labels); err != nil {
+			return nil, hcl.Diagnostics{&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid dynamic block labels",
+				Detail:   err.Error(),
+				Subject:  spec.labels.Range().Ptr(),
+			}}
+		}
+	}
+	return &hcl.Block{
+		Type:      name,
+		Labels:    labels,
+		Body:      &iteratorBody{body: spec.content.Body, vars: ectx.Variables},
+		DefRange:  spec.content.DefRange,
+		TypeRange: spec.content.TypeRange,
+	}, nil
+}
+
+func convertLabels(v cty.Value, labels *[]string) error {
+	v, err := convert.Convert(v, cty.List(cty.String))
+	if err != nil {
+		return err
+	}
+	for _, l := range v.AsValueSlice() {
+		if l.IsNull() {
+			return fmt.Errorf("labels cannot be null")
+		}
+		*labels = append(*labels, l.AsString())
+	}
+	return nil
+}
+
+// iteratorBody is the body of a generated block. Its expressions are
+// evaluated with the iterator variable of the dynamic block.
+type iteratorBody struct {
+	body hcl.Body
+	vars map[string]cty.Value
+}
+
+func (b *iteratorBody) Content(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Diagnostics) {
+	content, diags := b.body.Content(schema)
+	return b.wrapContent(content), diags
+}
+
+func (b *iteratorBody) PartialContent(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Body, hcl.Diagnostics) {
+	content, remain, diags := b.body.PartialContent(schema)
+	return b.wrapContent(content), &iteratorBody{body: remain, vars: b.vars}, diags
+}
+
+func (b *iteratorBody) JustAttributes() (hcl.Attributes, hcl.Diagnostics) {
+	attrs, diags := b.body.JustAttributes()
+	return b.wrapAttributes(attrs), diags
+}
+
+func (b *iteratorBody) MissingItemRange() hcl.Range {
+	return b.body.MissingItemRange()
+}
+
+func (b *iteratorBody) wrapContent(content *hcl.BodyContent) *hcl.BodyContent {
+	if content == nil {
+		return nil
+	}
+	out := *content
+	out.Attributes = b.wrapAttributes(content.Attributes)
+	out.Blocks = make(hcl.Blocks, len(content.Blocks))
+	for i, block := range content.Blocks {
+		nb := *block
+		nb.Body = &iteratorBody{body: block.Body, vars: b.vars}
+		out.Blocks[i] = &nb
+	}
+	return &out
+}
+
+func (b *iteratorBody) wrapAttributes(attrs hcl.Attributes) hcl.Attributes {
+	if attrs == nil {
+		return nil
+	}
+	out := make(hcl.Attributes, len(attrs))
+	for name, attr := range attrs {
+		na := *attr
+		na.Expr = &iteratorExpr{Expression: attr.Expr, vars: b.vars}
+		out[name] = &na
+	}
+	return out
+}
+
+// iteratorExpr evaluates an expression with the iterator variable of a
+// dynamic block.
+type iteratorExpr struct {
+	hcl.Expression
+	vars map[string]cty.Value
+}
+
+func (e *iteratorExpr) Value(ectx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	child := &hcl.EvalContext{}
+	if ectx != nil {
+		child = ectx.NewChild()
+	}
+	child.Variables = e.vars
+	return e.Expression.Value(child)
+}
+
+func (e *iteratorExpr) Variables() []hcl.Traversal {
+	var vars []hcl.Traversal
+	for _, v := range e.Expression.Variables() {
+		if _, ok := e.vars[v.RootName()]; !ok {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// loadDynamicDeps loads the dependencies of the expressions of the dynamic
+// blocks of a body, excluding their iterator variable.
+func (p *parser) loadDynamicDeps(ectx *hcl.EvalContext, body hcl.Body) hcl.Diagnostics {
+	sb, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+	for _, b := range sb.Blocks {
+		if b.Type != dynamicBlockType || len(b.Labels) != 1 {
+			continue
+		}
+		exclude := map[string]struct{}{b.Labels[0]: {}}
+		if attr, ok := b.Body.Attributes["iterator"]; ok {
+			if name := hcl.ExprAsKeyword(attr.Expr); name != "" {
+				exclude = map[string]struct{}{name: {}}
+			}
+		}
+		for name, attr := range b.Body.Attributes {
+			if name == "iterator" {
+				continue
+			}
+			if diags := p.loadDeps(ectx, attr.Expr, exclude, true); diags.HasErrors() {
+				return diags
+			}
+		}
+		for _, cb := range b.Body.Blocks {
+			if diags := p.loadBodyDeps(ectx, cb.Body, exclude); diags.HasErrors() {
+				return diags
+			}
+		}
+	}
+	return nil
+}
+
+// loadBodyDeps loads the dependencies of all the expressions of a body and
+// its nested blocks.
+func (p *parser) loadBodyDeps(ectx *hcl.EvalContext, body *hclsyntax.Body, exclude map[string]struct{}) hcl.Diagnostics {
+	for _, attr := range body.Attributes {
+		if diags := p.loadDeps(ectx, attr.Expr, exclude, true); diags.HasErrors() {
+			return diags
+		}
+	}
+	for _, b := range body.Blocks {
+		if diags := p.loadBodyDeps(ectx, b.Body, exclude); diags.HasErrors() {
+			return diags
+		}
+	}
+	return nil
+}
@@ -292,6 +292,15 @@ func (o DecodeOptions) DecodeExpression(expr hcl.Expression, ctx *hcl.EvalContex
 		panic(fmt.Sprintf("unsuitable DecodeExpression target: %s", err))
 	}
 
+	if srcVal.IsNull() && convTy.IsCapsuleType() {
+		// custom types decode null as their zero value, like pointers do
+		rv := reflect.ValueOf(val)
+		if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+			rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+			return diags
+		}
+	}
+
 	srcVal, err = o.Convert(srcVal, convTy)
 	if err != nil {
 		diags = append(diags, &hcl.Diagnostic{
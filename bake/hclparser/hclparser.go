@@ -7,6 +7,7 @@ import (
 	"math"
 	"math/big"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -21,6 +22,9 @@ type Opt struct {
 	LookupVar     func(string) (string, bool)
 	Vars          map[string]string
 	ValidateLabel func(string) error
+	// ReadFile reads the files rendered by the templatefile function. File
+	// access is not allowed if nil.
+	ReadFile func(string) ([]byte, error)
 }
 
 type variable struct {
@@ -44,9 +48,16 @@ type functionDef struct {
 	Result   *hcl.Attribute `json:"result,omitempty" hcl:"result"`
 }
 
+// localsBlock declares local values, which are derived from other values and
+// can't be set from the environment.
+type localsBlock struct {
+	Body hcl.Body `json:"-" hcl:",remain"`
+}
+
 type inputs struct {
 	Variables []*variable    `hcl:"variable,block"`
 	Functions []*functionDef `hcl:"function,block"`
+	Locals    []*localsBlock `hcl:"locals,block"`
 
 	Remain hcl.Body `json:"-" hcl:",remain"`
 }
@@ -54,9 +65,14 @@ type inputs struct {
 type parser struct {
 	opt Opt
 
-	vars  map[string]*variable
-	attrs map[string]*hcl.Attribute
-	funcs map[string]*functionDef
+	vars   map[string]*variable
+	attrs  map[string]*hcl.Attribute
+	funcs  map[string]*functionDef
+	locals map[string]*hcl.Attribute
+
+	// localValues holds the evaluated local values, exposed to expressions
+	// as the attributes of the local object
+	localValues map[string]cty.Value
 
 	blocks       map[string]map[string][]*hcl.Block
 	blockValues  map[*hcl.Block][]reflect.Value
@@ -66,8 +82,15 @@ type parser struct {
 
 	ectx *hcl.EvalContext
 
+	// referenced records the names of variables referenced by expressions
+	referenced map[string]struct{}
+	// missing records the names of variables without default that are not
+	// set in the environment
+	missing map[string]struct{}
+
 	progressV map[uint64]struct{}
 	progressF map[uint64]struct{}
+	progressL map[string]struct{}
 	progressB map[uint64]map[string]struct{}
 	doneB     map[uint64]map[string]struct{}
 }
@@ -80,6 +103,10 @@ type WithGetName interface {
 	GetName(ectx *hcl.EvalContext, block *hcl.Block, loadDeps func(hcl.Expression) hcl.Diagnostics) (string, error)
 }
 
+// localsRootName is the name of the object holding the local values in
+// expressions.
+const localsRootName = "local"
+
 // errUndefined is returned when a variable or function is not defined.
 type errUndefined struct{}
 
@@ -106,7 +133,39 @@ func (p *parser) loadDeps(ectx *hcl.EvalContext, exp hcl.Expression, exclude map
 		if _, ok := exclude[v.RootName()]; ok {
 			continue
 		}
-		if _, ok := p.blockTypes[v.RootName()]; ok {
+		p.referenced[v.RootName()] = struct{}{}
+		if v.RootName() == localsRootName && len(p.locals) > 0 {
+			split := v.SimpleSplit().Rel
+			if len(split) == 0 {
+				return hcl.Diagnostics{
+					&hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid expression",
+						Detail:   "cannot access local as a variable",
+						Subject:  exp.Range().Ptr(),
+						Context:  exp.Range().Ptr(),
+					},
+				}
+			}
+			name, ok := split[0].(hcl.TraverseAttr)
+			if !ok {
+				return hcl.Diagnostics{
+					&hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid expression",
+						Detail:   "cannot traverse local without attribute",
+						Subject:  exp.Range().Ptr(),
+						Context:  exp.Range().Ptr(),
+					},
+				}
+			}
+			if err := p.resolveLocal(name.Name); err != nil {
+				if allowMissing && errors.Is(err, errUndefined{}) {
+					continue
+				}
+				return wrapErrorDiagnostic("Invalid expression", err, exp.Range().Ptr(), exp.Range().Ptr())
+			}
+		} else if _, ok := p.blockTypes[v.RootName()]; ok {
 			blockType := v.RootName()
 
 			split := v.SimpleSplit().Rel
@@ -278,7 +337,10 @@ func (p *parser) resolveValue(ectx *hcl.EvalContext, name string) (err error) {
 	if def == nil {
 		val, ok := p.opt.Vars[name]
 		if !ok {
-			val, _ = p.opt.LookupVar(name)
+			val, ok = p.opt.LookupVar(name)
+			if !ok {
+				p.missing[name] = struct{}{}
+			}
 		}
 		vv := cty.StringVal(val)
 		v = &vv
@@ -323,20 +385,49 @@ func (p *parser) resolveValue(ectx *hcl.EvalContext, name string) (err error) {
 	return nil
 }
 
+// resolveLocal forces evaluation of a local value, storing the result into the
+// local object of the parser.
+func (p *parser) resolveLocal(name string) error {
+	if _, ok := p.localValues[name]; ok {
+		return nil
+	}
+	def, ok := p.locals[name]
+	if !ok {
+		return errors.Errorf("local value %q does not exist", name)
+	}
+	if _, ok := p.progressL[name]; ok {
+		return errors.Errorf("local value cycle not allowed for %s", name)
+	}
+	p.progressL[name] = struct{}{}
+
+	if diags := p.loadDeps(p.ectx, def.Expr, nil, true); diags.HasErrors() {
+		return diags
+	}
+	v, diags := def.Expr.Value(p.ectx)
+	if diags.HasErrors() {
+		return diags
+	}
+	p.localValues[name] = v
+	p.ectx.Variables[localsRootName] = cty.ObjectVal(p.localValues)
+	return nil
+}
+
 // resolveBlock force evaluates a block, storing the result in the parser. If a
 // target schema is provided, only the attributes and blocks present in the
 // schema will be evaluated.
 func (p *parser) resolveBlock(block *hcl.Block, target *hcl.BodySchema) (err error) {
+	// nested blocks, such as validation, are decoded with their parent
+	t, ok := p.blockTypes[block.Type]
+	if !ok {
+		return nil
+	}
+
 	// prepare the variable map for this type
 	if _, ok := p.ectx.Variables[block.Type]; !ok {
 		p.ectx.Variables[block.Type] = cty.MapValEmpty(cty.Map(cty.String))
 	}
 
 	// prepare the output destination and evaluation context
-	t, ok := p.blockTypes[block.Type]
-	if !ok {
-		return nil
-	}
 	var outputs []reflect.Value
 	var ectxs []*hcl.EvalContext
 	if prev, ok := p.blockValues[block]; ok {
@@ -419,10 +510,15 @@ func (p *parser) resolveBlock(block *hcl.Block, target *hcl.BodySchema) (err err
 			}
 		}
 
-		// create a filtered body that contains only the target properties
-		body := func() hcl.Body {
+		// create a filtered body that contains only the target properties,
+		// with the dynamic blocks expanded when decoding
+		body := func(expand bool) hcl.Body {
+			b := block.Body
+			if expand {
+				b = expandDynamicBody(b, ectx)
+			}
 			if target != nil {
-				return FilterIncludeBody(block.Body, target)
+				return FilterIncludeBody(b, target)
 			}
 
 			filter := &hcl.BodySchema{}
@@ -430,12 +526,12 @@ func (p *parser) resolveBlock(block *hcl.Block, target *hcl.BodySchema) (err err
 				filter.Attributes = append(filter.Attributes, hcl.AttributeSchema{Name: k})
 				filter.Blocks = append(filter.Blocks, hcl.BlockHeaderSchema{Type: k})
 			}
-			return FilterExcludeBody(block.Body, filter)
+			return FilterExcludeBody(b, filter)
 		}
 
 		// load dependencies from all targeted properties
 		schema, _ := gohcl.ImpliedBodySchema(reflect.New(t).Interface())
-		content, _, diag := body().PartialContent(schema)
+		content, _, diag := body(false).PartialContent(schema)
 		if diag.HasErrors() {
 			return diag
 		}
@@ -445,6 +541,9 @@ func (p *parser) resolveBlock(block *hcl.Block, target *hcl.BodySchema) (err err
 				return diag
 			}
 		}
+		if diag := p.loadDynamicDeps(ectx, block.Body); diag.HasErrors() {
+			return diag
+		}
 		for _, b := range content.Blocks {
 			err := p.resolveBlock(b, nil)
 			if err != nil {
@@ -453,10 +552,11 @@ func (p *parser) resolveBlock(block *hcl.Block, target *hcl.BodySchema) (err err
 		}
 
 		// decode!
-		diag = decodeBody(body(), ectx, output.Interface())
+		diag = decodeBody(body(true), ectx, output.Interface())
 		if diag.HasErrors() {
 			return diag
 		}
+		setValidationContexts(output, ectx)
 
 		// mark all targeted properties as done
 		for _, a := range content.Attributes {
@@ -587,6 +687,11 @@ type Variable struct {
 type ParseMeta struct {
 	Renamed      map[string]map[string][]string
 	AllVariables []*Variable
+	// UnusedVariables are the declared variables that are never referenced.
+	UnusedVariables []string
+	// MissingVariables are the referenced variables that have no default
+	// and are not set in the environment, and evaluate to an empty string.
+	MissingVariables []string
 }
 
 func Parse(b hcl.Body, opt Opt, val interface{}) (*ParseMeta, hcl.Diagnostics) {
@@ -621,9 +726,12 @@ func Parse(b hcl.Body, opt Opt, val interface{}) (*ParseMeta, hcl.Diagnostics) {
 	p := &parser{
 		opt: opt,
 
-		vars:  map[string]*variable{},
-		attrs: map[string]*hcl.Attribute{},
-		funcs: map[string]*functionDef{},
+		vars:   map[string]*variable{},
+		attrs:  map[string]*hcl.Attribute{},
+		funcs:  map[string]*functionDef{},
+		locals: map[string]*hcl.Attribute{},
+
+		localValues: map[string]cty.Value{},
 
 		blocks:       map[string]map[string][]*hcl.Block{},
 		blockValues:  map[*hcl.Block][]reflect.Value{},
@@ -635,11 +743,16 @@ func Parse(b hcl.Body, opt Opt, val interface{}) (*ParseMeta, hcl.Diagnostics) {
 			Functions: Stdlib(),
 		},
 
+		referenced: map[string]struct{}{},
+		missing:    map[string]struct{}{},
+
 		progressV: map[uint64]struct{}{},
 		progressF: map[uint64]struct{}{},
+		progressL: map[string]struct{}{},
 		progressB: map[uint64]map[string]struct{}{},
 		doneB:     map[uint64]map[string]struct{}{},
 	}
+	p.ectx.Functions[templatefileFuncName] = p.templatefileFunc()
 
 	for _, v := range defs.Variables {
 		// TODO: validate name
@@ -656,6 +769,18 @@ func Parse(b hcl.Body, opt Opt, val interface{}) (*ParseMeta, hcl.Diagnostics) {
 		p.funcs[v.Name] = v
 	}
 
+	for _, l := range defs.Locals {
+		attrs, diags := l.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		// like variables, a local value of a later file overrides the
+		// previous definition
+		for _, a := range attrs {
+			p.locals[a.Name] = a
+		}
+	}
+
 	content, b, diags := b.PartialContent(schema)
 	if diags.HasErrors() {
 		return nil, diags
@@ -869,9 +994,36 @@ func Parse(b hcl.Body, opt Opt, val interface{}) (*ParseMeta, hcl.Diagnostics) {
 		}
 	}
 
+	for k := range p.locals {
+		if err := p.resolveLocal(k); err != nil {
+			if diags, ok := err.(hcl.Diagnostics); ok {
+				return nil, diags
+			}
+			return nil, wrapErrorDiagnostic("Invalid local value", err, &p.locals[k].Range, &p.locals[k].Range)
+		}
+	}
+
+	var unused []string
+	for k := range p.vars {
+		if _, ok := p.referenced[k]; !ok {
+			unused = append(unused, k)
+		}
+	}
+	sort.Strings(unused)
+
+	var missing []string
+	for k := range p.missing {
+		if _, ok := p.referenced[k]; ok {
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+
 	return &ParseMeta{
-		Renamed:      renamed,
-		AllVariables: vars,
+		Renamed:          renamed,
+		AllVariables:     vars,
+		UnusedVariables:  unused,
+		MissingVariables: missing,
 	}, nil
 }
 
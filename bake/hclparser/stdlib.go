@@ -2,10 +2,13 @@ package hclparser
 
 import (
 	"errors"
+	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/docker/buildx/util/gitutil"
 	"github.com/hashicorp/go-cty-funcs/cidr"
 	"github.com/hashicorp/go-cty-funcs/crypto"
 	"github.com/hashicorp/go-cty-funcs/encoding"
@@ -58,6 +61,7 @@ var stdlibFunctions = []funcDef{
 	{name: "format", fn: stdlib.FormatFunc},
 	{name: "formatdate", fn: stdlib.FormatDateFunc},
 	{name: "formatlist", fn: stdlib.FormatListFunc},
+	{name: "git_branch", factory: gitBranchFunc},
 	{name: "greaterthan", fn: stdlib.GreaterThanFunc},
 	{name: "greaterthanorequalto", fn: stdlib.GreaterThanOrEqualToFunc},
 	{name: "hasindex", fn: stdlib.HasIndexFunc},
@@ -235,6 +239,48 @@ func sanitizeFunc() function.Function {
 	})
 }
 
+// gitBranchFunc constructs a function that returns the branch checked out in
+// the working directory. When HEAD is detached, like for pull requests in CI,
+// the branch is read from the environment of the CI provider. It returns an
+// empty string if the branch is unknown.
+func gitBranchFunc() function.Function {
+	var once sync.Once
+	var branch string
+	return function.New(&function.Spec{
+		Params: []function.Parameter{},
+		Type:   function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			once.Do(func() {
+				branch = gitBranch()
+			})
+			return cty.StringVal(branch), nil
+		},
+	})
+}
+
+func gitBranch() string {
+	if gitc, err := gitutil.New(); err == nil {
+		if b, err := gitc.Branch(); err == nil && b != "" {
+			return b
+		}
+	}
+	// GitHub Actions pull requests
+	if v := os.Getenv("GITHUB_HEAD_REF"); v != "" {
+		return v
+	}
+	if v, ok := strings.CutPrefix(os.Getenv("GITHUB_REF"), "refs/heads/"); ok {
+		return v
+	}
+	// GitLab CI
+	if v := os.Getenv("CI_COMMIT_BRANCH"); v != "" {
+		return v
+	}
+	if v := os.Getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"); v != "" {
+		return v
+	}
+	return ""
+}
+
 // timestampFunc constructs a function that returns a string representation of the current date and time.
 //
 // This function was imported from terraform's datetime utilities.
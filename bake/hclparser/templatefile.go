@@ -0,0 +1,87 @@
+package hclparser
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+const templatefileFuncName = "templatefile"
+
+// templatefileFunc constructs a function that reads a file with Opt.ReadFile
+// and renders it as a template. Only the given variables are visible to the
+// template, together with the functions of the definition.
+func (p *parser) templatefileFunc() function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{
+				Name: "path",
+				Type: cty.String,
+			},
+			{
+				Name: "vars",
+				Type: cty.DynamicPseudoType,
+			},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			fn := args[0].AsString()
+			vars := args[1]
+			if !vars.IsNull() && !vars.Type().IsObjectType() && !vars.Type().IsMapType() {
+				return cty.NilVal, errors.New("vars argument must be a map or an object")
+			}
+
+			if p.opt.ReadFile == nil {
+				return cty.NilVal, errors.Errorf("cannot read %s: file access is not allowed", fn)
+			}
+			dt, err := p.opt.ReadFile(fn)
+			if err != nil {
+				return cty.NilVal, err
+			}
+
+			expr, diags := hclsyntax.ParseTemplate(dt, fn, hcl.InitialPos)
+			if diags.HasErrors() {
+				return cty.NilVal, diags
+			}
+
+			fns, diags := funcCalls(expr)
+			if diags.HasErrors() {
+				return cty.NilVal, diags
+			}
+			for _, name := range fns {
+				if name == templatefileFuncName {
+					return cty.NilVal, errors.Errorf("cannot call %s from template %s", templatefileFuncName, fn)
+				}
+				if err := p.resolveFunction(p.ectx, name); err != nil {
+					return cty.NilVal, err
+				}
+			}
+
+			ectx := &hcl.EvalContext{
+				Variables: map[string]cty.Value{},
+				Functions: p.ectx.Functions,
+			}
+			if !vars.IsNull() {
+				ectx.Variables = vars.AsValueMap()
+			}
+			for _, v := range expr.Variables() {
+				if _, ok := ectx.Variables[v.RootName()]; !ok {
+					return cty.NilVal, errors.Errorf("template %s references undefined variable %q", fn, v.RootName())
+				}
+			}
+
+			v, diags := expr.Value(ectx)
+			if diags.HasErrors() {
+				return cty.NilVal, diags
+			}
+			v, err = convert.Convert(v, cty.String)
+			if err != nil {
+				return cty.NilVal, errors.Wrapf(err, "invalid result of template %s", fn)
+			}
+			return v, nil
+		},
+	})
+}
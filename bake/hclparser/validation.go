@@ -0,0 +1,90 @@
+package hclparser
+
+import (
+	"reflect"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Validation is a validation block of a definition block, such as a target.
+// Unlike the validations of variables, it's not evaluated while parsing but
+// with Validate once the block is fully resolved.
+type Validation struct {
+	Condition    hcl.Expression `json:"condition" hcl:"condition"`
+	ErrorMessage hcl.Expression `json:"error_message" hcl:"error_message"`
+
+	// ectx is the evaluation context of the block holding the validation.
+	ectx *hcl.EvalContext
+}
+
+// Validate evaluates the condition with the resolved block available as the
+// variable name. It returns a diagnostic with the error message if the
+// condition is false.
+func (v *Validation) Validate(name string, val cty.Value) hcl.Diagnostics {
+	ectx := &hcl.EvalContext{}
+	if v.ectx != nil {
+		ectx = v.ectx.NewChild()
+	}
+	ectx.Variables = map[string]cty.Value{name: val}
+
+	condition, diags := v.Condition.Value(ectx)
+	if diags.HasErrors() {
+		return diags
+	}
+	condition, err := convert.Convert(condition, cty.Bool)
+	if err != nil || condition.IsNull() || !condition.IsKnown() {
+		return hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid validation condition",
+			Detail:   "The condition must evaluate to a boolean.",
+			Subject:  v.Condition.Range().Ptr(),
+		}}
+	}
+	if condition.True() {
+		return nil
+	}
+
+	message, diags := v.ErrorMessage.Value(ectx)
+	if diags.HasErrors() {
+		return diags
+	}
+	message, err = convert.Convert(message, cty.String)
+	if err != nil || message.IsNull() {
+		return hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid validation error message",
+			Detail:   "The error message must evaluate to a string.",
+			Subject:  v.ErrorMessage.Range().Ptr(),
+		}}
+	}
+	return hcl.Diagnostics{&hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Validation failed",
+		Detail:   message.AsString(),
+		Subject:  v.Condition.Range().Ptr(),
+	}}
+}
+
+// setValidationContexts records the evaluation context of the validations of
+// a decoded block.
+func setValidationContexts(v reflect.Value, ectx *hcl.EvalContext) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanInterface() {
+			continue
+		}
+		if vs, ok := f.Interface().([]*Validation); ok {
+			for _, vv := range vs {
+				if vv.ectx == nil {
+					vv.ectx = ectx
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package bake
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/docker/buildx/util/progress"
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+	"github.com/pkg/errors"
+)
+
+// TargetHooks are commands run on the client before and after the build of a
+// target. They require the hooks entitlement.
+type TargetHooks struct {
+	Pre  []string `json:"pre,omitempty" hcl:"pre,optional" cty:"pre"`
+	Post []string `json:"post,omitempty" hcl:"post,optional" cty:"post"`
+}
+
+type HookStage string
+
+const (
+	HookStagePre  HookStage = "pre"
+	HookStagePost HookStage = "post"
+)
+
+func (h *TargetHooks) commands(stage HookStage) []string {
+	if h == nil {
+		return nil
+	}
+	switch stage {
+	case HookStagePre:
+		return h.Pre
+	case HookStagePost:
+		return h.Post
+	}
+	return nil
+}
+
+// RunHooks runs the hooks of the given stage for the targets, in the order of
+// their names. The environment of post hooks also holds the result of the
+// build of their target from resp.
+func RunHooks(ctx context.Context, pw progress.Writer, stage HookStage, tgts map[string]*Target, resp map[string]*client.SolveResponse) error {
+	names := make([]string, 0, len(tgts))
+	for name, t := range tgts {
+		if len(t.Hooks.commands(stage)) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := tgts[name]
+		var res map[string]string
+		if r, ok := resp[name]; ok && r != nil {
+			res = r.ExporterResponse
+		}
+		env := append(os.Environ(), hookEnv(name, t, res)...)
+		for _, cmd := range t.Hooks.commands(stage) {
+			err := progress.Wrap(fmt.Sprintf("[%s] %s hook: %s", name, stage, cmd), pw.Write, func(sub progress.SubLogger) error {
+				return runHook(ctx, cmd, env, sub)
+			})
+			if err != nil {
+				return errors.Wrapf(err, "%s hook %q of target %q failed", stage, cmd, name)
+			}
+		}
+	}
+	return nil
+}
+
+// hookEnv returns the environment variables describing the target to its
+// hooks.
+func hookEnv(name string, t *Target, res map[string]string) []string {
+	env := []string{
+		"BAKE_TARGET_NAME=" + name,
+		"BAKE_TARGET_TAGS=" + strings.Join(t.Tags, ","),
+		"BAKE_TARGET_PLATFORMS=" + strings.Join(t.Platforms, ","),
+	}
+	if t.Context != nil {
+		env = append(env, "BAKE_TARGET_CONTEXT="+*t.Context)
+	}
+	if t.Dockerfile != nil {
+		env = append(env, "BAKE_TARGET_DOCKERFILE="+*t.Dockerfile)
+	}
+	if v, ok := res[exptypes.ExporterImageDigestKey]; ok {
+		env = append(env, "BAKE_TARGET_DIGEST="+v)
+	}
+	if v, ok := res[exptypes.ExporterImageConfigDigestKey]; ok {
+		env = append(env, "BAKE_TARGET_CONFIG_DIGEST="+v)
+	}
+	if v, ok := res["buildx.build.ref"]; ok {
+		env = append(env, "BAKE_TARGET_BUILD_REF="+v)
+	}
+	return env
+}
+
+func runHook(ctx context.Context, command string, env []string, sub progress.SubLogger) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/S", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = env
+	cmd.Stdout = &hookLogWriter{sub: sub, stream: 1}
+	cmd.Stderr = &hookLogWriter{sub: sub, stream: 2}
+	return cmd.Run()
+}
+
+type hookLogWriter struct {
+	sub    progress.SubLogger
+	stream int
+}
+
+func (w *hookLogWriter) Write(dt []byte) (int, error) {
+	w.sub.Log(w.stream, append([]byte(nil), dt...))
+	return len(dt), nil
+}
@@ -0,0 +1,111 @@
+package bake
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/docker/buildx/util/progress"
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTargetsHooks(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+		target "base" {
+			hooks {
+				pre = ["./gen-version.sh"]
+			}
+		}
+		target "app" {
+			inherits = ["base"]
+			hooks {
+				post = ["./notify.sh app"]
+			}
+		}
+		target "db" {
+		}
+		`),
+	}
+
+	var ent EntitlementConf
+	m, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app", "db"}, nil, nil, &ent)
+	require.NoError(t, err)
+	require.Equal(t, &TargetHooks{Post: []string{"./notify.sh app"}}, m["app"].Hooks)
+	require.Nil(t, m["db"].Hooks)
+
+	exp, err := ent.Validate(nil)
+	require.NoError(t, err)
+	require.True(t, exp.Hooks)
+
+	ent = EntitlementConf{Hooks: true}
+	_, _, err = ReadTargets(context.TODO(), []File{fp}, []string{"app", "db"}, nil, nil, &ent)
+	require.NoError(t, err)
+	exp, err = ent.Validate(nil)
+	require.NoError(t, err)
+	require.False(t, exp.Hooks)
+
+	ent = EntitlementConf{}
+	_, _, err = ReadTargets(context.TODO(), []File{fp}, []string{"db"}, nil, nil, &ent)
+	require.NoError(t, err)
+	exp, err = ent.Validate(nil)
+	require.NoError(t, err)
+	require.False(t, exp.Hooks)
+}
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are run with sh in this test")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	tgts := map[string]*Target{
+		"app": {
+			Tags:      []string{"app:latest", "app:v1"},
+			Platforms: []string{"linux/amd64"},
+			Hooks: &TargetHooks{
+				Pre:  []string{`echo "pre $BAKE_TARGET_NAME $BAKE_TARGET_TAGS" >> ` + out},
+				Post: []string{`echo "post $BAKE_TARGET_NAME $BAKE_TARGET_DIGEST" >> ` + out},
+			},
+		},
+		"db": {
+			Hooks: &TargetHooks{
+				Pre: []string{`echo "pre $BAKE_TARGET_NAME $BAKE_TARGET_PLATFORMS" >> ` + out},
+			},
+		},
+	}
+	resp := map[string]*client.SolveResponse{
+		"app": {ExporterResponse: map[string]string{exptypes.ExporterImageDigestKey: "sha256:abcd"}},
+	}
+
+	pw := &nopProgressWriter{}
+	require.NoError(t, RunHooks(context.TODO(), pw, HookStagePre, tgts, nil))
+	require.NoError(t, RunHooks(context.TODO(), pw, HookStagePost, tgts, resp))
+
+	dt, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "pre app app:latest,app:v1\npre db \npost app sha256:abcd\n", string(dt))
+
+	tgts["app"].Hooks.Pre = []string{"exit 3"}
+	err = RunHooks(context.TODO(), pw, HookStagePre, tgts, nil)
+	require.ErrorContains(t, err, `pre hook "exit 3" of target "app" failed`)
+}
+
+type nopProgressWriter struct{}
+
+func (w *nopProgressWriter) Write(*client.SolveStatus) {}
+
+func (w *nopProgressWriter) WriteBuildRef(string, string) {}
+
+func (w *nopProgressWriter) ValidateLogSource(digest.Digest, interface{}) bool {
+	return true
+}
+
+func (w *nopProgressWriter) ClearLogSource(interface{}) {}
+
+var _ progress.Writer = &nopProgressWriter{}
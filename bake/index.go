@@ -0,0 +1,226 @@
+package bake
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/docker/buildx/util/progress"
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+	"github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// TargetTypeIndex is the type of the targets that don't build an image but
+// assemble the images pushed by their sources into an image index.
+const TargetTypeIndex = "index"
+
+// IsIndex returns whether the target assembles an image index.
+func (t *Target) IsIndex() bool {
+	return t.Type != nil && *t.Type == TargetTypeIndex
+}
+
+// loadIndexSources adds the sources of the index targets of m to m, so they
+// are built before their index is assembled.
+func (c Config) loadIndexSources(m map[string]*Target, o map[string]map[string]Override, ent *EntitlementConf) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := m[name]
+		if t.Type != nil && !t.IsIndex() && !t.IsTest() {
+			return errors.Errorf("invalid type %q for target %s, expecting %s or %s", *t.Type, name, TargetTypeIndex, TargetTypeTest)
+		}
+		if !t.IsIndex() {
+			continue
+		}
+		if len(t.Sources) == 0 {
+			return errors.Errorf("index target %s requires sources", name)
+		}
+		if len(t.Tags) == 0 {
+			return errors.Errorf("index target %s requires tags", name)
+		}
+		for _, src := range t.Sources {
+			st, ok := m[src]
+			if !ok {
+				var err error
+				if st, err = c.ResolveTarget(src, o, ent); err != nil {
+					return errors.Wrapf(err, "failed to resolve source of index target %s", name)
+				}
+				m[src] = st
+			}
+			if st.IsIndex() {
+				return errors.Errorf("source %s of index target %s can't be an index", src, name)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckIndexes checks that the sources of the index targets push their
+// image, which is required to assemble the index in the registry.
+func CheckIndexes(tgts map[string]*Target) error {
+	for _, name := range sortedKeys(tgts) {
+		t := tgts[name]
+		if !t.IsIndex() {
+			continue
+		}
+		for _, src := range t.Sources {
+			if st, ok := tgts[src]; ok && !pushesImage(st.Outputs) {
+				return errors.Errorf("source %s of index target %s must push its image, set --push or a registry output", src, name)
+			}
+		}
+	}
+	return nil
+}
+
+func pushesImage(outputs buildflags.Exports) bool {
+	for _, o := range outputs {
+		if o.Type == "registry" {
+			return true
+		}
+		if push, _ := strconv.ParseBool(o.Attrs["push"]); push && o.Type == "image" {
+			return true
+		}
+	}
+	return false
+}
+
+// PushIndexes assembles the images pushed by the sources of the index targets
+// into an image index, along with their attestations, and pushes it to the
+// tags of the index target. It returns the descriptors of the pushed indexes.
+func PushIndexes(ctx context.Context, pw progress.Writer, opt imagetools.Opt, tgts map[string]*Target, resp map[string]*client.SolveResponse) (map[string]ocispecs.Descriptor, error) {
+	names := make([]string, 0, len(tgts))
+	for name, t := range tgts {
+		if t.IsIndex() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	out := make(map[string]ocispecs.Descriptor, len(names))
+	for _, name := range names {
+		t := tgts[name]
+		tags, err := indexTags(t)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid tags of index target %s", name)
+		}
+		srcs, err := indexSources(name, t, resp, tags)
+		if err != nil {
+			return nil, err
+		}
+		annotationsList, err := t.annotations(exptypes.AnnotationIndex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid annotations of index target %s", name)
+		}
+		annotations, err := buildflags.ParseAnnotations(annotationsList)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid annotations of index target %s", name)
+		}
+
+		err = progress.Wrap(fmt.Sprintf("[%s] pushing index", name), pw.Write, func(sub progress.SubLogger) error {
+			dt, desc, err := imagetools.New(opt).Combine(ctx, srcs, annotations, true)
+			if err != nil {
+				return err
+			}
+			// new resolver cause need new auth
+			r := imagetools.New(opt)
+			eg, ctx := errgroup.WithContext(ctx)
+			for _, tag := range tags {
+				eg.Go(func() error {
+					for _, s := range srcs {
+						if reference.Domain(s.Ref) == reference.Domain(tag) && reference.Path(s.Ref) == reference.Path(tag) {
+							continue
+						}
+						sub.Log(1, []byte(fmt.Sprintf("copying %s from %s to %s\n", s.Desc.Digest, s.Ref, tag)))
+						if err := r.Copy(ctx, s, tag); err != nil {
+							return err
+						}
+					}
+					sub.Log(1, []byte(fmt.Sprintf("pushing %s to %s\n", desc.Digest, tag)))
+					return r.Push(ctx, tag, desc, dt)
+				})
+			}
+			if err := eg.Wait(); err != nil {
+				return err
+			}
+			out[name] = desc
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to push index target %s", name)
+		}
+	}
+	return out, nil
+}
+
+func indexTags(t *Target) ([]reference.Named, error) {
+	tags := make([]reference.Named, len(t.Tags))
+	for i, tag := range t.Tags {
+		n, err := reference.ParseNormalizedNamed(tag)
+		if err != nil {
+			return nil, err
+		}
+		tags[i] = reference.TagNameOnly(n)
+	}
+	return tags, nil
+}
+
+// indexSources returns the images pushed by the sources of the index target
+// from the result of their build. The name of an image in the repository of
+// one of the tags of the index is preferred so the image doesn't need to be
+// copied.
+func indexSources(name string, t *Target, resp map[string]*client.SolveResponse, tags []reference.Named) ([]*imagetools.Source, error) {
+	srcs := make([]*imagetools.Source, 0, len(t.Sources))
+	for _, src := range t.Sources {
+		var res map[string]string
+		if r, ok := resp[src]; ok && r != nil {
+			res = r.ExporterResponse
+		}
+		dgst := res[exptypes.ExporterImageDigestKey]
+		if dgst == "" || res["image.name"] == "" {
+			return nil, errors.Errorf("source %s of index target %s did not push an image", src, name)
+		}
+		var ref reference.Named
+		for _, v := range strings.Split(res["image.name"], ",") {
+			n, err := reference.ParseNormalizedNamed(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid image name of source %s of index target %s", src, name)
+			}
+			n = reference.TagNameOnly(n)
+			if ref == nil {
+				ref = n
+			}
+			if sameRepository(n, tags) {
+				ref = n
+				break
+			}
+		}
+		d, err := digest.Parse(dgst)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid image digest of source %s of index target %s", src, name)
+		}
+		srcs = append(srcs, &imagetools.Source{Ref: ref, Desc: ocispecs.Descriptor{Digest: d}})
+	}
+	return srcs, nil
+}
+
+func sameRepository(n reference.Named, tags []reference.Named) bool {
+	for _, t := range tags {
+		if reference.Domain(n) == reference.Domain(t) && reference.Path(n) == reference.Path(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package bake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTargetsIndex(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+target "app-amd64" {
+  platforms = ["linux/amd64"]
+  tags = ["docker.io/user/app:amd64"]
+}
+
+target "app-arm64" {
+  platforms = ["linux/arm64"]
+  tags = ["docker.io/user/app:arm64"]
+}
+
+target "index" {
+  type = "index"
+  sources = ["app-amd64", "app-arm64"]
+  tags = ["docker.io/user/app:latest"]
+  annotations = ["index:org.opencontainers.image.title=app"]
+}
+`),
+	}
+
+	ctx := context.TODO()
+
+	m, g, err := ReadTargets(ctx, []File{fp}, []string{"index"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(m))
+	require.Equal(t, []string{"index"}, g["default"].Targets)
+
+	idx := m["index"]
+	require.True(t, idx.IsIndex())
+	require.Equal(t, []string{"app-amd64", "app-arm64"}, idx.Sources)
+	require.Nil(t, idx.Context)
+	require.Nil(t, idx.Dockerfile)
+	require.False(t, m["app-amd64"].IsIndex())
+	require.Equal(t, ".", *m["app-amd64"].Context)
+
+	bo, err := TargetsToBuildOpt(m, &Input{})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(bo))
+	require.NotContains(t, bo, "index")
+
+	require.ErrorContains(t, CheckIndexes(m), "source app-amd64 of index target index must push its image")
+
+	m, _, err = ReadTargets(ctx, []File{fp}, []string{"index"}, []string{"*.push=true"}, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.NoError(t, CheckIndexes(m))
+
+	m, _, err = ReadTargets(ctx, []File{fp}, []string{"index"}, []string{"index.sources=app-arm64"}, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(m))
+	require.Equal(t, []string{"app-arm64"}, m["index"].Sources)
+}
+
+func TestReadTargetsIndexInvalid(t *testing.T) {
+	tcs := []struct {
+		name string
+		dt   string
+		err  string
+	}{
+		{
+			name: "no sources",
+			dt: `
+target "index" {
+  type = "index"
+  tags = ["user/app:latest"]
+}`,
+			err: "index target index requires sources",
+		},
+		{
+			name: "no tags",
+			dt: `
+target "app" {}
+target "index" {
+  type = "index"
+  sources = ["app"]
+}`,
+			err: "index target index requires tags",
+		},
+		{
+			name: "unknown source",
+			dt: `
+target "index" {
+  type = "index"
+  sources = ["app"]
+  tags = ["user/app:latest"]
+}`,
+			err: "failed to find target app",
+		},
+		{
+			name: "index source",
+			dt: `
+target "app" {
+  type = "index"
+  sources = ["index"]
+  tags = ["user/app:app"]
+}
+target "index" {
+  type = "index"
+  sources = ["app"]
+  tags = ["user/app:latest"]
+}`,
+			err: "source app of index target index can't be an index",
+		},
+		{
+			name: "invalid type",
+			dt: `
+target "index" {
+  type = "manifest"
+}`,
+			err: `invalid type "manifest" for target index, expecting index`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			fp := File{Name: "docker-bake.hcl", Data: []byte(tc.dt)}
+			_, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"index"}, nil, nil, &EntitlementConf{})
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestIndexSources(t *testing.T) {
+	idx := &Target{
+		Name:    "index",
+		Sources: []string{"app-amd64", "app-arm64"},
+		Tags:    []string{"docker.io/user/app:latest"},
+	}
+	tags, err := indexTags(idx)
+	require.NoError(t, err)
+
+	resp := map[string]*client.SolveResponse{
+		"app-amd64": {ExporterResponse: map[string]string{
+			"containerimage.digest": "sha256:4e1f9c1f7d1bbcf7e2b4f2a4a9c4bd5ef4f2f6b0c5d0e1f2a3b4c5d6e7f8a9b0",
+			"image.name":            "ghcr.io/user/app:amd64,docker.io/user/app:amd64",
+		}},
+		"app-arm64": {ExporterResponse: map[string]string{
+			"containerimage.digest": "sha256:5f2a0d2a8e2ccd08f3c5a3b5bad5ce6fa5a3a7c1d6e1f2a3b4c5d6e7f8a9b0c1",
+			"image.name":            "ghcr.io/user/app:arm64",
+		}},
+	}
+	srcs, err := indexSources("index", idx, resp, tags)
+	require.NoError(t, err)
+	require.Len(t, srcs, 2)
+
+	// the image in the repository of the index is preferred
+	require.Equal(t, "docker.io/user/app:amd64", srcs[0].Ref.String())
+	require.Equal(t, digest.Digest("sha256:4e1f9c1f7d1bbcf7e2b4f2a4a9c4bd5ef4f2f6b0c5d0e1f2a3b4c5d6e7f8a9b0"), srcs[0].Desc.Digest)
+	require.Equal(t, "ghcr.io/user/app:arm64", srcs[1].Ref.String())
+
+	delete(resp, "app-arm64")
+	_, err = indexSources("index", idx, resp, tags)
+	require.ErrorContains(t, err, "source app-arm64 of index target index did not push an image")
+
+	n, err := reference.ParseNormalizedNamed("user/app:amd64")
+	require.NoError(t, err)
+	require.True(t, sameRepository(n, tags))
+}
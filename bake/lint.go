@@ -0,0 +1,134 @@
+package bake
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/buildx/bake/hclparser"
+	"github.com/pkg/errors"
+)
+
+const (
+	LintRuleUnusedVariable    = "UnusedVariable"
+	LintRuleUnreachableTarget = "UnreachableTarget"
+)
+
+// LintWarning is a finding of the static analysis of a bake definition.
+type LintWarning struct {
+	Rule    string `json:"rule"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+func (w LintWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Rule, w.Message)
+}
+
+// Lint parses the definition files and reports variables that are declared
+// but never referenced, and targets that can't be reached from any group or
+// from the requested targets.
+func Lint(files []File, targets []string, defaults map[string]string) ([]LintWarning, error) {
+	c, pm, err := ParseFiles(files, defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []LintWarning
+	for _, name := range pm.UnusedVariables {
+		warnings = append(warnings, LintWarning{
+			Rule:    LintRuleUnusedVariable,
+			Name:    name,
+			Message: fmt.Sprintf("variable %q is declared but never used", name),
+		})
+	}
+	for _, name := range c.unreachableTargets(targets, pm) {
+		warnings = append(warnings, LintWarning{
+			Rule:    LintRuleUnreachableTarget,
+			Name:    name,
+			Message: fmt.Sprintf("target %q is not reachable from any group or requested target", name),
+		})
+	}
+	return warnings, nil
+}
+
+// CheckMissingVariables parses the definition files and returns an error
+// listing all the referenced variables that have no default and are not set
+// in the environment, which would otherwise evaluate to an empty string.
+func CheckMissingVariables(files []File, defaults map[string]string) error {
+	_, pm, err := ParseFiles(files, defaults)
+	if err != nil {
+		return err
+	}
+	if len(pm.MissingVariables) > 0 {
+		return errors.Errorf("variables without default are not set in the environment: %s", strings.Join(pm.MissingVariables, ", "))
+	}
+	return nil
+}
+
+// unreachableTargets returns the targets that are neither part of a group,
+// requested, inherited nor used as a context by a reachable target.
+func (c Config) unreachableTargets(requested []string, pm *hclparser.ParseMeta) []string {
+	// groups created for targets renamed by a matrix only alias them
+	aliases := map[string]struct{}{}
+	if pm != nil {
+		for oldName, newNames := range pm.Renamed["target"] {
+			if len(newNames) == 1 && newNames[0] == oldName {
+				continue
+			}
+			aliases[oldName] = struct{}{}
+		}
+	}
+
+	var roots []string
+	for _, g := range c.Groups {
+		if _, ok := aliases[g.Name]; ok {
+			continue
+		}
+		roots = append(roots, g.Name)
+	}
+	for _, name := range requested {
+		roots = append(roots, sanitizeTargetName(name))
+	}
+
+	targets := make(map[string]*Target, len(c.Targets))
+	for _, t := range c.Targets {
+		targets[t.Name] = t
+	}
+
+	reachable := map[string]struct{}{}
+	var visit func(name string)
+	visit = func(name string) {
+		ts, _ := c.ResolveGroup(name)
+		for _, tname := range ts {
+			if _, ok := reachable[tname]; ok {
+				continue
+			}
+			reachable[tname] = struct{}{}
+			t, ok := targets[tname]
+			if !ok {
+				continue
+			}
+			for _, dep := range t.Inherits {
+				visit(dep)
+			}
+			for _, v := range t.Contexts {
+				if dep, ok := strings.CutPrefix(v, "target:"); ok {
+					visit(dep)
+				}
+			}
+		}
+	}
+	for _, name := range roots {
+		visit(name)
+	}
+
+	var unreachable []string
+	for name := range targets {
+		if _, ok := reachable[name]; !ok {
+			unreachable = append(unreachable, name)
+		}
+	}
+	sort.Strings(unreachable)
+	return unreachable
+}
@@ -0,0 +1,103 @@
+package bake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+variable "TAG" {
+  default = "latest"
+}
+variable "REGISTRY" {
+  default = "docker.io"
+}
+variable "UNUSED" {
+  default = "foo"
+}
+variable "VERSION" {
+  default = "1.0"
+}
+function "tag" {
+  params = [name]
+  result = "${REGISTRY}/${name}:${TAG}"
+}
+group "default" {
+  targets = ["app"]
+}
+target "_common" {
+  args = {
+    VERSION = VERSION
+  }
+}
+target "base" {
+  dockerfile = "base.Dockerfile"
+}
+target "app" {
+  inherits = ["_common"]
+  contexts = {
+    base = "target:base"
+  }
+  tags = [tag("app")]
+}
+target "matrix" {
+  name = "matrix-${item}"
+  matrix = {
+    item = ["a", "b"]
+  }
+}
+target "orphan" {
+}
+`),
+	}
+
+	warnings, err := Lint([]File{fp}, []string{"default"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []LintWarning{
+		{Rule: LintRuleUnusedVariable, Name: "UNUSED", Message: `variable "UNUSED" is declared but never used`},
+		{Rule: LintRuleUnreachableTarget, Name: "matrix-a", Message: `target "matrix-a" is not reachable from any group or requested target`},
+		{Rule: LintRuleUnreachableTarget, Name: "matrix-b", Message: `target "matrix-b" is not reachable from any group or requested target`},
+		{Rule: LintRuleUnreachableTarget, Name: "orphan", Message: `target "orphan" is not reachable from any group or requested target`},
+	}, warnings)
+
+	warnings, err = Lint([]File{fp}, []string{"default", "matrix", "orphan"}, nil)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	require.Equal(t, "UNUSED", warnings[0].Name)
+}
+
+func TestCheckMissingVariables(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+variable "REGISTRY" {}
+variable "TAG" {}
+variable "VERSION" {}
+variable "SET" {}
+variable "EMPTY" {
+  default = ""
+}
+variable "UNUSED" {}
+target "app" {
+  context = BAKE_CMD_CONTEXT
+  tags = ["${REGISTRY}/app:${TAG}${EMPTY}", "app:${SET}"]
+  args = {
+    VERSION = VERSION
+  }
+}
+`),
+	}
+	t.Setenv("SET", "")
+
+	err := CheckMissingVariables([]File{fp}, map[string]string{"BAKE_CMD_CONTEXT": "."})
+	require.EqualError(t, err, "variables without default are not set in the environment: REGISTRY, TAG, VERSION")
+
+	t.Setenv("REGISTRY", "docker.io")
+	t.Setenv("TAG", "latest")
+	t.Setenv("VERSION", "1.0")
+	require.NoError(t, CheckMissingVariables([]File{fp}, map[string]string{"BAKE_CMD_CONTEXT": "."}))
+}
@@ -0,0 +1,163 @@
+package bake
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/docker/buildx/build"
+	"github.com/moby/buildkit/util/gitutil"
+	"github.com/pkg/errors"
+)
+
+// LockFilename is the name of the file recording the commits remote
+// definitions and contexts are pinned to.
+const LockFilename = "bake.lock"
+
+const lockVersion = 1
+
+// RefResolver returns the commit a ref of a remote git repository points to.
+type RefResolver func(ctx context.Context, remote, ref string) (string, error)
+
+// Lock pins the refs of remote git definitions and contexts to commits, so
+// they are resolved the same way until the lock is updated.
+type Lock struct {
+	Version int `json:"version"`
+	// Sources maps a git remote and ref to the commit it was resolved to.
+	Sources map[string]string `json:"sources"`
+
+	changed bool
+}
+
+func NewLock() *Lock {
+	return &Lock{
+		Version: lockVersion,
+		Sources: map[string]string{},
+	}
+}
+
+// ReadLock reads the lock file at fn. It returns nil if the file doesn't
+// exist.
+func ReadLock(fn string) (*Lock, error) {
+	dt, err := os.ReadFile(fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	l := NewLock()
+	if err := json.Unmarshal(dt, l); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", fn)
+	}
+	if l.Version != lockVersion {
+		return nil, errors.Errorf("unsupported version %d for %s", l.Version, fn)
+	}
+	if l.Sources == nil {
+		l.Sources = map[string]string{}
+	}
+	return l, nil
+}
+
+// Save writes the lock to fn if it has changed.
+func (l *Lock) Save(fn string) error {
+	if !l.changed {
+		return nil
+	}
+	dt, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(fn, append(dt, '\n'), 0644); err != nil {
+		return err
+	}
+	l.changed = false
+	return nil
+}
+
+// PinURL returns the url of a remote git definition or context with its ref
+// replaced by the commit recorded in the lock. Refs that are not locked yet
+// are resolved with resolve and recorded, or left as is if resolve is nil.
+// Other urls are returned unchanged.
+func (l *Lock) PinURL(ctx context.Context, u string, resolve RefResolver) (string, error) {
+	base, subpath := splitRemoteSubpath(u)
+	if !build.IsRemoteURL(base) {
+		return u, nil
+	}
+	gitRef, err := gitutil.ParseGitRef(base)
+	if err != nil || gitutil.IsCommitSHA(gitRef.Commit) {
+		return u, nil
+	}
+
+	key := gitRef.Remote
+	if gitRef.Commit != "" {
+		key += "#" + gitRef.Commit
+	}
+	commit, ok := l.Sources[key]
+	if !ok {
+		if resolve == nil {
+			return u, nil
+		}
+		commit, err = resolve(ctx, gitRef.Remote, gitRef.Commit)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to resolve %s", key)
+		}
+		l.Sources[key] = commit
+		l.changed = true
+	}
+
+	fragment := commit
+	if gitRef.SubDir != "" {
+		fragment += ":" + gitRef.SubDir
+	}
+	remote, _, _ := strings.Cut(base, "#")
+	if subpath != "" {
+		remote += "//" + subpath
+	}
+	return remote + "#" + fragment, nil
+}
+
+// PinTargets pins the remote git contexts of targets with PinURL.
+func (l *Lock) PinTargets(ctx context.Context, targets map[string]*Target, resolve RefResolver) error {
+	for _, t := range targets {
+		if t.Context != nil {
+			v, err := l.PinURL(ctx, *t.Context, resolve)
+			if err != nil {
+				return err
+			}
+			t.Context = &v
+		}
+		for k, c := range t.Contexts {
+			v, err := l.PinURL(ctx, c, resolve)
+			if err != nil {
+				return err
+			}
+			t.Contexts[k] = v
+		}
+	}
+	return nil
+}
+
+// splitRemoteSubpath splits a remote git url in the form
+// <remote>//<subpath>[#<ref>] into the url of the repository and the path of
+// the definition inside it.
+func splitRemoteSubpath(u string) (string, string) {
+	base, fragment, hasFragment := strings.Cut(u, "#")
+	start := 0
+	if i := strings.Index(base, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	i := strings.Index(base[start:], "//")
+	if i < 0 {
+		return u, ""
+	}
+	remote, subpath := base[:start+i], strings.Trim(base[start+i+2:], "/")
+	if hasFragment {
+		remote += "#" + fragment
+	}
+	if _, err := gitutil.ParseGitRef(remote); err != nil {
+		return u, ""
+	}
+	return remote, subpath
+}
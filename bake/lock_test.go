@@ -0,0 +1,160 @@
+package bake
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testCommit = "0123456789abcdef0123456789abcdef01234567"
+
+func TestSplitRemoteSubpath(t *testing.T) {
+	cases := []struct {
+		in      string
+		url     string
+		subpath string
+	}{
+		{
+			in:  "https://github.com/org/defs.git",
+			url: "https://github.com/org/defs.git",
+		},
+		{
+			in:  "https://github.com/org/defs.git#v2.1.0:ci",
+			url: "https://github.com/org/defs.git#v2.1.0:ci",
+		},
+		{
+			in:      "https://github.com/org/defs.git//ci/bake.hcl#v2.1.0",
+			url:     "https://github.com/org/defs.git#v2.1.0",
+			subpath: "ci/bake.hcl",
+		},
+		{
+			in:      "https://github.com/org/defs.git//ci/",
+			url:     "https://github.com/org/defs.git",
+			subpath: "ci",
+		},
+		{
+			in:      "git@github.com:org/defs.git//ci#main",
+			url:     "git@github.com:org/defs.git#main",
+			subpath: "ci",
+		},
+		{
+			in:  "https://example.com//bake.hcl",
+			url: "https://example.com//bake.hcl",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			url, subpath := splitRemoteSubpath(tc.in)
+			require.Equal(t, tc.url, url)
+			require.Equal(t, tc.subpath, subpath)
+		})
+	}
+}
+
+func TestLockPinURL(t *testing.T) {
+	var resolved []string
+	resolve := func(_ context.Context, remote, ref string) (string, error) {
+		resolved = append(resolved, remote+"#"+ref)
+		return testCommit, nil
+	}
+
+	l := NewLock()
+	ctx := context.Background()
+
+	// not locked yet, and not resolved
+	u, err := l.PinURL(ctx, "https://github.com/org/defs.git#v2.1.0", nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/org/defs.git#v2.1.0", u)
+
+	u, err = l.PinURL(ctx, "https://github.com/org/defs.git//ci/bake.hcl#v2.1.0", resolve)
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/org/defs.git//ci/bake.hcl#"+testCommit, u)
+
+	// locked ref with a subdir
+	u, err = l.PinURL(ctx, "https://github.com/org/defs.git#v2.1.0:app", resolve)
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/org/defs.git#"+testCommit+":app", u)
+
+	// default branch
+	u, err = l.PinURL(ctx, "https://github.com/org/app.git", resolve)
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/org/app.git#"+testCommit, u)
+
+	// not pinned
+	for _, in := range []string{
+		".",
+		"app",
+		"target:base",
+		"docker-image://alpine",
+		"https://example.com/context.tar.gz",
+		"https://github.com/org/app.git#" + testCommit,
+	} {
+		u, err = l.PinURL(ctx, in, resolve)
+		require.NoError(t, err)
+		require.Equal(t, in, u)
+	}
+
+	require.Equal(t, []string{
+		"https://github.com/org/defs.git#v2.1.0",
+		"https://github.com/org/app.git#",
+	}, resolved)
+	require.Equal(t, map[string]string{
+		"https://github.com/org/defs.git#v2.1.0": testCommit,
+		"https://github.com/org/app.git":         testCommit,
+	}, l.Sources)
+}
+
+func TestLockPinTargets(t *testing.T) {
+	l := NewLock()
+	l.Sources["https://github.com/org/app.git#main"] = testCommit
+
+	tgts := map[string]*Target{
+		"app": {
+			Context: ptrstr("https://github.com/org/app.git#main"),
+			Contexts: map[string]string{
+				"base": "target:base",
+				"src":  "https://github.com/org/app.git#main:src",
+			},
+		},
+		"base": {
+			Context: ptrstr("."),
+		},
+	}
+	require.NoError(t, l.PinTargets(context.Background(), tgts, nil))
+	require.Equal(t, "https://github.com/org/app.git#"+testCommit, *tgts["app"].Context)
+	require.Equal(t, map[string]string{
+		"base": "target:base",
+		"src":  "https://github.com/org/app.git#" + testCommit + ":src",
+	}, tgts["app"].Contexts)
+	require.Equal(t, ".", *tgts["base"].Context)
+}
+
+func TestLockReadSave(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), LockFilename)
+
+	l, err := ReadLock(fn)
+	require.NoError(t, err)
+	require.Nil(t, l)
+
+	l = NewLock()
+	require.NoError(t, l.Save(fn))
+	l, err = ReadLock(fn)
+	require.NoError(t, err)
+	require.Nil(t, l, "unchanged lock must not be written")
+
+	l = NewLock()
+	_, err = l.PinURL(context.Background(), "https://github.com/org/app.git#main", func(context.Context, string, string) (string, error) {
+		return testCommit, nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, l.Save(fn))
+
+	l, err = ReadLock(fn)
+	require.NoError(t, err)
+	require.Equal(t, 1, l.Version)
+	require.Equal(t, map[string]string{
+		"https://github.com/org/app.git#main": testCommit,
+	}, l.Sources)
+}
@@ -0,0 +1,197 @@
+package bake
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/docker/buildx/bake/hclparser"
+	"github.com/docker/buildx/build"
+	"github.com/pkg/errors"
+)
+
+// moduleSeparator separates the name of a module from the names of the
+// targets and groups it defines.
+const moduleSeparator = "/"
+
+// Module instantiates the targets and groups defined in the HCL files of a
+// local directory. Their names are prefixed by the name of the module, and
+// the inputs set the variables of the module.
+type Module struct {
+	Name   string            `json:"-" hcl:"name,label" cty:"name"`
+	Source string            `json:"source" hcl:"source" cty:"source"`
+	Inputs map[string]string `json:"inputs,omitempty" hcl:"inputs,optional" cty:"inputs"`
+}
+
+// loadModules adds the targets and groups of the modules of c, whose sources
+// are relative to dir. visited holds the directories of the modules being
+// loaded, to detect cycles.
+func (c *Config) loadModules(dir string, visited []string, defaults map[string]string, readFile func(string) ([]byte, error)) error {
+	for _, m := range c.Modules {
+		src := m.Source
+		if !filepath.IsAbs(src) {
+			src = filepath.Join(dir, src)
+		}
+		abs, err := filepath.Abs(src)
+		if err != nil {
+			return errors.Wrapf(err, "module %s", m.Name)
+		}
+		if slices.Contains(visited, abs) {
+			return errors.Errorf("module %s: cycle detected loading %s", m.Name, m.Source)
+		}
+		files, err := readModuleFiles(src)
+		if err != nil {
+			return errors.Wrapf(err, "module %s", m.Name)
+		}
+		// the variables of a module are only set by its inputs, not by the
+		// environment
+		inputs := m.Inputs
+		lookupVar := func(name string) (string, bool) {
+			v, ok := inputs[name]
+			return v, ok
+		}
+		mc, pm, err := parseFilesIn(src, append(visited, abs), files, defaults, lookupVar, readFile)
+		if err != nil {
+			return errors.Wrapf(formatHCLError(err, files), "module %s", m.Name)
+		}
+		for _, name := range sortedKeys(m.Inputs) {
+			if !slices.ContainsFunc(pm.AllVariables, func(v *hclparser.Variable) bool { return v.Name == name }) {
+				return errors.Errorf("module %s: unknown input %q, no such variable in %s", m.Name, name, m.Source)
+			}
+		}
+		if err := c.addModule(m.Name, mc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addModule adds the targets and groups of the config of a module to c with
+// their names, and the references between them, prefixed by the name of the
+// module. The default group of the module is added as a group named after
+// the module.
+func (c *Config) addModule(name string, mc *Config) error {
+	for _, t := range c.Targets {
+		if t.Name == name {
+			return errors.Errorf("module %s conflicts with target %s", name, name)
+		}
+	}
+	for _, g := range c.Groups {
+		if g.Name == name {
+			return errors.Errorf("module %s conflicts with group %s", name, name)
+		}
+	}
+
+	names := map[string]struct{}{}
+	for _, t := range mc.Targets {
+		names[t.Name] = struct{}{}
+	}
+	for _, g := range mc.Groups {
+		names[g.Name] = struct{}{}
+	}
+	prefix := func(n string) string {
+		if _, ok := names[n]; ok {
+			return name + moduleSeparator + n
+		}
+		return n
+	}
+	prefixAll := func(ns []string) []string {
+		if ns == nil {
+			return nil
+		}
+		out := make([]string, len(ns))
+		for i, n := range ns {
+			out[i] = prefix(n)
+		}
+		return out
+	}
+
+	for _, t := range mc.Targets {
+		t.Name = prefix(t.Name)
+		t.Inherits = prefixAll(t.Inherits)
+		t.Sources = prefixAll(t.Sources)
+		for k, v := range t.Contexts {
+			if target, ok := strings.CutPrefix(v, "target:"); ok {
+				t.Contexts[k] = "target:" + prefix(target)
+			}
+		}
+		c.Targets = append(c.Targets, t)
+	}
+	for _, g := range mc.Groups {
+		if g.Name == "default" {
+			c.Groups = append(c.Groups, &Group{
+				Name:        name,
+				Description: g.Description,
+				Targets:     prefixAll(g.Targets),
+			})
+		}
+		g.Name = prefix(g.Name)
+		g.Targets = prefixAll(g.Targets)
+		c.Groups = append(c.Groups, g)
+	}
+	return nil
+}
+
+// resolveModulePaths makes the local contexts of the targets of a module
+// relative to its directory. A target that doesn't set a context and doesn't
+// inherit one uses the directory of the module.
+func (c *Config) resolveModulePaths(dir string) {
+	for _, t := range c.Targets {
+		if t.Context == nil {
+			if len(t.Inherits) == 0 {
+				v := dir
+				t.Context = &v
+			}
+		} else if isRelativeLocalPath(*t.Context) {
+			v := filepath.Join(dir, *t.Context)
+			t.Context = &v
+		}
+		for k, v := range t.Contexts {
+			if isRelativeLocalPath(v) {
+				t.Contexts[k] = filepath.Join(dir, v)
+			}
+		}
+	}
+}
+
+// isRelativeLocalPath returns whether a context is a relative local path.
+func isRelativeLocalPath(p string) bool {
+	return p != "-" && !filepath.IsAbs(p) && !build.IsRemoteURL(p) && !strings.Contains(p, "://") &&
+		!strings.HasPrefix(p, "target:") && !strings.HasPrefix(p, "docker-image:")
+}
+
+// readModuleFiles reads the HCL files of the directory of a module.
+func readModuleFiles(dir string) ([]File, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.hcl"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+		return nil, errors.Errorf("no HCL files found in %s", dir)
+	}
+	slices.Sort(names)
+	files := make([]File, 0, len(names))
+	for _, name := range names {
+		dt, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: name, Data: dt})
+	}
+	return files, nil
+}
+
+// validateQualifiedTargetName validates the name of a target that may be
+// prefixed by the names of the modules it is defined in.
+func validateQualifiedTargetName(name string) error {
+	for _, part := range strings.Split(name, moduleSeparator) {
+		if err := validateTargetName(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
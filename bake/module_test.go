@@ -0,0 +1,142 @@
+package bake
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTargetsModule(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-bake.hcl"), []byte(`
+		variable "registry" {
+			default = "docker.io/library"
+		}
+		group "default" {
+			targets = ["alpine", "debian"]
+		}
+		target "_common" {
+			context = "images"
+		}
+		target "alpine" {
+			inherits = ["_common"]
+			tags = ["${registry}/alpine"]
+		}
+		target "debian" {
+			contexts = {
+				base = "target:alpine"
+				files = "files"
+				tools = "docker-image://tools"
+			}
+			tags = ["${registry}/debian"]
+		}
+	`), 0644))
+
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+		variable "REGISTRY" {
+			default = "ghcr.io/org"
+		}
+		module "base-images" {
+			source = "` + filepath.ToSlash(dir) + `"
+			inputs = {
+				registry = REGISTRY
+			}
+		}
+		target "app" {
+			contexts = {
+				base = "target:base-images/debian"
+			}
+		}
+		`),
+	}
+
+	// the variables of the module are not set by the environment
+	t.Setenv("registry", "docker.io/evil")
+
+	m, g, err := ReadTargets(context.TODO(), []File{fp}, []string{"base-images"}, []string{"base-images/alpine.tags=ghcr.io/org/alpine:edge"}, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Len(t, m, 2)
+	require.Equal(t, []string{"base-images/alpine", "base-images/debian"}, g["base-images"].Targets)
+
+	alpine := m["base-images/alpine"]
+	require.Equal(t, filepath.Join(dir, "images"), *alpine.Context)
+	require.Equal(t, []string{"ghcr.io/org/alpine:edge"}, alpine.Tags)
+
+	debian := m["base-images/debian"]
+	require.Equal(t, dir, *debian.Context)
+	require.Equal(t, []string{"ghcr.io/org/debian"}, debian.Tags)
+	require.Equal(t, "target:base-images/alpine", debian.Contexts["base"])
+	require.Equal(t, filepath.Join(dir, "files"), debian.Contexts["files"])
+	require.Equal(t, "docker-image://tools", debian.Contexts["tools"])
+
+	m, _, err = ReadTargets(context.TODO(), []File{fp}, []string{"app"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Contains(t, m, "base-images/debian")
+	require.Contains(t, m, "base-images/alpine")
+}
+
+func TestReadTargetsModuleInvalid(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-bake.hcl"), []byte(`
+		variable "registry" {
+			default = "docker.io/library"
+		}
+		target "alpine" {
+		}
+	`), 0644))
+	loop := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(loop, "docker-bake.hcl"), []byte(`
+		module "self" {
+			source = "."
+		}
+	`), 0644))
+
+	tcs := []struct {
+		name   string
+		module string
+		extra  string
+		err    string
+	}{
+		{
+			name:   "unknown input",
+			module: `source = "` + filepath.ToSlash(dir) + `"` + "\n" + `inputs = { tag = "1.0" }`,
+			err:    `unknown input "tag"`,
+		},
+		{
+			name:   "missing source",
+			module: `source = "` + filepath.ToSlash(filepath.Join(dir, "missing")) + `"`,
+			err:    "no such file or directory",
+		},
+		{
+			name:   "empty source",
+			module: `source = "` + filepath.ToSlash(t.TempDir()) + `"`,
+			err:    "no HCL files found",
+		},
+		{
+			name:   "cycle",
+			module: `source = "` + filepath.ToSlash(loop) + `"`,
+			err:    "cycle detected",
+		},
+		{
+			name:   "conflict",
+			module: `source = "` + filepath.ToSlash(dir) + `"`,
+			extra:  `target "base" {}`,
+			err:    "module base conflicts with target base",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			fp := File{
+				Name: "docker-bake.hcl",
+				Data: []byte("module \"base\" {\n" + tc.module + "\n}\n" + tc.extra),
+			}
+			_, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"default"}, nil, nil, &EntitlementConf{})
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
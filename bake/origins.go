@@ -0,0 +1,242 @@
+package bake
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Origin is where the value of a field of a target is set: a line of a
+// definition file, a definition file that has no line information, such as a
+// compose file, or an override.
+type Origin struct {
+	File     string
+	Line     int
+	Override string
+}
+
+func (o Origin) String() string {
+	switch {
+	case o.Override != "":
+		return "override " + o.Override
+	case o.Line > 0:
+		return fmt.Sprintf("%s:%d", o.File, o.Line)
+	}
+	return o.File
+}
+
+func (o Origin) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// TargetOrigins maps the fields of a target to the origins of their value, in
+// the order they are applied.
+type TargetOrigins map[string][]Origin
+
+// mergedFields are the fields whose values are merged with the ones of the
+// previous definitions instead of replacing them.
+var mergedFields = map[string]struct{}{
+	"args":            {},
+	"contexts":        {},
+	"labels":          {},
+	"annotations":     {},
+	"attest":          {},
+	"secret":          {},
+	"ssh":             {},
+	"cache-from":      {},
+	"no-cache-filter": {},
+	"ulimits":         {},
+	"entitlements":    {},
+	"watch":           {},
+	"validation":      {},
+	"inherits":        {},
+}
+
+// overrideFields maps the keys of overrides to the fields they set, when
+// they differ.
+var overrideFields = map[string]string{
+	"platform": "platforms",
+	"secrets":  "secret",
+	"push":     "output",
+	"load":     "output",
+}
+
+func (to TargetOrigins) add(field string, o Origin) {
+	if _, ok := mergedFields[field]; ok {
+		to[field] = append(to[field], o)
+		return
+	}
+	to[field] = []Origin{o}
+}
+
+func (to TargetOrigins) merge(to2 TargetOrigins) {
+	for field, origins := range to2 {
+		if _, ok := mergedFields[field]; ok {
+			to[field] = append(to[field], origins...)
+			continue
+		}
+		to[field] = origins
+	}
+}
+
+// ReadOrigins parses the definition files and returns where the fields of the
+// targets are set, including the fields inherited from other targets and the
+// ones set by overrides.
+func ReadOrigins(files []File, targets, overrides []string, defaults map[string]string) (map[string]TargetOrigins, error) {
+	c, pm, err := ParseFiles(files, defaults)
+	if err != nil {
+		return nil, err
+	}
+	o, err := c.newOverrides(overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	// compose files are merged before the HCL and JSON files
+	blocks := map[string]TargetOrigins{}
+	for _, f := range files {
+		if isCompose, _ := validateComposeFile(f.Data, f.Name); isCompose {
+			composeOrigins(f, blocks)
+		}
+	}
+	for _, f := range files {
+		if isCompose, _ := validateComposeFile(f.Data, f.Name); !isCompose {
+			hclOrigins(f, blocks)
+		}
+	}
+
+	// targets renamed by a matrix are defined by the block of their old name
+	labels := map[string]string{}
+	for oldName, newNames := range pm.Renamed["target"] {
+		for _, name := range newNames {
+			labels[name] = oldName
+		}
+	}
+
+	m := make(map[string]TargetOrigins, len(targets))
+	for _, name := range targets {
+		to := c.origins(name, blocks, labels, o, map[string]TargetOrigins{})
+		// inherits are resolved and not printed
+		delete(to, "inherits")
+		m[name] = to
+	}
+	return m, nil
+}
+
+func (c Config) origins(name string, blocks map[string]TargetOrigins, labels map[string]string, overrides map[string]map[string]Override, visited map[string]TargetOrigins) TargetOrigins {
+	if to, ok := visited[name]; ok {
+		return to
+	}
+	visited[name] = TargetOrigins{}
+
+	to := TargetOrigins{}
+	for _, t := range c.Targets {
+		if t.Name != name {
+			continue
+		}
+		for _, parent := range t.Inherits {
+			to.merge(c.origins(parent, blocks, labels, overrides, visited))
+		}
+		break
+	}
+	label := name
+	if l, ok := labels[name]; ok {
+		label = l
+	}
+	to.merge(blocks[label])
+	for _, key := range sortedKeys(overrides[name]) {
+		field, _, _ := strings.Cut(key, ".")
+		if f, ok := overrideFields[field]; ok {
+			field = f
+		}
+		to.add(field, Origin{Override: name + "." + key})
+	}
+	visited[name] = to
+	return to
+}
+
+// hclOrigins records the lines of the attributes and blocks of the targets
+// defined in a HCL or JSON file.
+func hclOrigins(f File, blocks map[string]TargetOrigins) {
+	hf, isHCL, err := ParseHCLFile(f.Data, f.Name)
+	if !isHCL || err != nil {
+		return
+	}
+	block := func(name string) TargetOrigins {
+		to, ok := blocks[name]
+		if !ok {
+			to = TargetOrigins{}
+			blocks[name] = to
+		}
+		return to
+	}
+
+	if body, ok := hf.Body.(*hclsyntax.Body); ok {
+		for _, b := range body.Blocks {
+			if b.Type != "target" || len(b.Labels) != 1 {
+				continue
+			}
+			to := block(b.Labels[0])
+			for _, attr := range sortedKeys(b.Body.Attributes) {
+				a := b.Body.Attributes[attr]
+				to.add(a.Name, Origin{File: f.Name, Line: a.SrcRange.Start.Line})
+			}
+			for _, nb := range b.Body.Blocks {
+				name := nb.Type
+				if name == "dynamic" && len(nb.Labels) == 1 {
+					name = nb.Labels[0]
+				}
+				to.add(name, Origin{File: f.Name, Line: nb.TypeRange.Start.Line})
+			}
+		}
+		return
+	}
+
+	content, _, diags := hf.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "target", LabelNames: []string{"name"}}},
+	})
+	if diags.HasErrors() {
+		return
+	}
+	for _, b := range content.Blocks {
+		attrs, diags := b.Body.JustAttributes()
+		if diags.HasErrors() {
+			continue
+		}
+		to := block(b.Labels[0])
+		for _, name := range sortedKeys(attrs) {
+			to.add(name, Origin{File: f.Name, Line: attrs[name].Range.Start.Line})
+		}
+	}
+}
+
+// composeOrigins records the fields set by the services of a compose file,
+// which has no line information.
+func composeOrigins(f File, blocks map[string]TargetOrigins) {
+	cfg, err := ParseComposeFiles([]File{f})
+	if err != nil {
+		// an override file may not be valid on its own
+		return
+	}
+	for _, t := range cfg.Targets {
+		dt, err := json.Marshal(t)
+		if err != nil {
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(dt, &fields); err != nil {
+			continue
+		}
+		to, ok := blocks[t.Name]
+		if !ok {
+			to = TargetOrigins{}
+			blocks[t.Name] = to
+		}
+		for _, field := range sortedKeys(fields) {
+			to.add(field, Origin{File: f.Name})
+		}
+	}
+}
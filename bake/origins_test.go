@@ -0,0 +1,115 @@
+package bake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOrigins(t *testing.T) {
+	files := []File{
+		{
+			Name: "docker-bake.hcl",
+			Data: []byte(`target "base" {
+  dockerfile = "base.Dockerfile"
+  args = {
+    GO_VERSION = "1.22"
+  }
+}
+
+target "app" {
+  inherits = ["base"]
+  tags = ["user/app:latest"]
+  args = {
+    APP = "1"
+  }
+  hooks {
+    pre = ["make generate"]
+  }
+}
+
+target "lint" {
+  name = "lint-${tgt}"
+  matrix = {
+    tgt = ["go", "docs"]
+  }
+  target = tgt
+}
+`),
+		},
+		{
+			Name: "docker-bake.override.hcl",
+			Data: []byte(`target "app" {
+  tags = ["user/app:dev"]
+}
+`),
+		},
+		{
+			Name: "docker-bake.override.json",
+			Data: []byte(`{
+  "target": {
+    "base": {
+      "platforms": ["linux/amd64"]
+    }
+  }
+}`),
+		},
+	}
+
+	m, err := ReadOrigins(files, []string{"app", "lint-go"}, []string{"app.platform=linux/arm64", "*.args.CI=1"}, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string][]string{
+		"dockerfile": {"docker-bake.hcl:2"},
+		"args":       {"docker-bake.hcl:3", "override base.args.CI", "docker-bake.hcl:11", "override app.args.CI"},
+		"platforms":  {"override app.platform"},
+		"tags":       {"docker-bake.override.hcl:2"},
+		"hooks":      {"docker-bake.hcl:14"},
+	}, stringOrigins(m["app"]))
+
+	require.Equal(t, map[string][]string{
+		"matrix": {"docker-bake.hcl:21"},
+		"name":   {"docker-bake.hcl:20"},
+		"target": {"docker-bake.hcl:24"},
+		"args":   {"override lint-go.args.CI"},
+	}, stringOrigins(m["lint-go"]))
+}
+
+func TestReadOriginsCompose(t *testing.T) {
+	files := []File{
+		{
+			Name: "compose.yaml",
+			Data: []byte(`services:
+  app:
+    build:
+      context: .
+      args:
+        FOO: bar
+`),
+		},
+		{
+			Name: "docker-bake.hcl",
+			Data: []byte(`target "app" {
+  args = {
+    BAR = "baz"
+  }
+}
+`),
+		},
+	}
+
+	m, err := ReadOrigins(files, []string{"app"}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"compose.yaml", "docker-bake.hcl:2"}, stringOrigins(m["app"])["args"])
+	require.Equal(t, []string{"compose.yaml"}, stringOrigins(m["app"])["context"])
+}
+
+func stringOrigins(to TargetOrigins) map[string][]string {
+	m := make(map[string][]string, len(to))
+	for field, origins := range to {
+		for _, o := range origins {
+			m[field] = append(m[field], o.String())
+		}
+	}
+	return m
+}
@@ -0,0 +1,194 @@
+package bake
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// ReadOverrideFiles reads the override files with the given names and
+// returns their overrides in the same format as the --set flag.
+func ReadOverrideFiles(names []string) ([]string, error) {
+	var overrides []string
+	for _, name := range names {
+		dt, err := os.ReadFile(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read override file")
+		}
+		o, err := ParseOverrideFile(dt, name)
+		if err != nil {
+			return nil, formatHCLError(err, []File{{Name: name, Data: dt}})
+		}
+		overrides = append(overrides, o...)
+	}
+	return overrides, nil
+}
+
+// ParseOverrideFile parses an HCL or JSON file defining a "set" attribute.
+// The attribute is either a list of "targetpattern.key=value" strings or a
+// map of "targetpattern.key" to a value or list of values.
+func ParseOverrideFile(dt []byte, fn string) ([]string, error) {
+	f, _, err := ParseHCLFile(dt, fn)
+	if err != nil {
+		return nil, err
+	}
+	content, diags := f.Body.Content(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "set", Required: true}},
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	attr := content.Attributes["set"]
+	v, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	if v.IsNull() {
+		return nil, nil
+	}
+
+	var overrides []string
+	switch {
+	case v.Type().IsListType(), v.Type().IsTupleType(), v.Type().IsSetType():
+		for it := v.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			s, err := overrideValue(ev)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid override in %s", fn)
+			}
+			overrides = append(overrides, s)
+		}
+	case v.Type().IsMapType(), v.Type().IsObjectType():
+		m := v.AsValueMap()
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ev := m[k]
+			if ev.Type().IsListType() || ev.Type().IsTupleType() || ev.Type().IsSetType() {
+				for it := ev.ElementIterator(); it.Next(); {
+					_, lv := it.Element()
+					s, err := overrideValue(lv)
+					if err != nil {
+						return nil, errors.Wrapf(err, "invalid value for override %s in %s", k, fn)
+					}
+					overrides = append(overrides, k+"="+s)
+				}
+				continue
+			}
+			s, err := overrideValue(ev)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid value for override %s in %s", k, fn)
+			}
+			overrides = append(overrides, k+"="+s)
+		}
+	default:
+		return nil, errors.Errorf("invalid set attribute in %s: expected list or map, got %s", fn, v.Type().FriendlyName())
+	}
+	return overrides, nil
+}
+
+func overrideValue(v cty.Value) (string, error) {
+	if v.IsNull() || !v.IsKnown() {
+		return "", errors.New("value must be known and not null")
+	}
+	sv, err := convert.Convert(v, cty.String)
+	if err != nil {
+		return "", err
+	}
+	return sv.AsString(), nil
+}
+
+// jsonOverridePrefix marks the overrides set with JSON values.
+const jsonOverridePrefix = "json:"
+
+// mapOverrideKeys are the map fields that can be replaced as a whole by a
+// JSON override.
+var mapOverrideKeys = map[string]struct{}{
+	"args":     {},
+	"contexts": {},
+	"labels":   {},
+}
+
+// JSONOverride returns the override "targetpattern.key=value" of the
+// --set-json flag, whose value is parsed as JSON and replaces the whole field.
+func JSONOverride(v string) string {
+	return jsonOverridePrefix + v
+}
+
+func (o *Override) setJSON(field string, nested bool, in string) error {
+	dec := json.NewDecoder(bytes.NewReader([]byte(in)))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+
+	_, isMap := mapOverrideKeys[field]
+	isMap = isMap && !nested
+	if _, ok := v.(map[string]interface{}); ok != isMap {
+		if isMap {
+			return errors.Errorf("%s expects an object", field)
+		}
+		return errors.Errorf("%s does not accept an object", field)
+	}
+
+	*o = Override{Replace: true}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		o.MapValue = make(map[string]string, len(v))
+		for k, ev := range v {
+			s, err := jsonScalar(ev)
+			if err != nil {
+				return errors.Wrapf(err, "invalid value for %s", k)
+			}
+			o.MapValue[k] = s
+		}
+	case []interface{}:
+		o.ArrValue = make([]string, 0, len(v))
+		for _, ev := range v {
+			s, err := jsonScalar(ev)
+			if err != nil {
+				return err
+			}
+			o.ArrValue = append(o.ArrValue, s)
+		}
+	default:
+		s, err := jsonScalar(v)
+		if err != nil {
+			return err
+		}
+		o.Value = s
+		o.ArrValue = []string{s}
+	}
+	return nil
+}
+
+func jsonScalar(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	case nil:
+		return "", errors.New("null is not supported")
+	default:
+		return "", errors.New("nested objects and lists are not supported")
+	}
+}
@@ -0,0 +1,108 @@
+package bake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOverrideFileHCL(t *testing.T) {
+	dt := []byte(`
+set = {
+  "*.platform" = ["linux/amd64", "linux/arm64"]
+  "app.args.VERSION" = "1.2.3"
+  "app.no-cache" = true
+}
+`)
+	overrides, err := ParseOverrideFile(dt, "overrides.hcl")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"*.platform=linux/amd64",
+		"*.platform=linux/arm64",
+		"app.args.VERSION=1.2.3",
+		"app.no-cache=true",
+	}, overrides)
+}
+
+func TestParseOverrideFileJSON(t *testing.T) {
+	dt := []byte(`{"set": ["app.tags=foo:latest", "app.tags=foo:1"]}`)
+	overrides, err := ParseOverrideFile(dt, "overrides.json")
+	require.NoError(t, err)
+	require.Equal(t, []string{"app.tags=foo:latest", "app.tags=foo:1"}, overrides)
+}
+
+func TestParseOverrideFileInvalid(t *testing.T) {
+	_, err := ParseOverrideFile([]byte(`foo = "bar"`), "overrides.hcl")
+	require.Error(t, err)
+
+	_, err = ParseOverrideFile([]byte(`set = "app.tags=foo"`), "overrides.hcl")
+	require.ErrorContains(t, err, "expected list or map")
+}
+
+func TestJSONOverride(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+target "app" {
+  args = {
+    A = "a"
+    B = "b"
+  }
+  contexts = {
+    foo = "bar"
+  }
+  platforms = ["linux/amd64"]
+  annotations = ["index:foo=bar"]
+}
+`),
+	}
+	m, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app"}, []string{
+		"app.args.C=c",
+		JSONOverride(`app.args={"A":"1","N":2,"T":true}`),
+		"app.args.D=d",
+		JSONOverride(`app.contexts={}`),
+		JSONOverride(`app.platform=["linux/arm64","linux/riscv64"]`),
+		JSONOverride(`app.annotations=["index:bar=baz"]`),
+		JSONOverride(`app.target="release"`),
+	}, nil, &EntitlementConf{})
+	require.NoError(t, err)
+
+	app := m["app"]
+	require.Equal(t, map[string]*string{
+		"A": ptrstr("1"),
+		"D": ptrstr("d"),
+		"N": ptrstr("2"),
+		"T": ptrstr("true"),
+	}, app.Args)
+	require.Empty(t, app.Contexts)
+	require.Equal(t, []string{"linux/arm64", "linux/riscv64"}, app.Platforms)
+	require.Equal(t, buildflags.Annotations{"index:bar=baz"}, app.Annotations)
+	require.Equal(t, Stages{"release"}, app.Target)
+}
+
+func TestJSONOverrideInvalid(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`target "app" {}`),
+	}
+	for _, tt := range []struct {
+		override string
+		err      string
+	}{
+		{override: `app.args=["a"]`, err: "args expects an object"},
+		{override: `app.platform={"a":"b"}`, err: "platform does not accept an object"},
+		{override: `app.args.A={"a":"b"}`, err: "args does not accept an object"},
+		{override: `app.args={"A":{"a":"b"}}`, err: "nested objects and lists are not supported"},
+		{override: `app.tags=[null]`, err: "null is not supported"},
+		{override: `app.tags=["a"`, err: "unexpected EOF"},
+		{override: `app.tags=["a"] x`, err: "unexpected data after JSON value"},
+		{override: `app.args`, err: "expected target.name=value"},
+	} {
+		t.Run(tt.override, func(t *testing.T) {
+			_, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app"}, []string{JSONOverride(tt.override)}, nil, &EntitlementConf{})
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
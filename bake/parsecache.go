@@ -0,0 +1,218 @@
+package bake
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/buildx/bake/hclparser"
+	"github.com/docker/buildx/util/confutil"
+	"github.com/docker/buildx/version"
+)
+
+// ParseCacheDir is the directory where parsed bake definitions are cached.
+// Caching is disabled when empty.
+var ParseCacheDir string
+
+const parseCacheSchema = "3"
+
+const (
+	// parseCacheMaxAge is how long an entry is kept after it was last used.
+	parseCacheMaxAge = 7 * 24 * time.Hour
+	// parseCacheMaxEntries is the number of most recently used entries kept.
+	parseCacheMaxEntries = 256
+)
+
+// impureFuncs are HCL functions whose result differs between invocations, or
+// depends on files that are not part of the definition. Definitions calling
+// them are never cached.
+var impureFuncs = [][]byte{
+	[]byte("timestamp("),
+	[]byte("uuidv4("),
+	[]byte("bcrypt("),
+	[]byte("git_branch("),
+	[]byte("templatefile("),
+}
+
+type parseCache struct {
+	path string
+
+	mu  sync.Mutex
+	env map[string]*string
+}
+
+type parseCacheEntry struct {
+	Env     map[string]*string   `json:"env"`
+	Groups  []parseCacheGroup    `json:"groups"`
+	Targets []parseCacheTarget   `json:"targets"`
+	Meta    *hclparser.ParseMeta `json:"meta"`
+}
+
+type parseCacheGroup struct {
+	Name  string `json:"name"`
+	Group *Group `json:"group"`
+}
+
+type parseCacheTarget struct {
+	Name   string  `json:"name"`
+	Target *Target `json:"target"`
+}
+
+func newParseCache(dir string, files []File, defaults map[string]string) *parseCache {
+	pc := &parseCache{
+		env: map[string]*string{},
+	}
+	if dir == "" || !cacheableFiles(files) {
+		return pc
+	}
+
+	h := sha256.New()
+	h.Write([]byte(parseCacheSchema + "\x00" + version.Version + "\x00" + version.Revision + "\x00"))
+	for _, f := range files {
+		h.Write([]byte(f.Name + "\x00"))
+		h.Write(f.Data)
+		h.Write([]byte{0})
+	}
+	keys := make([]string, 0, len(defaults))
+	for k := range defaults {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h.Write([]byte(k + "=" + defaults[k] + "\x00"))
+	}
+	pc.path = filepath.Join(dir, hex.EncodeToString(h.Sum(nil))+".json")
+	return pc
+}
+
+// cacheableFiles reports whether parsing files is deterministic for a given
+// set of environment variables. Compose files read the whole environment and
+// are therefore never cached.
+func cacheableFiles(files []File) bool {
+	for _, f := range files {
+		if isCompose, _ := validateComposeFile(f.Data, f.Name); isCompose {
+			return false
+		}
+		for _, fn := range impureFuncs {
+			if bytes.Contains(f.Data, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// lookupVar looks up an environment variable and records the result so the
+// cache entry can be invalidated when the environment changes.
+func (pc *parseCache) lookupVar(name string) (string, bool) {
+	v, ok := os.LookupEnv(name)
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if ok {
+		pc.env[name] = &v
+	} else {
+		pc.env[name] = nil
+	}
+	return v, ok
+}
+
+func (pc *parseCache) load() (*Config, *hclparser.ParseMeta, bool) {
+	if pc.path == "" {
+		return nil, nil, false
+	}
+	dt, err := os.ReadFile(pc.path)
+	if err != nil {
+		return nil, nil, false
+	}
+	var entry parseCacheEntry
+	if err := json.Unmarshal(dt, &entry); err != nil {
+		return nil, nil, false
+	}
+	for k, v := range entry.Env {
+		cur, ok := os.LookupEnv(k)
+		if ok != (v != nil) || (ok && cur != *v) {
+			return nil, nil, false
+		}
+	}
+
+	c := &Config{
+		Groups:  make([]*Group, 0, len(entry.Groups)),
+		Targets: make([]*Target, 0, len(entry.Targets)),
+	}
+	for _, g := range entry.Groups {
+		if g.Group == nil {
+			return nil, nil, false
+		}
+		g.Group.Name = g.Name
+		c.Groups = append(c.Groups, g.Group)
+	}
+	for _, t := range entry.Targets {
+		if t.Target == nil {
+			return nil, nil, false
+		}
+		t.Target.Name = t.Name
+		c.Targets = append(c.Targets, t.Target)
+	}
+	pm := entry.Meta
+	if pm == nil {
+		pm = &hclparser.ParseMeta{}
+	}
+	// mark the entry as used so that it isn't pruned
+	now := time.Now()
+	_ = os.Chtimes(pc.path, now, now)
+	return c, pm, true
+}
+
+// store writes the parse result to the cache and prunes the entries that
+// were not used recently. Failures are ignored as the cache is only an
+// optimization.
+func (pc *parseCache) store(c *Config, pm *hclparser.ParseMeta) {
+	if pc.path == "" {
+		return
+	}
+	pc.mu.Lock()
+	entry := parseCacheEntry{
+		Env:     pc.env,
+		Groups:  make([]parseCacheGroup, 0, len(c.Groups)),
+		Targets: make([]parseCacheTarget, 0, len(c.Targets)),
+		Meta:    pm,
+	}
+	pc.mu.Unlock()
+	for _, g := range c.Groups {
+		entry.Groups = append(entry.Groups, parseCacheGroup{Name: g.Name, Group: g})
+	}
+	for _, t := range c.Targets {
+		entry.Targets = append(entry.Targets, parseCacheTarget{Name: t.Name, Target: t})
+	}
+	dt, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(pc.path), 0755); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(pc.path), ".tmp-")
+	if err != nil {
+		return
+	}
+	if _, err := f.Write(dt); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), pc.path); err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	confutil.PruneCacheDir(filepath.Dir(pc.path), parseCacheMaxAge, parseCacheMaxEntries)
+}
@@ -0,0 +1,142 @@
+package bake
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCache(t *testing.T) {
+	dir := t.TempDir()
+	ParseCacheDir = dir
+	t.Cleanup(func() {
+		ParseCacheDir = ""
+	})
+	t.Setenv("TAG", "v1")
+
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+variable "TAG" {
+  default = "latest"
+}
+group "default" {
+  targets = ["app"]
+}
+target "app" {
+  tags = ["foo:${TAG}"]
+  args = {
+    EMPTY = null
+  }
+  attest = ["type=sbom,generator=foo"]
+  secret = ["id=mysecret,src=/tmp/secret"]
+  ssh = ["default"]
+  cache-to = ["type=local,dest=/tmp/cache"]
+  output = ["type=image,push=true"]
+}
+`),
+	}
+
+	c1, pm1, err := ParseFiles([]File{fp}, nil)
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	c2, pm2, err := ParseFiles([]File{fp}, nil)
+	require.NoError(t, err)
+	require.Equal(t, c1, c2)
+	require.Equal(t, pm1, pm2)
+	require.Equal(t, []string{"foo:v1"}, c2.Targets[0].Tags)
+
+	// changing an environment variable read during parsing invalidates the entry
+	t.Setenv("TAG", "v2")
+	c3, _, err := ParseFiles([]File{fp}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo:v2"}, c3.Targets[0].Tags)
+
+	// corrupted entries are ignored
+	for _, e := range entries {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, e.Name()), []byte("{"), 0644))
+	}
+	c4, _, err := ParseFiles([]File{fp}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo:v2"}, c4.Targets[0].Tags)
+}
+
+func TestParseCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	ParseCacheDir = dir
+	t.Cleanup(func() {
+		ParseCacheDir = ""
+	})
+
+	stale := filepath.Join(dir, "stale.json")
+	require.NoError(t, os.WriteFile(stale, []byte("{}"), 0644))
+	old := time.Now().Add(-parseCacheMaxAge - time.Hour)
+	require.NoError(t, os.Chtimes(stale, old, old))
+
+	_, _, err := ParseFiles([]File{{Name: "docker-bake.hcl", Data: []byte(`target "app" {}`)}}, nil)
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NotEqual(t, "stale.json", entries[0].Name())
+}
+
+func TestParseCacheImpure(t *testing.T) {
+	dir := t.TempDir()
+	ParseCacheDir = dir
+	t.Cleanup(func() {
+		ParseCacheDir = ""
+	})
+
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+target "app" {
+  tags = ["foo:${timestamp()}"]
+}
+`),
+	}
+
+	_, _, err := ParseFiles([]File{fp}, nil)
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestParseCacheTargetValidation(t *testing.T) {
+	dir := t.TempDir()
+	ParseCacheDir = dir
+	t.Cleanup(func() {
+		ParseCacheDir = ""
+	})
+
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+target "app" {
+  validation {
+    condition = length(target.tags) > 0
+    error_message = "tags required"
+  }
+}
+`),
+	}
+
+	c, _, err := ParseFiles([]File{fp}, nil)
+	require.NoError(t, err)
+	require.Len(t, c.Targets[0].Validations, 1)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
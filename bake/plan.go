@@ -0,0 +1,139 @@
+package bake
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/docker/buildx/build"
+	"github.com/pkg/errors"
+)
+
+// Plan is a machine-readable description of what a bake invocation is going
+// to build. It is meant to be reviewed before the build is executed.
+type Plan struct {
+	Group  map[string]*Group      `json:"group,omitempty"`
+	Target map[string]*PlanTarget `json:"target"`
+	// Entitlements lists the privileges requested by the targets, in the
+	// format of the --allow flag.
+	Entitlements []string `json:"entitlements,omitempty"`
+	// Remote is the URL of the remote definition of the targets.
+	Remote string `json:"remote,omitempty"`
+}
+
+// PlanTarget is a resolved target and the targets it depends on.
+type PlanTarget struct {
+	*Target
+	DependsOn []string `json:"depends-on,omitempty"`
+}
+
+// NewPlan returns the plan for the resolved targets and their build options.
+func NewPlan(tgts map[string]*Target, grps map[string]*Group, bo map[string]build.Options) (*Plan, error) {
+	p := &Plan{
+		Group:  grps,
+		Target: make(map[string]*PlanTarget, len(tgts)),
+	}
+	for name, t := range tgts {
+		pt := &PlanTarget{Target: t}
+		for _, v := range t.Contexts {
+			if dep, ok := strings.CutPrefix(v, "target:"); ok {
+				pt.DependsOn = append(pt.DependsOn, dep)
+			}
+		}
+		if pt.DependsOn != nil {
+			sort.Strings(pt.DependsOn)
+			pt.DependsOn = slices.Compact(pt.DependsOn)
+		}
+		p.Target[name] = pt
+	}
+
+	requested, err := EntitlementConf{}.Validate(bo)
+	if err != nil {
+		return nil, err
+	}
+	p.Entitlements = requested.List()
+	return p, nil
+}
+
+// ReadPlan reads a plan written with --plan-file.
+func ReadPlan(fn string) (*Plan, error) {
+	dt, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read plan file")
+	}
+	var p Plan
+	if err := json.Unmarshal(dt, &p); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse plan file %s", fn)
+	}
+	if len(p.Target) == 0 {
+		return nil, errors.Errorf("plan file %s has no targets", fn)
+	}
+	return &p, nil
+}
+
+// Targets returns the resolved targets and groups of the plan, to be built
+// without reading the definition again.
+func (p *Plan) Targets() (map[string]*Target, map[string]*Group, error) {
+	if p.Remote != "" {
+		return nil, nil, errors.Errorf("plan of remote definition %s cannot be built", p.Remote)
+	}
+	tgts := make(map[string]*Target, len(p.Target))
+	for name, pt := range p.Target {
+		if pt == nil || pt.Target == nil {
+			return nil, nil, errors.Errorf("invalid target %q in plan", name)
+		}
+		if err := validateQualifiedTargetName(name); err != nil {
+			return nil, nil, err
+		}
+		t := pt.Target
+		t.Name = name
+		tgts[name] = t
+	}
+	for _, pt := range p.Target {
+		for _, dep := range pt.DependsOn {
+			if _, ok := tgts[dep]; !ok {
+				return nil, nil, errors.Errorf("target %q depends on %q which is not in the plan", pt.Name, dep)
+			}
+		}
+	}
+	grps := p.Group
+	if grps == nil {
+		grps = map[string]*Group{}
+	}
+	for name, g := range grps {
+		if g == nil {
+			return nil, nil, errors.Errorf("invalid group %q in plan", name)
+		}
+		g.Name = name
+	}
+	return tgts, grps, nil
+}
+
+// List returns the entitlements in the format of the --allow flag.
+func (c EntitlementConf) List() []string {
+	var out []string
+	if c.NetworkHost {
+		out = append(out, string(EntitlementKeyNetworkHost))
+	}
+	if c.SecurityInsecure {
+		out = append(out, string(EntitlementKeySecurityInsecure))
+	}
+	if c.SSH {
+		out = append(out, string(EntitlementKeySSH))
+	}
+	for _, p := range c.FSRead {
+		out = append(out, string(EntitlementKeyFSRead)+"="+p)
+	}
+	for _, p := range c.FSWrite {
+		out = append(out, string(EntitlementKeyFSWrite)+"="+p)
+	}
+	for _, p := range c.ImagePush {
+		out = append(out, string(EntitlementKeyImagePush)+"="+p)
+	}
+	for _, p := range c.ImageLoad {
+		out = append(out, string(EntitlementKeyImageLoad)+"="+p)
+	}
+	return out
+}
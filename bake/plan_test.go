@@ -0,0 +1,133 @@
+package bake
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPlan(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "cache"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "base-cache"), 0755))
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+group "default" {
+  targets = ["app"]
+}
+target "base" {
+  dockerfile-inline = "FROM alpine"
+  cache-to = ["type=local,dest=` + filepath.ToSlash(filepath.Join(dir, "base-cache")) + `"]
+}
+target "app" {
+  contexts = {
+    base = "target:base"
+    alpine = "target:base"
+  }
+  args = {
+    FOO = "bar"
+  }
+  network = "host"
+  cache-to = ["type=local,dest=` + filepath.ToSlash(filepath.Join(dir, "cache")) + `"]
+}
+`),
+	}
+
+	ctx := context.TODO()
+	tgts, grps, err := ReadTargets(ctx, []File{fp}, []string{"default"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+
+	bo, err := TargetsToBuildOpt(tgts, &Input{})
+	require.NoError(t, err)
+
+	plan, err := NewPlan(tgts, grps, bo)
+	require.NoError(t, err)
+
+	require.Contains(t, plan.Group, "default")
+	require.Len(t, plan.Target, 2)
+	require.Equal(t, []string{"base"}, plan.Target["app"].DependsOn)
+	require.Empty(t, plan.Target["base"].DependsOn)
+	require.Equal(t, "bar", *plan.Target["app"].Args["FOO"])
+
+	expDir, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	expWd, err := filepath.EvalSymlinks(wd)
+	require.NoError(t, err)
+
+	require.Contains(t, plan.Entitlements, "network.host")
+	require.Contains(t, plan.Entitlements, "fs.read="+expWd)
+	require.Contains(t, plan.Entitlements, "fs.write="+filepath.Join(expDir, "cache"))
+	require.Contains(t, plan.Entitlements, "fs.write="+filepath.Join(expDir, "base-cache"))
+}
+
+func TestReadPlan(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+group "default" {
+  targets = ["app"]
+}
+target "base" {
+  dockerfile-inline = "FROM alpine"
+}
+target "app" {
+  contexts = {
+    base = "target:base"
+  }
+  args = {
+    FOO = "bar"
+  }
+  tags = ["user/app:latest"]
+  output = ["type=registry"]
+}
+`),
+	}
+
+	tgts, grps, err := ReadTargets(context.TODO(), []File{fp}, []string{"default"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	bo, err := TargetsToBuildOpt(tgts, &Input{})
+	require.NoError(t, err)
+	plan, err := NewPlan(tgts, grps, bo)
+	require.NoError(t, err)
+
+	fn := filepath.Join(t.TempDir(), "plan.json")
+	dt, err := json.Marshal(plan)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(fn, dt, 0644))
+
+	plan, err = ReadPlan(fn)
+	require.NoError(t, err)
+	tgts2, grps2, err := plan.Targets()
+	require.NoError(t, err)
+	require.Len(t, tgts2, 2)
+	require.Equal(t, "app", tgts2["app"].Name)
+	require.Equal(t, tgts["app"].Contexts, tgts2["app"].Contexts)
+	require.Equal(t, grps, grps2)
+
+	bo2, err := TargetsToBuildOpt(tgts2, nil)
+	require.NoError(t, err)
+	require.Equal(t, bo["app"].BuildArgs, bo2["app"].BuildArgs)
+	require.Equal(t, bo["app"].Tags, bo2["app"].Tags)
+	require.Equal(t, bo["app"].Exports, bo2["app"].Exports)
+
+	plan.Remote = "https://github.com/docker/buildx.git"
+	_, _, err = plan.Targets()
+	require.ErrorContains(t, err, "plan of remote definition https://github.com/docker/buildx.git cannot be built")
+
+	require.NoError(t, os.WriteFile(fn, []byte(`{"target":{"app":{"context":".","depends-on":["base"]}}}`), 0644))
+	plan, err = ReadPlan(fn)
+	require.NoError(t, err)
+	_, _, err = plan.Targets()
+	require.ErrorContains(t, err, `target "app" depends on "base" which is not in the plan`)
+
+	require.NoError(t, os.WriteFile(fn, []byte(`{"target":{}}`), 0644))
+	_, err = ReadPlan(fn)
+	require.ErrorContains(t, err, "has no targets")
+}
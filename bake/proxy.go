@@ -0,0 +1,101 @@
+package bake
+
+import (
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/pkg/errors"
+)
+
+// ProxyCASecretID is the ID of the secret holding the CA certificate set by
+// the proxy of a target.
+const ProxyCASecretID = "proxy-ca"
+
+// TargetProxy is the proxy used by the build of a target. Its URLs are set
+// as the predefined proxy build arguments, and its CA certificate as the
+// secret named ProxyCASecretID.
+type TargetProxy struct {
+	HTTP    *string `json:"http,omitempty" hcl:"http,optional" cty:"http"`
+	HTTPS   *string `json:"https,omitempty" hcl:"https,optional" cty:"https"`
+	NoProxy *string `json:"no_proxy,omitempty" hcl:"no_proxy,optional" cty:"no_proxy"`
+	CAFile  *string `json:"ca_file,omitempty" hcl:"ca_file,optional" cty:"ca_file"`
+}
+
+// merge returns p with the fields set on p2 overridden, so a target can
+// override a part of the proxy it inherits.
+func (p *TargetProxy) merge(p2 *TargetProxy) *TargetProxy {
+	if p == nil {
+		v := *p2
+		return &v
+	}
+	v := *p
+	if p2.HTTP != nil {
+		v.HTTP = p2.HTTP
+	}
+	if p2.HTTPS != nil {
+		v.HTTPS = p2.HTTPS
+	}
+	if p2.NoProxy != nil {
+		v.NoProxy = p2.NoProxy
+	}
+	if p2.CAFile != nil {
+		v.CAFile = p2.CAFile
+	}
+	return &v
+}
+
+func (p *TargetProxy) set(key, value string) error {
+	switch key {
+	case "http":
+		p.HTTP = &value
+	case "https":
+		p.HTTPS = &value
+	case "no_proxy":
+		p.NoProxy = &value
+	case "ca_file":
+		p.CAFile = &value
+	default:
+		return errors.Errorf("unknown proxy key %q, expecting http, https, no_proxy or ca_file", key)
+	}
+	return nil
+}
+
+// applyProxy sets the build arguments and the secret of the proxy of the
+// target. The build arguments and the secret set on the target are kept.
+func (t *Target) applyProxy() {
+	if t.Proxy == nil {
+		return
+	}
+	args := []struct {
+		names []string
+		value *string
+	}{
+		{[]string{"HTTP_PROXY", "http_proxy"}, t.Proxy.HTTP},
+		{[]string{"HTTPS_PROXY", "https_proxy"}, t.Proxy.HTTPS},
+		{[]string{"NO_PROXY", "no_proxy"}, t.Proxy.NoProxy},
+	}
+	for _, a := range args {
+		if a.value == nil || *a.value == "" {
+			continue
+		}
+		for _, name := range a.names {
+			if _, ok := t.Args[name]; ok {
+				continue
+			}
+			if t.Args == nil {
+				t.Args = map[string]*string{}
+			}
+			v := *a.value
+			t.Args[name] = &v
+		}
+	}
+	if t.Proxy.CAFile != nil && *t.Proxy.CAFile != "" {
+		for _, s := range t.Secrets {
+			if s.ID == ProxyCASecretID {
+				return
+			}
+		}
+		t.Secrets = append(t.Secrets, &buildflags.Secret{
+			ID:       ProxyCASecretID,
+			FilePath: *t.Proxy.CAFile,
+		})
+	}
+}
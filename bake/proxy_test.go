@@ -0,0 +1,69 @@
+package bake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTargetsProxy(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+		target "_proxy" {
+			proxy {
+				http = "http://proxy.corp:3128"
+				https = "http://proxy.corp:3128"
+				no_proxy = "localhost,.corp"
+				ca_file = "corp-ca.pem"
+			}
+		}
+		target "app" {
+			inherits = ["_proxy"]
+			args = {
+				no_proxy = "localhost"
+			}
+			proxy {
+				https = "http://secure.corp:3129"
+			}
+		}
+		target "db" {
+			inherits = ["_proxy"]
+			secret = ["id=proxy-ca,src=other-ca.pem"]
+		}
+		target "web" {
+		}
+		`),
+	}
+
+	m, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app", "db", "web"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+
+	app := m["app"]
+	require.Equal(t, "http://proxy.corp:3128", *app.Proxy.HTTP)
+	require.Equal(t, "http://secure.corp:3129", *app.Proxy.HTTPS)
+	require.Equal(t, "corp-ca.pem", *app.Proxy.CAFile)
+	require.Equal(t, "http://proxy.corp:3128", *app.Args["HTTP_PROXY"])
+	require.Equal(t, "http://proxy.corp:3128", *app.Args["http_proxy"])
+	require.Equal(t, "http://secure.corp:3129", *app.Args["HTTPS_PROXY"])
+	require.Equal(t, "http://secure.corp:3129", *app.Args["https_proxy"])
+	require.Equal(t, "localhost,.corp", *app.Args["NO_PROXY"])
+	require.Equal(t, "localhost", *app.Args["no_proxy"])
+	require.Equal(t, buildflags.Secrets{{ID: ProxyCASecretID, FilePath: "corp-ca.pem"}}, app.Secrets)
+
+	require.Equal(t, buildflags.Secrets{{ID: ProxyCASecretID, FilePath: "other-ca.pem"}}, m["db"].Secrets)
+
+	require.Nil(t, m["web"].Proxy)
+	require.Empty(t, m["web"].Args)
+	require.Empty(t, m["web"].Secrets)
+
+	m, _, err = ReadTargets(context.TODO(), []File{fp}, []string{"web"}, []string{"web.proxy.http=http://other:3128"}, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, "http://other:3128", *m["web"].Args["HTTP_PROXY"])
+	require.Empty(t, m["web"].Secrets)
+
+	_, _, err = ReadTargets(context.TODO(), []File{fp}, []string{"web"}, []string{"web.proxy.ftp=ftp://other"}, nil, &EntitlementConf{})
+	require.ErrorContains(t, err, "unknown proxy key")
+}
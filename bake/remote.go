@@ -5,11 +5,14 @@ import (
 	"bytes"
 	"context"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/docker/buildx/builder"
 	controllerapi "github.com/docker/buildx/controller/pb"
 	"github.com/docker/buildx/driver"
+	"github.com/docker/buildx/util/gitutil"
 	"github.com/docker/buildx/util/progress"
 	"github.com/docker/go-units"
 	"github.com/moby/buildkit/client"
@@ -25,12 +28,35 @@ const maxBakeDefinitionSize = 2 * 1024 * 1024 // 2 MB
 type Input struct {
 	State *llb.State
 	URL   string
+	// Dir is the local checkout of a remote definition cloned with clone
+	// options. Relative contexts are resolved against it.
+	Dir string
+	// Root is the directory to remove once the build is done.
+	Root string
 }
 
-func ReadRemoteFiles(ctx context.Context, nodes []builder.Node, url string, names []string, pw progress.Writer) ([]File, *Input, error) {
+// IsRemote returns whether the input is a remote definition.
+func (inp *Input) IsRemote() bool {
+	return inp != nil && (inp.State != nil || inp.Dir != "")
+}
+
+func ReadRemoteFiles(ctx context.Context, nodes []builder.Node, url string, names []string, profile string, pw progress.Writer) ([]File, *Input, error) {
+	if len(names) == 0 {
+		if err := validateProfile(profile); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	var sessions []session.Attachable
 	var filename string
 
+	url, subpath := splitRemoteSubpath(url)
+	if ref, err := gitutil.ParseCloneRef(url); err != nil {
+		return nil, nil, err
+	} else if ref != nil {
+		return readClonedFiles(ctx, ref, subpath, names, profile, pw)
+	}
+
 	st, ok := dockerui.DetectGitContext(url, false)
 	if ok {
 		if ssh, err := controllerapi.CreateSSH([]*controllerapi.SSH{{
@@ -103,9 +129,9 @@ func ReadRemoteFiles(ctx context.Context, nodes []builder.Node, url string, name
 		}
 
 		if filename != "" {
-			files, err = filesFromURLRef(ctx, c, ref, inp, filename, names)
+			files, err = filesFromURLRef(ctx, c, ref, inp, filename, names, profile)
 		} else {
-			files, err = filesFromRef(ctx, ref, names)
+			files, err = filesFromRef(ctx, ref, subpath, names, profile)
 		}
 		return nil, err
 	}, ch)
@@ -135,7 +161,7 @@ func isArchive(header []byte) bool {
 	return err == nil
 }
 
-func filesFromURLRef(ctx context.Context, c gwclient.Client, ref gwclient.Reference, inp *Input, filename string, names []string) ([]File, error) {
+func filesFromURLRef(ctx context.Context, c gwclient.Client, ref gwclient.Reference, inp *Input, filename string, names []string, profile string) ([]File, error) {
 	stat, err := ref.StatFile(ctx, gwclient.StatRequest{Path: filename})
 	if err != nil {
 		return nil, err
@@ -173,7 +199,7 @@ func filesFromURLRef(ctx context.Context, c gwclient.Client, ref gwclient.Refere
 			return nil, err
 		}
 
-		return filesFromRef(ctx, ref, names)
+		return filesFromRef(ctx, ref, "", names, profile)
 	}
 
 	inp.State = nil
@@ -196,17 +222,41 @@ func filesFromURLRef(ctx context.Context, c gwclient.Client, ref gwclient.Refere
 	return []File{{Name: name, Data: dt}}, nil
 }
 
-func filesFromRef(ctx context.Context, ref gwclient.Reference, names []string) ([]File, error) {
+// filesFromRef reads the definition files from ref. If subpath is set, it
+// selects either a definition file, or the directory to look up definition
+// files from. Names are relative to that directory.
+func filesFromRef(ctx context.Context, ref gwclient.Reference, subpath string, names []string, profile string) ([]File, error) {
 	// TODO: auto-remove parent dir in needed
 	var files []File
 
+	var dir string
+	var subfile bool
+	if subpath != "" {
+		st, err := ref.StatFile(ctx, gwclient.StatRequest{Path: subpath})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to stat definition subpath %s", subpath)
+		}
+		if os.FileMode(st.Mode).IsDir() {
+			dir = subpath
+		} else {
+			dir, subfile = path.Dir(subpath), true
+		}
+	}
+
 	isDefault := false
-	if len(names) == 0 {
+	if len(names) == 0 && !subfile {
 		isDefault = true
-		names = defaultFilenames()
+		names = defaultFilenames(profile)
+	}
+	var paths []string
+	if subfile {
+		paths = append(paths, subpath)
 	}
-
 	for _, name := range names {
+		paths = append(paths, path.Join(dir, name))
+	}
+
+	for _, name := range paths {
 		_, err := ref.StatFile(ctx, gwclient.StatRequest{Path: name})
 		if err != nil {
 			if isDefault {
@@ -221,5 +271,88 @@ func filesFromRef(ctx context.Context, ref gwclient.Reference, names []string) (
 		files = append(files, File{Name: name, Data: dt})
 	}
 
+	if isDefault {
+		if err := checkProfileFiles(profile, files); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// readClonedFiles clones a remote definition with clone options, such as
+// "?depth=1&sparse=services/api", on the client and reads the definition files
+// from the checkout.
+func readClonedFiles(ctx context.Context, ref *gitutil.CloneRef, subpath string, names []string, profile string, pw progress.Writer) ([]File, *Input, error) {
+	root, err := os.MkdirTemp("", "buildx-bake-git")
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []File
+	err = progress.Wrap("[internal] cloning "+ref.String(), pw.Write, func(progress.SubLogger) error {
+		if err := gitutil.Clone(ctx, ref.Remote, ref.Ref, root, ref.Opts); err != nil {
+			return err
+		}
+		files, err = filesFromDir(filepath.Join(root, filepath.FromSlash(ref.SubDir)), subpath, names, profile)
+		return err
+	})
+	if err != nil {
+		_ = os.RemoveAll(root)
+		return nil, nil, err
+	}
+	return files, &Input{
+		URL:  ref.URL,
+		Dir:  filepath.Join(root, filepath.FromSlash(ref.SubDir)),
+		Root: root,
+	}, nil
+}
+
+// filesFromDir reads the definition files from a local checkout the same way
+// filesFromRef does.
+func filesFromDir(root string, subpath string, names []string, profile string) ([]File, error) {
+	var files []File
+
+	var dir string
+	var subfile bool
+	if subpath != "" {
+		st, err := os.Stat(filepath.Join(root, filepath.FromSlash(subpath)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to stat definition subpath %s", subpath)
+		}
+		if st.IsDir() {
+			dir = subpath
+		} else {
+			dir, subfile = path.Dir(subpath), true
+		}
+	}
+
+	isDefault := false
+	if len(names) == 0 && !subfile {
+		isDefault = true
+		names = defaultFilenames(profile)
+	}
+	var paths []string
+	if subfile {
+		paths = append(paths, subpath)
+	}
+	for _, name := range names {
+		paths = append(paths, path.Join(dir, name))
+	}
+
+	for _, name := range paths {
+		dt, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			if isDefault && os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		files = append(files, File{Name: name, Data: dt})
+	}
+
+	if isDefault {
+		if err := checkProfileFiles(profile, files); err != nil {
+			return nil, err
+		}
+	}
 	return files, nil
 }
@@ -0,0 +1,67 @@
+package bake
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesFromDir(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "services", "api"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docker-bake.hcl"), []byte(`target "default" {}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "services", "api", "docker-bake.hcl"), []byte(`target "api" {}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "services", "api", "bake.json"), []byte(`{}`), 0644))
+
+	files, err := filesFromDir(root, "", nil, "")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "docker-bake.hcl", files[0].Name)
+
+	files, err = filesFromDir(root, "services/api", nil, "")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "services/api/docker-bake.hcl", files[0].Name)
+
+	files, err = filesFromDir(root, "services/api/bake.json", nil, "")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "services/api/bake.json", files[0].Name)
+
+	_, err = filesFromDir(root, "services/web", nil, "")
+	require.ErrorContains(t, err, "failed to stat definition subpath services/web")
+
+	_, err = filesFromDir(root, "", []string{"missing.hcl"}, "")
+	require.Error(t, err)
+}
+
+func TestClonedContext(t *testing.T) {
+	dir := t.TempDir()
+	m := map[string]*Target{
+		"app": {
+			Name:       "app",
+			Context:    ptrstr("services/api"),
+			Dockerfile: ptrstr("Dockerfile.prod"),
+			Contexts: map[string]string{
+				"shared": "libs/shared",
+				"base":   "docker-image://alpine",
+				"local":  "cwd://vendor",
+			},
+		},
+		"root": {Name: "root"},
+	}
+	bo, err := TargetsToBuildOpt(m, &Input{URL: "https://github.com/docker/buildx.git", Dir: dir})
+	require.NoError(t, err)
+
+	inp := bo["app"].Inputs
+	require.Equal(t, filepath.Join(dir, "services", "api"), inp.ContextPath)
+	require.Equal(t, filepath.Join(dir, "services", "api", "Dockerfile.prod"), filepath.FromSlash(inp.DockerfilePath))
+	require.Nil(t, inp.ContextState)
+	require.Equal(t, filepath.Join(dir, "libs", "shared"), inp.NamedContexts["shared"].Path)
+	require.Equal(t, "docker-image://alpine", inp.NamedContexts["base"].Path)
+	require.Equal(t, "vendor", inp.NamedContexts["local"].Path)
+
+	require.Equal(t, dir, bo["root"].Inputs.ContextPath)
+}
@@ -0,0 +1,171 @@
+package bake
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Stages is the stage of the Dockerfile to build for a target, or a list of
+// stages. A target with several stages is expanded into one target for each
+// stage when the targets are read.
+type Stages []string
+
+func (s *Stages) FromCtyValue(in cty.Value, p cty.Path) error {
+	if in.IsNull() {
+		*s = nil
+		return nil
+	}
+	got := in.Type()
+	if got == cty.String {
+		*s = Stages{in.AsString()}
+		return nil
+	}
+	if !got.IsTupleType() && !got.IsListType() {
+		return p.NewErrorf("%s", convert.MismatchMessage(got, cty.List(cty.String)))
+	}
+	stages := make(Stages, 0, in.LengthInt())
+	for elem := in.ElementIterator(); elem.Next(); {
+		_, v := elem.Element()
+		v, err := convert.Convert(v, cty.String)
+		if err != nil || v.IsNull() {
+			return p.NewErrorf("stages must be strings")
+		}
+		stages = append(stages, v.AsString())
+	}
+	*s = stages
+	return nil
+}
+
+// ToCtyValue returns a single stage as a string, so that references to the
+// target field of a target are unchanged, and several stages as a list.
+func (s Stages) ToCtyValue() cty.Value {
+	switch len(s) {
+	case 0:
+		return cty.NullVal(cty.DynamicPseudoType)
+	case 1:
+		return cty.StringVal(s[0])
+	}
+	vals := make([]cty.Value, len(s))
+	for i, v := range s {
+		vals[i] = cty.StringVal(v)
+	}
+	return cty.ListVal(vals)
+}
+
+func (s Stages) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 {
+		return json.Marshal(s[0])
+	}
+	return json.Marshal([]string(s))
+}
+
+func (s *Stages) UnmarshalJSON(dt []byte) error {
+	var v string
+	if err := json.Unmarshal(dt, &v); err == nil {
+		*s = Stages{v}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(dt, &l); err != nil {
+		return err
+	}
+	*s = l
+	return nil
+}
+
+// expandStages replaces the targets building several stages with a target for
+// each stage, named after the target and the stage. The output destinations
+// of each expanded target get the stage appended so they don't overlap, and
+// the groups referencing an expanded target reference all of them instead. A
+// group named after the original target is added if it was requested.
+func expandStages(m map[string]*Target, n map[string]*Group, requested []string) error {
+	expanded := map[string][]string{}
+	for _, name := range sortedKeys(m) {
+		t := m[name]
+		if len(t.Target) <= 1 {
+			continue
+		}
+		names := make([]string, 0, len(t.Target))
+		for _, stage := range t.Target {
+			sname := sanitizeTargetName(name + "-" + stage)
+			if _, ok := m[sname]; ok {
+				return errors.Errorf("target %s for stage %s of target %s is already defined", sname, stage, name)
+			}
+			t2 := &Target{Name: sname}
+			t2.Merge(t)
+			t2.Inherits = nil
+			t2.Target = Stages{stage}
+			t2.Outputs = stageOutputs(t.Outputs, stage)
+			t2.linked = t.linked
+			m[sname] = t2
+			names = append(names, sname)
+		}
+		delete(m, name)
+		expanded[name] = names
+	}
+	if len(expanded) == 0 {
+		return nil
+	}
+
+	for _, tname := range sortedKeys(m) {
+		t := m[tname]
+		for _, k := range sortedKeys(t.Contexts) {
+			if name, ok := strings.CutPrefix(t.Contexts[k], "target:"); ok {
+				if _, ok := expanded[name]; ok {
+					return errors.Errorf("target %s builds several stages and can't be used as context %s of target %s", name, k, t.Name)
+				}
+			}
+		}
+	}
+	for name, g := range n {
+		var targets []string
+		for _, t := range g.Targets {
+			if names, ok := expanded[t]; ok {
+				targets = append(targets, names...)
+			} else {
+				targets = append(targets, t)
+			}
+		}
+		// groups are shared with the cached definition so they are copied
+		g2 := *g
+		g2.Targets = targets
+		n[name] = &g2
+	}
+	for _, name := range requested {
+		if names, ok := expanded[name]; ok {
+			if _, ok := n[name]; !ok {
+				n[name] = &Group{Name: name, Targets: names}
+			}
+		}
+	}
+	return nil
+}
+
+// stageOutputs returns the outputs of an expanded target, with the stage
+// appended to the local destinations.
+func stageOutputs(outputs buildflags.Exports, stage string) buildflags.Exports {
+	if outputs == nil {
+		return nil
+	}
+	out := make(buildflags.Exports, len(outputs))
+	for i, o := range outputs {
+		o2 := *o
+		if o2.Destination != "" && o2.Destination != "-" {
+			switch o2.Type {
+			case "local":
+				o2.Destination = filepath.Join(o2.Destination, stage)
+			case "tar", "oci", "docker":
+				ext := filepath.Ext(o2.Destination)
+				o2.Destination = strings.TrimSuffix(o2.Destination, ext) + "-" + stage + ext
+			}
+		}
+		out[i] = &o2
+	}
+	return out
+}
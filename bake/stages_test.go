@@ -0,0 +1,157 @@
+package bake
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTargetsStages(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+group "default" {
+  targets = ["artifacts", "app"]
+}
+
+target "artifacts" {
+  target = ["binaries", "test-results"]
+  args = {
+    FOO = "bar"
+  }
+  output = ["type=local,dest=./out", "type=tar,dest=./out.tar"]
+}
+
+target "app" {
+  target = "release"
+}
+`),
+	}
+
+	ctx := context.TODO()
+
+	m, g, err := ReadTargets(ctx, []File{fp}, []string{"default"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(m))
+	require.NotContains(t, m, "artifacts")
+	require.Equal(t, []string{"artifacts-binaries", "artifacts-test-results", "app"}, g["default"].Targets)
+
+	bin := m["artifacts-binaries"]
+	require.Equal(t, "artifacts-binaries", bin.Name)
+	require.Equal(t, Stages{"binaries"}, bin.Target)
+	require.Equal(t, ptrstr("bar"), bin.Args["FOO"])
+	require.Equal(t, "out/binaries", bin.Outputs[0].Destination)
+	require.Equal(t, "./out-binaries.tar", bin.Outputs[1].Destination)
+
+	res := m["artifacts-test-results"]
+	require.Equal(t, Stages{"test-results"}, res.Target)
+	require.Equal(t, "out/test-results", res.Outputs[0].Destination)
+	require.Equal(t, "./out-test-results.tar", res.Outputs[1].Destination)
+
+	require.Equal(t, Stages{"release"}, m["app"].Target)
+
+	// the cached definition is not changed by the expansion
+	_, g, err = ReadTargets(ctx, []File{fp}, []string{"artifacts"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"artifacts-binaries", "artifacts-test-results"}, g["artifacts"].Targets)
+	require.Equal(t, []string{"artifacts-binaries", "artifacts-test-results"}, g["default"].Targets)
+}
+
+func TestStagesBuildOpt(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+target "artifacts" {
+  target = ["binaries", "test-results"]
+}
+`),
+	}
+
+	m, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"artifacts"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+
+	bo, err := TargetsToBuildOpt(m, &Input{})
+	require.NoError(t, err)
+	require.Equal(t, "binaries", bo["artifacts-binaries"].Target)
+	require.Equal(t, "test-results", bo["artifacts-test-results"].Target)
+
+	_, err = TargetsToBuildOpt(map[string]*Target{"artifacts": {Name: "artifacts", Target: Stages{"binaries", "test-results"}}}, &Input{})
+	require.ErrorContains(t, err, "target artifacts builds several stages")
+}
+
+func TestReadTargetsStagesOverride(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+target "artifacts" {
+  target = ["binaries", "test-results"]
+}
+`),
+	}
+
+	m, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"artifacts"}, []string{"artifacts.target=binaries"}, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(m))
+	require.Equal(t, Stages{"binaries"}, m["artifacts"].Target)
+}
+
+func TestReadTargetsStagesContext(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+target "artifacts" {
+  target = ["binaries", "test-results"]
+}
+
+target "app" {
+  contexts = {
+    base = "target:artifacts"
+  }
+}
+`),
+	}
+
+	_, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app"}, nil, nil, &EntitlementConf{})
+	require.ErrorContains(t, err, "target artifacts builds several stages")
+}
+
+func TestStagesReference(t *testing.T) {
+	dt := []byte(`
+target "base" {
+  target = "build"
+}
+
+target "app" {
+  target = target.base.target
+  tags = ["app:${target.base.target}"]
+}
+`)
+
+	c, err := ParseFile(dt, "docker-bake.hcl")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(c.Targets))
+	require.Equal(t, Stages{"build"}, c.Targets[1].Target)
+	require.Equal(t, []string{"app:build"}, c.Targets[1].Tags)
+}
+
+func TestStagesJSON(t *testing.T) {
+	dt, err := json.Marshal(&Target{Target: Stages{"release"}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"target":"release"}`, string(dt))
+
+	dt, err = json.Marshal(&Target{Target: Stages{"binaries", "test-results"}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"target":["binaries","test-results"]}`, string(dt))
+
+	var tgt Target
+	require.NoError(t, json.Unmarshal([]byte(`{"target":"release"}`), &tgt))
+	require.Equal(t, Stages{"release"}, tgt.Target)
+	require.NoError(t, json.Unmarshal([]byte(`{"target":["binaries","test-results"]}`), &tgt))
+	require.Equal(t, Stages{"binaries", "test-results"}, tgt.Target)
+
+	c, err := ParseFile([]byte(`{"target":{"artifacts":{"target":["binaries","test-results"]}}}`), "docker-bake.json")
+	require.NoError(t, err)
+	require.Equal(t, Stages{"binaries", "test-results"}, c.Targets[0].Target)
+}
@@ -0,0 +1,114 @@
+package bake
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/pkg/errors"
+)
+
+// TargetTypeTest is the type of the targets that don't build an image but
+// run a command in the image of their source, and fail if the command exits
+// with a non-zero status.
+const TargetTypeTest = "test"
+
+// testSourceContext is the named context holding the image of the source of
+// a test target in the Dockerfile running its command.
+const testSourceContext = "bake-test-source"
+
+// TargetTest is the command run by a test target in the image of its source.
+type TargetTest struct {
+	Command []string          `json:"command" hcl:"command" cty:"command"`
+	Env     map[string]string `json:"env,omitempty" hcl:"env,optional" cty:"env"`
+}
+
+// IsTest returns whether the target runs a command in the image of its
+// source.
+func (t *Target) IsTest() bool {
+	return t.Type != nil && *t.Type == TargetTypeTest
+}
+
+// prepareTest sets the build of a test target to a Dockerfile running its
+// command on the image of its source, which is linked as a named context so
+// that it is built first. The result of the build is only kept in the cache,
+// so the tags and outputs of the target, such as the ones set by --push, are
+// ignored.
+func (t *Target) prepareTest(name string) error {
+	if len(t.Sources) != 1 {
+		return errors.Errorf("test target %s requires a single source", name)
+	}
+	if t.Test == nil || len(t.Test.Command) == 0 {
+		return errors.Errorf("test target %s requires a test command", name)
+	}
+	dt, err := testDockerfile(t.Test)
+	if err != nil {
+		return errors.Wrapf(err, "invalid test of target %s", name)
+	}
+	t.DockerfileInline = &dt
+	t.Dockerfile = nil
+	t.Target = nil
+	t.Tags = nil
+	t.Contexts = map[string]string{
+		testSourceContext: "target:" + t.Sources[0],
+	}
+	t.Outputs = buildflags.Exports{
+		{Type: "cacheonly"},
+	}
+	return nil
+}
+
+// testDockerfile returns the Dockerfile running the command of a test in the
+// image of the source of the target, with its environment variables.
+func testDockerfile(test *TargetTest) (string, error) {
+	var b strings.Builder
+	b.WriteString("FROM " + testSourceContext + "\n")
+
+	keys := make([]string, 0, len(test.Env))
+	for k := range test.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := test.Env[k]
+		if k == "" || strings.ContainsAny(k, "= \t\n\"") {
+			return "", errors.Errorf("invalid environment variable name %q", k)
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return "", errors.Errorf("environment variable %s can't contain line breaks", k)
+		}
+		v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(v)
+		b.WriteString("ENV " + k + "=\"" + v + "\"\n")
+	}
+
+	cmd, err := json.Marshal(test.Command)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString("RUN " + string(cmd) + "\n")
+	return b.String(), nil
+}
+
+// checkTestSources checks that the sources of the test targets build an
+// image, and sets the platforms of the test targets that don't have any to
+// the platforms of their source.
+func checkTestSources(m map[string]*Target) error {
+	for _, name := range sortedKeys(m) {
+		t := m[name]
+		if !t.IsTest() {
+			continue
+		}
+		src, ok := m[t.Sources[0]]
+		if !ok {
+			return errors.Errorf("source %s of test target %s not found", t.Sources[0], name)
+		}
+		if src.IsIndex() || src.IsTest() {
+			return errors.Errorf("source %s of test target %s must build an image", t.Sources[0], name)
+		}
+		if len(t.Platforms) == 0 {
+			t.Platforms = src.Platforms
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package bake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTargetsTest(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+target "app" {
+  platforms = ["linux/amd64", "linux/arm64"]
+  tags = ["user/app:latest"]
+}
+
+target "app-test" {
+  type = "test"
+  sources = ["app"]
+  test {
+    command = ["/app", "--self-test"]
+    env = {
+      LOG_LEVEL = "debug"
+      GREETING = "say \"hi\" to $USER"
+    }
+  }
+}
+`),
+	}
+
+	ctx := context.TODO()
+
+	m, _, err := ReadTargets(ctx, []File{fp}, []string{"app-test"}, nil, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(m))
+
+	tt := m["app-test"]
+	require.True(t, tt.IsTest())
+	require.Equal(t, map[string]string{"bake-test-source": "target:app"}, tt.Contexts)
+	require.Equal(t, []string{"linux/amd64", "linux/arm64"}, tt.Platforms)
+	require.Equal(t, "cacheonly", tt.Outputs[0].Type)
+	require.Equal(t, `FROM bake-test-source
+ENV GREETING="say \"hi\" to \$USER"
+ENV LOG_LEVEL="debug"
+RUN ["/app","--self-test"]
+`, *tt.DockerfileInline)
+	require.Equal(t, "cacheonly", m["app"].Outputs[0].Type)
+
+	m, _, err = ReadTargets(ctx, []File{fp}, []string{"app-test"}, []string{"*.push=true"}, nil, &EntitlementConf{})
+	require.NoError(t, err)
+	require.Equal(t, "cacheonly", m["app-test"].Outputs[0].Type)
+	require.Empty(t, m["app-test"].Tags)
+
+	bo, err := TargetsToBuildOpt(m, &Input{})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(bo))
+	require.Equal(t, "target:app", bo["app-test"].Inputs.NamedContexts["bake-test-source"].Path)
+}
+
+func TestReadTargetsTestInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		dt   string
+		err  string
+	}{
+		{
+			name: "no source",
+			dt: `
+target "app-test" {
+  type = "test"
+  test {
+    command = ["true"]
+  }
+}`,
+			err: "test target app-test requires a single source",
+		},
+		{
+			name: "no command",
+			dt: `
+target "app" {}
+target "app-test" {
+  type = "test"
+  sources = ["app"]
+}`,
+			err: "test target app-test requires a test command",
+		},
+		{
+			name: "index source",
+			dt: `
+target "app" {
+  type = "index"
+  sources = ["app-amd64"]
+  tags = ["user/app:latest"]
+}
+target "app-amd64" {}
+target "app-test" {
+  type = "test"
+  sources = ["app"]
+  test {
+    command = ["true"]
+  }
+}`,
+			err: "source app of test target app-test must build an image",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fp := File{Name: "docker-bake.hcl", Data: []byte(tc.dt)}
+			_, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app-test"}, nil, nil, &EntitlementConf{})
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
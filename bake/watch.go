@@ -0,0 +1,183 @@
+package bake
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/moby/patternmatcher"
+	"github.com/pkg/errors"
+)
+
+// TargetWatch is a path watched by bake --watch to rebuild the target when a
+// file under it changes. Ignore patterns are relative to the path.
+type TargetWatch struct {
+	Path   string   `json:"path" hcl:"path" cty:"path"`
+	Ignore []string `json:"ignore,omitempty" hcl:"ignore,optional" cty:"ignore"`
+}
+
+// WatchInterval is the interval the watched paths are polled at.
+var WatchInterval = 500 * time.Millisecond
+
+// Watcher detects changes to the paths watched by targets by polling the
+// filesystem.
+type Watcher struct {
+	watches []*watchedPath
+	stamps  map[string]fileStamp
+}
+
+type watchedPath struct {
+	path string
+	pm   *patternmatcher.PatternMatcher
+}
+
+type fileStamp struct {
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewWatcher returns a watcher of the paths watched by the targets. The
+// current state of the files is recorded so the changes made from now on are
+// detected by Wait.
+func NewWatcher(tgts map[string]*Target) (*Watcher, error) {
+	names := make([]string, 0, len(tgts))
+	for name := range tgts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := map[string]*watchedPath{}
+	var paths []string
+	for _, name := range names {
+		for _, tw := range tgts[name].Watch {
+			if tw == nil || tw.Path == "" {
+				continue
+			}
+			p := filepath.Clean(tw.Path)
+			if _, ok := seen[p]; ok {
+				// the ignore patterns of the first target watching the
+				// path apply
+				continue
+			}
+			pm, err := patternmatcher.New(tw.Ignore)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid watch ignore pattern for target %s", name)
+			}
+			seen[p] = &watchedPath{path: p, pm: pm}
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("none of the targets define paths to watch")
+	}
+	w := &Watcher{}
+	for _, p := range paths {
+		w.watches = append(w.watches, seen[p])
+	}
+	stamps, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.stamps = stamps
+	return w, nil
+}
+
+// Paths returns the watched paths.
+func (w *Watcher) Paths() []string {
+	paths := make([]string, 0, len(w.watches))
+	for _, wp := range w.watches {
+		paths = append(paths, wp.path)
+	}
+	return paths
+}
+
+// Wait blocks until files under the watched paths are added, removed or
+// modified and returns them.
+func (w *Watcher) Wait(ctx context.Context) ([]string, error) {
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-ticker.C:
+		}
+		stamps, err := w.scan()
+		if err != nil {
+			return nil, err
+		}
+		changed := diffStamps(w.stamps, stamps)
+		w.stamps = stamps
+		if len(changed) > 0 {
+			return changed, nil
+		}
+	}
+}
+
+func (w *Watcher) scan() (map[string]fileStamp, error) {
+	stamps := map[string]fileStamp{}
+	for _, wp := range w.watches {
+		err := filepath.WalkDir(wp.path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if p != wp.path {
+				rel, err := filepath.Rel(wp.path, p)
+				if err != nil {
+					return err
+				}
+				ignored, err := wp.pm.MatchesOrParentMatches(filepath.ToSlash(rel))
+				if err != nil {
+					return err
+				}
+				if ignored {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+			}
+			fi, err := d.Info()
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			st := fileStamp{mode: fi.Mode()}
+			if !fi.IsDir() {
+				st.size = fi.Size()
+				st.modTime = fi.ModTime()
+			}
+			stamps[p] = st
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to watch %s", wp.path)
+		}
+	}
+	return stamps, nil
+}
+
+func diffStamps(prev, cur map[string]fileStamp) []string {
+	var changed []string
+	for p, st := range cur {
+		if old, ok := prev[p]; !ok || old != st {
+			changed = append(changed, p)
+		}
+	}
+	for p := range prev {
+		if _, ok := cur[p]; !ok {
+			changed = append(changed, p)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
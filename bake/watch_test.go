@@ -0,0 +1,81 @@
+package bake
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTargetsWatch(t *testing.T) {
+	fp := File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+		target "base" {
+			watch {
+				path = "go.mod"
+			}
+		}
+		target "app" {
+			inherits = ["base"]
+			watch {
+				path = "src"
+				ignore = ["*_test.go", "testdata"]
+			}
+		}
+		`),
+	}
+
+	m, _, err := ReadTargets(context.TODO(), []File{fp}, []string{"app"}, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []*TargetWatch{
+		{Path: "go.mod"},
+		{Path: "src", Ignore: []string{"*_test.go", "testdata"}},
+	}, m["app"].Watch)
+}
+
+func TestWatcher(t *testing.T) {
+	interval := WatchInterval
+	WatchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { WatchInterval = interval })
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "testdata"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "main.go"), []byte("package main"), 0644))
+
+	_, err := NewWatcher(map[string]*Target{"app": {Name: "app"}})
+	require.ErrorContains(t, err, "none of the targets define paths to watch")
+
+	w, err := NewWatcher(map[string]*Target{
+		"app": {Name: "app", Watch: []*TargetWatch{{Path: src, Ignore: []string{"*_test.go", "testdata"}}}},
+		"foo": {Name: "foo", Watch: []*TargetWatch{{Path: src}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{src}, w.Paths())
+
+	wait := func() ([]string, error) {
+		ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+		defer cancel()
+		return w.Wait(ctx)
+	}
+
+	// ignored files don't trigger a rebuild
+	require.NoError(t, os.WriteFile(filepath.Join(src, "main_test.go"), []byte("package main"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "testdata", "fixture"), []byte("foo"), 0644))
+	_, err = wait()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, os.WriteFile(filepath.Join(src, "util.go"), []byte("package main"), 0644))
+	changed, err := wait()
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(src, "util.go")}, changed)
+
+	require.NoError(t, os.Remove(filepath.Join(src, "util.go")))
+	changed, err = wait()
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(src, "util.go")}, changed)
+}
@@ -0,0 +1,130 @@
+package bake
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/buildx/build"
+	"github.com/docker/buildx/util/gitutil"
+	"github.com/pkg/errors"
+)
+
+// workspacePrefix is the prefix of the paths resolved against the workspace
+// root instead of the working directory.
+const workspacePrefix = "workspace://"
+
+// ResolveWorkspacePaths resolves the context, dockerfile and named contexts of
+// the targets prefixed with workspace:// against the workspace root. The root
+// defaults to the top-level directory of the git repository of the working
+// directory. When root is set, the relative context and named contexts without
+// prefix are resolved against it too.
+//
+// For remote definitions, the workspace root is the root of the remote
+// repository.
+func ResolveWorkspacePaths(m map[string]*Target, root string, inp *Input) error {
+	remote := inp.IsRemote()
+	w := &workspace{root: root, normalize: root != "" && !remote, remote: remote}
+	for _, t := range m {
+		if t.Context == nil && w.normalize {
+			v := "."
+			t.Context = &v
+		}
+		if t.Context != nil {
+			v, err := w.resolve(*t.Context)
+			if err != nil {
+				return errors.Wrapf(err, "invalid context for target %s", t.Name)
+			}
+			t.Context = &v
+		}
+		if t.Dockerfile != nil && strings.HasPrefix(*t.Dockerfile, workspacePrefix) {
+			v, err := w.resolve(*t.Dockerfile)
+			if err != nil {
+				return errors.Wrapf(err, "invalid dockerfile for target %s", t.Name)
+			}
+			t.Dockerfile = &v
+		}
+		for k, c := range t.Contexts {
+			v, err := w.resolve(c)
+			if err != nil {
+				return errors.Wrapf(err, "invalid context %s for target %s", k, t.Name)
+			}
+			t.Contexts[k] = v
+		}
+	}
+	return nil
+}
+
+type workspace struct {
+	root      string
+	normalize bool
+	remote    bool
+	wd        string
+}
+
+func (w *workspace) resolve(p string) (string, error) {
+	rel, ok := strings.CutPrefix(p, workspacePrefix)
+	if !ok {
+		if !w.normalize || isWorkspaceIndependent(p) || filepath.IsAbs(p) {
+			return p, nil
+		}
+		rel = p
+	}
+	rel = filepath.Clean(filepath.FromSlash(rel))
+	if !filepath.IsLocal(rel) && rel != "." {
+		return "", errors.Errorf("path %s is outside of the workspace root", p)
+	}
+	if w.remote {
+		return filepath.ToSlash(rel), nil
+	}
+	if err := w.init(); err != nil {
+		return "", err
+	}
+	dir, err := filepath.Rel(w.wd, filepath.Join(w.root, rel))
+	if err != nil {
+		return "", err
+	}
+	// resolved from the working directory, even for the dockerfile that is
+	// otherwise relative to the context
+	return "cwd://" + filepath.ToSlash(dir), nil
+}
+
+func (w *workspace) init() error {
+	if w.wd != "" {
+		return nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if w.root == "" {
+		gitc, err := gitutil.New(gitutil.WithWorkingDir(wd))
+		if err != nil {
+			return errors.Wrap(err, "failed to detect the workspace root, set --workspace-root")
+		}
+		if !gitc.IsInsideWorkTree() {
+			return errors.New("failed to detect the workspace root outside of a git repository, set --workspace-root")
+		}
+		if w.root, err = gitc.RootDir(); err != nil {
+			return errors.Wrap(err, "failed to detect the workspace root, set --workspace-root")
+		}
+	}
+	// symlinks are resolved so the paths relative to the working directory
+	// don't go through them
+	if w.root, err = filepath.EvalSymlinks(w.root); err != nil {
+		return errors.Wrap(err, "invalid workspace root")
+	}
+	if w.root, err = filepath.Abs(w.root); err != nil {
+		return err
+	}
+	if w.wd, err = filepath.EvalSymlinks(wd); err != nil {
+		return err
+	}
+	return nil
+}
+
+// isWorkspaceIndependent returns whether the path of a context doesn't
+// depend on the working directory.
+func isWorkspaceIndependent(p string) bool {
+	return build.IsRemoteURL(p) || strings.HasPrefix(p, "cwd://") || strings.HasPrefix(p, "target:") || strings.HasPrefix(p, "docker-image:")
+}
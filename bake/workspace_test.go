@@ -0,0 +1,86 @@
+package bake
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moby/buildkit/client/llb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveWorkspacePaths(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	wd := filepath.Join(root, "services", "api")
+	require.NoError(t, os.MkdirAll(wd, 0755))
+
+	pwd, err := os.Getwd()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.Chdir(pwd) })
+	require.NoError(t, os.Chdir(wd))
+
+	newTargets := func() map[string]*Target {
+		return map[string]*Target{
+			"app": {
+				Name:       "app",
+				Context:    ptrstr("workspace://services/api"),
+				Dockerfile: ptrstr("workspace://docker/api.Dockerfile"),
+				Contexts: map[string]string{
+					"shared": "workspace://libs/shared",
+					"local":  "libs",
+					"base":   "docker-image://alpine",
+				},
+			},
+			"tool": {
+				Name:       "tool",
+				Dockerfile: ptrstr("Dockerfile.tool"),
+			},
+		}
+	}
+
+	t.Run("prefix", func(t *testing.T) {
+		m := newTargets()
+		require.NoError(t, ResolveWorkspacePaths(m, root, nil))
+		require.Equal(t, "cwd://.", *m["app"].Context)
+		require.Equal(t, "cwd://../../docker/api.Dockerfile", *m["app"].Dockerfile)
+		require.Equal(t, map[string]string{
+			"shared": "cwd://../../libs/shared",
+			"local":  "cwd://../../libs",
+			"base":   "docker-image://alpine",
+		}, m["app"].Contexts)
+		require.Equal(t, "cwd://../..", *m["tool"].Context)
+		require.Equal(t, "Dockerfile.tool", *m["tool"].Dockerfile)
+	})
+
+	t.Run("no root", func(t *testing.T) {
+		m := newTargets()
+		m["app"].Contexts = nil
+		delete(m, "tool")
+		err := ResolveWorkspacePaths(m, "", nil)
+		if err != nil {
+			// not inside a git repository
+			require.ErrorContains(t, err, "set --workspace-root")
+		}
+
+		m = map[string]*Target{"tool": {Name: "tool", Context: ptrstr("tools")}}
+		require.NoError(t, ResolveWorkspacePaths(m, "", nil))
+		require.Equal(t, "tools", *m["tool"].Context)
+	})
+
+	t.Run("outside", func(t *testing.T) {
+		m := map[string]*Target{"app": {Name: "app", Context: ptrstr("workspace://../app")}}
+		require.ErrorContains(t, ResolveWorkspacePaths(m, root, nil), "outside of the workspace root")
+	})
+
+	t.Run("remote", func(t *testing.T) {
+		st := llb.Scratch()
+		m := newTargets()
+		require.NoError(t, ResolveWorkspacePaths(m, root, &Input{State: &st, URL: "https://github.com/docker/buildx.git"}))
+		require.Equal(t, "services/api", *m["app"].Context)
+		require.Equal(t, "docker/api.Dockerfile", *m["app"].Dockerfile)
+		require.Equal(t, "libs/shared", m["app"].Contexts["shared"])
+		require.Equal(t, "libs", m["app"].Contexts["local"])
+		require.Nil(t, m["tool"].Context)
+	})
+}
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/containerd/containerd/images"
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/distribution/reference"
 	"github.com/docker/buildx/builder"
 	controllerapi "github.com/docker/buildx/controller/pb"
@@ -69,6 +70,7 @@ type Options struct {
 	CacheFrom                  []client.CacheOptionsEntry
 	CacheTo                    []client.CacheOptionsEntry
 	CgroupParent               string
+	DNS                        *DNSConfig
 	Exports                    []client.ExportEntry
 	ExportsLocalPathsTemporary []string // should be removed after client.ExportEntry update in buildkit v0.19.0
 	ExtraHosts                 []string
@@ -76,8 +78,12 @@ type Options struct {
 	NetworkMode                string
 	NoCache                    bool
 	NoCacheFilter              []string
+	NoEmulation                bool
+	NoMetadataCache            bool
+	Offline                    bool
 	Platforms                  []specs.Platform
 	Pull                       bool
+	SchedulingPolicy           SchedulingPolicy
 	SecretSpecs                []*controllerapi.Secret
 	SSHSpecs                   []*controllerapi.SSH
 	ShmSize                    opts.MemBytes
@@ -91,6 +97,9 @@ type Options struct {
 	ProvenanceResponseMode confutil.MetadataProvenanceMode
 	SourcePolicy           *spb.Policy
 	GroupRef               string
+
+	// dockerLoads are the imports to docker set up by toSolveOpt.
+	dockerLoads []*dockerutil.ImageLoad
 }
 
 type CallFunc struct {
@@ -99,6 +108,14 @@ type CallFunc struct {
 	IgnoreStatus bool
 }
 
+// DNSConfig is the resolver configuration of the containers of the RUN
+// steps of a build.
+type DNSConfig struct {
+	Nameservers   []string
+	SearchDomains []string
+	Options       []string
+}
+
 type Inputs struct {
 	ContextPath      string
 	DockerfilePath   string
@@ -118,7 +135,8 @@ type NamedContext struct {
 
 type reqForNode struct {
 	*resolvedNode
-	so *client.SolveOpt
+	so    *client.SolveOpt
+	loads []*dockerutil.ImageLoad
 }
 
 func filterAvailableNodes(nodes []builder.Node) ([]builder.Node, error) {
@@ -205,6 +223,15 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 		return nil, err
 	}
 
+	if err := checkEmulation(ctx, opts, drivers, w); err != nil {
+		return nil, err
+	}
+
+	opts, err = createRepositories(ctx, opts, drivers, w)
+	if err != nil {
+		return nil, err
+	}
+
 	defers := make([]func(), 0, 2)
 	defer func() {
 		if err != nil {
@@ -224,9 +251,18 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 		if err != nil {
 			logrus.WithError(err).Warn("current commit information was not captured by the build")
 		}
+		provenanceVCS := true
+		if v := opt.Attests["provenance"]; v != nil {
+			if _, provenanceVCS, err = provenanceVCSAttrs(*v); err != nil {
+				return nil, err
+			}
+		}
 		if opt.Ref == "" {
 			opt.Ref = identity.NewID()
 		}
+		if r := metadataResolver(opt, drivers[k][0].Node(), cfg); r != nil {
+			pinImages(ctx, &opt, r)
+		}
 		var reqn []*reqForNode
 		for _, np := range drivers[k] {
 			if np.Node().Driver.IsMobyDriver() {
@@ -247,10 +283,18 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 				return nil, err
 			}
 			addGitAttrs(so)
+			if !provenanceVCS {
+				for k := range so.FrontendAttrs {
+					if strings.HasPrefix(k, "vcs:") {
+						delete(so.FrontendAttrs, k)
+					}
+				}
+			}
 			defers = append(defers, release)
 			reqn = append(reqn, &reqForNode{
 				resolvedNode: np,
 				so:           so,
+				loads:        localOpt.dockerLoads,
 			})
 		}
 		reqForNodes[k] = reqn
@@ -313,6 +357,7 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 
 	resp = map[string]*client.SolveResponse{}
 	var respMu sync.Mutex
+	loadErr := &LoadError{}
 	results := waitmap.New()
 
 	multiTarget := len(opts) > 1
@@ -337,6 +382,15 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 				}()
 			}
 
+			progress.StartTarget(w, k)
+			defer func() {
+				// the target finishes in the goroutine waiting for its
+				// solves unless it fails before it is started
+				if err != nil {
+					progress.FinishTarget(w, k, err)
+				}
+			}()
+
 			res := make([]*client.SolveResponse, len(dps))
 			eg2, ctx := errgroup.WithContext(ctx)
 
@@ -347,6 +401,7 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 				i, dp := i, dp
 				node := dp.Node()
 				so := reqForNodes[k][i].so
+				loads := reqForNodes[k][i].loads
 				if multiDriver {
 					for i, e := range so.Exports {
 						switch e.Type {
@@ -508,6 +563,13 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 						rr, err = c.Build(ctx, *so, "buildx", buildFunc, ch)
 						tracing.FinishWithError(span, err)
 					}
+					if err != nil {
+						if reason, ok := cancelReason(ctx); ok {
+							if err := saveCancelReason(so, node, cfg, reason); err != nil {
+								logrus.Warnf("failed to record cancel reason of %s: %v", buildRef, err)
+							}
+						}
+					}
 					if !so.Internal && desktop.BuildBackendEnabled() && node.Driver.HistoryAPISupported(ctx) {
 						if err != nil {
 							return &desktop.ErrorWithBuildRef{
@@ -528,8 +590,13 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 					for k, v := range callRes {
 						rr.ExporterResponse[k] = string(v)
 					}
+					if err := checkDockerLoads(ctx, loads, rr.ExporterResponse); err != nil {
+						respMu.Lock()
+						loadErr.add(k, err)
+						respMu.Unlock()
+					}
+					rr.ExporterResponse["buildx.build.ref"] = buildRef
 					if opt.CallFunc == nil {
-						rr.ExporterResponse["buildx.build.ref"] = buildRef
 						if node.Driver.HistoryAPISupported(ctx) {
 							if err := setRecordProvenance(ctx, c, rr, so.Ref, opt.ProvenanceResponseMode, pw); err != nil {
 								return err
@@ -548,12 +615,28 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 									}
 									pw := progress.ResetTime(pw)
 									pushList := strings.Split(pushNames, ",")
-									for _, name := range pushList {
-										if err := progress.Wrap(fmt.Sprintf("pushing %s with docker", name), pw.Write, func(l progress.SubLogger) error {
-											return pushWithMoby(ctx, node, name, l)
-										}); err != nil {
-											return err
-										}
+									groups, err := groupNamesByRegistry(pushList)
+									if err != nil {
+										return err
+									}
+									// push to different registries concurrently, but keep
+									// pushes to the same registry sequential so layers
+									// uploaded by the first push are reused by the next ones
+									egPush, pushCtx := errgroup.WithContext(ctx)
+									for _, group := range groups {
+										egPush.Go(func() error {
+											for _, name := range group {
+												if err := progress.Wrap(fmt.Sprintf("pushing %s with docker", name), pw.Write, func(l progress.SubLogger) error {
+													return pushWithMoby(pushCtx, node, name, l)
+												}); err != nil {
+													return err
+												}
+											}
+											return nil
+										})
+									}
+									if err := egPush.Wait(); err != nil {
+										return err
 									}
 									remoteDigest, err := remoteDigestWithMoby(ctx, node, pushList[0])
 									if err == nil && remoteDigest != "" {
@@ -589,8 +672,11 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 						err = errors.Wrapf(err, "target %s", k)
 					}()
 				}
+				defer func() {
+					progress.FinishTarget(w, k, err)
+				}()
 
-				pw := progress.WithPrefix(w, "default", false)
+				pw := progress.WithPrefix(w, k, false)
 				if err := eg2.Wait(); err != nil {
 					return err
 				}
@@ -684,14 +770,30 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 
 							itpush := imagetools.New(imageopt)
 
-							for _, n := range names {
-								nn, err := reference.ParseNormalizedNamed(n)
-								if err != nil {
-									return err
-								}
-								if err := itpush.Push(ctx, nn, desc, dt); err != nil {
-									return err
-								}
+							groups, err := groupNamesByRegistry(names)
+							if err != nil {
+								return err
+							}
+							// registry tokens are fetched again by the authorizer
+							// when they expire, so each registry can be pushed to
+							// independently
+							egPush, pushCtx := errgroup.WithContext(ctx)
+							for _, group := range groups {
+								egPush.Go(func() error {
+									for _, n := range group {
+										nn, err := reference.ParseNormalizedNamed(n)
+										if err != nil {
+											return err
+										}
+										if err := itpush.Push(pushCtx, nn, desc, dt); err != nil {
+											return err
+										}
+									}
+									return nil
+								})
+							}
+							if err := egPush.Wait(); err != nil {
+								return err
 							}
 
 							respMu.Lock()
@@ -722,6 +824,9 @@ func BuildWithResultHandler(ctx context.Context, nodes []builder.Node, opts map[
 		return nil, err
 	}
 
+	if len(loadErr.Targets) > 0 {
+		return resp, loadErr
+	}
 	return resp, nil
 }
 
@@ -747,6 +852,17 @@ func extractIndexAnnotations(exports []client.ExportEntry) (map[exptypes.Annotat
 }
 
 func pushWithMoby(ctx context.Context, d *driver.DriverHandle, name string, l progress.SubLogger) error {
+	err := pushWithMobyAuth(ctx, d, name, l)
+	if err != nil && cerrdefs.IsUnauthorized(err) {
+		// credentials may have expired during a long push, retry once with
+		// credentials fetched again from the credentials store
+		logrus.Debugf("retrying push of %s with refreshed credentials: %v", name, err)
+		err = pushWithMobyAuth(ctx, d, name, l)
+	}
+	return err
+}
+
+func pushWithMobyAuth(ctx context.Context, d *driver.DriverHandle, name string, l progress.SubLogger) error {
 	api := d.Config().DockerAPI
 	if api == nil {
 		return errors.Errorf("invalid empty Docker API reference") // should never happen
@@ -820,7 +936,7 @@ func pushWithMoby(ctx context.Context, d *driver.DriverHandle, name string, l pr
 			l.SetStatus(st)
 		}
 		if jm.Error != nil {
-			parsedError = jm.Error
+			parsedError = pushStreamError(jm.Error)
 		}
 	}
 	return nil
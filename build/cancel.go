@@ -0,0 +1,32 @@
+package build
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// CanceledError is the cause of a build canceled through the controller.
+type CanceledError struct {
+	Reason string
+}
+
+func (e *CanceledError) Error() string {
+	if e.Reason == "" {
+		return "build canceled"
+	}
+	return "build canceled: " + e.Reason
+}
+
+func (e *CanceledError) Unwrap() error {
+	return context.Canceled
+}
+
+// cancelReason returns the reason the build of ctx was canceled with, if any.
+func cancelReason(ctx context.Context) (string, bool) {
+	var cerr *CanceledError
+	if err := context.Cause(ctx); err != nil && errors.As(err, &cerr) {
+		return cerr.Reason, true
+	}
+	return "", false
+}
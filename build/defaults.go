@@ -0,0 +1,47 @@
+package build
+
+import (
+	"github.com/docker/buildx/store"
+	"github.com/docker/cli/opts"
+	"github.com/pkg/errors"
+)
+
+// WithBuildDefaults applies the build defaults of a builder instance to the
+// options of each target. Values set on the target take precedence, ulimits
+// being merged by name.
+func WithBuildDefaults(bo map[string]Options, d *store.BuildDefaults) error {
+	if d == nil {
+		return nil
+	}
+	for k, opt := range bo {
+		if opt.ShmSize.Value() == 0 && d.ShmSize > 0 {
+			opt.ShmSize = opts.MemBytes(d.ShmSize)
+		}
+		if len(d.Ulimits) > 0 {
+			ulimits, err := mergeUlimits(d.Ulimits, opt.Ulimits)
+			if err != nil {
+				return err
+			}
+			opt.Ulimits = ulimits
+		}
+		bo[k] = opt
+	}
+	return nil
+}
+
+func mergeUlimits(defaults []string, in *opts.UlimitOpt) (*opts.UlimitOpt, error) {
+	out := opts.NewUlimitOpt(nil)
+	for _, v := range defaults {
+		if err := out.Set(v); err != nil {
+			return nil, errors.Wrapf(err, "invalid default ulimit %q", v)
+		}
+	}
+	if in != nil {
+		for _, ul := range in.GetList() {
+			if err := out.Set(ul.String()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,45 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/docker/buildx/store"
+	"github.com/docker/cli/opts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithBuildDefaults(t *testing.T) {
+	ulimits := opts.NewUlimitOpt(nil)
+	require.NoError(t, ulimits.Set("nofile=1024:1024"))
+	require.NoError(t, ulimits.Set("nproc=512:512"))
+
+	bo := map[string]Options{
+		"default": {},
+		"override": {
+			ShmSize: opts.MemBytes(64 << 20),
+			Ulimits: ulimits,
+		},
+	}
+	err := WithBuildDefaults(bo, &store.BuildDefaults{
+		ShmSize: 128 << 20,
+		Ulimits: []string{"nofile=1048576:1048576", "memlock=-1:-1"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(128<<20), int64(bo["default"].ShmSize))
+	out, err := toBuildkitUlimits(bo["default"].Ulimits)
+	require.NoError(t, err)
+	require.Equal(t, "memlock=-1:-1,nofile=1048576:1048576", out)
+
+	require.Equal(t, int64(64<<20), int64(bo["override"].ShmSize))
+	out, err = toBuildkitUlimits(bo["override"].Ulimits)
+	require.NoError(t, err)
+	require.Equal(t, "memlock=-1:-1,nofile=1024:1024,nproc=512:512", out)
+}
+
+func TestWithBuildDefaultsInvalid(t *testing.T) {
+	err := WithBuildDefaults(map[string]Options{"default": {}}, &store.BuildDefaults{
+		Ulimits: []string{"nofile"},
+	})
+	require.ErrorContains(t, err, `invalid default ulimit "nofile"`)
+}
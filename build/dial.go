@@ -54,9 +54,15 @@ func Dial(ctx context.Context, nodes []builder.Node, pw progress.Writer, platfor
 			if err == nil {
 				return conn, nil
 			}
-			dialError = stderrors.Join(err)
+			dialError = stderrors.Join(dialError, err)
 		}
 	}
 
+	if dialError == nil {
+		if platform != nil {
+			return nil, errors.Errorf("no nodes available for platform %s", platforms.Format(*platform))
+		}
+		return nil, errors.New("no nodes available")
+	}
 	return nil, errors.Wrap(dialError, "no nodes available")
 }
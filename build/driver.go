@@ -3,6 +3,7 @@ package build
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/containerd/platforms"
@@ -63,6 +64,9 @@ type nodeResolver struct {
 	nodes     []builder.Node
 	clients   cachedGroup[*client.Client]
 	buildOpts cachedGroup[gateway.BuildOpts]
+
+	// load is the number of platforms scheduled on each node
+	load map[int]int
 }
 
 func resolveDrivers(ctx context.Context, nodes []builder.Node, opt map[string]Options, pw progress.Writer) (map[string][]*resolvedNode, error) {
@@ -79,6 +83,7 @@ func newDriverResolver(nodes []builder.Node) *nodeResolver {
 		nodes:     nodes,
 		clients:   newCachedGroup[*client.Client](),
 		buildOpts: newCachedGroup[gateway.BuildOpts](),
+		load:      map[int]int{},
 	}
 	return r
 }
@@ -88,9 +93,24 @@ func (r *nodeResolver) Resolve(ctx context.Context, opt map[string]Options, pw p
 		return nil, nil
 	}
 
+	for _, opt := range opt {
+		if err := opt.SchedulingPolicy.validate(r.nodes); err != nil {
+			return nil, err
+		}
+	}
+
+	// resolve the targets in a stable order so that the nodes picked by
+	// load-based policies don't depend on the map iteration order
+	keys := make([]string, 0, len(opt))
+	for k := range opt {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	nodes := map[string][]*resolvedNode{}
-	for k, opt := range opt {
-		node, perfect, err := r.resolve(ctx, opt.Platforms, pw, platforms.OnlyStrict, nil)
+	for _, k := range keys {
+		opt := opt[k]
+		node, perfect, err := r.resolve(ctx, opt.Platforms, opt.SchedulingPolicy, pw, platforms.OnlyStrict, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -143,8 +163,10 @@ func (r *nodeResolver) Resolve(ctx context.Context, opt map[string]Options, pw p
 		// then we can attempt to match against all the available platforms
 		// (this time we don't care about imperfect matches)
 		nodes = map[string][]*resolvedNode{}
-		for k, opt := range opt {
-			node, _, err := r.resolve(ctx, opt.Platforms, pw, platforms.Only, func(idx int, n builder.Node) []specs.Platform {
+		r.load = map[int]int{}
+		for _, k := range keys {
+			opt := opt[k]
+			node, _, err := r.resolve(ctx, opt.Platforms, opt.SchedulingPolicy, pw, platforms.Only, func(idx int, n builder.Node) []specs.Platform {
 				return workers[idx]
 			})
 			if err != nil {
@@ -172,7 +194,7 @@ func (r *nodeResolver) Resolve(ctx context.Context, opt map[string]Options, pw p
 	return nodes, nil
 }
 
-func (r *nodeResolver) resolve(ctx context.Context, ps []specs.Platform, pw progress.Writer, matcher matchMaker, additional func(idx int, n builder.Node) []specs.Platform) ([]*resolvedNode, bool, error) {
+func (r *nodeResolver) resolve(ctx context.Context, ps []specs.Platform, policy SchedulingPolicy, pw progress.Writer, matcher matchMaker, additional func(idx int, n builder.Node) []specs.Platform) ([]*resolvedNode, bool, error) {
 	if len(r.nodes) == 0 {
 		return nil, true, nil
 	}
@@ -180,21 +202,23 @@ func (r *nodeResolver) resolve(ctx context.Context, ps []specs.Platform, pw prog
 	perfect := true
 	nodeIdxs := make([]int, 0)
 	for _, p := range ps {
-		idx := r.get(p, matcher, additional)
+		idx := r.get(p, policy, matcher, additional)
 		if idx == -1 {
-			idx = 0
+			idx = policy.fallback(r.nodes, policy.builderFor(&p))
 			perfect = false
 		}
+		r.load[idx]++
 		nodeIdxs = append(nodeIdxs, idx)
 	}
 
 	var nodes []*resolvedNode
 	if len(nodeIdxs) == 0 {
+		idx := policy.fallback(r.nodes, policy.builderFor(nil))
 		nodes = append(nodes, &resolvedNode{
 			resolver:    r,
-			driverIndex: 0,
+			driverIndex: idx,
 		})
-		nodeIdxs = append(nodeIdxs, 0)
+		nodeIdxs = append(nodeIdxs, idx)
 	} else {
 		for i, idx := range nodeIdxs {
 			node := &resolvedNode{
@@ -215,10 +239,14 @@ func (r *nodeResolver) resolve(ctx context.Context, ps []specs.Platform, pw prog
 	return nodes, perfect, nil
 }
 
-func (r *nodeResolver) get(p specs.Platform, matcher matchMaker, additionalPlatforms func(int, builder.Node) []specs.Platform) int {
+func (r *nodeResolver) get(p specs.Platform, policy SchedulingPolicy, matcher matchMaker, additionalPlatforms func(int, builder.Node) []specs.Platform) int {
 	best := -1
 	bestPlatform := specs.Platform{}
+	builderName := policy.builderFor(&p)
 	for i, node := range r.nodes {
+		if !policy.allows(node, builderName) {
+			continue
+		}
 		platforms := node.Platforms
 		if additionalPlatforms != nil {
 			platforms = append([]specs.Platform{}, platforms...)
@@ -235,6 +263,13 @@ func (r *nodeResolver) get(p specs.Platform, matcher matchMaker, additionalPlatf
 				bestPlatform = p2
 				continue
 			}
+			if c := r.compareNodes(p, policy, i, best, additionalPlatforms); c != 0 {
+				if c < 0 {
+					best = i
+					bestPlatform = p2
+				}
+				continue
+			}
 			if matcher(p2).Less(p, bestPlatform) {
 				best = i
 				bestPlatform = p2
@@ -0,0 +1,161 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containerd/platforms"
+	"github.com/docker/buildx/util/progress"
+	"github.com/moby/buildkit/client"
+	gateway "github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/moby/buildkit/identity"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	// emulationSlowdown is a rough estimate of how much slower RUN
+	// instructions are under QEMU user-mode emulation.
+	emulationSlowdown = "5-20x"
+
+	emulationDocsURL = "https://docs.docker.com/build/building/multi-platform/"
+)
+
+type emulatedPlatform struct {
+	Target   string
+	Node     string
+	Platform specs.Platform
+	Native   []specs.Platform
+}
+
+// checkEmulation detects the requested platforms that run under emulation on
+// the nodes they were resolved to. It fails for targets that don't allow
+// emulation, and otherwise writes a warning for each of them.
+func checkEmulation(ctx context.Context, opts map[string]Options, drivers map[string][]*resolvedNode, w progress.Writer) error {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var emulated []emulatedPlatform
+	for _, k := range keys {
+		for _, dp := range drivers[k] {
+			if len(dp.platforms) == 0 {
+				continue
+			}
+			bopts, err := dp.BuildOpts(ctx)
+			if err != nil {
+				return err
+			}
+			for _, e := range emulatedPlatforms(k, dp.Node().Name, bopts.Workers, dp.platforms) {
+				if opts[k].NoEmulation {
+					return errors.Errorf("%s, and emulation is not allowed", e.message(len(opts) > 1))
+				}
+				emulated = append(emulated, e)
+			}
+		}
+	}
+	if len(emulated) > 0 {
+		writeEmulationWarnings(w, emulated, len(opts) > 1)
+	}
+	return nil
+}
+
+// emulatedPlatforms returns the platforms in pp that are not native to any
+// of the workers of a node. Nothing is returned if the native platforms are
+// unknown.
+func emulatedPlatforms(target, node string, workers []gateway.WorkerInfo, pp []specs.Platform) []emulatedPlatform {
+	native := nativePlatforms(workers)
+	if len(native) == 0 {
+		return nil
+	}
+	var out []emulatedPlatform
+	for _, p := range pp {
+		if isNativePlatform(native, p) {
+			continue
+		}
+		out = append(out, emulatedPlatform{
+			Target:   target,
+			Node:     node,
+			Platform: p,
+			Native:   native,
+		})
+	}
+	return out
+}
+
+// nativePlatforms returns the native platform of each worker, which BuildKit
+// always lists first.
+func nativePlatforms(workers []gateway.WorkerInfo) []specs.Platform {
+	var out []specs.Platform
+	for _, w := range workers {
+		if len(w.Platforms) == 0 {
+			continue
+		}
+		p := platforms.Normalize(w.Platforms[0])
+		if !slices.ContainsFunc(out, func(p2 specs.Platform) bool {
+			return platforms.Format(p2) == platforms.Format(p)
+		}) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// isNativePlatform returns true if p runs without emulation on one of the
+// native platforms, e.g. linux/386 on linux/amd64.
+func isNativePlatform(native []specs.Platform, p specs.Platform) bool {
+	for _, n := range native {
+		if platforms.Only(n).Match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e emulatedPlatform) message(withTarget bool) string {
+	var target string
+	if withTarget {
+		target = fmt.Sprintf(" for target %q", e.Target)
+	}
+	return fmt.Sprintf("platform %s%s runs under emulation on node %s", platforms.Format(e.Platform), target, e.Node)
+}
+
+func writeEmulationWarnings(w progress.Writer, emulated []emulatedPlatform, withTarget bool) {
+	dgst := digest.FromBytes([]byte(identity.NewID()))
+	tm := time.Now()
+	vtx := &client.Vertex{
+		Digest:    dgst,
+		Name:      "[internal] check platform emulation",
+		Started:   &tm,
+		Completed: &tm,
+	}
+	warnings := make([]*client.VertexWarning, 0, len(emulated))
+	for _, e := range emulated {
+		native := make([]string, 0, len(e.Native))
+		for _, p := range e.Native {
+			native = append(native, platforms.Format(p))
+		}
+		msg := e.message(withTarget)
+		warnings = append(warnings, &client.VertexWarning{
+			Vertex: dgst,
+			Level:  1,
+			Short:  []byte(fmt.Sprintf("%s%s, RUN instructions are estimated to be %s slower", strings.ToUpper(msg[:1]), msg[1:], emulationSlowdown)),
+			Detail: [][]byte{
+				[]byte(fmt.Sprintf("The native platforms of node %s are %s.", e.Node, strings.Join(native, ", "))),
+				[]byte("Add a node with a native platform to the builder, cross-compile in the Dockerfile, or set --no-emulation to fail instead."),
+			},
+			URL: emulationDocsURL,
+		})
+	}
+	w.Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{vtx},
+		Warnings: warnings,
+	})
+}
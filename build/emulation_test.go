@@ -0,0 +1,91 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/containerd/platforms"
+	"github.com/moby/buildkit/client"
+	gateway "github.com/moby/buildkit/frontend/gateway/client"
+	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmulatedPlatforms(t *testing.T) {
+	workers := []gateway.WorkerInfo{
+		{
+			ID: "amd64",
+			Platforms: []specs.Platform{
+				platforms.MustParse("linux/amd64"),
+				platforms.MustParse("linux/amd64/v2"),
+				platforms.MustParse("linux/386"),
+				platforms.MustParse("linux/arm64"),
+				platforms.MustParse("linux/riscv64"),
+			},
+		},
+	}
+	pp := []specs.Platform{
+		platforms.MustParse("linux/amd64"),
+		platforms.MustParse("linux/386"),
+		platforms.MustParse("linux/arm64"),
+		platforms.MustParse("linux/riscv64"),
+	}
+
+	out := emulatedPlatforms("default", "builder0", workers, pp)
+	require.Len(t, out, 2)
+	require.Equal(t, "linux/arm64", platforms.Format(out[0].Platform))
+	require.Equal(t, "linux/riscv64", platforms.Format(out[1].Platform))
+	require.Equal(t, "builder0", out[0].Node)
+	require.Equal(t, []specs.Platform{platforms.MustParse("linux/amd64")}, out[0].Native)
+
+	// arm64 nodes run arm/v7 natively
+	workers = append(workers, gateway.WorkerInfo{
+		ID: "arm64",
+		Platforms: []specs.Platform{
+			platforms.MustParse("linux/arm64"),
+			platforms.MustParse("linux/arm/v7"),
+		},
+	})
+	out = emulatedPlatforms("default", "builder0", workers, append(pp, platforms.MustParse("linux/arm/v7")))
+	require.Len(t, out, 1)
+	require.Equal(t, "linux/riscv64", platforms.Format(out[0].Platform))
+
+	// unknown native platforms
+	require.Empty(t, emulatedPlatforms("default", "builder0", nil, pp))
+}
+
+func TestWriteEmulationWarnings(t *testing.T) {
+	w := &statusRecorder{}
+	writeEmulationWarnings(w, []emulatedPlatform{
+		{
+			Target:   "app",
+			Node:     "builder0",
+			Platform: platforms.MustParse("linux/arm64"),
+			Native:   []specs.Platform{platforms.MustParse("linux/amd64")},
+		},
+	}, true)
+
+	require.Len(t, w.statuses, 1)
+	ss := w.statuses[0]
+	require.Len(t, ss.Vertexes, 1)
+	require.Len(t, ss.Warnings, 1)
+	require.Equal(t, ss.Vertexes[0].Digest, ss.Warnings[0].Vertex)
+	require.Equal(t, `Platform linux/arm64 for target "app" runs under emulation on node builder0, RUN instructions are estimated to be 5-20x slower`, string(ss.Warnings[0].Short))
+	require.Equal(t, "The native platforms of node builder0 are linux/amd64.", string(ss.Warnings[0].Detail[0]))
+}
+
+type statusRecorder struct {
+	statuses []*client.SolveStatus
+}
+
+func (w *statusRecorder) Write(ss *client.SolveStatus) {
+	w.statuses = append(w.statuses, ss)
+}
+
+func (w *statusRecorder) WriteBuildRef(string, string) {}
+
+func (w *statusRecorder) ValidateLogSource(digest.Digest, interface{}) bool {
+	return true
+}
+
+func (w *statusRecorder) ClearLogSource(interface{}) {}
@@ -0,0 +1,36 @@
+package build
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/buildx/util/gitutil"
+	"github.com/docker/buildx/util/progress"
+)
+
+// cloneGitContext clones a git context URL with clone options, such as
+// "?depth=1&sparse=services/api", on the client. It returns the local path of
+// the context and the directory to remove once the build is done, or empty
+// strings if the URL doesn't have clone options.
+func cloneGitContext(ctx context.Context, u string, pw progress.Writer) (string, string, error) {
+	ref, err := gitutil.ParseCloneRef(u)
+	if err != nil || ref == nil {
+		return "", "", err
+	}
+	dir, err := os.MkdirTemp("", "buildx-git-context")
+	if err != nil {
+		return "", "", err
+	}
+	if err := progress.Wrap("[internal] cloning "+ref.String(), pw.Write, func(progress.SubLogger) error {
+		return gitutil.Clone(ctx, ref.Remote, ref.Ref, dir, ref.Opts)
+	}); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", "", err
+	}
+	p := dir
+	if ref.SubDir != "" {
+		p = filepath.Join(dir, filepath.FromSlash(ref.SubDir))
+	}
+	return p, dir, nil
+}
@@ -0,0 +1,72 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/buildx/util/dockerutil"
+	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+)
+
+const (
+	// ExporterResponseLoadImages is set in the exporter response of a target
+	// to the images loaded to docker.
+	ExporterResponseLoadImages = "buildx.load.images"
+	// ExporterResponseLoadError is set in the exporter response of a target
+	// whose result could not be loaded to docker.
+	ExporterResponseLoadError = "buildx.load.error"
+)
+
+// LoadError is returned by Build, together with the responses of all targets,
+// when the results of some targets could not be loaded to docker. A failed
+// load doesn't abort the build of the other targets.
+type LoadError struct {
+	Targets map[string]error
+}
+
+func (e *LoadError) add(target string, err error) {
+	if e.Targets == nil {
+		e.Targets = map[string]error{}
+	}
+	e.Targets[target] = err
+}
+
+func (e *LoadError) Error() string {
+	names := make([]string, 0, len(e.Targets))
+	for name := range e.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 1 {
+		return fmt.Sprintf("failed to load %s to docker: %v", names[0], e.Targets[names[0]])
+	}
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %v", name, e.Targets[name])
+	}
+	return fmt.Sprintf("failed to load %d targets to docker:\n%s", len(names), strings.Join(msgs, "\n"))
+}
+
+// checkDockerLoads records the imports to docker of a build result in its
+// exporter response. It returns the first failed import, or an error if a
+// loaded image doesn't match the result.
+func checkDockerLoads(ctx context.Context, loads []*dockerutil.ImageLoad, resp map[string]string) error {
+	var images []string
+	for _, l := range loads {
+		if err := l.Err(); err != nil {
+			resp[ExporterResponseLoadError] = err.Error()
+			return err
+		}
+		if err := l.Verify(ctx, resp[exptypes.ExporterImageDigestKey], resp[exptypes.ExporterImageConfigDigestKey]); err != nil {
+			resp[ExporterResponseLoadError] = err.Error()
+			return err
+		}
+		images = append(images, l.Images()...)
+	}
+	if len(images) > 0 {
+		resp[ExporterResponseLoadImages] = strings.Join(images, ",")
+	}
+	return nil
+}
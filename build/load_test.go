@@ -0,0 +1,19 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadError(t *testing.T) {
+	err := &LoadError{}
+	err.add("db", errors.New("no space left on device"))
+	require.EqualError(t, err, "failed to load db to docker: no space left on device")
+
+	err.add("app", errors.New("loaded image app:latest has ID sha256:aaaa, expected sha256:bbbb"))
+	require.EqualError(t, err, `failed to load 2 targets to docker:
+app: loaded image app:latest has ID sha256:aaaa, expected sha256:bbbb
+db: no space left on device`)
+}
@@ -1,6 +1,7 @@
 package build
 
 import (
+	"os"
 	"path/filepath"
 
 	"github.com/docker/buildx/builder"
@@ -42,3 +43,24 @@ func saveLocalState(so *client.SolveOpt, target string, opts Options, node build
 		GroupRef:       opts.GroupRef,
 	})
 }
+
+// saveCancelReason records the reason of a canceled build in the local state
+// of its ref.
+func saveCancelReason(so *client.SolveOpt, node builder.Node, cfg *confutil.Config, reason string) error {
+	if so.Ref == "" || reason == "" {
+		return nil
+	}
+	l, err := localstate.New(cfg)
+	if err != nil {
+		return err
+	}
+	st, err := l.ReadRef(node.Builder, node.Name, so.Ref)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		st = &localstate.State{}
+	}
+	st.CancelReason = reason
+	return l.SaveRef(node.Builder, node.Name, so.Ref, *st)
+}
@@ -0,0 +1,223 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/util/confutil"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// metadataCacheDir is the directory, relative to the buildx config dir,
+	// where the digests of the resolved image references are cached.
+	metadataCacheDir = "cache/metadata"
+	// metadataCacheMaxEntries is the number of most recently resolved image
+	// references kept in the cache.
+	metadataCacheMaxEntries = 1024
+)
+
+// metadataCacheEnabled returns whether the metadata cache is enabled with
+// BUILDX_METADATA_CACHE.
+func metadataCacheEnabled() bool {
+	v, ok := os.LookupEnv("BUILDX_METADATA_CACHE")
+	if !ok {
+		return false
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		logrus.Warnf("invalid BUILDX_METADATA_CACHE value %q, not using the metadata cache", v)
+		return false
+	}
+	return enabled
+}
+
+// metadataCacheTTL returns the duration for which resolved image references
+// are cached, which can be set with BUILDX_METADATA_CACHE_TTL.
+func metadataCacheTTL() time.Duration {
+	if v, ok := os.LookupEnv("BUILDX_METADATA_CACHE_TTL"); ok {
+		ttl, err := time.ParseDuration(v)
+		if err == nil && ttl >= 0 {
+			return ttl
+		}
+		logrus.Warnf("invalid BUILDX_METADATA_CACHE_TTL value %q, using %s", v, imagetools.DefaultCacheTTL)
+	}
+	return imagetools.DefaultCacheTTL
+}
+
+// metadataResolver returns a resolver caching the image references it
+// resolves in the config dir, or nil if the metadata cache is not used for
+// the build. The cache is not used with the docker driver, whose images are
+// resolved by the image store of the engine. The expired entries and the
+// least recently resolved ones are pruned.
+func metadataResolver(opt Options, node builder.Node, cfg *confutil.Config) *imagetools.Resolver {
+	if opt.NoMetadataCache || opt.Pull || opt.Offline || cfg == nil || node.ImageOpt.Auth == nil {
+		return nil
+	}
+	if node.Driver == nil || node.Driver.IsMobyDriver() || !metadataCacheEnabled() {
+		return nil
+	}
+	ttl := metadataCacheTTL()
+	if ttl == 0 {
+		return nil
+	}
+	dir := filepath.Join(cfg.Dir(), metadataCacheDir)
+	confutil.PruneCacheDir(dir, ttl, metadataCacheMaxEntries)
+	imageopt := node.ImageOpt
+	imageopt.Cache = imagetools.NewCache(dir, ttl)
+	return imagetools.New(imageopt)
+}
+
+// pinImages resolves the tags of the frontend image, of the base images of a
+// local Dockerfile and of the image named contexts to digests, so that the
+// builder doesn't resolve them over the network again while their entry in
+// the metadata cache is valid. Images that can't be resolved are left as is.
+func pinImages(ctx context.Context, opt *Options, r *imagetools.Resolver) {
+	pin := func(in string) (string, bool) {
+		named, err := reference.ParseNormalizedNamed(in)
+		if err != nil {
+			return "", false
+		}
+		if _, ok := named.(reference.Digested); ok {
+			return "", false
+		}
+		named = reference.TagNameOnly(named)
+		_, desc, err := r.Resolve(ctx, named.String())
+		if err != nil {
+			logrus.WithError(err).Debugf("failed to resolve %s", in)
+			return "", false
+		}
+		return reference.FamiliarString(named) + "@" + desc.Digest.String(), true
+	}
+
+	frontend := frontendImage(*opt)
+	if frontend != "" {
+		if pinned, ok := pin(frontend); ok {
+			opt.BuildArgs = maps.Clone(opt.BuildArgs)
+			if opt.BuildArgs == nil {
+				opt.BuildArgs = map[string]string{}
+			}
+			opt.BuildArgs[FrontendImageArg] = pinned
+		}
+	}
+
+	contexts := maps.Clone(opt.Inputs.NamedContexts)
+	if contexts == nil {
+		contexts = map[string]NamedContext{}
+	}
+	for name, nc := range contexts {
+		if img, ok := strings.CutPrefix(nc.Path, "docker-image://"); ok && nc.State == nil {
+			if pinned, ok := pin(img); ok {
+				contexts[name] = NamedContext{Path: "docker-image://" + pinned}
+			}
+		}
+	}
+	var imgs []string
+	if supportsNamedContexts(frontend) {
+		imgs = baseImages(LocalDockerfile(opt.Inputs))
+	}
+	for _, img := range imgs {
+		name, ok := namedContextName(img)
+		if !ok || hasNamedContext(contexts, name) {
+			continue
+		}
+		if pinned, ok := pin(img); ok {
+			contexts[name] = NamedContext{Path: "docker-image://" + pinned}
+		}
+	}
+	if len(contexts) > 0 {
+		opt.Inputs.NamedContexts = contexts
+	}
+}
+
+// baseImages returns the images of the FROM instructions of a Dockerfile
+// that don't refer to a stage, to scratch or to build arguments.
+func baseImages(dt []byte) []string {
+	if dt == nil {
+		return nil
+	}
+	res, err := parser.Parse(bytes.NewReader(dt))
+	if err != nil {
+		return nil
+	}
+	stages := map[string]struct{}{}
+	var imgs []string
+	for _, n := range res.AST.Children {
+		if !strings.EqualFold(n.Value, "from") || n.Next == nil {
+			continue
+		}
+		img := n.Next.Value
+		_, isStage := stages[strings.ToLower(img)]
+		if next := n.Next.Next; next != nil && strings.EqualFold(next.Value, "as") && next.Next != nil {
+			stages[strings.ToLower(next.Next.Value)] = struct{}{}
+		}
+		if isStage || strings.EqualFold(img, "scratch") || strings.Contains(img, "$") {
+			continue
+		}
+		imgs = append(imgs, img)
+	}
+	return imgs
+}
+
+// supportsNamedContexts returns whether the Dockerfile frontend image is
+// known to support named contexts, which replace the base images of the
+// Dockerfile. The builtin frontend is used if the image is empty.
+func supportsNamedContexts(frontend string) bool {
+	if frontend == "" {
+		return true
+	}
+	named, err := reference.ParseNormalizedNamed(frontend)
+	if err != nil {
+		return false
+	}
+	switch reference.FamiliarName(named) {
+	case "docker/dockerfile", "docker/dockerfile-upstream":
+	default:
+		return false
+	}
+	tag := "latest"
+	if tagged, ok := named.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	}
+	tag = strings.TrimSuffix(tag, "-labs")
+	switch tag {
+	case "latest", "labs", "master", "1":
+		return true
+	}
+	major, rest, _ := strings.Cut(tag, ".")
+	minor, _, _ := strings.Cut(rest, ".")
+	if major != "1" {
+		return false
+	}
+	n, err := strconv.Atoi(minor)
+	return err == nil && n >= 4
+}
+
+// namedContextName returns the name of the named context replacing the
+// image of a FROM instruction, as looked up by the Dockerfile frontend.
+func namedContextName(img string) (string, bool) {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(reference.FamiliarString(named), ":latest"), true
+}
+
+func hasNamedContext(contexts map[string]NamedContext, name string) bool {
+	for k := range contexts {
+		if k == name || strings.HasPrefix(k, name+"::") {
+			return true
+		}
+	}
+	return false
+}
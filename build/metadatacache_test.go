@@ -0,0 +1,145 @@
+package build
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/driver"
+	"github.com/docker/buildx/util/confutil"
+	"github.com/docker/buildx/util/imagetools"
+	clitypes "github.com/docker/cli/cli/config/types"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaseImages(t *testing.T) {
+	dt := []byte(`# syntax=docker/dockerfile:1
+ARG GO_VERSION=1.23
+FROM golang:${GO_VERSION} AS build
+FROM --platform=$BUILDPLATFORM alpine:3.20 AS base
+FROM base AS dev
+FROM BUILD AS test
+FROM scratch
+FROM docker.io/library/busybox
+FROM dev
+`)
+	require.Equal(t, []string{"alpine:3.20", "docker.io/library/busybox"}, baseImages(dt))
+	require.Nil(t, baseImages(nil))
+}
+
+func TestSupportsNamedContexts(t *testing.T) {
+	for img, expected := range map[string]bool{
+		"":                                  true,
+		"docker/dockerfile":                 true,
+		"docker/dockerfile:1":               true,
+		"docker/dockerfile:1-labs":          true,
+		"docker/dockerfile:1.4":             true,
+		"docker/dockerfile:1.10.0":          true,
+		"docker.io/docker/dockerfile:labs":  true,
+		"docker/dockerfile-upstream:master": true,
+		"docker/dockerfile:1.3":             false,
+		"docker/dockerfile:1.3-labs":        false,
+		"docker/dockerfile:2":               false,
+		"tonistiigi/dockerfile:runmount":    false,
+	} {
+		require.Equal(t, expected, supportsNamedContexts(img), img)
+	}
+}
+
+func TestPinImages(t *testing.T) {
+	cache := imagetools.NewCache(t.TempDir(), time.Minute)
+	digests := map[string]digest.Digest{}
+	for _, ref := range []string{
+		"docker.io/docker/dockerfile:1",
+		"docker.io/library/alpine:3.20",
+		"docker.io/library/busybox:latest",
+		"docker.io/library/debian:bookworm",
+	} {
+		digests[ref] = digest.FromString(ref)
+		require.NoError(t, cache.Put(ref, ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageIndex,
+			Digest:    digests[ref],
+			Size:      100,
+		}))
+	}
+	r := imagetools.New(imagetools.Opt{Cache: cache})
+
+	buildArgs := map[string]string{"FOO": "bar"}
+	opt := Options{
+		Inputs: Inputs{
+			DockerfileInline: "# syntax=docker/dockerfile:1\nFROM alpine:3.20\nFROM busybox\nFROM golang:1.23\n",
+			NamedContexts: map[string]NamedContext{
+				"golang:1.23": {Path: "../golang"},
+				"os":          {Path: "docker-image://debian:bookworm"},
+			},
+		},
+		BuildArgs: buildArgs,
+	}
+	pinImages(context.TODO(), &opt, r)
+
+	require.Equal(t, map[string]string{
+		"FOO":             "bar",
+		"BUILDKIT_SYNTAX": "docker/dockerfile:1@" + digests["docker.io/docker/dockerfile:1"].String(),
+	}, opt.BuildArgs)
+	require.Equal(t, map[string]string{"FOO": "bar"}, buildArgs)
+	require.Equal(t, map[string]NamedContext{
+		"alpine:3.20": {Path: "docker-image://alpine:3.20@" + digests["docker.io/library/alpine:3.20"].String()},
+		"busybox":     {Path: "docker-image://busybox:latest@" + digests["docker.io/library/busybox:latest"].String()},
+		"golang:1.23": {Path: "../golang"},
+		"os":          {Path: "docker-image://debian:bookworm@" + digests["docker.io/library/debian:bookworm"].String()},
+	}, opt.Inputs.NamedContexts)
+}
+
+type metadataTestAuth struct{}
+
+func (metadataTestAuth) GetAuthConfig(string) (clitypes.AuthConfig, error) {
+	return clitypes.AuthConfig{}, nil
+}
+
+type metadataTestDriver struct {
+	driver.Driver
+	moby bool
+}
+
+func (d metadataTestDriver) IsMobyDriver() bool {
+	return d.moby
+}
+
+func TestMetadataResolver(t *testing.T) {
+	cfg := confutil.NewConfig(nil, confutil.WithDir(t.TempDir()))
+	node := builder.Node{
+		Driver:   &driver.DriverHandle{Driver: metadataTestDriver{}},
+		ImageOpt: imagetools.Opt{Auth: metadataTestAuth{}},
+	}
+
+	// the cache is opt-in
+	require.Nil(t, metadataResolver(Options{}, node, cfg))
+
+	t.Setenv("BUILDX_METADATA_CACHE", "1")
+	require.NotNil(t, metadataResolver(Options{}, node, cfg))
+	require.Nil(t, metadataResolver(Options{NoMetadataCache: true}, node, cfg))
+	require.Nil(t, metadataResolver(Options{Pull: true}, node, cfg))
+
+	moby := node
+	moby.Driver = &driver.DriverHandle{Driver: metadataTestDriver{moby: true}}
+	require.Nil(t, metadataResolver(Options{}, moby, cfg))
+
+	// expired entries are pruned
+	dir := filepath.Join(cfg.Dir(), metadataCacheDir)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	expired := filepath.Join(dir, "expired")
+	require.NoError(t, os.WriteFile(expired, []byte("{}"), 0644))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(expired, old, old))
+	valid := filepath.Join(dir, "valid")
+	require.NoError(t, os.WriteFile(valid, []byte("{}"), 0644))
+
+	require.NotNil(t, metadataResolver(Options{}, node, cfg))
+	require.NoFileExists(t, expired)
+	require.FileExists(t, valid)
+}
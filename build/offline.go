@@ -0,0 +1,122 @@
+package build
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/pkg/errors"
+)
+
+// offlineCacheTypes are the cache backends that don't need network access.
+var offlineCacheTypes = map[string]struct{}{
+	"local":  {},
+	"inline": {},
+}
+
+// checkOffline returns an error listing the options of an offline build that
+// require network access, either from the client or from the builder.
+func checkOffline(opt Options) error {
+	var needs []string
+
+	if IsRemoteURL(opt.Inputs.ContextPath) {
+		needs = append(needs, fmt.Sprintf("remote build context %s", opt.Inputs.ContextPath))
+	}
+	if IsRemoteURL(opt.Inputs.DockerfilePath) {
+		needs = append(needs, fmt.Sprintf("remote Dockerfile %s", opt.Inputs.DockerfilePath))
+	}
+
+	names := make([]string, 0, len(opt.Inputs.NamedContexts))
+	for name := range opt.Inputs.NamedContexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := opt.Inputs.NamedContexts[name].Path
+		if IsRemoteURL(p) || strings.HasPrefix(p, "docker-image://") {
+			needs = append(needs, fmt.Sprintf("named context %s from %s", name, p))
+		}
+	}
+
+	if img := frontendImage(opt); img != "" {
+		needs = append(needs, fmt.Sprintf("frontend image %s", img))
+	}
+	if opt.Pull {
+		needs = append(needs, "pull of base images")
+	}
+	if v, ok := opt.Attests["sbom"]; ok && v != nil {
+		needs = append(needs, "SBOM attestation generator image")
+	}
+
+	for _, c := range opt.CacheFrom {
+		if _, ok := offlineCacheTypes[c.Type]; !ok {
+			needs = append(needs, fmt.Sprintf("cache import from %s", cacheDescription(c)))
+		}
+	}
+	for _, c := range opt.CacheTo {
+		if _, ok := offlineCacheTypes[c.Type]; !ok {
+			needs = append(needs, fmt.Sprintf("cache export to %s", cacheDescription(c)))
+		}
+	}
+	for _, e := range opt.Exports {
+		if push, _ := strconv.ParseBool(e.Attrs["push"]); push || e.Type == "registry" {
+			needs = append(needs, fmt.Sprintf("push of %s exporter", e.Type))
+		}
+	}
+
+	switch opt.NetworkMode {
+	case "", "default", "none":
+	default:
+		needs = append(needs, fmt.Sprintf("%s network for RUN instructions", opt.NetworkMode))
+	}
+
+	if len(needs) == 0 {
+		return nil
+	}
+	return errors.Errorf("network access is not allowed in offline mode but is required by:\n - %s", strings.Join(needs, "\n - "))
+}
+
+// frontendImage returns the Dockerfile frontend image set by the build
+// arguments or by the syntax directive of a local Dockerfile.
+func frontendImage(opt Options) string {
+	if img := opt.BuildArgs[FrontendImageArg]; img != "" {
+		return img
+	}
+
+	dt := LocalDockerfile(opt.Inputs)
+	if dt == nil {
+		return ""
+	}
+	img, _, _, ok := parser.DetectSyntax(dt)
+	if !ok {
+		return ""
+	}
+	return img
+}
+
+func cacheDescription(c client.CacheOptionsEntry) string {
+	if ref := c.Attrs["ref"]; ref != "" {
+		return c.Type + " " + ref
+	}
+	return c.Type
+}
+
+// imageResolveMode returns the image resolve mode passed to the frontend, or
+// an empty string to keep the frontend default. Offline builds always prefer
+// images already present on the builder so that no registry is contacted.
+func imageResolveMode(opt *Options, mobyDriver bool) string {
+	switch {
+	case opt.Pull:
+		return pb.AttrImageResolveModeForcePull
+	case opt.Offline:
+		return pb.AttrImageResolveModePreferLocal
+	case mobyDriver:
+		// moby driver always resolves local images by default
+		return pb.AttrImageResolveModePreferLocal
+	}
+	return ""
+}
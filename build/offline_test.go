@@ -0,0 +1,79 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOffline(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch\n"), 0600))
+
+	err := checkOffline(Options{
+		Inputs: Inputs{
+			ContextPath: dir,
+			NamedContexts: map[string]NamedContext{
+				"base": {Path: "oci-layout:///tmp/base"},
+			},
+		},
+		NetworkMode: "default",
+		CacheFrom:   []client.CacheOptionsEntry{{Type: "local", Attrs: map[string]string{"src": "/tmp/cache"}}},
+		Exports:     []client.ExportEntry{{Type: client.ExporterLocal, Attrs: map[string]string{"dest": "out"}}},
+	})
+	require.NoError(t, err)
+
+	sbom := "true"
+	err = checkOffline(Options{
+		Inputs: Inputs{
+			ContextPath:      "https://github.com/docker/buildx.git",
+			DockerfileInline: "# syntax=docker/dockerfile:1\nFROM scratch\n",
+			NamedContexts: map[string]NamedContext{
+				"base":  {Path: "docker-image://alpine"},
+				"local": {Path: dir},
+			},
+		},
+		Pull:        true,
+		Attests:     map[string]*string{"sbom": &sbom},
+		NetworkMode: "host",
+		CacheFrom:   []client.CacheOptionsEntry{{Type: "registry", Attrs: map[string]string{"ref": "user/app:cache"}}},
+		CacheTo:     []client.CacheOptionsEntry{{Type: "gha"}},
+		Exports:     []client.ExportEntry{{Type: client.ExporterImage, Attrs: map[string]string{"push": "true"}}},
+	})
+	require.Error(t, err)
+	require.Equal(t, `network access is not allowed in offline mode but is required by:
+ - remote build context https://github.com/docker/buildx.git
+ - named context base from docker-image://alpine
+ - frontend image docker/dockerfile:1
+ - pull of base images
+ - SBOM attestation generator image
+ - cache import from registry user/app:cache
+ - cache export to gha
+ - push of image exporter
+ - host network for RUN instructions`, err.Error())
+}
+
+func TestCheckOfflineSyntaxDirective(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("# syntax=docker/dockerfile:1.7\nFROM scratch\n"), 0600))
+
+	err := checkOffline(Options{Inputs: Inputs{ContextPath: dir}})
+	require.EqualError(t, err, "network access is not allowed in offline mode but is required by:\n - frontend image docker/dockerfile:1.7")
+
+	err = checkOffline(Options{
+		Inputs:    Inputs{ContextPath: dir},
+		BuildArgs: map[string]string{FrontendImageArg: "docker/dockerfile:1.8"},
+	})
+	require.EqualError(t, err, "network access is not allowed in offline mode but is required by:\n - frontend image docker/dockerfile:1.8")
+}
+
+func TestImageResolveMode(t *testing.T) {
+	require.Equal(t, "", imageResolveMode(&Options{}, false))
+	require.Equal(t, pb.AttrImageResolveModePreferLocal, imageResolveMode(&Options{}, true))
+	require.Equal(t, pb.AttrImageResolveModeForcePull, imageResolveMode(&Options{Pull: true}, false))
+	require.Equal(t, pb.AttrImageResolveModePreferLocal, imageResolveMode(&Options{Offline: true}, false))
+}
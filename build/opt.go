@@ -3,7 +3,9 @@ package build
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
@@ -17,9 +19,11 @@ import (
 	"github.com/distribution/reference"
 	"github.com/docker/buildx/builder"
 	"github.com/docker/buildx/driver"
+	"github.com/docker/buildx/util/buildflags"
 	"github.com/docker/buildx/util/confutil"
 	"github.com/docker/buildx/util/dockerutil"
 	"github.com/docker/buildx/util/osutil"
+	"github.com/docker/buildx/util/platformutil"
 	"github.com/docker/buildx/util/progress"
 	"github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/client/llb"
@@ -31,8 +35,10 @@ import (
 	"github.com/moby/buildkit/util/apicaps"
 	"github.com/moby/buildkit/util/entitlements"
 	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 	"github.com/tonistiigi/fsutil"
+	"github.com/tonistiigi/go-csvvalue"
 )
 
 func toSolveOpt(ctx context.Context, node builder.Node, multiDriver bool, opt *Options, bopts gateway.BuildOpts, cfg *confutil.Config, pw progress.Writer, docker *dockerutil.Client) (_ *client.SolveOpt, release func(), err error) {
@@ -126,6 +132,13 @@ func toSolveOpt(ctx context.Context, node builder.Node, multiDriver bool, opt *O
 			attests[k] = *v
 		}
 	}
+	if v, ok := attests["provenance"]; ok {
+		attrs, _, err := provenanceVCSAttrs(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		attests["provenance"] = attrs
+	}
 
 	supportAttestations := bopts.LLBCaps.Contains(apicaps.CapID("exporter.image.attestations")) && nodeDriver.Features(ctx)[driver.MultiPlatform]
 	if len(attests) > 0 {
@@ -219,6 +232,8 @@ func toSolveOpt(ctx context.Context, node builder.Node, multiDriver bool, opt *O
 				// rely on oci importer if available (which supports
 				// multi-platform images), otherwise fall back to docker
 				opt.Exports[i].Type = "oci"
+			} else if e.Output == nil && !nodeDriver.IsMobyDriver() && hasWasmPlatform(opt.Platforms) {
+				return nil, nil, errWasmLoad
 			} else if len(opt.Platforms) > 1 || len(attests) > 0 {
 				if e.Output != nil {
 					return nil, nil, errors.Errorf("docker exporter does not support exporting manifest lists, use the oci exporter instead")
@@ -229,11 +244,16 @@ func toSolveOpt(ctx context.Context, node builder.Node, multiDriver bool, opt *O
 				if nodeDriver.IsMobyDriver() {
 					e.Type = "image"
 				} else {
-					w, cancel, err := docker.LoadImage(ctx, e.Attrs["context"], pw)
+					var images []string
+					if e.Attrs["name"] != "" {
+						images = strings.Split(e.Attrs["name"], ",")
+					}
+					w, cancel, err := docker.LoadImage(ctx, e.Attrs["context"], images, pw)
 					if err != nil {
 						return nil, nil, err
 					}
 					defers = append(defers, cancel)
+					opt.dockerLoads = append(opt.dockerLoads, w)
 					opt.Exports[i].Output = func(_ map[string]string) (io.WriteCloser, error) {
 						return w, nil
 					}
@@ -243,6 +263,9 @@ func toSolveOpt(ctx context.Context, node builder.Node, multiDriver bool, opt *O
 			}
 		}
 		if e.Type == "image" && nodeDriver.IsMobyDriver() {
+			if hasWasmPlatform(opt.Platforms) && !nodeDriver.Features(ctx)[driver.MultiPlatform] {
+				return nil, nil, errWasmLoad
+			}
 			opt.Exports[i].Type = "moby"
 			if e.Attrs["push"] != "" {
 				if ok, _ := strconv.ParseBool(e.Attrs["push"]); ok {
@@ -274,11 +297,8 @@ func toSolveOpt(ctx context.Context, node builder.Node, multiDriver bool, opt *O
 		so.SharedKey += ":" + cfg.TryNodeIdentifier()
 	}
 
-	if opt.Pull {
-		so.FrontendAttrs["image-resolve-mode"] = pb.AttrImageResolveModeForcePull
-	} else if nodeDriver.IsMobyDriver() {
-		// moby driver always resolves local images by default
-		so.FrontendAttrs["image-resolve-mode"] = pb.AttrImageResolveModePreferLocal
+	if mode := imageResolveMode(opt, nodeDriver.IsMobyDriver()); mode != "" {
+		so.FrontendAttrs["image-resolve-mode"] = mode
 	}
 	if opt.Target != "" {
 		so.FrontendAttrs["target"] = opt.Target
@@ -315,12 +335,16 @@ func toSolveOpt(ctx context.Context, node builder.Node, multiDriver bool, opt *O
 	}
 
 	// setup networkmode
-	switch opt.NetworkMode {
+	networkMode := opt.NetworkMode
+	if opt.Offline && (networkMode == "" || networkMode == "default") {
+		networkMode = "none"
+	}
+	switch networkMode {
 	case "host":
-		so.FrontendAttrs["force-network-mode"] = opt.NetworkMode
+		so.FrontendAttrs["force-network-mode"] = networkMode
 		so.AllowedEntitlements = append(so.AllowedEntitlements, entitlements.EntitlementNetworkHost)
 	case "none":
-		so.FrontendAttrs["force-network-mode"] = opt.NetworkMode
+		so.FrontendAttrs["force-network-mode"] = networkMode
 	case "", "default":
 	default:
 		return nil, nil, errors.Errorf("network mode %q not supported by buildkit - you can define a custom network for your builder using the network driver-opt in buildx create", opt.NetworkMode)
@@ -335,6 +359,13 @@ func toSolveOpt(ctx context.Context, node builder.Node, multiDriver bool, opt *O
 		so.FrontendAttrs["add-hosts"] = extraHosts
 	}
 
+	// setup dns
+	dnsAttrs, err := toBuildkitDNS(opt.DNS)
+	if err != nil {
+		return nil, nil, err
+	}
+	maps.Copy(so.FrontendAttrs, dnsAttrs)
+
 	// setup shm size
 	if opt.ShmSize.Value() > 0 {
 		so.FrontendAttrs["shm-size"] = strconv.FormatInt(opt.ShmSize.Value(), 10)
@@ -372,6 +403,23 @@ func loadInputs(ctx context.Context, d *driver.DriverHandle, inp *Inputs, pw pro
 		toRemove          []string
 	)
 
+	if inp.ContextState == nil && IsRemoteURL(inp.ContextPath) {
+		// git contexts with clone options are cloned on the client and
+		// sent as local contexts
+		p, dir, err := cloneGitContext(ctx, inp.ContextPath, pw)
+		if err != nil {
+			return nil, err
+		}
+		if dir != "" {
+			toRemove = append(toRemove, dir)
+			if inp.DockerfilePath != "" && inp.DockerfilePath != "-" && !filepath.IsAbs(inp.DockerfilePath) && !IsRemoteURL(inp.DockerfilePath) {
+				// relative to the remote context
+				inp.DockerfilePath = filepath.Join(p, inp.DockerfilePath)
+			}
+			inp.ContextPath = p
+		}
+	}
+
 	switch {
 	case inp.ContextState != nil:
 		if target.FrontendInputs == nil {
@@ -392,6 +440,14 @@ func loadInputs(ctx context.Context, d *driver.DriverHandle, inp *Inputs, pw pro
 		if !(err == io.EOF && len(magic) == 0) {
 			if isArchive(magic) {
 				// stdin is context
+				dockerfileName := inp.DockerfilePath
+				if dockerfileName == "" {
+					dockerfileName = "Dockerfile"
+				}
+				rc, err = filterTarContext(rc, dockerfileName)
+				if err != nil {
+					return nil, err
+				}
 				up := uploadprovider.New()
 				target.FrontendAttrs["context"] = up.Add(rc)
 				target.Session = append(target.Session, up)
@@ -492,6 +548,16 @@ func loadInputs(ctx context.Context, d *driver.DriverHandle, inp *Inputs, pw pro
 			continue
 		}
 
+		if IsRemoteURL(v.Path) {
+			p, dir, err := cloneGitContext(ctx, v.Path, pw)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to clone build context %v", k)
+			}
+			if dir != "" {
+				toRemove = append(toRemove, dir)
+				v.Path = p
+			}
+		}
 		if IsRemoteURL(v.Path) || strings.HasPrefix(v.Path, "docker-image://") || strings.HasPrefix(v.Path, "target:") {
 			target.FrontendAttrs["context:"+k] = v.Path
 			continue
@@ -515,6 +581,16 @@ func loadInputs(ctx context.Context, d *driver.DriverHandle, inp *Inputs, pw pro
 			if err != nil {
 				return nil, errors.Wrapf(err, "invalid store at %s", localPath)
 			}
+			if hasDigest {
+				// check the pinned digest early instead of failing in the frontend
+				dgst, err := digest.Parse(dig)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid digest in oci-layout reference %q", v.Path)
+				}
+				if _, err := store.Info(ctx, dgst); err != nil {
+					return nil, errors.Wrapf(err, "oci-layout reference %q could not be resolved", v.Path)
+				}
+			}
 			storeName := identity.NewID()
 			if target.OCIStores == nil {
 				target.OCIStores = map[string]content.Store{}
@@ -655,3 +731,49 @@ type fs struct {
 }
 
 var _ fsutil.FS = &fs{}
+
+// errWasmLoad is returned when WebAssembly images would be loaded into a
+// Docker image store that can't hold them.
+var errWasmLoad = errors.New("loading WebAssembly images requires the containerd image store, see https://docs.docker.com/go/containerd-image-store/ (or use --output type=oci,dest=<file> or --push instead)")
+
+func hasWasmPlatform(pp []specs.Platform) bool {
+	for _, p := range pp {
+		if platformutil.IsWasm(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// provenanceVCSAttrs removes the buildx-specific vcs attribute from the
+// provenance attestation attributes and reports whether version control
+// information should be embedded in the provenance.
+func provenanceVCSAttrs(in string) (string, bool, error) {
+	fields, err := csvvalue.Fields(in, nil)
+	if err != nil {
+		return "", false, err
+	}
+	vcs := true
+	out := make([]string, 0, len(fields))
+	for _, field := range fields {
+		key, value, _ := strings.Cut(field, "=")
+		if strings.TrimSpace(strings.ToLower(key)) == buildflags.ProvenanceAttrVCS {
+			vcs, err = strconv.ParseBool(value)
+			if err != nil {
+				return "", false, errors.Wrapf(err, "invalid provenance %s value %q", buildflags.ProvenanceAttrVCS, value)
+			}
+			continue
+		}
+		out = append(out, field)
+	}
+	if len(out) == len(fields) {
+		return in, vcs, nil
+	}
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(out); err != nil {
+		return "", false, err
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n"), vcs, nil
+}
@@ -0,0 +1,22 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvenanceVCSAttrs(t *testing.T) {
+	attrs, vcs, err := provenanceVCSAttrs("type=provenance,mode=max")
+	require.NoError(t, err)
+	require.True(t, vcs)
+	require.Equal(t, "type=provenance,mode=max", attrs)
+
+	attrs, vcs, err = provenanceVCSAttrs("type=provenance,vcs=false,builder-id=https://example.com/builder")
+	require.NoError(t, err)
+	require.False(t, vcs)
+	require.Equal(t, "type=provenance,builder-id=https://example.com/builder", attrs)
+
+	_, _, err = provenanceVCSAttrs("type=provenance,vcs=maybe")
+	require.ErrorContains(t, err, `invalid provenance vcs value "maybe"`)
+}
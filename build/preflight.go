@@ -0,0 +1,113 @@
+package build
+
+import (
+	stderrors "errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/moby/buildkit/client"
+	"github.com/pkg/errors"
+)
+
+// exporterBoolAttrs are the exporter attributes that only accept a boolean.
+var exporterBoolAttrs = []string{
+	"push",
+	"push-by-digest",
+	"insecure",
+	"unpack",
+	"store",
+	"oci-mediatypes",
+	"force-compression",
+	"buildinfo",
+	"tar",
+}
+
+// Preflight validates the tags and the exporter attributes of the given
+// targets, and the network needs of offline builds, without connecting to a
+// builder, so that a build fails before its
+// context is uploaded. All the errors found are returned at once.
+func Preflight(opts map[string]Options) error {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, k := range keys {
+		for _, err := range preflightOptions(opts[k]) {
+			if len(opts) > 1 {
+				err = errors.Wrapf(err, "target %q", k)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+func preflightOptions(opt Options) []error {
+	var errs []error
+	for _, tag := range opt.Tags {
+		if _, err := reference.Parse(tag); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid tag %q", tag))
+		}
+	}
+	for _, e := range opt.Exports {
+		errs = append(errs, preflightExport(e, len(opt.Tags) > 0)...)
+	}
+	if opt.Offline {
+		if err := checkOffline(opt); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func preflightExport(e client.ExportEntry, tagged bool) []error {
+	var errs []error
+	for _, k := range exporterBoolAttrs {
+		if v, ok := e.Attrs[k]; ok {
+			if _, err := strconv.ParseBool(v); err != nil {
+				errs = append(errs, errors.Errorf("invalid value %q for %s exporter attribute %s, expecting a boolean", v, e.Type, k))
+			}
+		}
+	}
+
+	switch e.Type {
+	case client.ExporterImage, "registry", client.ExporterOCI, client.ExporterDocker:
+	default:
+		return errs
+	}
+	if names, ok := e.Attrs["name"]; ok && !tagged {
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if _, err := reference.Parse(name); err != nil {
+				errs = append(errs, errors.Wrapf(err, "invalid name %q for %s exporter", name, e.Type))
+			}
+		}
+	}
+	if v, ok := e.Attrs["compression"]; ok {
+		c := v
+		if l, ok := e.Attrs["compression-level"]; ok {
+			c += ":" + l
+		}
+		if _, err := buildflags.ParseCompression(c); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid compression for %s exporter", e.Type))
+		}
+	} else if v, ok := e.Attrs["compression-level"]; ok {
+		if _, err := strconv.Atoi(v); err != nil {
+			errs = append(errs, errors.Errorf("invalid value %q for %s exporter attribute compression-level, expecting an integer", v, e.Type))
+		}
+	}
+	if !tagged && e.Type == client.ExporterImage && e.Attrs["name"] == "" {
+		if push, _ := strconv.ParseBool(e.Attrs["push"]); push {
+			errs = append(errs, errors.Errorf("tag is needed when pushing to registry"))
+		}
+	}
+	return errs
+}
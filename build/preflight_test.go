@@ -0,0 +1,43 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/moby/buildkit/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreflight(t *testing.T) {
+	err := Preflight(map[string]Options{
+		"default": {
+			Tags: []string{"docker.io/foo/bar:latest", "foo/bar:v1"},
+			Exports: []client.ExportEntry{
+				{Type: client.ExporterImage, Attrs: map[string]string{"push": "true", "compression": "zstd", "compression-level": "3"}},
+				{Type: client.ExporterLocal, Attrs: map[string]string{"dest": "out"}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = Preflight(map[string]Options{
+		"app": {
+			Tags: []string{"Foo/Bar", "foo/bar:latest"},
+			Exports: []client.ExportEntry{
+				{Type: client.ExporterImage, Attrs: map[string]string{"push": "yes", "compression": "lz4"}},
+			},
+		},
+		"db": {
+			Exports: []client.ExportEntry{
+				{Type: client.ExporterImage, Attrs: map[string]string{"name": "db:latest,db:!", "compression-level": "high"}},
+				{Type: client.ExporterImage, Attrs: map[string]string{"push": "true"}},
+			},
+		},
+	})
+	require.Error(t, err)
+	require.Equal(t, `target "app": invalid tag "Foo/Bar": repository name must be lowercase
+target "app": invalid value "yes" for image exporter attribute push, expecting a boolean
+target "app": invalid compression for image exporter: invalid compression type "lz4", expecting one of uncompressed, gzip, estargz, zstd
+target "db": invalid name "db:!" for image exporter: invalid reference format
+target "db": invalid value "high" for image exporter attribute compression-level, expecting an integer
+target "db": tag is needed when pushing to registry`, err.Error())
+}
@@ -79,7 +79,7 @@ func fetchProvenance(ctx context.Context, c *client.Client, ref string, mode con
 				if err != nil {
 					return errors.Wrapf(err, "failed to load provenance blob from build record")
 				}
-				prv, err := encodeProvenance(dt, mode)
+				res, err := provenanceResponse(dt, mode, "")
 				if err != nil {
 					return err
 				}
@@ -87,7 +87,9 @@ func fetchProvenance(ctx context.Context, c *client.Client, ref string, mode con
 				if out == nil {
 					out = make(map[string]string)
 				}
-				out["buildx.build.provenance"] = prv
+				for k, v := range res {
+					out[k] = v
+				}
 				mu.Unlock()
 				return nil
 			})
@@ -103,7 +105,7 @@ func fetchProvenance(ctx context.Context, c *client.Client, ref string, mode con
 					if err != nil {
 						return errors.Wrapf(err, "failed to load provenance blob from build record")
 					}
-					prv, err := encodeProvenance(dt, mode)
+					res, err := provenanceResponse(dt, mode, "/"+platform)
 					if err != nil {
 						return err
 					}
@@ -111,7 +113,9 @@ func fetchProvenance(ctx context.Context, c *client.Client, ref string, mode con
 					if out == nil {
 						out = make(map[string]string)
 					}
-					out["buildx.build.provenance/"+platform] = prv
+					for k, v := range res {
+						out[k] = v
+					}
 					mu.Unlock()
 					return nil
 				})
@@ -135,11 +139,30 @@ func lookupProvenance(res *controlapi.BuildResultInfo) *ocispecs.Descriptor {
 	return nil
 }
 
-func encodeProvenance(dt []byte, mode confutil.MetadataProvenanceMode) (string, error) {
+// provenanceResponse returns the exporter response entries of the provenance
+// and of the resource usage of the build steps, with keys suffixed by suffix.
+func provenanceResponse(dt []byte, mode confutil.MetadataProvenanceMode, suffix string) (map[string]string, error) {
 	var prv provenancePredicate
 	if err := json.Unmarshal(dt, &prv); err != nil {
-		return "", errors.Wrapf(err, "failed to unmarshal provenance")
+		return nil, errors.Wrapf(err, "failed to unmarshal provenance")
+	}
+	out := make(map[string]string, 2)
+	if res := stepResources(prv.BuildConfig); len(res) > 0 {
+		v, err := encodeResources(res)
+		if err != nil {
+			return nil, err
+		}
+		out[ExporterResponseResources+suffix] = v
 	}
+	v, err := encodeProvenance(prv, mode)
+	if err != nil {
+		return nil, err
+	}
+	out["buildx.build.provenance"+suffix] = v
+	return out, nil
+}
+
+func encodeProvenance(prv provenancePredicate, mode confutil.MetadataProvenanceMode) (string, error) {
 	if prv.Builder != nil && prv.Builder.ID == "" {
 		// reset builder if id is empty
 		prv.Builder = nil
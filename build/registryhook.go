@@ -0,0 +1,62 @@
+package build
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/buildx/util/progress"
+	"github.com/docker/buildx/util/registryhook"
+	"github.com/pkg/errors"
+)
+
+// createRepositories runs the registry pre-push hooks for image outputs that
+// set the create-repo attribute. It returns a copy of opts where the
+// attribute is removed from the outputs, as it is only understood by buildx.
+func createRepositories(ctx context.Context, opts map[string]Options, drivers map[string][]*resolvedNode, w progress.Writer) (map[string]Options, error) {
+	out := make(map[string]Options, len(opts))
+	for k, opt := range opts {
+		var names []string
+		var cloned bool
+		for i, e := range opt.Exports {
+			v, ok := e.Attrs["create-repo"]
+			if !ok {
+				continue
+			}
+			if !cloned {
+				opt.Exports = slices.Clone(opt.Exports)
+				cloned = true
+			}
+			attrs := maps.Clone(e.Attrs)
+			delete(attrs, "create-repo")
+			opt.Exports[i].Attrs = attrs
+
+			createRepo, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid create-repo value %q", v)
+			}
+			push, _ := strconv.ParseBool(e.Attrs["push"])
+			if !createRepo || !push || e.Type != "image" {
+				continue
+			}
+			if len(opt.Tags) > 0 {
+				names = append(names, opt.Tags...)
+			} else if name := e.Attrs["name"]; name != "" {
+				names = append(names, strings.Split(name, ",")...)
+			}
+		}
+		out[k] = opt
+		if len(names) == 0 || len(drivers[k]) == 0 {
+			continue
+		}
+		auth := drivers[k][0].Node().ImageOpt.Auth
+		if err := progress.Wrap("creating repositories", w.Write, func(progress.SubLogger) error {
+			return registryhook.CreateRepositories(ctx, names, auth)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,33 @@
+package build
+
+import (
+	"context"
+	"testing"
+
+	"github.com/moby/buildkit/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRepositoriesStripsAttribute(t *testing.T) {
+	opts := map[string]Options{
+		"default": {
+			Exports: []client.ExportEntry{
+				{Type: "image", Attrs: map[string]string{"name": "user/app", "push": "false", "create-repo": "true"}},
+				{Type: "local", Attrs: map[string]string{"dest": "out"}},
+			},
+		},
+	}
+
+	out, err := createRepositories(context.TODO(), opts, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"name": "user/app", "push": "false"}, out["default"].Exports[0].Attrs)
+	require.Equal(t, map[string]string{"dest": "out"}, out["default"].Exports[1].Attrs)
+
+	// the options of the caller are left untouched
+	require.Equal(t, "true", opts["default"].Exports[0].Attrs["create-repo"])
+
+	_, err = createRepositories(context.TODO(), map[string]Options{
+		"default": {Exports: []client.ExportEntry{{Type: "image", Attrs: map[string]string{"create-repo": "maybe"}}}},
+	}, nil, nil)
+	require.ErrorContains(t, err, `invalid create-repo value "maybe"`)
+}
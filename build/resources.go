@@ -0,0 +1,133 @@
+package build
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	resourcestypes "github.com/moby/buildkit/executor/resources/types"
+	provenancetypes "github.com/moby/buildkit/solver/llbsolver/provenance/types"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// ExporterResponseResources is the key of the exporter response holding the
+// resource usage of the build steps, suffixed with the platform for
+// multi-platform builds.
+const ExporterResponseResources = "buildx.build.resources"
+
+// StepResources is the resource usage of a build step sampled by BuildKit.
+// It is only available for the steps running processes, such as RUN
+// instructions, on builders that support resource sampling.
+type StepResources struct {
+	Vertex digest.Digest `json:"vertex"`
+	// CPUNanos is the CPU time used by the step, in nanoseconds.
+	CPUNanos uint64 `json:"cpuNanos,omitempty"`
+	// MemoryPeak is the peak memory usage of the step, in bytes.
+	MemoryPeak uint64 `json:"memoryPeak,omitempty"`
+	// OOMKills is the number of processes killed because the step ran out of
+	// memory.
+	OOMKills     uint64 `json:"oomKills,omitempty"`
+	IOReadBytes  uint64 `json:"ioReadBytes,omitempty"`
+	IOWriteBytes uint64 `json:"ioWriteBytes,omitempty"`
+}
+
+// stepResources returns the resource usage of the steps of the build config,
+// sorted by vertex digest.
+func stepResources(cfg *provenancetypes.BuildConfig) []StepResources {
+	if cfg == nil {
+		return nil
+	}
+	vertexes := map[string][]digest.Digest{}
+	for dgst, id := range cfg.DigestMapping {
+		vertexes[id] = append(vertexes[id], dgst)
+	}
+	var res []StepResources
+	for _, step := range cfg.Definition {
+		if step.ResourceUsage == nil || len(step.ResourceUsage.Samples) == 0 {
+			continue
+		}
+		r := summarizeSamples(step.ResourceUsage.Samples)
+		for _, dgst := range vertexes[step.ID] {
+			r.Vertex = dgst
+			res = append(res, r)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Vertex < res[j].Vertex
+	})
+	return res
+}
+
+// summarizeSamples returns the highest values of the samples. The CPU and IO
+// counters are cumulative so their highest value is the total of the step.
+func summarizeSamples(samples []*resourcestypes.Sample) StepResources {
+	var r StepResources
+	for _, s := range samples {
+		if s == nil {
+			continue
+		}
+		if s.CPUStat != nil && s.CPUStat.UsageNanos != nil {
+			r.CPUNanos = max(r.CPUNanos, *s.CPUStat.UsageNanos)
+		}
+		if m := s.MemoryStat; m != nil {
+			if m.Peak != nil {
+				r.MemoryPeak = max(r.MemoryPeak, *m.Peak)
+			} else {
+				// memory.peak is not available on older kernels
+				var current uint64
+				for _, v := range []*uint64{m.Anon, m.File, m.Kernel} {
+					if v != nil {
+						current += *v
+					}
+				}
+				r.MemoryPeak = max(r.MemoryPeak, current)
+			}
+			r.OOMKills = max(r.OOMKills, m.OomKillEvents)
+		}
+		if s.IOStat != nil {
+			if s.IOStat.ReadBytes != nil {
+				r.IOReadBytes = max(r.IOReadBytes, *s.IOStat.ReadBytes)
+			}
+			if s.IOStat.WriteBytes != nil {
+				r.IOWriteBytes = max(r.IOWriteBytes, *s.IOStat.WriteBytes)
+			}
+		}
+	}
+	return r
+}
+
+func encodeResources(res []StepResources) (string, error) {
+	dt, err := json.Marshal(res)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal resource usage")
+	}
+	return base64.StdEncoding.EncodeToString(dt), nil
+}
+
+// DecodeResources returns the resource usage of the build steps held in the
+// exporter response, keyed by vertex digest.
+func DecodeResources(exporterResponse map[string]string) (map[digest.Digest]StepResources, error) {
+	var out map[digest.Digest]StepResources
+	for k, v := range exporterResponse {
+		if k != ExporterResponseResources && !strings.HasPrefix(k, ExporterResponseResources+"/") {
+			continue
+		}
+		dt, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s", k)
+		}
+		var res []StepResources
+		if err := json.Unmarshal(dt, &res); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal %s", k)
+		}
+		for _, r := range res {
+			if out == nil {
+				out = make(map[digest.Digest]StepResources)
+			}
+			out[r.Vertex] = r
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package build
+
+import (
+	"testing"
+
+	resourcestypes "github.com/moby/buildkit/executor/resources/types"
+	provenancetypes "github.com/moby/buildkit/solver/llbsolver/provenance/types"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepResources(t *testing.T) {
+	u := func(v uint64) *uint64 {
+		return &v
+	}
+	run := digest.FromString("run")
+	cp := digest.FromString("copy")
+
+	cfg := &provenancetypes.BuildConfig{
+		Definition: []provenancetypes.BuildStep{
+			{
+				ID: "step0",
+				ResourceUsage: &resourcestypes.Samples{
+					Samples: []*resourcestypes.Sample{
+						{
+							CPUStat:    &resourcestypes.CPUStat{UsageNanos: u(1000)},
+							MemoryStat: &resourcestypes.MemoryStat{Peak: u(4096)},
+							IOStat:     &resourcestypes.IOStat{ReadBytes: u(10), WriteBytes: u(20)},
+						},
+						{
+							CPUStat:    &resourcestypes.CPUStat{UsageNanos: u(3000)},
+							MemoryStat: &resourcestypes.MemoryStat{Peak: u(2048), OomKillEvents: 1},
+							IOStat:     &resourcestypes.IOStat{ReadBytes: u(30), WriteBytes: u(40)},
+						},
+					},
+				},
+			},
+			{
+				ID: "step1",
+				ResourceUsage: &resourcestypes.Samples{
+					Samples: []*resourcestypes.Sample{
+						{MemoryStat: &resourcestypes.MemoryStat{Anon: u(100), File: u(200)}},
+					},
+				},
+			},
+			{ID: "step2"},
+		},
+		DigestMapping: map[digest.Digest]string{
+			run:                    "step0",
+			cp:                     "step1",
+			digest.FromString("x"): "step2",
+		},
+	}
+
+	res := stepResources(cfg)
+	expected := []StepResources{
+		{Vertex: run, CPUNanos: 3000, MemoryPeak: 4096, OOMKills: 1, IOReadBytes: 30, IOWriteBytes: 40},
+		{Vertex: cp, MemoryPeak: 300},
+	}
+	require.ElementsMatch(t, expected, res)
+
+	v, err := encodeResources(res)
+	require.NoError(t, err)
+	decoded, err := DecodeResources(map[string]string{
+		ExporterResponseResources + "/linux/amd64": v,
+		"buildx.build.provenance/linux/amd64":      "e30=",
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[digest.Digest]StepResources{
+		run: expected[0],
+		cp:  expected[1],
+	}, decoded)
+
+	require.Nil(t, stepResources(nil))
+}
@@ -0,0 +1,184 @@
+package build
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/containerd/platforms"
+	"github.com/docker/buildx/builder"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// SchedulingMode is the way platforms are distributed across the nodes of a
+// builder when several of them support a platform.
+type SchedulingMode string
+
+const (
+	// SchedulingDefault picks the node with the closest platform match, the
+	// first node of the builder winning ties.
+	SchedulingDefault SchedulingMode = ""
+	// SchedulingPreferNative picks a node running the platform natively over
+	// one running it under emulation.
+	SchedulingPreferNative SchedulingMode = "prefer-native"
+	// SchedulingLeastLoaded picks the node that was scheduled the fewest
+	// platforms of the build so far.
+	SchedulingLeastLoaded SchedulingMode = "least-loaded"
+	// SchedulingPinned only uses the given nodes, in order of preference.
+	SchedulingPinned SchedulingMode = "pinned"
+)
+
+// SchedulingPolicy controls how the platforms of a multi-platform build are
+// distributed across the nodes of a builder.
+type SchedulingPolicy struct {
+	Mode SchedulingMode
+	// Nodes are the names of the nodes platforms are pinned to.
+	Nodes []string
+	// Builder is the builder whose nodes are used when the nodes of several
+	// builders are available. The builder of the first node is used if
+	// empty.
+	Builder string
+	// PlatformBuilders maps normalized platforms to the builder they are
+	// built on, taking precedence over Builder.
+	PlatformBuilders map[string]string
+}
+
+// ParseSchedulingPolicy parses a policy in the form "prefer-native",
+// "least-loaded" or "pinned:node1[,node2...]".
+func ParseSchedulingPolicy(s string) (SchedulingPolicy, error) {
+	mode, nodes, _ := strings.Cut(s, ":")
+	switch SchedulingMode(mode) {
+	case SchedulingDefault, SchedulingPreferNative, SchedulingLeastLoaded:
+		if nodes != "" {
+			return SchedulingPolicy{}, errors.Errorf("invalid scheduling policy %q, only pinned takes a list of nodes", s)
+		}
+		return SchedulingPolicy{Mode: SchedulingMode(mode)}, nil
+	case SchedulingPinned:
+		var p SchedulingPolicy
+		p.Mode = SchedulingPinned
+		for _, n := range strings.Split(nodes, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				p.Nodes = append(p.Nodes, n)
+			}
+		}
+		if len(p.Nodes) == 0 {
+			return SchedulingPolicy{}, errors.Errorf("invalid scheduling policy %q, expecting pinned:NODE[,NODE...]", s)
+		}
+		return p, nil
+	default:
+		return SchedulingPolicy{}, errors.Errorf("invalid scheduling policy %q, expecting prefer-native, least-loaded or pinned:NODE[,NODE...]", s)
+	}
+}
+
+func (p SchedulingPolicy) String() string {
+	if p.Mode == SchedulingPinned {
+		return string(p.Mode) + ":" + strings.Join(p.Nodes, ",")
+	}
+	return string(p.Mode)
+}
+
+// validate checks that the pinned nodes and the builders are available.
+func (p SchedulingPolicy) validate(nodes []builder.Node) error {
+	for _, n := range p.Nodes {
+		if !slices.ContainsFunc(nodes, func(node builder.Node) bool {
+			return node.Name == n
+		}) {
+			return errors.Errorf("unknown node %q in scheduling policy", n)
+		}
+	}
+	builders := make([]string, 0, len(p.PlatformBuilders)+1)
+	if p.Builder != "" {
+		builders = append(builders, p.Builder)
+	}
+	for _, name := range p.PlatformBuilders {
+		builders = append(builders, name)
+	}
+	slices.Sort(builders)
+	for _, name := range builders {
+		if !slices.ContainsFunc(nodes, func(node builder.Node) bool {
+			return node.Builder == name
+		}) {
+			return errors.Errorf("no available node for builder %q", name)
+		}
+	}
+	return nil
+}
+
+// builderFor returns the builder platform pl is built on, or the default
+// builder if pl is nil. An empty name means that any node can be used.
+func (p SchedulingPolicy) builderFor(pl *specs.Platform) string {
+	if pl != nil {
+		if name, ok := p.PlatformBuilders[platforms.Format(platforms.Normalize(*pl))]; ok {
+			return name
+		}
+	}
+	return p.Builder
+}
+
+// allows returns whether platforms built on the given builder can be
+// scheduled on the node.
+func (p SchedulingPolicy) allows(node builder.Node, builderName string) bool {
+	if builderName != "" && node.Builder != builderName {
+		return false
+	}
+	return p.Mode != SchedulingPinned || slices.Contains(p.Nodes, node.Name)
+}
+
+// fallback returns the node used for platforms no node of the builder
+// supports.
+func (p SchedulingPolicy) fallback(nodes []builder.Node, builderName string) int {
+	if p.Mode == SchedulingPinned {
+		for _, name := range p.Nodes {
+			for i, node := range nodes {
+				if node.Name == name && (builderName == "" || node.Builder == builderName) {
+					return i
+				}
+			}
+		}
+	}
+	if builderName != "" {
+		for i, node := range nodes {
+			if node.Builder == builderName {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// compareNodes returns a negative number if the node at index a is preferred
+// over the node at index b to build platform p, a positive number if b is
+// preferred, and zero if policy has no preference.
+func (r *nodeResolver) compareNodes(p specs.Platform, policy SchedulingPolicy, a, b int, additional func(int, builder.Node) []specs.Platform) int {
+	if a == b {
+		return 0
+	}
+	switch policy.Mode {
+	case SchedulingPreferNative:
+		na, nb := r.isNative(a, p, additional), r.isNative(b, p, additional)
+		switch {
+		case na && !nb:
+			return -1
+		case nb && !na:
+			return 1
+		}
+	case SchedulingLeastLoaded:
+		return r.load[a] - r.load[b]
+	case SchedulingPinned:
+		return slices.Index(policy.Nodes, r.nodes[a].Name) - slices.Index(policy.Nodes, r.nodes[b].Name)
+	}
+	return 0
+}
+
+// isNative returns whether the node runs p natively. The native platform of
+// a node is the first one it lists.
+func (r *nodeResolver) isNative(idx int, p specs.Platform, additional func(int, builder.Node) []specs.Platform) bool {
+	ps := r.nodes[idx].Platforms
+	if len(ps) == 0 && additional != nil {
+		ps = additional(idx, r.nodes[idx])
+	}
+	if len(ps) == 0 {
+		return false
+	}
+	return isNativePlatform([]specs.Platform{platforms.Normalize(ps[0])}, p)
+}
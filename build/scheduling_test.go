@@ -0,0 +1,166 @@
+package build
+
+import (
+	"context"
+	"testing"
+
+	"github.com/containerd/platforms"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedulingPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		expected SchedulingPolicy
+		err      string
+	}{
+		{in: ""},
+		{in: "prefer-native", expected: SchedulingPolicy{Mode: SchedulingPreferNative}},
+		{in: "least-loaded", expected: SchedulingPolicy{Mode: SchedulingLeastLoaded}},
+		{in: "pinned:node1, node2", expected: SchedulingPolicy{Mode: SchedulingPinned, Nodes: []string{"node1", "node2"}}},
+		{in: "pinned", err: "expecting pinned:NODE"},
+		{in: "least-loaded:node1", err: "only pinned takes a list of nodes"},
+		{in: "random", err: `invalid scheduling policy "random"`},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			p, err := ParseSchedulingPolicy(tc.in)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, p)
+		})
+	}
+}
+
+func TestSchedulingPreferNative(t *testing.T) {
+	r := makeSchedulingTestResolver(map[string][]specs.Platform{
+		"aaa": {platforms.MustParse("linux/amd64"), platforms.MustParse("linux/arm64")},
+		"bbb": {platforms.MustParse("linux/arm64"), platforms.MustParse("linux/amd64")},
+	})
+	ps := []specs.Platform{platforms.MustParse("linux/amd64"), platforms.MustParse("linux/arm64")}
+
+	// without a policy the first node that supports all platforms is used
+	res, perfect, err := r.resolve(context.TODO(), ps, SchedulingPolicy{}, nil, platforms.Only, nil)
+	require.NoError(t, err)
+	require.True(t, perfect)
+	require.Len(t, res, 1)
+	require.Equal(t, "aaa", res[0].Node().Builder)
+
+	res, perfect, err = r.resolve(context.TODO(), ps, SchedulingPolicy{Mode: SchedulingPreferNative}, nil, platforms.Only, nil)
+	require.NoError(t, err)
+	require.True(t, perfect)
+	require.Len(t, res, 2)
+	require.Equal(t, "aaa", res[0].Node().Builder)
+	require.Equal(t, []specs.Platform{platforms.MustParse("linux/amd64")}, res[0].platforms)
+	require.Equal(t, "bbb", res[1].Node().Builder)
+	require.Equal(t, []specs.Platform{platforms.MustParse("linux/arm64")}, res[1].platforms)
+}
+
+func TestSchedulingLeastLoaded(t *testing.T) {
+	r := makeSchedulingTestResolver(map[string][]specs.Platform{
+		"aaa": {platforms.MustParse("linux/amd64")},
+		"bbb": {platforms.MustParse("linux/amd64")},
+	})
+	policy := SchedulingPolicy{Mode: SchedulingLeastLoaded}
+
+	res, _, err := r.resolve(context.TODO(), []specs.Platform{platforms.MustParse("linux/amd64")}, policy, nil, platforms.Only, nil)
+	require.NoError(t, err)
+	require.Equal(t, "aaa", res[0].Node().Builder)
+
+	// the next target goes to the node with the fewest platforms
+	res, _, err = r.resolve(context.TODO(), []specs.Platform{platforms.MustParse("linux/amd64")}, policy, nil, platforms.Only, nil)
+	require.NoError(t, err)
+	require.Equal(t, "bbb", res[0].Node().Builder)
+}
+
+func TestSchedulingLeastLoadedResolveOrder(t *testing.T) {
+	opt := map[string]Options{}
+	for _, k := range []string{"t1", "t2", "t3", "t4"} {
+		opt[k] = Options{
+			Platforms:        []specs.Platform{platforms.MustParse("linux/amd64")},
+			SchedulingPolicy: SchedulingPolicy{Mode: SchedulingLeastLoaded},
+		}
+	}
+	// the targets are resolved in the order of their names whatever the
+	// iteration order of the map
+	for i := 0; i < 10; i++ {
+		r := makeSchedulingTestResolver(map[string][]specs.Platform{
+			"aaa": {platforms.MustParse("linux/amd64")},
+			"bbb": {platforms.MustParse("linux/amd64")},
+		})
+		res, err := r.Resolve(context.TODO(), opt, nil)
+		require.NoError(t, err)
+		require.Equal(t, "aaa", res["t1"][0].Node().Builder)
+		require.Equal(t, "bbb", res["t2"][0].Node().Builder)
+		require.Equal(t, "aaa", res["t3"][0].Node().Builder)
+		require.Equal(t, "bbb", res["t4"][0].Node().Builder)
+	}
+}
+
+func TestSchedulingPinned(t *testing.T) {
+	r := makeSchedulingTestResolver(map[string][]specs.Platform{
+		"aaa": {platforms.MustParse("linux/amd64"), platforms.MustParse("linux/arm64")},
+		"bbb": {platforms.MustParse("linux/arm64")},
+		"ccc": {platforms.MustParse("linux/amd64")},
+	})
+	ps := []specs.Platform{platforms.MustParse("linux/amd64"), platforms.MustParse("linux/arm64")}
+
+	res, perfect, err := r.resolve(context.TODO(), ps, SchedulingPolicy{Mode: SchedulingPinned, Nodes: []string{"ccc", "bbb"}}, nil, platforms.Only, nil)
+	require.NoError(t, err)
+	require.True(t, perfect)
+	require.Len(t, res, 2)
+	require.Equal(t, "ccc", res[0].Node().Builder)
+	require.Equal(t, []specs.Platform{platforms.MustParse("linux/amd64")}, res[0].platforms)
+	require.Equal(t, "bbb", res[1].Node().Builder)
+
+	// platforms not supported by the pinned nodes fall back to the first one
+	res, perfect, err = r.resolve(context.TODO(), []specs.Platform{platforms.MustParse("linux/arm64")}, SchedulingPolicy{Mode: SchedulingPinned, Nodes: []string{"ccc"}}, nil, platforms.Only, nil)
+	require.NoError(t, err)
+	require.False(t, perfect)
+	require.Equal(t, "ccc", res[0].Node().Builder)
+
+	require.ErrorContains(t, SchedulingPolicy{Mode: SchedulingPinned, Nodes: []string{"ddd"}}.validate(r.nodes), `unknown node "ddd"`)
+}
+
+func TestSchedulingBuilders(t *testing.T) {
+	r := makeSchedulingTestResolver(map[string][]specs.Platform{
+		"aaa": {platforms.MustParse("linux/amd64"), platforms.MustParse("linux/arm64")},
+		"bbb": {platforms.MustParse("linux/arm64")},
+		"ccc": {platforms.MustParse("linux/amd64")},
+	})
+	ps := []specs.Platform{platforms.MustParse("linux/amd64"), platforms.MustParse("linux/arm64")}
+
+	policy := SchedulingPolicy{
+		Builder:          "ccc",
+		PlatformBuilders: map[string]string{"linux/arm64": "bbb"},
+	}
+	require.NoError(t, policy.validate(r.nodes))
+	res, perfect, err := r.resolve(context.TODO(), ps, policy, nil, platforms.Only, nil)
+	require.NoError(t, err)
+	require.True(t, perfect)
+	require.Len(t, res, 2)
+	require.Equal(t, "ccc", res[0].Node().Builder)
+	require.Equal(t, []specs.Platform{platforms.MustParse("linux/amd64")}, res[0].platforms)
+	require.Equal(t, "bbb", res[1].Node().Builder)
+
+	// platforms not supported by the builder fall back to its first node
+	res, perfect, err = r.resolve(context.TODO(), []specs.Platform{platforms.MustParse("linux/arm64")}, SchedulingPolicy{Builder: "ccc"}, nil, platforms.Only, nil)
+	require.NoError(t, err)
+	require.False(t, perfect)
+	require.Equal(t, "ccc", res[0].Node().Builder)
+
+	require.ErrorContains(t, SchedulingPolicy{Builder: "ddd"}.validate(r.nodes), `no available node for builder "ddd"`)
+}
+
+// makeSchedulingTestResolver returns a test resolver whose nodes are named
+// after their builder so that they can be pinned.
+func makeSchedulingTestResolver(nodes map[string][]specs.Platform) *nodeResolver {
+	r := makeTestResolver(nodes)
+	for i := range r.nodes {
+		r.nodes[i].Name = r.nodes[i].Builder
+	}
+	return r
+}
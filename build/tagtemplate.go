@@ -0,0 +1,180 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/util/gitutil"
+	"github.com/docker/buildx/util/osutil"
+	"github.com/docker/buildx/util/platformutil"
+	"github.com/pkg/errors"
+)
+
+var invalidTagChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// TagTemplateData is the data of the templates computing the tags of a build
+// with --tag-template.
+type TagTemplateData struct {
+	// Target is the name of the bake target, empty for build.
+	Target string
+	// Platform is the platform of a single-platform build with "/" replaced
+	// by "-", such as "linux-arm64", empty otherwise.
+	Platform string
+
+	ctx         context.Context
+	contextPath string
+	now         time.Time
+
+	gitOnce sync.Once
+	git     *gitutil.Git
+	gitErr  error
+}
+
+// NewTagTemplateData returns the data of the tag templates of a build of a
+// context. The git metadata is read from the repository of the context when
+// a template uses it. The date is the time set with SOURCE_DATE_EPOCH, or
+// the current time.
+func NewTagTemplateData(ctx context.Context, target, contextPath string, platforms []string) (*TagTemplateData, error) {
+	d := &TagTemplateData{
+		Target:      target,
+		ctx:         ctx,
+		contextPath: contextPath,
+		now:         time.Now().UTC(),
+	}
+	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid SOURCE_DATE_EPOCH %q", v)
+		}
+		d.now = time.Unix(sec, 0).UTC()
+	}
+	p, err := platformutil.Parse(platforms)
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 1 {
+		d.Platform = strings.ReplaceAll(platformutil.Format(p)[0], "/", "-")
+	}
+	return d, nil
+}
+
+// Date returns the date of the build formatted with a Go time layout, such
+// as "20060102".
+func (d *TagTemplateData) Date(layout string) string {
+	return d.now.Format(layout)
+}
+
+// GitSHA returns the commit checked out in the repository of the context.
+func (d *TagTemplateData) GitSHA() (string, error) {
+	gitc, err := d.gitClient()
+	if err != nil {
+		return "", err
+	}
+	return gitc.FullCommit()
+}
+
+// GitShortSHA returns the abbreviated commit checked out in the repository
+// of the context.
+func (d *TagTemplateData) GitShortSHA() (string, error) {
+	gitc, err := d.gitClient()
+	if err != nil {
+		return "", err
+	}
+	return gitc.ShortCommit()
+}
+
+// GitBranch returns the branch checked out in the repository of the context
+// with the characters not allowed in a tag replaced by "-".
+func (d *TagTemplateData) GitBranch() (string, error) {
+	gitc, err := d.gitClient()
+	if err != nil {
+		return "", err
+	}
+	branch, err := gitc.Branch()
+	if err != nil {
+		return "", err
+	}
+	if branch == "" {
+		return "", errors.New("HEAD is detached, no branch is checked out")
+	}
+	return sanitizeTag(branch), nil
+}
+
+// GitTag returns the most recent tag reachable from the commit checked out
+// in the repository of the context.
+func (d *TagTemplateData) GitTag() (string, error) {
+	gitc, err := d.gitClient()
+	if err != nil {
+		return "", err
+	}
+	tag, err := gitc.Tag()
+	if err != nil {
+		return "", err
+	}
+	if tag == "" {
+		return "", errors.New("no git tag found")
+	}
+	return tag, nil
+}
+
+func (d *TagTemplateData) gitClient() (*gitutil.Git, error) {
+	d.gitOnce.Do(func() {
+		if d.contextPath == "" || IsRemoteURL(d.contextPath) {
+			d.gitErr = errors.Errorf("git metadata is only available for a local context")
+			return
+		}
+		wd := d.contextPath
+		if !filepath.IsAbs(wd) {
+			wd, _ = filepath.Abs(filepath.Join(osutil.GetWd(), wd))
+		}
+		d.git, d.gitErr = gitutil.New(gitutil.WithContext(d.ctx), gitutil.WithWorkingDir(osutil.SanitizePath(wd)))
+		if d.gitErr == nil && !d.git.IsInsideWorkTree() {
+			d.gitErr = errors.Errorf("context %s is not in a git repository", d.contextPath)
+		}
+	})
+	return d.git, d.gitErr
+}
+
+// ExpandTagTemplates returns the tags computed by the templates with the
+// data of a build.
+func ExpandTagTemplates(templates []string, data *TagTemplateData) ([]string, error) {
+	funcs := template.FuncMap{
+		"sanitize": sanitizeTag,
+		"lower":    strings.ToLower,
+	}
+	tags := make([]string, 0, len(templates))
+	for _, s := range templates {
+		tmpl, err := template.New("tag").Funcs(funcs).Option("missingkey=error").Parse(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid tag template %q", s)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, errors.Wrapf(err, "failed to expand tag template %q", s)
+		}
+		tag := strings.TrimSpace(buf.String())
+		if _, err := reference.ParseNormalizedNamed(tag); err != nil {
+			return nil, errors.Wrapf(err, "tag template %q expands to invalid tag %q", s, tag)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// sanitizeTag replaces the characters not allowed in a tag by "-".
+func sanitizeTag(s string) string {
+	s = invalidTagChars.ReplaceAllString(s, "-")
+	if len(s) > 128 {
+		s = s[:128]
+	}
+	return s
+}
@@ -0,0 +1,69 @@
+package build
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/buildx/util/gitutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandTagTemplates(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	setupTest(t)
+
+	c, err := gitutil.New()
+	require.NoError(t, err)
+	gitutil.GitTag(c, t, "v1.2.3")
+	gitutil.GitCheckoutBranch(c, t, "feature/tags")
+	sha, err := c.FullCommit()
+	require.NoError(t, err)
+	shortSHA, err := c.ShortCommit()
+	require.NoError(t, err)
+
+	data, err := NewTagTemplateData(context.TODO(), "app", ".", []string{"linux/arm64"})
+	require.NoError(t, err)
+
+	tags, err := ExpandTagTemplates([]string{
+		`ghcr.io/org/app:{{.GitShortSHA}}-{{.Date "20060102"}}`,
+		`org/{{.Target}}:{{.GitBranch}}-{{.Platform}}`,
+		`org/app:{{.GitTag}}`,
+		`org/app:{{.GitSHA}}`,
+		`org/app:{{ "Feature/X" | sanitize | lower }}`,
+	}, data)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"ghcr.io/org/app:" + shortSHA + "-20231114",
+		"org/app:feature-tags-linux-arm64",
+		"org/app:v1.2.3",
+		"org/app:" + sha,
+		"org/app:feature-x",
+	}, tags)
+
+	data, err = NewTagTemplateData(context.TODO(), "", ".", []string{"linux/amd64,linux/arm64"})
+	require.NoError(t, err)
+	require.Empty(t, data.Platform)
+
+	_, err = ExpandTagTemplates([]string{"org/app:{{.Platform}}"}, data)
+	require.ErrorContains(t, err, "expands to invalid tag")
+
+	_, err = ExpandTagTemplates([]string{"org/app:{{.Unknown}}"}, data)
+	require.ErrorContains(t, err, "failed to expand tag template")
+
+	_, err = ExpandTagTemplates([]string{"org/app:{{.GitSHA"}, data)
+	require.ErrorContains(t, err, "invalid tag template")
+}
+
+func TestExpandTagTemplatesNotGitRepo(t *testing.T) {
+	data, err := NewTagTemplateData(context.TODO(), "", t.TempDir(), nil)
+	require.NoError(t, err)
+
+	_, err = ExpandTagTemplates([]string{"org/app:{{.GitShortSHA}}"}, data)
+	require.ErrorContains(t, err, "is not in a git repository")
+
+	data, err = NewTagTemplateData(context.TODO(), "", "https://github.com/docker/buildx.git", nil)
+	require.NoError(t, err)
+
+	_, err = ExpandTagTemplates([]string{"org/app:{{.GitShortSHA}}"}, data)
+	require.ErrorContains(t, err, "only available for a local context")
+}
@@ -0,0 +1,172 @@
+package build
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+	"github.com/pkg/errors"
+)
+
+const (
+	dockerignoreFilename = ".dockerignore"
+	// tarContextLookahead is the number of bytes of a context archive read
+	// from stdin that are searched for the .dockerignore file.
+	tarContextLookahead = 1 << 20
+)
+
+// filterTarContext returns a reader streaming the build context archive read
+// from r with the files excluded by the .dockerignore file at the root of the
+// archive removed. Files listed in keep, such as the Dockerfile, are never
+// excluded. The .dockerignore file is searched in the first
+// tarContextLookahead bytes of the archive. When it isn't found there, the
+// archive is streamed unmodified. Otherwise the archive is streamed as an
+// uncompressed tar, gzip and bzip2 compressed archives being decompressed.
+// Archives using any other compression are returned unmodified.
+func filterTarContext(r io.ReadCloser, keep ...string) (io.ReadCloser, error) {
+	br := bufio.NewReaderSize(r, archiveHeaderSize*2)
+	magic, err := br.Peek(archiveHeaderSize)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to read context header")
+	}
+
+	var decompress func(io.Reader) (io.Reader, error)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1F, 0x8B, 0x08}):
+		decompress = func(r io.Reader) (io.Reader, error) {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to decompress gzip context")
+			}
+			return gz, nil
+		}
+	case bytes.HasPrefix(magic, []byte{0x42, 0x5A, 0x68}):
+		decompress = func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		}
+	default:
+		if _, err := tar.NewReader(bytes.NewReader(magic)).Next(); err != nil {
+			return &readCloser{Reader: br, close: r.Close}, nil
+		}
+		decompress = func(r io.Reader) (io.Reader, error) {
+			return r, nil
+		}
+	}
+
+	// the bytes read while looking for the .dockerignore file are kept to be
+	// streamed again
+	var head bytes.Buffer
+	pm, err := findDockerignore(io.TeeReader(br, &head), decompress)
+	if err != nil {
+		return nil, err
+	}
+	rd := io.MultiReader(&head, br)
+	if pm == nil {
+		return &readCloser{Reader: rd, close: r.Close}, nil
+	}
+
+	dr, err := decompress(rd)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(copyTarContext(tar.NewWriter(pw), tar.NewReader(dr), pm, keep))
+	}()
+	return &readCloser{Reader: pr, close: func() error {
+		pr.Close()
+		return r.Close()
+	}}, nil
+}
+
+// findDockerignore returns the patterns of the .dockerignore file of the
+// archive read from r, or nil if it isn't found in the first
+// tarContextLookahead bytes.
+func findDockerignore(r io.Reader, decompress func(io.Reader) (io.Reader, error)) (*patternmatcher.PatternMatcher, error) {
+	lr := &io.LimitedReader{R: r, N: tarContextLookahead}
+	dr, err := decompress(lr)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(dr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			// the end of the archive or of the lookahead
+			return nil, nil
+		}
+		if cleanTarName(hdr.Name) != dockerignoreFilename {
+			continue
+		}
+		dt, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil
+		}
+		patterns, err := ignorefile.ReadAll(bytes.NewReader(dt))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse .dockerignore from context")
+		}
+		pm, err := patternmatcher.New(patterns)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid .dockerignore pattern in context")
+		}
+		return pm, nil
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r *readCloser) Close() error {
+	return r.close()
+}
+
+func copyTarContext(tw *tar.Writer, tr *tar.Reader, pm *patternmatcher.PatternMatcher, keep []string) error {
+	keep = append([]string{dockerignoreFilename}, keep...)
+	for i, k := range keep {
+		keep[i] = cleanTarName(k)
+	}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrap(err, "failed to read context archive")
+		}
+		if err := writeTarEntry(tw, hdr, tr, pm, keep); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeTarEntry(tw *tar.Writer, hdr *tar.Header, r io.Reader, pm *patternmatcher.PatternMatcher, keep []string) error {
+	if name := cleanTarName(hdr.Name); !slices.Contains(keep, name) {
+		excluded, err := pm.MatchesOrParentMatches(name)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			return nil
+		}
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+func cleanTarName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,81 @@
+package build
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterTarContext(t *testing.T) {
+	files := []struct {
+		name string
+		data string
+	}{
+		{"a.txt", "a"},
+		{"build/out.bin", "bin"},
+		{".dockerignore", "build\n*.log\nDockerfile\n"},
+		{"Dockerfile", "FROM scratch"},
+		{"app.log", "log"},
+		{"src/main.go", "package main"},
+	}
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(f.data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	rc, err := filterTarContext(io.NopCloser(buf), "Dockerfile")
+	require.NoError(t, err)
+	defer rc.Close()
+
+	var names []string
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+	}
+	require.Equal(t, []string{"a.txt", ".dockerignore", "Dockerfile", "src/main.go"}, names)
+}
+
+func TestFilterTarContextWithoutDockerignore(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0644, Size: 12, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("FROM scratch"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	expected := bytes.Clone(buf.Bytes())
+
+	rc, err := filterTarContext(io.NopCloser(buf), "Dockerfile")
+	require.NoError(t, err)
+	defer rc.Close()
+
+	// the archive is streamed unmodified
+	dt, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.Equal(t, expected, dt)
+}
+
+func TestFilterTarContextUnknownFormat(t *testing.T) {
+	rc, err := filterTarContext(io.NopCloser(bytes.NewReader([]byte("not an archive"))))
+	require.NoError(t, err)
+	dt, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.Equal(t, "not an archive", string(dt))
+}
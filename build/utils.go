@@ -5,12 +5,18 @@ import (
 	"bytes"
 	"context"
 	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/distribution/reference"
 	"github.com/docker/buildx/driver"
 	"github.com/docker/cli/opts"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/moby/buildkit/util/gitutil"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -25,6 +31,28 @@ const (
 	mobyHostGatewayName = "host-gateway"
 )
 
+// FrontendImageArg is the build argument that sets the Dockerfile frontend
+// image. It takes precedence over the syntax directive of the Dockerfile.
+const FrontendImageArg = "BUILDKIT_SYNTAX"
+
+var reFrontendChannel = regexp.MustCompile(`^(\d+(\.\d+){0,2})?(-?labs)?$`)
+
+// ParseFrontendImage validates the Dockerfile frontend image reference. A
+// version or channel of the official frontend, like "1.7" or "labs", is
+// expanded to the docker/dockerfile image.
+func ParseFrontendImage(in string) (string, error) {
+	if in == "" {
+		return "", errors.New("frontend image cannot be empty")
+	}
+	if reFrontendChannel.MatchString(in) {
+		in = "docker/dockerfile:" + in
+	}
+	if _, err := reference.ParseNormalizedNamed(in); err != nil {
+		return "", errors.Wrapf(err, "invalid frontend image %q", in)
+	}
+	return in, nil
+}
+
 // isHTTPURL returns true if the provided str is an HTTP(S) URL by checking if it
 // has a http:// or https:// scheme. No validation is performed to verify if the
 // URL is well-formed.
@@ -61,6 +89,64 @@ func isArchive(header []byte) bool {
 	return err == nil
 }
 
+// ReadHostsFile returns the host-to-IP mappings of a file in the format of
+// /etc/hosts as "host=ip" entries that can be added to Options.ExtraHosts.
+func ReadHostsFile(fn string) ([]string, error) {
+	dt, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []string
+	for i, line := range strings.Split(string(dt), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) == 1 || net.ParseIP(fields[0]) == nil {
+			return nil, errors.Errorf("invalid hosts entry at %s:%d: %q", fn, i+1, strings.TrimSpace(line))
+		}
+		for _, name := range fields[1:] {
+			hosts = append(hosts, name+"="+fields[0])
+		}
+	}
+	return hosts, nil
+}
+
+// toBuildkitDNS validates the DNS configuration of a build and returns it as
+// the frontend attributes passed to the workers supporting it.
+func toBuildkitDNS(d *DNSConfig) (map[string]string, error) {
+	if d == nil {
+		return nil, nil
+	}
+	attrs := map[string]string{}
+	for _, ns := range d.Nameservers {
+		if net.ParseIP(ns) == nil {
+			return nil, errors.Errorf("invalid DNS nameserver %q, must be an IP address", ns)
+		}
+	}
+	for _, v := range d.SearchDomains {
+		if v == "" || strings.ContainsAny(v, " \t,") {
+			return nil, errors.Errorf("invalid DNS search domain %q", v)
+		}
+	}
+	for _, v := range d.Options {
+		if v == "" || strings.ContainsAny(v, " \t,") {
+			return nil, errors.Errorf("invalid DNS option %q", v)
+		}
+	}
+	if len(d.Nameservers) > 0 {
+		attrs["dns"] = strings.Join(d.Nameservers, ",")
+	}
+	if len(d.SearchDomains) > 0 {
+		attrs["dns-search"] = strings.Join(d.SearchDomains, ",")
+	}
+	if len(d.Options) > 0 {
+		attrs["dns-options"] = strings.Join(d.Options, ",")
+	}
+	return attrs, nil
+}
+
 // toBuildkitExtraHosts converts hosts from docker key:value format to buildkit's csv format
 func toBuildkitExtraHosts(ctx context.Context, inp []string, nodeDriver *driver.DriverHandle) (string, error) {
 	if len(inp) == 0 {
@@ -111,6 +197,44 @@ func toBuildkitUlimits(inp *opts.UlimitOpt) (string, error) {
 	return strings.Join(ulimits, ","), nil
 }
 
+// groupNamesByRegistry splits image names into groups that share the same
+// registry. The order of the groups and of the names within each group
+// follows the order of the input.
+func groupNamesByRegistry(names []string) ([][]string, error) {
+	var groups [][]string
+	idx := map[string]int{}
+	for _, name := range names {
+		ref, err := reference.ParseNormalizedNamed(name)
+		if err != nil {
+			return nil, err
+		}
+		domain := reference.Domain(ref)
+		i, ok := idx[domain]
+		if !ok {
+			i = len(groups)
+			idx[domain] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], name)
+	}
+	return groups, nil
+}
+
+// unauthorizedErrorPrefix prefixes the messages of the errors with the
+// UNAUTHORIZED code of the registry API.
+const unauthorizedErrorPrefix = "unauthorized:"
+
+// pushStreamError returns the error reported in the progress stream of a
+// push to the Docker daemon. Errors with the unauthorized code of the
+// registry API are typed as unauthorized so they can be detected with
+// errdefs.
+func pushStreamError(jerr *jsonmessage.JSONError) error {
+	if jerr.Code == http.StatusUnauthorized || strings.HasPrefix(jerr.Message, unauthorizedErrorPrefix) {
+		return cerrdefs.ErrUnauthenticated.WithMessage(jerr.Message)
+	}
+	return jerr
+}
+
 func notSupported(f driver.Feature, d *driver.DriverHandle, docs string) error {
 	return errors.Errorf(`%s is not supported for the %s driver.
 Switch to a different driver, or turn on the containerd image store, and try again.
@@ -128,3 +252,23 @@ func noDefaultLoad() bool {
 	}
 	return b
 }
+
+// LocalDockerfile returns the content of the Dockerfile of the build if it is
+// set inline or read from the local filesystem, or nil otherwise.
+func LocalDockerfile(inp Inputs) []byte {
+	if inp.DockerfileInline != "" {
+		return []byte(inp.DockerfileInline)
+	}
+	fn := inp.DockerfilePath
+	switch {
+	case fn == "-" || IsRemoteURL(fn) || IsRemoteURL(inp.ContextPath):
+		return nil
+	case fn == "":
+		fn = filepath.Join(inp.ContextPath, "Dockerfile")
+	}
+	dt, err := os.ReadFile(fn)
+	if err != nil {
+		return nil
+	}
+	return dt
+}
@@ -2,9 +2,14 @@ package build
 
 import (
 	"context"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/stretchr/testify/require"
 )
 
@@ -146,3 +151,100 @@ func TestToBuildkitExtraHosts(t *testing.T) {
 		})
 	}
 }
+
+func TestGroupNamesByRegistry(t *testing.T) {
+	groups, err := groupNamesByRegistry([]string{
+		"a.io/x:1",
+		"b.io/x:1",
+		"user/app:latest",
+		"a.io/x:latest",
+		"docker.io/user/app:1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"a.io/x:1", "a.io/x:latest"},
+		{"b.io/x:1"},
+		{"user/app:latest", "docker.io/user/app:1"},
+	}, groups)
+
+	_, err = groupNamesByRegistry([]string{"INVALID:name:tag"})
+	require.Error(t, err)
+}
+
+func TestParseFrontendImage(t *testing.T) {
+	for in, expected := range map[string]string{
+		"docker/dockerfile:1.7":       "docker/dockerfile:1.7",
+		"1":                           "docker/dockerfile:1",
+		"1.7":                         "docker/dockerfile:1.7",
+		"1.7.1":                       "docker/dockerfile:1.7.1",
+		"labs":                        "docker/dockerfile:labs",
+		"1.7-labs":                    "docker/dockerfile:1.7-labs",
+		"registry.example.com/df:1.0": "registry.example.com/df:1.0",
+	} {
+		t.Run(in, func(t *testing.T) {
+			out, err := ParseFrontendImage(in)
+			require.NoError(t, err)
+			require.Equal(t, expected, out)
+		})
+	}
+
+	_, err := ParseFrontendImage("")
+	require.Error(t, err)
+	_, err = ParseFrontendImage("INVALID:image:ref")
+	require.ErrorContains(t, err, "invalid frontend image")
+}
+
+func TestReadHostsFile(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(fn, []byte(`# internal services
+10.0.0.5	git.corp.example.com git
+fd00::10 registry.corp.example.com # registry
+
+`), 0644))
+	hosts, err := ReadHostsFile(fn)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"git.corp.example.com=10.0.0.5",
+		"git=10.0.0.5",
+		"registry.corp.example.com=fd00::10",
+	}, hosts)
+
+	require.NoError(t, os.WriteFile(fn, []byte("10.0.0.5 git\ngit 10.0.0.5\n"), 0644))
+	_, err = ReadHostsFile(fn)
+	require.ErrorContains(t, err, `invalid hosts entry at `+fn+`:2: "git 10.0.0.5"`)
+}
+
+func TestToBuildkitDNS(t *testing.T) {
+	attrs, err := toBuildkitDNS(nil)
+	require.NoError(t, err)
+	require.Empty(t, attrs)
+
+	attrs, err = toBuildkitDNS(&DNSConfig{
+		Nameservers:   []string{"10.0.0.2", "fd00::2"},
+		SearchDomains: []string{"corp.example.com"},
+		Options:       []string{"ndots:2", "edns0"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"dns":         "10.0.0.2,fd00::2",
+		"dns-search":  "corp.example.com",
+		"dns-options": "ndots:2,edns0",
+	}, attrs)
+
+	_, err = toBuildkitDNS(&DNSConfig{Nameservers: []string{"dns.example.com"}})
+	require.ErrorContains(t, err, "must be an IP address")
+	_, err = toBuildkitDNS(&DNSConfig{Options: []string{"ndots:2 edns0"}})
+	require.ErrorContains(t, err, "invalid DNS option")
+}
+
+func TestPushStreamError(t *testing.T) {
+	err := pushStreamError(&jsonmessage.JSONError{Message: "unauthorized: authentication required"})
+	require.True(t, cerrdefs.IsUnauthorized(err))
+	require.EqualError(t, err, "unauthorized: authentication required")
+
+	err = pushStreamError(&jsonmessage.JSONError{Code: http.StatusUnauthorized, Message: "token expired"})
+	require.True(t, cerrdefs.IsUnauthorized(err))
+
+	err = pushStreamError(&jsonmessage.JSONError{Message: "blob upload unknown: upload failed, unauthorized retry"})
+	require.False(t, cerrdefs.IsUnauthorized(err))
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/docker/buildx/util/progress"
 	"github.com/docker/cli/cli/command"
 	dopts "github.com/docker/cli/opts"
+	"github.com/docker/go-units"
 	"github.com/google/shlex"
 	"github.com/moby/buildkit/util/progress/progressui"
 	"github.com/pkg/errors"
@@ -339,10 +340,18 @@ type CreateOpts struct {
 	Platforms           []string
 	BuildkitdFlags      string
 	BuildkitdConfigFile string
+	RegistryMirrors     []string
+	InsecureRegistries  []string
+	GCPolicy            []string
 	DriverOpts          []string
 	Use                 bool
 	Endpoint            string
 	Append              bool
+	DefaultShmSize      string
+	DefaultUlimits      []string
+	// MaxConcurrentBuilds is the number of concurrent builds of the
+	// instance, or nil to keep the current value.
+	MaxConcurrentBuilds *int
 }
 
 func Create(ctx context.Context, txn *store.Txn, dockerCli command.Cli, opts CreateOpts) (*Builder, error) {
@@ -449,6 +458,38 @@ func Create(ctx context.Context, txn *store.Txn, dockerCli command.Cli, opts Cre
 		return nil, err
 	}
 
+	var files map[string][]byte
+	if buildkitdConfigFile != "" {
+		files, err = confutil.LoadConfigFiles(buildkitdConfigFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.RegistryMirrors) > 0 || len(opts.InsecureRegistries) > 0 {
+		switch driverName {
+		case "docker":
+			return nil, errors.Errorf("setting registry configuration is not supported for docker driver, use dockerd configuration file")
+		case "remote":
+			return nil, errors.Errorf("setting registry configuration is not supported for remote driver, configure the remote BuildKit daemon instead")
+		}
+		files, err = confutil.SetRegistryConfig(files, opts.RegistryMirrors, opts.InsecureRegistries)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.GCPolicy) > 0 {
+		switch driverName {
+		case "docker":
+			return nil, errors.Errorf("setting gc policy is not supported for docker driver, use dockerd configuration file")
+		case "remote":
+			return nil, errors.Errorf("setting gc policy is not supported for remote driver, configure the remote BuildKit daemon instead")
+		}
+		files, err = confutil.SetGCPolicy(files, opts.GCPolicy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var ep string
 	var setEp bool
 	switch {
@@ -505,10 +546,21 @@ func Create(ctx context.Context, txn *store.Txn, dockerCli command.Cli, opts Cre
 		setEp = false
 	}
 
-	if err := ng.Update(opts.NodeName, ep, opts.Platforms, setEp, opts.Append, buildkitdFlags, buildkitdConfigFile, driverOpts); err != nil {
+	if err := ng.Update(opts.NodeName, ep, opts.Platforms, setEp, opts.Append, buildkitdFlags, files, driverOpts); err != nil {
+		return nil, err
+	}
+
+	if err := setBuildDefaults(ng, opts.DefaultShmSize, opts.DefaultUlimits); err != nil {
 		return nil, err
 	}
 
+	if n := opts.MaxConcurrentBuilds; n != nil {
+		if *n < 0 {
+			return nil, errors.Errorf("invalid max concurrent builds %d, must not be negative", *n)
+		}
+		ng.MaxConcurrentBuilds = *n
+	}
+
 	if err := txn.Save(ng); err != nil {
 		return nil, err
 	}
@@ -596,6 +648,37 @@ func Leave(ctx context.Context, txn *store.Txn, dockerCli command.Cli, opts Leav
 	return txn.Save(ng)
 }
 
+// setBuildDefaults validates and stores the build defaults of a builder
+// instance. Defaults that are not set keep their previous value.
+func setBuildDefaults(ng *store.NodeGroup, shmSize string, ulimits []string) error {
+	if shmSize == "" && len(ulimits) == 0 {
+		return nil
+	}
+	d := ng.BuildDefaults.Copy()
+	if d == nil {
+		d = &store.BuildDefaults{}
+	}
+	if shmSize != "" {
+		v, err := units.RAMInBytes(shmSize)
+		if err != nil {
+			return errors.Wrapf(err, "invalid default shm size %q", shmSize)
+		}
+		d.ShmSize = v
+	}
+	if len(ulimits) > 0 {
+		d.Ulimits = make([]string, 0, len(ulimits))
+		for _, v := range ulimits {
+			ul, err := units.ParseUlimit(v)
+			if err != nil {
+				return errors.Wrapf(err, "invalid default ulimit %q", v)
+			}
+			d.Ulimits = append(d.Ulimits, ul.String())
+		}
+	}
+	ng.BuildDefaults = d
+	return nil
+}
+
 func csvToMap(in []string) (map[string]string, error) {
 	if len(in) == 0 {
 		return nil, nil
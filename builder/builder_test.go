@@ -5,6 +5,7 @@ import (
 	"path"
 	"testing"
 
+	"github.com/docker/buildx/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -171,3 +172,24 @@ insecure-entitlements = [ "network.host", "security.insecure" ]
 		})
 	}
 }
+
+func TestSetBuildDefaults(t *testing.T) {
+	ng := &store.NodeGroup{}
+	require.NoError(t, setBuildDefaults(ng, "", nil))
+	require.Nil(t, ng.BuildDefaults)
+
+	require.NoError(t, setBuildDefaults(ng, "1g", []string{"nofile=1048576"}))
+	require.Equal(t, &store.BuildDefaults{
+		ShmSize: 1 << 30,
+		Ulimits: []string{"nofile=1048576:1048576"},
+	}, ng.BuildDefaults)
+
+	require.NoError(t, setBuildDefaults(ng, "", []string{"nproc=512:1024"}))
+	require.Equal(t, &store.BuildDefaults{
+		ShmSize: 1 << 30,
+		Ulimits: []string{"nproc=512:1024"},
+	}, ng.BuildDefaults)
+
+	require.ErrorContains(t, setBuildDefaults(ng, "foo", nil), `invalid default shm size "foo"`)
+	require.ErrorContains(t, setBuildDefaults(ng, "", []string{"nofile"}), `invalid default ulimit "nofile"`)
+}
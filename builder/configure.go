@@ -0,0 +1,132 @@
+package builder
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/docker/buildx/driver"
+	"github.com/docker/buildx/store"
+	"github.com/docker/buildx/util/confutil"
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/pkg/errors"
+)
+
+type ConfigureOpts struct {
+	Name                string
+	BuildkitdFlags      *string
+	BuildkitdConfigFile string
+}
+
+// Configure sets the BuildKit daemon flags and configuration file of all the
+// nodes of an existing builder. Flags that are not set keep their current
+// value. The new configuration is used once the nodes are restarted.
+func Configure(txn *store.Txn, opts ConfigureOpts) error {
+	if opts.BuildkitdFlags == nil && opts.BuildkitdConfigFile == "" {
+		return errors.New("nothing to configure, set the BuildKit daemon flags or configuration file")
+	}
+	ng, err := txn.NodeGroupByName(opts.Name)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return errors.Errorf("failed to find instance %q", opts.Name)
+		}
+		return err
+	}
+	switch ng.Driver {
+	case "docker-container", "kubernetes":
+	default:
+		return errors.Errorf("configuring the BuildKit daemon is not supported for %s driver", ng.Driver)
+	}
+
+	var files map[string][]byte
+	if opts.BuildkitdConfigFile != "" {
+		if files, err = confutil.LoadConfigFiles(opts.BuildkitdConfigFile); err != nil {
+			return err
+		}
+	}
+	for i, n := range ng.Nodes {
+		if opts.BuildkitdFlags != nil {
+			flags, err := parseBuildkitdFlags(*opts.BuildkitdFlags, ng.Driver, n.DriverOpts, opts.BuildkitdConfigFile)
+			if err != nil {
+				return err
+			}
+			ng.Nodes[i].BuildkitdFlags = flags
+		}
+		if files != nil {
+			ng.Nodes[i].Files = files
+		}
+	}
+	return txn.Save(ng)
+}
+
+// Restart removes the BuildKit daemon of the running nodes of the builder and
+// boots them again so that they use the current configuration of the builder.
+// The state volume of docker-container nodes is kept, but kubernetes nodes
+// store their state in the pods, so their build cache is lost. Unless force is
+// set, it fails if builds are in progress on one of the nodes.
+func (b *Builder) Restart(ctx context.Context, force bool) error {
+	nodes, err := b.LoadNodes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node.Err != nil {
+			return node.Err
+		}
+	}
+	if !force {
+		for _, node := range nodes {
+			if node.Driver == nil || node.DriverInfo == nil || node.DriverInfo.Status != driver.Running {
+				continue
+			}
+			n, err := activeBuilds(ctx, node)
+			if err != nil {
+				return errors.Wrapf(err, "failed to list the builds in progress on %s", node.Name)
+			}
+			if n > 0 {
+				return errors.Errorf("%d builds in progress on %s, wait for them to complete or use --force", n, node.Name)
+			}
+		}
+	}
+	for _, node := range nodes {
+		if node.Driver == nil {
+			continue
+		}
+		if err := node.Driver.Rm(ctx, true, false, true); err != nil {
+			return errors.Wrapf(err, "failed to remove the BuildKit daemon of %s", node.Name)
+		}
+	}
+	if _, err := b.LoadNodes(ctx); err != nil {
+		return err
+	}
+	_, err = b.Boot(ctx)
+	return err
+}
+
+// activeBuilds returns the number of builds in progress on the node.
+func activeBuilds(ctx context.Context, node Node) (int, error) {
+	c, err := node.Driver.Client(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cl, err := c.ControlClient().ListenBuildHistory(ctx, &controlapi.BuildHistoryRequest{
+		ActiveOnly: true,
+		EarlyExit:  true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for {
+		ev, err := cl.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return 0, err
+		}
+		if ev.Record != nil && ev.Type == controlapi.BuildHistoryEventType_STARTED {
+			n++
+		}
+	}
+}
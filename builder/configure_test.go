@@ -0,0 +1,51 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/buildx/store"
+	"github.com/docker/buildx/util/confutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigure(t *testing.T) {
+	s, err := store.New(confutil.NewConfig(nil, confutil.WithDir(t.TempDir())))
+	require.NoError(t, err)
+	txn, release, err := s.Txn()
+	require.NoError(t, err)
+	defer release()
+
+	ng := &store.NodeGroup{Name: "mybuilder", Driver: "docker-container"}
+	require.NoError(t, ng.Update("mybuilder0", "unix:///var/run/docker.sock", nil, true, true, []string{"--debug"}, nil, nil))
+	require.NoError(t, txn.Save(ng))
+
+	require.ErrorContains(t, Configure(txn, ConfigureOpts{Name: "mybuilder"}), "nothing to configure")
+
+	cfg := filepath.Join(t.TempDir(), "buildkitd.toml")
+	require.NoError(t, os.WriteFile(cfg, []byte("debug = true\n"), 0644))
+	require.NoError(t, Configure(txn, ConfigureOpts{
+		Name:                "mybuilder",
+		BuildkitdConfigFile: cfg,
+	}))
+	ng, err = txn.NodeGroupByName("mybuilder")
+	require.NoError(t, err)
+	require.Equal(t, []string{"--debug"}, ng.Nodes[0].BuildkitdFlags)
+	require.Equal(t, "debug = true\n", string(ng.Nodes[0].Files["buildkitd.toml"]))
+
+	flags := "--allow-insecure-entitlement security.insecure"
+	require.NoError(t, Configure(txn, ConfigureOpts{
+		Name:           "mybuilder",
+		BuildkitdFlags: &flags,
+	}))
+	ng, err = txn.NodeGroupByName("mybuilder")
+	require.NoError(t, err)
+	require.Equal(t, []string{"--allow-insecure-entitlement", "security.insecure"}, ng.Nodes[0].BuildkitdFlags)
+	require.Contains(t, ng.Nodes[0].Files, "buildkitd.toml")
+
+	ng = &store.NodeGroup{Name: "remotebuilder", Driver: "remote"}
+	require.NoError(t, ng.Update("remotebuilder0", "tcp://localhost:1234", nil, true, true, nil, nil, nil))
+	require.NoError(t, txn.Save(ng))
+	require.ErrorContains(t, Configure(txn, ConfigureOpts{Name: "remotebuilder", BuildkitdFlags: &flags}), "not supported for remote driver")
+}
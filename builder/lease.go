@@ -0,0 +1,92 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/docker/buildx/util/confutil"
+	"github.com/docker/buildx/util/progress"
+	"github.com/gofrs/flock"
+	"github.com/pkg/errors"
+)
+
+// slotsDir is the directory, relative to the buildx config dir, holding the
+// lock files of the build slots of the builders that limit their number of
+// concurrent builds.
+const slotsDir = "slots"
+
+// leaseRetryInterval is how often a build waiting for a slot checks if one
+// was released.
+const leaseRetryInterval = 500 * time.Millisecond
+
+// Lease reserves one of the build slots of the builder if it limits its
+// number of concurrent builds. If all the slots are taken by other builds,
+// it waits until one of them is released, showing the wait in pw. The
+// returned function releases the slot.
+func (b *Builder) Lease(ctx context.Context, pw progress.Writer) (func(), error) {
+	if b.NodeGroup == nil || b.MaxConcurrentBuilds <= 0 {
+		return func() {}, nil
+	}
+	cfg := confutil.NewConfig(b.opts.dockerCli)
+	if err := cfg.MkdirAll(filepath.Join(slotsDir, b.Name), 0700); err != nil {
+		return nil, err
+	}
+	return lease(ctx, filepath.Join(cfg.Dir(), slotsDir, b.Name), b.MaxConcurrentBuilds, func(wait func() error) error {
+		name := fmt.Sprintf("[internal] waiting for a build slot on %s (max %d concurrent builds)", b.Name, b.MaxConcurrentBuilds)
+		return progress.Wrap(name, pw.Write, func(progress.SubLogger) error {
+			return wait()
+		})
+	})
+}
+
+// lease locks one of the n slot files of dir, calling onWait with a function
+// waiting for a slot if they are all locked.
+func lease(ctx context.Context, dir string, n int, onWait func(wait func() error) error) (func(), error) {
+	l, err := tryLockSlot(dir, n)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		err := onWait(func() error {
+			t := time.NewTicker(leaseRetryInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return context.Cause(ctx)
+				case <-t.C:
+				}
+				l, err = tryLockSlot(dir, n)
+				if err != nil || l != nil {
+					return err
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return func() {
+		l.Close()
+	}, nil
+}
+
+// tryLockSlot locks the first free slot file of dir, or returns nil if the
+// n slots are all locked. The locks are released by the system if the
+// process holding them exits.
+func tryLockSlot(dir string, n int) (*flock.Flock, error) {
+	for i := 0; i < n; i++ {
+		l := flock.New(filepath.Join(dir, strconv.Itoa(i)+".lock"))
+		ok, err := l.TryLock()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to lock build slot %d", i)
+		}
+		if ok {
+			return l, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,45 @@
+package builder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLease(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+
+	noWait := func(func() error) error {
+		return errors.New("unexpected wait")
+	}
+	release1, err := lease(ctx, dir, 2, noWait)
+	require.NoError(t, err)
+	release2, err := lease(ctx, dir, 2, noWait)
+	require.NoError(t, err)
+
+	// both slots are taken, the third build waits for one to be released
+	var waited bool
+	release3, err := lease(ctx, dir, 2, func(wait func() error) error {
+		waited = true
+		release1()
+		return wait()
+	})
+	require.NoError(t, err)
+	require.True(t, waited)
+
+	cctx, cancel := context.WithCancelCause(ctx)
+	_, err = lease(cctx, dir, 2, func(wait func() error) error {
+		cancel(errors.New("build canceled"))
+		return wait()
+	})
+	require.ErrorContains(t, err, "build canceled")
+
+	release2()
+	release3()
+	release, err := lease(ctx, dir, 1, noWait)
+	require.NoError(t, err)
+	release()
+}
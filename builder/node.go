@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/containerd/platforms"
 	"github.com/docker/buildx/driver"
@@ -30,6 +31,9 @@ type Node struct {
 	ProxyConfig map[string]string
 	Version     string
 	Err         error
+	// Latency is the round-trip latency to the BuildKit daemon measured by
+	// Ping.
+	Latency time.Duration
 
 	// worker settings
 	IDs       []string
@@ -212,6 +216,7 @@ func (n *Node) MarshalJSON() ([]byte, error) {
 		Status         string             `json:",omitempty"`
 		ProxyConfig    map[string]string  `json:",omitempty"`
 		Version        string             `json:",omitempty"`
+		Latency        time.Duration      `json:",omitempty"`
 		Err            string             `json:",omitempty"`
 		IDs            []string           `json:",omitempty"`
 		Platforms      []string           `json:",omitempty"`
@@ -226,6 +231,7 @@ func (n *Node) MarshalJSON() ([]byte, error) {
 		Status:         status,
 		ProxyConfig:    n.ProxyConfig,
 		Version:        n.Version,
+		Latency:        n.Latency,
 		Err:            nerr,
 		IDs:            n.IDs,
 		Platforms:      pp,
@@ -276,3 +282,46 @@ func (n *Node) loadData(ctx context.Context, clientOpt ...client.ClientOpt) erro
 	}
 	return nil
 }
+
+// pingCount is the number of round trips made by Ping.
+const pingCount = 3
+
+// Ping measures the round-trip latency to the BuildKit daemon of a running
+// node, as the median of a few API calls. Nodes that aren't running are
+// skipped.
+func (n *Node) Ping(ctx context.Context) error {
+	if n.Driver == nil || n.Err != nil || n.DriverInfo == nil || n.DriverInfo.Status != driver.Running {
+		return nil
+	}
+	c, err := n.Driver.Client(ctx)
+	if err != nil {
+		return err
+	}
+	// older daemons don't support the info API
+	var listWorkers bool
+	call := func() error {
+		if listWorkers {
+			_, err := c.ListWorkers(ctx)
+			return err
+		}
+		_, err := c.Info(ctx)
+		return err
+	}
+	samples := make([]time.Duration, 0, pingCount)
+	for len(samples) < pingCount {
+		start := time.Now()
+		if err := call(); err != nil {
+			if st, ok := grpcerrors.AsGRPCStatus(err); ok && st.Code() == codes.Unimplemented && !listWorkers {
+				listWorkers = true
+				continue
+			}
+			return errors.Wrap(err, "ping")
+		}
+		samples = append(samples, time.Since(start))
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+	n.Latency = samples[len(samples)/2]
+	return nil
+}
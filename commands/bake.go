@@ -10,11 +10,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"text/tabwriter"
+	"time"
 
 	"github.com/containerd/console"
 	"github.com/containerd/platforms"
@@ -24,38 +27,91 @@ import (
 	"github.com/docker/buildx/builder"
 	"github.com/docker/buildx/controller/pb"
 	"github.com/docker/buildx/localstate"
+	"github.com/docker/buildx/store"
 	"github.com/docker/buildx/util/buildflags"
 	"github.com/docker/buildx/util/cobrautil"
 	"github.com/docker/buildx/util/cobrautil/completion"
 	"github.com/docker/buildx/util/confutil"
 	"github.com/docker/buildx/util/desktop"
 	"github.com/docker/buildx/util/dockerutil"
+	"github.com/docker/buildx/util/gitutil"
+	"github.com/docker/buildx/util/imagetools"
 	"github.com/docker/buildx/util/osutil"
 	"github.com/docker/buildx/util/progress"
 	"github.com/docker/buildx/util/tracing"
+	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+	"github.com/moby/buildkit/frontend/subrequests/lint"
 	"github.com/moby/buildkit/identity"
 	"github.com/moby/buildkit/util/progress/progressui"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/otel/attribute"
 )
 
+// Types of the lists printed with --list.
+const (
+	listTypeTargets   = "targets"
+	listTypeVariables = "variables"
+	listTypeGHAMatrix = "gha-matrix"
+)
+
+// bakeParseCacheDir is the directory, relative to the buildx config dir,
+// where parsed bake definitions are cached.
+const bakeParseCacheDir = "cache/bake"
+
 type bakeOptions struct {
-	files       []string
-	overrides   []string
-	printOnly   bool
-	listTargets bool
-	listVars    bool
-	sbom        string
-	provenance  string
-	allow       []string
-
-	builder      string
-	metadataFile string
-	exportPush   bool
-	exportLoad   bool
-	callFunc     string
+	files         []string
+	profile       string
+	overrides     []string
+	tagTemplates  []string
+	jsonOverrides []string
+	overrideFiles []string
+	defaultGroup  []string
+	noCacheTgts   []string
+	printOnly     bool
+	printVerbose  bool
+	printDiff     bool
+	lock          bool
+	updateLock    bool
+	noEmulation   bool
+	scheduling    string
+	builderMap    []string
+	planFile      string
+	planOnly      bool
+	fromPlan      string
+	list          string
+	sbom          string
+	provenance    string
+	allow         []string
+	allowDryRun   bool
+	workspaceRoot string
+	watch         bool
+	strictEnv     bool
+
+	// watchState is set in --watch mode to get the paths watched by the
+	// targets of the build
+	watchState *bakeWatchState
+
+	builder         string
+	metadataFile    string
+	metadataFormats []string
+	exportPush      bool
+	exportLoad      bool
+	callFunc        string
+
+	requireChecks bool
+	maxWarnings   int
+
+	report     bool
+	openReport bool
+
+	failOnSecretArgs bool
+	verifyPush       bool
 }
 
 func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in bakeOptions, cFlags commonFlags) (err error) {
@@ -69,17 +125,68 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 		end(err)
 	}()
 
+	if in.printDiff && !in.printOnly {
+		return errors.New("--diff requires --print")
+	}
+	if in.printDiff && in.printVerbose {
+		return errors.New("--diff cannot be used with --print=verbose")
+	}
+
 	url, cmdContext, targets := bakeArgs(targets)
-	if len(targets) == 0 {
-		targets = []string{"default"}
+	if in.fromPlan != "" {
+		switch {
+		case url != "" || len(targets) > 0:
+			return errors.New("--from-plan cannot be used with a remote definition or targets, the plan defines what to build")
+		case len(in.files) > 0 || in.list != "" || in.watch || in.lock || in.updateLock:
+			return errors.New("--from-plan cannot be used with --file, --list, --watch, --lock or --update-lock")
+		}
 	}
+	targets = defaultBakeTargets(targets, in.defaultGroup)
 
 	callFunc, err := buildflags.ParseCallFunc(in.callFunc)
 	if err != nil {
 		return err
 	}
+	if in.requireChecks && callFunc != nil {
+		return errors.New("--require-checks cannot be used with --call")
+	}
+	// with --check and a json or github format, the errors of the definition
+	// are printed as diagnostics with their position in the source
+	var diagFormat string
+	if callFunc != nil && callFunc.Name == "lint" && (callFunc.Format == "json" || callFunc.Format == "github") {
+		diagFormat = callFunc.Format
+	}
+	if in.maxWarnings >= 0 && !in.requireChecks {
+		return errors.New("--max-warnings requires --require-checks")
+	}
+	warnPolicy, err := cFlags.warnings.policy()
+	if err != nil {
+		return err
+	}
+	if len(in.metadataFormats) > 0 && in.metadataFile == "" {
+		return errors.New("--metadata-format requires --metadata-file")
+	}
+	metadataFormats, err := parseMetadataFormats(in.metadataFormats)
+	if err != nil {
+		return err
+	}
+	schedulingPolicy, err := build.ParseSchedulingPolicy(in.scheduling)
+	if err != nil {
+		return err
+	}
+	platformBuilders, err := parseBuilderMap(in.builderMap)
+	if err != nil {
+		return err
+	}
 
-	overrides := in.overrides
+	overrides, err := bake.ReadOverrideFiles(in.overrideFiles)
+	if err != nil {
+		return err
+	}
+	overrides = append(overrides, in.overrides...)
+	for _, v := range in.jsonOverrides {
+		overrides = append(overrides, bake.JSONOverride(v))
+	}
 	if in.exportPush {
 		overrides = append(overrides, "*.push=true")
 	}
@@ -92,6 +199,11 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 	if cFlags.noCache != nil {
 		overrides = append(overrides, fmt.Sprintf("*.no-cache=%t", *cFlags.noCache))
 	}
+	noCacheOverrides, err := noCacheTargetOverrides(in.noCacheTgts)
+	if err != nil {
+		return err
+	}
+	overrides = append(overrides, noCacheOverrides...)
 	if cFlags.pull != nil {
 		overrides = append(overrides, fmt.Sprintf("*.pull=%t", *cFlags.pull))
 	}
@@ -101,6 +213,9 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 	if in.provenance != "" {
 		overrides = append(overrides, fmt.Sprintf("*.attest=%s", buildflags.CanonicalizeAttest("provenance", in.provenance)))
 	}
+	if in.fromPlan != "" && len(overrides) > 0 {
+		return errors.New("--from-plan cannot be used with options overriding the targets of the plan")
+	}
 	contextPathHash, _ := os.Getwd()
 
 	ent, err := bake.ParseEntitlements(in.allow)
@@ -115,6 +230,16 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 	ent.FSRead = append(ent.FSRead, wd)
 	ent.FSWrite = append(ent.FSWrite, wd)
 
+	entPolicy, err := bake.LoadEntitlementPolicy(confutil.NewConfig(dockerCli).Dir())
+	if err != nil {
+		return err
+	}
+	if entPolicy != nil {
+		if err := entPolicy.Apply(&ent); err != nil {
+			return err
+		}
+	}
+
 	ctx2, cancel := context.WithCancelCause(context.TODO())
 	defer cancel(errors.WithStack(context.Canceled))
 
@@ -123,7 +248,10 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 
 	// instance only needed for reading remote bake files or building
 	var driverType string
-	if url != "" || !(in.printOnly || in.listTargets || in.listVars) {
+	var buildDefaults *store.BuildDefaults
+	var imageopt imagetools.Opt
+	var bldr *builder.Builder
+	if url != "" || !(in.printOnly || in.planOnly || in.list != "") {
 		b, err := builder.New(dockerCli,
 			builder.WithName(in.builder),
 			builder.WithContextPathHash(contextPathHash),
@@ -141,6 +269,11 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 		progressConsoleDesc = fmt.Sprintf("%s:%s", b.Driver, b.Name)
 		progressTextDesc = fmt.Sprintf("building with %q instance using %s driver", b.Name, b.Driver)
 		driverType = b.Driver
+		buildDefaults = b.BuildDefaults
+		bldr = b
+		if imageopt, err = b.ImageOpt(); err != nil {
+			return err
+		}
 	}
 
 	var term bool
@@ -150,17 +283,30 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 	attributes := bakeMetricAttributes(dockerCli, driverType, url, cmdContext, targets, &in)
 
 	progressMode := progressui.DisplayMode(cFlags.progress)
+	groupBy, err := progress.ParseGroupBy(cFlags.progressGroupBy)
+	if err != nil {
+		return err
+	}
 	var printer *progress.Printer
 
 	makePrinter := func() error {
-		var err error
-		printer, err = progress.NewPrinter(ctx2, os.Stderr, progressMode,
+		printerOpts := []progress.PrinterOpt{
 			progress.WithDesc(progressTextDesc, progressConsoleDesc),
 			progress.WithMetrics(mp, attributes),
+			progress.WithTargetEvents(),
 			progress.WithOnClose(func() {
-				printWarnings(os.Stderr, printer.Warnings(), progressMode)
+				printBuildWarnings(os.Stderr, printer.Warnings(), progressMode, warnPolicy.format)
 			}),
-		)
+		}
+		if cFlags.summary {
+			printerOpts = append(printerOpts, progress.WithSummary())
+		}
+		if in.report || in.openReport {
+			printerOpts = append(printerOpts, progress.WithTargetReport())
+		}
+		printerOpts = append(printerOpts, progress.WithGroupBy(groupBy))
+		var err error
+		printer, err = progress.NewPrinter(ctx2, os.Stderr, progressMode, printerOpts...)
 		return err
 	}
 
@@ -168,52 +314,164 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 		return err
 	}
 
-	files, inp, err := readBakeFiles(ctx, nodes, url, in.files, dockerCli.In(), printer)
-	if err != nil {
-		return err
-	}
+	var (
+		tgts         map[string]*bake.Target
+		grps         map[string]*bake.Group
+		inp          *bake.Input
+		lintWarnings []bake.LintWarning
+		origins      map[string]bake.TargetOrigins
+	)
+	if in.fromPlan != "" {
+		// the targets of a plan are already resolved, so the definition
+		// and the environment are not read again
+		plan, err := bake.ReadPlan(in.fromPlan)
+		if err != nil {
+			return err
+		}
+		if tgts, grps, err = plan.Targets(); err != nil {
+			return err
+		}
+	} else {
+		lock, err := loadBakeLock(in)
+		if err != nil {
+			return err
+		}
+		var resolveRef bake.RefResolver
+		if in.lock || in.updateLock {
+			resolveRef = resolveGitRef
+		}
 
-	if len(files) == 0 {
-		return errors.New("couldn't find a bake definition")
-	}
+		defURL := url
+		if lock != nil && defURL != "" {
+			if defURL, err = lock.PinURL(ctx, defURL, resolveRef); err != nil {
+				return err
+			}
+		}
 
-	defaults := map[string]string{
-		// don't forget to update documentation if you add a new
-		// built-in variable: docs/bake-reference.md#built-in-variables
-		"BAKE_CMD_CONTEXT":    cmdContext,
-		"BAKE_LOCAL_PLATFORM": platforms.Format(platforms.DefaultSpec()),
-	}
+		var files []bake.File
+		files, inp, err = readBakeFiles(ctx, nodes, defURL, in.files, in.profile, dockerCli.In(), printer)
+		if err != nil {
+			return err
+		}
+		if inp != nil && inp.Root != "" {
+			defer os.RemoveAll(inp.Root)
+		}
+
+		if len(files) == 0 {
+			return errors.New("couldn't find a bake definition")
+		}
+
+		defaults := map[string]string{
+			// don't forget to update documentation if you add a new
+			// built-in variable: docs/bake-reference.md#built-in-variables
+			"BAKE_CMD_CONTEXT":    cmdContext,
+			"BAKE_LOCAL_PLATFORM": platforms.Format(platforms.DefaultSpec()),
+		}
+
+		if provider, ok, err := bake.ParseCIVariablesEnv(os.Getenv("BUILDX_BAKE_CI_VARIABLES")); err != nil {
+			return errors.Wrap(err, "invalid BUILDX_BAKE_CI_VARIABLES value")
+		} else if ok {
+			vars, err := bake.CIVariables(provider, os.LookupEnv)
+			if err != nil {
+				return err
+			}
+			for k, v := range vars {
+				defaults[k] = v
+			}
+		}
 
-	if in.listTargets || in.listVars {
-		cfg, pm, err := bake.ParseFiles(files, defaults)
+		if v, ok := os.LookupEnv("BUILDX_BAKE_PARSE_CACHE"); ok {
+			if enabled, err := strconv.ParseBool(v); err != nil {
+				return errors.Wrap(err, "invalid BUILDX_BAKE_PARSE_CACHE value")
+			} else if enabled {
+				cfg := confutil.NewConfig(dockerCli)
+				if err := cfg.MkdirAll(bakeParseCacheDir, 0755); err == nil {
+					bake.ParseCacheDir = filepath.Join(cfg.Dir(), bakeParseCacheDir)
+				}
+			}
+		}
+
+		if in.list == listTypeTargets || in.list == listTypeVariables {
+			cfg, pm, err := bake.ParseFiles(files, defaults)
+			if err != nil {
+				return err
+			}
+			if err = printer.Wait(); err != nil {
+				return err
+			}
+			if in.list == listTypeTargets {
+				return printTargetList(dockerCli.Out(), cfg)
+			}
+			return printVars(dockerCli.Out(), pm.AllVariables)
+		}
+
+		tgts, grps, err = bake.ReadTargets(ctx, files, targets, overrides, defaults, &ent)
 		if err != nil {
+			if diagFormat != "" {
+				_ = printer.Wait()
+				if err := printBakeDiagnostics(dockerCli.Out(), diagFormat, bake.Diagnostics(err, files)); err != nil {
+					return err
+				}
+				return cli.StatusError{StatusCode: 1}
+			}
 			return err
 		}
-		if err = printer.Wait(); err != nil {
+		if in.strictEnv {
+			if err := bake.CheckMissingVariables(files, defaults); err != nil {
+				return err
+			}
+		}
+		if err := bake.ResolveWorkspacePaths(tgts, in.workspaceRoot, inp); err != nil {
 			return err
 		}
-		if in.listTargets {
-			return printTargetList(dockerCli.Out(), cfg)
-		} else if in.listVars {
-			return printVars(dockerCli.Out(), pm.AllVariables)
+		if in.list == listTypeGHAMatrix {
+			if err = printer.Wait(); err != nil {
+				return err
+			}
+			return printGHAMatrix(dockerCli.Out(), tgts)
 		}
-	}
 
-	tgts, grps, err := bake.ReadTargets(ctx, files, targets, overrides, defaults, &ent)
-	if err != nil {
-		return err
-	}
+		if lock != nil {
+			if err := lock.PinTargets(ctx, tgts, resolveRef); err != nil {
+				return err
+			}
+			if resolveRef != nil {
+				if err := lock.Save(bake.LockFilename); err != nil {
+					return errors.Wrapf(err, "failed to write %s", bake.LockFilename)
+				}
+			}
+		}
+
+		if in.printOnly || in.requireChecks || (callFunc != nil && callFunc.Name == "lint") {
+			lintWarnings, err = bake.Lint(files, targets, defaults)
+			if err != nil {
+				return err
+			}
+		}
+		if in.printVerbose {
+			origins, err = bake.ReadOrigins(files, sortedKeys(tgts), overrides, defaults)
+			if err != nil {
+				return err
+			}
+		}
 
-	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
-		// TODO: extract env var parsing to a method easily usable by library consumers
-		for _, t := range tgts {
-			if _, ok := t.Args["SOURCE_DATE_EPOCH"]; ok {
-				continue
+		if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
+			// TODO: extract env var parsing to a method easily usable by library consumers
+			for _, t := range tgts {
+				if _, ok := t.Args["SOURCE_DATE_EPOCH"]; ok {
+					continue
+				}
+				if t.Args == nil {
+					t.Args = map[string]*string{}
+				}
+				t.Args["SOURCE_DATE_EPOCH"] = &v
 			}
-			if t.Args == nil {
-				t.Args = map[string]*string{}
+		}
+
+		if len(in.tagTemplates) > 0 {
+			if err := expandTargetTagTemplates(ctx, tgts, in.tagTemplates); err != nil {
+				return err
 			}
-			t.Args["SOURCE_DATE_EPOCH"] = &v
 		}
 	}
 
@@ -224,26 +482,71 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 	}
 
 	def := struct {
-		Group  map[string]*bake.Group  `json:"group,omitempty"`
-		Target map[string]*bake.Target `json:"target"`
+		Group     map[string]*bake.Group        `json:"group,omitempty"`
+		Target    map[string]*bake.Target       `json:"target"`
+		Overrides []string                      `json:"overrides,omitempty"`
+		Origins   map[string]bake.TargetOrigins `json:"origins,omitempty"`
 	}{
 		Group:  grps,
 		Target: tgts,
 	}
+	if in.printVerbose {
+		// echo the effective overrides, some of them may come from files
+		def.Overrides = overrides
+	}
+
+	defKey := bakeDefinitionKey(&in, url, cmdContext, targets)
+	var prevTargets map[string]*bake.Target
+	if in.printDiff {
+		if prevTargets, err = readBakeDefinition(dockerCli, defKey); err != nil {
+			return errors.Wrap(err, "failed to read previous bake definition")
+		}
+	}
+	if err := saveBakeDefinition(dockerCli, defKey, tgts); err != nil {
+		logrus.WithError(err).Debug("failed to save bake definition")
+	}
 
 	if in.printOnly {
 		if err = printer.Wait(); err != nil {
 			return err
 		}
-		dtdef, err := json.MarshalIndent(def, "", "  ")
-		if err != nil {
+		if len(lintWarnings) > 0 {
+			// the warnings are not part of the printed definition to keep its
+			// format stable for the tools parsing it
+			printLintWarnings(dockerCli.Err(), lintWarnings)
+		}
+		def.Origins = origins
+		var dtdef []byte
+		if in.printDiff {
+			diff, err := bake.DiffTargets(prevTargets, tgts)
+			if err != nil {
+				return err
+			}
+			dtdef, err = json.MarshalIndent(struct {
+				Target map[string]*bake.TargetDiff `json:"target"`
+			}{
+				Target: diff,
+			}, "", "  ")
+			if err != nil {
+				return err
+			}
+		} else if dtdef, err = json.MarshalIndent(def, "", "  "); err != nil {
 			return err
 		}
 		_, err = fmt.Fprintln(dockerCli.Out(), string(dtdef))
 		return err
 	}
 
-	for _, opt := range bo {
+	if in.watchState != nil {
+		if inp.IsRemote() {
+			return errors.New("--watch cannot be used with a remote bake definition")
+		}
+		if in.watchState.watcher, err = bake.NewWatcher(tgts); err != nil {
+			return err
+		}
+	}
+
+	for k, opt := range bo {
 		if opt.CallFunc != nil {
 			cf, err := buildflags.ParseCallFunc(opt.CallFunc.Name)
 			if err != nil {
@@ -251,13 +554,82 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 			}
 			opt.CallFunc.Name = cf.Name
 		}
+		opt.NoMetadataCache = cFlags.noMetadataCache
+		bo[k] = opt
+	}
+
+	if err := build.Preflight(bo); err != nil {
+		return err
+	}
+	if callFunc == nil {
+		// indexes are only assembled from the images pushed by a build
+		if err := bake.CheckIndexes(tgts); err != nil {
+			return err
+		}
+	}
+
+	args := make(map[string]map[string]string, len(bo))
+	for k, opt := range bo {
+		args[k] = opt.BuildArgs
+	}
+	if err := checkSecretArgs(printer, args, in.failOnSecretArgs); err != nil {
+		return err
+	}
+
+	if in.verifyPush && !in.planOnly {
+		if err := verifyPush(ctx, printer, imageopt, bakePushDestinations(tgts, bo)); err != nil {
+			return err
+		}
+	}
+
+	if entPolicy != nil {
+		if err := entPolicy.Check(bo); err != nil {
+			return err
+		}
+	}
+
+	if in.planFile != "" || in.planOnly {
+		plan, err := bake.NewPlan(tgts, grps, bo)
+		if err != nil {
+			return err
+		}
+		plan.Remote = url
+		if in.planFile != "" {
+			if err := writeMetadataFile(in.planFile, plan); err != nil {
+				return errors.Wrap(err, "failed to write plan file")
+			}
+		}
+		if in.planOnly {
+			if err = printer.Wait(); err != nil {
+				return err
+			}
+			if in.planFile != "" {
+				return nil
+			}
+			dtplan, err := json.MarshalIndent(plan, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(dockerCli.Out(), string(dtplan))
+			return err
+		}
 	}
 
 	exp, err := ent.Validate(bo)
 	if err != nil {
 		return err
 	}
-	if err := exp.Prompt(ctx, url != "", &syncWriter{w: dockerCli.Err(), wait: printer.Wait}); err != nil {
+	reqs, err := ent.Requests(bo)
+	if err != nil {
+		return err
+	}
+	if in.allowDryRun {
+		if err := printer.Wait(); err != nil {
+			return err
+		}
+		return bake.PrintRequests(dockerCli.Out(), reqs)
+	}
+	if err := exp.Prompt(ctx, url != "", reqs, &syncWriter{w: dockerCli.Err(), wait: printer.Wait}); err != nil {
 		return err
 	}
 	if printer.IsDone() {
@@ -271,17 +643,113 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 		return err
 	}
 
+	if err := build.WithBuildDefaults(bo, buildDefaults); err != nil {
+		return err
+	}
+	if in.noEmulation {
+		for k, opt := range bo {
+			opt.NoEmulation = true
+			bo[k] = opt
+		}
+	}
+	if schedulingPolicy.Mode != build.SchedulingDefault {
+		for k, opt := range bo {
+			opt.SchedulingPolicy = schedulingPolicy
+			bo[k] = opt
+		}
+	}
+	builders, builderNodes, err := loadTargetBuilders(ctx, dockerCli, bldr, tgts, bo, platformBuilders)
+	if err != nil {
+		return err
+	}
+	nodes = append(nodes, builderNodes...)
+
+	if in.requireChecks {
+		err := runRequiredChecks(ctx, dockerCli, nodes, bo, tgts, lintWarnings, in.maxWarnings, printer)
+		if perr := printer.Wait(); err == nil {
+			err = perr
+		}
+		if err != nil {
+			return err
+		}
+		if err := makePrinter(); err != nil {
+			return err
+		}
+	}
+
+	// hooks are not run for targets that only call a frontend method
+	hookTargets := map[string]*bake.Target{}
+	for name, opt := range bo {
+		if t, ok := tgts[name]; ok && opt.CallFunc == nil {
+			hookTargets[name] = t
+		}
+	}
+
 	done := timeBuildCommand(mp, attributes)
-	resp, retErr := build.Build(ctx, nodes, bo, dockerutil.NewClient(dockerCli), confutil.NewConfig(dockerCli), printer)
+	started := time.Now()
+	var resp map[string]*client.SolveResponse
+	var indexes map[string]ocispecs.Descriptor
+	retErr := bake.RunHooks(ctx, printer, bake.HookStagePre, hookTargets, nil)
+	var release func()
+	if retErr == nil {
+		release, retErr = leaseBuilders(ctx, printer, append([]*builder.Builder{bldr}, builders...))
+	}
+	if retErr == nil {
+		resp, retErr = build.Build(ctx, nodes, bo, dockerutil.NewClient(dockerCli), confutil.NewConfig(dockerCli), printer)
+		release()
+	}
+	if retErr == nil && callFunc == nil {
+		indexes, retErr = bake.PushIndexes(ctx, printer, imageopt, tgts, resp)
+	}
+	if retErr == nil {
+		retErr = bake.RunHooks(ctx, printer, bake.HookStagePost, hookTargets, resp)
+	}
 	if err := printer.Wait(); retErr == nil {
 		retErr = err
 	}
+	if err := warnPolicy.writeFile(printer.Warnings()); retErr == nil {
+		retErr = err
+	}
+	resps := make([]*client.SolveResponse, 0, len(resp))
+	for _, name := range sortedKeys(resp) {
+		resps = append(resps, resp[name])
+	}
+	summary := addStepResources(printer.Summary(), resps...)
+	printStepSummary(os.Stderr, summary, progressMode)
 	if retErr != nil {
 		err = wrapBuildError(retErr, true)
 	}
 	done(err)
 
-	if err != nil {
+	// the report is also written when the build failed
+	if in.report || in.openReport {
+		names := make([]string, 0, len(bo))
+		for name := range bo {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		imageNames := map[string]string{}
+		for name := range indexes {
+			imageNames[name] = strings.Join(tgts[name].Tags, ",")
+		}
+		report := newBakeReport(names, printer.TargetReports(), resp, indexes, imageNames, printer.BuildRefs(), started)
+		fn := reportFile(in.metadataFile)
+		if werr := writeBakeReport(fn, report); werr != nil {
+			logrus.Warnf("failed to write bake report: %v", werr)
+		} else {
+			fmt.Fprintf(dockerCli.Err(), "Bake report written to %s\n", fn)
+			if in.openReport {
+				if oerr := openInBrowser(fn); oerr != nil {
+					logrus.Warnf("failed to open bake report: %v", oerr)
+				}
+			}
+		}
+	}
+
+	// the targets that were loaded to docker are still reported when others
+	// failed to load
+	var loadErr *build.LoadError
+	if err != nil && !errors.As(retErr, &loadErr) {
 		return err
 	}
 
@@ -293,23 +761,40 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 		for t, r := range resp {
 			dt[t] = decodeExporterResponse(r.ExporterResponse)
 		}
+		for t, desc := range indexes {
+			dt[t] = map[string]any{
+				exptypes.ExporterImageDigestKey: desc.Digest.String(),
+				"image.name":                    strings.Join(tgts[t].Tags, ","),
+			}
+		}
+		if summary := dockerLoadSummary(resp); summary != nil {
+			dt["buildx.load.summary"] = summary
+		}
 		if callFunc == nil {
-			if warnings := printer.Warnings(); len(warnings) > 0 && confutil.MetadataWarningsEnabled() {
+			if warnings := printer.Warnings(); len(warnings) > 0 && (confutil.MetadataWarningsEnabled() || anyExplicitlyIncludes(metadataFormats, metadataSectionWarnings)) {
 				dt["buildx.build.warnings"] = warnings
 			}
 		}
-		if err := writeMetadataFile(in.metadataFile, dt); err != nil {
+		if len(summary) > 0 {
+			dt["buildx.build.summary"] = summary
+		}
+		if err := writeMetadataFiles(in.metadataFile, metadataFormats, dt); err != nil {
 			return err
 		}
 	}
+	if loadErr != nil {
+		return err
+	}
 
-	var callFormatJSON bool
+	var callFormatJSON, outlineMarkdown bool
 	jsonResults := map[string]map[string]any{}
+	var outlines []outlineTarget
 	if callFunc != nil {
 		callFormatJSON = callFunc.Format == "json"
+		outlineMarkdown = callFunc.Name == "outline" && callFunc.Format == "markdown"
 	}
 	var sep bool
-	var exitCode int
+	var results []callResult
 
 	names := make([]string, 0, len(bo))
 	for name := range bo {
@@ -317,6 +802,11 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 	}
 	slices.Sort(names)
 
+	if !callFormatJSON && len(lintWarnings) > 0 {
+		printLintWarnings(dockerCli.Out(), lintWarnings)
+		sep = true
+	}
+
 	for _, name := range names {
 		req := bo[name]
 		if req.CallFunc == nil {
@@ -338,15 +828,42 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 		if sp, ok := resp[name]; ok {
 			res = sp.ExporterResponse
 		}
+		cr := newCallResult(name, pf.Name, res, printer.BuildRefs()[name])
+
+		if outlineMarkdown && pf.Name == "outline" {
+			o, err := parseOutline(res)
+			if err != nil {
+				return err
+			}
+			t := tgts[name]
+			ot := outlineTarget{
+				Name:        name,
+				Description: t.Description,
+				Platforms:   t.Platforms,
+				Outline:     o,
+			}
+			if t.Context != nil {
+				ot.Context = *t.Context
+			}
+			if t.DockerfileInline != nil {
+				ot.Dockerfile = "(inline)"
+			} else if t.Dockerfile != nil {
+				ot.Dockerfile = *t.Dockerfile
+			}
+			if len(t.Target) > 0 {
+				ot.Stage = t.Target[0]
+			}
+			outlines = append(outlines, ot)
+			results = append(results, cr)
+			continue
+		}
 
 		if callFormatJSON {
 			jsonResults[name] = map[string]any{}
 			buf := &bytes.Buffer{}
-			if code, err := printResult(buf, pf, res, name, &req.Inputs); err != nil {
-				jsonResults[name]["error"] = err.Error()
-				exitCode = 1
-			} else if code != 0 && exitCode == 0 {
-				exitCode = code
+			cr.StatusCode, cr.Err = printResult(buf, pf, res, name, &req.Inputs)
+			if cr.Err != nil {
+				jsonResults[name]["error"] = cr.Err.Error()
 			}
 			m := map[string]*json.RawMessage{}
 			if err := json.Unmarshal(buf.Bytes(), &m); err == nil {
@@ -368,21 +885,29 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 			}
 
 			fmt.Fprintln(dockerCli.Out())
-			if code, err := printResult(dockerCli.Out(), pf, res, name, &req.Inputs); err != nil {
-				fmt.Fprintf(dockerCli.Out(), "error: %v\n", err)
-				exitCode = 1
-			} else if code != 0 && exitCode == 0 {
-				exitCode = code
+			cr.StatusCode, cr.Err = printResult(dockerCli.Out(), pf, res, name, &req.Inputs)
+			if cr.Err != nil {
+				fmt.Fprintf(dockerCli.Out(), "error: %v\n", cr.Err)
 			}
 		}
+		results = append(results, cr)
+	}
+	if outlineMarkdown {
+		if err := writeOutlineMarkdown(dockerCli.Out(), outlines); err != nil {
+			return err
+		}
+		fmt.Fprintln(dockerCli.Out())
 	}
+
 	if callFormatJSON {
 		out := struct {
-			Group  map[string]*bake.Group    `json:"group,omitempty"`
-			Target map[string]map[string]any `json:"target"`
+			Group    map[string]*bake.Group    `json:"group,omitempty"`
+			Target   map[string]map[string]any `json:"target"`
+			Warnings []bake.LintWarning        `json:"warnings,omitempty"`
 		}{
-			Group:  grps,
-			Target: map[string]map[string]any{},
+			Group:    grps,
+			Target:   map[string]map[string]any{},
+			Warnings: lintWarnings,
 		}
 
 		for name, def := range tgts {
@@ -402,18 +927,232 @@ func runBake(ctx context.Context, dockerCli command.Cli, targets []string, in ba
 			return err
 		}
 		fmt.Fprintln(dockerCli.Out(), string(dt))
+	} else if !outlineMarkdown && len(results) > 1 {
+		fmt.Fprintln(dockerCli.Out())
+		printCallSummary(dockerCli.Out(), results)
 	}
 
-	if exitCode != 0 {
+	if exitCode := callExitCode(results); exitCode != 0 {
 		os.Exit(exitCode)
 	}
 
+	if callFunc == nil {
+		return warnPolicy.check(printer.Warnings())
+	}
+	return nil
+}
+
+// runRequiredChecks runs the check method for all the targets and prints the
+// combined report. It fails if a check reports an error, or if the number of
+// warnings of the checks and of the definition exceeds maxWarnings when it is
+// not negative.
+func runRequiredChecks(ctx context.Context, dockerCli command.Cli, nodes []builder.Node, bo map[string]build.Options, tgts map[string]*bake.Target, defWarnings []bake.LintWarning, maxWarnings int, printer *progress.Printer) error {
+	checks := make(map[string]build.Options, len(bo))
+	for name, opt := range bo {
+		if opt.CallFunc != nil {
+			continue
+		}
+		opt.CallFunc = &build.CallFunc{Name: "lint"}
+		opt.Exports = nil
+		opt.ExportsLocalPathsTemporary = nil
+		opt.CacheTo = nil
+		checks[name] = opt
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+
+	resp, err := build.Build(ctx, nodes, checks, dockerutil.NewClient(dockerCli), confutil.NewConfig(dockerCli), printer)
+	if err != nil {
+		return wrapBuildError(err, true)
+	}
+	if err := printer.Wait(); err != nil {
+		return err
+	}
+	return reportRequiredChecks(dockerCli.Out(), checks, resp, tgts, defWarnings, maxWarnings)
+}
+
+// reportRequiredChecks prints the results of the checks of the targets and
+// returns an error if the build must not start.
+func reportRequiredChecks(w io.Writer, checks map[string]build.Options, resp map[string]*client.SolveResponse, tgts map[string]*bake.Target, defWarnings []bake.LintWarning, maxWarnings int) error {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	warnings := len(defWarnings)
+	var failed []string
+	if len(defWarnings) > 0 {
+		printLintWarnings(w, defWarnings)
+		fmt.Fprintln(w)
+	}
+	for _, name := range names {
+		var res map[string]string
+		if sp, ok := resp[name]; ok {
+			res = sp.ExporterResponse
+		}
+		var lintResults lint.LintResults
+		if dt, ok := res["result.json"]; ok {
+			if err := json.Unmarshal([]byte(dt), &lintResults); err != nil {
+				return err
+			}
+		}
+		warnings += len(lintResults.Warnings)
+
+		fmt.Fprintf(w, "%s\n", name)
+		if descr := tgts[name].Description; descr != "" {
+			fmt.Fprintf(w, "%s\n", descr)
+		}
+		fmt.Fprintln(w)
+		inp := checks[name].Inputs
+		if _, err := printResult(w, &pb.CallFunc{Name: "lint"}, res, name, &inp); err != nil {
+			fmt.Fprintf(w, "error: %v\n", err)
+			failed = append(failed, name)
+		}
+		fmt.Fprintln(w)
+	}
+
+	switch {
+	case len(failed) > 0:
+		return errors.Errorf("checks failed for %s, not building", strings.Join(failed, ", "))
+	case maxWarnings >= 0 && warnings > maxWarnings:
+		return errors.Errorf("checks found %d warnings, more than the maximum of %d, not building", warnings, maxWarnings)
+	}
+	fmt.Fprintf(w, "Checks passed for %d targets with %d warnings, building\n", len(names), warnings)
 	return nil
 }
 
+// dockerLoadSummary returns the images loaded to docker for each target and
+// the errors of the targets that failed to load, or nil if nothing was loaded.
+func dockerLoadSummary(resp map[string]*client.SolveResponse) map[string]any {
+	loaded := map[string][]string{}
+	failed := map[string]string{}
+	for name, r := range resp {
+		if v, ok := r.ExporterResponse[build.ExporterResponseLoadError]; ok {
+			failed[name] = v
+		} else if v, ok := r.ExporterResponse[build.ExporterResponseLoadImages]; ok {
+			loaded[name] = strings.Split(v, ",")
+		}
+	}
+	if len(loaded) == 0 && len(failed) == 0 {
+		return nil
+	}
+	return map[string]any{
+		"loaded": loaded,
+		"failed": failed,
+	}
+}
+
+// printLintWarnings prints the findings of the static analysis of the bake
+// definition.
+func printLintWarnings(w io.Writer, warnings []bake.LintWarning) {
+	fmt.Fprintln(w, "bake definition")
+	fmt.Fprintln(w)
+	if len(warnings) == 1 {
+		fmt.Fprintln(w, "1 warning found:")
+	} else {
+		fmt.Fprintf(w, "%d warnings found:\n", len(warnings))
+	}
+	for _, warn := range warnings {
+		fmt.Fprintf(w, " - %s\n", warn)
+	}
+}
+
+// callResult is the result of the method called for a target, as reported
+// in the summary printed after the results of all the targets.
+type callResult struct {
+	Name       string
+	Func       string
+	Warnings   int
+	Ref        string
+	DesktopRef string
+	StatusCode int
+	Err        error
+}
+
+func newCallResult(name, fn string, res map[string]string, desktopRef string) callResult {
+	cr := callResult{
+		Name:       name,
+		Func:       fn,
+		Ref:        res["buildx.build.ref"],
+		DesktopRef: desktopRef,
+	}
+	if fn == "lint" {
+		var lintResults lint.LintResults
+		if dt, ok := res["result.json"]; ok && json.Unmarshal([]byte(dt), &lintResults) == nil {
+			cr.Warnings = len(lintResults.Warnings)
+		}
+	}
+	return cr
+}
+
+// Failed returns whether the method failed or returned a non-zero status.
+func (r callResult) Failed() bool {
+	return r.Err != nil || r.StatusCode != 0
+}
+
+// callExitCode returns the exit code of bake for the results of the called
+// methods: 1 if the method of a target failed, otherwise the first non-zero
+// status code of the targets in name order. Results must be sorted by name.
+func callExitCode(results []callResult) int {
+	var code int
+	for _, r := range results {
+		if r.Err != nil {
+			return 1
+		}
+		if r.StatusCode != 0 && code == 0 {
+			code = r.StatusCode
+		}
+	}
+	return code
+}
+
+// printCallSummary prints whether the method called for each target passed,
+// with the number of warnings it reported and its build ref, or the link to
+// the build in Docker Desktop if it has one.
+func printCallSummary(w io.Writer, results []callResult) {
+	var failed, warnings int
+	for _, r := range results {
+		if r.Failed() {
+			failed++
+		}
+		warnings += r.Warnings
+	}
+
+	fmt.Fprintln(w, "Summary")
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	fmt.Fprintln(tw, "TARGET\tRESULT\tWARNINGS\tBUILD")
+	for _, r := range results {
+		status := "pass"
+		if r.Err != nil {
+			status = "fail"
+		} else if r.StatusCode != 0 {
+			status = fmt.Sprintf("fail (status %d)", r.StatusCode)
+		}
+		count := "-"
+		if r.Func == "lint" {
+			count = strconv.Itoa(r.Warnings)
+		}
+		ref := r.Ref
+		if r.DesktopRef != "" {
+			ref = desktop.BuildURL(r.DesktopRef)
+		}
+		if ref == "" {
+			ref = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Name, status, count, ref)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%d passed, %d failed, %d warnings\n", len(results)-failed, failed, warnings)
+}
+
 func bakeCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
 	var options bakeOptions
 	var cFlags commonFlags
+	var listTargets, listVars bool
 
 	cmd := &cobra.Command{
 		Use:     "bake [OPTIONS] [TARGET...]",
@@ -427,50 +1166,104 @@ func bakeCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
 			if !cmd.Flags().Lookup("pull").Changed {
 				cFlags.pull = nil
 			}
+			if !cmd.Flags().Lookup("env-profile").Changed {
+				options.profile = os.Getenv("BUILDX_BAKE_PROFILE")
+			}
+			if listTargets {
+				options.list = listTypeTargets
+			} else if listVars {
+				options.list = listTypeVariables
+			}
+			switch options.list {
+			case "", listTypeTargets, listTypeVariables, listTypeGHAMatrix:
+			default:
+				return errors.Errorf("invalid list type %q, expecting %s, %s or %s", options.list, listTypeTargets, listTypeVariables, listTypeGHAMatrix)
+			}
+			rootOpts.useProjectBuilder()
 			options.builder = rootOpts.builder
 			options.metadataFile = cFlags.metadataFile
+			options.metadataFormats = cFlags.metadataFormats
 			// Other common flags (noCache, pull and progress) are processed in runBake function.
+			if options.watch {
+				return runBakeWatch(cmd.Context(), dockerCli, args, options, cFlags)
+			}
 			return runBake(cmd.Context(), dockerCli, args, options, cFlags)
 		},
-		ValidArgsFunction: completion.BakeTargets(options.files),
+		ValidArgsFunction: completion.BakeTargets(&options.files),
 	}
 
 	flags := cmd.Flags()
 
 	flags.StringArrayVarP(&options.files, "file", "f", []string{}, "Build definition file")
+	flags.StringVar(&options.profile, "env-profile", "", `Include the "docker-bake.<profile>.hcl" and "docker-bake.<profile>.json" override files`)
+	flags.StringSliceVar(&options.defaultGroup, "default-group", nil, "Targets to build when no target is specified")
 	flags.BoolVar(&options.exportLoad, "load", false, `Shorthand for "--set=*.output=type=docker"`)
-	flags.BoolVar(&options.printOnly, "print", false, "Print the options without building")
+	flags.StringSliceVar(&options.noCacheTgts, "no-cache-target", nil, `Do not use cache for the given targets, or only for a stage of a target ("TARGET[:STAGE]")`)
+	flags.BoolVar(&options.noEmulation, "no-emulation", false, "Fail instead of building a platform under emulation")
+	flags.VarPF(printFlag(&options), "print", "", `Print the options without building ("verbose" to include where each field is set)`)
+	flags.Lookup("print").NoOptDefVal = "true"
+	flags.StringVar(&options.list, "list", "", `List targets, variables or a GitHub Actions matrix ("targets", "variables", "gha-matrix")`)
+	flags.StringVar(&options.scheduling, "scheduling-policy", "", `Distribute platforms across nodes ("prefer-native", "least-loaded", "pinned:NODE")`)
+	flags.StringArrayVar(&options.builderMap, "builder-map", nil, `Build a platform on another builder (e.g., "linux/arm64=arm-builder")`)
+	flags.BoolVar(&options.printDiff, "diff", false, "Print only the changes since the previous invocation (with --print)")
+	flags.BoolVar(&options.lock, "lock", false, `Pin remote definitions and contexts to commits recorded in "bake.lock"`)
+	flags.BoolVar(&options.updateLock, "update-lock", false, `Resolve remote definitions and contexts again and update "bake.lock"`)
+	flags.StringVar(&options.planFile, "plan-file", "", "Write the build plan to a file before building")
+	flags.BoolVar(&options.planOnly, "plan-only", false, "Write the build plan and exit without building")
+	flags.StringVar(&options.fromPlan, "from-plan", "", "Build the resolved targets of a plan file without reading the definition")
 	flags.BoolVar(&options.exportPush, "push", false, `Shorthand for "--set=*.output=type=registry"`)
 	flags.StringVar(&options.sbom, "sbom", "", `Shorthand for "--set=*.attest=type=sbom"`)
 	flags.StringVar(&options.provenance, "provenance", "", `Shorthand for "--set=*.attest=type=provenance"`)
 	flags.StringArrayVar(&options.overrides, "set", nil, `Override target value (e.g., "targetpattern.key=value")`)
+	flags.StringArrayVar(&options.jsonOverrides, "set-json", nil, `Override target value with a JSON value replacing the whole field (e.g., "targetpattern.key=json")`)
+	flags.StringArrayVar(&options.overrideFiles, "override-file", nil, "Read target overrides from a JSON or HCL file")
+	flags.StringArrayVar(&options.tagTemplates, "tag-template", nil, `Add a tag computed from a template to each target (e.g., "org/{{.Target}}:{{.GitShortSHA}}")`)
+	flags.BoolVar(&options.watch, "watch", false, "Rebuild the targets when the files they watch change")
+	flags.StringVar(&options.workspaceRoot, "workspace-root", "", "Resolve relative context paths against this directory (default: git repository root for workspace:// paths)")
 	flags.StringVar(&options.callFunc, "call", "build", `Set method for evaluating build ("check", "outline", "targets")`)
 	flags.StringArrayVar(&options.allow, "allow", nil, "Allow build to access specified resources")
+	flags.BoolVar(&options.allowDryRun, "allow-dry-run", false, "Print the privileges requested by the targets without building")
+	flags.BoolVar(&options.requireChecks, "require-checks", false, "Run the build checks of all targets first and build only if they pass")
+	flags.IntVar(&options.maxWarnings, "max-warnings", -1, "Maximum number of check warnings allowed with --require-checks")
+	flags.BoolVar(&options.failOnSecretArgs, "fail-on-secret-args", false, "Fail if build arguments look like secrets")
+	flags.BoolVar(&options.verifyPush, "verify-push", false, "Verify that the current credentials can push the images before building")
+	flags.BoolVar(&options.strictEnv, "strict-env", false, "Fail if variables without default are not set in the environment")
+	flags.BoolVar(&options.report, "report", false, "Write an HTML report of the build next to the metadata file")
+	flags.BoolVar(&options.openReport, "open-report", false, "Write an HTML report of the build and open it in the browser")
 
 	flags.VarPF(callAlias(&options.callFunc, "check"), "check", "", `Shorthand for "--call=check"`)
 	flags.Lookup("check").NoOptDefVal = "true"
 
-	flags.BoolVar(&options.listTargets, "list-targets", false, "List available targets")
+	flags.BoolVar(&listTargets, "list-targets", false, "List available targets")
 	cobrautil.MarkFlagsExperimental(flags, "list-targets")
 	flags.MarkHidden("list-targets")
 
-	flags.BoolVar(&options.listVars, "list-variables", false, "List defined variables")
+	flags.BoolVar(&listVars, "list-variables", false, "List defined variables")
 	cobrautil.MarkFlagsExperimental(flags, "list-variables")
 	flags.MarkHidden("list-variables")
 
 	commonBuildFlags(&cFlags, flags)
 
+	cmd.RegisterFlagCompletionFunc( //nolint:errcheck
+		"default-group",
+		completion.BakeTargets(&options.files),
+	)
 	return cmd
 }
 
 func saveLocalStateGroup(dockerCli command.Cli, in bakeOptions, targets []string, bo map[string]build.Options, overrides []string, def any) error {
+	metadataFormats, err := parseMetadataFormats(in.metadataFormats)
+	if err != nil {
+		return err
+	}
 	prm := confutil.MetadataProvenance()
-	if len(in.metadataFile) == 0 {
+	if len(in.metadataFile) == 0 || !anyIncludes(metadataFormats, metadataSectionProvenance) {
 		prm = confutil.MetadataProvenanceModeDisabled
 	}
 	groupRef := identity.NewID()
 	refs := make([]string, 0, len(bo))
-	for k, b := range bo {
+	for _, k := range sortedKeys(bo) {
+		b := bo[k]
 		if b.CallFunc != nil {
 			continue
 		}
@@ -499,8 +1292,234 @@ func saveLocalStateGroup(dockerCli command.Cli, in bakeOptions, targets []string
 	})
 }
 
+// bakeDefinitionKey identifies the definition files and targets of an
+// invocation, to compare its resolved targets with the previous one.
+func bakeDefinitionKey(in *bakeOptions, url, cmdContext string, targets []string) string {
+	if cmdContext == "cwd://" {
+		cmdContext = osutil.GetWd()
+	}
+	h := sha256.New()
+	for _, s := range []string{url, cmdContext, strings.Join(immutableSort(in.files), ","), strings.Join(immutableSort(targets), ","), in.profile} {
+		_, _ = io.WriteString(h, s)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readBakeDefinition returns the targets resolved by the last invocation of
+// bake with the same definition key, or nil if there is none.
+func readBakeDefinition(dockerCli command.Cli, key string) (map[string]*bake.Target, error) {
+	l, err := localstate.New(confutil.NewConfig(dockerCli))
+	if err != nil {
+		return nil, err
+	}
+	dt, err := l.ReadBakeDefinition(key)
+	if err != nil || dt == nil {
+		return nil, err
+	}
+	var tgts map[string]*bake.Target
+	if err := json.Unmarshal(dt, &tgts); err != nil {
+		return nil, err
+	}
+	return tgts, nil
+}
+
+func saveBakeDefinition(dockerCli command.Cli, key string, tgts map[string]*bake.Target) error {
+	l, err := localstate.New(confutil.NewConfig(dockerCli))
+	if err != nil {
+		return err
+	}
+	dt, err := json.Marshal(tgts)
+	if err != nil {
+		return err
+	}
+	return l.SaveBakeDefinition(key, dt)
+}
+
 // bakeArgs will retrieve the remote url, command context, and targets
 // from the command line arguments.
+// loadBakeLock returns the lock of remote definitions and contexts from the
+// working directory. A new lock is returned if it doesn't exist and locking
+// is requested, or if it's updated.
+func loadBakeLock(in bakeOptions) (*bake.Lock, error) {
+	if in.updateLock {
+		return bake.NewLock(), nil
+	}
+	l, err := bake.ReadLock(bake.LockFilename)
+	if err != nil || l != nil {
+		return l, err
+	}
+	if in.lock {
+		return bake.NewLock(), nil
+	}
+	return nil, nil
+}
+
+func resolveGitRef(ctx context.Context, remote, ref string) (string, error) {
+	gitc, err := gitutil.New(gitutil.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	return gitc.ResolveRef(remote, ref)
+}
+
+// expandTargetTagTemplates adds the tags computed by the templates to the
+// targets, with the metadata of each target.
+func expandTargetTagTemplates(ctx context.Context, tgts map[string]*bake.Target, templates []string) error {
+	for _, name := range sortedKeys(tgts) {
+		t := tgts[name]
+		var contextPath string
+		if t.Context != nil {
+			contextPath = *t.Context
+		}
+		data, err := build.NewTagTemplateData(ctx, name, contextPath, t.Platforms)
+		if err != nil {
+			return err
+		}
+		tags, err := build.ExpandTagTemplates(templates, data)
+		if err != nil {
+			return errors.Wrapf(err, "target %s", name)
+		}
+		t.Tags = append(t.Tags, tags...)
+	}
+	return nil
+}
+
+// noCacheTargetOverrides returns the overrides disabling the cache for the
+// targets of --no-cache-target. A value of the form TARGET:STAGE disables the
+// cache only for this stage of the target, like --no-cache-filter.
+func noCacheTargetOverrides(in []string) ([]string, error) {
+	var overrides []string
+	for _, v := range in {
+		name, stage, hasStage := strings.Cut(strings.TrimSpace(v), ":")
+		if name == "" || (hasStage && stage == "") {
+			return nil, errors.Errorf("invalid no-cache-target %q, expecting TARGET[:STAGE]", v)
+		}
+		if hasStage {
+			overrides = append(overrides, fmt.Sprintf("%s.no-cache-filter=%s", name, stage))
+		} else {
+			overrides = append(overrides, name+".no-cache=true")
+		}
+	}
+	return overrides, nil
+}
+
+// printFlag sets whether the definition is printed, and with "verbose" also
+// where the fields of the targets are set.
+// parseBuilderMap parses PLATFORM=BUILDER pairs into a map of normalized
+// platforms to builder names.
+func parseBuilderMap(in []string) (map[string]string, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]string, len(in))
+	for _, v := range in {
+		p, name, ok := strings.Cut(v, "=")
+		if !ok || p == "" || name == "" {
+			return nil, errors.Errorf("invalid builder map %q, expecting PLATFORM=BUILDER", v)
+		}
+		pp, err := platforms.Parse(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid platform in builder map %q", v)
+		}
+		m[platforms.Format(platforms.Normalize(pp))] = name
+	}
+	return m, nil
+}
+
+// loadTargetBuilders sets the builders the targets and platforms are built on
+// when they are not the builder b of the command. It returns the other
+// builders and their nodes, which are added to the ones of the build.
+func loadTargetBuilders(ctx context.Context, dockerCli command.Cli, b *builder.Builder, tgts map[string]*bake.Target, bo map[string]build.Options, platformBuilders map[string]string) ([]*builder.Builder, []builder.Node, error) {
+	names := map[string]struct{}{}
+	for name := range bo {
+		if t, ok := tgts[name]; ok && t.Builder != nil && *t.Builder != "" {
+			names[*t.Builder] = struct{}{}
+		}
+	}
+	for _, name := range platformBuilders {
+		names[name] = struct{}{}
+	}
+	delete(names, b.Name)
+	if len(names) == 0 {
+		return nil, nil, nil
+	}
+
+	var builders []*builder.Builder
+	var nodes []builder.Node
+	for _, name := range sortedKeys(names) {
+		ob, err := builder.New(dockerCli, builder.WithName(name))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := updateLastActivity(dockerCli, ob.NodeGroup); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to update builder last activity time")
+		}
+		ns, err := ob.LoadNodes(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		builders = append(builders, ob)
+		nodes = append(nodes, ns...)
+	}
+
+	for name, opt := range bo {
+		opt.SchedulingPolicy.Builder = b.Name
+		if t, ok := tgts[name]; ok && t.Builder != nil && *t.Builder != "" {
+			opt.SchedulingPolicy.Builder = *t.Builder
+		}
+		opt.SchedulingPolicy.PlatformBuilders = platformBuilders
+		bo[name] = opt
+	}
+	return builders, nodes, nil
+}
+
+// leaseBuilders takes a build slot on each of the builders.
+func leaseBuilders(ctx context.Context, pw progress.Writer, builders []*builder.Builder) (func(), error) {
+	releases := make([]func(), 0, len(builders))
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	for _, b := range builders {
+		r, err := b.Lease(ctx, pw)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+	return release, nil
+}
+
+func printFlag(in *bakeOptions) cobrautil.BoolFuncValue {
+	return func(s string) error {
+		if s == "verbose" {
+			in.printOnly, in.printVerbose = true, true
+			return nil
+		}
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.Errorf("invalid print mode %q, expecting a boolean or verbose", s)
+		}
+		in.printOnly, in.printVerbose = v, false
+		return nil
+	}
+}
+
+// defaultBakeTargets returns the targets to build. If none is named, the
+// targets set with --default-group are built, or the "default" group.
+func defaultBakeTargets(targets, defaultGroup []string) []string {
+	if len(targets) > 0 {
+		return targets
+	}
+	if len(defaultGroup) > 0 {
+		return defaultGroup
+	}
+	return []string{"default"}
+}
+
 func bakeArgs(args []string) (url, cmdContext string, targets []string) {
 	cmdContext, targets = "cwd://", args
 	if len(targets) == 0 || !build.IsRemoteURL(targets[0]) {
@@ -514,7 +1533,7 @@ func bakeArgs(args []string) (url, cmdContext string, targets []string) {
 	return url, cmdContext, targets
 }
 
-func readBakeFiles(ctx context.Context, nodes []builder.Node, url string, names []string, stdin io.Reader, pw progress.Writer) (files []bake.File, inp *bake.Input, err error) {
+func readBakeFiles(ctx context.Context, nodes []builder.Node, url string, names []string, profile string, stdin io.Reader, pw progress.Writer) (files []bake.File, inp *bake.Input, err error) {
 	var lnames []string // local
 	var rnames []string // remote
 	var anames []string // both
@@ -531,7 +1550,7 @@ func readBakeFiles(ctx context.Context, nodes []builder.Node, url string, names
 
 	if url != "" {
 		var rfiles []bake.File
-		rfiles, inp, err = bake.ReadRemoteFiles(ctx, nodes, url, rnames, pw)
+		rfiles, inp, err = bake.ReadRemoteFiles(ctx, nodes, url, rnames, profile, pw)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -542,9 +1561,9 @@ func readBakeFiles(ctx context.Context, nodes []builder.Node, url string, names
 		var lfiles []bake.File
 		progress.Wrap("[internal] load local bake definitions", pw.Write, func(sub progress.SubLogger) error {
 			if url != "" {
-				lfiles, err = bake.ReadLocalFiles(lnames, stdin, sub)
+				lfiles, err = bake.ReadLocalFiles(lnames, profile, stdin, sub)
 			} else {
-				lfiles, err = bake.ReadLocalFiles(anames, stdin, sub)
+				lfiles, err = bake.ReadLocalFiles(anames, profile, stdin, sub)
 			}
 			return nil
 		})
@@ -557,6 +1576,27 @@ func readBakeFiles(ctx context.Context, nodes []builder.Node, url string, names
 	return
 }
 
+// printBakeDiagnostics prints the diagnostics of the definition as JSON, or as
+// GitHub Actions annotations.
+func printBakeDiagnostics(w io.Writer, format string, diags []bake.Diagnostic) error {
+	if format == "github" {
+		for _, d := range diags {
+			fmt.Fprintln(w, d.GitHubAnnotation())
+		}
+		return nil
+	}
+	dt, err := json.MarshalIndent(struct {
+		Diagnostics []bake.Diagnostic `json:"diagnostics"`
+	}{
+		Diagnostics: diags,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(dt))
+	return err
+}
+
 func printVars(w io.Writer, vars []*hclparser.Variable) error {
 	slices.SortFunc(vars, func(a, b *hclparser.Variable) int {
 		return cmp.Compare(a.Name, b.Name)
@@ -629,6 +1669,42 @@ func printTargetList(w io.Writer, cfg *bake.Config) error {
 	return nil
 }
 
+// ghaMatrixEntry is an entry of the GitHub Actions matrix printed with
+// --list=gha-matrix, to run a job for each target.
+type ghaMatrixEntry struct {
+	Target    string   `json:"target"`
+	Platforms []string `json:"platforms,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// printGHAMatrix prints the resolved targets as the include list of a GitHub
+// Actions matrix.
+func printGHAMatrix(w io.Writer, tgts map[string]*bake.Target) error {
+	names := make([]string, 0, len(tgts))
+	for name := range tgts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	include := make([]ghaMatrixEntry, 0, len(names))
+	for _, name := range names {
+		t := tgts[name]
+		include = append(include, ghaMatrixEntry{
+			Target:    name,
+			Platforms: t.Platforms,
+			Tags:      t.Tags,
+		})
+	}
+	dt, err := json.Marshal(struct {
+		Include []ghaMatrixEntry `json:"include"`
+	}{Include: include})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(dt))
+	return err
+}
+
 func bakeMetricAttributes(dockerCli command.Cli, driverType, url, cmdContext string, targets []string, options *bakeOptions) attribute.Set {
 	return attribute.NewSet(
 		commandNameAttribute.String("bake"),
@@ -673,7 +1749,11 @@ func (o *bakeOptionsHash) String() string {
 		salt := o.cfg.TryNodeIdentifier()
 
 		h := sha256.New()
-		for _, s := range []string{url, cmdContext, joinedFiles, joinedTargets, salt} {
+		inputs := []string{url, cmdContext, joinedFiles, joinedTargets, salt}
+		if o.profile != "" {
+			inputs = append(inputs, o.profile)
+		}
+		for _, s := range inputs {
 			_, _ = io.WriteString(h, s)
 			h.Write([]byte{0})
 		}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/buildx/bake"
+	"github.com/docker/buildx/build"
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/frontend/subrequests/lint"
+	solverpb "github.com/moby/buildkit/solver/pb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoCacheTargetOverrides(t *testing.T) {
+	overrides, err := noCacheTargetOverrides([]string{"web", "api:deps", "api:base"})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"web.no-cache=true",
+		"api.no-cache-filter=deps",
+		"api.no-cache-filter=base",
+	}, overrides)
+
+	for _, v := range []string{"", ":deps", "web:"} {
+		_, err := noCacheTargetOverrides([]string{v})
+		require.ErrorContains(t, err, "invalid no-cache-target")
+	}
+}
+
+func TestDefaultBakeTargets(t *testing.T) {
+	fp := bake.File{
+		Name: "docker-bake.hcl",
+		Data: []byte(`
+group "default" {
+  targets = ["app"]
+}
+group "ci" {
+  targets = ["app", "test"]
+}
+target "app" {}
+target "test" {}
+target "docs" {}
+`),
+	}
+
+	tcs := []struct {
+		name         string
+		targets      []string
+		defaultGroup []string
+		expected     []string
+	}{
+		{
+			name:     "default group",
+			expected: []string{"app"},
+		},
+		{
+			name:         "default group flag",
+			defaultGroup: []string{"ci"},
+			expected:     []string{"app", "test"},
+		},
+		{
+			name:         "default group flag with targets",
+			defaultGroup: []string{"ci", "docs"},
+			expected:     []string{"app", "docs", "test"},
+		},
+		{
+			name:         "named target",
+			targets:      []string{"docs"},
+			defaultGroup: []string{"ci"},
+			expected:     []string{"docs"},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			m, _, err := bake.ReadTargets(context.TODO(), []bake.File{fp}, defaultBakeTargets(tc.targets, tc.defaultGroup), nil, nil, &bake.EntitlementConf{})
+			require.NoError(t, err)
+			names := make([]string, 0, len(m))
+			for name := range m {
+				names = append(names, name)
+			}
+			require.ElementsMatch(t, tc.expected, names)
+		})
+	}
+}
+
+func TestPrintGHAMatrix(t *testing.T) {
+	var b bytes.Buffer
+	err := printGHAMatrix(&b, map[string]*bake.Target{
+		"web": {Platforms: []string{"linux/amd64", "linux/arm64"}, Tags: []string{"user/web:latest"}},
+		"api": {},
+	})
+	require.NoError(t, err)
+	require.Equal(t, `{"include":[{"target":"api"},{"target":"web","platforms":["linux/amd64","linux/arm64"],"tags":["user/web:latest"]}]}`+"\n", b.String())
+}
+
+func TestCallSummary(t *testing.T) {
+	results := []callResult{
+		newCallResult("api", "lint", map[string]string{
+			"buildx.build.ref": "builder/builder0/ref1",
+			"result.json":      `{"warnings":[{"ruleName":"StageNameCasing"},{"ruleName":"FromAsCasing"}]}`,
+		}, ""),
+		newCallResult("db", "lint", map[string]string{}, ""),
+		newCallResult("web", "outline", map[string]string{}, "ref3"),
+	}
+	require.Equal(t, 2, results[0].Warnings)
+	require.Equal(t, 0, callExitCode(results))
+
+	results[1].StatusCode = 2
+	require.Equal(t, 2, callExitCode(results))
+	results[2].StatusCode = 3
+	require.Equal(t, 2, callExitCode(results))
+	results[2].Err = errors.New("failed")
+	require.Equal(t, 1, callExitCode(results))
+
+	var b bytes.Buffer
+	printCallSummary(&b, results)
+	require.Equal(t, `Summary
+
+TARGET	RESULT		WARNINGS	BUILD
+api	pass		2		builder/builder0/ref1
+db	fail (status 2)	0		-
+web	fail		-		docker-desktop://dashboard/build/ref3
+
+1 passed, 2 failed, 2 warnings
+`, b.String())
+}
+
+func TestParseBuilderMap(t *testing.T) {
+	m, err := parseBuilderMap([]string{"linux/arm64=arm-builder", "linux/arm/v7=arm-builder", "linux/amd64=x86"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"linux/arm64":  "arm-builder",
+		"linux/arm/v7": "arm-builder",
+		"linux/amd64":  "x86",
+	}, m)
+
+	_, err = parseBuilderMap([]string{"linux/arm64"})
+	require.ErrorContains(t, err, "expecting PLATFORM=BUILDER")
+	_, err = parseBuilderMap([]string{"linux/arm64="})
+	require.ErrorContains(t, err, "expecting PLATFORM=BUILDER")
+}
+
+func lintResponse(t *testing.T, res lint.LintResults) *client.SolveResponse {
+	dt, err := json.Marshal(res)
+	require.NoError(t, err)
+	return &client.SolveResponse{ExporterResponse: map[string]string{"result.json": string(dt)}}
+}
+
+func TestReportRequiredChecks(t *testing.T) {
+	checks := map[string]build.Options{"app": {}, "docs": {}}
+	tgts := map[string]*bake.Target{"app": {Name: "app"}, "docs": {Name: "docs"}}
+	warning := lint.Warning{
+		RuleName:    "FromAsCasing",
+		Description: "The 'as' keyword should match the case of the 'from' keyword",
+		Detail:      "'as' and 'FROM' keywords' casing do not match",
+		Location:    &solverpb.Location{SourceIndex: -1},
+	}
+
+	t.Run("passing checks build", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := reportRequiredChecks(&buf, checks, map[string]*client.SolveResponse{
+			"app":  lintResponse(t, lint.LintResults{Warnings: []lint.Warning{warning}}),
+			"docs": lintResponse(t, lint.LintResults{}),
+		}, tgts, nil, -1)
+		require.NoError(t, err)
+		require.Contains(t, buf.String(), "Checks passed for 2 targets with 1 warnings, building")
+	})
+
+	t.Run("failing check aborts", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := reportRequiredChecks(&buf, checks, map[string]*client.SolveResponse{
+			"app": lintResponse(t, lint.LintResults{
+				Sources: []*solverpb.SourceInfo{{Filename: "Dockerfile", Data: []byte("FROM\n")}},
+				Error: &lint.BuildError{
+					Message:  "Dockerfile parse error on line 1: FROM requires either one or three arguments",
+					Location: solverpb.Location{SourceIndex: 0, Ranges: []*solverpb.Range{{Start: &solverpb.Position{Line: 1}, End: &solverpb.Position{Line: 1}}}},
+				},
+			}),
+			"docs": lintResponse(t, lint.LintResults{}),
+		}, tgts, nil, -1)
+		require.EqualError(t, err, "checks failed for app, not building")
+		require.NotContains(t, buf.String(), "building\n")
+	})
+
+	t.Run("too many warnings abort", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := reportRequiredChecks(&buf, checks, map[string]*client.SolveResponse{
+			"app":  lintResponse(t, lint.LintResults{Warnings: []lint.Warning{warning}}),
+			"docs": lintResponse(t, lint.LintResults{}),
+		}, tgts, []bake.LintWarning{{Rule: "UnusedVariable", Message: "variable \"TAG\" is declared but never used"}}, 1)
+		require.EqualError(t, err, "checks found 2 warnings, more than the maximum of 1, not building")
+	})
+}
@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/docker/buildx/util/desktop"
+	"github.com/docker/buildx/util/progress"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	reportStatusSucceeded  = "succeeded"
+	reportStatusFailed     = "failed"
+	reportStatusIncomplete = "incomplete"
+	reportStatusNotStarted = "not started"
+)
+
+// bakeReport is the summary of a bake run rendered as an HTML page.
+type bakeReport struct {
+	Created   time.Time
+	Duration  time.Duration
+	Succeeded int
+	Failed    int
+	Targets   []bakeReportTarget
+}
+
+type bakeReportTarget struct {
+	Name       string
+	Status     string
+	Error      string
+	Duration   time.Duration
+	Steps      int
+	Cached     int
+	CacheRatio float64
+	Image      string
+	Digest     string
+	Warnings   []string
+	BuildURL   string
+}
+
+// newBakeReport returns the report of the targets of a bake run from the
+// reports of the printer and the responses of the build.
+func newBakeReport(names []string, reports []progress.TargetReport, resp map[string]*client.SolveResponse, indexes map[string]ocispecs.Descriptor, imageNames map[string]string, buildRefs map[string]string, started time.Time) bakeReport {
+	byName := map[string]progress.TargetReport{}
+	for _, r := range reports {
+		byName[r.Name] = r
+	}
+	report := bakeReport{
+		Created:  time.Now(),
+		Duration: time.Since(started),
+	}
+	for _, name := range names {
+		t := bakeReportTarget{
+			Name:   name,
+			Status: reportStatusNotStarted,
+		}
+		if r, ok := byName[name]; ok {
+			switch {
+			case r.Error != "":
+				t.Status = reportStatusFailed
+				t.Error = r.Error
+			case r.Finished.IsZero():
+				t.Status = reportStatusIncomplete
+			default:
+				t.Status = reportStatusSucceeded
+			}
+			t.Duration = r.Duration()
+			t.Steps = r.Steps
+			t.Cached = r.Cached
+			t.CacheRatio = r.CacheRatio()
+			t.Warnings = r.Warnings
+		}
+		if sp, ok := resp[name]; ok && sp != nil {
+			t.Image = sp.ExporterResponse["image.name"]
+			t.Digest = sp.ExporterResponse[exptypes.ExporterImageDigestKey]
+		}
+		if desc, ok := indexes[name]; ok {
+			t.Image = imageNames[name]
+			t.Digest = desc.Digest.String()
+		}
+		if ref, ok := buildRefs[name]; ok {
+			t.BuildURL = desktop.BuildURL(ref)
+		}
+		switch t.Status {
+		case reportStatusSucceeded:
+			report.Succeeded++
+		case reportStatusFailed:
+			report.Failed++
+		}
+		report.Targets = append(report.Targets, t)
+	}
+	return report
+}
+
+// reportFile returns the path of the report of a bake run, next to the
+// metadata file if set.
+func reportFile(metadataFile string) string {
+	if metadataFile == "" {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("buildx-bake-report-%d.html", time.Now().Unix()))
+	}
+	return strings.TrimSuffix(metadataFile, filepath.Ext(metadataFile)) + ".html"
+}
+
+func writeBakeReport(filename string, report bakeReport) error {
+	var buf bytes.Buffer
+	if err := bakeReportTemplate.Execute(&buf, report); err != nil {
+		return err
+	}
+	return ioutils.AtomicWriteFile(filename, buf.Bytes(), 0644)
+}
+
+// fileURL returns the file URL of filename, made absolute.
+func fileURL(filename string) (string, error) {
+	p, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	p = filepath.ToSlash(p)
+	if !strings.HasPrefix(p, "/") {
+		// windows paths start with the volume name
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String(), nil
+}
+
+// openInBrowser opens a file with the default browser of the system.
+func openInBrowser(filename string) error {
+	u, err := fileURL(filename)
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	return cmd.Start()
+}
+
+var bakeReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": func(d time.Duration) string {
+		if d == 0 {
+			return "-"
+		}
+		return d.Round(100 * time.Millisecond).String()
+	},
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%.0f%%", v*100)
+	},
+	"url": func(s string) template.URL {
+		return template.URL(s) //nolint:gosec // build refs are only links to Docker Desktop
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Bake report</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1d1d1f; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.4em 0.8em; border-bottom: 1px solid #ddd; vertical-align: top; }
+th { background: #f5f5f7; }
+code { font-size: 0.9em; word-break: break-all; }
+.succeeded { color: #1a7f37; }
+.failed { color: #cf222e; }
+.incomplete, .not-started { color: #9a6700; }
+ul { margin: 0; padding-left: 1.2em; }
+pre { white-space: pre-wrap; margin: 0.4em 0 0; color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>Bake report</h1>
+<p>{{ len .Targets }} targets: {{ .Succeeded }} succeeded, {{ .Failed }} failed, in {{ duration .Duration }}. Generated on {{ .Created.Format "2006-01-02 15:04:05 MST" }}.</p>
+<table>
+<tr><th>Target</th><th>Status</th><th>Duration</th><th>Cached steps</th><th>Image</th><th>Warnings</th><th>Build</th></tr>
+{{- range .Targets }}
+<tr>
+<td>{{ .Name }}</td>
+<td class="{{ if eq .Status "not started" }}not-started{{ else }}{{ .Status }}{{ end }}">{{ .Status }}{{ if .Error }}<pre>{{ .Error }}</pre>{{ end }}</td>
+<td>{{ duration .Duration }}</td>
+<td>{{ if .Steps }}{{ .Cached }}/{{ .Steps }} ({{ percent .CacheRatio }}){{ else }}-{{ end }}</td>
+<td>{{ if .Image }}<code>{{ .Image }}</code><br>{{ end }}{{ if .Digest }}<code>{{ .Digest }}</code>{{ end }}{{ if not (or .Image .Digest) }}-{{ end }}</td>
+<td>{{ if .Warnings }}<ul>{{ range .Warnings }}<li>{{ . }}</li>{{ end }}</ul>{{ else }}-{{ end }}</td>
+<td>{{ if .BuildURL }}<a href="{{ url .BuildURL }}">details</a>{{ else }}-{{ end }}</td>
+</tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/buildx/util/progress"
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBakeReport(t *testing.T) {
+	now := time.Now()
+	reports := []progress.TargetReport{
+		{Name: "app", Started: now, Finished: now.Add(2 * time.Second), Steps: 4, Cached: 3, Warnings: []string{"FromAsCasing"}},
+		{Name: "db", Started: now, Finished: now.Add(time.Second), Error: "process \"<make>\" did not complete"},
+	}
+	resp := map[string]*client.SolveResponse{
+		"app": {ExporterResponse: map[string]string{
+			"image.name":                    "docker.io/org/app:latest",
+			exptypes.ExporterImageDigestKey: "sha256:0123",
+		}},
+	}
+	report := newBakeReport([]string{"app", "db", "web"}, reports, resp, nil, nil, map[string]string{"app": "builder/builder0/abc"}, now)
+	require.Equal(t, 1, report.Succeeded)
+	require.Equal(t, 1, report.Failed)
+	require.Len(t, report.Targets, 3)
+	require.Equal(t, reportStatusSucceeded, report.Targets[0].Status)
+	require.Equal(t, "sha256:0123", report.Targets[0].Digest)
+	require.InDelta(t, 0.75, report.Targets[0].CacheRatio, 0.001)
+	require.Equal(t, "docker-desktop://dashboard/build/builder/builder0/abc", report.Targets[0].BuildURL)
+	require.Equal(t, reportStatusFailed, report.Targets[1].Status)
+	require.Equal(t, reportStatusNotStarted, report.Targets[2].Status)
+
+	fn := filepath.Join(t.TempDir(), "report.html")
+	require.NoError(t, writeBakeReport(fn, report))
+	dt, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	require.Contains(t, string(dt), "3/4 (75%)")
+	require.Contains(t, string(dt), "docker.io/org/app:latest")
+	require.Contains(t, string(dt), `href="docker-desktop://dashboard/build/builder/builder0/abc"`)
+	require.Contains(t, string(dt), "&lt;make&gt;")
+}
+
+func TestReportFile(t *testing.T) {
+	require.Equal(t, filepath.Join("out", "metadata.html"), reportFile(filepath.Join("out", "metadata.json")))
+	require.Equal(t, os.TempDir(), filepath.Dir(reportFile("")))
+}
+
+func TestFileURL(t *testing.T) {
+	u, err := fileURL("bake report #1.html")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(u, "file:///"), u)
+	require.True(t, strings.HasSuffix(u, "/commands/bake%20report%20%231.html"), u)
+
+	pu, err := url.Parse(u)
+	require.NoError(t, err)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.Equal(t, "/"+strings.TrimPrefix(filepath.ToSlash(filepath.Join(wd, "bake report #1.html")), "/"), pu.Path)
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/buildx/bake"
+	"github.com/docker/cli/cli/command"
+	"github.com/pkg/errors"
+)
+
+type bakeWatchState struct {
+	watcher *bake.Watcher
+}
+
+// runBakeWatch builds the targets, then builds them again each time the
+// files under the paths they watch change, until the context is canceled.
+// Failed builds don't stop watching, but errors happening before the targets
+// are resolved do.
+func runBakeWatch(ctx context.Context, dockerCli command.Cli, targets []string, in bakeOptions, cFlags commonFlags) error {
+	if in.printOnly || in.planOnly || in.list != "" || in.allowDryRun {
+		return errors.New("--watch can only be used to build")
+	}
+	for {
+		st := &bakeWatchState{}
+		in.watchState = st
+		err := runBake(ctx, dockerCli, targets, in, cFlags)
+		if st.watcher == nil {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(dockerCli.Err(), "ERROR: %v\n", err)
+		}
+		fmt.Fprintf(dockerCli.Err(), "Watching %s for changes...\n", strings.Join(st.watcher.Paths(), ", "))
+		changed, err := st.watcher.Wait(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
+		fmt.Fprintf(dockerCli.Err(), "Change detected in %s, rebuilding\n", changed[0])
+	}
+}
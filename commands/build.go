@@ -11,9 +11,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"text/tabwriter"
 	"time"
 
 	"github.com/containerd/console"
@@ -30,6 +33,7 @@ import (
 	"github.com/docker/buildx/store/storeutil"
 	"github.com/docker/buildx/util/buildflags"
 	"github.com/docker/buildx/util/cobrautil"
+	"github.com/docker/buildx/util/cobrautil/completion"
 	"github.com/docker/buildx/util/confutil"
 	"github.com/docker/buildx/util/desktop"
 	"github.com/docker/buildx/util/ioset"
@@ -42,17 +46,20 @@ import (
 	dockeropts "github.com/docker/cli/opts"
 	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/go-units"
 	"github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/exporter/containerimage/exptypes"
 	"github.com/moby/buildkit/frontend/subrequests"
 	"github.com/moby/buildkit/frontend/subrequests/lint"
 	"github.com/moby/buildkit/frontend/subrequests/outline"
 	"github.com/moby/buildkit/frontend/subrequests/targets"
+	"github.com/moby/buildkit/identity"
 	"github.com/moby/buildkit/solver/errdefs"
 	solverpb "github.com/moby/buildkit/solver/pb"
 	"github.com/moby/buildkit/util/grpcerrors"
 	"github.com/moby/buildkit/util/progress/progressui"
 	"github.com/morikuni/aec"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -71,14 +78,23 @@ type buildOptions struct {
 	cacheFrom      []string
 	cacheTo        []string
 	cgroupParent   string
+	compression    string
 	contextPath    string
 	contexts       []string
 	dockerfileName string
+	dns            []string
+	dnsOptions     []string
+	dnsSearch      []string
 	extraHosts     []string
+	hostsFile      string
+	frontendImage  string
 	imageIDFile    string
 	labels         []string
 	networkMode    string
 	noCacheFilter  []string
+	noEmulation    bool
+	scheduling     string
+	offline        bool
 	outputs        []string
 	platforms      []string
 	callFunc       string
@@ -86,6 +102,7 @@ type buildOptions struct {
 	shmSize        dockeropts.MemBytes
 	ssh            []string
 	tags           []string
+	tagTemplates   []string
 	target         string
 	ulimits        *dockeropts.UlimitOpt
 
@@ -93,15 +110,26 @@ type buildOptions struct {
 	sbom       string
 	provenance string
 
-	progress string
-	quiet    bool
-
-	builder      string
-	metadataFile string
-	noCache      bool
-	pull         bool
-	exportPush   bool
-	exportLoad   bool
+	progress        string
+	progressGroupBy string
+	quiet           bool
+
+	builder         string
+	metadataFile    string
+	metadataFormats []string
+	summary         bool
+	cacheReport     string
+	noCache         bool
+	pull            bool
+	noMetadataCache bool
+	exportPush      bool
+	exportLoad      bool
+	createRepo      bool
+	attachLogs      string
+
+	failOnSecretArgs bool
+	verifyPush       bool
+	warnings         warningsOptions
 
 	control.ControlOptions
 
@@ -126,12 +154,15 @@ func (o *buildOptions) toControllerOptions() (*controllerapi.BuildOptions, error
 		Annotations:    o.annotations,
 		BuildArgs:      buildArgs,
 		CgroupParent:   o.cgroupParent,
+		Compression:    o.compression,
 		ContextPath:    o.contextPath,
 		DockerfileName: o.dockerfileName,
 		ExtraHosts:     o.extraHosts,
 		Labels:         labels,
 		NetworkMode:    o.networkMode,
 		NoCacheFilter:  o.noCacheFilter,
+		NoEmulation:    o.noEmulation,
+		Offline:        o.offline,
 		Platforms:      o.platforms,
 		ShmSize:        int64(o.shmSize),
 		Tags:           o.tags,
@@ -143,6 +174,33 @@ func (o *buildOptions) toControllerOptions() (*controllerapi.BuildOptions, error
 		ExportPush:     o.exportPush,
 		ExportLoad:     o.exportLoad,
 	}
+	if o.scheduling != "" {
+		opts.SchedulingPolicy = o.scheduling
+	}
+	opts.NoMetadataCache = o.noMetadataCache
+	opts.DNS = o.dns
+	opts.DNSSearch = o.dnsSearch
+	opts.DNSOptions = o.dnsOptions
+
+	if o.hostsFile != "" {
+		hosts, err := build.ReadHostsFile(o.hostsFile)
+		if err != nil {
+			return nil, err
+		}
+		// hosts set with --add-host come first so that they take precedence
+		opts.ExtraHosts = append(slices.Clone(o.extraHosts), hosts...)
+	}
+
+	if o.frontendImage != "" {
+		img, err := build.ParseFrontendImage(o.frontendImage)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := opts.BuildArgs[build.FrontendImageArg]; ok && v != img {
+			return nil, errors.Errorf("--frontend-image conflicts with build argument %s=%s", build.FrontendImageArg, v)
+		}
+		opts.BuildArgs[build.FrontendImageArg] = img
+	}
 
 	// TODO: extract env var parsing to a method easily usable by library consumers
 	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
@@ -182,6 +240,33 @@ func (o *buildOptions) toControllerOptions() (*controllerapi.BuildOptions, error
 			return nil, errors.Errorf("local and tar exporters are incompatible with image ID file")
 		}
 	}
+	if o.createRepo {
+		var imageExport bool
+		for _, e := range opts.Exports {
+			if e.Type == client.ExporterImage || e.Type == "registry" {
+				if e.Attrs == nil {
+					e.Attrs = map[string]string{}
+				}
+				e.Attrs["create-repo"] = "true"
+				imageExport = true
+			}
+		}
+		if !imageExport {
+			if !o.exportPush {
+				return nil, errors.Errorf("--create-repo requires --push or an image output")
+			}
+			opts.Exports = append(opts.Exports, &controllerapi.ExportEntry{
+				Type: client.ExporterImage,
+				Attrs: map[string]string{
+					"create-repo": "true",
+				},
+			})
+		}
+	}
+
+	if o.attachLogs != "" && !o.exportPush && !pushExports(opts.Exports) {
+		return nil, errors.Errorf("--attach-logs requires pushing the image")
+	}
 
 	opts.CacheFrom, err = buildflags.ParseCacheEntry(o.cacheFrom)
 	if err != nil {
@@ -206,8 +291,16 @@ func (o *buildOptions) toControllerOptions() (*controllerapi.BuildOptions, error
 		return nil, err
 	}
 
+	if len(o.metadataFormats) > 0 && o.metadataFile == "" {
+		return nil, errors.New("--metadata-format requires --metadata-file")
+	}
+	metadataFormats, err := parseMetadataFormats(o.metadataFormats)
+	if err != nil {
+		return nil, err
+	}
+
 	prm := confutil.MetadataProvenance()
-	if opts.CallFunc != nil || len(o.metadataFile) == 0 {
+	if opts.CallFunc != nil || len(o.metadataFile) == 0 || !anyIncludes(metadataFormats, metadataSectionProvenance) {
 		prm = confutil.MetadataProvenanceModeDisabled
 	}
 	opts.ProvenanceResponseMode = string(prm)
@@ -294,6 +387,17 @@ func runBuild(ctx context.Context, dockerCli command.Cli, options buildOptions)
 	if err != nil {
 		return err
 	}
+	if len(options.tagTemplates) > 0 {
+		data, err := build.NewTagTemplateData(ctx, "", opts.ContextPath, opts.Platforms)
+		if err != nil {
+			return err
+		}
+		tags, err := build.ExpandTagTemplates(options.tagTemplates, data)
+		if err != nil {
+			return err
+		}
+		opts.Tags = append(opts.Tags, tags...)
+	}
 
 	// Avoid leaving a stale file if we eventually fail
 	if options.imageIDFile != "" {
@@ -331,21 +435,75 @@ func runBuild(ctx context.Context, dockerCli command.Cli, options buildOptions)
 	if err != nil {
 		return err
 	}
+	warnPolicy, err := options.warnings.policy()
+	if err != nil {
+		return err
+	}
 	var printer *progress.Printer
-	printer, err = progress.NewPrinter(ctx2, os.Stderr, progressMode,
+	printerOpts := []progress.PrinterOpt{
 		progress.WithDesc(
 			fmt.Sprintf("building with %q instance using %s driver", b.Name, b.Driver),
 			fmt.Sprintf("%s:%s", b.Driver, b.Name),
 		),
 		progress.WithMetrics(mp, attributes),
 		progress.WithOnClose(func() {
-			printWarnings(os.Stderr, printer.Warnings(), progressMode)
+			printBuildWarnings(os.Stderr, printer.Warnings(), progressMode, warnPolicy.format)
 		}),
-	)
+	}
+	if options.summary {
+		printerOpts = append(printerOpts, progress.WithSummary())
+	}
+	if options.cacheReport != "" {
+		printerOpts = append(printerOpts, progress.WithCacheReport())
+	}
+	groupBy, err := progress.ParseGroupBy(options.progressGroupBy)
+	if err != nil {
+		return err
+	}
+	printerOpts = append(printerOpts, progress.WithGroupBy(groupBy))
+	attachLogs, err := parseAttachLogs(options.attachLogs)
+	if err != nil {
+		return err
+	}
+	if attachLogs != nil {
+		printerOpts = append(printerOpts, progress.WithLog())
+	}
+	compression, err := buildflags.ParseCompression(options.compression)
+	if err != nil {
+		return err
+	}
+	if compression != nil && compression.Estimate {
+		printerOpts = append(printerOpts, progress.WithExportSize())
+	}
+	printer, err = progress.NewPrinter(ctx2, os.Stderr, progressMode, printerOpts...)
 	if err != nil {
 		return err
 	}
 
+	if err := checkSecretArgs(printer, map[string]map[string]string{"default": opts.BuildArgs}, options.failOnSecretArgs); err != nil {
+		printer.Wait()
+		return err
+	}
+
+	if options.verifyPush {
+		var names []string
+		if options.exportPush {
+			names = append(names, opts.Tags...)
+		}
+		for _, e := range opts.Exports {
+			names = append(names, pushDestinations(opts.Tags, e.Type, e.Attrs)...)
+		}
+		imageopt, err := b.ImageOpt()
+		if err != nil {
+			printer.Wait()
+			return err
+		}
+		if err := verifyPush(ctx, printer, imageopt, uniqueNames(names)); err != nil {
+			printer.Wait()
+			return err
+		}
+	}
+
 	done := timeBuildCommand(mp, attributes)
 	var resp *client.SolveResponse
 	var inputs *build.Inputs
@@ -359,9 +517,34 @@ func runBuild(ctx context.Context, dockerCli command.Cli, options buildOptions)
 	if err := printer.Wait(); retErr == nil {
 		retErr = err
 	}
+	if err := warnPolicy.writeFile(printer.Warnings()); retErr == nil {
+		retErr = err
+	}
+	summary := addStepResources(printer.Summary(), resp)
+	printStepSummary(os.Stderr, summary, progressMode)
+	if retErr == nil && compression != nil && compression.Estimate {
+		printCompressionEstimate(os.Stderr, compression, printer.ExportedSize(), progressMode)
+	}
+
+	// the cache report is also written when the build failed
+	if options.cacheReport != "" {
+		if err := writeMetadataFile(options.cacheReport, newCacheReport(printer.CacheReport(), opts.CacheFrom)); err != nil && retErr == nil {
+			retErr = errors.Wrap(err, "writing cache report")
+		}
+	}
 
 	done(retErr)
 	if retErr != nil {
+		if options.metadataFile != "" && options.invokeConfig != nil && len(options.invokeConfig.onErrorResults) > 0 {
+			var buildRef string
+			var be *desktop.ErrorWithBuildRef
+			if errors.As(retErr, &be) {
+				buildRef = be.Ref
+			}
+			if err := writeOnErrorMetadata(options.metadataFile, options.metadataFormats, buildRef, options.invokeConfig.onErrorResults); err != nil {
+				logrus.Warnf("failed to write metadata file: %v", err)
+			}
+		}
 		return retErr
 	}
 
@@ -378,14 +561,32 @@ func runBuild(ctx context.Context, dockerCli command.Cli, options buildOptions)
 			return errors.Wrap(err, "writing image ID file")
 		}
 	}
+	var logsDigest digest.Digest
+	if attachLogs != nil && opts.CallFunc == nil {
+		desc, err := attachBuildLogs(ctx, b, printer, resp.ExporterResponse, inputs, attachLogs)
+		if err != nil {
+			return err
+		}
+		logsDigest = desc.Digest
+	}
 	if options.metadataFile != "" {
+		metadataFormats, err := parseMetadataFormats(options.metadataFormats)
+		if err != nil {
+			return err
+		}
 		dt := decodeExporterResponse(resp.ExporterResponse)
+		if logsDigest != "" {
+			dt["buildx.build.logs.digest"] = logsDigest
+		}
 		if opts.CallFunc == nil {
-			if warnings := printer.Warnings(); len(warnings) > 0 && confutil.MetadataWarningsEnabled() {
+			if warnings := printer.Warnings(); len(warnings) > 0 && (confutil.MetadataWarningsEnabled() || anyExplicitlyIncludes(metadataFormats, metadataSectionWarnings)) {
 				dt["buildx.build.warnings"] = warnings
 			}
 		}
-		if err := writeMetadataFile(options.metadataFile, dt); err != nil {
+		if len(summary) > 0 {
+			dt["buildx.build.summary"] = summary
+		}
+		if err := writeMetadataFiles(options.metadataFile, metadataFormats, dt); err != nil {
 			return err
 		}
 	}
@@ -395,8 +596,9 @@ func runBuild(ctx context.Context, dockerCli command.Cli, options buildOptions)
 		} else if exitcode != 0 {
 			os.Exit(exitcode)
 		}
+		return nil
 	}
-	return nil
+	return warnPolicy.check(printer.Warnings())
 }
 
 // getImageID returns the image ID - the digest of the image config
@@ -502,6 +704,16 @@ func runControllerBuild(ctx context.Context, dockerCli command.Cli, opts *contro
 			// Update return values with the last build result from monitor
 			resp, retErr = monitorBuildResult.Resp, monitorBuildResult.Err
 		}
+	} else if options.invokeConfig != nil && len(options.invokeConfig.onErrorCmds) > 0 && retErr != nil {
+		if err := printError(retErr, printer); err != nil {
+			logrus.Warnf("failed to print error information: %v", err)
+		}
+		if err := options.invokeConfig.runOnError(ctx, ref, c, os.Stdout, os.Stderr, printer); err != nil {
+			logrus.Warnf("failed to run on-error commands: %v", err)
+		}
+		if err := c.Disconnect(ctx, ref); err != nil {
+			logrus.Warnf("disconnect error: %v", err)
+		}
 	} else {
 		if err := c.Disconnect(ctx, ref); err != nil {
 			logrus.Warnf("disconnect error: %v", err)
@@ -553,8 +765,13 @@ func buildCmd(dockerCli command.Cli, rootOpts *rootOptions, debugConfig *debug.D
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			options.contextPath = args[0]
+			rootOpts.useProjectBuilder()
 			options.builder = rootOpts.builder
 			options.metadataFile = cFlags.metadataFile
+			options.metadataFormats = cFlags.metadataFormats
+			options.summary = cFlags.summary
+			options.noMetadataCache = cFlags.noMetadataCache
+			options.warnings = cFlags.warnings
 			options.noCache = false
 			if cFlags.noCache != nil {
 				options.noCache = *cFlags.noCache
@@ -564,13 +781,15 @@ func buildCmd(dockerCli command.Cli, rootOpts *rootOptions, debugConfig *debug.D
 				options.pull = *cFlags.pull
 			}
 			options.progress = cFlags.progress
+			options.progressGroupBy = cFlags.progressGroupBy
 			cmd.Flags().VisitAll(checkWarnedFlags)
 
-			if debugConfig != nil && (debugConfig.InvokeFlag != "" || debugConfig.OnFlag != "") {
+			if debugConfig != nil && (debugConfig.InvokeFlag != "" || debugConfig.OnFlag != "" || debugConfig.OnErrorFlag != "") {
 				iConfig := new(invokeConfig)
 				if err := iConfig.parseInvokeConfig(debugConfig.InvokeFlag, debugConfig.OnFlag); err != nil {
 					return err
 				}
+				iConfig.onErrorCmds = parseOnErrorCmds(debugConfig.OnErrorFlag)
 				options.invokeConfig = iConfig
 			}
 
@@ -590,22 +809,41 @@ func buildCmd(dockerCli command.Cli, rootOpts *rootOptions, debugConfig *debug.D
 
 	flags.StringSliceVar(&options.extraHosts, "add-host", []string{}, `Add a custom host-to-IP mapping (format: "host:ip")`)
 
+	flags.StringVar(&options.hostsFile, "add-hosts-file", "", "Add the host-to-IP mappings of a file in hosts file format")
+
 	flags.StringSliceVar(&options.allow, "allow", []string{}, `Allow extra privileged entitlement (e.g., "network.host", "security.insecure")`)
 
 	flags.StringArrayVarP(&options.annotations, "annotation", "", []string{}, "Add annotation to the image")
 
+	flags.StringVar(&options.attachLogs, "attach-logs", "", `Push the build log as an artifact referring to the image (e.g., "log,dockerfile")`)
+	flags.Lookup("attach-logs").NoOptDefVal = "log"
+
 	flags.StringArrayVar(&options.buildArgs, "build-arg", []string{}, "Set build-time variables")
 
 	flags.StringArrayVar(&options.cacheFrom, "cache-from", []string{}, `External cache sources (e.g., "user/app:cache", "type=local,src=path/to/dir")`)
 
 	flags.StringArrayVar(&options.cacheTo, "cache-to", []string{}, `Cache export destinations (e.g., "user/app:cache", "type=local,dest=path/to/dir")`)
+	flags.StringVar(&options.cacheReport, "cache-report", "", "Write a report of the cache hits and misses of the build steps to a file")
 
 	flags.StringVar(&options.cgroupParent, "cgroup-parent", "", `Set the parent cgroup for the "RUN" instructions during build`)
 
+	flags.StringVar(&options.compression, "compression", "", `Compression of the image layers (format: "<type>[:<level>][,force][,estimate]")`)
+
 	flags.StringArrayVar(&options.contexts, "build-context", []string{}, "Additional build contexts (e.g., name=path)")
 
+	flags.StringArrayVar(&options.dns, "dns", []string{}, "Set the DNS nameservers of the build containers")
+	flags.StringArrayVar(&options.dnsOptions, "dns-option", []string{}, "Set the DNS options of the build containers")
+	flags.StringArrayVar(&options.dnsSearch, "dns-search", []string{}, "Set the DNS search domains of the build containers")
+
+	flags.BoolVar(&options.createRepo, "create-repo", false, "Create the repository on Amazon ECR or Google Artifact Registry if it does not exist when pushing")
+
+	flags.BoolVar(&options.failOnSecretArgs, "fail-on-secret-args", false, "Fail if build arguments look like secrets")
+	flags.BoolVar(&options.verifyPush, "verify-push", false, "Verify that the current credentials can push the image before building")
+
 	flags.StringVarP(&options.dockerfileName, "file", "f", "", `Name of the Dockerfile (default: "PATH/Dockerfile")`)
 
+	flags.StringVar(&options.frontendImage, "frontend-image", "", `Dockerfile frontend image, overriding the syntax directive (e.g., "docker/dockerfile:1.7", "1.7")`)
+
 	flags.StringVar(&options.imageIDFile, "iidfile", "", "Write the image ID to a file")
 
 	flags.StringArrayVar(&options.labels, "label", []string{}, "Set metadata for an image")
@@ -616,10 +854,16 @@ func buildCmd(dockerCli command.Cli, rootOpts *rootOptions, debugConfig *debug.D
 
 	flags.StringArrayVar(&options.noCacheFilter, "no-cache-filter", []string{}, "Do not cache specified stages")
 
+	flags.BoolVar(&options.noEmulation, "no-emulation", false, "Fail instead of building a platform under emulation")
+
+	flags.BoolVar(&options.offline, "offline", false, "Fail instead of accessing the network from the client or the build")
+
 	flags.StringArrayVarP(&options.outputs, "output", "o", []string{}, `Output destination (format: "type=local,dest=path")`)
 
 	flags.StringArrayVar(&options.platforms, "platform", platformsDefault, "Set target platform for build")
 
+	flags.StringVar(&options.scheduling, "scheduling-policy", "", `Distribute platforms across nodes ("prefer-native", "least-loaded", "pinned:NODE")`)
+
 	flags.BoolVar(&options.exportPush, "push", false, `Shorthand for "--output=type=registry"`)
 
 	flags.BoolVarP(&options.quiet, "quiet", "q", false, "Suppress the build output and print image ID on success")
@@ -631,6 +875,7 @@ func buildCmd(dockerCli command.Cli, rootOpts *rootOptions, debugConfig *debug.D
 	flags.StringArrayVar(&options.ssh, "ssh", []string{}, `SSH agent socket or keys to expose to the build (format: "default|<id>[=<socket>|<key>[,<key>]]")`)
 
 	flags.StringArrayVarP(&options.tags, "tag", "t", []string{}, `Name and optionally a tag (format: "name:tag")`)
+	flags.StringArrayVar(&options.tagTemplates, "tag-template", []string{}, `Name and tag computed from a template of the build metadata (e.g., "app:{{.GitShortSHA}}")`)
 
 	flags.StringVar(&options.target, "target", "", "Set the target build stage to build")
 
@@ -707,22 +952,37 @@ func buildCmd(dockerCli command.Cli, rootOpts *rootOptions, debugConfig *debug.D
 	flags.MarkHidden("force-rm")
 
 	commonBuildFlags(cFlags, flags)
+
+	cmd.RegisterFlagCompletionFunc( //nolint:errcheck
+		"platform",
+		completion.Platforms,
+	)
 	return cmd
 }
 
 // comomnFlags is a set of flags commonly shared among subcommands.
 type commonFlags struct {
-	metadataFile string
-	progress     string
-	noCache      *bool
-	pull         *bool
+	metadataFile    string
+	metadataFormats []string
+	progress        string
+	progressGroupBy string
+	summary         bool
+	noCache         *bool
+	pull            *bool
+	noMetadataCache bool
+	warnings        warningsOptions
 }
 
 func commonBuildFlags(options *commonFlags, flags *pflag.FlagSet) {
 	options.noCache = flags.Bool("no-cache", false, "Do not use cache when building the image")
 	flags.StringVar(&options.progress, "progress", "auto", `Set type of progress output ("auto", "plain", "tty", "rawjson"). Use plain to show container output`)
+	flags.StringVar(&options.progressGroupBy, "progress-group-by", "", `Group the steps of the build in the progress output ("stage", "target", "none")`)
 	options.pull = flags.Bool("pull", false, "Always attempt to pull all referenced images")
+	flags.BoolVar(&options.noMetadataCache, "no-metadata-cache", false, "Resolve image references over the network instead of using the metadata cache")
 	flags.StringVar(&options.metadataFile, "metadata-file", "", "Write build result metadata to a file")
+	flags.StringArrayVar(&options.metadataFormats, "metadata-format", nil, "Write only these sections of the metadata, one file per flag")
+	flags.BoolVar(&options.summary, "summary", false, "Print a summary of the build steps sorted by duration")
+	warningsFlags(&options.warnings, flags)
 }
 
 func checkWarnedFlags(f *pflag.Flag) {
@@ -888,9 +1148,148 @@ func printWarnings(w io.Writer, warnings []client.VertexWarning, mode progressui
 	}
 }
 
+// checkSecretArgs reports the build arguments of the targets that are likely
+// secrets, and fails instead if fail is set.
+func checkSecretArgs(pw progress.Writer, args map[string]map[string]string, fail bool) error {
+	patterns, err := buildflags.DefaultSecretArgPatterns()
+	if err != nil {
+		return err
+	}
+
+	targets := make([]string, 0, len(args))
+	for k := range args {
+		targets = append(targets, k)
+	}
+	sort.Strings(targets)
+
+	var found []string
+	for _, k := range targets {
+		for _, a := range buildflags.ScanSecretArgs(args[k], patterns) {
+			msg := fmt.Sprintf("build argument %q looks like a secret (%s)", a.Name, a.Pattern)
+			if len(args) > 1 {
+				msg = fmt.Sprintf("target %q: %s", k, msg)
+			}
+			found = append(found, msg)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	if fail {
+		return errors.Errorf("secrets must not be passed as build arguments, use --secret instead:\n - %s", strings.Join(found, "\n - "))
+	}
+	return progress.Wrap("WARNING: build arguments may contain secrets, use --secret instead", pw.Write, func(sub progress.SubLogger) error {
+		for _, msg := range found {
+			sub.Log(2, []byte(msg+"\n"))
+		}
+		return nil
+	})
+}
+
+// addStepResources sets the resource usage sampled by the builders on the
+// steps of the summary.
+func addStepResources(steps []progress.StepSummary, resps ...*client.SolveResponse) []progress.StepSummary {
+	resources := map[digest.Digest]build.StepResources{}
+	for _, resp := range resps {
+		if resp == nil {
+			continue
+		}
+		res, err := build.DecodeResources(resp.ExporterResponse)
+		if err != nil {
+			logrus.Debugf("failed to decode resource usage: %v", err)
+			continue
+		}
+		for k, v := range res {
+			resources[k] = v
+		}
+	}
+	for i, s := range steps {
+		if r, ok := resources[s.Vertex]; ok {
+			steps[i].CPU = time.Duration(r.CPUNanos)
+			steps[i].MemoryPeak = int64(r.MemoryPeak)
+		}
+	}
+	return steps
+}
+
+func printStepSummary(w io.Writer, steps []progress.StepSummary, mode progressui.DisplayMode) {
+	if len(steps) == 0 || mode == progressui.QuietMode || mode == progressui.RawJSONMode {
+		return
+	}
+	var resources bool
+	for _, s := range steps {
+		if s.CPU > 0 || s.MemoryPeak > 0 {
+			resources = true
+			break
+		}
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 1, 8, 2, ' ', 0)
+	if resources {
+		fmt.Fprintln(tw, "DURATION\tCACHED\tTRANSFERRED\tCPU\tMEMORY\tSTEP")
+	} else {
+		fmt.Fprintln(tw, "DURATION\tCACHED\tTRANSFERRED\tSTEP")
+	}
+	for _, s := range steps {
+		cached := "no"
+		if s.Cached {
+			cached = "yes"
+		}
+		transferred := "-"
+		if s.Transferred > 0 {
+			transferred = units.HumanSize(float64(s.Transferred))
+		}
+		if !resources {
+			fmt.Fprintf(tw, "%.1fs\t%s\t%s\t%s\n", s.Duration.Seconds(), cached, transferred, s.Name)
+			continue
+		}
+		cpu, memory := "-", "-"
+		if s.CPU > 0 {
+			cpu = fmt.Sprintf("%.1fs", s.CPU.Seconds())
+		}
+		if s.MemoryPeak > 0 {
+			memory = units.BytesSize(float64(s.MemoryPeak))
+		}
+		fmt.Fprintf(tw, "%.1fs\t%s\t%s\t%s\t%s\t%s\n", s.Duration.Seconds(), cached, transferred, cpu, memory, s.Name)
+	}
+	tw.Flush()
+}
+
+// printCompressionEstimate prints the size of the exported image blobs, and
+// the size difference expected against gzip.
+func printCompressionEstimate(w io.Writer, c *buildflags.Compression, size int64, mode progressui.DisplayMode) {
+	if mode == progressui.QuietMode || mode == progressui.RawJSONMode {
+		return
+	}
+	fmt.Fprintln(w)
+	if size == 0 {
+		fmt.Fprintf(w, "Compression estimate unavailable: no image layers were pushed\n")
+		return
+	}
+	gzipSize := int64(float64(size) / c.GzipRatio())
+	diff := float64(size-gzipSize) / float64(gzipSize) * 100
+	fmt.Fprintf(w, "Compression estimate: %s as %s, about %s as gzip (%+.0f%%)\n", units.HumanSize(float64(size)), c, units.HumanSize(float64(gzipSize)), diff)
+}
+
 func printResult(w io.Writer, f *controllerapi.CallFunc, res map[string]string, target string, inp *build.Inputs) (int, error) {
 	switch f.Name {
 	case "outline":
+		if f.Format == "markdown" {
+			o, err := parseOutline(res)
+			if err != nil {
+				return 0, err
+			}
+			name := target
+			if name == "" {
+				name = "default"
+			}
+			if err := writeOutlineMarkdown(w, []outlineTarget{{Name: name, Stage: target, Outline: o}}); err != nil {
+				return 0, err
+			}
+			fmt.Fprintln(w)
+			return 0, nil
+		}
 		return 0, printValue(w, outline.PrintOutline, outline.SubrequestsOutlineDefinition.Version, f.Format, res)
 	case "targets":
 		return 0, printValue(w, targets.PrintTargets, targets.SubrequestsTargetsDefinition.Version, f.Format, res)
@@ -992,9 +1391,24 @@ type invokeConfig struct {
 	controllerapi.InvokeConfig
 	onFlag     string
 	invokeFlag string
+
+	// onErrorCmds are executed non-interactively when the build fails
+	// instead of launching the monitor.
+	onErrorCmds []string
+	// onErrorResults holds the results of onErrorCmds.
+	onErrorResults []debugCommandResult
+}
+
+type debugCommandResult struct {
+	Command string `json:"command"`
+	Output  string `json:"output"`
+	Error   string `json:"error,omitempty"`
 }
 
 func (cfg *invokeConfig) needsDebug(retErr error) bool {
+	if len(cfg.onErrorCmds) > 0 && cfg.invokeFlag == "" && cfg.onFlag != "always" {
+		return false
+	}
 	switch cfg.onFlag {
 	case "always":
 		return true
@@ -1018,6 +1432,92 @@ func (cfg *invokeConfig) runDebug(ctx context.Context, ref string, options *cont
 	return monitor.RunMonitor(ctx, ref, options, &cfg.InvokeConfig, c, stdin, stdout, stderr, progress)
 }
 
+// runOnError executes onErrorCmds one after another in the container of the
+// failed step and records their combined output.
+func (cfg *invokeConfig) runOnError(ctx context.Context, ref string, c control.BuildxController, stdout, stderr io.Writer, progress *progress.Printer) error {
+	if err := progress.Pause(); err != nil {
+		return err
+	}
+	defer progress.Unpause()
+
+	for _, cmd := range cfg.onErrorCmds {
+		fmt.Fprintf(stderr, "#debug> %s\n", cmd)
+		var buf bytes.Buffer
+		var mu sync.Mutex
+		icfg := &controllerapi.InvokeConfig{
+			Entrypoint: []string{"/bin/sh", "-c"},
+			Cmd:        []string{cmd},
+			Env:        cfg.Env,
+		}
+		err := c.Invoke(ctx, ref, identity.NewID(), icfg,
+			io.NopCloser(strings.NewReader("")),
+			nopWriteCloser{&lockedWriter{mu: &mu, w: io.MultiWriter(stdout, &buf)}},
+			nopWriteCloser{&lockedWriter{mu: &mu, w: io.MultiWriter(stderr, &buf)}},
+		)
+		res := debugCommandResult{
+			Command: cmd,
+		}
+		if err != nil {
+			res.Error = err.Error()
+			fmt.Fprintf(stderr, "#debug> %s: %v\n", cmd, err)
+		}
+		mu.Lock()
+		res.Output = buf.String()
+		mu.Unlock()
+		cfg.onErrorResults = append(cfg.onErrorResults, res)
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseOnErrorCmds splits a semicolon-separated list of commands.
+func parseOnErrorCmds(v string) []string {
+	var cmds []string
+	for _, cmd := range strings.Split(v, ";") {
+		if cmd = strings.TrimSpace(cmd); cmd != "" {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+// writeOnErrorMetadata writes the results of the commands run on error to the
+// metadata file of the failed build, with the ref of its build record if
+// known. Nothing else is written to the metadata file when the build fails,
+// so the file doesn't hold the metadata of a previous build.
+func writeOnErrorMetadata(filename string, formats []string, buildRef string, results []debugCommandResult) error {
+	metadataFormats, err := parseMetadataFormats(formats)
+	if err != nil {
+		return err
+	}
+	dt := map[string]any{
+		"buildx.debug.output": results,
+	}
+	if buildRef != "" {
+		dt["buildx.build.ref"] = buildRef
+	}
+	return writeMetadataFiles(filename, metadataFormats, dt)
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
 func (cfg *invokeConfig) parseInvokeConfig(invoke, on string) error {
 	cfg.onFlag = on
 	cfg.invokeFlag = invoke
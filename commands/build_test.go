@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/buildx/controller/control"
+	controllerapi "github.com/docker/buildx/controller/pb"
+	"github.com/docker/buildx/util/progress"
+	"github.com/moby/buildkit/util/progress/progressui"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOnErrorCmds(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected []string
+	}{
+		{in: "", expected: nil},
+		{in: "ls -l /tmp", expected: []string{"ls -l /tmp"}},
+		{in: "cat /etc/os-release; env ;; ls", expected: []string{"cat /etc/os-release", "env", "ls"}},
+		{in: " ; ", expected: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			require.Equal(t, tt.expected, parseOnErrorCmds(tt.in))
+		})
+	}
+}
+
+type onErrorController struct {
+	control.BuildxController
+	invoked []*controllerapi.InvokeConfig
+}
+
+func (c *onErrorController) Invoke(ctx context.Context, ref, pid string, options *controllerapi.InvokeConfig, ioIn io.ReadCloser, ioOut io.WriteCloser, ioErr io.WriteCloser) error {
+	c.invoked = append(c.invoked, options)
+	if options.Cmd[0] == "false" {
+		io.WriteString(ioErr, "exit status 1\n")
+		return errors.New("process exited with code 1")
+	}
+	io.WriteString(ioOut, "output of "+options.Cmd[0]+"\n")
+	return nil
+}
+
+func TestRunOnError(t *testing.T) {
+	printer, err := progress.NewPrinter(context.TODO(), os.Stderr, progressui.QuietMode)
+	require.NoError(t, err)
+
+	c := &onErrorController{}
+	cfg := &invokeConfig{
+		InvokeConfig: controllerapi.InvokeConfig{Env: []string{"FOO=bar"}},
+		onErrorCmds:  []string{"false", "ls"},
+	}
+	require.NoError(t, cfg.runOnError(context.TODO(), "ref", c, io.Discard, io.Discard, printer))
+
+	require.Len(t, c.invoked, 2)
+	require.Equal(t, []string{"/bin/sh", "-c"}, c.invoked[0].Entrypoint)
+	require.Equal(t, []string{"FOO=bar"}, c.invoked[0].Env)
+	require.Equal(t, []debugCommandResult{
+		{Command: "false", Output: "exit status 1\n", Error: "process exited with code 1"},
+		{Command: "ls", Output: "output of ls\n"},
+	}, cfg.onErrorResults)
+}
+
+func TestWriteOnErrorMetadata(t *testing.T) {
+	results := []debugCommandResult{{Command: "ls", Output: "Dockerfile\n"}}
+
+	fn := filepath.Join(t.TempDir(), "metadata.json")
+	require.NoError(t, writeOnErrorMetadata(fn, nil, "", results))
+	dt, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"buildx.debug.output":[{"command":"ls","output":"Dockerfile\n"}]}`, string(dt))
+
+	// the metadata of a previous build is not reported as the one of the
+	// failed build
+	require.NoError(t, os.WriteFile(fn, []byte(`{"containerimage.digest":"sha256:ee0ee3b2a1bf0b5b1d0e4a1b1e9eb5d4a7f8c8e9f0a1b2c3d4e5f6a7b8c9d0e1"}`), 0644))
+	require.NoError(t, writeOnErrorMetadata(fn, nil, "default/default/k2xgp6o5xdbmjz5q8dwqmtj2a", results))
+	dt, err = os.ReadFile(fn)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"buildx.build.ref":"default/default/k2xgp6o5xdbmjz5q8dwqmtj2a","buildx.debug.output":[{"command":"ls","output":"Dockerfile\n"}]}`, string(dt))
+
+	// the sections of --metadata-format are honored
+	require.NoError(t, writeOnErrorMetadata(fn, []string{"digests", "debug"}, "default/default/k2xgp6o5xdbmjz5q8dwqmtj2a", results))
+	dt, err = os.ReadFile(filepath.Join(filepath.Dir(fn), "metadata.digests.json"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{}`, string(dt))
+	dt, err = os.ReadFile(filepath.Join(filepath.Dir(fn), "metadata.debug.json"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"buildx.debug.output":[{"command":"ls","output":"Dockerfile\n"}]}`, string(dt))
+}
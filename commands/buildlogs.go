@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/build"
+	"github.com/docker/buildx/builder"
+	controllerapi "github.com/docker/buildx/controller/pb"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/docker/buildx/util/progress"
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// buildLogsArtifactType is the artifact type of the build logs attached to
+// pushed images.
+const buildLogsArtifactType = "application/vnd.docker.buildx.build-logs.v1"
+
+type attachLogsOptions struct {
+	dockerfile bool
+}
+
+// parseAttachLogs parses the comma-separated list of files to attach to the
+// pushed image. The build log is always attached.
+func parseAttachLogs(in string) (*attachLogsOptions, error) {
+	if in == "" {
+		return nil, nil
+	}
+	opts := &attachLogsOptions{}
+	for _, v := range strings.Split(in, ",") {
+		switch strings.TrimSpace(v) {
+		case "log":
+		case "dockerfile":
+			opts.dockerfile = true
+		default:
+			return nil, errors.Errorf("invalid --attach-logs value %q, expecting log or dockerfile", v)
+		}
+	}
+	return opts, nil
+}
+
+// pushExports returns whether one of the exports pushes an image.
+func pushExports(exports []*controllerapi.ExportEntry) bool {
+	for _, e := range exports {
+		if e.Type == "registry" {
+			return true
+		}
+		if push, _ := strconv.ParseBool(e.Attrs["push"]); push && e.Type == client.ExporterImage {
+			return true
+		}
+	}
+	return false
+}
+
+// attachBuildLogs pushes the log of the build, and the Dockerfile if
+// requested, as an artifact referring to the pushed image in each of its
+// repositories.
+func attachBuildLogs(ctx context.Context, b *builder.Builder, printer *progress.Printer, exporterResponse map[string]string, inp *build.Inputs, opts *attachLogsOptions) (ocispecs.Descriptor, error) {
+	dgst := exporterResponse[exptypes.ExporterImageDigestKey]
+	names := exporterResponse["image.name"]
+	if dgst == "" || names == "" {
+		return ocispecs.Descriptor{}, errors.New("no pushed image to attach the build logs to")
+	}
+
+	log, err := printer.Log(ctx)
+	if err != nil {
+		return ocispecs.Descriptor{}, errors.Wrap(err, "failed to render build log")
+	}
+	blobs := []imagetools.ArtifactBlob{{
+		MediaType: "text/plain",
+		Title:     "build.log",
+		Data:      log,
+	}}
+	if opts.dockerfile {
+		var dt []byte
+		if inp != nil {
+			dt = build.LocalDockerfile(*inp)
+		}
+		if dt != nil {
+			blobs = append(blobs, imagetools.ArtifactBlob{
+				MediaType: "text/plain",
+				Title:     "Dockerfile",
+				Data:      dt,
+			})
+		} else {
+			logrus.Warn("Dockerfile is not available locally and is not attached to the build logs")
+		}
+	}
+	annotations := map[string]string{
+		ocispecs.AnnotationCreated: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	imageopt, err := b.ImageOpt()
+	if err != nil {
+		return ocispecs.Descriptor{}, err
+	}
+	r := imagetools.New(imageopt)
+
+	var desc ocispecs.Descriptor
+	seen := map[string]struct{}{}
+	for _, name := range strings.Split(names, ",") {
+		ref, err := reference.ParseNormalizedNamed(name)
+		if err != nil {
+			return ocispecs.Descriptor{}, err
+		}
+		repo := reference.TrimNamed(ref)
+		if _, ok := seen[repo.String()]; ok {
+			continue
+		}
+		seen[repo.String()] = struct{}{}
+
+		_, subject, err := r.Resolve(ctx, repo.String()+"@"+dgst)
+		if err != nil {
+			return ocispecs.Descriptor{}, errors.Wrapf(err, "failed to resolve pushed image %s", repo)
+		}
+		desc, err = r.Attach(ctx, repo, subject, buildLogsArtifactType, blobs, annotations)
+		if err != nil {
+			return ocispecs.Descriptor{}, errors.Wrapf(err, "failed to attach build logs to %s", repo)
+		}
+	}
+	return desc, nil
+}
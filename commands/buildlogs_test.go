@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"testing"
+
+	controllerapi "github.com/docker/buildx/controller/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAttachLogs(t *testing.T) {
+	opts, err := parseAttachLogs("")
+	require.NoError(t, err)
+	require.Nil(t, opts)
+
+	opts, err = parseAttachLogs("log")
+	require.NoError(t, err)
+	require.Equal(t, &attachLogsOptions{}, opts)
+
+	opts, err = parseAttachLogs("log,dockerfile")
+	require.NoError(t, err)
+	require.Equal(t, &attachLogsOptions{dockerfile: true}, opts)
+
+	_, err = parseAttachLogs("log,sbom")
+	require.ErrorContains(t, err, `invalid --attach-logs value "sbom"`)
+}
+
+func TestPushExports(t *testing.T) {
+	require.False(t, pushExports(nil))
+	require.False(t, pushExports([]*controllerapi.ExportEntry{{Type: "image"}, {Type: "local", Attrs: map[string]string{"push": "true"}}}))
+	require.True(t, pushExports([]*controllerapi.ExportEntry{{Type: "registry"}}))
+	require.True(t, pushExports([]*controllerapi.ExportEntry{{Type: "image", Attrs: map[string]string{"push": "true"}}}))
+}
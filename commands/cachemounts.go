@@ -0,0 +1,315 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/go-units"
+	"github.com/moby/buildkit/client"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// cacheMountFilter selects the cache records of RUN --mount=type=cache
+// mounts.
+const cacheMountFilter = "type==" + string(client.UsageRecordTypeCacheMount)
+
+// cacheMount is a named cache mount on a node. A cache mount can be backed
+// by several records, e.g. when it's used with the private sharing mode.
+type cacheMount struct {
+	Node       string
+	ID         string
+	Target     string
+	Size       int64
+	InUse      bool
+	LastUsedAt *time.Time
+	records    []string
+}
+
+// parseCacheMountDescription returns the id and the target of a cache mount
+// from the description of its record, which has the form
+// `cached mount <target> from <name>[ with id "<id>"]`. The id defaults to
+// the target.
+func parseCacheMountDescription(desc string) (id string, target string) {
+	rest, ok := strings.CutPrefix(desc, "cached mount ")
+	if !ok {
+		return desc, ""
+	}
+	if i := strings.LastIndex(rest, " with id "); i >= 0 {
+		if v, err := strconv.Unquote(rest[i+len(" with id "):]); err == nil {
+			id = v
+		}
+		rest = rest[:i]
+	}
+	if i := strings.LastIndex(rest, " from "); i >= 0 {
+		rest = rest[:i]
+	}
+	target = rest
+	if id == "" {
+		id = target
+	}
+	return id, target
+}
+
+// toCacheMounts groups the cache mount records of a node by id, sorted by
+// id.
+func toCacheMounts(node string, du []*client.UsageInfo) []*cacheMount {
+	var res []*cacheMount
+	m := map[string]*cacheMount{}
+	for _, di := range du {
+		if di.RecordType != client.UsageRecordTypeCacheMount {
+			continue
+		}
+		id, target := parseCacheMountDescription(di.Description)
+		cm, ok := m[id]
+		if !ok {
+			cm = &cacheMount{Node: node, ID: id, Target: target}
+			m[id] = cm
+			res = append(res, cm)
+		}
+		cm.Size += di.Size
+		cm.InUse = cm.InUse || di.InUse
+		if di.LastUsedAt != nil && (cm.LastUsedAt == nil || di.LastUsedAt.After(*cm.LastUsedAt)) {
+			cm.LastUsedAt = di.LastUsedAt
+		}
+		cm.records = append(cm.records, di.ID)
+	}
+	slices.SortFunc(res, func(a, b *cacheMount) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return res
+}
+
+func loadCacheMounts(ctx context.Context, nodes []builder.Node) ([][]*cacheMount, error) {
+	out := make([][]*cacheMount, len(nodes))
+	eg, ctx := errgroup.WithContext(ctx)
+	for i, node := range nodes {
+		func(i int, node builder.Node) {
+			eg.Go(func() error {
+				if node.Driver == nil {
+					return nil
+				}
+				c, err := node.Driver.Client(ctx)
+				if err != nil {
+					return err
+				}
+				du, err := c.DiskUsage(ctx, client.WithFilter([]string{cacheMountFilter}))
+				if err != nil {
+					return err
+				}
+				out[i] = toCacheMounts(node.Name, du)
+				return nil
+			})
+		}(i, node)
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func loadBuilderNodes(ctx context.Context, dockerCli command.Cli, name string) ([]builder.Node, error) {
+	b, err := builder.New(dockerCli, builder.WithName(name))
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := b.LoadNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		if node.Err != nil {
+			return nil, node.Err
+		}
+	}
+	return nodes, nil
+}
+
+type cacheMountsLsOptions struct {
+	builder string
+}
+
+func runCacheMountsLs(ctx context.Context, dockerCli command.Cli, opts cacheMountsLsOptions) error {
+	nodes, err := loadBuilderNodes(ctx, dockerCli, opts.builder)
+	if err != nil {
+		return err
+	}
+	out, err := loadCacheMounts(ctx, nodes)
+	if err != nil {
+		return err
+	}
+	printCacheMounts(dockerCli.Out(), out, len(nodes) > 1)
+	return nil
+}
+
+func printCacheMounts(w io.Writer, out [][]*cacheMount, withNode bool) {
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	defer tw.Flush()
+
+	header := "ID\tTARGET\tSIZE\tIN USE\tLAST USED"
+	if withNode {
+		header = "NODE\t" + header
+	}
+	fmt.Fprintln(tw, header)
+	for _, cms := range out {
+		for _, cm := range cms {
+			lastUsed := ""
+			if cm.LastUsedAt != nil {
+				lastUsed = units.HumanDuration(time.Since(*cm.LastUsedAt)) + " ago"
+			}
+			row := fmt.Sprintf("%s\t%s\t%s\t%v\t%s", cm.ID, cm.Target, units.HumanSize(float64(cm.Size)), cm.InUse, lastUsed)
+			if withNode {
+				row = cm.Node + "\t" + row
+			}
+			fmt.Fprintln(tw, row)
+		}
+	}
+}
+
+type cacheMountsRmOptions struct {
+	builder string
+	force   bool
+}
+
+func runCacheMountsRm(ctx context.Context, dockerCli command.Cli, ids []string, opts cacheMountsRmOptions) error {
+	if !opts.force {
+		msg := fmt.Sprintf("WARNING! This will remove the cache mounts %s. Are you sure you want to continue?", strings.Join(ids, ", "))
+		if ok, err := prompt(ctx, dockerCli.In(), dockerCli.Out(), msg); err != nil {
+			return err
+		} else if !ok {
+			return nil
+		}
+	}
+
+	nodes, err := loadBuilderNodes(ctx, dockerCli, opts.builder)
+	if err != nil {
+		return err
+	}
+	out, err := loadCacheMounts(ctx, nodes)
+	if err != nil {
+		return err
+	}
+
+	found := map[string]struct{}{}
+	filters := make([][]string, len(nodes))
+	for i, cms := range out {
+		for _, cm := range cms {
+			if !slices.Contains(ids, cm.ID) {
+				continue
+			}
+			if cm.InUse {
+				return errors.Errorf("cache mount %q is in use on node %s", cm.ID, cm.Node)
+			}
+			found[cm.ID] = struct{}{}
+			for _, id := range cm.records {
+				filters[i] = append(filters[i], "id=="+id)
+			}
+		}
+	}
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			return errors.Errorf("cache mount %q not found", id)
+		}
+	}
+
+	var total int64
+	ch := make(chan client.UsageInfo)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for du := range ch {
+			total += du.Size
+		}
+	}()
+
+	eg, ctx := errgroup.WithContext(ctx)
+	for i, node := range nodes {
+		if len(filters[i]) == 0 || node.Driver == nil {
+			continue
+		}
+		func(node builder.Node, filter []string) {
+			eg.Go(func() error {
+				c, err := node.Driver.Client(ctx)
+				if err != nil {
+					return err
+				}
+				return c.Prune(ctx, ch, client.PruneAll, client.WithFilter(filter))
+			})
+		}(node, filters[i])
+	}
+	err = eg.Wait()
+	close(ch)
+	<-done
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		fmt.Fprintln(dockerCli.Out(), id)
+	}
+	fmt.Fprintf(dockerCli.Err(), "Total reclaimed space: %s\n", units.HumanSize(float64(total)))
+	return nil
+}
+
+func cacheMountsCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "cache-mounts",
+		Short:             "Manage the cache mounts of a builder",
+		ValidArgsFunction: completion.Disable,
+	}
+
+	cmd.AddCommand(
+		cacheMountsLsCmd(dockerCli, rootOpts),
+		cacheMountsRmCmd(dockerCli, rootOpts),
+	)
+
+	return cmd
+}
+
+func cacheMountsLsCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options cacheMountsLsOptions
+
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List cache mounts",
+		Args:  cli.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			return runCacheMountsLs(cmd.Context(), dockerCli, options)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	return cmd
+}
+
+func cacheMountsRmCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options cacheMountsRmOptions
+
+	cmd := &cobra.Command{
+		Use:   "rm [OPTIONS] ID [ID...]",
+		Short: "Remove cache mounts",
+		Args:  cli.RequiresMinArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			return runCacheMountsRm(cmd.Context(), dockerCli, args, options)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVarP(&options.force, "force", "f", false, "Do not prompt for confirmation")
+
+	return cmd
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCacheMountDescription(t *testing.T) {
+	tests := []struct {
+		desc   string
+		id     string
+		target string
+	}{
+		{
+			desc:   "cached mount /root/.cache/go-build from exec /bin/sh -c go build ./...",
+			id:     "/root/.cache/go-build",
+			target: "/root/.cache/go-build",
+		},
+		{
+			desc:   `cached mount /go/pkg/mod from exec /bin/sh -c go mod download with id "gomod"`,
+			id:     "gomod",
+			target: "/go/pkg/mod",
+		},
+		{
+			desc: "local source for context",
+			id:   "local source for context",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			id, target := parseCacheMountDescription(tt.desc)
+			require.Equal(t, tt.id, id)
+			require.Equal(t, tt.target, target)
+		})
+	}
+}
+
+func TestToCacheMounts(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	du := []*client.UsageInfo{
+		{ID: "a", Size: 10, RecordType: client.UsageRecordTypeCacheMount, Description: `cached mount /go/pkg/mod from exec go mod download with id "gomod"`, LastUsedAt: &earlier},
+		{ID: "b", Size: 20, RecordType: client.UsageRecordTypeCacheMount, Description: `cached mount /go/pkg/mod from exec go build with id "gomod"`, LastUsedAt: &now, InUse: true},
+		{ID: "c", Size: 5, RecordType: client.UsageRecordTypeCacheMount, Description: "cached mount /root/.npm from exec npm ci"},
+		{ID: "d", Size: 100, RecordType: client.UsageRecordTypeRegular},
+	}
+	cms := toCacheMounts("node0", du)
+	require.Len(t, cms, 2)
+
+	require.Equal(t, "/root/.npm", cms[0].ID)
+	require.Equal(t, int64(5), cms[0].Size)
+
+	require.Equal(t, "gomod", cms[1].ID)
+	require.Equal(t, "/go/pkg/mod", cms[1].Target)
+	require.Equal(t, int64(30), cms[1].Size)
+	require.True(t, cms[1].InUse)
+	require.Equal(t, &now, cms[1].LastUsedAt)
+	require.Equal(t, []string{"a", "b"}, cms[1].records)
+
+	var b bytes.Buffer
+	printCacheMounts(&b, [][]*cacheMount{cms}, false)
+	lines := strings.Split(b.String(), "\n")
+	require.Len(t, lines, 4)
+	require.Equal(t, []string{"ID", "TARGET", "SIZE", "IN", "USE", "LAST", "USED"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{"/root/.npm", "/root/.npm", "5B", "false"}, strings.Fields(lines[1]))
+	require.Equal(t, []string{"gomod", "/go/pkg/mod", "30B", "true"}, strings.Fields(lines[2])[:4])
+}
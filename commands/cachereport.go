@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"strings"
+
+	controllerapi "github.com/docker/buildx/controller/pb"
+	"github.com/docker/buildx/util/progress"
+)
+
+// cacheSourceAttrs are the attributes identifying a cache source. The other
+// attributes, such as credentials, are not written to the cache report.
+var cacheSourceAttrs = []string{"ref", "scope", "src", "name", "bucket", "prefix", "container"}
+
+// cacheReport is the cache report of a build written with --cache-report.
+type cacheReport struct {
+	Hits    int                 `json:"hits"`
+	Misses  int                 `json:"misses"`
+	Sources []cacheReportSource `json:"sources,omitempty"`
+	Steps   []cacheReportStep   `json:"steps"`
+}
+
+type cacheReportSource struct {
+	Type  string            `json:"type"`
+	Attrs map[string]string `json:"attrs,omitempty"`
+	// Imported is true if the cache manifest of the source was imported.
+	Imported bool `json:"imported"`
+}
+
+type cacheReportStep struct {
+	progress.CacheStep
+	// Source is the cache that satisfied a cache hit, if known.
+	Source string `json:"source,omitempty"`
+}
+
+// newCacheReport returns the cache report of a build from the cache status of
+// its steps and the cache sources it imports.
+func newCacheReport(r progress.CacheReport, cacheFrom []*controllerapi.CacheOptionsEntry) cacheReport {
+	report := cacheReport{
+		Steps: []cacheReportStep{},
+	}
+	for _, e := range cacheFrom {
+		src := cacheReportSource{
+			Type:  e.Type,
+			Attrs: map[string]string{},
+		}
+		for _, k := range cacheSourceAttrs {
+			if v, ok := e.Attrs[k]; ok && v != "" {
+				src.Attrs[k] = v
+				for _, imp := range r.Imports {
+					if strings.Contains(imp, v) {
+						src.Imported = true
+					}
+				}
+			}
+		}
+		report.Sources = append(report.Sources, src)
+	}
+	// BuildKit doesn't report which cache satisfied a cache hit, it's only
+	// known when no cache source was imported
+	var source string
+	if len(r.Imports) == 0 {
+		source = "local"
+	}
+	for _, s := range r.Steps {
+		step := cacheReportStep{CacheStep: s}
+		if s.Cached {
+			report.Hits++
+			step.Source = source
+		} else {
+			report.Misses++
+		}
+		report.Steps = append(report.Steps, step)
+	}
+	return report
+}
@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"testing"
+
+	controllerapi "github.com/docker/buildx/controller/pb"
+	"github.com/docker/buildx/util/progress"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCacheReport(t *testing.T) {
+	steps := []progress.CacheStep{
+		{Name: "[1/2] FROM docker.io/library/alpine", Cached: true},
+		{Name: "[2/2] RUN make", Reason: progress.CacheMissNoMatch},
+	}
+	cacheFrom := []*controllerapi.CacheOptionsEntry{
+		{Type: "registry", Attrs: map[string]string{"ref": "docker.io/org/app:cache"}},
+		{Type: "gha", Attrs: map[string]string{"scope": "main", "token": "s3cr3t"}},
+	}
+
+	report := newCacheReport(progress.CacheReport{Steps: steps, Imports: []string{"docker.io/org/app:cache"}}, cacheFrom)
+	require.Equal(t, 1, report.Hits)
+	require.Equal(t, 1, report.Misses)
+	require.Len(t, report.Sources, 2)
+	require.True(t, report.Sources[0].Imported)
+	require.False(t, report.Sources[1].Imported)
+	require.Equal(t, map[string]string{"scope": "main"}, report.Sources[1].Attrs)
+	require.Empty(t, report.Steps[0].Source)
+
+	report = newCacheReport(progress.CacheReport{Steps: steps}, nil)
+	require.Equal(t, "local", report.Steps[0].Source)
+	require.Empty(t, report.Steps[1].Source)
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/docker/buildx/controller"
+	"github.com/docker/buildx/controller/control"
+	"github.com/docker/buildx/util/cobrautil"
+	"github.com/docker/buildx/util/progress"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/moby/buildkit/util/progress/progressui"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type cancelOptions struct {
+	ref         string
+	reason      string
+	gracePeriod time.Duration
+	root        string
+}
+
+func runCancel(ctx context.Context, dockerCli command.Cli, in cancelOptions) error {
+	if in.gracePeriod < 0 {
+		return errors.Errorf("invalid grace period %s", in.gracePeriod)
+	}
+	printer, err := progress.NewPrinter(ctx, os.Stderr, progressui.QuietMode)
+	if err != nil {
+		return err
+	}
+	c, err := controller.NewController(ctx, control.ControlOptions{
+		Root:   in.root,
+		Detach: true,
+	}, dockerCli, printer)
+	if err := printer.Wait(); err != nil {
+		logrus.Debugf("failed to wait for progress printer: %v", err)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := c.Close(); err != nil {
+			logrus.Warnf("failed to close server connection %v", err)
+		}
+	}()
+	return c.Cancel(ctx, in.ref, in.reason, in.gracePeriod)
+}
+
+func cancelCmd(dockerCli command.Cli) *cobra.Command {
+	var options cancelOptions
+
+	cmd := &cobra.Command{
+		Use:   "cancel REF",
+		Short: "Cancel a build in progress",
+		Args:  cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.ref = args[0]
+			return runCancel(cmd.Context(), dockerCli, options)
+		},
+	}
+	cobrautil.MarkCommandExperimental(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&options.reason, "reason", "", "Reason of the cancellation returned as the error of the build")
+	flags.DurationVar(&options.gracePeriod, "grace-period", 10*time.Second, "Time given to the cache exports in progress to complete before canceling")
+	flags.StringVar(&options.root, "root", "", "Specify root directory of server to connect")
+
+	return cmd
+}
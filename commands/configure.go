@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/store/storeutil"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/spf13/cobra"
+)
+
+type configureOptions struct {
+	builder             string
+	buildkitdFlags      *string
+	buildkitdConfigFile string
+	noRestart           bool
+	force               bool
+}
+
+func runConfigure(ctx context.Context, dockerCli command.Cli, in configureOptions) error {
+	b, err := configureBuilder(dockerCli, in)
+	if err != nil {
+		return err
+	}
+
+	if in.noRestart {
+		fmt.Fprintf(dockerCli.Err(), "Configuration of %s updated, restart its nodes to apply it\n", b.Name)
+		return nil
+	}
+
+	// load the builder again to use the new configuration
+	b, err = builder.New(dockerCli,
+		builder.WithName(b.Name),
+		builder.WithSkippedValidation(),
+	)
+	if err != nil {
+		return err
+	}
+	if b.Driver == "kubernetes" {
+		fmt.Fprintf(dockerCli.Err(), "WARNING: restarting the nodes of %s removes their build cache\n", b.Name)
+	}
+	if err := b.Restart(ctx, in.force); err != nil {
+		return err
+	}
+	fmt.Fprintln(dockerCli.Out(), b.Name)
+	return nil
+}
+
+// configureBuilder updates the configuration of the builder in the store and
+// returns it. The store lock is released before the nodes are restarted.
+func configureBuilder(dockerCli command.Cli, in configureOptions) (*builder.Builder, error) {
+	txn, release, err := storeutil.GetStore(dockerCli)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	b, err := builder.New(dockerCli,
+		builder.WithName(in.builder),
+		builder.WithStore(txn),
+		builder.WithSkippedValidation(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := builder.Configure(txn, builder.ConfigureOpts{
+		Name:                b.Name,
+		BuildkitdFlags:      in.buildkitdFlags,
+		BuildkitdConfigFile: in.buildkitdConfigFile,
+	}); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func configureCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options configureOptions
+	var buildkitdFlags string
+
+	cmd := &cobra.Command{
+		Use:   "configure [OPTIONS] [NAME]",
+		Short: "Update the BuildKit daemon configuration of a builder instance",
+		Args:  cli.RequiresMaxArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			if len(args) > 0 {
+				options.builder = args[0]
+			}
+			if cmd.Flags().Changed("buildkitd-flags") {
+				options.buildkitdFlags = &buildkitdFlags
+			}
+			return runConfigure(cmd.Context(), dockerCli, options)
+		},
+		ValidArgsFunction: completion.BuilderNames(dockerCli),
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&buildkitdFlags, "buildkitd-flags", "", "BuildKit daemon flags")
+	flags.StringVar(&options.buildkitdConfigFile, "buildkitd-config", "", "BuildKit daemon config file")
+	flags.BoolVar(&options.noRestart, "no-restart", false, "Update the configuration without restarting the BuildKit daemon")
+	flags.BoolVarP(&options.force, "force", "f", false, "Restart even if builds are in progress")
+
+	return cmd
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 
+	"github.com/docker/buildx/bake"
 	"github.com/docker/buildx/builder"
 	"github.com/docker/buildx/driver"
 	"github.com/docker/buildx/store/storeutil"
@@ -12,6 +14,7 @@ import (
 	"github.com/docker/buildx/util/cobrautil/completion"
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -26,6 +29,13 @@ type createOptions struct {
 	driverOpts          []string
 	buildkitdFlags      string
 	buildkitdConfigFile string
+	registryMirrors     []string
+	insecureRegistries  []string
+	gcPolicy            []string
+	defaultShmSize      string
+	defaultUlimits      []string
+	maxConcurrentBuilds *int
+	fromFile            string
 	bootstrap           bool
 	// upgrade      bool // perform upgrade of the driver
 }
@@ -45,6 +55,12 @@ func runCreate(ctx context.Context, dockerCli command.Cli, in createOptions, arg
 		})
 	}
 
+	if in.fromFile != "" {
+		if err := loadCreateOptionsFromFile(&in); err != nil {
+			return err
+		}
+	}
+
 	var ep string
 	if len(args) > 0 {
 		ep = args[0]
@@ -58,9 +74,15 @@ func runCreate(ctx context.Context, dockerCli command.Cli, in createOptions, arg
 		DriverOpts:          in.driverOpts,
 		BuildkitdFlags:      in.buildkitdFlags,
 		BuildkitdConfigFile: in.buildkitdConfigFile,
+		RegistryMirrors:     in.registryMirrors,
+		InsecureRegistries:  in.insecureRegistries,
+		GCPolicy:            in.gcPolicy,
 		Use:                 in.use,
 		Endpoint:            ep,
 		Append:              in.actionAppend,
+		DefaultShmSize:      in.defaultShmSize,
+		DefaultUlimits:      in.defaultUlimits,
+		MaxConcurrentBuilds: in.maxConcurrentBuilds,
 	})
 	if err != nil {
 		return err
@@ -80,8 +102,43 @@ func runCreate(ctx context.Context, dockerCli command.Cli, in createOptions, arg
 	return nil
 }
 
+// loadCreateOptionsFromFile sets the options not specified on the command line
+// from the builder described by the x-builder extension of a Compose file.
+func loadCreateOptionsFromFile(in *createOptions) error {
+	dt, err := os.ReadFile(in.fromFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", in.fromFile)
+	}
+	b, err := bake.ParseComposeBuilder([]bake.File{{Name: in.fromFile, Data: dt}})
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s", in.fromFile)
+	}
+	if b == nil {
+		return errors.Errorf("no x-builder extension found in %s", in.fromFile)
+	}
+	if in.name == "" {
+		in.name = b.Name
+	}
+	if in.driver == "" {
+		in.driver = b.Driver
+	}
+	if len(in.platform) == 0 {
+		in.platform = b.Platforms
+	}
+	// driver options set on the command line take precedence
+	in.driverOpts = append(append([]string{}, b.DriverOpts...), in.driverOpts...)
+	if in.buildkitdFlags == "" {
+		in.buildkitdFlags = b.BuildkitdFlags
+	}
+	if in.buildkitdConfigFile == "" {
+		in.buildkitdConfigFile = b.BuildkitdConfig
+	}
+	return nil
+}
+
 func createCmd(dockerCli command.Cli) *cobra.Command {
 	var options createOptions
+	var maxConcurrentBuilds int
 
 	var drivers bytes.Buffer
 	for _, d := range driver.GetFactories(true) {
@@ -96,6 +153,9 @@ func createCmd(dockerCli command.Cli) *cobra.Command {
 		Short: "Create a new builder instance",
 		Args:  cli.RequiresMaxArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("max-concurrent-builds") {
+				options.maxConcurrentBuilds = &maxConcurrentBuilds
+			}
 			return runCreate(cmd.Context(), dockerCli, options, args)
 		},
 		ValidArgsFunction: completion.Disable,
@@ -115,6 +175,16 @@ func createCmd(dockerCli command.Cli) *cobra.Command {
 	flags.StringVar(&options.buildkitdConfigFile, "config", "", "BuildKit daemon config file")
 	flags.MarkHidden("config")
 
+	flags.StringArrayVar(&options.registryMirrors, "registry-mirror", []string{}, `Registry mirror for the BuildKit daemon (format: "[registry=]mirror")`)
+	flags.StringArrayVar(&options.insecureRegistries, "insecure-registry", []string{}, `Registry the BuildKit daemon can access without TLS verification (format: "host[:port]")`)
+	flags.StringArrayVar(&options.gcPolicy, "gc-policy", []string{}, `Garbage collection policy for the BuildKit daemon (e.g., "keep-storage=20GB,keep-duration=168h")`)
+
+	flags.StringVar(&options.defaultShmSize, "default-shm-size", "", `Default shared memory size for builds (format: "<number>[<unit>]")`)
+	flags.StringArrayVar(&options.defaultUlimits, "default-ulimit", []string{}, `Default ulimit for builds (format: "type=soft:hard")`)
+	flags.IntVar(&maxConcurrentBuilds, "max-concurrent-builds", 0, "Maximum number of builds running at the same time on the builder, others wait (0 for no limit)")
+
+	flags.StringVar(&options.fromFile, "from-file", "", "Create the builder described by the x-builder extension of a Compose file")
+
 	flags.BoolVar(&options.bootstrap, "bootstrap", false, "Boot builder after creation")
 	flags.BoolVar(&options.actionAppend, "append", false, "Append a node to builder instead of changing it")
 	flags.BoolVar(&options.actionLeave, "leave", false, "Remove a node from builder instead of changing it")
@@ -123,5 +193,9 @@ func createCmd(dockerCli command.Cli) *cobra.Command {
 	// hide builder persistent flag for this command
 	cobrautil.HideInheritedFlags(cmd, "builder")
 
+	cmd.RegisterFlagCompletionFunc( //nolint:errcheck
+		"platform",
+		completion.Platforms,
+	)
 	return cmd
 }
@@ -26,6 +26,9 @@ type DebugConfig struct {
 
 	// OnFlag is a flag to configure the timing of launching the debugger.
 	OnFlag string
+
+	// OnErrorFlag is a flag to configure the commands non-interactively executed on the debugger when the build fails.
+	OnErrorFlag string
 }
 
 // DebuggableCmd is a command that supports debugger with recognizing the user-specified DebugConfig.
@@ -76,13 +79,14 @@ func RootCmd(dockerCli command.Cli, children ...DebuggableCmd) *cobra.Command {
 	flags := cmd.Flags()
 	flags.StringVar(&options.InvokeFlag, "invoke", "", "Launch a monitor with executing specified command")
 	flags.StringVar(&options.OnFlag, "on", "error", "When to launch the monitor ([always, error])")
+	flags.StringVar(&options.OnErrorFlag, "on-error", "", `Commands to run non-interactively when the build fails (e.g., "cat /etc/resolv.conf; env")`)
 
 	flags.StringVar(&controlOptions.Root, "root", "", "Specify root directory of server to connect for the monitor")
 	flags.BoolVar(&controlOptions.Detach, "detach", runtime.GOOS == "linux", "Detach buildx server for the monitor (supported only on linux)")
 	flags.StringVar(&controlOptions.ServerConfig, "server-config", "", "Specify buildx server config file for the monitor (used only when launching new server)")
 	flags.StringVar(&progressMode, "progress", "auto", `Set type of progress output ("auto", "plain", "tty", "rawjson") for the monitor. Use plain to show container output`)
 
-	cobrautil.MarkFlagsExperimental(flags, "invoke", "on", "root", "detach", "server-config")
+	cobrautil.MarkFlagsExperimental(flags, "invoke", "on", "on-error", "root", "detach", "server-config")
 
 	for _, c := range children {
 		cmd.AddCommand(c.NewDebugger(&options))
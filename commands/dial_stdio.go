@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"fmt"
 	"io"
 	"net"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/containerd/platforms"
 	"github.com/docker/buildx/build"
@@ -19,9 +22,11 @@ import (
 )
 
 type stdioOptions struct {
-	builder  string
-	platform string
-	progress string
+	builder   string
+	node      string
+	platform  string
+	progress  string
+	keepalive time.Duration
 }
 
 func runDialStdio(dockerCli command.Cli, opts stdioOptions) error {
@@ -43,6 +48,12 @@ func runDialStdio(dockerCli command.Cli, opts stdioOptions) error {
 	if err != nil {
 		return err
 	}
+	if opts.node != "" {
+		nodes, err = selectNode(nodes, opts.node)
+		if err != nil {
+			return err
+		}
+	}
 
 	printer, err := progress.NewPrinter(ctx, os.Stderr, progressui.DisplayMode(opts.progress), progress.WithPhase("dial-stdio"), progress.WithDesc("builder: "+b.Name, "builder:"+b.Name))
 	if err != nil {
@@ -76,6 +87,12 @@ func runDialStdio(dockerCli command.Cli, opts stdioOptions) error {
 
 		defer conn.Close()
 
+		if opts.keepalive > 0 {
+			if err := setKeepAlive(conn, opts.keepalive); err != nil {
+				sub.Log(2, []byte(fmt.Sprintf("keepalive not enabled: %v\n", err)))
+			}
+		}
+
 		go func() {
 			<-ctx.Done()
 			closeWrite(conn)
@@ -97,6 +114,36 @@ func runDialStdio(dockerCli command.Cli, opts stdioOptions) error {
 	})
 }
 
+// selectNode returns the node with the given name.
+func selectNode(nodes []builder.Node, name string) ([]builder.Node, error) {
+	names := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Name == name {
+			return []builder.Node{n}, nil
+		}
+		names = append(names, n.Name)
+	}
+	return nil, errors.Errorf("node %q not found, available nodes: %s", name, strings.Join(names, ", "))
+}
+
+// setKeepAlive enables TCP keep-alive probes on the connection to the
+// builder so idle sessions aren't dropped by intermediate proxies.
+func setKeepAlive(conn net.Conn, period time.Duration) error {
+	for {
+		switch c := conn.(type) {
+		case *net.TCPConn:
+			if err := c.SetKeepAlive(true); err != nil {
+				return err
+			}
+			return c.SetKeepAlivePeriod(period)
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return errors.Errorf("unsupported connection type %T", conn)
+		}
+	}
+}
+
 func closeRead(conn net.Conn) error {
 	if c, ok := conn.(interface{ CloseRead() error }); ok {
 		return c.CloseRead()
@@ -125,7 +172,9 @@ func dialStdioCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
 	}
 
 	flags := cmd.Flags()
+	flags.StringVar(&opts.node, "node", "", "Node of the builder to connect to")
 	flags.StringVar(&opts.platform, "platform", os.Getenv("DOCKER_DEFAULT_PLATFORM"), "Target platform: this is used for node selection")
+	flags.DurationVar(&opts.keepalive, "keepalive", 0, `Interval of TCP keep-alive probes sent to the builder (e.g., "30s")`)
 	flags.StringVar(&opts.progress, "progress", "quiet", `Set type of progress output ("auto", "plain", "tty", "rawjson"). Use plain to show container output`)
 	return cmd
 }
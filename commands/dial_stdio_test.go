@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectNode(t *testing.T) {
+	nodes := []builder.Node{
+		{Node: store.Node{Name: "node0"}},
+		{Node: store.Node{Name: "node1"}},
+	}
+
+	res, err := selectNode(nodes, "node1")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, "node1", res[0].Name)
+
+	_, err = selectNode(nodes, "node2")
+	require.ErrorContains(t, err, `node "node2" not found, available nodes: node0, node1`)
+}
+
+func TestSetKeepAlive(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, setKeepAlive(conn, 30*time.Second))
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	require.Error(t, setKeepAlive(c1, 30*time.Second))
+}
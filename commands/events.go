@@ -0,0 +1,428 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/driver"
+	"github.com/docker/buildx/store/storeutil"
+	"github.com/docker/buildx/util/cobrautil"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	eventTypeBuilder = "builder"
+	eventTypeBuild   = "build"
+
+	eventActionCreated      = "created"
+	eventActionRemoved      = "removed"
+	eventActionBootstrapped = "bootstrapped"
+	eventActionStopped      = "stopped"
+	eventActionStarted      = "started"
+	eventActionFinished     = "finished"
+	eventActionFailed       = "failed"
+)
+
+// eventsPollInterval is the interval at which the builders and the status of
+// their nodes are checked with --follow.
+const eventsPollInterval = 2 * time.Second
+
+type eventsOptions struct {
+	builder string
+	all     bool
+	follow  bool
+	format  string
+}
+
+// builderEvent is an event of the lifecycle of a builder or of a build.
+type builderEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Action  string    `json:"action"`
+	Builder string    `json:"builder"`
+	Node    string    `json:"node,omitempty"`
+	Ref     string    `json:"ref,omitempty"`
+	Target  string    `json:"target,omitempty"`
+	// Duration is the duration of a finished or failed build.
+	Duration time.Duration `json:"duration,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// nodeKey identifies a node of a builder.
+type nodeKey struct {
+	builder string
+	node    string
+}
+
+func runEvents(ctx context.Context, dockerCli command.Cli, in eventsOptions) error {
+	switch in.format {
+	case "", "pretty", "json":
+	default:
+		return errors.Errorf("unsupported format %q, use pretty or json", in.format)
+	}
+
+	var name string
+	if !in.all {
+		b, err := builder.New(dockerCli, builder.WithName(in.builder))
+		if err != nil {
+			return err
+		}
+		name = b.Name
+	}
+
+	w := &eventWriter{w: dockerCli.Out(), json: in.format == "json", seen: map[string]struct{}{}}
+	if !in.follow {
+		return printHistoryEvents(ctx, dockerCli, name, w)
+	}
+	return followEvents(ctx, dockerCli, name, w)
+}
+
+// loadEventBuilders returns the builders whose events are written, all of
+// them if name is empty.
+func loadEventBuilders(dockerCli command.Cli, name string) ([]*builder.Builder, error) {
+	txn, release, err := storeutil.GetStore(dockerCli)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	builders, err := builder.GetBuilders(dockerCli, txn)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return builders, nil
+	}
+	for _, b := range builders {
+		if b.Name == name {
+			return []*builder.Builder{b}, nil
+		}
+	}
+	return nil, nil
+}
+
+// loadNodeStatus returns whether each node of the builders is running, and
+// the running nodes.
+func loadNodeStatus(ctx context.Context, builders []*builder.Builder) (map[string]map[string]bool, map[nodeKey]builder.Node) {
+	status := map[string]map[string]bool{}
+	running := map[nodeKey]builder.Node{}
+	for _, b := range builders {
+		status[b.Name] = map[string]bool{}
+		nodes, err := b.LoadNodes(ctx)
+		if err != nil {
+			continue
+		}
+		for _, n := range nodes {
+			var ok bool
+			if n.Err == nil && n.Driver != nil {
+				if info, err := n.Driver.Info(ctx); err == nil && info.Status == driver.Running {
+					ok = true
+				}
+			}
+			status[b.Name][n.Name] = ok
+			if ok {
+				running[nodeKey{builder: b.Name, node: n.Name}] = n
+			}
+		}
+	}
+	return status, running
+}
+
+// printHistoryEvents writes the events of the builds recorded by the running
+// nodes of the builders, oldest first.
+func printHistoryEvents(ctx context.Context, dockerCli command.Cli, name string, w *eventWriter) error {
+	builders, err := loadEventBuilders(dockerCli, name)
+	if err != nil {
+		return err
+	}
+	_, running := loadNodeStatus(ctx, builders)
+
+	var events []builderEvent
+	for k, n := range running {
+		err := listenHistoryEvents(ctx, n, true, func(ev builderEvent) {
+			events = append(events, ev)
+		})
+		if err != nil {
+			fmt.Fprintf(dockerCli.Err(), "Cannot list builds of %s (%s): %v\n", k.builder, k.node, err)
+		}
+	}
+	sortBuilderEvents(events)
+	for _, ev := range events {
+		if err := w.write(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// followEvents writes the events of the builders and of their builds as they
+// happen, until the context is canceled.
+func followEvents(ctx context.Context, dockerCli command.Cli, name string, w *eventWriter) error {
+	since := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var prev map[string]map[string]bool
+	listeners := map[nodeKey]context.CancelFunc{}
+	ended := make(chan nodeKey)
+	errCh := make(chan error, 1)
+
+	emit := func(ev builderEvent) {
+		if ev.Time.Before(since) {
+			return
+		}
+		if err := w.write(ev); err != nil {
+			select {
+			case errCh <- err:
+			default:
+			}
+		}
+	}
+
+	ticker := time.NewTicker(eventsPollInterval)
+	defer ticker.Stop()
+	for {
+		builders, err := loadEventBuilders(dockerCli, name)
+		if err != nil {
+			return err
+		}
+		status, running := loadNodeStatus(ctx, builders)
+		if prev != nil {
+			for _, ev := range diffBuilderStatus(prev, status, time.Now()) {
+				emit(ev)
+			}
+		}
+		prev = status
+
+		for k, cancel := range listeners {
+			if _, ok := running[k]; !ok {
+				cancel()
+				delete(listeners, k)
+			}
+		}
+		for k, n := range running {
+			if _, ok := listeners[k]; ok {
+				continue
+			}
+			lctx, lcancel := context.WithCancel(ctx)
+			listeners[k] = lcancel
+			go func(k nodeKey, n builder.Node) {
+				if err := listenHistoryEvents(lctx, n, false, emit); err != nil && lctx.Err() == nil {
+					fmt.Fprintf(dockerCli.Err(), "Cannot follow builds of %s (%s): %v\n", k.builder, k.node, err)
+				}
+				select {
+				case ended <- k:
+				case <-ctx.Done():
+				}
+			}(k, n)
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case err := <-errCh:
+				return err
+			case k := <-ended:
+				// the listener is started again on the next poll if the
+				// node is still running
+				if cancel, ok := listeners[k]; ok {
+					cancel()
+					delete(listeners, k)
+				}
+			case <-ticker.C:
+				break wait
+			}
+		}
+	}
+}
+
+// listenHistoryEvents calls fn with the events of the builds of a node. The
+// events of the recorded builds are sent first. If earlyExit is set, it
+// returns once they are sent.
+func listenHistoryEvents(ctx context.Context, node builder.Node, earlyExit bool, fn func(builderEvent)) error {
+	c, err := node.Driver.Client(ctx)
+	if err != nil {
+		return err
+	}
+	cl, err := c.ControlClient().ListenBuildHistory(ctx, &controlapi.BuildHistoryRequest{
+		EarlyExit: earlyExit,
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		he, err := cl.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if ev, ok := newBuildEvent(node.Builder, node.Name, he); ok {
+			fn(ev)
+		}
+	}
+}
+
+// newBuildEvent returns the event of a build from an event of the build
+// history of a node. Deleted records have no event.
+func newBuildEvent(builderName, nodeName string, he *controlapi.BuildHistoryEvent) (builderEvent, bool) {
+	rec := he.Record
+	if rec == nil {
+		return builderEvent{}, false
+	}
+	ev := builderEvent{
+		Type:    eventTypeBuild,
+		Builder: builderName,
+		Node:    nodeName,
+		Ref:     rec.Ref,
+		Target:  rec.FrontendAttrs["target"],
+	}
+	switch he.Type {
+	case controlapi.BuildHistoryEventType_STARTED:
+		ev.Action = eventActionStarted
+		if rec.CreatedAt != nil {
+			ev.Time = rec.CreatedAt.AsTime()
+		}
+	case controlapi.BuildHistoryEventType_COMPLETE:
+		ev.Action = eventActionFinished
+		if rec.Error != nil {
+			ev.Action = eventActionFailed
+			ev.Error = rec.Error.Message
+		}
+		if rec.CompletedAt != nil {
+			ev.Time = rec.CompletedAt.AsTime()
+			if rec.CreatedAt != nil {
+				ev.Duration = rec.CompletedAt.AsTime().Sub(rec.CreatedAt.AsTime())
+			}
+		}
+	default:
+		return builderEvent{}, false
+	}
+	return ev, true
+}
+
+// diffBuilderStatus returns the events of the changes of the builders and of
+// the status of their nodes.
+func diffBuilderStatus(prev, cur map[string]map[string]bool, now time.Time) []builderEvent {
+	var events []builderEvent
+	for _, name := range sortedKeys(cur) {
+		prevNodes, existed := prev[name]
+		if !existed {
+			events = append(events, builderEvent{Time: now, Type: eventTypeBuilder, Action: eventActionCreated, Builder: name})
+		}
+		for _, node := range sortedKeys(cur[name]) {
+			running, wasRunning := cur[name][node], prevNodes[node]
+			switch {
+			case running && !wasRunning:
+				events = append(events, builderEvent{Time: now, Type: eventTypeBuilder, Action: eventActionBootstrapped, Builder: name, Node: node})
+			case !running && wasRunning:
+				events = append(events, builderEvent{Time: now, Type: eventTypeBuilder, Action: eventActionStopped, Builder: name, Node: node})
+			}
+		}
+	}
+	for _, name := range sortedKeys(prev) {
+		if _, ok := cur[name]; !ok {
+			events = append(events, builderEvent{Time: now, Type: eventTypeBuilder, Action: eventActionRemoved, Builder: name})
+		}
+	}
+	return events
+}
+
+// sortBuilderEvents sorts the events by time, oldest first.
+func sortBuilderEvents(events []builderEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Time.Equal(events[j].Time) {
+			return events[i].Time.Before(events[j].Time)
+		}
+		return events[i].Ref < events[j].Ref
+	})
+}
+
+// eventWriter writes the events as JSON lines or as text, once each. The
+// events of a build can be received again when the history of a node is
+// listened to again.
+type eventWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	json bool
+	seen map[string]struct{}
+}
+
+func (w *eventWriter) write(ev builderEvent) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if ev.Type == eventTypeBuild {
+		key := ev.Builder + "/" + ev.Node + "/" + ev.Ref + "/" + ev.Action
+		if _, ok := w.seen[key]; ok {
+			return nil
+		}
+		w.seen[key] = struct{}{}
+	}
+	if w.json {
+		dt, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w.w, string(dt))
+		return err
+	}
+	_, err := fmt.Fprintln(w.w, formatBuilderEvent(ev))
+	return err
+}
+
+func formatBuilderEvent(ev builderEvent) string {
+	s := fmt.Sprintf("%s %s %s builder=%s", ev.Time.Format(time.RFC3339), ev.Type, ev.Action, ev.Builder)
+	if ev.Node != "" {
+		s += " node=" + ev.Node
+	}
+	if ev.Ref != "" {
+		s += " ref=" + ev.Ref
+	}
+	if ev.Target != "" {
+		s += " target=" + ev.Target
+	}
+	if ev.Duration > 0 {
+		s += " duration=" + ev.Duration.Round(time.Millisecond).String()
+	}
+	if ev.Error != "" {
+		s += fmt.Sprintf(" error=%q", ev.Error)
+	}
+	return s
+}
+
+func eventsCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options eventsOptions
+
+	cmd := &cobra.Command{
+		Use:   "events [OPTIONS]",
+		Short: "Show the events of the builders and of their builds",
+		Args:  cli.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			return runEvents(cmd.Context(), dockerCli, options)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+	cobrautil.MarkCommandExperimental(cmd)
+
+	flags := cmd.Flags()
+	flags.BoolVarP(&options.all, "all", "a", false, "Show the events of all builders")
+	flags.BoolVarP(&options.follow, "follow", "f", false, "Stream the events as they happen")
+	flags.StringVar(&options.format, "format", "pretty", `Format of the output ("pretty", "json")`)
+
+	return cmd
+}
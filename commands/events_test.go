@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestNewBuildEvent(t *testing.T) {
+	created := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	completed := created.Add(90 * time.Second)
+
+	ev, ok := newBuildEvent("mybuilder", "mybuilder0", &controlapi.BuildHistoryEvent{
+		Type: controlapi.BuildHistoryEventType_STARTED,
+		Record: &controlapi.BuildHistoryRecord{
+			Ref:           "k2xgp6o5xdbmjz5q8dwqmtj2a",
+			FrontendAttrs: map[string]string{"target": "release"},
+			CreatedAt:     timestamppb.New(created),
+		},
+	})
+	require.True(t, ok)
+	require.Equal(t, builderEvent{
+		Time:    created,
+		Type:    eventTypeBuild,
+		Action:  eventActionStarted,
+		Builder: "mybuilder",
+		Node:    "mybuilder0",
+		Ref:     "k2xgp6o5xdbmjz5q8dwqmtj2a",
+		Target:  "release",
+	}, ev)
+
+	ev, ok = newBuildEvent("mybuilder", "mybuilder0", &controlapi.BuildHistoryEvent{
+		Type: controlapi.BuildHistoryEventType_COMPLETE,
+		Record: &controlapi.BuildHistoryRecord{
+			Ref:         "k2xgp6o5xdbmjz5q8dwqmtj2a",
+			CreatedAt:   timestamppb.New(created),
+			CompletedAt: timestamppb.New(completed),
+		},
+	})
+	require.True(t, ok)
+	require.Equal(t, eventActionFinished, ev.Action)
+	require.Equal(t, completed, ev.Time)
+	require.Equal(t, 90*time.Second, ev.Duration)
+
+	ev, ok = newBuildEvent("mybuilder", "mybuilder0", &controlapi.BuildHistoryEvent{
+		Type: controlapi.BuildHistoryEventType_COMPLETE,
+		Record: &controlapi.BuildHistoryRecord{
+			Ref:         "k2xgp6o5xdbmjz5q8dwqmtj2a",
+			CreatedAt:   timestamppb.New(created),
+			CompletedAt: timestamppb.New(completed),
+			Error:       &status.Status{Message: "process did not complete successfully"},
+		},
+	})
+	require.True(t, ok)
+	require.Equal(t, eventActionFailed, ev.Action)
+	require.Equal(t, "process did not complete successfully", ev.Error)
+
+	_, ok = newBuildEvent("mybuilder", "mybuilder0", &controlapi.BuildHistoryEvent{
+		Type:   controlapi.BuildHistoryEventType_DELETED,
+		Record: &controlapi.BuildHistoryRecord{Ref: "k2xgp6o5xdbmjz5q8dwqmtj2a"},
+	})
+	require.False(t, ok)
+}
+
+func TestDiffBuilderStatus(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	prev := map[string]map[string]bool{
+		"default": {"default": true},
+		"ci":      {"ci0": false, "ci1": true},
+		"old":     {"old0": true},
+	}
+	cur := map[string]map[string]bool{
+		"default": {"default": true},
+		"ci":      {"ci0": true, "ci1": false},
+		"new":     {"new0": false},
+	}
+	require.Equal(t, []builderEvent{
+		{Time: now, Type: eventTypeBuilder, Action: eventActionBootstrapped, Builder: "ci", Node: "ci0"},
+		{Time: now, Type: eventTypeBuilder, Action: eventActionStopped, Builder: "ci", Node: "ci1"},
+		{Time: now, Type: eventTypeBuilder, Action: eventActionCreated, Builder: "new"},
+		{Time: now, Type: eventTypeBuilder, Action: eventActionRemoved, Builder: "old"},
+	}, diffBuilderStatus(prev, cur, now))
+}
+
+func TestEventWriter(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	ev := builderEvent{
+		Time:     now,
+		Type:     eventTypeBuild,
+		Action:   eventActionFailed,
+		Builder:  "mybuilder",
+		Node:     "mybuilder0",
+		Ref:      "k2xgp6o5xdbmjz5q8dwqmtj2a",
+		Duration: 1500 * time.Millisecond,
+		Error:    "exit code: 1",
+	}
+
+	var buf bytes.Buffer
+	w := &eventWriter{w: &buf, seen: map[string]struct{}{}}
+	require.NoError(t, w.write(ev))
+	// the events of a build received again are written once
+	require.NoError(t, w.write(ev))
+	require.NoError(t, w.write(builderEvent{Time: now, Type: eventTypeBuilder, Action: eventActionBootstrapped, Builder: "mybuilder", Node: "mybuilder0"}))
+	require.Equal(t, `2024-06-15T12:00:00Z build failed builder=mybuilder node=mybuilder0 ref=k2xgp6o5xdbmjz5q8dwqmtj2a duration=1.5s error="exit code: 1"
+2024-06-15T12:00:00Z builder bootstrapped builder=mybuilder node=mybuilder0
+`, buf.String())
+
+	buf.Reset()
+	w = &eventWriter{w: &buf, json: true, seen: map[string]struct{}{}}
+	require.NoError(t, w.write(ev))
+	require.JSONEq(t, `{"time":"2024-06-15T12:00:00Z","type":"build","action":"failed","builder":"mybuilder","node":"mybuilder0","ref":"k2xgp6o5xdbmjz5q8dwqmtj2a","duration":1500000000,"error":"exit code: 1"}`, buf.String())
+}
@@ -0,0 +1,391 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/content/proxy"
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/localstate"
+	"github.com/docker/buildx/util/cobrautil"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/buildx/util/confutil"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/moby/buildkit/client"
+	provenancetypes "github.com/moby/buildkit/solver/llbsolver/provenance/types"
+	"github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// predicateTypeAnnotation is the annotation of the attestations of a build
+// record holding their in-toto predicate type.
+const predicateTypeAnnotation = "in-toto.io/predicate-type"
+
+// historyStep is a step of a build record along with its logs.
+type historyStep struct {
+	Vertex *client.Vertex
+	Logs   []byte
+	Env    []string
+}
+
+// Duration returns how long the step ran, or zero if it didn't complete.
+func (s *historyStep) Duration() time.Duration {
+	if s.Vertex.Started == nil || s.Vertex.Completed == nil {
+		return 0
+	}
+	return s.Vertex.Completed.Sub(*s.Vertex.Started)
+}
+
+// collectSteps returns the steps of the status updates of a build in the
+// order they were first seen. Later updates of a vertex replace the earlier
+// ones.
+func collectSteps(statuses []*client.SolveStatus) []*historyStep {
+	var steps []*historyStep
+	m := map[digest.Digest]*historyStep{}
+	for _, st := range statuses {
+		for _, v := range st.Vertexes {
+			s, ok := m[v.Digest]
+			if !ok {
+				s = &historyStep{}
+				m[v.Digest] = s
+				steps = append(steps, s)
+			}
+			s.Vertex = v
+		}
+		for _, l := range st.Logs {
+			if s, ok := m[l.Vertex]; ok {
+				s.Logs = append(s.Logs, l.Data...)
+			}
+		}
+	}
+	return steps
+}
+
+// findStep returns the step matching name, which is either the name or the
+// digest of the step, or a part of the name matching a single step.
+func findStep(steps []*historyStep, name string) (*historyStep, error) {
+	for _, s := range steps {
+		if s.Vertex.Name == name || s.Vertex.Digest.String() == name {
+			return s, nil
+		}
+	}
+	var found []*historyStep
+	for _, s := range steps {
+		if strings.Contains(s.Vertex.Name, name) {
+			found = append(found, s)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, errors.Errorf("no step matching %q in build record", name)
+	case 1:
+		return found[0], nil
+	}
+	names := make([]string, len(found))
+	for i, s := range found {
+		names[i] = fmt.Sprintf("%q", s.Vertex.Name)
+	}
+	return nil, errors.Errorf("several steps match %q: %s", name, strings.Join(names, ", "))
+}
+
+// stepEnv returns the environment of the exec step built for the vertex dgst
+// from the provenance of the build.
+func stepEnv(pred *provenancetypes.ProvenancePredicate, dgst digest.Digest) []string {
+	if pred == nil || pred.BuildConfig == nil {
+		return nil
+	}
+	id, ok := pred.BuildConfig.DigestMapping[dgst]
+	if !ok {
+		return nil
+	}
+	for _, s := range pred.BuildConfig.Definition {
+		if s.ID == id {
+			return s.Op.GetExec().GetMeta().GetEnv()
+		}
+	}
+	return nil
+}
+
+type historyInspectOptions struct {
+	builder string
+	ref     string
+	step    string
+}
+
+func runHistoryInspect(ctx context.Context, dockerCli command.Cli, opts historyInspectOptions) error {
+	nodes, err := loadBuilderNodes(ctx, dockerCli, opts.builder)
+	if err != nil {
+		return err
+	}
+	node, c, rec, err := loadHistoryRecord(ctx, nodes, opts.ref)
+	if err != nil {
+		return err
+	}
+	steps, err := loadHistorySteps(ctx, c, rec.Ref)
+	if err != nil {
+		return err
+	}
+	if opts.step == "" {
+		ls, err := localstate.New(confutil.NewConfig(dockerCli))
+		if err != nil {
+			return err
+		}
+		st, _ := ls.ReadRef(node.Builder, node.Name, rec.Ref)
+		printHistoryRecord(dockerCli.Out(), rec, st, steps)
+		return nil
+	}
+	s, err := findStep(steps, opts.step)
+	if err != nil {
+		return err
+	}
+	pred, err := loadHistoryProvenance(ctx, c, rec)
+	if err != nil {
+		return err
+	}
+	s.Env = stepEnv(pred, s.Vertex.Digest)
+	printHistoryStep(dockerCli.Out(), s)
+	return nil
+}
+
+// loadHistoryRecord returns the build record ref along with the node that
+// built it.
+func loadHistoryRecord(ctx context.Context, nodes []builder.Node, ref string) (builder.Node, *client.Client, *controlapi.BuildHistoryRecord, error) {
+	for _, node := range nodes {
+		if node.Driver == nil {
+			continue
+		}
+		c, err := node.Driver.Client(ctx)
+		if err != nil {
+			return builder.Node{}, nil, nil, err
+		}
+		cl, err := c.ControlClient().ListenBuildHistory(ctx, &controlapi.BuildHistoryRequest{
+			Ref:       ref,
+			EarlyExit: true,
+		})
+		if err != nil {
+			return builder.Node{}, nil, nil, err
+		}
+		for {
+			ev, err := cl.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return builder.Node{}, nil, nil, err
+			}
+			if ev.Record != nil && ev.Record.Ref == ref {
+				return node, c, ev.Record, nil
+			}
+		}
+	}
+	return builder.Node{}, nil, nil, errors.Errorf("build record %s not found", ref)
+}
+
+// loadHistorySteps replays the progress of the build record ref.
+func loadHistorySteps(ctx context.Context, c *client.Client, ref string) ([]*historyStep, error) {
+	cl, err := c.ControlClient().Status(ctx, &controlapi.StatusRequest{Ref: ref})
+	if err != nil {
+		return nil, err
+	}
+	var statuses []*client.SolveStatus
+	for {
+		resp, err := cl.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, errors.Wrapf(err, "failed to load logs of build record %s", ref)
+		}
+		statuses = append(statuses, client.NewSolveStatus(resp))
+	}
+	return collectSteps(statuses), nil
+}
+
+// loadHistoryProvenance returns the provenance recorded with the build, or
+// nil if there is none.
+func loadHistoryProvenance(ctx context.Context, c *client.Client, rec *controlapi.BuildHistoryRecord) (*provenancetypes.ProvenancePredicate, error) {
+	results := make([]*controlapi.BuildResultInfo, 0, len(rec.Results)+1)
+	if rec.Result != nil {
+		results = append(results, rec.Result)
+	}
+	for _, res := range rec.Results {
+		results = append(results, res)
+	}
+	store := proxy.NewContentStore(c.ContentClient())
+	for _, res := range results {
+		for _, att := range res.Attestations {
+			if att.Annotations[predicateTypeAnnotation] != slsa02.PredicateSLSAProvenance {
+				continue
+			}
+			dt, err := content.ReadBlob(ctx, store, ocispecs.Descriptor{
+				MediaType: att.MediaType,
+				Digest:    digest.Digest(att.Digest),
+				Size:      att.Size,
+			})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read provenance of build record %s", rec.Ref)
+			}
+			var stmt struct {
+				Predicate provenancetypes.ProvenancePredicate `json:"predicate"`
+			}
+			if err := json.Unmarshal(dt, &stmt); err != nil {
+				return nil, errors.Wrapf(err, "failed to parse provenance of build record %s", rec.Ref)
+			}
+			return &stmt.Predicate, nil
+		}
+	}
+	return nil, nil
+}
+
+// printHistoryRecord prints the build record along with the cancel reason
+// kept in its local state st, which may be nil.
+func printHistoryRecord(w io.Writer, rec *controlapi.BuildHistoryRecord, st *localstate.State, steps []*historyStep) {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "Ref:\t%s\n", rec.Ref)
+	if rec.Frontend != "" {
+		fmt.Fprintf(tw, "Frontend:\t%s\n", rec.Frontend)
+	}
+	if target := rec.FrontendAttrs["target"]; target != "" {
+		fmt.Fprintf(tw, "Target:\t%s\n", target)
+	}
+	status := "Running"
+	switch {
+	case rec.Error != nil:
+		status = "Error"
+	case rec.CompletedAt != nil:
+		status = "Completed"
+	}
+	fmt.Fprintf(tw, "Status:\t%s\n", status)
+	if rec.CreatedAt != nil {
+		fmt.Fprintf(tw, "Created:\t%s\n", rec.CreatedAt.AsTime().Local().Format(time.RFC3339))
+	}
+	if rec.CreatedAt != nil && rec.CompletedAt != nil {
+		fmt.Fprintf(tw, "Completed:\t%s\n", rec.CompletedAt.AsTime().Local().Format(time.RFC3339))
+		fmt.Fprintf(tw, "Duration:\t%s\n", formatStepDuration(rec.CompletedAt.AsTime().Sub(rec.CreatedAt.AsTime())))
+	}
+	fmt.Fprintf(tw, "Build Steps:\t%d/%d (%d cached)\n", rec.NumCompletedSteps, rec.NumTotalSteps, rec.NumCachedSteps)
+	if rec.NumWarnings > 0 {
+		fmt.Fprintf(tw, "Warnings:\t%d\n", rec.NumWarnings)
+	}
+	if rec.Error != nil {
+		fmt.Fprintf(tw, "Error:\t%s\n", rec.Error.Message)
+	}
+	if st != nil && st.CancelReason != "" {
+		fmt.Fprintf(tw, "Cancel Reason:\t%s\n", st.CancelReason)
+	}
+	tw.Flush()
+
+	if len(steps) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nSteps:\n")
+	tw = tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tDURATION")
+	for _, s := range steps {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Vertex.Name, stepStatus(s.Vertex), formatStepDuration(s.Duration()))
+	}
+	tw.Flush()
+}
+
+func printHistoryStep(w io.Writer, s *historyStep) {
+	v := s.Vertex
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", v.Name)
+	fmt.Fprintf(tw, "Digest:\t%s\n", v.Digest)
+	fmt.Fprintf(tw, "Status:\t%s\n", stepStatus(v))
+	if v.Started != nil {
+		fmt.Fprintf(tw, "Started:\t%s\n", v.Started.Local().Format(time.RFC3339))
+	}
+	if v.Completed != nil {
+		fmt.Fprintf(tw, "Completed:\t%s\n", v.Completed.Local().Format(time.RFC3339))
+		fmt.Fprintf(tw, "Duration:\t%s\n", formatStepDuration(s.Duration()))
+	}
+	if v.Error != "" {
+		fmt.Fprintf(tw, "Error:\t%s\n", v.Error)
+	}
+	tw.Flush()
+
+	if len(s.Env) > 0 {
+		fmt.Fprintf(w, "\nEnv:\n")
+		for _, e := range s.Env {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+	if len(s.Logs) > 0 {
+		fmt.Fprintf(w, "\nLogs:\n")
+		w.Write(s.Logs)
+		if s.Logs[len(s.Logs)-1] != '\n' {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// stepStatus returns whether the step was cached, failed, completed or is
+// still running.
+func stepStatus(v *client.Vertex) string {
+	switch {
+	case v.Cached:
+		return "Cached"
+	case v.Error != "":
+		return "Error"
+	case v.Completed != nil:
+		return "Completed"
+	case v.Started != nil:
+		return "Running"
+	}
+	return "Pending"
+}
+
+func formatStepDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+func historyCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "history",
+		Short:             "Inspect the build records of a builder",
+		ValidArgsFunction: completion.Disable,
+	}
+	cobrautil.MarkCommandExperimental(cmd)
+
+	cmd.AddCommand(
+		historyInspectCmd(dockerCli, rootOpts),
+	)
+
+	return cmd
+}
+
+func historyInspectCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options historyInspectOptions
+
+	cmd := &cobra.Command{
+		Use:   "inspect [OPTIONS] REF",
+		Short: "Inspect a build record",
+		Args:  cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			options.ref = args[0]
+			return runHistoryInspect(cmd.Context(), dockerCli, options)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&options.step, "step", "", "Show the logs, environment and timing of a single step")
+
+	return cmd
+}
@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/docker/buildx/localstate"
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/moby/buildkit/client"
+	provenancetypes "github.com/moby/buildkit/solver/llbsolver/provenance/types"
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func testHistorySteps() []*historyStep {
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	completed := started.Add(1500 * time.Millisecond)
+	return collectSteps([]*client.SolveStatus{
+		{
+			Vertexes: []*client.Vertex{
+				{Digest: "sha256:aaa", Name: "[internal] load build definition from Dockerfile"},
+				{Digest: "sha256:bbb", Name: "[build 2/3] RUN go build ./...", Started: &started},
+			},
+		},
+		{
+			Vertexes: []*client.Vertex{
+				{Digest: "sha256:ccc", Name: "[build 3/3] RUN go test ./...", Cached: true},
+			},
+			Logs: []*client.VertexLog{
+				{Vertex: "sha256:bbb", Stream: 1, Data: []byte("compiling\n")},
+			},
+		},
+		{
+			Vertexes: []*client.Vertex{
+				{Digest: "sha256:bbb", Name: "[build 2/3] RUN go build ./...", Started: &started, Completed: &completed, Error: "exit code: 1"},
+			},
+			Logs: []*client.VertexLog{
+				{Vertex: "sha256:bbb", Stream: 2, Data: []byte("undefined: foo")},
+				{Vertex: "sha256:ddd", Stream: 1, Data: []byte("unknown vertex\n")},
+			},
+		},
+	})
+}
+
+func TestCollectSteps(t *testing.T) {
+	steps := testHistorySteps()
+	require.Len(t, steps, 3)
+
+	require.Equal(t, "[internal] load build definition from Dockerfile", steps[0].Vertex.Name)
+
+	require.Equal(t, "[build 2/3] RUN go build ./...", steps[1].Vertex.Name)
+	require.Equal(t, "exit code: 1", steps[1].Vertex.Error)
+	require.Equal(t, "compiling\nundefined: foo", string(steps[1].Logs))
+	require.Equal(t, 1500*time.Millisecond, steps[1].Duration())
+	require.Equal(t, "Error", stepStatus(steps[1].Vertex))
+
+	require.Equal(t, "Cached", stepStatus(steps[2].Vertex))
+	require.Equal(t, time.Duration(0), steps[2].Duration())
+}
+
+func TestFindStep(t *testing.T) {
+	steps := testHistorySteps()
+
+	s, err := findStep(steps, "[build 2/3] RUN go build ./...")
+	require.NoError(t, err)
+	require.Equal(t, digest.Digest("sha256:bbb"), s.Vertex.Digest)
+
+	s, err = findStep(steps, "sha256:ccc")
+	require.NoError(t, err)
+	require.Equal(t, "[build 3/3] RUN go test ./...", s.Vertex.Name)
+
+	s, err = findStep(steps, "go test")
+	require.NoError(t, err)
+	require.Equal(t, digest.Digest("sha256:ccc"), s.Vertex.Digest)
+
+	_, err = findStep(steps, "RUN go")
+	require.ErrorContains(t, err, "several steps match")
+
+	_, err = findStep(steps, "COPY")
+	require.ErrorContains(t, err, `no step matching "COPY"`)
+}
+
+func TestStepEnv(t *testing.T) {
+	dt, err := json.Marshal(map[string]any{
+		"buildConfig": map[string]any{
+			"llbDefinition": []provenancetypes.BuildStep{
+				{
+					ID: "step0",
+					Op: &pb.Op{Op: &pb.Op_Exec{Exec: &pb.ExecOp{Meta: &pb.Meta{
+						Args: []string{"/bin/sh", "-c", "go build ./..."},
+						Env:  []string{"PATH=/usr/local/go/bin:/usr/bin", "CGO_ENABLED=0"},
+					}}}},
+				},
+				{
+					ID: "step1",
+					Op: &pb.Op{Op: &pb.Op_Source{Source: &pb.SourceOp{Identifier: "local://context"}}},
+				},
+			},
+			"digestMapping": map[string]string{
+				"sha256:bbb": "step0",
+				"sha256:aaa": "step1",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	var pred provenancetypes.ProvenancePredicate
+	require.NoError(t, json.Unmarshal(dt, &pred))
+
+	require.Equal(t, []string{"PATH=/usr/local/go/bin:/usr/bin", "CGO_ENABLED=0"}, stepEnv(&pred, "sha256:bbb"))
+	require.Nil(t, stepEnv(&pred, "sha256:aaa"))
+	require.Nil(t, stepEnv(&pred, "sha256:ccc"))
+	require.Nil(t, stepEnv(nil, "sha256:bbb"))
+}
+
+func TestPrintHistoryStep(t *testing.T) {
+	steps := testHistorySteps()
+	s := steps[1]
+	s.Env = []string{"CGO_ENABLED=0"}
+
+	var buf bytes.Buffer
+	printHistoryStep(&buf, s)
+	out := buf.String()
+	require.Contains(t, out, "Name:      [build 2/3] RUN go build ./...\n")
+	require.Contains(t, out, "Status:    Error\n")
+	require.Contains(t, out, "Duration:  1.5s\n")
+	require.Contains(t, out, "Error:     exit code: 1\n")
+	require.Contains(t, out, "\nEnv:\n  CGO_ENABLED=0\n")
+	require.Contains(t, out, "\nLogs:\ncompiling\nundefined: foo\n")
+}
+
+func TestPrintHistoryRecordCancelReason(t *testing.T) {
+	rec := &controlapi.BuildHistoryRecord{Ref: "qu2gsuo8ejqrwdfii23xkkckt", NumTotalSteps: 3, NumCompletedSteps: 1}
+
+	var buf bytes.Buffer
+	printHistoryRecord(&buf, rec, nil, nil)
+	require.NotContains(t, buf.String(), "Cancel Reason:")
+
+	buf.Reset()
+	printHistoryRecord(&buf, rec, &localstate.State{CancelReason: "superseded by newer commit"}, nil)
+	require.Contains(t, buf.String(), "Cancel Reason: superseded by newer commit\n")
+}
@@ -28,9 +28,11 @@ type createOptions struct {
 	tags         []string
 	annotations  []string
 	dryrun       bool
+	format       string
 	actionAppend bool
 	progress     string
 	preferIndex  bool
+	retainProv   bool
 }
 
 func runCreate(ctx context.Context, dockerCli command.Cli, in createOptions, args []string) error {
@@ -38,6 +40,15 @@ func runCreate(ctx context.Context, dockerCli command.Cli, in createOptions, arg
 		return errors.Errorf("no sources specified")
 	}
 
+	switch in.format {
+	case "raw", "plan":
+	default:
+		return errors.Errorf("invalid format %q, expecting raw or plan", in.format)
+	}
+	if in.format != "raw" && !in.dryrun {
+		return errors.Errorf("--format requires --dry-run")
+	}
+
 	if !in.dryrun && len(in.tags) == 0 {
 		return errors.Errorf("can't push with no tags specified, please set --tag or --dry-run")
 	}
@@ -155,6 +166,14 @@ func runCreate(ctx context.Context, dockerCli command.Cli, in createOptions, arg
 		}
 	}
 
+	if in.retainProv {
+		atts, err := r.Attestations(ctx, srcs)
+		if err != nil {
+			return err
+		}
+		srcs = append(srcs, atts...)
+	}
+
 	annotations, err := buildflags.ParseAnnotations(in.annotations)
 	if err != nil {
 		return errors.Wrapf(err, "failed to parse annotations")
@@ -166,6 +185,9 @@ func runCreate(ctx context.Context, dockerCli command.Cli, in createOptions, arg
 	}
 
 	if in.dryrun {
+		if in.format == "plan" {
+			return printPlan(dockerCli.Out(), dt, desc, srcs, tags)
+		}
 		fmt.Printf("%s\n", dt)
 		return nil
 	}
@@ -287,9 +309,11 @@ func createCmd(dockerCli command.Cli, opts RootOptions) *cobra.Command {
 	flags.StringArrayVarP(&options.files, "file", "f", []string{}, "Read source descriptor from file")
 	flags.StringArrayVarP(&options.tags, "tag", "t", []string{}, "Set reference for new image")
 	flags.BoolVar(&options.dryrun, "dry-run", false, "Show final image instead of pushing")
+	flags.StringVar(&options.format, "format", "raw", `Format of the dry-run output ("raw", "plan")`)
 	flags.BoolVar(&options.actionAppend, "append", false, "Append to existing manifest")
 	flags.StringVar(&options.progress, "progress", "auto", `Set type of progress output ("auto", "plain", "tty", "rawjson"). Use plain to show container output`)
 	flags.StringArrayVarP(&options.annotations, "annotation", "", []string{}, "Add annotation to the image")
+	flags.BoolVar(&options.retainProv, "retain-provenance", false, "Carry over the provenance and SBOM attestations of source image manifests")
 	flags.BoolVar(&options.preferIndex, "prefer-index", true, "When only a single source is specified, prefer outputting an image index or manifest list instead of performing a carbon copy")
 
 	return cmd
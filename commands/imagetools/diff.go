@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/go-units"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type diffOptions struct {
+	builder string
+	format  string
+}
+
+func runDiff(ctx context.Context, dockerCli command.Cli, in diffOptions, from, to string) error {
+	switch in.format {
+	case "", "text", "json":
+	default:
+		return errors.Errorf("unsupported format %q, use text or json", in.format)
+	}
+
+	b, err := builder.New(dockerCli, builder.WithName(in.builder))
+	if err != nil {
+		return err
+	}
+	imageopt, err := b.ImageOpt()
+	if err != nil {
+		return err
+	}
+
+	d, err := imagetools.Diff(ctx, imageopt, from, to)
+	if err != nil {
+		return err
+	}
+
+	if in.format == "json" {
+		enc := json.NewEncoder(dockerCli.Out())
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	}
+	printDiff(dockerCli.Out(), d)
+	return nil
+}
+
+func printDiff(out io.Writer, d *imagetools.ImageDiff) {
+	fmt.Fprintf(out, "From: %s\n", d.From)
+	fmt.Fprintf(out, "To:   %s\n", d.To)
+	if d.Identical() {
+		fmt.Fprintln(out, "\nImages are identical")
+		return
+	}
+	for _, p := range d.Coverage.Removed {
+		fmt.Fprintf(out, "\nPlatform %s: only in %s\n", p, d.From)
+	}
+	for _, p := range d.Coverage.Added {
+		fmt.Fprintf(out, "\nPlatform %s: only in %s\n", p, d.To)
+	}
+
+	for _, p := range d.Platforms {
+		fmt.Fprintf(out, "\nPlatform %s:\n", p.Platform)
+		fmt.Fprintf(out, "  Size: %s -> %s (%s)\n", units.HumanSize(float64(p.Size.From)), units.HumanSize(float64(p.Size.To)), formatSizeDelta(p.Size.Delta))
+
+		fmt.Fprintln(out, "  Layers:")
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "    #\tSTATUS\tFROM\tTO")
+		for _, l := range p.Layers {
+			from, to := "-", "-"
+			if l.From != nil {
+				from = fmt.Sprintf("%s (%s)", l.From.Digest, units.HumanSize(float64(l.From.Size)))
+			}
+			if l.To != nil {
+				to = fmt.Sprintf("%s (%s)", l.To.Digest, units.HumanSize(float64(l.To.Size)))
+			}
+			if l.Status == imagetools.LayerUnchanged {
+				to = ""
+			}
+			fmt.Fprintf(w, "    %d\t%s\t%s\t%s\n", l.Index, l.Status, from, to)
+		}
+		w.Flush()
+
+		if len(p.Config) > 0 {
+			fmt.Fprintln(out, "  Config:")
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			for _, c := range p.Config {
+				fmt.Fprintf(w, "    %s\t%s\t->\t%s\n", c.Field, formatConfigValue(c.From), formatConfigValue(c.To))
+			}
+			w.Flush()
+		}
+	}
+}
+
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + units.HumanSize(float64(-delta))
+	}
+	return "+" + units.HumanSize(float64(delta))
+}
+
+func formatConfigValue(v string) string {
+	if v == "" {
+		return "<unset>"
+	}
+	return v
+}
+
+func diffCmd(dockerCli command.Cli, rootOpts RootOptions) *cobra.Command {
+	var options diffOptions
+
+	cmd := &cobra.Command{
+		Use:   "diff [OPTIONS] IMAGE1 IMAGE2",
+		Short: "Compare the layers, sizes and config of two images in the registry",
+		Args:  cli.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = *rootOpts.Builder
+			return runDiff(cmd.Context(), dockerCli, options, args[0], args[1])
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&options.format, "format", "text", `Format of the output ("text", "json")`)
+
+	return cmd
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/platforms"
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const planPfx = "  "
+
+// printPlan prints the image that would be created by imagetools create
+// without pushing it: the tags, the manifests of the index along with the
+// sources they come from, the annotations and the blobs copied between
+// repositories.
+func printPlan(out io.Writer, dt []byte, desc ocispec.Descriptor, srcs []*imagetools.Source, tags []reference.Named) error {
+	var idx ocispec.Index
+	if err := json.Unmarshal(dt, &idx); err != nil {
+		return err
+	}
+	isIndex := desc.MediaType == ocispec.MediaTypeImageIndex || desc.MediaType == images.MediaTypeDockerSchema2ManifestList
+
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	if len(tags) == 0 {
+		_, _ = fmt.Fprintf(w, "Tags:\t<none>\n")
+	} else {
+		names := make([]string, len(tags))
+		for i, t := range tags {
+			names[i] = t.String()
+		}
+		_, _ = fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(names, ", "))
+	}
+	_, _ = fmt.Fprintf(w, "MediaType:\t%s\n", desc.MediaType)
+	_, _ = fmt.Fprintf(w, "Digest:\t%s\n", desc.Digest)
+	_, _ = fmt.Fprintf(w, "Size:\t%d\n", desc.Size)
+	_ = w.Flush()
+
+	if isIndex {
+		_, _ = fmt.Fprintf(out, "\nManifests:\n")
+		w = tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+		for i, m := range idx.Manifests {
+			if i != 0 {
+				_, _ = fmt.Fprintf(w, "\n")
+			}
+			_, _ = fmt.Fprintf(w, "%sDigest:\t%s\n", planPfx, m.Digest)
+			if src := planSource(srcs, m.Digest); src != "" {
+				_, _ = fmt.Fprintf(w, "%sSource:\t%s\n", planPfx, src)
+			}
+			_, _ = fmt.Fprintf(w, "%sMediaType:\t%s\n", planPfx, m.MediaType)
+			if p := m.Platform; p != nil {
+				_, _ = fmt.Fprintf(w, "%sPlatform:\t%s\n", planPfx, platforms.Format(*p))
+			}
+			printPlanAnnotations(w, out, planPfx, m.Annotations)
+		}
+		_ = w.Flush()
+	} else if src := planSource(srcs, desc.Digest); src != "" {
+		w = tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+		_, _ = fmt.Fprintf(w, "Source:\t%s\n", src)
+		_ = w.Flush()
+	}
+
+	if len(idx.Annotations) > 0 {
+		_, _ = fmt.Fprintf(out, "\n")
+		printPlanAnnotations(nil, out, "", idx.Annotations)
+	}
+
+	var copies []string
+	for _, t := range tags {
+		for _, s := range srcs {
+			if s.Ref == nil || reference.Domain(s.Ref) == reference.Domain(t) && reference.Path(s.Ref) == reference.Path(t) {
+				continue
+			}
+			copies = append(copies, fmt.Sprintf("%s from %s to %s", s.Desc.Digest, s.Ref.String(), t.String()))
+		}
+	}
+	if len(copies) > 0 {
+		_, _ = fmt.Fprintf(out, "\nCopies:\n")
+		for _, c := range copies {
+			_, _ = fmt.Fprintf(out, "%s%s\n", planPfx, c)
+		}
+	}
+	return nil
+}
+
+// planSource returns the reference of the source of a manifest.
+func planSource(srcs []*imagetools.Source, dgst digest.Digest) string {
+	for _, s := range srcs {
+		if s.Desc.Digest != dgst || s.Ref == nil {
+			continue
+		}
+		if _, ok := s.Ref.(reference.Digested); ok {
+			return s.Ref.String()
+		}
+		return s.Ref.String() + "@" + dgst.String()
+	}
+	return ""
+}
+
+// printPlanAnnotations flushes w and prints the annotations sorted by key,
+// aligned independently from the other fields.
+func printPlanAnnotations(w *tabwriter.Writer, out io.Writer, pfx string, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+	if w != nil {
+		_ = w.Flush()
+	}
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	_, _ = fmt.Fprintf(out, "%sAnnotations:\n", pfx)
+	w2 := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(w2, "%s%s%s:\t%s\n", pfx, planPfx, k, annotations[k])
+	}
+	_ = w2.Flush()
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintPlan(t *testing.T) {
+	amd64 := digest.FromString("amd64")
+	arm64 := digest.FromString("arm64")
+	idx := ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{
+			{MediaType: ocispec.MediaTypeImageManifest, Digest: amd64, Size: 10, Platform: &ocispec.Platform{OS: "linux", Architecture: "amd64"}},
+			{MediaType: ocispec.MediaTypeImageManifest, Digest: arm64, Size: 10, Platform: &ocispec.Platform{OS: "linux", Architecture: "arm64"}, Annotations: map[string]string{"org.opencontainers.image.title": "app"}},
+		},
+		Annotations: map[string]string{"org.opencontainers.image.version": "1.0"},
+	}
+	idx.SchemaVersion = 2
+	dt, err := json.Marshal(idx)
+	require.NoError(t, err)
+	desc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageIndex, Digest: digest.FromBytes(dt), Size: int64(len(dt))}
+
+	ref := func(s string) reference.Named {
+		n, err := reference.ParseNormalizedNamed(s)
+		require.NoError(t, err)
+		return n
+	}
+	srcs := []*imagetools.Source{
+		{Ref: ref("user/app:amd64"), Desc: idx.Manifests[0]},
+		{Ref: ref("user/staging@" + arm64.String()), Desc: idx.Manifests[1]},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, printPlan(&buf, dt, desc, srcs, []reference.Named{ref("user/app:latest")}))
+	require.Equal(t, `Tags:      docker.io/user/app:latest
+MediaType: application/vnd.oci.image.index.v1+json
+Digest:    `+desc.Digest.String()+`
+Size:      `+strconv.FormatInt(desc.Size, 10)+`
+
+Manifests:
+  Digest:    `+amd64.String()+`
+  Source:    docker.io/user/app:amd64@`+amd64.String()+`
+  MediaType: application/vnd.oci.image.manifest.v1+json
+  Platform:  linux/amd64
+
+  Digest:    `+arm64.String()+`
+  Source:    docker.io/user/staging@`+arm64.String()+`
+  MediaType: application/vnd.oci.image.manifest.v1+json
+  Platform:  linux/arm64
+  Annotations:
+    org.opencontainers.image.title: app
+
+Annotations:
+  org.opencontainers.image.version: 1.0
+
+Copies:
+  `+arm64.String()+` from docker.io/user/staging@`+arm64.String()+` to docker.io/user/app:latest
+`, buf.String())
+}
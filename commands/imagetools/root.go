@@ -20,6 +20,7 @@ func RootCmd(rootcmd *cobra.Command, dockerCli command.Cli, opts RootOptions) *c
 
 	cmd.AddCommand(
 		createCmd(dockerCli, opts),
+		diffCmd(dockerCli, opts),
 		inspectCmd(dockerCli, opts),
 	)
 
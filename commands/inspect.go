@@ -57,6 +57,17 @@ func runInspect(ctx context.Context, dockerCli command.Cli, in inspectOptions) e
 	if !b.NodeGroup.LastActivity.IsZero() {
 		fmt.Fprintf(w, "Last Activity:\t%v\n", b.NodeGroup.LastActivity)
 	}
+	if d := b.NodeGroup.BuildDefaults; d != nil {
+		if d.ShmSize > 0 {
+			fmt.Fprintf(w, "Default Shm Size:\t%s\n", units.BytesSize(float64(d.ShmSize)))
+		}
+		if len(d.Ulimits) > 0 {
+			fmt.Fprintf(w, "Default Ulimits:\t%s\n", strings.Join(d.Ulimits, ", "))
+		}
+	}
+	if n := b.NodeGroup.MaxConcurrentBuilds; n > 0 {
+		fmt.Fprintf(w, "Max Concurrent Builds:\t%d\n", n)
+	}
 
 	if err != nil {
 		fmt.Fprintf(w, "Error:\t%s\n", err.Error())
@@ -157,6 +168,7 @@ func inspectCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
 		Short: "Inspect current builder instance",
 		Args:  cli.RequiresMaxArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			rootOpts.useProjectBuilder()
 			options.builder = rootOpts.builder
 			if len(args) > 0 {
 				options.builder = args[0]
@@ -172,7 +184,7 @@ func inspectCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
 	return cmd
 }
 
-func sortedKeys(m map[string]string) []string {
+func sortedKeys[T any](m map[string]T) []string {
 	s := make([]string, len(m))
 	i := 0
 	for k := range m {
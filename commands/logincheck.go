@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/buildx/bake"
+	"github.com/docker/buildx/build"
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/docker/buildx/util/progress"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/moby/buildkit/client"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type loginCheckOptions struct {
+	builder string
+}
+
+func runLoginCheck(ctx context.Context, dockerCli command.Cli, in loginCheckOptions, names []string) error {
+	b, err := builder.New(dockerCli, builder.WithName(in.builder))
+	if err != nil {
+		return err
+	}
+	imageopt, err := b.ImageOpt()
+	if err != nil {
+		return err
+	}
+
+	r := imagetools.New(imageopt)
+	var failed int
+	for _, name := range names {
+		if err := r.CheckPush(ctx, name); err != nil {
+			fmt.Fprintf(dockerCli.Err(), "%s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(dockerCli.Out(), "%s: push access OK\n", name)
+	}
+	if failed > 0 {
+		return errors.Errorf("push access check failed for %d of %d images", failed, len(names))
+	}
+	return nil
+}
+
+func loginCheckCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options loginCheckOptions
+
+	cmd := &cobra.Command{
+		Use:   "login-check [OPTIONS] NAME [NAME...]",
+		Short: "Verify that the current credentials can push to images in the registry",
+		Args:  cli.RequiresMinArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			return runLoginCheck(cmd.Context(), dockerCli, options, args)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	return cmd
+}
+
+// verifyPush checks that the current credentials can push to each of the
+// images, so that a build doesn't fail when exporting its result.
+func verifyPush(ctx context.Context, pw progress.Writer, opt imagetools.Opt, names []string) error {
+	if len(names) == 0 {
+		return errors.New("--verify-push requires pushing the image to a named destination")
+	}
+	r := imagetools.New(opt)
+	return progress.Wrap("[internal] verifying push access", pw.Write, func(sub progress.SubLogger) error {
+		var failed []string
+		for _, name := range names {
+			sub.Log(1, []byte(fmt.Sprintf("checking %s\n", name)))
+			if err := r.CheckPush(ctx, name); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+			}
+		}
+		if len(failed) > 0 {
+			return errors.Errorf("cannot push the result of the build:\n - %s", strings.Join(failed, "\n - "))
+		}
+		return nil
+	})
+}
+
+// pushDestinations returns the images that an export pushes to, which are
+// the names set on the exporter or the tags of the build.
+func pushDestinations(tags []string, typ string, attrs map[string]string) []string {
+	if typ != "registry" {
+		if push, _ := strconv.ParseBool(attrs["push"]); !push || typ != client.ExporterImage {
+			return nil
+		}
+	}
+	if name := attrs["name"]; name != "" {
+		return strings.Split(name, ",")
+	}
+	return tags
+}
+
+func uniqueNames(names []string) []string {
+	names = slices.Clone(names)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// bakePushDestinations returns the images pushed by the targets of a bake
+// invocation, including the indexes assembled by the index targets.
+func bakePushDestinations(tgts map[string]*bake.Target, bo map[string]build.Options) []string {
+	var names []string
+	for _, opt := range bo {
+		for _, e := range opt.Exports {
+			names = append(names, pushDestinations(opt.Tags, e.Type, e.Attrs)...)
+		}
+	}
+	for _, t := range tgts {
+		if t.IsIndex() {
+			names = append(names, t.Tags...)
+		}
+	}
+	return uniqueNames(names)
+}
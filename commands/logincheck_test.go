@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/buildx/bake"
+	"github.com/docker/buildx/build"
+	"github.com/moby/buildkit/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBakePushDestinations(t *testing.T) {
+	index := bake.TargetTypeIndex
+	tgts := map[string]*bake.Target{
+		"app":   {Name: "app"},
+		"docs":  {Name: "docs"},
+		"local": {Name: "local"},
+		"all":   {Name: "all", Type: &index, Tags: []string{"org/app:latest"}},
+	}
+	bo := map[string]build.Options{
+		"app": {
+			Tags: []string{"org/app:amd64", "org/app:v1"},
+			Exports: []client.ExportEntry{
+				{Type: "registry"},
+			},
+		},
+		"docs": {
+			Tags: []string{"org/docs:latest"},
+			Exports: []client.ExportEntry{
+				{Type: client.ExporterImage, Attrs: map[string]string{"name": "org/docs:v1,org/docs:edge", "push": "true"}},
+			},
+		},
+		"local": {
+			Tags: []string{"org/local:latest"},
+			Exports: []client.ExportEntry{
+				{Type: client.ExporterImage, Attrs: map[string]string{"push": "false"}},
+				{Type: client.ExporterDocker},
+			},
+		},
+	}
+	require.Equal(t, []string{
+		"org/app:amd64",
+		"org/app:latest",
+		"org/app:v1",
+		"org/docs:edge",
+		"org/docs:v1",
+	}, bakePushDestinations(tgts, bo))
+}
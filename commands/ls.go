@@ -30,15 +30,18 @@ const (
 	lsLastActivityHeader   = "LAST ACTIVITY"
 	lsBuildkitHeader       = "BUILDKIT"
 	lsPlatformsHeader      = "PLATFORMS"
+	lsLatencyHeader        = "LATENCY"
 
 	lsIndent = ` \_ `
 
 	lsDefaultTableFormat = "table {{.Name}}\t{{.DriverEndpoint}}\t{{.Status}}\t{{.Buildkit}}\t{{.Platforms}}"
+	lsPingTableFormat    = "table {{.Name}}\t{{.DriverEndpoint}}\t{{.Status}}\t{{.Latency}}\t{{.Buildkit}}\t{{.Platforms}}"
 )
 
 type lsOptions struct {
 	format  string
 	noTrunc bool
+	ping    bool
 }
 
 func runLs(ctx context.Context, dockerCli command.Cli, in lsOptions) error {
@@ -76,6 +79,10 @@ func runLs(ctx context.Context, dockerCli command.Cli, in lsOptions) error {
 		return err
 	}
 
+	if in.ping {
+		pingNodes(timeoutCtx, builders)
+	}
+
 	if hasErrors, err := lsPrint(dockerCli, current, builders, in); err != nil {
 		return err
 	} else if hasErrors {
@@ -112,6 +119,7 @@ func lsCmd(dockerCli command.Cli) *cobra.Command {
 	flags := cmd.Flags()
 	flags.StringVar(&options.format, "format", formatter.TableFormatKey, "Format the output")
 	flags.BoolVar(&options.noTrunc, "no-trunc", false, "Don't truncate output")
+	flags.BoolVar(&options.ping, "ping", false, "Measure the round-trip latency to the running nodes")
 
 	// hide builder persistent flag for this command
 	cobrautil.HideInheritedFlags(cmd, "builder")
@@ -119,9 +127,33 @@ func lsCmd(dockerCli command.Cli) *cobra.Command {
 	return cmd
 }
 
+// pingNodes measures the latency to the running nodes of the builders. The
+// nodes that can't be reached are reported as errors.
+func pingNodes(ctx context.Context, builders []*builder.Builder) {
+	eg, _ := errgroup.WithContext(ctx)
+	for _, b := range builders {
+		if b.Err() != nil {
+			continue
+		}
+		nodes := b.Nodes()
+		for i := range nodes {
+			eg.Go(func() error {
+				if err := nodes[i].Ping(ctx); err != nil {
+					nodes[i].Err = err
+				}
+				return nil
+			})
+		}
+	}
+	_ = eg.Wait()
+}
+
 func lsPrint(dockerCli command.Cli, current *store.NodeGroup, builders []*builder.Builder, in lsOptions) (hasErrors bool, _ error) {
 	if in.format == formatter.TableFormatKey {
 		in.format = lsDefaultTableFormat
+		if in.ping {
+			in.format = lsPingTableFormat
+		}
 	}
 
 	ctx := formatter.Context{
@@ -189,6 +221,7 @@ func lsPrint(dockerCli command.Cli, current *store.NodeGroup, builders []*builde
 		"Status":         lsStatusHeader,
 		"Buildkit":       lsBuildkitHeader,
 		"Platforms":      lsPlatformsHeader,
+		"Latency":        lsLatencyHeader,
 	}
 
 	return hasErrors, ctx.Write(&lsCtx, render)
@@ -266,6 +299,13 @@ func (c *lsContext) Buildkit() string {
 	return c.node.Version
 }
 
+func (c *lsContext) Latency() string {
+	if c.node.Name == "" || c.node.Latency == 0 {
+		return ""
+	}
+	return c.node.Latency.Round(100 * time.Microsecond).String()
+}
+
 func (c *lsContext) Platforms() string {
 	if c.node.Name == "" {
 		return ""
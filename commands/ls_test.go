@@ -2,7 +2,11 @@ package commands
 
 import (
 	"testing"
+	"time"
 
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/store"
+	"github.com/docker/cli/cli/command/formatter"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -172,3 +176,17 @@ func TestTruncPlatforms(t *testing.T) {
 		})
 	}
 }
+
+func TestLsContextLatency(t *testing.T) {
+	c := &lsContext{
+		Builder: &lsBuilder{Builder: &builder.Builder{}},
+		format:  formatter.TableFormatKey,
+	}
+	assert.Equal(t, "", c.Latency())
+
+	c.node = builder.Node{Node: store.Node{Name: "node0"}}
+	assert.Equal(t, "", c.Latency())
+
+	c.node.Latency = 12345678 * time.Nanosecond
+	assert.Equal(t, "12.3ms", c.Latency())
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/buildkit/exporter/containerimage/exptypes"
+	"github.com/pkg/errors"
+)
+
+// metadataSection is a section of the metadata file selected with
+// --metadata-format.
+type metadataSection string
+
+const (
+	metadataSectionFull       metadataSection = "full"
+	metadataSectionDigests    metadataSection = "digests"
+	metadataSectionProvenance metadataSection = "provenance"
+	metadataSectionWarnings   metadataSection = "warnings"
+	metadataSectionLint       metadataSection = "lint"
+	metadataSectionSummary    metadataSection = "summary"
+	metadataSectionDebug      metadataSection = "debug"
+)
+
+// metadataFormat is the list of sections written to a metadata file.
+type metadataFormat []metadataSection
+
+// parseMetadataFormats parses the values of --metadata-format, each one a
+// comma-separated list of sections. The full metadata is written if none are
+// set.
+func parseMetadataFormats(in []string) ([]metadataFormat, error) {
+	if len(in) == 0 {
+		return []metadataFormat{{metadataSectionFull}}, nil
+	}
+	formats := make([]metadataFormat, 0, len(in))
+	for _, v := range in {
+		var f metadataFormat
+		for _, s := range strings.Split(v, ",") {
+			switch sec := metadataSection(strings.TrimSpace(s)); sec {
+			case metadataSectionFull, metadataSectionDigests, metadataSectionProvenance, metadataSectionWarnings, metadataSectionLint, metadataSectionSummary, metadataSectionDebug:
+				f = append(f, sec)
+			default:
+				return nil, errors.Errorf("invalid metadata format section %q, expecting full, digests, provenance, warnings, lint, summary or debug", s)
+			}
+		}
+		formats = append(formats, f)
+	}
+	return formats, nil
+}
+
+func (f metadataFormat) includes(sec metadataSection) bool {
+	for _, s := range f {
+		if s == sec || s == metadataSectionFull {
+			return true
+		}
+	}
+	return false
+}
+
+func (f metadataFormat) String() string {
+	s := make([]string, len(f))
+	for i, sec := range f {
+		s[i] = string(sec)
+	}
+	return strings.Join(s, "-")
+}
+
+// includesKey returns whether the metadata key belongs to one of the sections
+// of the format.
+func (f metadataFormat) includesKey(k string) bool {
+	if f.includes(metadataSectionFull) {
+		return true
+	}
+	switch {
+	case k == exptypes.ExporterImageDigestKey, k == exptypes.ExporterImageConfigDigestKey, k == exptypes.ExporterImageDescriptorKey, k == "image.name":
+		return f.includes(metadataSectionDigests)
+	case k == "buildx.build.provenance", strings.HasPrefix(k, "buildx.build.provenance/"):
+		return f.includes(metadataSectionProvenance)
+	case k == "buildx.build.warnings":
+		return f.includes(metadataSectionWarnings)
+	case strings.HasPrefix(k, "result."):
+		return f.includes(metadataSectionLint)
+	case k == "buildx.build.summary":
+		return f.includes(metadataSectionSummary)
+	case k == "buildx.debug.output":
+		return f.includes(metadataSectionDebug)
+	}
+	return false
+}
+
+// filter returns the metadata of the sections of the format. The metadata of
+// each target of a bake invocation is filtered too.
+func (f metadataFormat) filter(dt map[string]any) map[string]any {
+	out := make(map[string]any)
+	for k, v := range dt {
+		if m, ok := v.(map[string]any); ok {
+			if m = f.filter(m); len(m) > 0 {
+				out[k] = m
+			}
+			continue
+		}
+		if f.includesKey(k) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// anyIncludes returns whether one of the formats includes the section.
+func anyIncludes(formats []metadataFormat, sec metadataSection) bool {
+	for _, f := range formats {
+		if f.includes(sec) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyExplicitlyIncludes returns whether one of the formats explicitly lists
+// the section.
+func anyExplicitlyIncludes(formats []metadataFormat, sec metadataSection) bool {
+	for _, f := range formats {
+		for _, s := range f {
+			if s == sec {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeMetadataFiles writes the metadata for each format. With a single
+// format, the metadata is written to filename. Otherwise, the sections of
+// each format are inserted before the extension of filename.
+func writeMetadataFiles(filename string, formats []metadataFormat, dt map[string]any) error {
+	if len(formats) == 1 {
+		return writeMetadataFile(filename, formats[0].filter(dt))
+	}
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	for _, f := range formats {
+		if err := writeMetadataFile(base+"."+f.String()+ext, f.filter(dt)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMetadataFormats(t *testing.T) {
+	formats, err := parseMetadataFormats(nil)
+	require.NoError(t, err)
+	require.Equal(t, []metadataFormat{{metadataSectionFull}}, formats)
+
+	formats, err = parseMetadataFormats([]string{"digests", "provenance, warnings"})
+	require.NoError(t, err)
+	require.Equal(t, []metadataFormat{
+		{metadataSectionDigests},
+		{metadataSectionProvenance, metadataSectionWarnings},
+	}, formats)
+	require.True(t, anyIncludes(formats, metadataSectionProvenance))
+	require.False(t, anyIncludes(formats, metadataSectionSummary))
+	require.True(t, anyExplicitlyIncludes(formats, metadataSectionWarnings))
+
+	_, err = parseMetadataFormats([]string{"spdx"})
+	require.ErrorContains(t, err, `invalid metadata format section "spdx"`)
+}
+
+func TestWriteMetadataFiles(t *testing.T) {
+	dt := map[string]any{
+		"app": map[string]any{
+			"containerimage.digest":          "sha256:abc",
+			"image.name":                     "docker.io/user/app:latest",
+			"buildx.build.ref":               "builder/builder0/ref",
+			"buildx.build.provenance":        json.RawMessage(`{"buildType":"https://mobyproject.org/buildkit@v1"}`),
+			"buildx.build.provenance/arm64":  json.RawMessage(`{"buildType":"https://mobyproject.org/buildkit@v1"}`),
+			"buildx.build.resources/unknown": "foo",
+		},
+		"lint": map[string]any{
+			"result.json": json.RawMessage(`{"warnings":[]}`),
+		},
+		"buildx.build.warnings": []string{"warning"},
+	}
+
+	dir := t.TempDir()
+	read := func(name string) map[string]any {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		var m map[string]any
+		require.NoError(t, json.Unmarshal(b, &m))
+		return m
+	}
+
+	formats, err := parseMetadataFormats([]string{"digests"})
+	require.NoError(t, err)
+	require.NoError(t, writeMetadataFiles(filepath.Join(dir, "metadata.json"), formats, dt))
+	require.Equal(t, map[string]any{
+		"app": map[string]any{
+			"containerimage.digest": "sha256:abc",
+			"image.name":            "docker.io/user/app:latest",
+		},
+	}, read("metadata.json"))
+
+	formats, err = parseMetadataFormats([]string{"provenance", "lint,warnings", "full"})
+	require.NoError(t, err)
+	require.NoError(t, writeMetadataFiles(filepath.Join(dir, "out.json"), formats, dt))
+	require.Equal(t, map[string]any{
+		"app": map[string]any{
+			"buildx.build.provenance":       map[string]any{"buildType": "https://mobyproject.org/buildkit@v1"},
+			"buildx.build.provenance/arm64": map[string]any{"buildType": "https://mobyproject.org/buildkit@v1"},
+		},
+	}, read("out.provenance.json"))
+	require.Equal(t, map[string]any{
+		"lint": map[string]any{
+			"result.json": map[string]any{"warnings": []any{}},
+		},
+		"buildx.build.warnings": []any{"warning"},
+	}, read("out.lint-warnings.json"))
+	require.Len(t, read("out.full.json"), 3)
+}
@@ -0,0 +1,227 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/docker/buildx/util/ocilayout"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/go-units"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type ociLayoutAddOptions struct {
+	builder string
+	name    string
+}
+
+// ociLayoutImageName returns the name of an image in the index of a layout
+// for ref: its familiar name with its tag, without digest, so that it can be
+// used in an oci-layout://PATH:NAME reference.
+func ociLayoutImageName(ref reference.Named) string {
+	name := reference.FamiliarName(ref)
+	if tagged, ok := ref.(reference.Tagged); ok {
+		name += ":" + tagged.Tag()
+	}
+	return name
+}
+
+func runOCILayoutAdd(ctx context.Context, dockerCli command.Cli, path, image string, opts ociLayoutAddOptions) error {
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return err
+	}
+	name := opts.name
+	if name == "" {
+		name = ociLayoutImageName(ref)
+	}
+
+	b, err := builder.New(dockerCli, builder.WithName(opts.builder))
+	if err != nil {
+		return err
+	}
+	imageopt, err := b.ImageOpt()
+	if err != nil {
+		return err
+	}
+	r := imagetools.New(imageopt)
+
+	_, desc, err := r.Resolve(ctx, ref.String())
+	if err != nil {
+		return err
+	}
+
+	l, err := ocilayout.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Fetch(ctx, &imagetools.Source{Ref: ref, Desc: desc}, l.Store()); err != nil {
+		return errors.Wrapf(err, "failed to fetch %s", ref)
+	}
+	if err := l.Tag(ctx, name, desc); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(dockerCli.Out(), ocilayout.Reference(path, name, desc.Digest))
+	return nil
+}
+
+func runOCILayoutLs(dockerCli command.Cli, path string) error {
+	l, err := ocilayout.Open(path)
+	if err != nil {
+		return err
+	}
+	imgs, err := l.Images()
+	if err != nil {
+		return err
+	}
+	printOCILayoutImages(dockerCli.Out(), imgs)
+	return nil
+}
+
+func printOCILayoutImages(w io.Writer, imgs []ocilayout.Image) {
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "NAME\tDIGEST\tMEDIA TYPE")
+	for _, img := range imgs {
+		name := img.Name
+		if name == "" {
+			name = "<none>"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, img.Descriptor.Digest, img.Descriptor.MediaType)
+	}
+}
+
+func runOCILayoutVerify(ctx context.Context, dockerCli command.Cli, path string) error {
+	l, err := ocilayout.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := l.Verify(ctx); err != nil {
+		return errors.Wrapf(err, "failed to verify %s", path)
+	}
+	fmt.Fprintf(dockerCli.Err(), "All blobs of %s are valid\n", path)
+	return nil
+}
+
+type ociLayoutPruneOptions struct {
+	force bool
+}
+
+func runOCILayoutPrune(ctx context.Context, dockerCli command.Cli, path string, opts ociLayoutPruneOptions) error {
+	if !opts.force {
+		msg := fmt.Sprintf("WARNING! This will remove all the blobs of %s not referenced by its images. Are you sure you want to continue?", path)
+		if ok, err := prompt(ctx, dockerCli.In(), dockerCli.Out(), msg); err != nil {
+			return err
+		} else if !ok {
+			return nil
+		}
+	}
+
+	l, err := ocilayout.Open(path)
+	if err != nil {
+		return err
+	}
+	res, err := l.Prune(ctx)
+	if err != nil {
+		return err
+	}
+	for _, dgst := range res.Blobs {
+		fmt.Fprintln(dockerCli.Out(), dgst)
+	}
+	fmt.Fprintf(dockerCli.Err(), "Total reclaimed space: %s\n", units.HumanSize(float64(res.Size)))
+	return nil
+}
+
+func ociLayoutCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "oci-layout",
+		Short:             "Manage OCI layout directories used as named contexts",
+		ValidArgsFunction: completion.Disable,
+	}
+
+	cmd.AddCommand(
+		ociLayoutAddCmd(dockerCli, rootOpts),
+		ociLayoutLsCmd(dockerCli),
+		ociLayoutVerifyCmd(dockerCli),
+		ociLayoutPruneCmd(dockerCli),
+	)
+
+	return cmd
+}
+
+func ociLayoutAddCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options ociLayoutAddOptions
+
+	cmd := &cobra.Command{
+		Use:   "add [OPTIONS] PATH IMAGE",
+		Short: "Add an image from a registry to an OCI layout",
+		Args:  cli.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			return runOCILayoutAdd(cmd.Context(), dockerCli, args[0], args[1], options)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&options.name, "name", "", "Name of the image in the index of the layout")
+
+	return cmd
+}
+
+func ociLayoutLsCmd(dockerCli command.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ls PATH",
+		Short: "List the images of an OCI layout",
+		Args:  cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOCILayoutLs(dockerCli, args[0])
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	return cmd
+}
+
+func ociLayoutVerifyCmd(dockerCli command.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify PATH",
+		Short: "Verify the digests of the blobs of an OCI layout",
+		Args:  cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOCILayoutVerify(cmd.Context(), dockerCli, args[0])
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	return cmd
+}
+
+func ociLayoutPruneCmd(dockerCli command.Cli) *cobra.Command {
+	var options ociLayoutPruneOptions
+
+	cmd := &cobra.Command{
+		Use:   "prune [OPTIONS] PATH",
+		Short: "Remove the blobs of an OCI layout not referenced by its images",
+		Args:  cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOCILayoutPrune(cmd.Context(), dockerCli, args[0], options)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVarP(&options.force, "force", "f", false, "Do not prompt for confirmation")
+
+	return cmd
+}
@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOCILayoutImageName(t *testing.T) {
+	for in, expected := range map[string]string{
+		"alpine":                        "alpine",
+		"alpine:3.20":                   "alpine:3.20",
+		"docker.io/library/alpine:3.20": "alpine:3.20",
+		"ghcr.io/user/app:v1@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef": "ghcr.io/user/app:v1",
+		"user/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef":            "user/app",
+	} {
+		ref, err := reference.ParseNormalizedNamed(in)
+		require.NoError(t, err)
+		require.Equal(t, expected, ociLayoutImageName(ref), in)
+	}
+}
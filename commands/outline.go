@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/subrequests/outline"
+)
+
+// outlineTarget is the outline of a build target along with the bake
+// definition fields that describe where it's built from.
+type outlineTarget struct {
+	Name        string
+	Description string
+	Context     string
+	Dockerfile  string
+	Stage       string
+	Platforms   []string
+	Outline     outline.Outline
+}
+
+func parseOutline(res map[string]string) (outline.Outline, error) {
+	var o outline.Outline
+	if dt, ok := res["result.json"]; ok {
+		if err := json.Unmarshal([]byte(dt), &o); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
+
+// writeOutlineMarkdown writes the outlines of the targets as a single
+// Markdown document.
+func writeOutlineMarkdown(w io.Writer, targets []outlineTarget) error {
+	var b strings.Builder
+	b.WriteString("# Build targets\n\n")
+	if len(targets) > 1 {
+		for _, t := range targets {
+			fmt.Fprintf(&b, "- [%s](#%s)\n", t.Name, strings.ToLower(t.Name))
+		}
+		b.WriteString("\n")
+	}
+
+	for _, t := range targets {
+		fmt.Fprintf(&b, "## %s\n\n", t.Name)
+		description := t.Description
+		if description == "" {
+			description = t.Outline.Description
+		}
+		if description != "" {
+			fmt.Fprintf(&b, "%s\n\n", description)
+		}
+
+		stage := t.Stage
+		if stage == "" {
+			stage = t.Outline.Name
+		}
+		var props []string
+		if t.Context != "" {
+			props = append(props, fmt.Sprintf("- Context: `%s`", t.Context))
+		}
+		if t.Dockerfile != "" {
+			props = append(props, fmt.Sprintf("- Dockerfile: `%s`", t.Dockerfile))
+		}
+		if stage != "" {
+			props = append(props, fmt.Sprintf("- Stage: `%s`", stage))
+		}
+		if len(t.Platforms) > 0 {
+			props = append(props, fmt.Sprintf("- Platforms: `%s`", strings.Join(t.Platforms, "`, `")))
+		}
+		if len(props) > 0 {
+			b.WriteString(strings.Join(props, "\n") + "\n\n")
+		}
+
+		if len(t.Outline.Args) > 0 {
+			b.WriteString("### Build arguments\n\n")
+			b.WriteString("| Name | Default | Description |\n")
+			b.WriteString("|------|---------|-------------|\n")
+			for _, a := range t.Outline.Args {
+				fmt.Fprintf(&b, "| `%s` | %s | %s |\n", a.Name, markdownCode(a.Value), markdownCell(a.Description))
+			}
+			b.WriteString("\n")
+		}
+		if len(t.Outline.Secrets) > 0 {
+			b.WriteString("### Secrets\n\n")
+			b.WriteString("| ID | Required |\n")
+			b.WriteString("|----|----------|\n")
+			for _, s := range t.Outline.Secrets {
+				fmt.Fprintf(&b, "| `%s` | %s |\n", s.Name, markdownBool(s.Required))
+			}
+			b.WriteString("\n")
+		}
+		if len(t.Outline.SSH) > 0 {
+			b.WriteString("### SSH\n\n")
+			b.WriteString("| ID | Required |\n")
+			b.WriteString("|----|----------|\n")
+			for _, s := range t.Outline.SSH {
+				fmt.Fprintf(&b, "| `%s` | %s |\n", s.Name, markdownBool(s.Required))
+			}
+			b.WriteString("\n")
+		}
+		if len(t.Outline.Cache) > 0 {
+			b.WriteString("### Cache mounts\n\n")
+			for _, c := range t.Outline.Cache {
+				fmt.Fprintf(&b, "- `%s`\n", c.ID)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, strings.TrimSuffix(b.String(), "\n"))
+	return err
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
+
+func markdownBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
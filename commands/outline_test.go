@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/moby/buildkit/frontend/subrequests/outline"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOutlineMarkdown(t *testing.T) {
+	o, err := parseOutline(map[string]string{
+		"result.json": `{"name":"release","description":"is the release stage","args":[{"name":"GO_VERSION","value":"1.22","description":"sets the Go version | base"},{"name":"TOKEN"}],"secrets":[{"name":"github_token","required":true}],"ssh":[{"name":"default"}]}`,
+	})
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	err = writeOutlineMarkdown(buf, []outlineTarget{
+		{
+			Name:       "app",
+			Context:    ".",
+			Dockerfile: "Dockerfile",
+			Platforms:  []string{"linux/amd64", "linux/arm64"},
+			Outline:    o,
+		},
+		{
+			Name:        "docs",
+			Description: "Documentation site",
+			Stage:       "docs",
+			Outline:     outline.Outline{Cache: []outline.CacheMount{{ID: "/root/.npm"}}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "# Build targets\n"+
+		"\n"+
+		"- [app](#app)\n"+
+		"- [docs](#docs)\n"+
+		"\n"+
+		"## app\n"+
+		"\n"+
+		"is the release stage\n"+
+		"\n"+
+		"- Context: `.`\n"+
+		"- Dockerfile: `Dockerfile`\n"+
+		"- Stage: `release`\n"+
+		"- Platforms: `linux/amd64`, `linux/arm64`\n"+
+		"\n"+
+		"### Build arguments\n"+
+		"\n"+
+		"| Name | Default | Description |\n"+
+		"|------|---------|-------------|\n"+
+		"| `GO_VERSION` | `1.22` | sets the Go version \\| base |\n"+
+		"| `TOKEN` |  |  |\n"+
+		"\n"+
+		"### Secrets\n"+
+		"\n"+
+		"| ID | Required |\n"+
+		"|----|----------|\n"+
+		"| `github_token` | yes |\n"+
+		"\n"+
+		"### SSH\n"+
+		"\n"+
+		"| ID | Required |\n"+
+		"|----|----------|\n"+
+		"| `default` | no |\n"+
+		"\n"+
+		"## docs\n"+
+		"\n"+
+		"Documentation site\n"+
+		"\n"+
+		"- Stage: `docs`\n"+
+		"\n"+
+		"### Cache mounts\n"+
+		"\n"+
+		"- `/root/.npm`\n", buf.String())
+}
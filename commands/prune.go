@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/driver"
+	"github.com/docker/buildx/store/storeutil"
 	"github.com/docker/buildx/util/cobrautil/completion"
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
@@ -53,6 +55,9 @@ func runPrune(ctx context.Context, dockerCli command.Cli, opts pruneOptions) err
 	if opts.all {
 		warning = allCacheWarning
 	}
+	if opts.builder == pruneAllBuilders {
+		warning = strings.Replace(warning, "build cache", "build cache of all active builders", 1)
+	}
 
 	if !opts.force {
 		if ok, err := prompt(ctx, dockerCli.In(), dockerCli.Out(), warning); err != nil {
@@ -62,18 +67,25 @@ func runPrune(ctx context.Context, dockerCli command.Cli, opts pruneOptions) err
 		}
 	}
 
-	b, err := builder.New(dockerCli, builder.WithName(opts.builder))
-	if err != nil {
-		return err
-	}
-
-	nodes, err := b.LoadNodes(ctx)
-	if err != nil {
-		return err
-	}
-	for _, node := range nodes {
-		if node.Err != nil {
-			return node.Err
+	var nodes []builder.Node
+	if opts.builder == pruneAllBuilders {
+		nodes, err = loadActiveNodes(ctx, dockerCli)
+		if err != nil {
+			return err
+		}
+	} else {
+		b, err := builder.New(dockerCli, builder.WithName(opts.builder))
+		if err != nil {
+			return err
+		}
+		nodes, err = b.LoadNodes(ctx)
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if node.Err != nil {
+				return node.Err
+			}
 		}
 	}
 
@@ -147,6 +159,43 @@ func runPrune(ctx context.Context, dockerCli command.Cli, opts pruneOptions) err
 	return nil
 }
 
+// pruneAllBuilders is the builder name that prunes the cache of all builders.
+const pruneAllBuilders = "all"
+
+// loadActiveNodes returns the running nodes of all the builders. Inactive
+// builders are skipped, so that pruning doesn't boot them.
+func loadActiveNodes(ctx context.Context, dockerCli command.Cli) ([]builder.Node, error) {
+	txn, release, err := storeutil.GetStore(dockerCli)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	builders, err := builder.GetBuilders(dockerCli, txn)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []builder.Node
+	for _, b := range builders {
+		bnodes, err := b.LoadNodes(ctx, builder.WithData())
+		if err != nil {
+			_, _ = fmt.Fprintf(dockerCli.Err(), "Skipping builder %s: %s\n", b.Name, strings.TrimSpace(err.Error()))
+			continue
+		}
+		for _, node := range bnodes {
+			if node.Err != nil {
+				_, _ = fmt.Fprintf(dockerCli.Err(), "Skipping node %s of builder %s: %s\n", node.Name, b.Name, strings.TrimSpace(node.Err.Error()))
+				continue
+			}
+			if node.DriverInfo != nil && node.DriverInfo.Status == driver.Running {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return nodes, nil
+}
+
 func loadLLBCaps(ctx context.Context, c *client.Client) (apicaps.CapSet, error) {
 	var caps apicaps.CapSet
 	_, err := c.Build(ctx, client.SolveOpt{
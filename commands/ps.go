@@ -0,0 +1,228 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/localstate"
+	"github.com/docker/buildx/store/storeutil"
+	"github.com/docker/buildx/util/cobrautil"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/buildx/util/confutil"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type psOptions struct {
+	builder string
+	all     bool
+	format  string
+	cancel  string
+	reason  string
+}
+
+// runningBuild is a build in progress on a node of a builder.
+type runningBuild struct {
+	Ref       string    `json:"ref"`
+	Builder   string    `json:"builder"`
+	Node      string    `json:"node"`
+	Target    string    `json:"target,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Steps     int32     `json:"steps"`
+	Completed int32     `json:"completedSteps"`
+	// Client is the host that started the build, which is only known for
+	// the builds started from this host.
+	Client    string `json:"client,omitempty"`
+	LocalPath string `json:"localPath,omitempty"`
+}
+
+func runPs(ctx context.Context, dockerCli command.Cli, in psOptions) error {
+	switch in.format {
+	case "", "table", "json":
+	default:
+		return errors.Errorf("unsupported format %q, use table or json", in.format)
+	}
+	if in.cancel != "" {
+		return runCancel(ctx, dockerCli, cancelOptions{
+			ref:         in.cancel,
+			reason:      in.reason,
+			gracePeriod: 10 * time.Second,
+		})
+	}
+
+	var builders []*builder.Builder
+	if in.all {
+		txn, release, err := storeutil.GetStore(dockerCli)
+		if err != nil {
+			return err
+		}
+		defer release()
+		builders, err = builder.GetBuilders(dockerCli, txn)
+		if err != nil {
+			return err
+		}
+	} else {
+		b, err := builder.New(dockerCli, builder.WithName(in.builder))
+		if err != nil {
+			return err
+		}
+		builders = []*builder.Builder{b}
+	}
+
+	ls, err := localstate.New(confutil.NewConfig(dockerCli))
+	if err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+
+	var builds []runningBuild
+	for _, b := range builders {
+		nodes, err := b.LoadNodes(ctx)
+		if err != nil {
+			if !in.all {
+				return err
+			}
+			fmt.Fprintf(dockerCli.Err(), "Cannot load builder %s: %v\n", b.Name, err)
+			continue
+		}
+		for _, node := range nodes {
+			if node.Err != nil || node.Driver == nil {
+				continue
+			}
+			recs, err := loadRunningRecords(ctx, node)
+			if err != nil {
+				fmt.Fprintf(dockerCli.Err(), "Cannot list builds of %s (%s): %v\n", b.Name, node.Name, err)
+				continue
+			}
+			for _, rec := range recs {
+				st, _ := ls.ReadRef(b.Name, node.Name, rec.Ref)
+				builds = append(builds, newRunningBuild(b.Name, node.Name, rec, st, host))
+			}
+		}
+	}
+	sortRunningBuilds(builds)
+
+	if in.format == "json" {
+		enc := json.NewEncoder(dockerCli.Out())
+		enc.SetIndent("", "  ")
+		return enc.Encode(builds)
+	}
+	printRunningBuilds(dockerCli.Out(), builds, time.Now())
+	return nil
+}
+
+// loadRunningRecords returns the records of the builds in progress on a node.
+func loadRunningRecords(ctx context.Context, node builder.Node) ([]*controlapi.BuildHistoryRecord, error) {
+	c, err := node.Driver.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cl, err := c.ControlClient().ListenBuildHistory(ctx, &controlapi.BuildHistoryRequest{
+		ActiveOnly: true,
+		EarlyExit:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var recs []*controlapi.BuildHistoryRecord
+	for {
+		ev, err := cl.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return recs, nil
+			}
+			return nil, err
+		}
+		if ev.Record != nil && ev.Record.CompletedAt == nil {
+			recs = append(recs, ev.Record)
+		}
+	}
+}
+
+// newRunningBuild returns the build of a record, completed by the local state
+// saved when the build was started from this host.
+func newRunningBuild(builderName, nodeName string, rec *controlapi.BuildHistoryRecord, st *localstate.State, host string) runningBuild {
+	rb := runningBuild{
+		Ref:       rec.Ref,
+		Builder:   builderName,
+		Node:      nodeName,
+		Target:    rec.FrontendAttrs["target"],
+		Steps:     rec.NumTotalSteps,
+		Completed: rec.NumCompletedSteps,
+	}
+	if rec.CreatedAt != nil {
+		rb.CreatedAt = rec.CreatedAt.AsTime()
+	}
+	if st != nil {
+		if st.Target != "" {
+			rb.Target = st.Target
+		}
+		rb.LocalPath = st.LocalPath
+		rb.Client = host
+	}
+	return rb
+}
+
+// sortRunningBuilds sorts the builds by start time, oldest first.
+func sortRunningBuilds(builds []runningBuild) {
+	sort.SliceStable(builds, func(i, j int) bool {
+		if !builds[i].CreatedAt.Equal(builds[j].CreatedAt) {
+			return builds[i].CreatedAt.Before(builds[j].CreatedAt)
+		}
+		return builds[i].Ref < builds[j].Ref
+	})
+}
+
+func printRunningBuilds(w io.Writer, builds []runningBuild, now time.Time) {
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	fmt.Fprintln(tw, "REF\tBUILDER\tNODE\tTARGET\tELAPSED\tSTEPS\tCLIENT")
+	for _, b := range builds {
+		target, client := b.Target, b.Client
+		if target == "" {
+			target = "-"
+		}
+		if client == "" {
+			client = "-"
+		}
+		elapsed := "-"
+		if !b.CreatedAt.IsZero() {
+			elapsed = now.Sub(b.CreatedAt).Round(time.Second).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d/%d\t%s\n", b.Ref, b.Builder, b.Node, target, elapsed, b.Completed, b.Steps, client)
+	}
+	tw.Flush()
+}
+
+func psCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options psOptions
+
+	cmd := &cobra.Command{
+		Use:   "ps [OPTIONS]",
+		Short: "List the builds in progress",
+		Args:  cli.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			return runPs(cmd.Context(), dockerCli, options)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVarP(&options.all, "all", "a", false, "List the builds of all builders")
+	flags.StringVar(&options.format, "format", "table", `Format of the output ("table", "json")`)
+	flags.StringVar(&options.cancel, "cancel", "", "Cancel the build in progress in the given session of the buildx server")
+	flags.StringVar(&options.reason, "reason", "", "Reason of the cancellation returned as the error of the build")
+	cobrautil.MarkFlagsExperimental(flags, "cancel", "reason")
+
+	return cmd
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/docker/buildx/localstate"
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestRunningBuilds(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	recs := []*controlapi.BuildHistoryRecord{
+		{
+			Ref:               "k2xgp6o5xdbmjz5q8dwqmtj2a",
+			FrontendAttrs:     map[string]string{"target": "release"},
+			CreatedAt:         timestamppb.New(now.Add(-30 * time.Second)),
+			NumTotalSteps:     12,
+			NumCompletedSteps: 4,
+		},
+		{
+			Ref:               "qs7zv9ma8l6bhfdxnnf2rf5ul",
+			CreatedAt:         timestamppb.New(now.Add(-5 * time.Minute)),
+			NumTotalSteps:     8,
+			NumCompletedSteps: 7,
+		},
+	}
+
+	builds := []runningBuild{
+		newRunningBuild("shared", "shared0", recs[0], nil, "laptop"),
+		newRunningBuild("shared", "shared0", recs[1], &localstate.State{Target: "app", LocalPath: "/src/app"}, "laptop"),
+	}
+	require.Equal(t, "release", builds[0].Target)
+	require.Empty(t, builds[0].Client, "client is only known for the builds of this host")
+	require.Equal(t, "app", builds[1].Target)
+	require.Equal(t, "laptop", builds[1].Client)
+	require.Equal(t, "/src/app", builds[1].LocalPath)
+
+	sortRunningBuilds(builds)
+	require.Equal(t, "qs7zv9ma8l6bhfdxnnf2rf5ul", builds[0].Ref)
+
+	var buf bytes.Buffer
+	printRunningBuilds(&buf, builds, now)
+	require.Equal(t, `REF				BUILDER	NODE	TARGET	ELAPSED	STEPS	CLIENT
+qs7zv9ma8l6bhfdxnnf2rf5ul	shared	shared0	app	5m0s	7/8	laptop
+k2xgp6o5xdbmjz5q8dwqmtj2a	shared	shared0	release	30s	4/12	-
+`, buf.String())
+}
@@ -0,0 +1,619 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containerd/platforms"
+	"github.com/docker/buildx/build"
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/docker/buildx/util/cobrautil"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/buildx/version"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// reproBundleVersion is the version of the format of the repro bundles.
+const reproBundleVersion = 1
+
+// reproRedacted replaces the parts of the arguments that look like secrets.
+const reproRedacted = "<redacted>"
+
+// reproBundle is everything needed to reproduce a build, without the values
+// of the environment variables nor the arguments that look like secrets.
+type reproBundle struct {
+	Version   int           `json:"version"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Command   string        `json:"command"`
+	Args      []string      `json:"args"`
+	Redacted  bool          `json:"redacted,omitempty"`
+	Env       []string      `json:"env,omitempty"`
+	Context   *reproContext `json:"context,omitempty"`
+	Builder   *reproBuilder `json:"builder,omitempty"`
+	Versions  reproVersions `json:"versions"`
+}
+
+// reproContext is the listing of the local build context.
+type reproContext struct {
+	Path  string      `json:"path"`
+	Files []reproFile `json:"files"`
+}
+
+type reproFile struct {
+	Path   string        `json:"path"`
+	Mode   fs.FileMode   `json:"mode"`
+	Size   int64         `json:"size"`
+	Digest digest.Digest `json:"digest,omitempty"`
+}
+
+// reproBuilder describes the builder the build ran on. The endpoints of the
+// nodes are not recorded.
+type reproBuilder struct {
+	Name   string      `json:"name"`
+	Driver string      `json:"driver"`
+	Nodes  []reproNode `json:"nodes,omitempty"`
+	Err    string      `json:"error,omitempty"`
+}
+
+type reproNode struct {
+	Name      string   `json:"name"`
+	Version   string   `json:"version,omitempty"`
+	Platforms []string `json:"platforms,omitempty"`
+	Err       string   `json:"error,omitempty"`
+}
+
+type reproVersions struct {
+	Buildx   string `json:"buildx"`
+	Revision string `json:"revision,omitempty"`
+	Go       string `json:"go"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Docker   string `json:"docker,omitempty"`
+}
+
+type reproBundleOptions struct {
+	builder string
+	output  string
+}
+
+type reproReplayOptions struct {
+	file   string
+	dryRun bool
+	force  bool
+}
+
+func runReproBundle(ctx context.Context, dockerCli command.Cli, rootOpts *rootOptions, in reproBundleOptions, args []string) error {
+	name, cmdArgs := args[0], args[1:]
+	contextPath, err := reproContextPath(dockerCli, rootOpts, name, cmdArgs)
+	if err != nil {
+		return err
+	}
+
+	patterns, err := buildflags.DefaultSecretArgPatterns()
+	if err != nil {
+		return err
+	}
+	sanitized, redacted := sanitizeReproArgs(cmdArgs, patterns)
+
+	b := &reproBundle{
+		Version:   reproBundleVersion,
+		CreatedAt: time.Now().UTC(),
+		Command:   name,
+		Args:      sanitized,
+		Redacted:  redacted,
+		Env:       reproEnvNames(os.Environ(), cmdArgs),
+		Versions: reproVersions{
+			Buildx:   version.Version,
+			Revision: version.Revision,
+			Go:       runtime.Version(),
+			OS:       runtime.GOOS,
+			Arch:     runtime.GOARCH,
+		},
+	}
+	if v, err := dockerCli.Client().ServerVersion(ctx); err == nil {
+		b.Versions.Docker = v.Version
+	}
+	if contextPath != "" {
+		files, err := listReproContext(contextPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list build context %s", contextPath)
+		}
+		b.Context = &reproContext{Path: contextPath, Files: files}
+	}
+	b.Builder = loadReproBuilder(ctx, dockerCli, in.builder)
+
+	dt, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	dt = append(dt, '\n')
+	if in.output == "-" {
+		_, err = dockerCli.Out().Write(dt)
+		return err
+	}
+	if err := os.WriteFile(in.output, dt, 0644); err != nil {
+		return err
+	}
+	if redacted {
+		fmt.Fprintf(dockerCli.Err(), "Arguments that look like secrets were redacted from %s\n", in.output)
+	}
+	return nil
+}
+
+// reproContextPath returns the local context directory of a build or bake
+// invocation, or an empty string if the context is not local.
+func reproContextPath(dockerCli command.Cli, rootOpts *rootOptions, name string, args []string) (string, error) {
+	switch name {
+	case "build":
+		c := buildCmd(dockerCli, rootOpts, nil)
+		if err := c.ParseFlags(args); err != nil {
+			return "", err
+		}
+		pos := c.Flags().Args()
+		if len(pos) != 1 {
+			return "", errors.New("build requires exactly 1 argument")
+		}
+		if pos[0] == "-" || build.IsRemoteURL(pos[0]) {
+			return "", nil
+		}
+		return pos[0], nil
+	case "bake":
+		c := bakeCmd(dockerCli, rootOpts)
+		if err := c.ParseFlags(args); err != nil {
+			return "", err
+		}
+		if url, _, _ := bakeArgs(c.Flags().Args()); url != "" {
+			return "", nil
+		}
+		return ".", nil
+	}
+	return "", errors.Errorf("unsupported command %q, expecting build or bake", name)
+}
+
+// reproSecretKeys are the parts of the names of the build arguments whose
+// values are always redacted.
+var reproSecretKeys = []string{"PASSWORD", "PASSWD", "TOKEN", "SECRET", "CREDENTIAL", "API_KEY", "APIKEY", "ACCESS_KEY", "PRIVATE_KEY"}
+
+// sanitizeReproArgs redacts the values of the build arguments whose name
+// looks like a secret, the secrets read from a value that is not the name of
+// an environment variable, and the parts of the arguments matching the secret
+// patterns. It returns whether anything was redacted.
+func sanitizeReproArgs(args []string, patterns []buildflags.SecretArgPattern) ([]string, bool) {
+	out := make([]string, len(args))
+	var redacted bool
+	var flag string
+	for i, arg := range args {
+		name, value := flag, arg
+		if flag == "" {
+			if k, v, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(k, "--") {
+				name, value = k, v
+			}
+		}
+		if v, ok := sanitizeReproFlag(name, value); ok {
+			arg = strings.TrimSuffix(arg, value) + v
+			redacted = true
+		}
+		for _, p := range patterns {
+			if p.Regexp.MatchString(arg) {
+				arg = p.Regexp.ReplaceAllString(arg, reproRedacted)
+				redacted = true
+			}
+		}
+		out[i] = arg
+
+		flag = ""
+		switch arg {
+		case "--build-arg", "--set", "--secret":
+			flag = arg
+		}
+	}
+	return out, redacted
+}
+
+// sanitizeReproFlag returns the redacted value of a flag, if any part of it
+// has to be redacted.
+func sanitizeReproFlag(name, value string) (string, bool) {
+	switch name {
+	case "--build-arg", "--set":
+		k, _, ok := strings.Cut(value, "=")
+		if !ok {
+			return "", false
+		}
+		arg := k
+		if name == "--set" {
+			// only the args of bake targets hold build arguments
+			if _, arg, ok = strings.Cut(k, ".args."); !ok {
+				return "", false
+			}
+		}
+		if isReproSecretKey(arg) {
+			return k + "=" + reproRedacted, true
+		}
+	case "--secret":
+		fields := strings.Split(value, ",")
+		var redacted bool
+		for i, f := range fields {
+			k, v, _ := strings.Cut(f, "=")
+			if k != "env" && !strings.HasPrefix(v, "env://") {
+				continue
+			}
+			env := strings.TrimPrefix(v, "env://")
+			if _, ok := os.LookupEnv(env); ok || reproEnvName.MatchString(env) {
+				continue
+			}
+			// not the name of an environment variable, so it may be the
+			// value of the secret itself
+			fields[i] = k + "=" + reproRedacted
+			redacted = true
+		}
+		if redacted {
+			return strings.Join(fields, ","), true
+		}
+	}
+	return "", false
+}
+
+// reproEnvName matches the conventional names of environment variables.
+var reproEnvName = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+func isReproSecretKey(k string) bool {
+	k = strings.ToUpper(k)
+	for _, s := range reproSecretKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// reproEnvPrefixes are the prefixes of the environment variables affecting
+// the builds that are recorded in a bundle.
+var reproEnvPrefixes = []string{"BUILDX_", "BUILDKIT_", "DOCKER_", "COMPOSE_", "BAKE_", "SOURCE_DATE_EPOCH"}
+
+// reproEnvNames returns the sorted names of the environment variables that
+// have one of the recorded prefixes or are referenced by the arguments, such
+// as the name of a build argument or a secret read from the environment.
+func reproEnvNames(env []string, args []string) []string {
+	words := map[string]struct{}{}
+	for _, arg := range args {
+		for _, w := range strings.FieldsFunc(arg, func(r rune) bool {
+			return !(r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+		}) {
+			words[w] = struct{}{}
+		}
+	}
+	var names []string
+	for _, e := range env {
+		k, _, _ := strings.Cut(e, "=")
+		if k == "" {
+			continue
+		}
+		_, ok := words[k]
+		for _, p := range reproEnvPrefixes {
+			ok = ok || strings.HasPrefix(k, p)
+		}
+		if ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// listReproContext lists the files of a context directory that are not
+// excluded by its .dockerignore file, with the digest of the regular files.
+func listReproContext(dir string) ([]reproFile, error) {
+	var pm *patternmatcher.PatternMatcher
+	if dt, err := os.ReadFile(filepath.Join(dir, ".dockerignore")); err == nil {
+		patterns, err := ignorefile.ReadAll(bytes.NewReader(dt))
+		if err != nil {
+			return nil, err
+		}
+		if pm, err = patternmatcher.New(patterns); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	var files []reproFile
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() && rel == ".git" {
+			return filepath.SkipDir
+		}
+		if pm != nil {
+			if ok, err := pm.MatchesOrParentMatches(rel); err != nil {
+				return err
+			} else if ok {
+				if d.IsDir() && !pm.Exclusions() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f := reproFile{Path: rel, Mode: fi.Mode(), Size: fi.Size()}
+		if fi.Mode().IsRegular() {
+			if f.Digest, err = fileDigest(p); err != nil {
+				return err
+			}
+		}
+		files = append(files, f)
+		return nil
+	})
+	return files, err
+}
+
+func fileDigest(p string) (digest.Digest, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return digest.SHA256.FromReader(f)
+}
+
+func loadReproBuilder(ctx context.Context, dockerCli command.Cli, name string) *reproBuilder {
+	b, err := builder.New(dockerCli, builder.WithName(name))
+	if err != nil {
+		return &reproBuilder{Name: name, Err: err.Error()}
+	}
+	rb := &reproBuilder{Name: b.Name, Driver: b.Driver}
+	nodes, err := b.LoadNodes(ctx, builder.WithData())
+	if err != nil {
+		rb.Err = err.Error()
+		return rb
+	}
+	for _, n := range nodes {
+		rn := reproNode{Name: n.Name, Version: n.Version}
+		for _, p := range n.Platforms {
+			rn.Platforms = append(rn.Platforms, platforms.Format(p))
+		}
+		if n.Err != nil {
+			rn.Err = strings.TrimSpace(n.Err.Error())
+		}
+		rb.Nodes = append(rb.Nodes, rn)
+	}
+	return rb
+}
+
+func readReproBundle(r io.Reader) (*reproBundle, error) {
+	var b reproBundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, errors.Wrap(err, "invalid repro bundle")
+	}
+	if b.Version != reproBundleVersion {
+		return nil, errors.Errorf("unsupported repro bundle version %d", b.Version)
+	}
+	switch b.Command {
+	case "build", "bake":
+	default:
+		return nil, errors.Errorf("unsupported command %q in repro bundle", b.Command)
+	}
+	return &b, nil
+}
+
+// diffReproContext returns the paths of the files of the bundle that are
+// missing or changed in the current context, and the ones that were added.
+func diffReproContext(old, cur []reproFile) (missing, changed, added []string) {
+	m := make(map[string]reproFile, len(cur))
+	for _, f := range cur {
+		m[f.Path] = f
+	}
+	for _, f := range old {
+		c, ok := m[f.Path]
+		if !ok {
+			missing = append(missing, f.Path)
+			continue
+		}
+		delete(m, f.Path)
+		if c.Mode != f.Mode || c.Size != f.Size || c.Digest != f.Digest {
+			changed = append(changed, f.Path)
+		}
+	}
+	for p := range m {
+		added = append(added, p)
+	}
+	sort.Strings(added)
+	return missing, changed, added
+}
+
+func runReproReplay(ctx context.Context, dockerCli command.Cli, rootOpts *rootOptions, in reproReplayOptions) error {
+	f, err := os.Open(in.file)
+	if err != nil {
+		return err
+	}
+	b, err := readReproBundle(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	w := dockerCli.Err()
+	fmt.Fprintf(w, "Bundle captured on %s with buildx %s (%s/%s)\n", b.CreatedAt.Format(time.RFC3339), b.Versions.Buildx, b.Versions.OS, b.Versions.Arch)
+	if b.Builder != nil && b.Builder.Driver != "" {
+		fmt.Fprintf(w, "Builder %s (%s driver)\n", b.Builder.Name, b.Builder.Driver)
+	}
+	if b.Context != nil {
+		cur, err := listReproContext(b.Context.Path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list build context %s", b.Context.Path)
+		}
+		// the bundle itself may have been saved in the context
+		if rel, err := filepath.Rel(b.Context.Path, in.file); err == nil {
+			cur = slices.DeleteFunc(cur, func(f reproFile) bool {
+				return f.Path == filepath.ToSlash(rel)
+			})
+		}
+		missing, changed, added := diffReproContext(b.Context.Files, cur)
+		printReproPaths(w, "missing from the context", missing)
+		printReproPaths(w, "changed in the context", changed)
+		printReproPaths(w, "added to the context", added)
+	}
+	var unset []string
+	for _, name := range b.Env {
+		if _, ok := os.LookupEnv(name); !ok {
+			unset = append(unset, name)
+		}
+	}
+	printReproPaths(w, "environment variables not set", unset)
+	if b.Redacted {
+		fmt.Fprintf(w, "WARNING: arguments were redacted when the bundle was captured\n")
+	}
+
+	fmt.Fprintf(w, "Running: %s %s\n", b.Command, strings.Join(b.Args, " "))
+	if in.dryRun {
+		return nil
+	}
+
+	privileges, err := reproPrivileges(newReproCmd(dockerCli, rootOpts, b.Command), b.Args)
+	if err != nil {
+		return err
+	}
+	if len(privileges) > 0 && !in.force {
+		msg := "WARNING! The build of the bundle is granted the following privileges:\n  " + strings.Join(privileges, "\n  ") + "\nAre you sure you want to run it?"
+		if ok, err := prompt(ctx, dockerCli.In(), dockerCli.Out(), msg); err != nil {
+			return err
+		} else if !ok {
+			return nil
+		}
+	}
+
+	c := newReproCmd(dockerCli, rootOpts, b.Command)
+	c.SilenceUsage = true
+	c.SilenceErrors = true
+	c.SetArgs(b.Args)
+	return c.ExecuteContext(ctx)
+}
+
+func newReproCmd(dockerCli command.Cli, rootOpts *rootOptions, name string) *cobra.Command {
+	if name == "bake" {
+		return bakeCmd(dockerCli, rootOpts)
+	}
+	return buildCmd(dockerCli, rootOpts, nil)
+}
+
+// reproPrivileges returns the privileges granted by the arguments of a
+// replayed command, which have to be confirmed before running it: the
+// entitlements it allows and the remote bake definition it reads.
+func reproPrivileges(c *cobra.Command, args []string) ([]string, error) {
+	if err := c.ParseFlags(args); err != nil {
+		return nil, err
+	}
+	var privileges []string
+	if f := c.Flags().Lookup("allow"); f != nil {
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			for _, e := range v.GetSlice() {
+				privileges = append(privileges, "entitlement "+e)
+			}
+		}
+	}
+	if c.Name() == "bake" {
+		if url, _, _ := bakeArgs(c.Flags().Args()); url != "" {
+			privileges = append(privileges, "remote bake definition "+url)
+		}
+	}
+	return privileges, nil
+}
+
+func printReproPaths(w io.Writer, title string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%d %s:\n", len(paths), title)
+	for _, p := range paths {
+		fmt.Fprintf(w, "  %s\n", p)
+	}
+}
+
+func reproCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "repro",
+		Short:             "Capture and replay builds to reproduce failures",
+		ValidArgsFunction: completion.Disable,
+	}
+	cobrautil.MarkCommandExperimental(cmd)
+
+	cmd.AddCommand(
+		reproBundleCmd(dockerCli, rootOpts),
+		reproReplayCmd(dockerCli, rootOpts),
+	)
+
+	return cmd
+}
+
+func reproBundleCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options reproBundleOptions
+
+	cmd := &cobra.Command{
+		Use:   "bundle [OPTIONS] build|bake [ARGS...]",
+		Short: "Capture what is needed to reproduce a build",
+		Args:  cli.RequiresMinArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			return runReproBundle(cmd.Context(), dockerCli, rootOpts, options, args)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	flags := cmd.Flags()
+	flags.SetInterspersed(false)
+	flags.StringVarP(&options.output, "output", "o", "buildx-repro.json", `Write the bundle to a file ("-" for stdout)`)
+
+	return cmd
+}
+
+func reproReplayCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options reproReplayOptions
+
+	cmd := &cobra.Command{
+		Use:   "replay [OPTIONS] BUNDLE",
+		Short: "Run the build captured in a bundle",
+		Args:  cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.file = args[0]
+			return runReproReplay(cmd.Context(), dockerCli, rootOpts, options)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVar(&options.dryRun, "dry-run", false, "Compare the bundle with the current environment without running the build")
+	flags.BoolVarP(&options.force, "force", "f", false, "Do not prompt for confirmation of the entitlements and remote bake definition of the build")
+
+	return cmd
+}
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/buildx/util/buildflags"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeReproArgs(t *testing.T) {
+	patterns, err := buildflags.DefaultSecretArgPatterns()
+	require.NoError(t, err)
+
+	token := "ghp_" + strings.Repeat("a1B2", 9)
+	args, redacted := sanitizeReproArgs([]string{"--build-arg", "GH_TOKEN=" + token, "--set", "*.args.VERSION=1.2.3", "."}, patterns)
+	require.True(t, redacted)
+	require.Equal(t, []string{"--build-arg", "GH_TOKEN=<redacted>", "--set", "*.args.VERSION=1.2.3", "."}, args)
+
+	_, redacted = sanitizeReproArgs([]string{"-t", "app", "."}, patterns)
+	require.False(t, redacted)
+}
+
+func TestSanitizeReproArgsKeys(t *testing.T) {
+	t.Setenv("npm_token", "secret")
+
+	args, redacted := sanitizeReproArgs([]string{
+		"--build-arg", "DB_PASSWORD=hunter2",
+		"--build-arg=api_key=abc",
+		"--build-arg", "VERSION=1.2.3",
+		"--build-arg", "NPM_TOKEN",
+		"--set", "app.args.REGISTRY_TOKEN=abc",
+		"--set=app.tags=user/app:secret-santa",
+		"--secret", "id=npm,env=npm_token",
+		"--secret", "id=gh,env=NOT_SET_TOKEN_NAME",
+		"--secret=id=aws,env=wJalrXUtnFEMI/K7MDENG",
+		"--secret", "id=key,src=env://s3cr3t-v4lue",
+		".",
+	}, nil)
+	require.True(t, redacted)
+	require.Equal(t, []string{
+		"--build-arg", "DB_PASSWORD=<redacted>",
+		"--build-arg=api_key=<redacted>",
+		"--build-arg", "VERSION=1.2.3",
+		"--build-arg", "NPM_TOKEN",
+		"--set", "app.args.REGISTRY_TOKEN=<redacted>",
+		"--set=app.tags=user/app:secret-santa",
+		"--secret", "id=npm,env=npm_token",
+		"--secret", "id=gh,env=NOT_SET_TOKEN_NAME",
+		"--secret=id=aws,env=<redacted>",
+		"--secret", "id=key,src=<redacted>",
+		".",
+	}, args)
+}
+
+func TestReproPrivileges(t *testing.T) {
+	privileges, err := reproPrivileges(newReproCmd(nil, &rootOptions{}, "build"), []string{"--allow", "network.host,security.insecure", "-t", "app", "."})
+	require.NoError(t, err)
+	require.Equal(t, []string{"entitlement network.host", "entitlement security.insecure"}, privileges)
+
+	privileges, err = reproPrivileges(newReproCmd(nil, &rootOptions{}, "bake"), []string{"--allow=hooks", "https://github.com/user/repo.git", "app"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"entitlement hooks", "remote bake definition https://github.com/user/repo.git"}, privileges)
+
+	privileges, err = reproPrivileges(newReproCmd(nil, &rootOptions{}, "bake"), []string{"--push", "app"})
+	require.NoError(t, err)
+	require.Empty(t, privileges)
+}
+
+func TestReproEnvNames(t *testing.T) {
+	names := reproEnvNames([]string{
+		"HOME=/root",
+		"BUILDX_BUILDER=remote",
+		"GH_TOKEN=secret",
+		"NPM_TOKEN=secret",
+		"SOURCE_DATE_EPOCH=0",
+	}, []string{"--secret", "id=npm,env=NPM_TOKEN", "."})
+	require.Equal(t, []string{"BUILDX_BUILDER", "NPM_TOKEN", "SOURCE_DATE_EPOCH"}, names)
+}
+
+func TestReproContext(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM alpine\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte("secrets\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "secrets"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secrets", "key"), []byte("key"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0644))
+
+	files, err := listReproContext(dir)
+	require.NoError(t, err)
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	require.Equal(t, []string{".dockerignore", "Dockerfile", "src/main.go"}, paths)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM busybox\n"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(dir, "src", "main.go")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("app\n"), 0644))
+
+	cur, err := listReproContext(dir)
+	require.NoError(t, err)
+	missing, changed, added := diffReproContext(files, cur)
+	require.Equal(t, []string{"src/main.go"}, missing)
+	require.Equal(t, []string{"Dockerfile"}, changed)
+	require.Equal(t, []string{"README.md"}, added)
+}
+
+func TestReadReproBundle(t *testing.T) {
+	b, err := readReproBundle(strings.NewReader(`{"version":1,"command":"bake","args":["--push","app"]}`))
+	require.NoError(t, err)
+	require.Equal(t, "bake", b.Command)
+	require.Equal(t, []string{"--push", "app"}, b.Args)
+
+	_, err = readReproBundle(strings.NewReader(`{"version":2,"command":"bake"}`))
+	require.ErrorContains(t, err, "unsupported repro bundle version 2")
+	_, err = readReproBundle(strings.NewReader(`{"version":1,"command":"rm"}`))
+	require.ErrorContains(t, err, `unsupported command "rm"`)
+}
@@ -87,6 +87,23 @@ type rootOptions struct {
 	debug   bool
 }
 
+// useProjectBuilder sets the builder to the one pinned in the project
+// configuration file of the current directory, unless a builder is set with
+// --builder or BUILDX_BUILDER. An invalid file is reported and ignored.
+func (o *rootOptions) useProjectBuilder() {
+	if o.builder != "" {
+		return
+	}
+	project, err := confutil.LoadProjectConfig(".")
+	if err != nil {
+		logrus.Warnf("ignoring project config: %v", err)
+		return
+	}
+	if project != nil {
+		o.builder = project.Builder
+	}
+}
+
 func addCommands(cmd *cobra.Command, opts *rootOptions, dockerCli command.Cli) {
 	rootFlags(opts, cmd.PersistentFlags())
 
@@ -100,17 +117,29 @@ func addCommands(cmd *cobra.Command, opts *rootOptions, dockerCli command.Cli) {
 		useCmd(dockerCli, opts),
 		inspectCmd(dockerCli, opts),
 		stopCmd(dockerCli, opts),
+		updateCmd(dockerCli, opts),
+		configureCmd(dockerCli, opts),
 		installCmd(dockerCli),
 		uninstallCmd(dockerCli),
 		versionCmd(dockerCli),
+		selfUpdateCmd(dockerCli),
 		pruneCmd(dockerCli, opts),
 		duCmd(dockerCli, opts),
+		cacheMountsCmd(dockerCli, opts),
+		ociLayoutCmd(dockerCli, opts),
+		loginCheckCmd(dockerCli, opts),
+		verifyCmd(dockerCli, opts),
+		psCmd(dockerCli, opts),
 		imagetoolscmd.RootCmd(cmd, dockerCli, imagetoolscmd.RootOptions{Builder: &opts.builder}),
 	)
 	if confutil.IsExperimental() {
 		cmd.AddCommand(debugcmd.RootCmd(dockerCli,
 			newDebuggableBuild(dockerCli, opts),
 		))
+		cmd.AddCommand(cancelCmd(dockerCli))
+		cmd.AddCommand(historyCmd(dockerCli, opts))
+		cmd.AddCommand(reproCmd(dockerCli, opts))
+		cmd.AddCommand(eventsCmd(dockerCli, opts))
 		remote.AddControllerCommands(cmd, dockerCli)
 	}
 
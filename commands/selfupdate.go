@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/platforms"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/buildx/util/selfupdate"
+	"github.com/docker/buildx/version"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type selfUpdateOptions struct {
+	channel string
+	check   bool
+}
+
+func runSelfUpdate(ctx context.Context, dockerCli command.Cli, in selfUpdateOptions) error {
+	u := selfupdate.New()
+	rel, err := u.Latest(ctx, in.channel)
+	if err != nil {
+		return err
+	}
+	if !selfupdate.IsNewer(rel.TagName, version.Version) {
+		fmt.Fprintf(dockerCli.Out(), "buildx %s is up to date\n", version.Version)
+		return nil
+	}
+	if in.check {
+		fmt.Fprintf(dockerCli.Out(), "buildx %s is available (current version %s)\n", rel.TagName, version.Version)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	name := selfupdate.AssetName(rel.TagName, platforms.DefaultSpec())
+	if err := u.Install(ctx, rel, name, exe); err != nil {
+		return errors.Wrapf(err, "failed to update %s", exe)
+	}
+	fmt.Fprintf(dockerCli.Out(), "updated buildx from %s to %s in %s\n", version.Version, rel.TagName, exe)
+	return nil
+}
+
+func selfUpdateCmd(dockerCli command.Cli) *cobra.Command {
+	var options selfUpdateOptions
+
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update buildx to the latest release",
+		Args:  cli.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSelfUpdate(cmd.Context(), dockerCli, options)
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&options.channel, "channel", selfupdate.ChannelStable, `Release channel ("stable", "rc")`)
+	flags.BoolVar(&options.check, "check", false, "Only check whether a newer release is available")
+
+	return cmd
+}
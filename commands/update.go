@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/driver"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/buildx/util/progress"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/moby/buildkit/util/progress/progressui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type updateOptions struct {
+	builder string
+}
+
+func runUpdate(ctx context.Context, dockerCli command.Cli, in updateOptions) error {
+	b, err := builder.New(dockerCli,
+		builder.WithName(in.builder),
+		builder.WithSkippedValidation(),
+	)
+	if err != nil {
+		return err
+	}
+	nodes, err := b.LoadNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	var updaters []builder.Node
+	for _, node := range nodes {
+		if node.Err != nil {
+			return node.Err
+		}
+		if node.Driver == nil {
+			continue
+		}
+		if _, ok := node.Driver.Driver.(driver.Updater); ok {
+			updaters = append(updaters, node)
+		}
+	}
+	if len(updaters) == 0 {
+		return errors.Errorf("%s driver does not support updating the BuildKit image", b.Driver)
+	}
+
+	printer, err := progress.NewPrinter(context.TODO(), os.Stderr, progressui.AutoMode)
+	if err != nil {
+		return err
+	}
+	var updated []string
+	for _, node := range updaters {
+		pw := progress.WithPrefix(printer, node.Name, len(updaters) > 1)
+		var ok bool
+		ok, err = node.Driver.Driver.(driver.Updater).Update(ctx, pw.Write)
+		if err != nil {
+			break
+		}
+		if ok {
+			updated = append(updated, node.Name)
+		}
+	}
+	if err1 := printer.Wait(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+	for _, name := range updated {
+		fmt.Fprintf(dockerCli.Out(), "%s\n", name)
+	}
+	return nil
+}
+
+func updateCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options updateOptions
+
+	cmd := &cobra.Command{
+		Use:   "update [NAME]",
+		Short: "Update the BuildKit image of a builder instance",
+		Args:  cli.RequiresMaxArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			if len(args) > 0 {
+				options.builder = args[0]
+			}
+			return runUpdate(cmd.Context(), dockerCli, options)
+		},
+		ValidArgsFunction: completion.BuilderNames(dockerCli),
+	}
+
+	return cmd
+}
@@ -1,10 +1,12 @@
 package commands
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/docker/buildx/store/storeutil"
 	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/buildx/util/confutil"
 	"github.com/docker/buildx/util/dockerutil"
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
@@ -13,9 +15,10 @@ import (
 )
 
 type useOptions struct {
-	isGlobal  bool
-	isDefault bool
-	builder   string
+	isGlobal   bool
+	isDefault  bool
+	perProject bool
+	builder    string
 }
 
 func runUse(dockerCli command.Cli, in useOptions) error {
@@ -31,6 +34,9 @@ func runUse(dockerCli command.Cli, in useOptions) error {
 				return errors.Errorf("run `docker context use default` to switch to default context")
 			}
 			if in.builder == "default" || in.builder == dockerCli.CurrentContext() {
+				if in.perProject {
+					return useProjectBuilder(dockerCli, in.builder)
+				}
 				ep, err := dockerutil.GetCurrentEndpoint(dockerCli)
 				if err != nil {
 					return err
@@ -50,6 +56,10 @@ func runUse(dockerCli command.Cli, in useOptions) error {
 		return errors.Wrapf(err, "failed to find instance %q", in.builder)
 	}
 
+	if in.perProject {
+		return useProjectBuilder(dockerCli, in.builder)
+	}
+
 	ep, err := dockerutil.GetCurrentEndpoint(dockerCli)
 	if err != nil {
 		return err
@@ -57,6 +67,21 @@ func runUse(dockerCli command.Cli, in useOptions) error {
 	return txn.SetCurrent(ep, in.builder, in.isGlobal, in.isDefault)
 }
 
+// useProjectBuilder pins the builder in the project config of the current
+// directory instead of changing the current builder.
+func useProjectBuilder(dockerCli command.Cli, builder string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	fp, err := confutil.WriteProjectConfig(wd, builder)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(dockerCli.Err(), "Using builder %q for commands run in %s (set in %s)\n", builder, wd, fp)
+	return nil
+}
+
 func useCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
 	var options useOptions
 
@@ -77,6 +102,7 @@ func useCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
 	flags := cmd.Flags()
 	flags.BoolVar(&options.isGlobal, "global", false, "Builder persists context changes")
 	flags.BoolVar(&options.isDefault, "default", false, "Set builder as default for current context")
+	flags.BoolVar(&options.perProject, "builder-per-project", false, "Set builder for commands run in the current directory and its subdirectories")
 
 	return cmd
 }
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/util/cobrautil/completion"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type verifyOptions struct {
+	builder string
+	key     string
+	policy  string
+	format  string
+}
+
+func runVerify(ctx context.Context, dockerCli command.Cli, in verifyOptions, name string) error {
+	switch in.format {
+	case "", "text", "json":
+	default:
+		return errors.Errorf("unsupported format %q, use text or json", in.format)
+	}
+
+	var vopt imagetools.VerifyOpt
+	if in.key != "" {
+		key, err := imagetools.LoadPublicKey(in.key)
+		if err != nil {
+			return errors.Wrap(err, "failed to load public key")
+		}
+		vopt.Key = key
+	}
+	if in.policy != "" {
+		policy, err := imagetools.LoadVerifyPolicy(ctx, in.policy)
+		if err != nil {
+			return err
+		}
+		vopt.Policy = policy
+	}
+
+	b, err := builder.New(dockerCli, builder.WithName(in.builder))
+	if err != nil {
+		return err
+	}
+	imageopt, err := b.ImageOpt()
+	if err != nil {
+		return err
+	}
+
+	report, err := imagetools.Verify(ctx, imageopt, name, vopt)
+	if err != nil {
+		return err
+	}
+
+	if in.format == "json" {
+		enc := json.NewEncoder(dockerCli.Out())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printVerifyReport(dockerCli.Out(), report)
+	}
+	if !report.Passed() {
+		return errors.Errorf("verification of %s failed", name)
+	}
+	return nil
+}
+
+func printVerifyReport(out io.Writer, r *imagetools.VerifyReport) {
+	fmt.Fprintf(out, "Name:   %s\n", r.Name)
+	fmt.Fprintf(out, "Digest: %s\n\n", r.Digest)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PLATFORM\tCHECK\tSTATUS\tMESSAGE")
+	for _, c := range r.Checks {
+		platform := c.Platform
+		if platform == "" {
+			platform = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", platform, c.Check, strings.ToUpper(c.Status), c.Message)
+	}
+	w.Flush()
+
+	if r.Passed() {
+		fmt.Fprintln(out, "\nVerification passed")
+	} else {
+		fmt.Fprintln(out, "\nVerification failed")
+	}
+}
+
+func verifyCmd(dockerCli command.Cli, rootOpts *rootOptions) *cobra.Command {
+	var options verifyOptions
+
+	cmd := &cobra.Command{
+		Use:   "verify [OPTIONS] IMAGE",
+		Short: "Verify the attestations and signatures of an image in the registry",
+		Args:  cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.builder = rootOpts.builder
+			return runVerify(cmd.Context(), dockerCli, options, args[0])
+		},
+		ValidArgsFunction: completion.Disable,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&options.key, "key", "", "Public key to verify the cosign signatures of the image")
+	flags.StringVar(&options.policy, "policy", "", "JSON, CUE or Rego policy file setting the checks that must pass")
+	flags.StringVar(&options.format, "format", "text", `Format of the output ("text", "json")`)
+
+	return cmd
+}
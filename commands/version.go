@@ -1,32 +1,198 @@
 package commands
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"runtime"
+	"strings"
+	"time"
 
+	"github.com/docker/buildx/builder"
+	"github.com/docker/buildx/driver"
+	"github.com/docker/buildx/store/storeutil"
 	"github.com/docker/buildx/util/cobrautil"
 	"github.com/docker/buildx/util/cobrautil/completion"
 	"github.com/docker/buildx/version"
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
+	"github.com/moby/buildkit/client"
+	gateway "github.com/moby/buildkit/frontend/gateway/client"
+	gatewaypb "github.com/moby/buildkit/frontend/gateway/pb"
+	"github.com/moby/buildkit/util/apicaps"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 )
 
-func runVersion(_ command.Cli) error {
-	fmt.Println(version.Package, version.Version, version.Revision)
+type versionOptions struct {
+	json bool
+}
+
+type versionInfo struct {
+	Package   string
+	Version   string
+	Revision  string
+	GoVersion string
+	Builders  []versionBuilder
+}
+
+type versionBuilder struct {
+	Name   string
+	Driver string
+	Nodes  []versionNode `json:",omitempty"`
+	Err    string        `json:",omitempty"`
+}
+
+type versionNode struct {
+	Name     string
+	Endpoint string
+	Status   string `json:",omitempty"`
+	Version  string `json:",omitempty"`
+	// Features are the features supported by the driver of the node
+	Features map[string]bool `json:",omitempty"`
+	// FrontendCaps are the gateway capabilities enabled by BuildKit for
+	// frontends
+	FrontendCaps []string `json:",omitempty"`
+	Err          string   `json:",omitempty"`
+}
+
+func runVersion(ctx context.Context, dockerCli command.Cli, in versionOptions) error {
+	if !in.json {
+		fmt.Println(version.Package, version.Version, version.Revision)
+		return nil
+	}
+
+	info := versionInfo{
+		Package:   version.Package,
+		Version:   version.Version,
+		Revision:  version.Revision,
+		GoVersion: runtime.Version(),
+		Builders:  []versionBuilder{},
+	}
+
+	builders, err := loadVersionBuilders(ctx, dockerCli)
+	if err != nil {
+		return err
+	}
+	for _, b := range builders {
+		vb := versionBuilder{
+			Name:   b.Name,
+			Driver: b.Driver,
+		}
+		if b.Err() != nil {
+			vb.Err = strings.TrimSpace(b.Err().Error())
+			info.Builders = append(info.Builders, vb)
+			continue
+		}
+		for _, n := range b.Nodes() {
+			vn := versionNode{
+				Name:     n.Name,
+				Endpoint: n.Endpoint,
+				Version:  n.Version,
+			}
+			if n.DriverInfo != nil {
+				vn.Status = n.DriverInfo.Status.String()
+			}
+			if n.Err != nil {
+				vn.Err = strings.TrimSpace(n.Err.Error())
+			} else if n.Driver != nil {
+				vn.Features = map[string]bool{}
+				for f, ok := range n.Driver.Features(ctx) {
+					vn.Features[string(f)] = ok
+				}
+				if n.DriverInfo != nil && n.DriverInfo.Status == driver.Running {
+					caps, err := frontendCaps(ctx, n)
+					if err != nil {
+						vn.Err = strings.TrimSpace(err.Error())
+					}
+					vn.FrontendCaps = caps
+				}
+			}
+			vb.Nodes = append(vb.Nodes, vn)
+		}
+		info.Builders = append(info.Builders, vb)
+	}
+
+	dt, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(dockerCli.Out(), string(dt))
 	return nil
 }
 
+// frontendCaps returns the IDs of the gateway capabilities that BuildKit
+// enables for frontends on the node.
+func frontendCaps(ctx context.Context, n builder.Node) ([]string, error) {
+	c, err := n.Driver.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var caps []string
+	_, err = c.Build(ctx, client.SolveOpt{
+		Internal: true,
+	}, "buildx", func(ctx context.Context, c gateway.Client) (*gateway.Result, error) {
+		bopts := c.BuildOpts()
+		for _, gc := range gatewaypb.Caps.All() {
+			if bopts.Caps.Supports(apicaps.CapID(gc.ID)) == nil {
+				caps = append(caps, gc.ID)
+			}
+		}
+		return nil, nil
+	}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get frontend capabilities")
+	}
+	return caps, nil
+}
+
+func loadVersionBuilders(ctx context.Context, dockerCli command.Cli) ([]*builder.Builder, error) {
+	txn, release, err := storeutil.GetStore(dockerCli)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	builders, err := builder.GetBuilders(dockerCli, txn)
+	if err != nil {
+		return nil, err
+	}
+
+	timeoutCtx, cancel := context.WithTimeoutCause(ctx, 20*time.Second, errors.WithStack(context.DeadlineExceeded))
+	defer cancel()
+
+	eg, _ := errgroup.WithContext(timeoutCtx)
+	for _, b := range builders {
+		func(b *builder.Builder) {
+			eg.Go(func() error {
+				_, _ = b.LoadNodes(timeoutCtx, builder.WithData())
+				return nil
+			})
+		}(b)
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return builders, nil
+}
+
 func versionCmd(dockerCli command.Cli) *cobra.Command {
+	var options versionOptions
+
 	cmd := &cobra.Command{
 		Use:   "version",
 		Short: "Show buildx version information",
 		Args:  cli.ExactArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runVersion(dockerCli)
+			return runVersion(cmd.Context(), dockerCli, options)
 		},
 		ValidArgsFunction: completion.Disable,
 	}
 
+	flags := cmd.Flags()
+	flags.BoolVar(&options.json, "json", false, "Format the output as JSON, including the BuildKit version of each builder")
+
 	// hide builder persistent flag for this command
 	cobrautil.HideInheritedFlags(cmd, "builder")
 
@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/util/progress/progressui"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	warningsFormatText = "text"
+	warningsFormatJSON = "json"
+
+	warningActionError   = "error"
+	warningActionWarning = "warning"
+)
+
+// warningRulePattern matches the short message of the warnings of the build
+// checks, prefixed with the name of their rule and suffixed with their line.
+var warningRulePattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]*): (.*?)(?: \(line \d+\))?$`)
+
+type warningsOptions struct {
+	format string
+	file   string
+	failOn []string
+}
+
+func warningsFlags(options *warningsOptions, flags *pflag.FlagSet) {
+	flags.StringVar(&options.format, "warnings-format", warningsFormatText, `Format of the build warnings printed after the build ("text", "json")`)
+	flags.StringVar(&options.file, "warnings-file", "", "Write the build warnings as JSON to a file")
+	flags.StringSliceVar(&options.failOn, "fail-on", nil, `Fail if the build emits warnings of these rules ("[ACTION:]RULE")`)
+}
+
+// buildWarning is a build warning in the structured warnings output.
+type buildWarning struct {
+	Rule    string   `json:"rule,omitempty"`
+	Message string   `json:"message"`
+	Detail  []string `json:"detail,omitempty"`
+	URL     string   `json:"url,omitempty"`
+	File    string   `json:"file,omitempty"`
+	Line    int32    `json:"line,omitempty"`
+}
+
+func toBuildWarnings(warnings []client.VertexWarning) []buildWarning {
+	out := make([]buildWarning, 0, len(warnings))
+	for _, w := range warnings {
+		bw := buildWarning{
+			Message: string(w.Short),
+			URL:     w.URL,
+		}
+		if m := warningRulePattern.FindStringSubmatch(bw.Message); m != nil {
+			bw.Rule, bw.Message = m[1], m[2]
+		}
+		for _, d := range w.Detail {
+			bw.Detail = append(bw.Detail, string(d))
+		}
+		if w.SourceInfo != nil {
+			bw.File = w.SourceInfo.Filename
+		}
+		if len(w.Range) > 0 && w.Range[0].Start != nil {
+			bw.Line = w.Range[0].Start.Line
+		}
+		out = append(out, bw)
+	}
+	return out
+}
+
+// writeWarningsJSON writes the warnings as JSON, one warning per line.
+func writeWarningsJSON(w io.Writer, warnings []client.VertexWarning) error {
+	enc := json.NewEncoder(w)
+	for _, bw := range toBuildWarnings(warnings) {
+		if err := enc.Encode(bw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printBuildWarnings prints the warnings on w in the format of the
+// --warnings-format flag.
+func printBuildWarnings(w io.Writer, warnings []client.VertexWarning, mode progressui.DisplayMode, format string) {
+	if format == warningsFormatJSON {
+		writeWarningsJSON(w, warnings)
+		return
+	}
+	printWarnings(w, warnings, mode)
+}
+
+// warningPolicy holds the validated warnings options, with the rules of the
+// --fail-on flag.
+type warningPolicy struct {
+	format string
+	file   string
+	rules  []warningRule
+}
+
+type warningRule struct {
+	fail bool
+	name string
+}
+
+func (o warningsOptions) policy() (*warningPolicy, error) {
+	p := &warningPolicy{format: o.format, file: o.file}
+	switch o.format {
+	case warningsFormatText, warningsFormatJSON:
+	default:
+		return nil, errors.Errorf("invalid warnings format %q, expecting %s or %s", o.format, warningsFormatText, warningsFormatJSON)
+	}
+	for _, v := range o.failOn {
+		action, name, ok := strings.Cut(v, ":")
+		if !ok {
+			action, name = warningActionError, v
+		}
+		if action != warningActionError && action != warningActionWarning {
+			return nil, errors.Errorf("invalid fail-on %q, expecting %s or %s action", v, warningActionError, warningActionWarning)
+		}
+		if name == "" {
+			return nil, errors.Errorf("invalid fail-on %q, expecting a rule", v)
+		}
+		p.rules = append(p.rules, warningRule{fail: action == warningActionError, name: name})
+	}
+	return p, nil
+}
+
+// fails returns whether the warning fails the build. A rule for the name of
+// the warning takes precedence over "*", and a later rule over an earlier
+// one. Warnings without rule only match "*".
+func (p *warningPolicy) fails(w buildWarning) bool {
+	var matched, exact, fail bool
+	for _, r := range p.rules {
+		switch {
+		case w.Rule != "" && r.name == w.Rule:
+			matched, exact, fail = true, true, r.fail
+		case r.name == "*" && !exact:
+			matched, fail = true, r.fail
+		}
+	}
+	return matched && fail
+}
+
+// writeFile writes the warnings to the file of the --warnings-file flag.
+func (p *warningPolicy) writeFile(warnings []client.VertexWarning) error {
+	if p.file == "" {
+		return nil
+	}
+	f, err := os.Create(p.file)
+	if err != nil {
+		return errors.Wrap(err, "failed to write warnings file")
+	}
+	err = writeWarningsJSON(f, warnings)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return errors.Wrap(err, "failed to write warnings file")
+}
+
+// check returns an error if some of the warnings fail the build.
+func (p *warningPolicy) check(warnings []client.VertexWarning) error {
+	var failed []string
+	for _, w := range toBuildWarnings(warnings) {
+		if !p.fails(w) {
+			continue
+		}
+		name := w.Rule
+		if name == "" {
+			name = w.Message
+		}
+		if !slices.Contains(failed, name) {
+			failed = append(failed, name)
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("build emitted warnings matching --fail-on: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/stretchr/testify/require"
+)
+
+var testWarnings = []client.VertexWarning{
+	{
+		Level:      1,
+		Short:      []byte("UndefinedVar: Usage of undefined variable '$FOO' (line 3)"),
+		Detail:     [][]byte{[]byte("Variables should be defined before their use")},
+		URL:        "https://docs.docker.com/go/dockerfile/rule/undefined-var/",
+		SourceInfo: &pb.SourceInfo{Filename: "Dockerfile"},
+		Range:      []*pb.Range{{Start: &pb.Position{Line: 3}, End: &pb.Position{Line: 3}}},
+	},
+	{
+		Level: 1,
+		Short: []byte("FromAsCasing: 'as' and 'FROM' keywords' casing do not match (line 1)"),
+	},
+	{
+		Level: 1,
+		Short: []byte("Platform linux/arm64 is built under emulation"),
+	},
+}
+
+func TestWriteWarningsJSON(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, writeWarningsJSON(&b, testWarnings))
+	require.Equal(t, `{"rule":"UndefinedVar","message":"Usage of undefined variable '$FOO'","detail":["Variables should be defined before their use"],"url":"https://docs.docker.com/go/dockerfile/rule/undefined-var/","file":"Dockerfile","line":3}
+{"rule":"FromAsCasing","message":"'as' and 'FROM' keywords' casing do not match"}
+{"message":"Platform linux/arm64 is built under emulation"}
+`, b.String())
+}
+
+func TestWarningPolicy(t *testing.T) {
+	tcs := []struct {
+		failOn []string
+		err    string
+	}{
+		{
+			failOn: nil,
+		},
+		{
+			failOn: []string{"UndefinedVar"},
+			err:    "build emitted warnings matching --fail-on: UndefinedVar",
+		},
+		{
+			failOn: []string{"warning:UndefinedVar", "error:*"},
+			err:    "build emitted warnings matching --fail-on: FromAsCasing, Platform linux/arm64 is built under emulation",
+		},
+		{
+			failOn: []string{"error:UndefinedVar", "warning:*"},
+			err:    "build emitted warnings matching --fail-on: UndefinedVar",
+		},
+		{
+			failOn: []string{"error:*", "warning:*"},
+		},
+		{
+			failOn: []string{"JSONArgsRecommended"},
+		},
+	}
+	for _, tc := range tcs {
+		p, err := warningsOptions{format: warningsFormatText, failOn: tc.failOn}.policy()
+		require.NoError(t, err)
+		err = p.check(testWarnings)
+		if tc.err == "" {
+			require.NoError(t, err, "%v", tc.failOn)
+		} else {
+			require.EqualError(t, err, tc.err, "%v", tc.failOn)
+		}
+	}
+
+	_, err := warningsOptions{format: warningsFormatText, failOn: []string{"fatal:UndefinedVar"}}.policy()
+	require.ErrorContains(t, err, `invalid fail-on "fatal:UndefinedVar"`)
+	_, err = warningsOptions{format: warningsFormatText, failOn: []string{"error:"}}.policy()
+	require.ErrorContains(t, err, "expecting a rule")
+	_, err = warningsOptions{format: "yaml"}.policy()
+	require.ErrorContains(t, err, `invalid warnings format "yaml"`)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/docker/buildx/store/storeutil"
 	"github.com/docker/buildx/util/buildflags"
 	"github.com/docker/buildx/util/confutil"
+	"github.com/docker/buildx/util/credplugin"
 	"github.com/docker/buildx/util/dockerutil"
 	"github.com/docker/buildx/util/platformutil"
 	"github.com/docker/buildx/util/progress"
@@ -21,7 +22,6 @@ import (
 	dockeropts "github.com/docker/cli/opts"
 	"github.com/docker/docker/api/types/container"
 	"github.com/moby/buildkit/client"
-	"github.com/moby/buildkit/session/auth/authprovider"
 	"github.com/moby/buildkit/util/grpcerrors"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/codes"
@@ -59,6 +59,9 @@ func RunBuild(ctx context.Context, dockerCli command.Cli, in *controllerapi.Buil
 		NetworkMode:            in.NetworkMode,
 		NoCache:                in.NoCache,
 		NoCacheFilter:          in.NoCacheFilter,
+		NoEmulation:            in.NoEmulation,
+		NoMetadataCache:        in.NoMetadataCache,
+		Offline:                in.Offline,
 		Pull:                   in.Pull,
 		ShmSize:                dockeropts.MemBytes(in.ShmSize),
 		Tags:                   in.Tags,
@@ -74,8 +77,30 @@ func RunBuild(ctx context.Context, dockerCli command.Cli, in *controllerapi.Buil
 	}
 	opts.Platforms = platforms
 
-	dockerConfig := dockerCli.ConfigFile()
-	opts.Session = append(opts.Session, authprovider.NewDockerAuthProvider(dockerConfig, nil))
+	if len(in.DNS) > 0 || len(in.DNSSearch) > 0 || len(in.DNSOptions) > 0 {
+		opts.DNS = &build.DNSConfig{
+			Nameservers:   in.DNS,
+			SearchDomains: in.DNSSearch,
+			Options:       in.DNSOptions,
+		}
+	}
+
+	// offline builds don't expose registry credentials, so that the builder
+	// can't fetch tokens on behalf of the client
+	if !in.Offline {
+		dockerConfig := dockerCli.ConfigFile()
+		credPlugins, err := credplugin.LoadConfig(confutil.NewConfig(dockerCli).Dir())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		opts.Session = append(opts.Session, credplugin.NewAuthProvider(dockerConfig, credPlugins))
+	}
+
+	schedulingPolicy, err := build.ParseSchedulingPolicy(in.SchedulingPolicy)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	opts.SchedulingPolicy = schedulingPolicy
 
 	secrets, err := controllerapi.CreateSecrets(in.Secrets)
 	if err != nil {
@@ -132,6 +157,16 @@ func RunBuild(ctx context.Context, dockerCli command.Cli, in *controllerapi.Buil
 		}
 	}
 
+	compression, err := buildflags.ParseCompression(in.Compression)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if compression != nil {
+		for _, o := range outputs {
+			compression.Apply(o.Type, o.Attrs)
+		}
+	}
+
 	annotations, err := buildflags.ParseAnnotations(in.Annotations)
 	if err != nil {
 		return nil, nil, nil, errors.Wrap(err, "parse annotations")
@@ -172,6 +207,10 @@ func RunBuild(ctx context.Context, dockerCli command.Cli, in *controllerapi.Buil
 		contextPathHash = in.ContextPath
 	}
 
+	if err := build.Preflight(map[string]build.Options{defaultTargetName: opts}); err != nil {
+		return nil, nil, nil, err
+	}
+
 	// TODO: this should not be loaded this side of the controller api
 	b, err := builder.New(dockerCli,
 		builder.WithName(in.Builder),
@@ -190,6 +229,14 @@ func RunBuild(ctx context.Context, dockerCli command.Cli, in *controllerapi.Buil
 
 	var inputs *build.Inputs
 	buildOptions := map[string]build.Options{defaultTargetName: opts}
+	if err := build.WithBuildDefaults(buildOptions, b.BuildDefaults); err != nil {
+		return nil, nil, nil, err
+	}
+	release, err := b.Lease(ctx, progress)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer release()
 	resp, res, err := buildTargets(ctx, dockerCli, nodes, buildOptions, progress, generateResult)
 	err = wrapBuildError(err, false)
 	if err != nil {
@@ -0,0 +1,114 @@
+package build
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/buildx/build"
+	"github.com/docker/buildx/util/progress"
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// Canceler cancels an in-flight build. The cache exports in progress when the
+// build is canceled are given a grace period to complete so the cache of the
+// steps already built isn't lost.
+type Canceler struct {
+	cancel context.CancelCauseFunc
+
+	mu      sync.Mutex
+	exports map[digest.Digest]struct{}
+	changed chan struct{}
+}
+
+// NewCanceler returns a context for the build canceled by the returned
+// Canceler.
+func NewCanceler(ctx context.Context) (context.Context, *Canceler) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return ctx, &Canceler{
+		cancel:  cancel,
+		exports: make(map[digest.Digest]struct{}),
+		changed: make(chan struct{}),
+	}
+}
+
+// Writer returns a progress writer tracking the cache exports of the build.
+func (c *Canceler) Writer(w progress.Writer) progress.Writer {
+	return &cancelWriter{Writer: w, c: c}
+}
+
+// Cancel cancels the build with reason, after waiting for up to gracePeriod
+// for the cache exports in progress to complete. It returns once the build is
+// canceled.
+func (c *Canceler) Cancel(ctx context.Context, reason string, gracePeriod time.Duration) {
+	if gracePeriod > 0 {
+		timer := time.NewTimer(gracePeriod)
+		defer timer.Stop()
+	wait:
+		for {
+			c.mu.Lock()
+			n, changed := len(c.exports), c.changed
+			c.mu.Unlock()
+			if n == 0 {
+				break
+			}
+			select {
+			case <-changed:
+			case <-timer.C:
+				break wait
+			case <-ctx.Done():
+				break wait
+			}
+		}
+	}
+	c.cancel(errors.WithStack(&build.CanceledError{Reason: reason}))
+}
+
+func (c *Canceler) record(ss *client.SolveStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var changed bool
+	for _, v := range ss.Vertexes {
+		if !isCacheExport(v.Name) {
+			continue
+		}
+		_, ok := c.exports[v.Digest]
+		if v.Completed != nil {
+			if ok {
+				delete(c.exports, v.Digest)
+				changed = true
+			}
+		} else if v.Started != nil && !ok {
+			c.exports[v.Digest] = struct{}{}
+			changed = true
+		}
+	}
+	if changed {
+		close(c.changed)
+		c.changed = make(chan struct{})
+	}
+}
+
+// isCacheExport returns whether the vertex name is one of the steps of the
+// cache export of BuildKit. The name may be prefixed with the target.
+func isCacheExport(name string) bool {
+	if strings.HasPrefix(name, "[") {
+		if _, after, ok := strings.Cut(name, "] "); ok {
+			name = after
+		}
+	}
+	return strings.HasPrefix(name, "exporting cache") || strings.HasPrefix(name, "preparing build cache")
+}
+
+type cancelWriter struct {
+	progress.Writer
+	c *Canceler
+}
+
+func (w *cancelWriter) Write(ss *client.SolveStatus) {
+	w.c.record(ss)
+	w.Writer.Write(ss)
+}
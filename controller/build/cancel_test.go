@@ -0,0 +1,85 @@
+package build
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/buildx/build"
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelerGracePeriod(t *testing.T) {
+	ctx, c := NewCanceler(context.TODO())
+	w := c.Writer(nopWriter{})
+
+	now := time.Now()
+	export := &client.Vertex{
+		Digest:  digest.FromString("export"),
+		Name:    "[linux/amd64] exporting cache to registry",
+		Started: &now,
+	}
+	w.Write(&client.SolveStatus{Vertexes: []*client.Vertex{
+		{Digest: digest.FromString("run"), Name: "[2/2] RUN make", Started: &now},
+		export,
+	}})
+
+	canceled := make(chan struct{})
+	go func() {
+		c.Cancel(context.TODO(), "superseded by newer commit", time.Minute)
+		close(canceled)
+	}()
+
+	select {
+	case <-canceled:
+		t.Fatal("build canceled before the cache export completed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.NoError(t, ctx.Err())
+
+	completed := *export
+	completed.Completed = &now
+	w.Write(&client.SolveStatus{Vertexes: []*client.Vertex{&completed}})
+
+	select {
+	case <-canceled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("build not canceled after the cache export completed")
+	}
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	var cerr *build.CanceledError
+	require.ErrorAs(t, context.Cause(ctx), &cerr)
+	require.Equal(t, "superseded by newer commit", cerr.Reason)
+}
+
+func TestCancelerGracePeriodElapsed(t *testing.T) {
+	ctx, c := NewCanceler(context.TODO())
+	w := c.Writer(nopWriter{})
+
+	now := time.Now()
+	w.Write(&client.SolveStatus{Vertexes: []*client.Vertex{
+		{Digest: digest.FromString("prepare"), Name: "preparing build cache for export", Started: &now},
+	}})
+
+	c.Cancel(context.TODO(), "", 10*time.Millisecond)
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
+	require.EqualError(t, context.Cause(ctx), "build canceled")
+}
+
+func TestIsCacheExport(t *testing.T) {
+	require.True(t, isCacheExport("exporting cache to client directory"))
+	require.True(t, isCacheExport("[app linux/arm64] exporting cache to registry"))
+	require.True(t, isCacheExport("preparing build cache for export"))
+	require.False(t, isCacheExport("exporting to image"))
+	require.False(t, isCacheExport("[1/2] FROM docker.io/library/alpine"))
+}
+
+type nopWriter struct{}
+
+func (nopWriter) Write(*client.SolveStatus)                         {}
+func (nopWriter) WriteBuildRef(string, string)                      {}
+func (nopWriter) ValidateLogSource(digest.Digest, interface{}) bool { return true }
+func (nopWriter) ClearLogSource(interface{})                        {}
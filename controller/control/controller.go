@@ -3,6 +3,7 @@ package control
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/docker/buildx/build"
 	controllerapi "github.com/docker/buildx/controller/pb"
@@ -24,6 +25,10 @@ type BuildxController interface {
 	ListProcesses(ctx context.Context, ref string) (infos []*controllerapi.ProcessInfo, retErr error)
 	DisconnectProcess(ctx context.Context, ref, pid string) error
 	Inspect(ctx context.Context, ref string) (*controllerapi.InspectResponse, error)
+	// Cancel cancels the build in progress in the specified session and records the reason in its local state,
+	// which is shown by "buildx history inspect".
+	// The cache exports in progress are given gracePeriod to complete before the build is canceled.
+	Cancel(ctx context.Context, ref, reason string, gracePeriod time.Duration) error
 }
 
 type ControlOptions struct {
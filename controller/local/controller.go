@@ -3,7 +3,9 @@ package local
 import (
 	"context"
 	"io"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/docker/buildx/build"
 	cbuild "github.com/docker/buildx/controller/build"
@@ -41,6 +43,8 @@ type localController struct {
 	processes   *processes.Manager
 
 	buildOnGoing atomic.Bool
+	cancelerMu   sync.Mutex
+	canceler     *cbuild.Canceler
 }
 
 func (b *localController) Build(ctx context.Context, options *controllerapi.BuildOptions, in io.ReadCloser, progress progress.Writer) (string, *client.SolveResponse, *build.Inputs, error) {
@@ -49,7 +53,11 @@ func (b *localController) Build(ctx context.Context, options *controllerapi.Buil
 	}
 	defer b.buildOnGoing.Store(false)
 
-	resp, res, dockerfileMappings, buildErr := cbuild.RunBuild(ctx, b.dockerCli, options, in, progress, true)
+	ctx, canceler := cbuild.NewCanceler(ctx)
+	b.setCanceler(canceler)
+	defer b.setCanceler(nil)
+
+	resp, res, dockerfileMappings, buildErr := cbuild.RunBuild(ctx, b.dockerCli, options, in, canceler.Writer(progress), true)
 	// NOTE: RunBuild can return *build.ResultHandle even on error.
 	if res != nil {
 		b.buildConfig = buildConfig{
@@ -71,6 +79,26 @@ func (b *localController) Build(ctx context.Context, options *controllerapi.Buil
 	return b.sessionID, resp, dockerfileMappings, nil
 }
 
+func (b *localController) setCanceler(c *cbuild.Canceler) {
+	b.cancelerMu.Lock()
+	b.canceler = c
+	b.cancelerMu.Unlock()
+}
+
+func (b *localController) Cancel(ctx context.Context, sessionID, reason string, gracePeriod time.Duration) error {
+	if sessionID != b.sessionID {
+		return errors.Errorf("unknown session ID %q", sessionID)
+	}
+	b.cancelerMu.Lock()
+	c := b.canceler
+	b.cancelerMu.Unlock()
+	if c == nil {
+		return errors.New("no build in progress")
+	}
+	c.Cancel(ctx, reason, gracePeriod)
+	return nil
+}
+
 func (b *localController) ListProcesses(ctx context.Context, sessionID string) (infos []*controllerapi.ProcessInfo, retErr error) {
 	if sessionID != b.sessionID {
 		return nil, errors.Errorf("unknown session ID %q", sessionID)
@@ -0,0 +1,74 @@
+package pb
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// APIVersion is the version of the controller API implemented by this
+	// package. It is incremented when the semantics of existing messages
+	// change in a way that can't be detected through a capability.
+	APIVersion uint32 = 1
+	// MinAPIVersion is the oldest controller API version this package can
+	// talk to.
+	MinAPIVersion uint32 = 1
+
+	// legacyAPIVersion is the version implemented by the peers that predate
+	// API versioning and report zero.
+	legacyAPIVersion uint32 = 1
+)
+
+// Capabilities of a controller server that clients can check before using
+// an optional feature.
+const (
+	// CapInvoke is set if the server can run processes in the result of a
+	// build.
+	CapInvoke = "invoke"
+	// CapDetach is set if the server keeps build sessions alive after the
+	// client disconnects.
+	CapDetach = "detach"
+	// CapCancel is set if the server implements the Cancel RPC to cancel a
+	// build session with a reason and a grace period.
+	CapCancel = "cancel"
+	// CapMultiExporterMetadata is set if the server returns the metadata of
+	// all exporters when a build has several of them.
+	CapMultiExporterMetadata = "multi-exporter-metadata"
+)
+
+// legacyCapabilities are the capabilities of servers that predate API
+// versioning and don't report any.
+var legacyCapabilities = []string{CapInvoke, CapDetach}
+
+// Capabilities returns the capabilities of a server implementing this
+// version of the API.
+func Capabilities() []string {
+	return []string{CapInvoke, CapDetach, CapCancel, CapMultiExporterMetadata}
+}
+
+// NegotiateAPIVersion returns the API version to use with a peer
+// implementing the given version, which is the lowest of both. An error is
+// returned if the peer implements a version older than MinAPIVersion.
+func NegotiateAPIVersion(peer uint32) (uint32, error) {
+	return negotiateAPIVersion(peer, MinAPIVersion, APIVersion)
+}
+
+func negotiateAPIVersion(peer, minVersion, version uint32) (uint32, error) {
+	if peer == 0 {
+		peer = legacyAPIVersion
+	}
+	if peer < minVersion {
+		return 0, errors.Errorf("controller API version %d is no longer supported, minimum version is %d", peer, minVersion)
+	}
+	return min(peer, version), nil
+}
+
+// HasCapability reports whether the server that returned the info supports
+// the capability.
+func (x *InfoResponse) HasCapability(c string) bool {
+	if x.GetApiVersion() == 0 {
+		return slices.Contains(legacyCapabilities, c)
+	}
+	return slices.Contains(x.GetCapabilities(), c)
+}
@@ -0,0 +1,51 @@
+package pb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateAPIVersion(t *testing.T) {
+	v, err := NegotiateAPIVersion(APIVersion)
+	require.NoError(t, err)
+	require.Equal(t, APIVersion, v)
+
+	// peers that predate versioning implement version 1
+	v, err = NegotiateAPIVersion(0)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), v)
+
+	// a newer peer falls back to the version of the older one
+	v, err = NegotiateAPIVersion(APIVersion + 1)
+	require.NoError(t, err)
+	require.Equal(t, APIVersion, v)
+}
+
+func TestNegotiateAPIVersionUnsupported(t *testing.T) {
+	v, err := negotiateAPIVersion(3, 2, 3)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), v)
+
+	_, err = negotiateAPIVersion(1, 2, 3)
+	require.EqualError(t, err, "controller API version 1 is no longer supported, minimum version is 2")
+
+	_, err = negotiateAPIVersion(0, 2, 3)
+	require.EqualError(t, err, "controller API version 1 is no longer supported, minimum version is 2")
+}
+
+func TestCapabilities(t *testing.T) {
+	require.ElementsMatch(t, []string{CapInvoke, CapDetach, CapCancel, CapMultiExporterMetadata}, Capabilities())
+}
+
+func TestHasCapability(t *testing.T) {
+	legacy := &InfoResponse{}
+	require.True(t, legacy.HasCapability(CapInvoke))
+	require.True(t, legacy.HasCapability(CapDetach))
+	require.False(t, legacy.HasCapability(CapCancel))
+	require.False(t, legacy.HasCapability(CapMultiExporterMetadata))
+
+	res := &InfoResponse{ApiVersion: APIVersion, Capabilities: []string{CapInvoke}}
+	require.True(t, res.HasCapability(CapInvoke))
+	require.False(t, res.HasCapability(CapDetach))
+}
@@ -29,6 +29,7 @@ const (
 	Controller_Info_FullMethodName              = "/buildx.controller.v1.Controller/Info"
 	Controller_ListProcesses_FullMethodName     = "/buildx.controller.v1.Controller/ListProcesses"
 	Controller_DisconnectProcess_FullMethodName = "/buildx.controller.v1.Controller/DisconnectProcess"
+	Controller_Cancel_FullMethodName            = "/buildx.controller.v1.Controller/Cancel"
 )
 
 // ControllerClient is the client API for Controller service.
@@ -45,6 +46,7 @@ type ControllerClient interface {
 	Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
 	ListProcesses(ctx context.Context, in *ListProcessesRequest, opts ...grpc.CallOption) (*ListProcessesResponse, error)
 	DisconnectProcess(ctx context.Context, in *DisconnectProcessRequest, opts ...grpc.CallOption) (*DisconnectProcessResponse, error)
+	Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error)
 }
 
 type controllerClient struct {
@@ -170,6 +172,16 @@ func (c *controllerClient) DisconnectProcess(ctx context.Context, in *Disconnect
 	return out, nil
 }
 
+func (c *controllerClient) Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelResponse)
+	err := c.cc.Invoke(ctx, Controller_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ControllerServer is the server API for Controller service.
 // All implementations should embed UnimplementedControllerServer
 // for forward compatibility.
@@ -184,6 +196,7 @@ type ControllerServer interface {
 	Info(context.Context, *InfoRequest) (*InfoResponse, error)
 	ListProcesses(context.Context, *ListProcessesRequest) (*ListProcessesResponse, error)
 	DisconnectProcess(context.Context, *DisconnectProcessRequest) (*DisconnectProcessResponse, error)
+	Cancel(context.Context, *CancelRequest) (*CancelResponse, error)
 }
 
 // UnimplementedControllerServer should be embedded to have
@@ -223,6 +236,9 @@ func (UnimplementedControllerServer) ListProcesses(context.Context, *ListProcess
 func (UnimplementedControllerServer) DisconnectProcess(context.Context, *DisconnectProcessRequest) (*DisconnectProcessResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DisconnectProcess not implemented")
 }
+func (UnimplementedControllerServer) Cancel(context.Context, *CancelRequest) (*CancelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
 func (UnimplementedControllerServer) testEmbeddedByValue() {}
 
 // UnsafeControllerServer may be embedded to opt out of forward compatibility for this service.
@@ -394,6 +410,24 @@ func _Controller_DisconnectProcess_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Controller_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControllerServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Controller_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControllerServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Controller_ServiceDesc is the grpc.ServiceDesc for Controller service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -429,6 +463,10 @@ var Controller_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DisconnectProcess",
 			Handler:    _Controller_DisconnectProcess_Handler,
 		},
+		{
+			MethodName: "Cancel",
+			Handler:    _Controller_Cancel_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
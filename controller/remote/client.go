@@ -58,8 +58,21 @@ func (c *Client) Close() (err error) {
 	return
 }
 
+// Info returns the version and capabilities of the server. An error is
+// returned if the server doesn't implement a compatible API version.
+func (c *Client) Info(ctx context.Context) (*pb.InfoResponse, error) {
+	res, err := c.client().Info(ctx, &pb.InfoRequest{ApiVersion: pb.APIVersion})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pb.NegotiateAPIVersion(res.ApiVersion); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (c *Client) Version(ctx context.Context) (string, string, string, error) {
-	res, err := c.client().Info(ctx, &pb.InfoRequest{})
+	res, err := c.Info(ctx)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -83,6 +96,21 @@ func (c *Client) Disconnect(ctx context.Context, sessionID string) error {
 	return err
 }
 
+func (c *Client) Cancel(ctx context.Context, sessionID, reason string, gracePeriod time.Duration) error {
+	if sessionID == "" {
+		return errors.New("build session ID must be specified")
+	}
+	info, err := c.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if !info.HasCapability(pb.CapCancel) {
+		return errors.New("build server does not support canceling builds, restart it with a newer version of buildx")
+	}
+	_, err = c.client().Cancel(ctx, &pb.CancelRequest{SessionID: sessionID, Reason: reason, GracePeriod: int64(gracePeriod)})
+	return err
+}
+
 func (c *Client) ListProcesses(ctx context.Context, sessionID string) (infos []*pb.ProcessInfo, retErr error) {
 	res, err := c.client().ListProcesses(ctx, &pb.ListProcessesRequest{SessionID: sessionID})
 	if err != nil {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/docker/buildx/build"
+	cbuild "github.com/docker/buildx/controller/build"
 	controllererrors "github.com/docker/buildx/controller/errdefs"
 	"github.com/docker/buildx/controller/pb"
 	"github.com/docker/buildx/controller/processes"
@@ -38,6 +39,7 @@ type session struct {
 	buildOnGoing atomic.Bool
 	statusChan   chan *pb.StatusResponse
 	cancelBuild  func(error)
+	canceler     *cbuild.Canceler
 	buildOptions *pb.BuildOptions
 	inputPipe    *io.PipeWriter
 
@@ -73,12 +75,17 @@ func (m *Server) DisconnectProcess(ctx context.Context, req *pb.DisconnectProces
 }
 
 func (m *Server) Info(ctx context.Context, req *pb.InfoRequest) (res *pb.InfoResponse, err error) {
+	if _, err := pb.NegotiateAPIVersion(req.ApiVersion); err != nil {
+		return nil, err
+	}
 	return &pb.InfoResponse{
 		BuildxVersion: &pb.BuildxVersion{
 			Package:  version.Package,
 			Version:  version.Version,
 			Revision: version.Revision,
 		},
+		ApiVersion:   pb.APIVersion,
+		Capabilities: pb.Capabilities(),
 	}, nil
 }
 
@@ -122,6 +129,27 @@ func (m *Server) Disconnect(ctx context.Context, req *pb.DisconnectRequest) (res
 	return &pb.DisconnectResponse{}, nil
 }
 
+func (m *Server) Cancel(ctx context.Context, req *pb.CancelRequest) (*pb.CancelResponse, error) {
+	sessionID := req.SessionID
+	if sessionID == "" {
+		return nil, errors.New("cancel: empty session ID")
+	}
+	var canceler *cbuild.Canceler
+	m.sessionMu.Lock()
+	if s, ok := m.session[sessionID]; ok {
+		canceler = s.canceler
+	} else {
+		m.sessionMu.Unlock()
+		return nil, errors.Errorf("cancel: unknown key %v", sessionID)
+	}
+	m.sessionMu.Unlock()
+	if canceler == nil {
+		return nil, errors.Errorf("cancel: no build in progress for %v", sessionID)
+	}
+	canceler.Cancel(ctx, req.Reason, time.Duration(req.GracePeriod))
+	return &pb.CancelResponse{}, nil
+}
+
 func (m *Server) Close() error {
 	m.sessionMu.Lock()
 	for k := range m.session {
@@ -196,14 +224,18 @@ func (m *Server) Build(ctx context.Context, req *pb.BuildRequest) (*pb.BuildResp
 		m.sessionMu.Unlock()
 	}()
 
-	pw := pb.NewProgressWriter(statusChan)
-
 	// Build the specified request
 	ctx, cancel := context.WithCancelCause(ctx)
 	defer func() { cancel(errors.WithStack(context.Canceled)) }()
+	ctx, canceler := cbuild.NewCanceler(ctx)
+	pw := canceler.Writer(pb.NewProgressWriter(statusChan))
+	m.sessionMu.Lock()
+	s.canceler = canceler
+	m.sessionMu.Unlock()
 	resp, res, _, buildErr := m.buildFunc(ctx, req.Options, inR, pw)
 	m.sessionMu.Lock()
 	if s, ok := m.session[sessionID]; ok {
+		s.canceler = nil
 		// NOTE: buildFunc can return *build.ResultHandle even on error (e.g. when it's implemented using (github.com/docker/buildx/controller/build).RunBuild).
 		if res != nil {
 			s.result = res
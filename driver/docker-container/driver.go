@@ -18,6 +18,7 @@ import (
 	"github.com/docker/buildx/util/imagetools"
 	"github.com/docker/buildx/util/progress"
 	"github.com/docker/cli/opts"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
@@ -37,6 +38,20 @@ const (
 	buildkitdConfigFile = "buildkitd.toml"
 )
 
+// Image update policies of the BuildKit image.
+const (
+	// imageUpdateOnCreate pulls the image when the container is created.
+	imageUpdateOnCreate = "on-create"
+	// imageUpdateWeekly also checks for a newer image when booting a
+	// container created more than a week ago, and recreates the container
+	// if there is one.
+	imageUpdateWeekly = "weekly"
+	// imageUpdateNever only pulls the image if it's missing.
+	imageUpdateNever = "never"
+
+	imageUpdateInterval = 7 * 24 * time.Hour
+)
+
 type Driver struct {
 	driver.InitConfig
 	factory driver.Factory
@@ -56,6 +71,9 @@ type Driver struct {
 	restartPolicy container.RestartPolicy
 	env           []string
 	defaultLoad   bool
+	imageUpdate   string
+	stateVolume   string
+	stateBind     string
 }
 
 func (d *Driver) IsMobyDriver() bool {
@@ -68,13 +86,28 @@ func (d *Driver) Config() driver.InitConfig {
 
 func (d *Driver) Bootstrap(ctx context.Context, l progress.Logger) error {
 	return progress.Wrap("[internal] booting buildkit", l, func(sub progress.SubLogger) error {
-		_, err := d.DockerAPI.ContainerInspect(ctx, d.Name)
+		ctn, err := d.DockerAPI.ContainerInspect(ctx, d.Name)
 		if err != nil {
 			if dockerclient.IsErrNotFound(err) {
 				return d.create(ctx, sub)
 			}
 			return err
 		}
+		if prev, ok := stateMountChanged(ctn.Mounts, d.stateMount()); ok {
+			if err := d.migrateState(ctx, sub, prev); err != nil {
+				return err
+			}
+			return d.create(ctx, sub)
+		}
+		if d.imageUpdate == imageUpdateWeekly && imageUpdateDue(ctn.Created, time.Now()) {
+			updated, err := d.update(ctx, sub, ctn.Image)
+			if err != nil {
+				// keep using the current image if the registry is unreachable
+				sub.Wrap("checking for image update failed: "+err.Error(), func() error { return nil })
+			} else if updated {
+				return d.create(ctx, sub)
+			}
+		}
 		return sub.Wrap("starting container "+d.Name, func() error {
 			if err := d.start(ctx); err != nil {
 				return err
@@ -84,13 +117,71 @@ func (d *Driver) Bootstrap(ctx context.Context, l progress.Logger) error {
 	})
 }
 
-func (d *Driver) create(ctx context.Context, l progress.SubLogger) error {
-	imageName := bkimage.DefaultImage
+// Update pulls the BuildKit image and recreates the container if the image
+// changed, preserving the state volume. It returns whether the container was
+// recreated.
+func (d *Driver) Update(ctx context.Context, l progress.Logger) (updated bool, err error) {
+	err = progress.Wrap("[internal] updating buildkit", l, func(sub progress.SubLogger) error {
+		ctn, err := d.DockerAPI.ContainerInspect(ctx, d.Name)
+		if err != nil {
+			if dockerclient.IsErrNotFound(err) {
+				// the image is pulled when the container is created
+				return nil
+			}
+			return err
+		}
+		running := ctn.State != nil && ctn.State.Running
+		updated, err = d.update(ctx, sub, ctn.Image)
+		if err != nil || !updated || !running {
+			return err
+		}
+		return d.create(ctx, sub)
+	})
+	return updated, err
+}
+
+// update pulls the BuildKit image, and removes the container if the image ID
+// differs from the one of the container. The state volume is kept so it is
+// reused when the container is created again.
+func (d *Driver) update(ctx context.Context, l progress.SubLogger, imageID string) (bool, error) {
+	imageName := d.imageName()
+	if err := d.pull(ctx, l, imageName); err != nil {
+		return false, err
+	}
+	img, _, err := d.DockerAPI.ImageInspectWithRaw(ctx, imageName)
+	if err != nil {
+		return false, err
+	}
+	if img.ID == imageID {
+		l.Wrap("image "+imageName+" is up to date", func() error { return nil })
+		return false, nil
+	}
+	return true, l.Wrap("removing container "+d.Name+" to update image", func() error {
+		return d.DockerAPI.ContainerRemove(ctx, d.Name, container.RemoveOptions{
+			Force: true,
+		})
+	})
+}
+
+// imageUpdateDue returns true if a container created at the given time is due
+// for an image update check.
+func imageUpdateDue(created string, now time.Time) bool {
+	t, err := time.Parse(time.RFC3339Nano, created)
+	if err != nil {
+		return false
+	}
+	return now.Sub(t) >= imageUpdateInterval
+}
+
+func (d *Driver) imageName() string {
 	if d.image != "" {
-		imageName = d.image
+		return d.image
 	}
+	return bkimage.DefaultImage
+}
 
-	if err := l.Wrap("pulling image "+imageName, func() error {
+func (d *Driver) pull(ctx context.Context, l progress.SubLogger, imageName string) error {
+	return l.Wrap("pulling image "+imageName, func() error {
 		ra, err := imagetools.RegistryAuthForRef(imageName, d.Auth)
 		if err != nil {
 			return err
@@ -103,7 +194,19 @@ func (d *Driver) create(ctx context.Context, l progress.SubLogger) error {
 		}
 		_, err = io.Copy(io.Discard, rc)
 		return err
-	}); err != nil {
+	})
+}
+
+func (d *Driver) create(ctx context.Context, l progress.SubLogger) error {
+	imageName := d.imageName()
+
+	if d.imageUpdate == imageUpdateNever {
+		if _, _, err := d.DockerAPI.ImageInspectWithRaw(ctx, imageName); err == nil {
+			l.Wrap("using local image "+imageName, func() error { return nil })
+		} else if err := d.pull(ctx, l, imageName); err != nil {
+			return err
+		}
+	} else if err := d.pull(ctx, l, imageName); err != nil {
 		// image pulling failed, check if it exists in local image store.
 		// if not, return pulling error. otherwise log it.
 		_, _, errInspect := d.DockerAPI.ImageInspectWithRaw(ctx, imageName)
@@ -125,11 +228,7 @@ func (d *Driver) create(ctx context.Context, l progress.SubLogger) error {
 			Privileged:    true,
 			RestartPolicy: d.restartPolicy,
 			Mounts: []mount.Mount{
-				{
-					Type:   mount.TypeVolume,
-					Source: d.Name + volumeStateSuffix,
-					Target: confutil.DefaultBuildKitStateDir,
-				},
+				d.stateMount(),
 			},
 			Init: &useInit,
 		}
@@ -194,6 +293,111 @@ func (d *Driver) create(ctx context.Context, l progress.SubLogger) error {
 	})
 }
 
+// stateMount returns the mount of the BuildKit state directory, which is the
+// volume of the builder unless a custom volume or bind mount is set.
+func (d *Driver) stateMount() mount.Mount {
+	if d.stateBind != "" {
+		return mount.Mount{
+			Type:   mount.TypeBind,
+			Source: d.stateBind,
+			Target: confutil.DefaultBuildKitStateDir,
+			BindOptions: &mount.BindOptions{
+				CreateMountpoint: true,
+			},
+		}
+	}
+	name := d.Name + volumeStateSuffix
+	if d.stateVolume != "" {
+		name = d.stateVolume
+	}
+	return mount.Mount{
+		Type:   mount.TypeVolume,
+		Source: name,
+		Target: confutil.DefaultBuildKitStateDir,
+	}
+}
+
+// stateMountChanged returns the current state mount of the container if it
+// differs from the configured one.
+func stateMountChanged(mounts []types.MountPoint, m mount.Mount) (mount.Mount, bool) {
+	for _, mp := range mounts {
+		if mp.Destination != m.Target {
+			continue
+		}
+		prev := mount.Mount{
+			Type:   mp.Type,
+			Target: mp.Destination,
+		}
+		if mp.Type == mount.TypeVolume {
+			prev.Source = mp.Name
+		} else {
+			prev.Source = mp.Source
+		}
+		return prev, prev.Type != m.Type || prev.Source != m.Source
+	}
+	return mount.Mount{}, false
+}
+
+// migrateState copies the state of the container to the configured state
+// mount, unless the latter already holds some state, and removes the
+// container once the copy succeeded. The container is stopped during the
+// copy and kept if it fails, so that the migration is retried on the next
+// boot. The previous state is kept and has to be removed manually.
+func (d *Driver) migrateState(ctx context.Context, l progress.SubLogger, prev mount.Mount) error {
+	if err := l.Wrap("stopping container "+d.Name+" to change state mount", func() error {
+		return d.DockerAPI.ContainerStop(ctx, d.Name, container.StopOptions{})
+	}); err != nil {
+		return err
+	}
+	to := d.stateMount()
+	if err := l.Wrap("migrating state from "+prev.Source+" to "+to.Source, func() error {
+		return d.copyState(ctx, prev, to)
+	}); err != nil {
+		return err
+	}
+	return l.Wrap("removing container "+d.Name, func() error {
+		return d.DockerAPI.ContainerRemove(ctx, d.Name, container.RemoveOptions{
+			Force: true,
+		})
+	})
+}
+
+// copyState copies the content of the from mount to the to mount with a
+// short-lived container, unless the latter isn't empty.
+func (d *Driver) copyState(ctx context.Context, from, to mount.Mount) error {
+	from.Target = "/from"
+	from.ReadOnly = true
+	to.Target = "/to"
+	name := d.Name + "_migrate"
+	_, err := d.DockerAPI.ContainerCreate(ctx, &container.Config{
+		Image:      d.imageName(),
+		Entrypoint: []string{"/bin/sh", "-c"},
+		Cmd:        []string{`[ -n "$(ls -A /to)" ] || cp -a /from/. /to/`},
+	}, &container.HostConfig{
+		Mounts: []mount.Mount{from, to},
+	}, &network.NetworkingConfig{}, nil, name)
+	if err != nil {
+		return err
+	}
+	defer d.DockerAPI.ContainerRemove(context.WithoutCancel(ctx), name, container.RemoveOptions{Force: true})
+
+	waitC, errC := d.DockerAPI.ContainerWait(ctx, name, container.WaitConditionNextExit)
+	if err := d.DockerAPI.ContainerStart(ctx, name, container.StartOptions{}); err != nil {
+		return err
+	}
+	select {
+	case res := <-waitC:
+		if res.StatusCode != 0 {
+			return errors.Errorf("copying state exited with code %d", res.StatusCode)
+		}
+		return nil
+	case err := <-errC:
+		return err
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
 func (d *Driver) wait(ctx context.Context, l progress.SubLogger) error {
 	try := 1
 	for {
@@ -0,0 +1,181 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/buildx/driver"
+	"github.com/docker/buildx/util/confutil"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/moby/buildkit/client"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageUpdateDue(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	require.False(t, imageUpdateDue("2024-06-10T12:00:00.123456789Z", now))
+	require.True(t, imageUpdateDue("2024-06-08T12:00:00Z", now))
+	require.False(t, imageUpdateDue("", now))
+}
+
+func TestFactoryImageUpdate(t *testing.T) {
+	api, err := dockerclient.NewClientWithOpts()
+	require.NoError(t, err)
+
+	f := &factory{}
+	d, err := f.New(context.TODO(), driver.InitConfig{DockerAPI: api})
+	require.NoError(t, err)
+	require.Equal(t, imageUpdateOnCreate, d.(*Driver).imageUpdate)
+
+	d, err = f.New(context.TODO(), driver.InitConfig{
+		DockerAPI:  api,
+		DriverOpts: map[string]string{"image-update": "weekly"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, imageUpdateWeekly, d.(*Driver).imageUpdate)
+
+	_, err = f.New(context.TODO(), driver.InitConfig{
+		DockerAPI:  api,
+		DriverOpts: map[string]string{"image-update": "daily"},
+	})
+	require.ErrorContains(t, err, `invalid image-update policy "daily"`)
+}
+
+func TestFactoryStateMount(t *testing.T) {
+	api, err := dockerclient.NewClientWithOpts()
+	require.NoError(t, err)
+
+	f := &factory{}
+	d, err := f.New(context.TODO(), driver.InitConfig{Name: "buildx_buildkit_foo0", DockerAPI: api})
+	require.NoError(t, err)
+	m := d.(*Driver).stateMount()
+	require.Equal(t, mount.TypeVolume, m.Type)
+	require.Equal(t, "buildx_buildkit_foo0_state", m.Source)
+
+	d, err = f.New(context.TODO(), driver.InitConfig{
+		Name:       "buildx_buildkit_foo0",
+		DockerAPI:  api,
+		DriverOpts: map[string]string{"state-volume": "buildkit-cache"},
+	})
+	require.NoError(t, err)
+	m = d.(*Driver).stateMount()
+	require.Equal(t, mount.TypeVolume, m.Type)
+	require.Equal(t, "buildkit-cache", m.Source)
+
+	d, err = f.New(context.TODO(), driver.InitConfig{
+		Name:       "buildx_buildkit_foo0",
+		DockerAPI:  api,
+		DriverOpts: map[string]string{"state-bind": "/mnt/fastdisk/buildkit/"},
+	})
+	require.NoError(t, err)
+	m = d.(*Driver).stateMount()
+	require.Equal(t, mount.TypeBind, m.Type)
+	require.Equal(t, "/mnt/fastdisk/buildkit", m.Source)
+
+	_, err = f.New(context.TODO(), driver.InitConfig{
+		DockerAPI:  api,
+		DriverOpts: map[string]string{"state-bind": "buildkit"},
+	})
+	require.ErrorContains(t, err, "expecting an absolute path")
+
+	_, err = f.New(context.TODO(), driver.InitConfig{
+		DockerAPI:  api,
+		DriverOpts: map[string]string{"state-volume": "buildkit-cache", "state-bind": "/mnt/buildkit"},
+	})
+	require.ErrorContains(t, err, "mutually exclusive")
+}
+
+func TestStateMountChanged(t *testing.T) {
+	mounts := []types.MountPoint{
+		{Type: mount.TypeBind, Source: "/etc/buildkit", Destination: "/etc/buildkit"},
+		{Type: mount.TypeVolume, Name: "buildx_buildkit_foo0_state", Source: "/var/lib/docker/volumes/buildx_buildkit_foo0_state/_data", Destination: confutil.DefaultBuildKitStateDir},
+	}
+
+	_, changed := stateMountChanged(mounts, mount.Mount{Type: mount.TypeVolume, Source: "buildx_buildkit_foo0_state", Target: confutil.DefaultBuildKitStateDir})
+	require.False(t, changed)
+
+	prev, changed := stateMountChanged(mounts, mount.Mount{Type: mount.TypeBind, Source: "/mnt/buildkit", Target: confutil.DefaultBuildKitStateDir})
+	require.True(t, changed)
+	require.Equal(t, mount.Mount{Type: mount.TypeVolume, Source: "buildx_buildkit_foo0_state", Target: confutil.DefaultBuildKitStateDir}, prev)
+
+	_, changed = stateMountChanged(nil, mount.Mount{Type: mount.TypeBind, Source: "/mnt/buildkit", Target: confutil.DefaultBuildKitStateDir})
+	require.False(t, changed)
+}
+
+// migrateAPI records the container calls of a state migration. The copy
+// exits with copyStatus.
+type migrateAPI struct {
+	dockerclient.APIClient
+	copyStatus int64
+	calls      []string
+}
+
+func (a *migrateAPI) ContainerStop(_ context.Context, name string, _ container.StopOptions) error {
+	a.calls = append(a.calls, "stop "+name)
+	return nil
+}
+
+func (a *migrateAPI) ContainerRemove(_ context.Context, name string, _ container.RemoveOptions) error {
+	a.calls = append(a.calls, "rm "+name)
+	return nil
+}
+
+func (a *migrateAPI) ContainerCreate(_ context.Context, _ *container.Config, _ *container.HostConfig, _ *network.NetworkingConfig, _ *ocispecs.Platform, name string) (container.CreateResponse, error) {
+	a.calls = append(a.calls, "create "+name)
+	return container.CreateResponse{ID: name}, nil
+}
+
+func (a *migrateAPI) ContainerWait(context.Context, string, container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	waitC := make(chan container.WaitResponse, 1)
+	waitC <- container.WaitResponse{StatusCode: a.copyStatus}
+	return waitC, make(chan error)
+}
+
+func (a *migrateAPI) ContainerStart(_ context.Context, name string, _ container.StartOptions) error {
+	a.calls = append(a.calls, "start "+name)
+	return nil
+}
+
+type nopSubLogger struct{}
+
+func (nopSubLogger) Wrap(_ string, fn func() error) error { return fn() }
+func (nopSubLogger) Log(int, []byte)                      {}
+func (nopSubLogger) SetStatus(*client.VertexStatus)       {}
+
+func TestMigrateState(t *testing.T) {
+	prev := mount.Mount{Type: mount.TypeVolume, Source: "buildx_buildkit_foo0_state", Target: confutil.DefaultBuildKitStateDir}
+
+	api := &migrateAPI{}
+	d := &Driver{InitConfig: driver.InitConfig{Name: "buildx_buildkit_foo0", DockerAPI: api}, stateBind: "/mnt/buildkit"}
+	require.NoError(t, d.migrateState(context.TODO(), nopSubLogger{}, prev))
+	require.Equal(t, []string{
+		"stop buildx_buildkit_foo0",
+		"create buildx_buildkit_foo0_migrate",
+		"start buildx_buildkit_foo0_migrate",
+		"rm buildx_buildkit_foo0_migrate",
+		"rm buildx_buildkit_foo0",
+	}, api.calls)
+}
+
+func TestMigrateStateCopyFailed(t *testing.T) {
+	prev := mount.Mount{Type: mount.TypeVolume, Source: "buildx_buildkit_foo0_state", Target: confutil.DefaultBuildKitStateDir}
+
+	api := &migrateAPI{copyStatus: 1}
+	d := &Driver{InitConfig: driver.InitConfig{Name: "buildx_buildkit_foo0", DockerAPI: api}, stateBind: "/mnt/buildkit"}
+	err := d.migrateState(context.TODO(), nopSubLogger{}, prev)
+	require.EqualError(t, err, "copying state exited with code 1")
+
+	// the container is kept along with its state
+	require.Equal(t, []string{
+		"stop buildx_buildkit_foo0",
+		"create buildx_buildkit_foo0_migrate",
+		"start buildx_buildkit_foo0_migrate",
+		"rm buildx_buildkit_foo0_migrate",
+	}, api.calls)
+}
@@ -3,6 +3,7 @@ package docker
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -50,6 +51,7 @@ func (f *factory) New(ctx context.Context, cfg driver.InitConfig) (driver.Driver
 		factory:       f,
 		InitConfig:    cfg,
 		restartPolicy: rp,
+		imageUpdate:   imageUpdateOnCreate,
 	}
 	for k, v := range cfg.DriverOpts {
 		switch {
@@ -57,6 +59,13 @@ func (f *factory) New(ctx context.Context, cfg driver.InitConfig) (driver.Driver
 			d.netMode = v
 		case k == "image":
 			d.image = v
+		case k == "image-update":
+			switch v {
+			case imageUpdateOnCreate, imageUpdateWeekly, imageUpdateNever:
+				d.imageUpdate = v
+			default:
+				return nil, errors.Errorf("invalid image-update policy %q, expecting %s, %s or %s", v, imageUpdateOnCreate, imageUpdateWeekly, imageUpdateNever)
+			}
 		case k == "memory":
 			if err := d.memory.Set(v); err != nil {
 				return nil, err
@@ -94,6 +103,16 @@ func (f *factory) New(ctx context.Context, cfg driver.InitConfig) (driver.Driver
 			if err != nil {
 				return nil, err
 			}
+		case k == "state-volume":
+			if v == "" {
+				return nil, errors.Errorf("invalid state-volume option, expecting a volume name")
+			}
+			d.stateVolume = v
+		case k == "state-bind":
+			if !filepath.IsAbs(v) {
+				return nil, errors.Errorf("invalid state-bind option %q, expecting an absolute path", v)
+			}
+			d.stateBind = filepath.Clean(v)
 		case k == "default-load":
 			d.defaultLoad, err = strconv.ParseBool(v)
 			if err != nil {
@@ -110,6 +129,10 @@ func (f *factory) New(ctx context.Context, cfg driver.InitConfig) (driver.Driver
 		}
 	}
 
+	if d.stateVolume != "" && d.stateBind != "" {
+		return nil, errors.Errorf("state-volume and state-bind options are mutually exclusive")
+	}
+
 	return d, nil
 }
 
@@ -77,6 +77,13 @@ type Driver interface {
 	Config() InitConfig
 }
 
+// Updater is implemented by drivers that can update the BuildKit daemon of a
+// node to the latest version of its image.
+type Updater interface {
+	// Update returns whether the BuildKit daemon was updated.
+	Update(ctx context.Context, l progress.Logger) (bool, error)
+}
+
 const builderNamePrefix = "buildx_buildkit_"
 
 func BuilderName(name string) string {
@@ -17,7 +17,9 @@ import (
 	"github.com/docker/go-units"
 	"github.com/moby/buildkit/client"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -47,12 +49,16 @@ type Driver struct {
 	deployment       *appsv1.Deployment
 	configMaps       []*corev1.ConfigMap
 	clientset        *kubernetes.Clientset
+	namespace        string
 	deploymentClient clientappsv1.DeploymentInterface
 	podClient        clientcorev1.PodInterface
 	configMapClient  clientcorev1.ConfigMapInterface
 	podChooser       podchooser.PodChooser
 	defaultLoad      bool
 	timeout          time.Duration
+	// checkAdmission is set when the pods use security options that
+	// admission control may reject.
+	checkAdmission bool
 }
 
 func (d *Driver) IsMobyDriver() bool {
@@ -71,6 +77,12 @@ func (d *Driver) Bootstrap(ctx context.Context, l progress.Logger) error {
 				return errors.Wrapf(err, "error for bootstrap %q", d.deployment.Name)
 			}
 
+			if d.checkAdmission {
+				if err := d.validateAdmission(ctx); err != nil {
+					return err
+				}
+			}
+
 			for _, cfg := range d.configMaps {
 				// create ConfigMap first if exists
 				_, err = d.configMapClient.Create(ctx, cfg, metav1.CreateOptions{})
@@ -98,6 +110,61 @@ func (d *Driver) Bootstrap(ctx context.Context, l progress.Logger) error {
 	})
 }
 
+// validateAdmission submits the pod template with a server-side dry run so
+// that pods rejected by admission control (e.g. PodSecurity) because of their
+// security options are reported before the deployment is created instead of
+// never becoming ready.
+func (d *Driver) validateAdmission(ctx context.Context) error {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: d.deployment.Name + "-",
+			Labels:       d.deployment.Spec.Template.Labels,
+			Annotations:  d.deployment.Spec.Template.Annotations,
+		},
+		Spec: d.deployment.Spec.Template.Spec,
+	}
+	_, err := d.podClient.Create(ctx, pod, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	if err == nil {
+		return nil
+	}
+	switch apierrors.ReasonForError(err) {
+	case metav1.StatusReasonInvalid:
+		return errors.Wrapf(err, "buildkit pod for %q rejected by cluster admission", d.deployment.Name)
+	case metav1.StatusReasonForbidden:
+		// a forbidden dry run is an admission rejection only if the client
+		// is authorized to create pods, otherwise admission is only checked
+		// by the deployment controller
+		allowed, rerr := d.canCreatePods(ctx)
+		if rerr != nil {
+			logrus.Debugf("skipping admission check for %q: %v", d.deployment.Name, rerr)
+			return nil
+		}
+		if allowed {
+			return errors.Wrapf(err, "buildkit pod for %q rejected by cluster admission", d.deployment.Name)
+		}
+	}
+	logrus.Debugf("skipping admission check for %q: %v", d.deployment.Name, err)
+	return nil
+}
+
+// canCreatePods returns whether the client is authorized to create pods in
+// the namespace of the deployment.
+func (d *Driver) canCreatePods(ctx context.Context) (bool, error) {
+	review, err := d.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: d.namespace,
+				Verb:      "create",
+				Resource:  "pods",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
 func (d *Driver) wait(ctx context.Context) error {
 	// TODO: use watch API
 	var (
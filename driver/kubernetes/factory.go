@@ -16,6 +16,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -135,6 +136,7 @@ func (f *factory) New(ctx context.Context, cfg driver.InitConfig) (driver.Driver
 
 	d.defaultLoad = defaultLoad
 	d.timeout = timeout
+	d.checkAdmission = deploymentOpt.Rootless || deploymentOpt.UserNamespace || deploymentOpt.SeccompProfile != nil || deploymentOpt.AppArmorProfile != ""
 
 	d.deployment, d.configMaps, err = manifest.NewDeployment(deploymentOpt)
 	if err != nil {
@@ -143,6 +145,7 @@ func (f *factory) New(ctx context.Context, cfg driver.InitConfig) (driver.Driver
 
 	d.minReplicas = deploymentOpt.Replicas
 
+	d.namespace = namespace
 	d.deploymentClient = clientset.AppsV1().Deployments(namespace)
 	d.podClient = clientset.CoreV1().Pods(namespace)
 	d.configMapClient = clientset.CoreV1().ConfigMaps(namespace)
@@ -215,6 +218,21 @@ func (f *factory) processDriverOpts(deploymentName string, namespace string, cfg
 			if _, isImage := cfg.DriverOpts["image"]; !isImage {
 				deploymentOpt.Image = bkimage.DefaultRootlessImage
 			}
+		case "seccomp-profile":
+			deploymentOpt.SeccompProfile, err = parseSeccompProfile(v)
+			if err != nil {
+				return nil, "", "", false, 0, err
+			}
+		case "apparmor-profile":
+			deploymentOpt.AppArmorProfile, err = parseAppArmorProfile(v)
+			if err != nil {
+				return nil, "", "", false, 0, err
+			}
+		case "user-namespace":
+			deploymentOpt.UserNamespace, err = strconv.ParseBool(v)
+			if err != nil {
+				return nil, "", "", false, 0, err
+			}
 		case "schedulername":
 			deploymentOpt.SchedulerName = v
 		case "serviceaccount":
@@ -235,39 +253,19 @@ func (f *factory) processDriverOpts(deploymentName string, namespace string, cfg
 				return nil, "", "", false, 0, errors.Wrap(err, "cannot parse labels")
 			}
 		case "tolerations":
-			ts := strings.Split(v, ";")
-			deploymentOpt.Tolerations = []corev1.Toleration{}
-			for i := range ts {
-				kvs := strings.Split(ts[i], ",")
-
-				t := corev1.Toleration{}
-
-				for j := range kvs {
-					kv := strings.Split(kvs[j], "=")
-					if len(kv) == 2 {
-						switch kv[0] {
-						case "key":
-							t.Key = kv[1]
-						case "operator":
-							t.Operator = corev1.TolerationOperator(kv[1])
-						case "value":
-							t.Value = kv[1]
-						case "effect":
-							t.Effect = corev1.TaintEffect(kv[1])
-						case "tolerationSeconds":
-							c, err := strconv.Atoi(kv[1])
-							if nil != err {
-								return nil, "", "", false, 0, err
-							}
-							c64 := int64(c)
-							t.TolerationSeconds = &c64
-						default:
-							return nil, "", "", false, 0, errors.Errorf("invalid tolaration %q", v)
-						}
-					}
-				}
-
-				deploymentOpt.Tolerations = append(deploymentOpt.Tolerations, t)
+			deploymentOpt.Tolerations, err = parseTolerations(v)
+			if err != nil {
+				return nil, "", "", false, 0, err
+			}
+		case "priorityclassname":
+			if errs := validation.IsDNS1123Subdomain(v); len(errs) > 0 {
+				return nil, "", "", false, 0, errors.Errorf("invalid priority class name %q: %s", v, strings.Join(errs, ", "))
+			}
+			deploymentOpt.PriorityClassName = v
+		case "topologyspread":
+			deploymentOpt.TopologySpread, err = parseTopologySpread(v)
+			if err != nil {
+				return nil, "", "", false, 0, err
 			}
 		case "loadbalance":
 			switch v {
@@ -304,6 +302,150 @@ func (f *factory) processDriverOpts(deploymentName string, namespace string, cfg
 	return deploymentOpt, loadbalance, namespace, defaultLoad, timeout, nil
 }
 
+// parseTolerations parses a list of tolerations separated by ";", each in the
+// form "key=<key>,operator=<operator>,value=<value>,effect=<effect>,
+// tolerationSeconds=<seconds>".
+func parseTolerations(v string) ([]corev1.Toleration, error) {
+	var tolerations []corev1.Toleration
+	for _, ts := range strings.Split(v, ";") {
+		t := corev1.Toleration{}
+		for _, field := range strings.Split(ts, ",") {
+			if field == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, errors.Errorf("invalid toleration %q", ts)
+			}
+			switch key {
+			case "key":
+				t.Key = value
+			case "operator":
+				t.Operator = corev1.TolerationOperator(value)
+			case "value":
+				t.Value = value
+			case "effect":
+				t.Effect = corev1.TaintEffect(value)
+			case "tolerationSeconds":
+				c, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid tolerationSeconds in toleration %q", ts)
+				}
+				t.TolerationSeconds = &c
+			default:
+				return nil, errors.Errorf("invalid toleration %q", v)
+			}
+		}
+		switch t.Operator {
+		case "", corev1.TolerationOpEqual:
+		case corev1.TolerationOpExists:
+			if t.Value != "" {
+				return nil, errors.Errorf("invalid toleration %q, value must be empty with the Exists operator", ts)
+			}
+		default:
+			return nil, errors.Errorf("invalid operator %q in toleration %q, expecting Equal or Exists", t.Operator, ts)
+		}
+		switch t.Effect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return nil, errors.Errorf("invalid effect %q in toleration %q, expecting NoSchedule, PreferNoSchedule or NoExecute", t.Effect, ts)
+		}
+		if t.Key == "" && t.Operator != corev1.TolerationOpExists {
+			return nil, errors.Errorf("invalid toleration %q, key can only be empty with the Exists operator", ts)
+		}
+		tolerations = append(tolerations, t)
+	}
+	return tolerations, nil
+}
+
+// topologyKeys are the shorthands of the well-known topology keys.
+var topologyKeys = map[string]string{
+	"zone":     corev1.LabelTopologyZone,
+	"region":   corev1.LabelTopologyRegion,
+	"hostname": corev1.LabelHostname,
+}
+
+// parseTopologySpread parses a list of topology spread constraints separated
+// by ";", each in the form "<topologyKey>[,maxSkew=<n>][,whenUnsatisfiable=
+// <action>]". The topology key can be zone, region or hostname for the
+// well-known keys. Builder pods are spread with a maximum skew of 1 and are
+// scheduled anyway if the constraint can't be satisfied by default.
+func parseTopologySpread(v string) ([]corev1.TopologySpreadConstraint, error) {
+	var constraints []corev1.TopologySpreadConstraint
+	for _, cs := range strings.Split(v, ";") {
+		c := corev1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+		}
+		for _, field := range strings.Split(cs, ",") {
+			if field == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				key, value = "topologyKey", field
+			}
+			switch key {
+			case "topologyKey":
+				if k, ok := topologyKeys[value]; ok {
+					value = k
+				}
+				c.TopologyKey = value
+			case "maxSkew":
+				n, err := strconv.ParseInt(value, 10, 32)
+				if err != nil || n < 1 {
+					return nil, errors.Errorf("invalid maxSkew %q in topology spread %q, expecting a positive number", value, cs)
+				}
+				c.MaxSkew = int32(n)
+			case "whenUnsatisfiable":
+				switch a := corev1.UnsatisfiableConstraintAction(value); a {
+				case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+					c.WhenUnsatisfiable = a
+				default:
+					return nil, errors.Errorf("invalid whenUnsatisfiable %q in topology spread %q, expecting DoNotSchedule or ScheduleAnyway", value, cs)
+				}
+			default:
+				return nil, errors.Errorf("invalid topology spread %q", cs)
+			}
+		}
+		if c.TopologyKey == "" {
+			return nil, errors.Errorf("invalid topology spread %q, topology key is required", cs)
+		}
+		if errs := validation.IsQualifiedName(c.TopologyKey); len(errs) > 0 {
+			return nil, errors.Errorf("invalid topology key %q: %s", c.TopologyKey, strings.Join(errs, ", "))
+		}
+		constraints = append(constraints, c)
+	}
+	return constraints, nil
+}
+
+// parseSeccompProfile parses a seccomp profile in the form "unconfined",
+// "runtime/default" or "localhost/<path>".
+func parseSeccompProfile(v string) (*corev1.SeccompProfile, error) {
+	switch {
+	case v == "unconfined":
+		return &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}, nil
+	case v == "runtime/default":
+		return &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}, nil
+	case strings.HasPrefix(v, "localhost/") && len(v) > len("localhost/"):
+		p := strings.TrimPrefix(v, "localhost/")
+		return &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost, LocalhostProfile: &p}, nil
+	}
+	return nil, errors.Errorf("invalid seccomp profile %q", v)
+}
+
+// parseAppArmorProfile validates an AppArmor profile in the form
+// "unconfined", "runtime/default" or "localhost/<name>".
+func parseAppArmorProfile(v string) (string, error) {
+	switch {
+	case v == "unconfined", v == "runtime/default":
+		return v, nil
+	case strings.HasPrefix(v, "localhost/") && len(v) > len("localhost/"):
+		return v, nil
+	}
+	return "", errors.Errorf("invalid apparmor profile %q", v)
+}
+
 func splitMultiValues(in string, itemsep string, kvsep string) (map[string]string, error) {
 	kvs := strings.Split(strings.Trim(in, `"`), itemsep)
 	s := map[string]string{}
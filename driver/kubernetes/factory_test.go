@@ -37,24 +37,29 @@ func TestFactory_processDriverOpts(t *testing.T) {
 	t.Run(
 		"ValidOptions", func(t *testing.T) {
 			cfg.DriverOpts = map[string]string{
-				"namespace":       "test-ns",
-				"image":           "test:latest",
-				"replicas":        "2",
-				"timeout":         "300s",
-				"requests.cpu":    "100m",
-				"requests.memory": "32Mi",
-				"limits.cpu":      "200m",
-				"limits.memory":   "64Mi",
-				"rootless":        "true",
-				"nodeselector":    "selector1=value1,selector2=value2",
-				"tolerations":     "key=tolerationKey1,value=tolerationValue1,operator=Equal,effect=NoSchedule,tolerationSeconds=60;key=tolerationKey2,operator=Exists",
-				"annotations":     "example.com/expires-after=annotation1,example.com/other=annotation2",
-				"labels":          "example.com/owner=label1,example.com/other=label2",
-				"loadbalance":     "random",
-				"qemu.install":    "true",
-				"qemu.image":      "qemu:latest",
-				"default-load":    "true",
+				"namespace":        "test-ns",
+				"image":            "test:latest",
+				"replicas":         "2",
+				"timeout":          "300s",
+				"requests.cpu":     "100m",
+				"requests.memory":  "32Mi",
+				"limits.cpu":       "200m",
+				"limits.memory":    "64Mi",
+				"rootless":         "true",
+				"nodeselector":     "selector1=value1,selector2=value2",
+				"tolerations":      "key=tolerationKey1,value=tolerationValue1,operator=Equal,effect=NoSchedule,tolerationSeconds=60;key=tolerationKey2,operator=Exists",
+				"annotations":      "example.com/expires-after=annotation1,example.com/other=annotation2",
+				"labels":           "example.com/owner=label1,example.com/other=label2",
+				"loadbalance":      "random",
+				"qemu.install":     "true",
+				"qemu.image":       "qemu:latest",
+				"default-load":     "true",
+				"seccomp-profile":  "localhost/profiles/buildkitd.json",
+				"apparmor-profile": "runtime/default",
+				"user-namespace":   "true",
 			}
+			cfg.DriverOpts["priorityclassname"] = "buildkit-high"
+			cfg.DriverOpts["topologyspread"] = "zone;kubernetes.io/arch,maxSkew=2,whenUnsatisfiable=DoNotSchedule"
 			r, loadbalance, ns, defaultLoad, timeout, err := f.processDriverOpts(cfg.Name, "test", cfg)
 
 			nodeSelectors := map[string]string{
@@ -77,6 +82,19 @@ func TestFactory_processDriverOpts(t *testing.T) {
 				},
 			}
 
+			topologySpread := []v1.TopologySpreadConstraint{
+				{
+					MaxSkew:           1,
+					TopologyKey:       v1.LabelTopologyZone,
+					WhenUnsatisfiable: v1.ScheduleAnyway,
+				},
+				{
+					MaxSkew:           2,
+					TopologyKey:       "kubernetes.io/arch",
+					WhenUnsatisfiable: v1.DoNotSchedule,
+				},
+			}
+
 			customAnnotations := map[string]string{
 				"example.com/expires-after": "annotation1",
 				"example.com/other":         "annotation2",
@@ -101,11 +119,17 @@ func TestFactory_processDriverOpts(t *testing.T) {
 			require.Equal(t, customAnnotations, r.CustomAnnotations)
 			require.Equal(t, customLabels, r.CustomLabels)
 			require.Equal(t, tolerations, r.Tolerations)
+			require.Equal(t, "buildkit-high", r.PriorityClassName)
+			require.Equal(t, topologySpread, r.TopologySpread)
 			require.Equal(t, LoadbalanceRandom, loadbalance)
 			require.True(t, r.Qemu.Install)
 			require.Equal(t, "qemu:latest", r.Qemu.Image)
 			require.True(t, defaultLoad)
 			require.Equal(t, 300*time.Second, timeout)
+			require.Equal(t, v1.SeccompProfileTypeLocalhost, r.SeccompProfile.Type)
+			require.Equal(t, "profiles/buildkitd.json", *r.SeccompProfile.LocalhostProfile)
+			require.Equal(t, "runtime/default", r.AppArmorProfile)
+			require.True(t, r.UserNamespace)
 		},
 	)
 
@@ -208,6 +232,64 @@ func TestFactory_processDriverOpts(t *testing.T) {
 		},
 	)
 
+	t.Run(
+		"InvalidTolerationOperator", func(t *testing.T) {
+			cfg.DriverOpts = map[string]string{
+				"tolerations": "key=foo,operator=Equals",
+			}
+			_, _, _, _, _, err := f.processDriverOpts(cfg.Name, "test", cfg)
+			require.ErrorContains(t, err, `invalid operator "Equals"`)
+		},
+	)
+
+	t.Run(
+		"InvalidTolerationEffect", func(t *testing.T) {
+			cfg.DriverOpts = map[string]string{
+				"tolerations": "key=foo,effect=NoScheduling",
+			}
+			_, _, _, _, _, err := f.processDriverOpts(cfg.Name, "test", cfg)
+			require.ErrorContains(t, err, `invalid effect "NoScheduling"`)
+		},
+	)
+
+	t.Run(
+		"InvalidTolerationExistsValue", func(t *testing.T) {
+			cfg.DriverOpts = map[string]string{
+				"tolerations": "key=foo,operator=Exists,value=bar",
+			}
+			_, _, _, _, _, err := f.processDriverOpts(cfg.Name, "test", cfg)
+			require.Error(t, err)
+		},
+	)
+
+	t.Run(
+		"InvalidPriorityClassName", func(t *testing.T) {
+			cfg.DriverOpts = map[string]string{
+				"priorityclassname": "High_Priority",
+			}
+			_, _, _, _, _, err := f.processDriverOpts(cfg.Name, "test", cfg)
+			require.ErrorContains(t, err, "invalid priority class name")
+		},
+	)
+
+	t.Run(
+		"InvalidTopologySpread", func(t *testing.T) {
+			for _, v := range []string{
+				"zone,maxSkew=0",
+				"zone,whenUnsatisfiable=Never",
+				"maxSkew=1",
+				"zone,foo=bar",
+				"not a key",
+			} {
+				cfg.DriverOpts = map[string]string{
+					"topologyspread": v,
+				}
+				_, _, _, _, _, err := f.processDriverOpts(cfg.Name, "test", cfg)
+				require.Error(t, err, v)
+			}
+		},
+	)
+
 	t.Run(
 		"InvalidCustomAnnotation", func(t *testing.T) {
 			cfg.DriverOpts = map[string]string{
@@ -248,6 +330,26 @@ func TestFactory_processDriverOpts(t *testing.T) {
 		},
 	)
 
+	t.Run(
+		"InvalidSeccompProfile", func(t *testing.T) {
+			cfg.DriverOpts = map[string]string{
+				"seccomp-profile": "localhost/",
+			}
+			_, _, _, _, _, err := f.processDriverOpts(cfg.Name, "test", cfg)
+			require.Error(t, err)
+		},
+	)
+
+	t.Run(
+		"InvalidAppArmorProfile", func(t *testing.T) {
+			cfg.DriverOpts = map[string]string{
+				"apparmor-profile": "invalid",
+			}
+			_, _, _, _, _, err := f.processDriverOpts(cfg.Name, "test", cfg)
+			require.Error(t, err)
+		},
+	)
+
 	t.Run(
 		"InvalidOption", func(t *testing.T) {
 			cfg.DriverOpts = map[string]string{
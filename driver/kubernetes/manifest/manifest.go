@@ -33,10 +33,15 @@ type DeploymentOpt struct {
 	ConfigFiles map[string][]byte
 
 	Rootless                 bool
+	UserNamespace            bool
+	SeccompProfile           *corev1.SeccompProfile
+	AppArmorProfile          string
 	NodeSelector             map[string]string
 	CustomAnnotations        map[string]string
 	CustomLabels             map[string]string
 	Tolerations              []corev1.Toleration
+	PriorityClassName        string
+	TopologySpread           []corev1.TopologySpreadConstraint
 	RequestsCPU              string
 	RequestsMemory           string
 	RequestsEphemeralStorage string
@@ -50,6 +55,8 @@ const (
 	containerName      = "buildkitd"
 	AnnotationPlatform = "buildx.docker.com/platform"
 	LabelApp           = "app"
+
+	annotationAppArmorPrefix = "container.apparmor.security.beta.kubernetes.io/"
 )
 
 type ErrReservedAnnotationPlatform struct{}
@@ -191,6 +198,25 @@ func NewDeployment(opt *DeploymentOpt) (d *appsv1.Deployment, c []*corev1.Config
 		}
 	}
 
+	if opt.UserNamespace {
+		hostUsers := false
+		d.Spec.Template.Spec.HostUsers = &hostUsers
+	}
+
+	if opt.SeccompProfile != nil {
+		if d.Spec.Template.Spec.Containers[0].SecurityContext == nil {
+			d.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{}
+		}
+		d.Spec.Template.Spec.Containers[0].SecurityContext.SeccompProfile = opt.SeccompProfile
+	}
+
+	if opt.AppArmorProfile != "" {
+		if d.Spec.Template.ObjectMeta.Annotations == nil {
+			d.Spec.Template.ObjectMeta.Annotations = make(map[string]string, 1)
+		}
+		d.Spec.Template.ObjectMeta.Annotations[annotationAppArmorPrefix+containerName] = opt.AppArmorProfile
+	}
+
 	if len(opt.NodeSelector) > 0 {
 		d.Spec.Template.Spec.NodeSelector = opt.NodeSelector
 	}
@@ -199,6 +225,20 @@ func NewDeployment(opt *DeploymentOpt) (d *appsv1.Deployment, c []*corev1.Config
 		d.Spec.Template.Spec.Tolerations = opt.Tolerations
 	}
 
+	if opt.PriorityClassName != "" {
+		d.Spec.Template.Spec.PriorityClassName = opt.PriorityClassName
+	}
+
+	for _, c := range opt.TopologySpread {
+		if c.LabelSelector == nil {
+			// spread the pods of the builder
+			c.LabelSelector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{LabelApp: opt.Name},
+			}
+		}
+		d.Spec.Template.Spec.TopologySpreadConstraints = append(d.Spec.Template.Spec.TopologySpreadConstraints, c)
+	}
+
 	if opt.RequestsCPU != "" {
 		reqCPU, err := resource.ParseQuantity(opt.RequestsCPU)
 		if err != nil {
@@ -263,7 +303,7 @@ func toRootless(d *appsv1.Deployment) error {
 	if d.Spec.Template.ObjectMeta.Annotations == nil {
 		d.Spec.Template.ObjectMeta.Annotations = make(map[string]string, 1)
 	}
-	d.Spec.Template.ObjectMeta.Annotations["container.apparmor.security.beta.kubernetes.io/"+containerName] = "unconfined"
+	d.Spec.Template.ObjectMeta.Annotations[annotationAppArmorPrefix+containerName] = "unconfined"
 
 	// Dockerfile has `VOLUME /home/user/.local/share/buildkit` by default too,
 	// but the default VOLUME does not work with rootless on Google's Container-Optimized OS
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"net"
+	"net/url"
 	"os"
 	"strings"
 	"sync"
@@ -15,6 +16,7 @@ import (
 	"github.com/docker/buildx/util/progress"
 	"github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/client/connhelper"
+	"github.com/moby/buildkit/client/connhelper/ssh"
 	"github.com/moby/buildkit/util/tracing/delegated"
 	"github.com/pkg/errors"
 )
@@ -27,6 +29,8 @@ type Driver struct {
 	// https://github.com/docker/docs/blob/main/content/build/drivers/remote.md
 	*tlsOpts
 	defaultLoad bool
+	proxy       *url.URL
+	sshJump     *ssh.Spec
 
 	// remote driver caches the client because its Bootstap/Info methods reuse it internally
 	clientOnce sync.Once
@@ -115,8 +119,15 @@ func (d *Driver) Dial(ctx context.Context) (net.Conn, error) {
 		return nil, errors.Errorf("invalid endpoint address: %s", d.InitConfig.EndpointAddr)
 	}
 
-	conn, err := util.DialContext(ctx, network, addr)
-
+	var conn net.Conn
+	switch {
+	case d.proxy != nil:
+		conn, err = util.DialProxy(ctx, d.proxy, addr)
+	case d.sshJump != nil:
+		conn, err = util.DialSSHJump(ctx, d.sshJump, addr)
+	default:
+		conn, err = util.DialContext(ctx, network, addr)
+	}
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -87,11 +87,32 @@ func (f *factory) New(ctx context.Context, cfg driver.InitConfig) (driver.Driver
 				return nil, err
 			}
 			d.defaultLoad = parsed
+		case "proxy":
+			u, err := util.ParseProxy(v)
+			if err != nil {
+				return nil, err
+			}
+			d.proxy = u
+		case "ssh-jump":
+			sp, err := util.ParseSSHJump(v)
+			if err != nil {
+				return nil, err
+			}
+			d.sshJump = sp
 		default:
 			return nil, errors.Errorf("invalid driver option %s for remote driver", k)
 		}
 	}
 
+	if d.proxy != nil || d.sshJump != nil {
+		if d.proxy != nil && d.sshJump != nil {
+			return nil, errors.Errorf("proxy and ssh-jump driver options are mutually exclusive")
+		}
+		if !strings.HasPrefix(cfg.EndpointAddr, "tcp://") {
+			return nil, errors.Errorf("proxy and ssh-jump driver options are only supported for tcp endpoints")
+		}
+	}
+
 	if tlsEnabled {
 		if tls.serverName == "" {
 			// guess servername as hostname of target address
@@ -16,6 +16,7 @@ import (
 const (
 	refsDir  = "refs"
 	groupDir = "__group__"
+	bakeDir  = "__bake__"
 )
 
 type State struct {
@@ -28,6 +29,8 @@ type State struct {
 	DockerfilePath string
 	// GroupRef is the ref of the state group that this ref belongs to
 	GroupRef string `json:",omitempty"`
+	// CancelReason is the reason given when the build was canceled
+	CancelReason string `json:",omitempty"`
 }
 
 type StateGroup struct {
@@ -111,6 +114,35 @@ func (ls *LocalState) SaveGroup(id string, stg StateGroup) error {
 	return ls.cfg.AtomicWriteFile(filepath.Join(refDir, id), dt, 0600)
 }
 
+// ReadBakeDefinition returns the last bake definition saved with
+// SaveBakeDefinition for the key, or nil if there is none.
+func (ls *LocalState) ReadBakeDefinition(key string) ([]byte, error) {
+	if key == "" {
+		return nil, errors.Errorf("bake definition key empty")
+	}
+	dt, err := os.ReadFile(filepath.Join(ls.cfg.Dir(), refsDir, bakeDir, key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return dt, nil
+}
+
+// SaveBakeDefinition saves the resolved bake definition of an invocation,
+// replacing the previous one saved for the key.
+func (ls *LocalState) SaveBakeDefinition(key string, dt []byte) error {
+	if key == "" {
+		return errors.Errorf("bake definition key empty")
+	}
+	refDir := filepath.Join(refsDir, bakeDir)
+	if err := ls.cfg.MkdirAll(refDir, 0700); err != nil {
+		return err
+	}
+	return ls.cfg.AtomicWriteFile(filepath.Join(refDir, key), dt, 0600)
+}
+
 func (ls *LocalState) RemoveBuilder(builderName string) error {
 	if builderName == "" {
 		return errors.Errorf("builder name empty")
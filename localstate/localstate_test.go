@@ -26,6 +26,18 @@ func TestReadGroup(t *testing.T) {
 	require.Equal(t, testStateGroup, *g)
 }
 
+func TestBakeDefinition(t *testing.T) {
+	l := newls(t)
+	dt, err := l.ReadBakeDefinition("abc")
+	require.NoError(t, err)
+	require.Nil(t, dt)
+
+	require.NoError(t, l.SaveBakeDefinition("abc", []byte(`{"target":{}}`)))
+	dt, err = l.ReadBakeDefinition("abc")
+	require.NoError(t, err)
+	require.Equal(t, `{"target":{}}`, string(dt))
+}
+
 func TestRemoveBuilder(t *testing.T) {
 	l := newls(t)
 	require.NoError(t, l.RemoveBuilder(testBuilderName))
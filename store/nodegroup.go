@@ -2,10 +2,10 @@ package store
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/containerd/platforms"
-	"github.com/docker/buildx/util/confutil"
 	"github.com/docker/buildx/util/platformutil"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
@@ -18,11 +18,37 @@ type NodeGroup struct {
 	Nodes   []Node
 	Dynamic bool
 
+	// BuildDefaults are applied to all the builds run on the instance
+	// unless overridden per invocation.
+	BuildDefaults *BuildDefaults `json:",omitempty"`
+
+	// MaxConcurrentBuilds is the number of builds that can run on the
+	// instance at the same time. Other builds wait for one of them to
+	// complete. Zero means no limit.
+	MaxConcurrentBuilds int `json:",omitempty"`
+
 	// skip the following fields from being saved in the store
 	DockerContext bool      `json:"-"`
 	LastActivity  time.Time `json:"-"`
 }
 
+type BuildDefaults struct {
+	// ShmSize is the default size of /dev/shm in bytes.
+	ShmSize int64 `json:",omitempty"`
+	// Ulimits are the default ulimits in docker type=soft:hard format.
+	Ulimits []string `json:",omitempty"`
+}
+
+func (d *BuildDefaults) Copy() *BuildDefaults {
+	if d == nil {
+		return nil
+	}
+	return &BuildDefaults{
+		ShmSize: d.ShmSize,
+		Ulimits: slices.Clone(d.Ulimits),
+	}
+}
+
 type Node struct {
 	Name           string
 	Endpoint       string
@@ -48,7 +74,7 @@ func (ng *NodeGroup) Leave(name string) error {
 	return nil
 }
 
-func (ng *NodeGroup) Update(name, endpoint string, platforms []string, endpointsSet bool, actionAppend bool, buildkitdFlags []string, buildkitdConfigFile string, do map[string]string) error {
+func (ng *NodeGroup) Update(name, endpoint string, platforms []string, endpointsSet bool, actionAppend bool, buildkitdFlags []string, files map[string][]byte, do map[string]string) error {
 	if ng.Dynamic {
 		return errors.New("dynamic node group does not support Update")
 	}
@@ -65,14 +91,6 @@ func (ng *NodeGroup) Update(name, endpoint string, platforms []string, endpoints
 		return err
 	}
 
-	var files map[string][]byte
-	if buildkitdConfigFile != "" {
-		files, err = confutil.LoadConfigFiles(buildkitdConfigFile)
-		if err != nil {
-			return err
-		}
-	}
-
 	if i != -1 {
 		n := ng.Nodes[i]
 		needsRestart := false
@@ -91,7 +109,10 @@ func (ng *NodeGroup) Update(name, endpoint string, platforms []string, endpoints
 			n.DriverOpts = do
 			needsRestart = true
 		}
-		if buildkitdConfigFile != "" {
+		if files != nil {
+			if n.Files == nil {
+				n.Files = map[string][]byte{}
+			}
 			for k, v := range files {
 				n.Files[k] = v
 			}
@@ -137,6 +158,9 @@ func (ng *NodeGroup) Copy() *NodeGroup {
 		Driver:  ng.Driver,
 		Nodes:   nodes,
 		Dynamic: ng.Dynamic,
+
+		BuildDefaults:       ng.BuildDefaults.Copy(),
+		MaxConcurrentBuilds: ng.MaxConcurrentBuilds,
 	}
 }
 
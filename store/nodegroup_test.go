@@ -11,16 +11,16 @@ func TestNodeGroupUpdate(t *testing.T) {
 	t.Parallel()
 
 	ng := &NodeGroup{}
-	err := ng.Update("foo", "foo0", []string{"linux/amd64"}, true, false, []string{"--debug"}, "", nil)
+	err := ng.Update("foo", "foo0", []string{"linux/amd64"}, true, false, []string{"--debug"}, nil, nil)
 	require.NoError(t, err)
 
-	err = ng.Update("foo1", "foo1", []string{"linux/arm64", "linux/arm/v7"}, true, true, nil, "", nil)
+	err = ng.Update("foo1", "foo1", []string{"linux/arm64", "linux/arm/v7"}, true, true, nil, nil, nil)
 	require.NoError(t, err)
 
 	require.Equal(t, 2, len(ng.Nodes))
 
 	// update
-	err = ng.Update("foo", "foo2", []string{"linux/amd64", "linux/arm"}, true, false, nil, "", nil)
+	err = ng.Update("foo", "foo2", []string{"linux/amd64", "linux/arm"}, true, false, nil, nil, nil)
 	require.NoError(t, err)
 
 	require.Equal(t, 2, len(ng.Nodes))
@@ -32,7 +32,7 @@ func TestNodeGroupUpdate(t *testing.T) {
 	require.Equal(t, []string(nil), ng.Nodes[1].BuildkitdFlags)
 
 	// duplicate endpoint
-	err = ng.Update("foo1", "foo2", nil, true, false, nil, "", nil)
+	err = ng.Update("foo1", "foo2", nil, true, false, nil, nil, nil)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "duplicate endpoint")
 
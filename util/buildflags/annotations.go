@@ -0,0 +1,47 @@
+package buildflags
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Annotations is a list of annotations in the KEY=VALUE format, where the key
+// can be prefixed with the levels of the annotation. It can also be set from
+// a map of keys to values.
+type Annotations []string
+
+func (a *Annotations) UnmarshalJSON(data []byte) error {
+	var l []string
+	if err := json.Unmarshal(data, &l); err == nil {
+		*a = l
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return errors.New("annotations must be a list of KEY=VALUE strings or a map")
+	}
+	return a.fromMap(m)
+}
+
+// fromMap sets the annotations from a map of keys, with their optional
+// levels, to values. The annotations are sorted by key so that the result
+// is deterministic.
+func (a *Annotations) fromMap(m map[string]string) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	l := make([]string, 0, len(keys))
+	for _, k := range keys {
+		l = append(l, k+"="+m[k])
+	}
+	if _, err := ParseAnnotations(l); err != nil {
+		return err
+	}
+	*a = l
+	return nil
+}
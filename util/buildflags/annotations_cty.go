@@ -0,0 +1,54 @@
+package buildflags
+
+import (
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+func (a *Annotations) FromCtyValue(in cty.Value, p cty.Path) error {
+	got := in.Type()
+	switch {
+	case got.IsTupleType() || got.IsListType():
+		conv, err := convert.Convert(in, cty.List(cty.String))
+		if err != nil {
+			return p.NewError(err)
+		}
+		*a = make([]string, 0, conv.LengthInt())
+		for elem := conv.ElementIterator(); elem.Next(); {
+			_, value := elem.Element()
+			if value.IsNull() || isEmpty(value) {
+				continue
+			}
+			*a = append(*a, value.AsString())
+		}
+		return nil
+	case got.IsObjectType() || got.IsMapType():
+		conv, err := convert.Convert(in, cty.Map(cty.String))
+		if err != nil {
+			return p.NewError(err)
+		}
+		m := make(map[string]string, conv.LengthInt())
+		for k, v := range conv.AsValueMap() {
+			if !v.IsNull() {
+				m[k] = v.AsString()
+			}
+		}
+		if err := a.fromMap(m); err != nil {
+			return p.NewError(err)
+		}
+		return nil
+	}
+	return p.NewErrorf("%s", convert.MismatchMessage(got, cty.List(cty.String)))
+}
+
+func (a Annotations) ToCtyValue() cty.Value {
+	if len(a) == 0 {
+		return cty.ListValEmpty(cty.String)
+	}
+
+	vals := make([]cty.Value, len(a))
+	for i, v := range a {
+		vals[i] = cty.StringVal(v)
+	}
+	return cty.ListVal(vals)
+}
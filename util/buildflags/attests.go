@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"maps"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -91,7 +92,7 @@ func (a *Attest) ToPB() *controllerapi.Attest {
 
 func (a *Attest) MarshalJSON() ([]byte, error) {
 	m := make(map[string]interface{}, len(a.Attrs)+2)
-	for k, v := range m {
+	for k, v := range a.Attrs {
 		m[k] = v
 	}
 	m["type"] = a.Type
@@ -170,6 +171,49 @@ func (a *Attest) validate() error {
 	if a.Type == "" {
 		return errors.Errorf("attestation type not specified")
 	}
+	if a.Type == "provenance" && !a.Disabled {
+		if err := validateProvenanceAttrs(a.Attrs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProvenanceAttrVCS is the provenance attribute that controls whether
+// version control information of the build context is embedded in the
+// provenance. It is handled by buildx and not passed to BuildKit.
+const ProvenanceAttrVCS = "vcs"
+
+var provenanceAttrs = []string{"builder-id", "filename", "inline-only", "mode", "reproducible", "version", ProvenanceAttrVCS}
+
+func validateProvenanceAttrs(attrs map[string]string) error {
+	for k, v := range attrs {
+		switch k {
+		case "mode":
+			if v != "min" && v != "max" {
+				return errors.Errorf("invalid provenance mode %q, expected min or max", v)
+			}
+		case "builder-id":
+			u, err := url.Parse(v)
+			if err != nil || !u.IsAbs() {
+				return errors.Errorf("invalid provenance builder-id %q, expected an absolute URI", v)
+			}
+		case "inline-only", "reproducible", ProvenanceAttrVCS:
+			if _, err := strconv.ParseBool(v); err != nil {
+				return errors.Errorf("invalid provenance %s value %q, expected a boolean", k, v)
+			}
+		case "version":
+			if v != "v0.2" && v != "v1" {
+				return errors.Errorf("invalid provenance version %q, expected v0.2 or v1", v)
+			}
+		case "filename":
+			if v == "" {
+				return errors.Errorf("provenance filename cannot be empty")
+			}
+		default:
+			return errors.Errorf("unknown provenance attribute %q, supported attributes are: %s", k, strings.Join(provenanceAttrs, ", "))
+		}
+	}
 	return nil
 }
 
@@ -77,6 +77,9 @@ func (e *Attest) FromCtyValue(in cty.Value, p cty.Path) error {
 			e.Attrs[key] = v.AsString()
 		}
 	}
+	if err := e.validate(); err != nil {
+		return p.NewError(err)
+	}
 	return nil
 }
 
@@ -0,0 +1,82 @@
+package buildflags
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProvenanceAttest(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr string
+	}{
+		{
+			name: "mode",
+			in:   "type=provenance,mode=max",
+		},
+		{
+			name: "all attributes",
+			in:   "type=provenance,mode=min,builder-id=https://github.com/docker/buildx/actions/runs/1,reproducible=true,inline-only=false,vcs=false,version=v1,filename=prov.json",
+		},
+		{
+			name: "disabled ignores attributes",
+			in:   "type=provenance,disabled=true,mode=foo",
+		},
+		{
+			name:    "invalid mode",
+			in:      "type=provenance,mode=full",
+			wantErr: `invalid provenance mode "full", expected min or max`,
+		},
+		{
+			name:    "relative builder-id",
+			in:      "type=provenance,builder-id=mybuilder",
+			wantErr: `invalid provenance builder-id "mybuilder", expected an absolute URI`,
+		},
+		{
+			name:    "invalid bool",
+			in:      "type=provenance,reproducible=yes",
+			wantErr: `invalid provenance reproducible value "yes", expected a boolean`,
+		},
+		{
+			name:    "invalid version",
+			in:      "type=provenance,version=v2",
+			wantErr: `invalid provenance version "v2", expected v0.2 or v1`,
+		},
+		{
+			name:    "unknown attribute",
+			in:      "type=provenance,builderid=https://example.com",
+			wantErr: `unknown provenance attribute "builderid"`,
+		},
+		{
+			name: "other types are not validated",
+			in:   "type=sbom,generator=foo,bar=baz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAttests([]string{tt.in})
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAttestJSON(t *testing.T) {
+	a := &Attest{
+		Type:  "provenance",
+		Attrs: map[string]string{"mode": "max"},
+	}
+	dt, err := json.Marshal(a)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"provenance","mode":"max"}`, string(dt))
+
+	var out Attest
+	require.NoError(t, json.Unmarshal(dt, &out))
+	require.Equal(t, a, &out)
+}
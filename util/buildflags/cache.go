@@ -50,6 +50,24 @@ func (o CacheOptions) ToPB() []*controllerapi.CacheOptionsEntry {
 	return outs
 }
 
+// Resolve returns the options with the attribute values referenced with the
+// env:// or file:// scheme resolved. The options themselves keep the
+// references so that the values are not printed.
+func (o CacheOptions) Resolve() (CacheOptions, error) {
+	if len(o) == 0 {
+		return o, nil
+	}
+	out := make(CacheOptions, len(o))
+	for i, entry := range o {
+		attrs, err := resolveAttrs(entry.Attrs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cache option %s", entry.Type)
+		}
+		out[i] = &CacheOptionsEntry{Type: entry.Type, Attrs: attrs}
+	}
+	return out, nil
+}
+
 type CacheOptionsEntry struct {
 	Type  string            `json:"type"`
 	Attrs map[string]string `json:"attrs,omitempty"`
@@ -180,6 +198,10 @@ func ParseCacheEntry(in []string) ([]*controllerapi.CacheOptionsEntry, error) {
 		}
 		opts = append(opts, &out)
 	}
+	opts, err := opts.Resolve()
+	if err != nil {
+		return nil, err
+	}
 	return opts.ToPB(), nil
 }
 
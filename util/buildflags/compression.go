@@ -0,0 +1,137 @@
+package buildflags
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/moby/buildkit/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	CompressionUncompressed = "uncompressed"
+	CompressionGzip         = "gzip"
+	CompressionEstargz      = "estargz"
+	CompressionZstd         = "zstd"
+)
+
+// Compression holds the layer compression of the image exports of a build.
+type Compression struct {
+	Type string
+	// Level is the compression level, or nil for the default level of Type.
+	Level *int
+	// Force recompresses the layers that already exist with another
+	// compression.
+	Force bool
+	// Estimate reports the expected size difference of the exported layers
+	// against gzip.
+	Estimate bool
+}
+
+// ParseCompression parses a compression in the format
+// "<type>[:<level>][,force][,estimate]". It returns nil for an empty value.
+func ParseCompression(s string) (*Compression, error) {
+	if s == "" {
+		return nil, nil
+	}
+	fields := strings.Split(s, ",")
+
+	var c Compression
+	typ, level, hasLevel := strings.Cut(fields[0], ":")
+	c.Type = strings.ToLower(strings.TrimSpace(typ))
+	maxLevel := 9
+	switch c.Type {
+	case CompressionUncompressed:
+		maxLevel = -1
+	case CompressionGzip, CompressionEstargz:
+	case CompressionZstd:
+		maxLevel = 22
+	default:
+		return nil, errors.Errorf("invalid compression type %q, expecting one of %s", typ, strings.Join([]string{CompressionUncompressed, CompressionGzip, CompressionEstargz, CompressionZstd}, ", "))
+	}
+	if hasLevel {
+		if maxLevel < 0 {
+			return nil, errors.Errorf("compression level is not supported for %s", c.Type)
+		}
+		l, err := strconv.Atoi(strings.TrimSpace(level))
+		if err != nil || l < 0 || l > maxLevel {
+			return nil, errors.Errorf("invalid compression level %q for %s, expecting 0-%d", level, c.Type, maxLevel)
+		}
+		c.Level = &l
+	}
+
+	for _, f := range fields[1:] {
+		switch strings.ToLower(strings.TrimSpace(f)) {
+		case "force":
+			c.Force = true
+		case "estimate":
+			c.Estimate = true
+		default:
+			return nil, errors.Errorf("invalid compression option %q, expecting force or estimate", f)
+		}
+	}
+	return &c, nil
+}
+
+func (c *Compression) String() string {
+	if c.Level == nil {
+		return c.Type
+	}
+	return c.Type + ":" + strconv.Itoa(*c.Level)
+}
+
+// Apply sets the compression attributes of an exporter that produces image
+// layers, unless they are already set.
+func (c *Compression) Apply(typ string, attrs map[string]string) {
+	switch typ {
+	case client.ExporterImage, "registry", client.ExporterOCI, client.ExporterDocker:
+	default:
+		return
+	}
+	setDefault := func(k, v string) {
+		if _, ok := attrs[k]; !ok {
+			attrs[k] = v
+		}
+	}
+	setDefault("compression", c.Type)
+	if c.Level != nil {
+		setDefault("compression-level", strconv.Itoa(*c.Level))
+	}
+	if c.Force {
+		setDefault("force-compression", "true")
+	}
+}
+
+// GzipRatio returns the estimated size of layers compressed with c, relative
+// to the same layers compressed with gzip at the default level. The ratios are
+// rough averages observed on typical image layers.
+func (c *Compression) GzipRatio() float64 {
+	switch c.Type {
+	case CompressionUncompressed:
+		return 2.5
+	case CompressionGzip, CompressionEstargz:
+		level := 6
+		if c.Level != nil {
+			level = *c.Level
+		}
+		if level == 0 {
+			return 2.5
+		}
+		r := 1 + float64(6-level)*0.015
+		if c.Type == CompressionEstargz {
+			// table of contents and per-file gzip streams
+			r *= 1.04
+		}
+		return r
+	case CompressionZstd:
+		level := 3
+		if c.Level != nil {
+			level = *c.Level
+		}
+		if level <= 3 {
+			return 1 - float64(level)*0.02
+		}
+		return 0.94 - float64(level-3)*0.01
+	}
+	return 1
+}
@@ -0,0 +1,88 @@
+package buildflags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCompression(t *testing.T) {
+	level := func(l int) *int { return &l }
+
+	cases := []struct {
+		in       string
+		expected *Compression
+		err      string
+	}{
+		{in: ""},
+		{
+			in:       "zstd",
+			expected: &Compression{Type: "zstd"},
+		},
+		{
+			in:       "zstd:3,estimate",
+			expected: &Compression{Type: "zstd", Level: level(3), Estimate: true},
+		},
+		{
+			in:       "gzip:9,force",
+			expected: &Compression{Type: "gzip", Level: level(9), Force: true},
+		},
+		{
+			in:       "uncompressed",
+			expected: &Compression{Type: "uncompressed"},
+		},
+		{in: "zstd:23", err: `invalid compression level "23" for zstd, expecting 0-22`},
+		{in: "estargz:10", err: `invalid compression level "10" for estargz, expecting 0-9`},
+		{in: "uncompressed:1", err: "compression level is not supported for uncompressed"},
+		{in: "lz4", err: `invalid compression type "lz4"`},
+		{in: "zstd,fast", err: `invalid compression option "fast"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			c, err := ParseCompression(tc.in)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, c)
+		})
+	}
+}
+
+func TestCompressionApply(t *testing.T) {
+	c, err := ParseCompression("zstd:3,force")
+	require.NoError(t, err)
+	require.Equal(t, "zstd:3", c.String())
+
+	attrs := map[string]string{}
+	c.Apply("image", attrs)
+	require.Equal(t, map[string]string{
+		"compression":       "zstd",
+		"compression-level": "3",
+		"force-compression": "true",
+	}, attrs)
+
+	// attributes of the output take precedence
+	attrs = map[string]string{"compression": "gzip"}
+	c.Apply("oci", attrs)
+	require.Equal(t, "gzip", attrs["compression"])
+	require.Equal(t, "3", attrs["compression-level"])
+
+	attrs = map[string]string{}
+	c.Apply("local", attrs)
+	require.Empty(t, attrs)
+}
+
+func TestCompressionGzipRatio(t *testing.T) {
+	ratio := func(s string) float64 {
+		c, err := ParseCompression(s)
+		require.NoError(t, err)
+		return c.GzipRatio()
+	}
+	require.InDelta(t, 1.0, ratio("gzip"), 0.001)
+	require.Less(t, ratio("zstd:3"), ratio("zstd:1"))
+	require.Less(t, ratio("zstd:19"), ratio("zstd:3"))
+	require.Greater(t, ratio("estargz"), ratio("gzip"))
+	require.Greater(t, ratio("uncompressed"), ratio("gzip:1"))
+}
@@ -49,6 +49,23 @@ func (e Exports) ToPB() []*controllerapi.ExportEntry {
 	return entries
 }
 
+// Resolve returns the exports with the attribute values referenced with the
+// env:// or file:// scheme resolved.
+func (e Exports) Resolve() (Exports, error) {
+	if len(e) == 0 {
+		return e, nil
+	}
+	out := make(Exports, len(e))
+	for i, entry := range e {
+		attrs, err := resolveAttrs(entry.Attrs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid output %s", entry.Type)
+		}
+		out[i] = &ExportEntry{Type: entry.Type, Attrs: attrs, Destination: entry.Destination}
+	}
+	return out, nil
+}
+
 type ExportEntry struct {
 	Type        string            `json:"type"`
 	Attrs       map[string]string `json:"attrs,omitempty"`
@@ -180,6 +197,10 @@ func ParseExports(inp []string) ([]*controllerapi.ExportEntry, error) {
 		}
 		export = append(export, &out)
 	}
+	export, err := export.Resolve()
+	if err != nil {
+		return nil, err
+	}
 	return export.ToPB(), nil
 }
 
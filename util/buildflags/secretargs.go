@@ -0,0 +1,96 @@
+package buildflags
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tonistiigi/go-csvvalue"
+)
+
+// SecretArgPatternsEnv holds additional patterns matching the values of build
+// arguments that are likely secrets, as comma-separated name=regexp pairs.
+const SecretArgPatternsEnv = "BUILDX_SECRET_ARG_PATTERNS"
+
+// SecretArgPattern matches the values of build arguments that are likely
+// secrets.
+type SecretArgPattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+var defaultSecretArgPatterns = []SecretArgPattern{
+	{Name: "AWS access key ID", Regexp: regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "GitHub token", Regexp: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{Name: "GitLab token", Regexp: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{Name: "Slack token", Regexp: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{Name: "Google API key", Regexp: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{Name: "JSON web token", Regexp: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\b`)},
+	{Name: "private key", Regexp: regexp.MustCompile(`-----BEGIN ([A-Z]+ )*PRIVATE KEY( BLOCK)?-----`)},
+	{Name: "credentials in URL", Regexp: regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:[^/\s:@]+@`)},
+}
+
+// DefaultSecretArgPatterns returns the built-in patterns followed by the ones
+// set with the BUILDX_SECRET_ARG_PATTERNS environment variable.
+func DefaultSecretArgPatterns() ([]SecretArgPattern, error) {
+	extra, err := ParseSecretArgPatterns(os.Getenv(SecretArgPatternsEnv))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", SecretArgPatternsEnv)
+	}
+	return append(append([]SecretArgPattern{}, defaultSecretArgPatterns...), extra...), nil
+}
+
+// ParseSecretArgPatterns parses comma-separated name=regexp pairs. Patterns
+// containing a comma must be quoted.
+func ParseSecretArgPatterns(in string) ([]SecretArgPattern, error) {
+	if strings.TrimSpace(in) == "" {
+		return nil, nil
+	}
+	fields, err := csvvalue.Fields(in, nil)
+	if err != nil {
+		return nil, err
+	}
+	patterns := make([]SecretArgPattern, 0, len(fields))
+	for _, field := range fields {
+		name, expr, ok := strings.Cut(field, "=")
+		if !ok || name == "" || expr == "" {
+			return nil, errors.Errorf("expected name=regexp, got %q", field)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern %q", name)
+		}
+		patterns = append(patterns, SecretArgPattern{Name: name, Regexp: re})
+	}
+	return patterns, nil
+}
+
+// SecretArg is a build argument whose value is likely a secret.
+type SecretArg struct {
+	Name    string
+	Pattern string
+}
+
+// ScanSecretArgs returns the build arguments whose value matches one of the
+// patterns, sorted by name. Only the first matching pattern of each argument
+// is reported.
+func ScanSecretArgs(args map[string]string, patterns []SecretArgPattern) []SecretArg {
+	var res []SecretArg
+	for k, v := range args {
+		if v == "" {
+			continue
+		}
+		for _, p := range patterns {
+			if p.Regexp.MatchString(v) {
+				res = append(res, SecretArg{Name: k, Pattern: p.Name})
+				break
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
@@ -1,6 +1,7 @@
 package buildflags
 
 import (
+	"encoding/json"
 	"strings"
 
 	controllerapi "github.com/docker/buildx/controller/pb"
@@ -73,6 +74,22 @@ func (s *Secret) ToPB() *controllerapi.Secret {
 	}
 }
 
+func (s *Secret) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		return s.UnmarshalText([]byte(str))
+	}
+
+	type secret Secret
+	var v secret
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = Secret(v)
+	s.normalize()
+	return nil
+}
+
 func (s *Secret) UnmarshalText(text []byte) error {
 	value := string(text)
 	fields, err := csvvalue.Fields(value, nil)
@@ -112,9 +129,26 @@ func (s *Secret) UnmarshalText(text []byte) error {
 		s.Env = s.FilePath
 		s.FilePath = ""
 	}
+	s.normalize()
 	return nil
 }
 
+// normalize moves a source referenced with the env:// or file:// scheme to
+// the field of its type. The value itself is only read when the secret is
+// exposed to the build.
+func (s *Secret) normalize() {
+	switch {
+	case strings.HasPrefix(s.FilePath, envScheme):
+		if s.Env == "" {
+			s.Env = strings.TrimPrefix(s.FilePath, envScheme)
+			s.FilePath = ""
+		}
+	case strings.HasPrefix(s.FilePath, fileScheme):
+		s.FilePath = strings.TrimPrefix(s.FilePath, fileScheme)
+	}
+	s.Env = strings.TrimPrefix(s.Env, envScheme)
+}
+
 func ParseSecretSpecs(sl []string) ([]*controllerapi.Secret, error) {
 	fs := make([]*controllerapi.Secret, 0, len(sl))
 	for _, v := range sl {
@@ -80,6 +80,7 @@ func (e *Secret) FromCtyValue(in cty.Value, p cty.Path) error {
 	if env := conv.GetAttr("env"); !env.IsNull() {
 		e.Env = env.AsString()
 	}
+	e.normalize()
 	return nil
 }
 
@@ -0,0 +1,25 @@
+package buildflags
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretUnmarshalJSON(t *testing.T) {
+	var s Secret
+	require.NoError(t, json.Unmarshal([]byte(`"id=mysecret,src=/local/secret"`), &s))
+	require.Equal(t, Secret{ID: "mysecret", FilePath: "/local/secret"}, s)
+
+	s = Secret{}
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"token","env":"TOKEN"}`), &s))
+	require.Equal(t, Secret{ID: "token", Env: "TOKEN"}, s)
+
+	var secrets Secrets
+	require.NoError(t, json.Unmarshal([]byte(`["id=mysecret,src=/local/secret",{"id":"token","env":"TOKEN"}]`), &secrets))
+	require.Equal(t, Secrets{
+		{ID: "mysecret", FilePath: "/local/secret"},
+		{ID: "token", Env: "TOKEN"},
+	}, secrets)
+}
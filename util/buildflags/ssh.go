@@ -2,11 +2,13 @@ package buildflags
 
 import (
 	"cmp"
+	"encoding/json"
 	"slices"
 	"strings"
 
 	controllerapi "github.com/docker/buildx/controller/pb"
 	"github.com/moby/buildkit/util/gitutil"
+	"github.com/pkg/errors"
 )
 
 type SSHKeys []*SSH
@@ -76,6 +78,21 @@ func (s *SSH) ToPB() *controllerapi.SSH {
 	}
 }
 
+func (s *SSH) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		return s.UnmarshalText([]byte(str))
+	}
+
+	type ssh SSH
+	var v ssh
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = SSH(v)
+	return s.resolvePaths()
+}
+
 func (s *SSH) UnmarshalText(text []byte) error {
 	parts := strings.SplitN(string(text), "=", 2)
 
@@ -85,6 +102,25 @@ func (s *SSH) UnmarshalText(text []byte) error {
 	} else {
 		s.Paths = nil
 	}
+	return s.resolvePaths()
+}
+
+// resolvePaths resolves the paths referenced with the env:// or file://
+// scheme. A path read from an environment variable, such as SSH_AUTH_SOCK, is
+// used as is, while file:// only marks the value as a path.
+func (s *SSH) resolvePaths() error {
+	for i, p := range s.Paths {
+		switch {
+		case strings.HasPrefix(p, envScheme):
+			v, err := ResolveValue(p)
+			if err != nil {
+				return errors.Wrapf(err, "invalid ssh path for %s", s.ID)
+			}
+			s.Paths[i] = v
+		case strings.HasPrefix(p, fileScheme):
+			s.Paths[i] = strings.TrimPrefix(p, fileScheme)
+		}
+	}
 	return nil
 }
 
@@ -79,6 +79,9 @@ func (e *SSH) FromCtyValue(in cty.Value, p cty.Path) error {
 			return err
 		}
 	}
+	if err := e.resolvePaths(); err != nil {
+		return p.NewError(err)
+	}
 	return nil
 }
 
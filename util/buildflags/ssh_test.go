@@ -0,0 +1,22 @@
+package buildflags
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSHUnmarshalJSON(t *testing.T) {
+	var s SSH
+	require.NoError(t, json.Unmarshal([]byte(`"default"`), &s))
+	require.Equal(t, SSH{ID: "default"}, s)
+
+	s = SSH{}
+	require.NoError(t, json.Unmarshal([]byte(`"key=/path/to/key1,/path/to/key2"`), &s))
+	require.Equal(t, SSH{ID: "key", Paths: []string{"/path/to/key1", "/path/to/key2"}}, s)
+
+	s = SSH{}
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"key","paths":["/path/to/key"]}`), &s))
+	require.Equal(t, SSH{ID: "key", Paths: []string{"/path/to/key"}}, s)
+}
@@ -0,0 +1,71 @@
+package buildflags
+
+import (
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	envScheme  = "env://"
+	fileScheme = "file://"
+)
+
+// ResolveValue returns the value referenced by v when it uses the env:// or
+// file:// scheme, and v itself otherwise. A trailing newline of a file is
+// removed. The returned errors never contain the referenced value.
+func ResolveValue(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, envScheme):
+		name := strings.TrimPrefix(v, envScheme)
+		if name == "" {
+			return "", errors.Errorf("missing environment variable name in %q", v)
+		}
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errors.Errorf("environment variable %s is not set", name)
+		}
+		return val, nil
+	case strings.HasPrefix(v, fileScheme):
+		p := strings.TrimPrefix(v, fileScheme)
+		if p == "" {
+			return "", errors.Errorf("missing file path in %q", v)
+		}
+		dt, err := os.ReadFile(p)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read value from %s", p)
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(string(dt), "\n"), "\r"), nil
+	}
+	return v, nil
+}
+
+// IsValueRef returns true if v references a value with the env:// or file://
+// scheme.
+func IsValueRef(v string) bool {
+	return strings.HasPrefix(v, envScheme) || strings.HasPrefix(v, fileScheme)
+}
+
+// resolveAttrs returns a copy of attrs with the referenced values resolved.
+func resolveAttrs(attrs map[string]string) (map[string]string, error) {
+	var out map[string]string
+	for k, v := range attrs {
+		if !IsValueRef(v) {
+			continue
+		}
+		if out == nil {
+			out = maps.Clone(attrs)
+		}
+		val, err := ResolveValue(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value for %s", k)
+		}
+		out[k] = val
+	}
+	if out == nil {
+		return attrs, nil
+	}
+	return out, nil
+}
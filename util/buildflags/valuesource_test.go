@@ -0,0 +1,73 @@
+package buildflags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveValue(t *testing.T) {
+	t.Setenv("BUILDX_TEST_SECRET", "s3cr3t")
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("fromfile\n"), 0600))
+
+	v, err := ResolveValue("env://BUILDX_TEST_SECRET")
+	require.NoError(t, err)
+	require.Equal(t, "s3cr3t", v)
+
+	v, err = ResolveValue("file://" + keyFile)
+	require.NoError(t, err)
+	require.Equal(t, "fromfile", v)
+
+	v, err = ResolveValue("plain")
+	require.NoError(t, err)
+	require.Equal(t, "plain", v)
+
+	_, err = ResolveValue("env://BUILDX_TEST_UNSET")
+	require.ErrorContains(t, err, "BUILDX_TEST_UNSET is not set")
+
+	_, err = ResolveValue("file://" + filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
+
+func TestResolveCacheAndExports(t *testing.T) {
+	t.Setenv("BUILDX_TEST_SECRET", "s3cr3t")
+
+	var cache CacheOptionsEntry
+	require.NoError(t, cache.UnmarshalText([]byte("type=s3,region=us-east-1,access_key_id=AKID,secret_access_key=env://BUILDX_TEST_SECRET")))
+	resolved, err := CacheOptions{&cache}.Resolve()
+	require.NoError(t, err)
+	require.Equal(t, "s3cr3t", resolved[0].Attrs["secret_access_key"])
+	// the reference is kept in the printed options
+	require.Equal(t, "env://BUILDX_TEST_SECRET", cache.Attrs["secret_access_key"])
+	require.NotContains(t, cache.String(), "s3cr3t")
+
+	var export ExportEntry
+	require.NoError(t, export.UnmarshalText([]byte("type=image,name=example.com/app,registry.token=env://BUILDX_TEST_SECRET")))
+	exports, err := Exports{&export}.Resolve()
+	require.NoError(t, err)
+	require.Equal(t, "s3cr3t", exports[0].Attrs["registry.token"])
+	require.Equal(t, "env://BUILDX_TEST_SECRET", export.Attrs["registry.token"])
+
+	_, err = ParseCacheEntry([]string{"type=s3,secret_access_key=env://BUILDX_TEST_UNSET"})
+	require.ErrorContains(t, err, "secret_access_key")
+}
+
+func TestSecretAndSSHSchemes(t *testing.T) {
+	var s Secret
+	require.NoError(t, s.UnmarshalText([]byte("id=token,src=env://GH_TOKEN")))
+	require.Equal(t, Secret{ID: "token", Env: "GH_TOKEN"}, s)
+
+	require.NoError(t, s.UnmarshalText([]byte("id=key,src=file:///run/key")))
+	require.Equal(t, Secret{ID: "key", FilePath: "/run/key"}, s)
+
+	t.Setenv("BUILDX_TEST_SOCK", "/tmp/agent.sock")
+	var ssh SSH
+	require.NoError(t, ssh.UnmarshalText([]byte("default=env://BUILDX_TEST_SOCK,file:///home/user/.ssh/id_ed25519")))
+	require.Equal(t, []string{"/tmp/agent.sock", "/home/user/.ssh/id_ed25519"}, ssh.Paths)
+
+	require.Error(t, ssh.UnmarshalText([]byte("default=env://BUILDX_TEST_UNSET")))
+}
@@ -1,6 +1,7 @@
 package completion
 
 import (
+	"os"
 	"strings"
 
 	"github.com/docker/buildx/bake"
@@ -17,9 +18,20 @@ func Disable(cmd *cobra.Command, args []string, toComplete string) ([]string, co
 	return nil, cobra.ShellCompDirectiveNoSpace
 }
 
-func BakeTargets(files []string) ValidArgsFn {
+// BakeTargets completes target and group names from the local bake
+// definition files. files is read when completion runs so the value of
+// the --file flag is taken into account.
+func BakeTargets(files *[]string) ValidArgsFn {
 	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		f, err := bake.ReadLocalFiles(files, nil, nil)
+		var names []string
+		if files != nil {
+			names = *files
+		}
+		profile := os.Getenv("BUILDX_BAKE_PROFILE")
+		if f := cmd.Flags().Lookup("env-profile"); f != nil && f.Changed {
+			profile = f.Value.String()
+		}
+		f, err := bake.ReadLocalFiles(names, profile, nil, nil)
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveError
 		}
@@ -27,16 +39,7 @@ func BakeTargets(files []string) ValidArgsFn {
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveError
 		}
-		var filtered []string
-		if toComplete == "" {
-			return tgts, cobra.ShellCompDirectiveNoFileComp
-		}
-		for _, tgt := range tgts {
-			if strings.HasPrefix(tgt, toComplete) {
-				filtered = append(filtered, tgt)
-			}
-		}
-		return filtered, cobra.ShellCompDirectiveNoFileComp
+		return filterPrefix(tgts, toComplete), cobra.ShellCompDirectiveNoFileComp
 	}
 }
 
@@ -51,12 +54,59 @@ func BuilderNames(dockerCli command.Cli) ValidArgsFn {
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveError
 		}
-		var filtered []string
+		names := make([]string, 0, len(builders))
 		for _, b := range builders {
-			if toComplete == "" || strings.HasPrefix(b.Name, toComplete) {
-				filtered = append(filtered, b.Name)
-			}
+			names = append(names, b.Name)
+		}
+		return filterPrefix(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// knownPlatforms is the list of platforms suggested by Platforms.
+var knownPlatforms = []string{
+	"local",
+	"linux/386",
+	"linux/amd64",
+	"linux/amd64/v2",
+	"linux/amd64/v3",
+	"linux/amd64/v4",
+	"linux/arm/v5",
+	"linux/arm/v6",
+	"linux/arm/v7",
+	"linux/arm64",
+	"linux/loong64",
+	"linux/mips64",
+	"linux/mips64le",
+	"linux/ppc64le",
+	"linux/riscv64",
+	"linux/s390x",
+	"windows/amd64",
+	"windows/arm64",
+	"darwin/amd64",
+	"darwin/arm64",
+	"freebsd/amd64",
+}
+
+// Platforms completes values for platform flags. Comma-separated lists are
+// supported, in which case only the last element is completed.
+func Platforms(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var prefix string
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, toComplete = toComplete[:i+1], toComplete[i+1:]
+	}
+	filtered := filterPrefix(knownPlatforms, toComplete)
+	for i, p := range filtered {
+		filtered[i] = prefix + p
+	}
+	return filtered, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+func filterPrefix(values []string, toComplete string) []string {
+	filtered := make([]string, 0, len(values))
+	for _, v := range values {
+		if strings.HasPrefix(v, toComplete) {
+			filtered = append(filtered, v)
 		}
-		return filtered, cobra.ShellCompDirectiveNoFileComp
 	}
+	return filtered
 }
@@ -0,0 +1,20 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlatforms(t *testing.T) {
+	res, directive := Platforms(nil, nil, "linux/arm")
+	require.Equal(t, []string{"linux/arm/v5", "linux/arm/v6", "linux/arm/v7", "linux/arm64"}, res)
+	require.Equal(t, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace, directive)
+
+	res, _ = Platforms(nil, nil, "linux/amd64,linux/s")
+	require.Equal(t, []string{"linux/amd64,linux/s390x"}, res)
+
+	res, _ = Platforms(nil, nil, "")
+	require.Equal(t, knownPlatforms, res)
+}
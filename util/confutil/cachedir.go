@@ -0,0 +1,49 @@
+package confutil
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// PruneCacheDir removes the files of a client-side cache directory that
+// haven't been modified for maxAge, then the least recently modified ones
+// beyond maxEntries. A zero maxEntries doesn't limit the number of files.
+// Errors are ignored as these caches are only an optimization.
+func PruneCacheDir(dir string, maxAge time.Duration, maxEntries int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type cacheFile struct {
+		path    string
+		modTime time.Time
+	}
+	now := time.Now()
+	files := make([]cacheFile, 0, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		if now.Sub(fi.ModTime()) > maxAge {
+			os.Remove(p)
+			continue
+		}
+		files = append(files, cacheFile{path: p, modTime: fi.ModTime()})
+	}
+	if maxEntries <= 0 || len(files) <= maxEntries {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	for _, f := range files[maxEntries:] {
+		os.Remove(f.path)
+	}
+}
@@ -0,0 +1,39 @@
+package confutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneCacheDir(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for name, age := range map[string]time.Duration{
+		"expired": 48 * time.Hour,
+		"old":     3 * time.Hour,
+		"recent":  2 * time.Hour,
+		"new":     time.Hour,
+	} {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(p, []byte(name), 0600))
+		require.NoError(t, os.Chtimes(p, now.Add(-age), now.Add(-age)))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0755))
+
+	PruneCacheDir(dir, 24*time.Hour, 2)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	require.ElementsMatch(t, []string{"new", "recent", "subdir"}, names)
+
+	// a missing directory is ignored
+	PruneCacheDir(filepath.Join(dir, "missing"), time.Hour, 0)
+}
@@ -46,10 +46,7 @@ func NewConfig(dockerCli command.Cli, opts ...ConfigOption) *Config {
 
 	configDir := co.dir
 	if configDir == "" {
-		configDir = os.Getenv("BUILDX_CONFIG")
-		if configDir == "" {
-			configDir = filepath.Join(filepath.Dir(dockerCli.ConfigFile().Filename), "buildx")
-		}
+		configDir = DefaultDir(dockerCli.ConfigFile().Filename)
 	}
 
 	return &Config{
@@ -58,6 +55,15 @@ func NewConfig(dockerCli command.Cli, opts ...ConfigOption) *Config {
 	}
 }
 
+// DefaultDir returns the configuration store path for the Docker config file
+// dockerConfigFile, or `$BUILDX_CONFIG` if set.
+func DefaultDir(dockerConfigFile string) string {
+	if dir := os.Getenv("BUILDX_CONFIG"); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(dockerConfigFile), "buildx")
+}
+
 // Dir will look for correct configuration store path;
 // if `$BUILDX_CONFIG` is set - use it, otherwise use parent directory
 // of Docker config file (i.e. `${DOCKER_CONFIG}/buildx`)
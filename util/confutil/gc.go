@@ -0,0 +1,121 @@
+package confutil
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	buildkitdconfig "github.com/moby/buildkit/cmd/buildkitd/config"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+	"github.com/tonistiigi/go-csvvalue"
+)
+
+// gcWorkers are the BuildKit workers configured with GC policies. Only the
+// enabled worker of the daemon uses its policies.
+var gcWorkers = []string{"oci", "containerd"}
+
+// SetGCPolicy replaces the garbage collection policies of the workers in the
+// BuildKit daemon config held in files, creating it if needed.
+//
+// Each policy is a comma-separated list of key=value pairs with the keys
+// keep-storage (or reserved-space), max-used-space, min-free-space,
+// keep-duration, filter and all. The policies are applied in order by the
+// daemon.
+func SetGCPolicy(files map[string][]byte, policies []string) (map[string][]byte, error) {
+	if len(policies) == 0 {
+		return files, nil
+	}
+
+	var tree *toml.Tree
+	var err error
+	if dt, ok := files[buildkitdConfigFilename]; ok {
+		tree, err = toml.LoadBytes(dt)
+	} else {
+		tree, err = toml.TreeFromMap(map[string]interface{}{})
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse buildkit configuration")
+	}
+
+	trees := make([]*toml.Tree, 0, len(policies))
+	for _, p := range policies {
+		m, err := parseGCPolicy(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid gc policy %q", p)
+		}
+		t, err := toml.TreeFromMap(m)
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, t)
+	}
+	for _, w := range gcWorkers {
+		tree.SetPath([]string{"worker", w, "gc"}, true)
+		tree.SetPath([]string{"worker", w, "gcpolicy"}, trees)
+	}
+
+	b := bytes.NewBuffer(nil)
+	if _, err := tree.WriteTo(b); err != nil {
+		return nil, err
+	}
+	res := make(map[string][]byte, len(files)+1)
+	for k, v := range files {
+		res[k] = v
+	}
+	res[buildkitdConfigFilename] = b.Bytes()
+	return res, nil
+}
+
+func parseGCPolicy(in string) (map[string]interface{}, error) {
+	fields, err := csvvalue.Fields(in, nil)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	var filters []string
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, errors.Errorf("expected key=value, got %q", field)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		switch key {
+		case "keep-storage", "reserved-space", "max-used-space", "min-free-space":
+			var ds buildkitdconfig.DiskSpace
+			if err := ds.UnmarshalText([]byte(value)); err != nil {
+				return nil, errors.Wrapf(err, "invalid value for %s", key)
+			}
+			name := map[string]string{
+				"keep-storage":   "reservedSpace",
+				"reserved-space": "reservedSpace",
+				"max-used-space": "maxUsedSpace",
+				"min-free-space": "minFreeSpace",
+			}[key]
+			m[name] = value
+		case "keep-duration":
+			var d buildkitdconfig.Duration
+			if err := d.UnmarshalText([]byte(value)); err != nil {
+				return nil, errors.Wrapf(err, "invalid value for %s", key)
+			}
+			m["keepDuration"] = value
+		case "filter":
+			filters = append(filters, value)
+		case "all":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid value for %s", key)
+			}
+			m["all"] = b
+		default:
+			return nil, errors.Errorf("unknown key %q", key)
+		}
+	}
+	if len(filters) > 0 {
+		m["filters"] = filters
+	}
+	if len(m) == 0 {
+		return nil, errors.New("empty policy")
+	}
+	return m, nil
+}
@@ -0,0 +1,48 @@
+package confutil
+
+import (
+	"bytes"
+	"testing"
+
+	buildkitdconfig "github.com/moby/buildkit/cmd/buildkitd/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetGCPolicy(t *testing.T) {
+	files, err := SetGCPolicy(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, files)
+
+	files, err = SetGCPolicy(map[string][]byte{
+		"buildkitd.toml": []byte(`
+debug = true
+[worker.oci]
+  gc = false
+  [[worker.oci.gcpolicy]]
+    keepBytes = "1GB"
+`),
+	}, []string{
+		"keep-storage=20GB,keep-duration=168h,filter=type==source.local,filter=type==exec.cachemount",
+		"max-used-space=80%,all=true",
+	})
+	require.NoError(t, err)
+
+	cfg, err := buildkitdconfig.Load(bytes.NewReader(files["buildkitd.toml"]))
+	require.NoError(t, err)
+	require.True(t, cfg.Debug)
+	for _, w := range [][]buildkitdconfig.GCPolicy{cfg.Workers.OCI.GCPolicy, cfg.Workers.Containerd.GCPolicy} {
+		require.Len(t, w, 2)
+		require.Equal(t, int64(20<<30), w[0].ReservedSpace.Bytes)
+		require.Equal(t, "168h0m0s", w[0].KeepDuration.String())
+		require.Equal(t, []string{"type==source.local", "type==exec.cachemount"}, w[0].Filters)
+		require.Equal(t, int64(80), w[1].MaxUsedSpace.Percentage)
+		require.True(t, w[1].All)
+	}
+	require.True(t, *cfg.Workers.OCI.GC)
+
+	_, err = SetGCPolicy(nil, []string{"keep-storage=lots"})
+	require.ErrorContains(t, err, "invalid value for keep-storage")
+
+	_, err = SetGCPolicy(nil, []string{"keep=1GB"})
+	require.ErrorContains(t, err, `unknown key "keep"`)
+}
@@ -0,0 +1,91 @@
+package confutil
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+)
+
+// ProjectConfigFilenames are the names of the project configuration files,
+// in order of precedence.
+var ProjectConfigFilenames = []string{".buildx.toml", ".buildxrc"}
+
+// ProjectConfig is the configuration pinned for commands run in a project
+// directory and its subdirectories.
+type ProjectConfig struct {
+	// Builder is the builder instance used by default.
+	Builder string `toml:"builder,omitempty"`
+
+	// Path is the path of the loaded configuration file.
+	Path string `toml:"-"`
+}
+
+// LoadProjectConfig looks for a project configuration file in dir and its
+// parent directories. It returns nil if none is found.
+func LoadProjectConfig(dir string) (*ProjectConfig, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		for _, name := range ProjectConfigFilenames {
+			fp := filepath.Join(dir, name)
+			dt, err := os.ReadFile(fp)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, errors.Wrapf(err, "failed to read project config %s", fp)
+			}
+			var cfg ProjectConfig
+			if err := toml.Unmarshal(dt, &cfg); err != nil {
+				return nil, errors.Wrapf(err, "failed to parse project config %s", fp)
+			}
+			cfg.Path = fp
+			return &cfg, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// WriteProjectConfig sets the builder in the project configuration file of
+// dir, creating the file if it does not exist. Other settings are preserved.
+func WriteProjectConfig(dir string, builder string) (string, error) {
+	fp := filepath.Join(dir, ProjectConfigFilenames[0])
+	for _, name := range ProjectConfigFilenames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			fp = filepath.Join(dir, name)
+			break
+		}
+	}
+
+	tree, err := toml.TreeFromMap(map[string]interface{}{})
+	if err != nil {
+		return "", err
+	}
+	if dt, err := os.ReadFile(fp); err == nil {
+		tree, err = toml.LoadBytes(dt)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse project config %s", fp)
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	tree.Set("builder", builder)
+
+	dt, err := tree.Marshal()
+	if err != nil {
+		return "", err
+	}
+	if err := ioutils.AtomicWriteFile(fp, dt, 0644); err != nil {
+		return "", err
+	}
+	return fp, nil
+}
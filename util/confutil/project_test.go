@@ -0,0 +1,62 @@
+package confutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "a", "b")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+
+	cfg, err := LoadProjectConfig(sub)
+	require.NoError(t, err)
+	require.Nil(t, cfg)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".buildx.toml"), []byte(`builder = "mybuilder"`), 0644))
+
+	cfg, err = LoadProjectConfig(sub)
+	require.NoError(t, err)
+	require.NotNil(t, cfg)
+	require.Equal(t, "mybuilder", cfg.Builder)
+	require.Equal(t, filepath.Join(dir, ".buildx.toml"), cfg.Path)
+
+	// closest config file wins
+	require.NoError(t, os.WriteFile(filepath.Join(sub, ".buildxrc"), []byte(`builder = "other"`), 0644))
+	cfg, err = LoadProjectConfig(sub)
+	require.NoError(t, err)
+	require.Equal(t, "other", cfg.Builder)
+
+	require.NoError(t, os.WriteFile(filepath.Join(sub, ".buildxrc"), []byte(`builder = `), 0644))
+	_, err = LoadProjectConfig(sub)
+	require.ErrorContains(t, err, "failed to parse project config")
+}
+
+func TestWriteProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	fp, err := WriteProjectConfig(dir, "mybuilder")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, ".buildx.toml"), fp)
+
+	cfg, err := LoadProjectConfig(dir)
+	require.NoError(t, err)
+	require.Equal(t, "mybuilder", cfg.Builder)
+
+	require.NoError(t, os.WriteFile(fp, []byte("builder = \"old\"\nplatforms = [\"linux/arm64\"]\n"), 0644))
+	_, err = WriteProjectConfig(dir, "new")
+	require.NoError(t, err)
+
+	cfg, err = LoadProjectConfig(dir)
+	require.NoError(t, err)
+	require.Equal(t, "new", cfg.Builder)
+
+	// other settings of the file are preserved
+	dt, err := os.ReadFile(fp)
+	require.NoError(t, err)
+	require.Contains(t, string(dt), "platforms")
+}
@@ -0,0 +1,107 @@
+package confutil
+
+import (
+	"bytes"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+)
+
+const buildkitdConfigFilename = "buildkitd.toml"
+
+// SetRegistryConfig adds registry mirrors and insecure registries to the
+// BuildKit daemon config held in files, creating it if needed.
+//
+// Mirrors take the form [registry=]mirror, where registry defaults to
+// docker.io. A mirror with the http scheme is configured to be accessed over
+// plain HTTP. Insecure registries take the form host[:port].
+func SetRegistryConfig(files map[string][]byte, mirrors []string, insecure []string) (map[string][]byte, error) {
+	if len(mirrors) == 0 && len(insecure) == 0 {
+		return files, nil
+	}
+
+	var tree *toml.Tree
+	var err error
+	if dt, ok := files[buildkitdConfigFilename]; ok {
+		tree, err = toml.LoadBytes(dt)
+	} else {
+		tree, err = toml.TreeFromMap(map[string]interface{}{})
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse buildkit configuration")
+	}
+
+	for _, m := range mirrors {
+		reg, mirror, ok := strings.Cut(m, "=")
+		if !ok {
+			reg, mirror = "docker.io", m
+		}
+		if err := validateRegistryHost(reg); err != nil {
+			return nil, errors.Wrapf(err, "invalid registry mirror %q", m)
+		}
+		var plainHTTP bool
+		if v, ok := strings.CutPrefix(mirror, "http://"); ok {
+			mirror, plainHTTP = v, true
+		} else {
+			mirror = strings.TrimPrefix(mirror, "https://")
+		}
+		mirror = strings.TrimSuffix(mirror, "/")
+		if host, _, _ := strings.Cut(mirror, "/"); host == "" {
+			return nil, errors.Errorf("invalid registry mirror %q", m)
+		}
+
+		key := []string{"registry", reg, "mirrors"}
+		var values []string
+		if v, ok := tree.GetPath(key).([]interface{}); ok {
+			for _, vv := range v {
+				if s, ok := vv.(string); ok {
+					values = append(values, s)
+				}
+			}
+		} else if v, ok := tree.GetPath(key).([]string); ok {
+			values = v
+		}
+		if !slices.Contains(values, mirror) {
+			values = append(values, mirror)
+		}
+		tree.SetPath(key, values)
+		if plainHTTP {
+			host, _, _ := strings.Cut(mirror, "/")
+			tree.SetPath([]string{"registry", host, "http"}, true)
+		}
+	}
+
+	for _, reg := range insecure {
+		if _, _, err := net.ParseCIDR(reg); err == nil {
+			return nil, errors.Errorf("invalid insecure registry %q: IP ranges are not supported by BuildKit, specify the registry host instead", reg)
+		}
+		if err := validateRegistryHost(reg); err != nil {
+			return nil, errors.Wrapf(err, "invalid insecure registry %q", reg)
+		}
+		tree.SetPath([]string{"registry", reg, "insecure"}, true)
+	}
+
+	b := bytes.NewBuffer(nil)
+	if _, err := tree.WriteTo(b); err != nil {
+		return nil, err
+	}
+	res := make(map[string][]byte, len(files)+1)
+	for k, v := range files {
+		res[k] = v
+	}
+	res[buildkitdConfigFilename] = b.Bytes()
+	return res, nil
+}
+
+func validateRegistryHost(host string) error {
+	if host == "" {
+		return errors.New("empty registry host")
+	}
+	if strings.Contains(host, "://") || strings.ContainsAny(host, "/ ") {
+		return errors.Errorf("expected a registry host, got %q", host)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package confutil
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetRegistryConfig(t *testing.T) {
+	files, err := SetRegistryConfig(nil, nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, files)
+
+	files, err = SetRegistryConfig(map[string][]byte{
+		"buildkitd.toml": []byte(`
+debug = true
+[registry."docker.io"]
+  mirrors = ["mirror.gcr.io"]
+`),
+		"certs/myca.pem": []byte("ca"),
+	}, []string{
+		"https://mirror.example.com",
+		"ghcr.io=http://10.0.0.2:5000/ghcr/",
+	}, []string{"10.0.0.1:5000"})
+	require.NoError(t, err)
+	require.Equal(t, []byte("ca"), files["certs/myca.pem"])
+
+	tree, err := toml.LoadBytes(files["buildkitd.toml"])
+	require.NoError(t, err)
+	require.Equal(t, true, tree.Get("debug"))
+	require.Equal(t, []interface{}{"mirror.gcr.io", "mirror.example.com"}, tree.GetPath([]string{"registry", "docker.io", "mirrors"}))
+	require.Equal(t, []interface{}{"10.0.0.2:5000/ghcr"}, tree.GetPath([]string{"registry", "ghcr.io", "mirrors"}))
+	require.Equal(t, true, tree.GetPath([]string{"registry", "10.0.0.2:5000", "http"}))
+	require.Equal(t, true, tree.GetPath([]string{"registry", "10.0.0.1:5000", "insecure"}))
+
+	_, err = SetRegistryConfig(nil, nil, []string{"10.0.0.0/8"})
+	require.ErrorContains(t, err, "IP ranges are not supported")
+
+	_, err = SetRegistryConfig(nil, []string{"https://docker.io=mirror"}, nil)
+	require.ErrorContains(t, err, "invalid registry mirror")
+}
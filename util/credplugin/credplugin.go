@@ -0,0 +1,144 @@
+// Package credplugin mints registry credentials at build time by running
+// credential plugins configured per registry in the buildx config, so
+// short-lived tokens don't need to be stored with docker login.
+package credplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/cli/cli/config/types"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+)
+
+// ConfigFilename is the name of the credential plugins configuration file
+// in the buildx config dir.
+const ConfigFilename = "credentials.toml"
+
+// RegistryEnv is the environment variable holding the registry host the
+// credentials are requested for when running a plugin.
+const RegistryEnv = "BUILDX_CREDENTIAL_REGISTRY"
+
+// Config holds the credential plugins by registry host.
+type Config struct {
+	Registries map[string]Plugin `toml:"registry"`
+}
+
+// Plugin is a command printing the credentials of a registry as JSON on its
+// standard output.
+type Plugin struct {
+	Command string   `toml:"command"`
+	Args    []string `toml:"args,omitempty"`
+	Env     []string `toml:"env,omitempty"`
+}
+
+// Credentials are the credentials printed by a plugin. RegistryToken is a
+// bearer token passed as is to the registry, while the other credentials
+// are used to fetch a token from the registry auth server. The plugin is run
+// again after ExpiresAt.
+type Credentials struct {
+	Username      string     `json:"username,omitempty"`
+	Password      string     `json:"password,omitempty"`
+	IdentityToken string     `json:"identityToken,omitempty"`
+	RegistryToken string     `json:"registryToken,omitempty"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+}
+
+// LoadConfig reads the credential plugins configuration in configDir. It
+// returns nil if there is none.
+func LoadConfig(configDir string) (*Config, error) {
+	if configDir == "" {
+		return nil, nil
+	}
+	fp := filepath.Join(configDir, ConfigFilename)
+	dt, err := os.ReadFile(fp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read credential plugins config")
+	}
+	return ParseConfig(dt, fp)
+}
+
+// ParseConfig parses a credential plugins configuration file.
+func ParseConfig(dt []byte, fp string) (*Config, error) {
+	var cfg Config
+	if err := toml.Unmarshal(dt, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse credential plugins config %s", fp)
+	}
+	for host, p := range cfg.Registries {
+		if host == "" || strings.Contains(host, "://") || strings.ContainsAny(host, "/ ") {
+			return nil, errors.Errorf("invalid registry %q in %s, expecting a registry host", host, fp)
+		}
+		if p.Command == "" {
+			return nil, errors.Errorf("credential plugin for registry %s in %s requires a command", host, fp)
+		}
+		for _, e := range p.Env {
+			if k, _, ok := strings.Cut(e, "="); !ok || k == "" {
+				return nil, errors.Errorf("invalid env %q of credential plugin for registry %s in %s, expecting KEY=VALUE", e, host, fp)
+			}
+		}
+	}
+	return &cfg, nil
+}
+
+// Lookup returns the plugin configured for the registry host, as requested
+// by BuildKit. Plugins configured for docker.io apply to Docker Hub.
+func (c *Config) Lookup(host string) (Plugin, bool) {
+	if c == nil {
+		return Plugin{}, false
+	}
+	if p, ok := c.Registries[host]; ok {
+		return p, true
+	}
+	if host == dockerHubRegistryHost {
+		for _, h := range []string{"docker.io", "index.docker.io"} {
+			if p, ok := c.Registries[h]; ok {
+				return p, true
+			}
+		}
+	}
+	return Plugin{}, false
+}
+
+// Run runs the plugin to get the credentials of the registry host.
+func (p Plugin) Run(ctx context.Context, host string) (*Credentials, error) {
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Env = append(os.Environ(), p.Env...)
+	cmd.Env = append(cmd.Env, RegistryEnv+"="+host)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.Errorf("%v: %s", err, msg)
+		}
+		return nil, errors.Wrapf(err, "credential plugin %s failed for registry %s", p.Command, host)
+	}
+	var creds Credentials
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return nil, errors.Wrapf(err, "invalid output of credential plugin %s for registry %s", p.Command, host)
+	}
+	if creds.RegistryToken == "" && creds.IdentityToken == "" && creds.Password == "" {
+		return nil, errors.Errorf("credential plugin %s returned no credentials for registry %s", p.Command, host)
+	}
+	return &creds, nil
+}
+
+func (c *Credentials) authConfig(host string) types.AuthConfig {
+	return types.AuthConfig{
+		ServerAddress: host,
+		Username:      c.Username,
+		Password:      c.Password,
+		IdentityToken: c.IdentityToken,
+		RegistryToken: c.RegistryToken,
+	}
+}
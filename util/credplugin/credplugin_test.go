@@ -0,0 +1,129 @@
+package credplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/moby/buildkit/session/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+[registry."123456789012.dkr.ecr.us-east-1.amazonaws.com"]
+  command = "ecr-token"
+  args = ["--region", "us-east-1"]
+  env = ["AWS_PROFILE=ci"]
+
+[registry."docker.io"]
+  command = "hub-token"
+`), "credentials.toml")
+	require.NoError(t, err)
+
+	p, ok := cfg.Lookup("123456789012.dkr.ecr.us-east-1.amazonaws.com")
+	require.True(t, ok)
+	require.Equal(t, Plugin{Command: "ecr-token", Args: []string{"--region", "us-east-1"}, Env: []string{"AWS_PROFILE=ci"}}, p)
+
+	p, ok = cfg.Lookup("registry-1.docker.io")
+	require.True(t, ok)
+	require.Equal(t, "hub-token", p.Command)
+
+	_, ok = cfg.Lookup("ghcr.io")
+	require.False(t, ok)
+
+	_, err = ParseConfig([]byte(`
+[registry."ghcr.io"]
+  args = ["token"]
+`), "credentials.toml")
+	require.ErrorContains(t, err, "credential plugin for registry ghcr.io in credentials.toml requires a command")
+
+	_, err = ParseConfig([]byte(`
+[registry."https://ghcr.io"]
+  command = "ghcr-token"
+`), "credentials.toml")
+	require.ErrorContains(t, err, "expecting a registry host")
+
+	_, err = ParseConfig([]byte(`
+[registry."ghcr.io"]
+  command = "ghcr-token"
+  env = ["TOKEN"]
+`), "credentials.toml")
+	require.ErrorContains(t, err, "expecting KEY=VALUE")
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := LoadConfig(dir)
+	require.NoError(t, err)
+	require.Nil(t, cfg)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ConfigFilename), []byte(`
+[registry."ghcr.io"]
+  command = "ghcr-token"
+`), 0600))
+	cfg, err = LoadConfig(dir)
+	require.NoError(t, err)
+	require.Contains(t, cfg.Registries, "ghcr.io")
+}
+
+func TestPluginRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin test uses a shell script")
+	}
+
+	p := Plugin{
+		Command: "sh",
+		Args:    []string{"-c", `echo "{\"username\":\"$USER_NAME\",\"password\":\"$` + RegistryEnv + `\"}"`},
+		Env:     []string{"USER_NAME=ci"},
+	}
+	creds, err := p.Run(context.TODO(), "ghcr.io")
+	require.NoError(t, err)
+	require.Equal(t, &Credentials{Username: "ci", Password: "ghcr.io"}, creds)
+
+	p = Plugin{Command: "sh", Args: []string{"-c", "echo token expired >&2; exit 1"}}
+	_, err = p.Run(context.TODO(), "ghcr.io")
+	require.ErrorContains(t, err, "credential plugin sh failed for registry ghcr.io")
+	require.ErrorContains(t, err, "token expired")
+
+	p = Plugin{Command: "sh", Args: []string{"-c", "echo '{}'"}}
+	_, err = p.Run(context.TODO(), "ghcr.io")
+	require.ErrorContains(t, err, "returned no credentials")
+}
+
+func TestAuthProvider(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin test uses a shell script")
+	}
+
+	// the plugin counts its runs to return a new token each time
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "count")
+	expiresAt := time.Now().Add(expiryMargin / 2).UTC().Format(time.RFC3339)
+	script := `echo x >> "` + counter + `"; n=$(wc -l < "` + counter + `" | tr -d ' '); echo "{\"registryToken\":\"token$n\",\"expiresAt\":\"` + expiresAt + `\"}"`
+
+	ap := NewAuthProvider(configfile.New(filepath.Join(dir, "config.json")), &Config{
+		Registries: map[string]Plugin{
+			"docker.io": {Command: "sh", Args: []string{"-c", script}},
+		},
+	}).(auth.AuthServer)
+
+	ctx := context.TODO()
+	resp, err := ap.FetchToken(ctx, &auth.FetchTokenRequest{Host: "registry-1.docker.io"})
+	require.NoError(t, err)
+	require.Equal(t, "token1", resp.Token)
+
+	// the token expires within the margin so the plugin runs again
+	resp, err = ap.FetchToken(ctx, &auth.FetchTokenRequest{Host: "registry-1.docker.io"})
+	require.NoError(t, err)
+	require.Equal(t, "token2", resp.Token)
+
+	// registries without plugin use the docker config
+	creds, err := ap.Credentials(ctx, &auth.CredentialsRequest{Host: "ghcr.io"})
+	require.NoError(t, err)
+	require.Empty(t, creds.Secret)
+}
@@ -0,0 +1,130 @@
+package credplugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/cli/cli/config/types"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/auth"
+	"github.com/moby/buildkit/session/auth/authprovider"
+	"github.com/moby/buildkit/util/progress/progresswriter"
+	"google.golang.org/grpc"
+)
+
+const (
+	dockerHubConfigfileKey = "https://index.docker.io/v1/"
+	dockerHubRegistryHost  = "registry-1.docker.io"
+
+	// expiryMargin is how long before their expiration the credentials of
+	// a plugin are renewed, so they don't expire while they are in use.
+	expiryMargin = 30 * time.Second
+)
+
+// NewAuthProvider returns the session attachable providing the registry
+// credentials of the docker config cfg, except for the registries that have a
+// credential plugin in plugins.
+func NewAuthProvider(cfg *configfile.ConfigFile, plugins *Config) session.Attachable {
+	ap := authprovider.NewDockerAuthProvider(cfg, nil)
+	if plugins == nil || len(plugins.Registries) == 0 {
+		return ap
+	}
+	return &authProvider{
+		AuthServer: ap.(auth.AuthServer),
+		plugins:    plugins,
+		hosts:      map[string]*pluginAuth{},
+	}
+}
+
+type authProvider struct {
+	// AuthServer provides the credentials of the registries without plugin
+	// and the token authority.
+	auth.AuthServer
+
+	plugins *Config
+	logger  progresswriter.Logger
+
+	mu    sync.Mutex
+	hosts map[string]*pluginAuth
+}
+
+// pluginAuth provides the credentials minted by a plugin for a registry until
+// they expire.
+type pluginAuth struct {
+	auth.AuthServer
+	expiresAt *time.Time
+}
+
+func (ap *authProvider) SetLogger(l progresswriter.Logger) {
+	ap.mu.Lock()
+	ap.logger = l
+	for _, pa := range ap.hosts {
+		setLogger(pa.AuthServer, l)
+	}
+	ap.mu.Unlock()
+	setLogger(ap.AuthServer, l)
+}
+
+func (ap *authProvider) Register(server *grpc.Server) {
+	auth.RegisterAuthServer(server, ap)
+}
+
+func (ap *authProvider) Credentials(ctx context.Context, req *auth.CredentialsRequest) (*auth.CredentialsResponse, error) {
+	as, err := ap.server(ctx, req.Host)
+	if err != nil {
+		return nil, err
+	}
+	return as.Credentials(ctx, req)
+}
+
+func (ap *authProvider) FetchToken(ctx context.Context, req *auth.FetchTokenRequest) (*auth.FetchTokenResponse, error) {
+	as, err := ap.server(ctx, req.Host)
+	if err != nil {
+		return nil, err
+	}
+	return as.FetchToken(ctx, req)
+}
+
+// server returns the auth server providing the credentials of host, running
+// its plugin if the credentials haven't been minted yet or expire soon.
+func (ap *authProvider) server(ctx context.Context, host string) (auth.AuthServer, error) {
+	p, ok := ap.plugins.Lookup(host)
+	if !ok {
+		return ap.AuthServer, nil
+	}
+
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+
+	if pa, ok := ap.hosts[host]; ok && (pa.expiresAt == nil || time.Until(*pa.expiresAt) > expiryMargin) {
+		return pa.AuthServer, nil
+	}
+	creds, err := p.Run(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	key := host
+	if host == dockerHubRegistryHost {
+		key = dockerHubConfigfileKey
+	}
+	cfg := configfile.New("")
+	cfg.AuthConfigs = map[string]types.AuthConfig{key: creds.authConfig(key)}
+	pa := &pluginAuth{
+		AuthServer: authprovider.NewDockerAuthProvider(cfg, nil).(auth.AuthServer),
+		expiresAt:  creds.ExpiresAt,
+	}
+	setLogger(pa.AuthServer, ap.logger)
+	ap.hosts[host] = pa
+	return pa.AuthServer, nil
+}
+
+func setLogger(as auth.AuthServer, l progresswriter.Logger) {
+	if s, ok := as.(interface {
+		SetLogger(progresswriter.Logger)
+	}); ok && l != nil {
+		s.SetLogger(l)
+	}
+}
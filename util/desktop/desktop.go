@@ -28,13 +28,15 @@ func BuildBackendEnabled() bool {
 	return bbEnabled
 }
 
+// BuildURL returns the URL of the details of the build ref in Docker Desktop.
+func BuildURL(ref string) string {
+	return fmt.Sprintf("docker-desktop://dashboard/build/%s", ref)
+}
+
 func BuildDetailsOutput(refs map[string]string, term bool) string {
 	if len(refs) == 0 {
 		return ""
 	}
-	refURL := func(ref string) string {
-		return fmt.Sprintf("docker-desktop://dashboard/build/%s", ref)
-	}
 	var out bytes.Buffer
 	out.WriteString("View build details: ")
 	multiTargets := len(refs) > 1
@@ -43,9 +45,9 @@ func BuildDetailsOutput(refs map[string]string, term bool) string {
 			out.WriteString(fmt.Sprintf("\n  %s: ", target))
 		}
 		if term {
-			out.WriteString(hyperlink(refURL(ref)))
+			out.WriteString(hyperlink(BuildURL(ref)))
 		} else {
-			out.WriteString(refURL(ref))
+			out.WriteString(BuildURL(ref))
 		}
 	}
 	return out.String()
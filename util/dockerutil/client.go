@@ -2,12 +2,16 @@ package dockerutil
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/docker/buildx/util/progress"
 	"github.com/docker/cli/cli/command"
 	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
 )
 
 // Client represents an active docker object.
@@ -31,37 +35,54 @@ func (c *Client) API(name string) (client.APIClient, error) {
 	return NewClientAPI(c.cli, name)
 }
 
-// LoadImage imports an image to docker.
-func (c *Client) LoadImage(ctx context.Context, name string, status progress.Writer) (io.WriteCloser, func(), error) {
+// LoadImage imports an image to docker. The returned ImageLoad is written
+// with the image archive and keeps the outcome of the import. The image names
+// label the progress of the import.
+func (c *Client) LoadImage(ctx context.Context, name string, images []string, status progress.Writer) (*ImageLoad, func(), error) {
 	dapi, err := c.API(name)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	desc := "importing to docker"
+	if len(images) > 0 {
+		desc = fmt.Sprintf("importing %s to docker", strings.Join(images, ", "))
+	}
+
 	pr, pw := io.Pipe()
 	done := make(chan struct{})
 
-	var w *waitingWriter
-	w = &waitingWriter{
+	var w *ImageLoad
+	w = &ImageLoad{
 		PipeWriter: pw,
+		api:        dapi,
 		f: func() {
 			handleErr := func(err error) {
-				pr.CloseWithError(err)
 				w.mu.Lock()
 				w.err = err
 				w.mu.Unlock()
+				// consume the rest of the archive so the export of the
+				// build completes and other targets are not aborted
+				_, _ = io.Copy(io.Discard, pr)
 			}
 
-			resp, err := dapi.ImageLoad(ctx, pr, false)
 			defer close(done)
+			// hide Close of the pipe from the API client so the archive can
+			// still be consumed if the request fails
+			resp, err := dapi.ImageLoad(ctx, struct{ io.Reader }{pr}, false)
 			if err != nil {
 				handleErr(err)
 				return
 			}
+			defer resp.Body.Close()
 
 			status = progress.ResetTime(status)
-			if err := progress.Wrap("importing to docker", status.Write, func(l progress.SubLogger) error {
-				return fromReader(l, resp.Body)
+			if err := progress.Wrap(desc, status.Write, func(l progress.SubLogger) error {
+				loaded, err := fromReader(l, resp.Body)
+				w.mu.Lock()
+				w.loaded = loaded
+				w.mu.Unlock()
+				return err
 			}); err != nil {
 				handleErr(err)
 			}
@@ -97,29 +118,72 @@ func (c *Client) features(ctx context.Context, name string) map[Feature]bool {
 	return features
 }
 
-type waitingWriter struct {
+// ImageLoad is an import of an image archive to docker.
+type ImageLoad struct {
 	*io.PipeWriter
-	f    func()
-	once sync.Once
-	mu   sync.Mutex
-	err  error
-	done chan struct{}
+	api    client.APIClient
+	f      func()
+	once   sync.Once
+	mu     sync.Mutex
+	err    error
+	loaded []string
+	done   chan struct{}
 }
 
-func (w *waitingWriter) Write(dt []byte) (int, error) {
+func (w *ImageLoad) Write(dt []byte) (int, error) {
 	w.once.Do(func() {
 		go w.f()
 	})
 	return w.PipeWriter.Write(dt)
 }
 
-func (w *waitingWriter) Close() error {
+// Close waits for the import to complete. A failed import is not returned
+// but reported by Err.
+func (w *ImageLoad) Close() error {
 	err := w.PipeWriter.Close()
+	w.once.Do(func() {
+		// nothing was written
+		close(w.done)
+	})
 	<-w.done
-	if err == nil {
-		w.mu.Lock()
-		defer w.mu.Unlock()
-		return w.err
-	}
 	return err
 }
+
+// Err returns the error of the import once the load is closed.
+func (w *ImageLoad) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Images returns the names or IDs of the images reported as loaded by docker.
+func (w *ImageLoad) Images() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.loaded
+}
+
+// Verify checks that the loaded images match one of the given digests, which
+// are usually the manifest and config digests of the build result. Empty
+// digests are ignored.
+func (w *ImageLoad) Verify(ctx context.Context, dgsts ...string) error {
+	expected := make([]string, 0, len(dgsts))
+	for _, dgst := range dgsts {
+		if dgst != "" {
+			expected = append(expected, dgst)
+		}
+	}
+	if len(expected) == 0 {
+		return nil
+	}
+	for _, img := range w.Images() {
+		ii, _, err := w.api.ImageInspectWithRaw(ctx, img)
+		if err != nil {
+			return errors.Wrapf(err, "failed to inspect loaded image %s", img)
+		}
+		if !slices.Contains(expected, ii.ID) {
+			return errors.Errorf("loaded image %s has ID %s, expected %s", img, ii.ID, strings.Join(expected, " or "))
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package dockerutil
 import (
 	"encoding/json"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/docker/buildx/util/progress"
@@ -12,8 +13,11 @@ import (
 
 const minTimeDelta = 2 * time.Second
 
-func fromReader(l progress.SubLogger, rc io.ReadCloser) error {
+// fromReader reports the progress of an image load from its JSON messages and
+// returns the names or IDs of the loaded images.
+func fromReader(l progress.SubLogger, rc io.ReadCloser) ([]string, error) {
 	started := map[string]client.VertexStatus{}
+	var loaded []string
 
 	defer func() {
 		for _, st := range started {
@@ -30,18 +34,23 @@ func fromReader(l progress.SubLogger, rc io.ReadCloser) error {
 	var parsedErr error
 	var jm jsonmessage.JSONMessage
 	for {
+		jm = jsonmessage.JSONMessage{}
 		if err := dec.Decode(&jm); err != nil {
 			if parsedErr != nil {
-				return parsedErr
+				return loaded, parsedErr
 			}
 			if err == io.EOF {
 				break
 			}
-			return err
+			return loaded, err
 		}
 		if jm.Error != nil {
 			parsedErr = jm.Error
 		}
+		if img, ok := loadedImage(jm.Stream); ok {
+			loaded = append(loaded, img)
+			l.Log(1, []byte(jm.Stream))
+		}
 		if jm.ID == "" || jm.Progress == nil {
 			continue
 		}
@@ -74,5 +83,16 @@ func fromReader(l progress.SubLogger, rc io.ReadCloser) error {
 		l.SetStatus(&st)
 	}
 
-	return nil
+	return loaded, nil
+}
+
+// loadedImage returns the image reported by a "Loaded image" message.
+func loadedImage(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	for _, prefix := range []string{"Loaded image ID: ", "Loaded image: "} {
+		if img, ok := strings.CutPrefix(s, prefix); ok && img != "" {
+			return img, true
+		}
+	}
+	return "", false
 }
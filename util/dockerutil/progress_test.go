@@ -0,0 +1,39 @@
+package dockerutil
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/moby/buildkit/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromReader(t *testing.T) {
+	l := &subLogger{}
+	loaded, err := fromReader(l, io.NopCloser(strings.NewReader(`
+{"status":"Loading layer","progressDetail":{"current":512,"total":1024},"id":"a1b2c3"}
+{"stream":"Loaded image: app:latest\n"}
+{"stream":"Loaded image ID: sha256:1234\n"}
+`)))
+	require.NoError(t, err)
+	require.Equal(t, []string{"app:latest", "sha256:1234"}, loaded)
+	require.Equal(t, "Loaded image: app:latest\nLoaded image ID: sha256:1234\n", l.logs.String())
+
+	_, err = fromReader(l, io.NopCloser(strings.NewReader(`{"errorDetail":{"message":"unexpected EOF"},"error":"unexpected EOF"}`)))
+	require.EqualError(t, err, "unexpected EOF")
+}
+
+type subLogger struct {
+	logs strings.Builder
+}
+
+func (l *subLogger) Wrap(_ string, fn func() error) error {
+	return fn()
+}
+
+func (l *subLogger) Log(_ int, dt []byte) {
+	l.logs.Write(dt)
+}
+
+func (l *subLogger) SetStatus(*client.VertexStatus) {}
@@ -0,0 +1,157 @@
+package gitutil
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	bkgitutil "github.com/moby/buildkit/util/gitutil"
+	"github.com/pkg/errors"
+)
+
+// CloneOpts are the options of a client-side clone of a remote repository,
+// set in the query of a git URL (e.g. "?depth=1&sparse=services/api").
+type CloneOpts struct {
+	// Depth limits the history fetched to the given number of commits.
+	Depth int
+	// Sparse restricts the checkout to these directories.
+	Sparse []string
+	// Submodules initializes the submodules of the repository.
+	Submodules bool
+}
+
+// CloneRef is a git URL with clone options.
+type CloneRef struct {
+	// Remote is the URL of the repository, without the clone options and
+	// the fragment.
+	Remote string
+	// Ref is the branch, tag or commit to checkout, HEAD if empty.
+	Ref string
+	// SubDir is the directory of the repository the URL points to.
+	SubDir string
+	// URL is the git URL without the clone options.
+	URL  string
+	Opts CloneOpts
+}
+
+var cloneOptKeys = []string{"depth", "sparse", "submodules"}
+
+// ParseCloneRef parses the clone options of a git URL. It returns nil if the
+// URL is not a git URL or doesn't have clone options.
+func ParseCloneRef(s string) (*CloneRef, error) {
+	base, fragment, hasFragment := strings.Cut(s, "#")
+	base, rawQuery, ok := strings.Cut(base, "?")
+	if !ok {
+		return nil, nil
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, nil
+	}
+	var found bool
+	for _, k := range cloneOptKeys {
+		if query.Has(k) {
+			found = true
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+
+	opts := CloneOpts{Submodules: true}
+	if v := query.Get("depth"); v != "" {
+		if opts.Depth, err = strconv.Atoi(v); err != nil || opts.Depth < 1 {
+			return nil, errors.Errorf("invalid depth %q in git URL, expecting a positive number", v)
+		}
+	}
+	for _, v := range query["sparse"] {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.Trim(p, "/"); p != "" {
+				opts.Sparse = append(opts.Sparse, p)
+			}
+		}
+	}
+	if v := query.Get("submodules"); v != "" {
+		if opts.Submodules, err = strconv.ParseBool(v); err != nil {
+			return nil, errors.Errorf("invalid submodules %q in git URL, expecting a boolean", v)
+		}
+	}
+	for _, k := range cloneOptKeys {
+		query.Del(k)
+	}
+
+	u := base
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	if hasFragment {
+		u += "#" + fragment
+	}
+	gitRef, err := bkgitutil.ParseGitRef(u)
+	if err != nil {
+		return nil, nil
+	}
+	return &CloneRef{
+		Remote: gitRef.Remote,
+		Ref:    gitRef.Commit,
+		SubDir: gitRef.SubDir,
+		URL:    u,
+		Opts:   opts,
+	}, nil
+}
+
+// String returns the URL without the clone options and credentials.
+func (r *CloneRef) String() string {
+	return stripCredentials(r.URL)
+}
+
+// Clone checks out the ref of the remote repository in dir with the git CLI.
+// Unlike the git sources of BuildKit, the depth of the history, the sparse
+// checkout and the submodules are controlled by the clone options.
+func Clone(ctx context.Context, remote, ref, dir string, opts CloneOpts) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	c, err := New(WithContext(ctx), WithWorkingDir(dir))
+	if err != nil {
+		return err
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := c.run("init", "-q"); err != nil {
+		return errors.Wrap(err, "git init")
+	}
+	if _, err := c.run("remote", "add", "origin", remote); err != nil {
+		return errors.Wrap(err, "git remote add")
+	}
+	fetchArgs := []string{"fetch", "-q", "--no-tags"}
+	if opts.Depth > 0 {
+		fetchArgs = append(fetchArgs, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if len(opts.Sparse) > 0 {
+		// blobs outside of the sparse paths are not fetched
+		fetchArgs = append(fetchArgs, "--filter=blob:none")
+		if _, err := c.run(append([]string{"sparse-checkout", "set", "--cone", "--"}, opts.Sparse...)...); err != nil {
+			return errors.Wrap(err, "git sparse-checkout")
+		}
+	}
+	if _, err := c.run(append(fetchArgs, "origin", ref)...); err != nil {
+		return errors.Wrapf(errors.New(strings.TrimSuffix(err.Error(), "\n")), "failed to fetch %s from %s", ref, stripCredentials(remote))
+	}
+	if _, err := c.run("checkout", "-q", "FETCH_HEAD"); err != nil {
+		return errors.Wrap(err, "git checkout")
+	}
+	if opts.Submodules {
+		args := []string{"submodule", "update", "-q", "--init", "--recursive"}
+		if opts.Depth > 0 {
+			args = append(args, "--depth", strconv.Itoa(opts.Depth))
+		}
+		if _, err := c.run(args...); err != nil {
+			return errors.Wrap(err, "git submodule update")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package gitutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCloneRef(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected *CloneRef
+		err      string
+	}{
+		{
+			in: "https://github.com/docker/buildx.git#main",
+		},
+		{
+			in: "https://github.com/docker/buildx.git?foo=bar",
+		},
+		{
+			in: "https://example.com/context.tar.gz?depth=1",
+		},
+		{
+			in: "https://github.com/docker/buildx.git?depth=1&sparse=services/api,libs/&sparse=docker#main:services/api",
+			expected: &CloneRef{
+				Remote: "https://github.com/docker/buildx.git",
+				Ref:    "main",
+				SubDir: "services/api",
+				URL:    "https://github.com/docker/buildx.git#main:services/api",
+				Opts: CloneOpts{
+					Depth:      1,
+					Sparse:     []string{"services/api", "libs", "docker"},
+					Submodules: true,
+				},
+			},
+		},
+		{
+			in: "git@github.com:docker/buildx.git?submodules=false",
+			expected: &CloneRef{
+				Remote: "git@github.com:docker/buildx.git",
+				URL:    "git@github.com:docker/buildx.git",
+			},
+		},
+		{
+			in:  "https://github.com/docker/buildx.git?depth=0",
+			err: `invalid depth "0"`,
+		},
+		{
+			in:  "https://github.com/docker/buildx.git?submodules=maybe",
+			err: `invalid submodules "maybe"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			ref, err := ParseCloneRef(tc.in)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, ref)
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	repo := Mktmp(t)
+	c, err := New()
+	require.NoError(t, err)
+	GitInit(c, t)
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "services", "api"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "services", "web"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "services", "api", "Dockerfile"), []byte("FROM scratch"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "services", "web", "Dockerfile"), []byte("FROM scratch"), 0644))
+	GitAdd(c, t, ".")
+	GitCommit(c, t, "first")
+	GitCommit(c, t, "second")
+
+	dir := filepath.Join(t.TempDir(), "clone")
+	require.NoError(t, Clone(context.TODO(), "file://"+repo, "main", dir, CloneOpts{
+		Depth:  1,
+		Sparse: []string{"services/api"},
+	}))
+	require.FileExists(t, filepath.Join(dir, "services", "api", "Dockerfile"))
+	require.NoFileExists(t, filepath.Join(dir, "services", "web", "Dockerfile"))
+
+	cc, err := New(WithWorkingDir(dir))
+	require.NoError(t, err)
+	out, err := cc.run("rev-list", "--count", "HEAD")
+	require.NoError(t, err)
+	require.Equal(t, "1\n", out)
+
+	err = Clone(context.TODO(), "file://"+repo, "unknown", filepath.Join(t.TempDir(), "clone"), CloneOpts{})
+	require.ErrorContains(t, err, "failed to fetch unknown")
+}
@@ -127,6 +127,51 @@ func (c *Git) Tag() (string, error) {
 	return tag, err
 }
 
+// Branch returns the name of the checked out branch, or an empty string if
+// HEAD is detached.
+func (c *Git) Branch() (string, error) {
+	symref, err := c.clean(c.run("symbolic-ref", "-q", "HEAD"))
+	if err != nil || symref == "" {
+		// symbolic-ref fails quietly when HEAD is detached
+		if _, err2 := c.clean(c.run("rev-parse", "--git-dir")); err2 != nil {
+			return "", err2
+		}
+		return "", nil
+	}
+	return strings.TrimPrefix(symref, "refs/heads/"), nil
+}
+
+// ResolveRef returns the commit a branch or tag of a remote repository points
+// to. The ref defaults to HEAD.
+func (c *Git) ResolveRef(remote, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	out, err := c.run("ls-remote", "--", remote, ref, ref+"^{}")
+	if err != nil {
+		return "", errors.New(strings.TrimSuffix(err.Error(), "\n"))
+	}
+	refs := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if commit, name, ok := strings.Cut(strings.TrimSpace(line), "\t"); ok {
+			refs[name] = commit
+		}
+	}
+	// annotated tags are peeled to the commit they point to
+	for _, name := range []string{
+		"refs/tags/" + ref + "^{}",
+		"refs/tags/" + ref,
+		"refs/heads/" + ref,
+		ref + "^{}",
+		ref,
+	} {
+		if commit, ok := refs[name]; ok {
+			return commit, nil
+		}
+	}
+	return "", errors.Errorf("ref %q not found in %s", ref, stripCredentials(remote))
+}
+
 func (c *Git) run(args ...string) (string, error) {
 	var extraArgs = []string{
 		"-c", "log.showSignature=false",
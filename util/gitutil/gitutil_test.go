@@ -72,6 +72,29 @@ func TestGitShortCommitErr(t *testing.T) {
 	require.False(t, IsAmbiguousArgument(err))
 }
 
+func TestGitBranch(t *testing.T) {
+	Mktmp(t)
+	c, err := New()
+	require.NoError(t, err)
+
+	GitInit(c, t)
+	GitCommit(c, t, "bar")
+
+	out, err := c.Branch()
+	require.NoError(t, err)
+	require.Equal(t, "main", out)
+
+	GitCheckoutBranch(c, t, "feature/foo")
+	out, err = c.Branch()
+	require.NoError(t, err)
+	require.Equal(t, "feature/foo", out)
+
+	GitCheckoutDetach(c, t)
+	out, err = c.Branch()
+	require.NoError(t, err)
+	require.Empty(t, out)
+}
+
 func TestGitTagsPointsAt(t *testing.T) {
 	Mktmp(t)
 	c, err := New()
@@ -265,3 +288,27 @@ func TestStripCredentials(t *testing.T) {
 		})
 	}
 }
+
+func TestGitResolveRef(t *testing.T) {
+	dir := Mktmp(t)
+	c, err := New()
+	require.NoError(t, err)
+
+	GitInit(c, t)
+	GitCommit(c, t, "foo")
+	GitTag(c, t, "v1.0.0")
+	_, err = fakeGit(c, "tag", "-a", "v2.0.0", "-m", "v2.0.0")
+	require.NoError(t, err)
+
+	commit, err := c.FullCommit()
+	require.NoError(t, err)
+
+	for _, ref := range []string{"", "HEAD", "main", "refs/heads/main", "v1.0.0", "v2.0.0"} {
+		out, err := c.ResolveRef(dir, ref)
+		require.NoError(t, err, ref)
+		require.Equal(t, commit, out, ref)
+	}
+
+	_, err = c.ResolveRef(dir, "v3.0.0")
+	require.ErrorContains(t, err, `ref "v3.0.0" not found`)
+}
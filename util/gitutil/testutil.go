@@ -39,6 +39,12 @@ func GitCheckoutBranch(c *Git, tb testing.TB, name string) {
 	require.Empty(tb, out)
 }
 
+func GitCheckoutDetach(c *Git, tb testing.TB) {
+	tb.Helper()
+	_, err := fakeGit(c, "checkout", "--detach")
+	require.NoError(tb, err)
+}
+
 func GitAdd(c *Git, tb testing.TB, files ...string) {
 	tb.Helper()
 	args := append([]string{"add"}, files...)
@@ -0,0 +1,89 @@
+package imagetools
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// ArtifactBlob is a file of an artifact pushed with Attach.
+type ArtifactBlob struct {
+	MediaType string
+	// Title is the file name of the blob.
+	Title string
+	Data  []byte
+}
+
+// Attach pushes an OCI artifact of type artifactType holding blobs to the
+// repository of ref. The artifact refers to the manifest subject so it is
+// listed by the referrers API of the registry.
+func (r *Resolver) Attach(ctx context.Context, ref reference.Named, subject ocispec.Descriptor, artifactType string, blobs []ArtifactBlob, annotations map[string]string) (ocispec.Descriptor, error) {
+	repo := reference.TrimNamed(ref)
+	ctx = remotes.WithMediaTypeKeyPrefix(ctx, ocispec.MediaTypeEmptyJSON, "config")
+
+	config := ocispec.DescriptorEmptyJSON
+	if err := r.Push(ctx, repo, config, config.Data); err != nil {
+		return ocispec.Descriptor{}, errors.Wrap(err, "failed to push artifact config")
+	}
+
+	layers := make([]ocispec.Descriptor, 0, len(blobs))
+	for _, b := range blobs {
+		ctx := remotes.WithMediaTypeKeyPrefix(ctx, b.MediaType, "layer")
+		desc := ocispec.Descriptor{
+			MediaType: b.MediaType,
+			Digest:    digest.FromBytes(b.Data),
+			Size:      int64(len(b.Data)),
+		}
+		if b.Title != "" {
+			desc.Annotations = map[string]string{
+				ocispec.AnnotationTitle: b.Title,
+			}
+		}
+		if err := r.Push(ctx, repo, desc, b.Data); err != nil {
+			return ocispec.Descriptor{}, errors.Wrapf(err, "failed to push artifact blob %s", b.Title)
+		}
+		layers = append(layers, desc)
+	}
+
+	subject = ocispec.Descriptor{
+		MediaType: subject.MediaType,
+		Digest:    subject.Digest,
+		Size:      subject.Size,
+	}
+	mfst := ocispec.Manifest{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2,
+		},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: artifactType,
+		Config:       config,
+		Layers:       layers,
+		Subject:      &subject,
+		Annotations:  annotations,
+	}
+	dt, err := json.Marshal(mfst)
+	if err != nil {
+		return ocispec.Descriptor{}, errors.WithStack(err)
+	}
+	desc := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: artifactType,
+		Digest:       digest.FromBytes(dt),
+		Size:         int64(len(dt)),
+	}
+	// pushed by digest so the tags of the repository are left untouched
+	dref, err := reference.WithDigest(repo, desc.Digest)
+	if err != nil {
+		return ocispec.Descriptor{}, errors.WithStack(err)
+	}
+	if err := r.Push(ctx, dref, desc, dt); err != nil {
+		return ocispec.Descriptor{}, errors.Wrap(err, "failed to push artifact manifest")
+	}
+	return desc, nil
+}
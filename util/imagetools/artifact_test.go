@@ -0,0 +1,106 @@
+package imagetools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/util/resolver"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttach(t *testing.T) {
+	var mu sync.Mutex
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		const prefix = "/v2/library/app/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			http.NotFound(w, r)
+			return
+		}
+		p := strings.TrimPrefix(r.URL.Path, prefix)
+		switch {
+		case r.Method == http.MethodPost && p == "blobs/uploads/":
+			w.Header().Set("Location", prefix+"blobs/uploads/1")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && p == "blobs/uploads/1":
+			dt, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			dgst := r.URL.Query().Get("digest")
+			require.Equal(t, dgst, digest.FromBytes(dt).String())
+			blobs[dgst] = dt
+			w.Header().Set("Docker-Content-Digest", dgst)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && strings.HasPrefix(p, "manifests/"):
+			dt, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			manifests[strings.TrimPrefix(p, "manifests/")] = dt
+			w.Header().Set("Docker-Content-Digest", digest.FromBytes(dt).String())
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	plainHTTP := true
+	r := New(Opt{
+		RegistryConfig: map[string]resolver.RegistryConfig{
+			u.Host: {PlainHTTP: &plainHTTP},
+		},
+	})
+
+	ref, err := reference.ParseNormalizedNamed(u.Host + "/library/app:latest")
+	require.NoError(t, err)
+	subject := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromString("image"),
+		Size:      1234,
+		Annotations: map[string]string{
+			"foo": "bar",
+		},
+	}
+	log := []byte("#1 [internal] load build definition from Dockerfile\n#1 DONE 0.0s\n")
+
+	desc, err := r.Attach(context.TODO(), ref, subject, "application/vnd.example.log", []ArtifactBlob{
+		{MediaType: "text/plain", Title: "build.log", Data: log},
+	}, map[string]string{"created": "now"})
+	require.NoError(t, err)
+	require.Equal(t, ocispec.MediaTypeImageManifest, desc.MediaType)
+	require.Equal(t, "application/vnd.example.log", desc.ArtifactType)
+
+	require.Equal(t, log, blobs[digest.FromBytes(log).String()])
+	require.Equal(t, []byte("{}"), blobs[ocispec.DescriptorEmptyJSON.Digest.String()])
+
+	dt, ok := manifests[desc.Digest.String()]
+	require.True(t, ok, "artifact manifest must be pushed by digest")
+	require.Len(t, manifests, 1)
+
+	var mfst ocispec.Manifest
+	require.NoError(t, json.Unmarshal(dt, &mfst))
+	require.Equal(t, "application/vnd.example.log", mfst.ArtifactType)
+	require.Equal(t, ocispec.DescriptorEmptyJSON.Digest, mfst.Config.Digest)
+	require.Equal(t, &ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    subject.Digest,
+		Size:      subject.Size,
+	}, mfst.Subject)
+	require.Len(t, mfst.Layers, 1)
+	require.Equal(t, "build.log", mfst.Layers[0].Annotations[ocispec.AnnotationTitle])
+	require.Equal(t, map[string]string{"created": "now"}, mfst.Annotations)
+}
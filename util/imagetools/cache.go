@@ -0,0 +1,98 @@
+package imagetools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// DefaultCacheTTL is the duration for which a resolved image reference is
+// cached by default.
+const DefaultCacheTTL = 10 * time.Minute
+
+// Cache caches the descriptors that image references resolve to in a
+// directory, so that tags are not resolved again over the network until
+// their entry expires.
+type Cache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	Ref        string             `json:"ref"`
+	Descriptor ocispec.Descriptor `json:"descriptor"`
+	CreatedAt  time.Time          `json:"createdAt"`
+}
+
+// NewCache returns a cache storing its entries in dir for ttl.
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{
+		dir: dir,
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// Get returns the cached descriptor of ref if it has not expired.
+func (c *Cache) Get(ref string) (ocispec.Descriptor, bool) {
+	dt, err := os.ReadFile(c.path(ref))
+	if err != nil {
+		return ocispec.Descriptor{}, false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(dt, &e); err != nil || e.Ref != ref {
+		return ocispec.Descriptor{}, false
+	}
+	if age := c.now().Sub(e.CreatedAt); age < 0 || age >= c.ttl {
+		return ocispec.Descriptor{}, false
+	}
+	if e.Descriptor.Digest.Validate() != nil {
+		return ocispec.Descriptor{}, false
+	}
+	return e.Descriptor, true
+}
+
+// Put caches the descriptor that ref resolves to.
+func (c *Cache) Put(ref string, desc ocispec.Descriptor) error {
+	dt, err := json.Marshal(cacheEntry{
+		Ref: ref,
+		Descriptor: ocispec.Descriptor{
+			MediaType: desc.MediaType,
+			Digest:    desc.Digest,
+			Size:      desc.Size,
+		},
+		CreatedAt: c.now(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first so that concurrent builds never read
+	// a partial entry
+	f, err := os.CreateTemp(c.dir, ".entry-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(dt); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.path(ref))
+}
+
+func (c *Cache) path(ref string) string {
+	h := sha256.Sum256([]byte(ref))
+	return filepath.Join(c.dir, hex.EncodeToString(h[:])+".json")
+}
@@ -0,0 +1,46 @@
+package imagetools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCache(t.TempDir(), time.Minute)
+	c.now = func() time.Time { return now }
+
+	desc := ocispec.Descriptor{
+		MediaType:   ocispec.MediaTypeImageIndex,
+		Digest:      digest.FromString("alpine"),
+		Size:        1234,
+		Annotations: map[string]string{"foo": "bar"},
+	}
+
+	_, ok := c.Get("docker.io/library/alpine:latest")
+	require.False(t, ok)
+
+	require.NoError(t, c.Put("docker.io/library/alpine:latest", desc))
+	got, ok := c.Get("docker.io/library/alpine:latest")
+	require.True(t, ok)
+	require.Equal(t, ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromString("alpine"),
+		Size:      1234,
+	}, got)
+
+	_, ok = c.Get("docker.io/library/alpine:3.20")
+	require.False(t, ok)
+
+	now = now.Add(59 * time.Second)
+	_, ok = c.Get("docker.io/library/alpine:latest")
+	require.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok = c.Get("docker.io/library/alpine:latest")
+	require.False(t, ok)
+}
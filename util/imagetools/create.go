@@ -251,6 +251,18 @@ func (r *Resolver) Copy(ctx context.Context, src *Source, dest reference.Named)
 	return nil
 }
 
+// Fetch copies the image of src and all the blobs it references to dest.
+func (r *Resolver) Fetch(ctx context.Context, src *Source, dest content.Ingester) error {
+	ctx = remotes.WithMediaTypeKeyPrefix(ctx, "application/vnd.in-toto+json", "intoto")
+
+	srcRef := reference.TagNameOnly(src.Ref)
+	f, err := r.resolver().Fetcher(ctx, srcRef.String())
+	if err != nil {
+		return err
+	}
+	return contentutil.CopyChain(ctx, dest, contentutil.FromFetcher(f), src.Desc)
+}
+
 func (r *Resolver) loadPlatform(ctx context.Context, p2 *ocispec.Platform, in string, dt []byte) error {
 	var manifest ocispec.Manifest
 	if err := json.Unmarshal(dt, &manifest); err != nil {
@@ -0,0 +1,252 @@
+package imagetools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	LayerUnchanged = "unchanged"
+	LayerChanged   = "changed"
+	LayerAdded     = "added"
+	LayerRemoved   = "removed"
+)
+
+// ImageDiff is the difference between two images, compared per platform.
+type ImageDiff struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Platforms []PlatformDiff  `json:"platforms,omitempty"`
+	Coverage  PlatformsChange `json:"coverage"`
+}
+
+// PlatformsChange lists the platforms that are only provided by one of the
+// compared images.
+type PlatformsChange struct {
+	Removed []string `json:"removed,omitempty"`
+	Added   []string `json:"added,omitempty"`
+}
+
+// PlatformDiff is the difference between the images of a platform provided by
+// both compared images.
+type PlatformDiff struct {
+	Platform string         `json:"platform"`
+	From     digest.Digest  `json:"from"`
+	To       digest.Digest  `json:"to"`
+	Size     SizeChange     `json:"size"`
+	Layers   []LayerDiff    `json:"layers"`
+	Config   []ConfigChange `json:"config,omitempty"`
+}
+
+// SizeChange is the compressed size of the layers of both images.
+type SizeChange struct {
+	From  int64 `json:"from"`
+	To    int64 `json:"to"`
+	Delta int64 `json:"delta"`
+}
+
+// LayerDiff compares the layers at the same position in both images.
+type LayerDiff struct {
+	Index  int                 `json:"index"`
+	Status string              `json:"status"`
+	From   *ocispec.Descriptor `json:"from,omitempty"`
+	To     *ocispec.Descriptor `json:"to,omitempty"`
+}
+
+// ConfigChange is a field of the image config that differs between both
+// images. Empty values are unset.
+type ConfigChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// Identical returns whether both images have the same layers and config for
+// the same platforms.
+func (d *ImageDiff) Identical() bool {
+	if len(d.Coverage.Added) > 0 || len(d.Coverage.Removed) > 0 {
+		return false
+	}
+	for _, p := range d.Platforms {
+		if len(p.Config) > 0 {
+			return false
+		}
+		for _, l := range p.Layers {
+			if l.Status != LayerUnchanged {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Diff loads two images from the registry and compares their layers, sizes
+// and config for each platform.
+func Diff(ctx context.Context, opt Opt, from, to string) (*ImageDiff, error) {
+	l := newLoader(New(opt).resolver())
+	a, err := l.Load(ctx, from)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load %s", from)
+	}
+	b, err := l.Load(ctx, to)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load %s", to)
+	}
+	return diffResults(from, to, a, b)
+}
+
+func diffResults(from, to string, a, b *result) (*ImageDiff, error) {
+	d := &ImageDiff{
+		From: from,
+		To:   to,
+	}
+	for _, p := range a.platforms {
+		if _, ok := b.images[p]; !ok {
+			d.Coverage.Removed = append(d.Coverage.Removed, p)
+		}
+	}
+	for _, p := range b.platforms {
+		if _, ok := a.images[p]; !ok {
+			d.Coverage.Added = append(d.Coverage.Added, p)
+		}
+	}
+	for _, p := range a.platforms {
+		if _, ok := b.images[p]; !ok {
+			continue
+		}
+		pd, err := diffPlatform(p, a, b)
+		if err != nil {
+			return nil, err
+		}
+		d.Platforms = append(d.Platforms, *pd)
+	}
+	return d, nil
+}
+
+func diffPlatform(p string, a, b *result) (*PlatformDiff, error) {
+	ma, ok := a.manifests[a.images[p]]
+	if !ok {
+		return nil, errors.Errorf("image %s not found", p)
+	}
+	mb, ok := b.manifests[b.images[p]]
+	if !ok {
+		return nil, errors.Errorf("image %s not found", p)
+	}
+	pd := &PlatformDiff{
+		Platform: p,
+		From:     ma.desc.Digest,
+		To:       mb.desc.Digest,
+		Layers:   diffLayers(ma.manifest.Layers, mb.manifest.Layers),
+		Config:   diffConfigs(a.assets[p].config, b.assets[p].config),
+	}
+	for _, l := range ma.manifest.Layers {
+		pd.Size.From += l.Size
+	}
+	for _, l := range mb.manifest.Layers {
+		pd.Size.To += l.Size
+	}
+	pd.Size.Delta = pd.Size.To - pd.Size.From
+	return pd, nil
+}
+
+// diffLayers compares the layers by position, as a change in a layer changes
+// all the layers above it.
+func diffLayers(a, b []ocispec.Descriptor) []LayerDiff {
+	res := make([]LayerDiff, 0, max(len(a), len(b)))
+	for i := 0; i < max(len(a), len(b)); i++ {
+		ld := LayerDiff{Index: i}
+		if i < len(a) {
+			ld.From = &a[i]
+		}
+		if i < len(b) {
+			ld.To = &b[i]
+		}
+		switch {
+		case ld.From == nil:
+			ld.Status = LayerAdded
+		case ld.To == nil:
+			ld.Status = LayerRemoved
+		case ld.From.Digest == ld.To.Digest:
+			ld.Status = LayerUnchanged
+		default:
+			ld.Status = LayerChanged
+		}
+		res = append(res, ld)
+	}
+	return res
+}
+
+func diffConfigs(a, b *ocispec.Image) []ConfigChange {
+	var ca, cb ocispec.ImageConfig
+	if a != nil {
+		ca = a.Config
+	}
+	if b != nil {
+		cb = b.Config
+	}
+
+	var res []ConfigChange
+	add := func(field, from, to string) {
+		if from != to {
+			res = append(res, ConfigChange{Field: field, From: from, To: to})
+		}
+	}
+	add("User", ca.User, cb.User)
+	add("WorkingDir", ca.WorkingDir, cb.WorkingDir)
+	add("Entrypoint", formatArgs(ca.Entrypoint), formatArgs(cb.Entrypoint))
+	add("Cmd", formatArgs(ca.Cmd), formatArgs(cb.Cmd))
+	add("StopSignal", ca.StopSignal, cb.StopSignal)
+	add("ExposedPorts", strings.Join(sortedKeys(ca.ExposedPorts), " "), strings.Join(sortedKeys(cb.ExposedPorts), " "))
+	add("Volumes", strings.Join(sortedKeys(ca.Volumes), " "), strings.Join(sortedKeys(cb.Volumes), " "))
+
+	envA, envB := envMap(ca.Env), envMap(cb.Env)
+	for _, k := range unionKeys(envA, envB) {
+		add("Env."+k, envA[k], envB[k])
+	}
+	for _, k := range unionKeys(ca.Labels, cb.Labels) {
+		add("Labels."+k, ca.Labels[k], cb.Labels[k])
+	}
+	return res
+}
+
+func formatArgs(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = fmt.Sprintf("%q", a)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, e := range env {
+		k, v, _ := strings.Cut(e, "=")
+		m[k] = v
+	}
+	return m
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func unionKeys(a, b map[string]string) []string {
+	keys := append(sortedKeys(a), sortedKeys(b)...)
+	sort.Strings(keys)
+	return slices.Compact(keys)
+}
@@ -0,0 +1,103 @@
+package imagetools
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func diffTestResult(t *testing.T, images map[string]diffTestImage) *result {
+	r := &result{
+		manifests: make(map[digest.Digest]manifest),
+		images:    make(map[string]digest.Digest),
+		assets:    make(map[string]asset),
+	}
+	for p, img := range images {
+		var mfst ocispec.Manifest
+		for _, l := range img.layers {
+			mfst.Layers = append(mfst.Layers, ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageLayerGzip,
+				Digest:    digest.FromString(l),
+				Size:      int64(len(l)),
+			})
+		}
+		dt, err := json.Marshal(mfst)
+		require.NoError(t, err)
+		dgst := digest.FromBytes(dt)
+		r.manifests[dgst] = manifest{
+			desc:     ocispec.Descriptor{Digest: dgst},
+			manifest: mfst,
+		}
+		r.images[p] = dgst
+		r.assets[p] = asset{config: &ocispec.Image{Config: img.config}}
+		r.platforms = append(r.platforms, p)
+	}
+	sort.Strings(r.platforms)
+	return r
+}
+
+type diffTestImage struct {
+	layers []string
+	config ocispec.ImageConfig
+}
+
+func TestDiff(t *testing.T) {
+	a := diffTestResult(t, map[string]diffTestImage{
+		"linux/amd64": {
+			layers: []string{"base", "deps", "app"},
+			config: ocispec.ImageConfig{
+				Env:        []string{"PATH=/usr/bin", "DEBUG=1"},
+				Entrypoint: []string{"/app"},
+				Labels:     map[string]string{"version": "1.0"},
+			},
+		},
+		"linux/arm64": {
+			layers: []string{"base"},
+		},
+	})
+	b := diffTestResult(t, map[string]diffTestImage{
+		"linux/amd64": {
+			layers: []string{"base", "dependencies", "app", "assets"},
+			config: ocispec.ImageConfig{
+				Env:        []string{"PATH=/usr/bin"},
+				Entrypoint: []string{"/app", "serve"},
+				Labels:     map[string]string{"version": "1.1"},
+			},
+		},
+		"linux/s390x": {
+			layers: []string{"base"},
+		},
+	})
+
+	d, err := diffResults("app:1.0", "app:1.1", a, b)
+	require.NoError(t, err)
+	require.False(t, d.Identical())
+	require.Equal(t, []string{"linux/arm64"}, d.Coverage.Removed)
+	require.Equal(t, []string{"linux/s390x"}, d.Coverage.Added)
+
+	require.Len(t, d.Platforms, 1)
+	pd := d.Platforms[0]
+	require.Equal(t, "linux/amd64", pd.Platform)
+	require.Equal(t, SizeChange{From: 11, To: 25, Delta: 14}, pd.Size)
+
+	var statuses []string
+	for _, l := range pd.Layers {
+		statuses = append(statuses, l.Status)
+	}
+	require.Equal(t, []string{LayerUnchanged, LayerChanged, LayerUnchanged, LayerAdded}, statuses)
+	require.Nil(t, pd.Layers[3].From)
+
+	require.Equal(t, []ConfigChange{
+		{Field: "Entrypoint", From: `["/app"]`, To: `["/app", "serve"]`},
+		{Field: "Env.DEBUG", From: "1"},
+		{Field: "Labels.version", From: "1.0", To: "1.1"},
+	}, pd.Config)
+
+	d, err = diffResults("app:1.0", "app:1.0", a, a)
+	require.NoError(t, err)
+	require.True(t, d.Identical())
+}
@@ -27,12 +27,16 @@ type Auth interface {
 type Opt struct {
 	Auth           Auth
 	RegistryConfig map[string]resolver.RegistryConfig
+	// Cache caches the descriptors that tags resolve to. Tags are always
+	// resolved over the network if nil.
+	Cache *Cache
 }
 
 type Resolver struct {
 	auth   docker.Authorizer
 	hosts  docker.RegistryHosts
 	buffer contentutil.Buffer
+	cache  *Cache
 }
 
 func New(opt Opt) *Resolver {
@@ -40,6 +44,7 @@ func New(opt Opt) *Resolver {
 		auth:   docker.NewDockerAuthorizer(docker.WithAuthCreds(toCredentialsFunc(opt.Auth)), docker.WithAuthClient(http.DefaultClient)),
 		hosts:  resolver.NewRegistryConfig(opt.RegistryConfig),
 		buffer: contentutil.NewBuffer(),
+		cache:  opt.Cache,
 	}
 }
 
@@ -71,11 +76,23 @@ func (r *Resolver) Resolve(ctx context.Context, in string) (string, ocispec.Desc
 		return "", ocispec.Descriptor{}, err
 	}
 
+	_, digested := ref.(reference.Digested)
+	if r.cache != nil && !digested {
+		if desc, ok := r.cache.Get(ref.String()); ok {
+			return ref.String(), desc, nil
+		}
+	}
+
 	in, desc, err := r.resolver().Resolve(ctx, ref.String())
 	if err != nil {
 		return "", ocispec.Descriptor{}, err
 	}
 
+	if r.cache != nil && !digested {
+		if err := r.cache.Put(ref.String(), desc); err != nil {
+			logrus.WithError(err).Debug("failed to cache image reference")
+		}
+	}
 	return in, desc, nil
 }
 
@@ -0,0 +1,103 @@
+package imagetools
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/containerd/containerd/remotes/docker"
+	remoteserrors "github.com/containerd/containerd/remotes/errors"
+	"github.com/distribution/reference"
+	"github.com/moby/buildkit/util/tracing"
+	"github.com/pkg/errors"
+)
+
+// CheckPush verifies that the current credentials can push to the repository
+// of ref. It starts a blob upload in the repository, which requires push
+// access, and cancels it right away so that nothing is written.
+func (r *Resolver) CheckPush(ctx context.Context, ref string) error {
+	named, err := parseRef(ref)
+	if err != nil {
+		return err
+	}
+	hosts, err := r.hosts(reference.Domain(named))
+	if err != nil {
+		return err
+	}
+	var host *docker.RegistryHost
+	for i := range hosts {
+		if hosts[i].Capabilities.Has(docker.HostCapabilityPush) {
+			host = &hosts[i]
+			break
+		}
+	}
+	if host == nil {
+		return errors.Errorf("no push host for %s", reference.Domain(named))
+	}
+	client := host.Client
+	if client == nil {
+		client = tracing.DefaultClient
+	}
+
+	ctx = docker.WithScope(ctx, "repository:"+reference.Path(named)+":pull,push")
+	u := host.Scheme + "://" + host.Host + host.Path + "/" + reference.Path(named) + "/blobs/uploads/"
+	denied := errors.Errorf("push access denied to %s, run docker login or check the permissions of the current credentials", named.Name())
+	resp, err := r.doAuthorized(ctx, client, http.MethodPost, u)
+	if err != nil {
+		if errors.Is(err, docker.ErrInvalidAuthorization) {
+			return denied
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusCreated:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return denied
+	case http.StatusNotFound:
+		return errors.Errorf("repository %s does not exist or push access is denied", named.Name())
+	default:
+		return remoteserrors.NewUnexpectedStatusErr(resp)
+	}
+
+	// cancel the upload, registries also expire unfinished ones
+	if loc := resp.Header.Get("Location"); loc != "" {
+		if lu, err := url.Parse(u); err == nil {
+			if lu, err = lu.Parse(loc); err == nil {
+				if resp, err := r.doAuthorized(ctx, client, http.MethodDelete, lu.String()); err == nil {
+					resp.Body.Close()
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// doAuthorized sends a request authorized with the credentials of the
+// resolver, retrying it once if the registry requests to authenticate.
+func (r *Resolver) doAuthorized(ctx context.Context, client *http.Client, method, u string) (*http.Response, error) {
+	var resp *http.Response
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(ctx, method, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.auth.Authorize(ctx, req); err != nil {
+			return nil, errors.Wrap(err, "failed to authorize")
+		}
+		resp, err = client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || i > 0 {
+			break
+		}
+		if err := r.auth.AddResponses(ctx, []*http.Response{resp}); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		resp.Body.Close()
+	}
+	return resp, nil
+}
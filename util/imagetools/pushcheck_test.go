@@ -0,0 +1,61 @@
+package imagetools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	clitypes "github.com/docker/cli/cli/config/types"
+	"github.com/stretchr/testify/require"
+)
+
+type staticAuth clitypes.AuthConfig
+
+func (a staticAuth) GetAuthConfig(string) (clitypes.AuthConfig, error) {
+	return clitypes.AuthConfig(a), nil
+}
+
+func TestCheckPush(t *testing.T) {
+	var canceled []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || pass != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/org/app/blobs/uploads/":
+			if user != "pusher" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Location", "/v2/org/app/blobs/uploads/abc")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v2/org/app/blobs/uploads/"):
+			canceled = append(canceled, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ref := strings.TrimPrefix(srv.URL, "http://") + "/org/app:latest"
+	ctx := context.TODO()
+
+	r := New(Opt{Auth: staticAuth{Username: "pusher", Password: "secret"}})
+	require.NoError(t, r.CheckPush(ctx, ref))
+	require.Equal(t, []string{"/v2/org/app/blobs/uploads/abc"}, canceled)
+
+	r = New(Opt{Auth: staticAuth{Username: "reader", Password: "secret"}})
+	require.ErrorContains(t, r.CheckPush(ctx, ref), "push access denied to "+strings.TrimSuffix(ref, ":latest"))
+
+	r = New(Opt{Auth: staticAuth{}})
+	require.ErrorContains(t, r.CheckPush(ctx, ref), "push access denied")
+
+	r = New(Opt{Auth: staticAuth{Username: "pusher", Password: "secret"}})
+	require.ErrorContains(t, r.CheckPush(ctx, strings.TrimPrefix(srv.URL, "http://")+"/org/other:latest"), "does not exist")
+}
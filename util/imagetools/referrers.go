@@ -0,0 +1,188 @@
+package imagetools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/containerd/errdefs"
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	annotationReferenceDigest = "vnd.docker.reference.digest"
+	annotationReferenceType   = "vnd.docker.reference.type"
+	attestationManifestType   = "attestation-manifest"
+	maxReferrersResponseSize  = 4 * 1024 * 1024
+)
+
+// Attestations returns the attestation manifests of the image manifests in
+// srcs, as sources that can be combined with them in an index. Sources that
+// are already indexes are skipped as their attestation manifests are carried
+// over by Combine.
+//
+// Attestation manifests are looked up with the OCI referrers API, falling back
+// to the referrers tag schema for registries that don't support it.
+func (r *Resolver) Attestations(ctx context.Context, srcs []*Source) ([]*Source, error) {
+	var out []*Source
+	for _, src := range srcs {
+		switch src.Desc.MediaType {
+		case images.MediaTypeDockerSchema2Manifest, ocispec.MediaTypeImageManifest:
+		default:
+			continue
+		}
+		idx, err := r.referrers(ctx, src.Ref, src.Desc.Digest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get referrers of %s", src.Desc.Digest)
+		}
+		if idx == nil {
+			continue
+		}
+		for _, desc := range idx.Manifests {
+			if !isAttestationManifest(desc) {
+				continue
+			}
+			desc.Annotations = maps.Clone(desc.Annotations)
+			if desc.Annotations == nil {
+				desc.Annotations = map[string]string{}
+			}
+			desc.Annotations[annotationReferenceDigest] = src.Desc.Digest.String()
+			desc.Annotations[annotationReferenceType] = attestationManifestType
+			desc.Platform = &ocispec.Platform{
+				OS:           "unknown",
+				Architecture: "unknown",
+			}
+			desc.ArtifactType = ""
+			out = append(out, &Source{
+				Desc: desc,
+				Ref:  src.Ref,
+			})
+		}
+	}
+	return out, nil
+}
+
+func isAttestationManifest(desc ocispec.Descriptor) bool {
+	if desc.Annotations[annotationReferenceType] == attestationManifestType {
+		return true
+	}
+	return strings.HasPrefix(desc.ArtifactType, "application/vnd.in-toto")
+}
+
+// referrers returns the index of the manifests referring to dgst in the
+// repository of ref, or nil if there are none.
+func (r *Resolver) referrers(ctx context.Context, ref reference.Named, dgst digest.Digest) (*ocispec.Index, error) {
+	idx, err := r.referrersAPI(ctx, ref, dgst)
+	if err == nil || !errdefs.IsNotFound(err) {
+		return idx, err
+	}
+
+	// referrers tag schema: https://github.com/opencontainers/distribution-spec/blob/v1.1.0/spec.md#referrers-tag-schema
+	tagRef, err := reference.WithTag(reference.TrimNamed(ref), dgst.Algorithm().String()+"-"+dgst.Encoded())
+	if err != nil {
+		return nil, err
+	}
+	dt, desc, err := r.Get(ctx, tagRef.String())
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if desc.MediaType != ocispec.MediaTypeImageIndex {
+		return nil, nil
+	}
+	var fallback ocispec.Index
+	if err := json.Unmarshal(dt, &fallback); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &fallback, nil
+}
+
+func (r *Resolver) referrersAPI(ctx context.Context, ref reference.Named, dgst digest.Digest) (*ocispec.Index, error) {
+	hosts, err := r.hosts(reference.Domain(ref))
+	if err != nil {
+		return nil, err
+	}
+	repo := reference.Path(ref)
+	ctx = docker.WithScope(ctx, "repository:"+repo+":pull")
+
+	for _, host := range hosts {
+		if !host.Capabilities.Has(docker.HostCapabilityResolve) {
+			continue
+		}
+		u := url.URL{
+			Scheme: host.Scheme,
+			Host:   host.Host,
+			Path:   path.Join(host.Path, repo, "referrers", dgst.String()),
+		}
+		idx, err := r.fetchReferrers(ctx, host, u.String())
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		return idx, nil
+	}
+	return nil, errors.Wrapf(errdefs.ErrNotFound, "referrers of %s", dgst)
+}
+
+func (r *Resolver) fetchReferrers(ctx context.Context, host docker.RegistryHost, u string) (*ocispec.Index, error) {
+	var resps []*http.Response
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", ocispec.MediaTypeImageIndex)
+		if err := r.auth.Authorize(ctx, req); err != nil {
+			return nil, err
+		}
+		client := host.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && len(resps) == 0 {
+			resp.Body.Close()
+			resps = append(resps, resp)
+			if err := r.auth.AddResponses(ctx, resps); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusNotFound:
+			return nil, errdefs.ErrNotFound
+		default:
+			return nil, errors.Errorf("unexpected status from %s: %s", u, resp.Status)
+		}
+		// registries without referrers API support can answer with an
+		// unrelated payload
+		if mt, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";"); mt != ocispec.MediaTypeImageIndex {
+			return nil, errdefs.ErrNotFound
+		}
+		var idx ocispec.Index
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxReferrersResponseSize)).Decode(&idx); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &idx, nil
+	}
+}
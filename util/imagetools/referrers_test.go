@@ -0,0 +1,87 @@
+package imagetools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/util/resolver"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestations(t *testing.T) {
+	imgDigest := digest.FromString("image")
+	attDigest := digest.FromString("attestation")
+	sigDigest := digest.FromString("signature")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/library/app/referrers/"+imgDigest.String() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageIndex)
+		require.NoError(t, json.NewEncoder(w).Encode(ocispec.Index{
+			MediaType: ocispec.MediaTypeImageIndex,
+			Manifests: []ocispec.Descriptor{
+				{
+					MediaType:    ocispec.MediaTypeImageManifest,
+					Digest:       attDigest,
+					Size:         100,
+					ArtifactType: "application/vnd.in-toto+json",
+				},
+				{
+					MediaType:    ocispec.MediaTypeImageManifest,
+					Digest:       sigDigest,
+					Size:         100,
+					ArtifactType: "application/vnd.dev.cosign.artifact.sig.v1+json",
+				},
+			},
+		}))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	plainHTTP := true
+	r := New(Opt{
+		RegistryConfig: map[string]resolver.RegistryConfig{
+			u.Host: {PlainHTTP: &plainHTTP},
+		},
+	})
+
+	ref, err := reference.ParseNormalizedNamed(u.Host + "/library/app@" + imgDigest.String())
+	require.NoError(t, err)
+	idxRef, err := reference.ParseNormalizedNamed(u.Host + "/library/app:index")
+	require.NoError(t, err)
+
+	atts, err := r.Attestations(context.Background(), []*Source{
+		{
+			Ref:  ref,
+			Desc: ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: imgDigest},
+		},
+		{
+			// indexes already carry their attestations
+			Ref:  idxRef,
+			Desc: ocispec.Descriptor{MediaType: ocispec.MediaTypeImageIndex, Digest: digest.FromString("index")},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, atts, 1)
+	require.Equal(t, ref, atts[0].Ref)
+	require.Equal(t, ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    attDigest,
+		Size:      100,
+		Platform:  &ocispec.Platform{OS: "unknown", Architecture: "unknown"},
+		Annotations: map[string]string{
+			"vnd.docker.reference.digest": imgDigest.String(),
+			"vnd.docker.reference.type":   "attestation-manifest",
+		},
+	}, atts[0].Desc)
+}
@@ -0,0 +1,430 @@
+package imagetools
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/remotes"
+	"github.com/distribution/reference"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	VerifyPass = "pass"
+	VerifyFail = "fail"
+	VerifySkip = "skip"
+)
+
+// Checks run by Verify, which can be required by a policy.
+const (
+	CheckAttestations = "attestations"
+	CheckSBOM         = "sbom"
+	CheckProvenance   = "provenance"
+	CheckSignature    = "signature"
+	CheckPlatforms    = "platforms"
+	CheckPolicy       = "policy"
+)
+
+const cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+// VerifyReport is the result of the verification of an image.
+type VerifyReport struct {
+	Name   string        `json:"name"`
+	Digest digest.Digest `json:"digest"`
+	Checks []VerifyCheck `json:"checks"`
+}
+
+// VerifyCheck is the status of a check, for a platform of the image or for the
+// image as a whole if the platform is empty.
+type VerifyCheck struct {
+	Platform string `json:"platform,omitempty"`
+	Check    string `json:"check"`
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Passed returns whether none of the checks failed.
+func (r *VerifyReport) Passed() bool {
+	for _, c := range r.Checks {
+		if c.Status == VerifyFail {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyOpt sets how an image is verified.
+type VerifyOpt struct {
+	// Key verifies the cosign signatures of the image. Signatures are not
+	// checked if nil.
+	Key crypto.PublicKey
+	// Policy sets the checks that must pass. Missing attestations and
+	// signatures are skipped if nil.
+	Policy *VerifyPolicy
+}
+
+// VerifyPolicy is the policy that a verified image must comply with.
+type VerifyPolicy struct {
+	// Require lists the checks that fail instead of being skipped when the
+	// image lacks what they verify: sbom, provenance or signature.
+	Require []string `json:"require,omitempty"`
+	// Platforms lists the platforms that the image must provide.
+	Platforms []string `json:"platforms,omitempty"`
+	// Rego is the path of a Rego policy evaluated against the report of the
+	// checks.
+	Rego string `json:"-"`
+}
+
+func (p *VerifyPolicy) requires(check string) bool {
+	return p != nil && slices.Contains(p.Require, check)
+}
+
+// LoadVerifyPolicy reads a JSON or CUE policy file, or a Rego policy
+// evaluated against the report once the checks ran.
+func LoadVerifyPolicy(ctx context.Context, fp string) (*VerifyPolicy, error) {
+	var dt []byte
+	var err error
+	switch filepath.Ext(fp) {
+	case ".rego":
+		if _, err := os.Stat(fp); err != nil {
+			return nil, err
+		}
+		return &VerifyPolicy{Rego: fp}, nil
+	case ".cue":
+		dt, err = exportCUEPolicy(ctx, fp)
+	default:
+		dt, err = os.ReadFile(fp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p VerifyPolicy
+	if err := json.Unmarshal(dt, &p); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse policy %s", fp)
+	}
+	for _, c := range p.Require {
+		switch c {
+		case CheckSBOM, CheckProvenance, CheckSignature:
+		default:
+			return nil, errors.Errorf("invalid required check %q in policy %s, expected sbom, provenance or signature", c, fp)
+		}
+	}
+	return &p, nil
+}
+
+// LoadPublicKey reads a PEM encoded public key, as generated by
+// "cosign generate-key-pair".
+func LoadPublicKey(fp string) (crypto.PublicKey, error) {
+	dt, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(dt)
+	if block == nil {
+		return nil, errors.Errorf("no PEM encoded public key found in %s", fp)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// Verify loads an image with its attestations from the registry, and checks
+// that the attestations refer to the manifests of the image, that their
+// digests match, and optionally that the image is signed.
+func Verify(ctx context.Context, opt Opt, ref string, vopt VerifyOpt) (*VerifyReport, error) {
+	r := New(opt)
+	named, err := parseRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	_, desc, err := r.Resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := reference.WithDigest(named, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	l := newLoader(r.resolver())
+	res, err := l.Load(ctx, canonical.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load %s", ref)
+	}
+	fetcher, err := r.resolver().Fetcher(ctx, canonical.String())
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{
+		Name:   named.String(),
+		Digest: desc.Digest,
+	}
+	report.Checks = append(report.Checks, verifyAttestations(res)...)
+	for _, p := range res.platforms {
+		report.Checks = append(report.Checks, verifyPlatform(ctx, l, fetcher, res, p, vopt.Policy)...)
+	}
+	report.Checks = append(report.Checks, verifyPlatforms(res, vopt.Policy)...)
+	report.Checks = append(report.Checks, r.verifySignature(ctx, named, desc.Digest, vopt))
+	if vopt.Policy != nil && vopt.Policy.Rego != "" {
+		checks, err := evalRegoPolicy(ctx, vopt.Policy.Rego, report)
+		if err != nil {
+			return nil, err
+		}
+		report.Checks = append(report.Checks, checks...)
+	}
+	return report, nil
+}
+
+// verifyAttestations checks that every attestation manifest refers to a
+// manifest of the image.
+func verifyAttestations(res *result) []VerifyCheck {
+	var checks []VerifyCheck
+	images := make(map[digest.Digest]struct{}, len(res.images))
+	for _, dgst := range res.images {
+		images[dgst] = struct{}{}
+	}
+	var orphans []digest.Digest
+	for dgst := range res.refs {
+		if _, ok := images[dgst]; !ok {
+			orphans = append(orphans, dgst)
+		}
+	}
+	slices.Sort(orphans)
+	for _, dgst := range orphans {
+		for _, ref := range res.refs[dgst] {
+			checks = append(checks, VerifyCheck{
+				Check:   CheckAttestations,
+				Status:  VerifyFail,
+				Message: fmt.Sprintf("attestation manifest %s refers to unknown manifest %s", ref, dgst),
+			})
+		}
+	}
+	return checks
+}
+
+func verifyPlatform(ctx context.Context, l *loader, fetcher remotes.Fetcher, res *result, platform string, policy *VerifyPolicy) []VerifyCheck {
+	dgst := res.images[platform]
+	check := func(name, status, msg string) VerifyCheck {
+		return VerifyCheck{Platform: platform, Check: name, Status: status, Message: msg}
+	}
+
+	refs := res.refs[dgst]
+	if len(refs) == 0 {
+		return []VerifyCheck{
+			check(CheckAttestations, VerifySkip, "no attestation manifest"),
+			missing(platform, CheckSBOM, policy),
+			missing(platform, CheckProvenance, policy),
+		}
+	}
+
+	ctx = withIntotoMediaTypes(ctx)
+	var checks []VerifyCheck
+	var failed bool
+	found := map[string]bool{}
+	for _, ref := range refs {
+		mfst, ok := res.manifests[ref]
+		if !ok {
+			return append(checks, check(CheckAttestations, VerifyFail, fmt.Sprintf("attestation manifest %s not found", ref)))
+		}
+		for _, layer := range mfst.manifest.Layers {
+			if layer.MediaType != inTotoGenericMime && !isInTotoDSSE(layer.MediaType) {
+				continue
+			}
+			dt, err := l.fetchBlob(ctx, fetcher, layer)
+			if err != nil {
+				checks = append(checks, check(CheckAttestations, VerifyFail, fmt.Sprintf("attestation %s: %v", layer.Digest, err)))
+				failed = true
+				continue
+			}
+			predicateType, err := verifyStatement(dt, layer.MediaType, dgst)
+			if err != nil {
+				checks = append(checks, check(CheckAttestations, VerifyFail, fmt.Sprintf("attestation %s: %v", layer.Digest, err)))
+				failed = true
+				continue
+			}
+			switch {
+			case predicateType == intoto.PredicateSPDX:
+				found[CheckSBOM] = true
+			case strings.HasPrefix(predicateType, "https://slsa.dev/provenance/"):
+				found[CheckProvenance] = true
+			}
+		}
+	}
+	if !failed {
+		checks = append(checks, check(CheckAttestations, VerifyPass, "attestations match the image manifest"))
+	}
+	for _, name := range []string{CheckSBOM, CheckProvenance} {
+		if found[name] {
+			checks = append(checks, check(name, VerifyPass, ""))
+		} else {
+			checks = append(checks, missing(platform, name, policy))
+		}
+	}
+	return checks
+}
+
+// missing returns the status of a check for something that the image lacks,
+// which only fails if required by the policy.
+func missing(platform, name string, policy *VerifyPolicy) VerifyCheck {
+	c := VerifyCheck{Platform: platform, Check: name, Status: VerifySkip, Message: "not found"}
+	if policy.requires(name) {
+		c.Status = VerifyFail
+		c.Message = "not found, required by policy"
+	}
+	return c
+}
+
+// fetchBlob fetches a blob through the content cache of the loader, which
+// fails if its digest doesn't match the descriptor.
+func (l *loader) fetchBlob(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) ([]byte, error) {
+	if _, err := remotes.FetchHandler(l.cache, fetcher)(ctx, desc); err != nil {
+		return nil, err
+	}
+	return content.ReadBlob(ctx, l.cache, desc)
+}
+
+// verifyStatement checks that an in-toto statement has the image manifest as
+// subject, and returns its predicate type.
+func verifyStatement(dt []byte, mediaType string, dgst digest.Digest) (string, error) {
+	dt, err := decodeDSSE(dt, mediaType)
+	if err != nil {
+		return "", err
+	}
+	var stmt intoto.Statement
+	if err := json.Unmarshal(dt, &stmt); err != nil {
+		return "", errors.Wrap(err, "invalid in-toto statement")
+	}
+	for _, s := range stmt.Subject {
+		if s.Digest[dgst.Algorithm().String()] == dgst.Encoded() {
+			return stmt.PredicateType, nil
+		}
+	}
+	return "", errors.Errorf("subject of %s statement doesn't match manifest %s", stmt.PredicateType, dgst)
+}
+
+func verifyPlatforms(res *result, policy *VerifyPolicy) []VerifyCheck {
+	if policy == nil || len(policy.Platforms) == 0 {
+		return nil
+	}
+	var absent []string
+	for _, p := range policy.Platforms {
+		if !slices.Contains(res.platforms, p) {
+			absent = append(absent, p)
+		}
+	}
+	if len(absent) > 0 {
+		return []VerifyCheck{{Check: CheckPlatforms, Status: VerifyFail, Message: "missing " + strings.Join(absent, ", ")}}
+	}
+	return []VerifyCheck{{Check: CheckPlatforms, Status: VerifyPass}}
+}
+
+// verifySignature checks the cosign signatures of the image, which are
+// stored in a tag named after the digest of the image.
+func (r *Resolver) verifySignature(ctx context.Context, named reference.Named, dgst digest.Digest, opt VerifyOpt) VerifyCheck {
+	c := VerifyCheck{Check: CheckSignature}
+	if opt.Key == nil {
+		c.Status = VerifySkip
+		c.Message = "no public key to verify signatures"
+		if opt.Policy.requires(CheckSignature) {
+			c.Status = VerifyFail
+			c.Message += ", required by policy"
+		}
+		return c
+	}
+
+	c.Status = VerifyFail
+	tag := named.Name() + ":" + dgst.Algorithm().String() + "-" + dgst.Encoded() + ".sig"
+	dt, _, err := r.Get(ctx, tag)
+	if err != nil {
+		c.Message = "no signature found: " + err.Error()
+		return c
+	}
+	var mfst ocispec.Manifest
+	if err := json.Unmarshal(dt, &mfst); err != nil {
+		c.Message = "invalid signature manifest: " + err.Error()
+		return c
+	}
+	var errs []string
+	for _, layer := range mfst.Layers {
+		sig, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		payload, err := r.GetDescriptor(ctx, tag, layer)
+		if err == nil && digest.FromBytes(payload) != layer.Digest {
+			err = errors.Errorf("digest of signature payload doesn't match %s", layer.Digest)
+		}
+		if err == nil {
+			err = verifyCosignPayload(opt.Key, dgst, payload, sig)
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		c.Status = VerifyPass
+		c.Message = "signed by the public key"
+		return c
+	}
+	if len(errs) == 0 {
+		c.Message = "no signature found in " + tag
+	} else {
+		c.Message = strings.Join(errs, "; ")
+	}
+	return c
+}
+
+// verifyCosignPayload checks a cosign simple signing payload against the
+// image digest and its signature.
+func verifyCosignPayload(key crypto.PublicKey, dgst digest.Digest, payload []byte, sig string) error {
+	var p struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return errors.Wrap(err, "invalid signature payload")
+	}
+	if p.Critical.Image.DockerManifestDigest != dgst.String() {
+		return errors.Errorf("signature is for %s, not %s", p.Critical.Image.DockerManifestDigest, dgst)
+	}
+	rawSig, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return errors.Wrap(err, "invalid signature encoding")
+	}
+	h := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, h[:], rawSig) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], rawSig); err != nil {
+			return errors.Wrap(err, "invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, payload, rawSig) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return errors.Errorf("unsupported public key type %T", key)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package imagetools
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyStatement(t *testing.T) {
+	dgst := digest.FromString("manifest")
+	stmt := func(subject digest.Digest) []byte {
+		dt, err := json.Marshal(map[string]any{
+			"_type":         intoto.StatementInTotoV01,
+			"predicateType": intoto.PredicateSPDX,
+			"subject": []map[string]any{
+				{"name": "pkg:docker/foo@latest", "digest": map[string]string{"sha256": subject.Encoded()}},
+			},
+			"predicate": map[string]any{},
+		})
+		require.NoError(t, err)
+		return dt
+	}
+
+	predicateType, err := verifyStatement(stmt(dgst), inTotoGenericMime, dgst)
+	require.NoError(t, err)
+	require.Equal(t, intoto.PredicateSPDX, predicateType)
+
+	dsse, err := json.Marshal(map[string]string{"payload": base64.StdEncoding.EncodeToString(stmt(dgst))})
+	require.NoError(t, err)
+	_, err = verifyStatement(dsse, inTotoSPDXDSSEMime, dgst)
+	require.NoError(t, err)
+
+	_, err = verifyStatement(stmt(digest.FromString("other")), inTotoGenericMime, dgst)
+	require.ErrorContains(t, err, "doesn't match manifest "+dgst.String())
+}
+
+func TestVerifyCosignPayload(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	dgst := digest.FromString("index")
+	payload := []byte(`{"critical":{"identity":{"docker-reference":"docker.io/library/foo"},"image":{"docker-manifest-digest":"` + dgst.String() + `"},"type":"cosign container image signature"},"optional":null}`)
+	sign := func(dt []byte) string {
+		h := sha256.Sum256(dt)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, h[:])
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+
+	require.NoError(t, verifyCosignPayload(key.Public(), dgst, payload, sign(payload)))
+	require.ErrorContains(t, verifyCosignPayload(key.Public(), digest.FromString("other"), payload, sign(payload)), "signature is for "+dgst.String())
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	require.ErrorContains(t, verifyCosignPayload(other.Public(), dgst, payload, sign(payload)), "invalid signature")
+
+	// the public key is loaded from a PEM file
+	dt, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	fp := filepath.Join(t.TempDir(), "cosign.pub")
+	require.NoError(t, os.WriteFile(fp, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: dt}), 0600))
+	pub, err := LoadPublicKey(fp)
+	require.NoError(t, err)
+	require.True(t, key.PublicKey.Equal(pub))
+}
+
+func TestLoadVerifyPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	fp := filepath.Join(dir, "policy.json")
+	require.NoError(t, os.WriteFile(fp, []byte(`{"require": ["sbom", "signature"], "platforms": ["linux/amd64"]}`), 0600))
+	p, err := LoadVerifyPolicy(context.TODO(), fp)
+	require.NoError(t, err)
+	require.Equal(t, &VerifyPolicy{Require: []string{"sbom", "signature"}, Platforms: []string{"linux/amd64"}}, p)
+	require.True(t, p.requires(CheckSBOM))
+	require.False(t, p.requires(CheckProvenance))
+
+	require.Equal(t, VerifySkip, missing("linux/amd64", CheckProvenance, p).Status)
+	require.Equal(t, VerifyFail, missing("linux/amd64", CheckSBOM, p).Status)
+	require.Equal(t, VerifySkip, missing("linux/amd64", CheckSBOM, nil).Status)
+
+	fp = filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(fp, []byte(`{"require": ["vex"]}`), 0600))
+	_, err = LoadVerifyPolicy(context.TODO(), fp)
+	require.ErrorContains(t, err, `invalid required check "vex"`)
+
+	fp = filepath.Join(dir, "policy.rego")
+	_, err = LoadVerifyPolicy(context.TODO(), fp)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	require.NoError(t, os.WriteFile(fp, []byte("package buildx.verify\n"), 0600))
+	p, err = LoadVerifyPolicy(context.TODO(), fp)
+	require.NoError(t, err)
+	require.Equal(t, &VerifyPolicy{Rego: fp}, p)
+}
+
+func TestVerifyAttestations(t *testing.T) {
+	r := getImageWithAttestation(plainSpdx)
+	require.Empty(t, verifyAttestations(r))
+
+	orphan := digest.FromString("orphan")
+	r.refs[orphan] = []digest.Digest{digest.FromString("attestation")}
+	checks := verifyAttestations(r)
+	require.Len(t, checks, 1)
+	require.Equal(t, VerifyFail, checks[0].Status)
+	require.Contains(t, checks[0].Message, "refers to unknown manifest "+orphan.String())
+
+	report := &VerifyReport{Checks: checks}
+	require.False(t, report.Passed())
+	require.True(t, (&VerifyReport{Checks: []VerifyCheck{{Status: VerifyPass}, {Status: VerifySkip}}}).Passed())
+}
@@ -0,0 +1,97 @@
+package imagetools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// regoPolicyQuery is the rule of a Rego policy listing the reasons an image
+// is denied.
+const regoPolicyQuery = "data.buildx.verify.deny"
+
+// exportCUEPolicy evaluates a CUE policy to JSON with the cue command.
+func exportCUEPolicy(ctx context.Context, fp string) ([]byte, error) {
+	dt, err := runPolicyTool(ctx, "cue", nil, "export", "--out", "json", fp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to evaluate policy %s", fp)
+	}
+	return dt, nil
+}
+
+// evalRegoPolicy evaluates the deny rule of the buildx.verify package of a
+// Rego policy with the opa command, the report being the input. A check
+// fails for each reason the image is denied.
+func evalRegoPolicy(ctx context.Context, fp string, report *VerifyReport) ([]VerifyCheck, error) {
+	input, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	dt, err := runPolicyTool(ctx, "opa", input, "eval", "--format", "json", "--stdin-input", "--data", fp, regoPolicyQuery)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to evaluate policy %s", fp)
+	}
+	return regoPolicyChecks(dt)
+}
+
+// regoPolicyChecks returns the checks of the JSON output of opa eval for the
+// deny rule.
+func regoPolicyChecks(dt []byte) ([]VerifyCheck, error) {
+	var out struct {
+		Result []struct {
+			Expressions []struct {
+				Value json.RawMessage `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(dt, &out); err != nil {
+		return nil, errors.Wrap(err, "invalid opa output")
+	}
+	var reasons []json.RawMessage
+	for _, r := range out.Result {
+		for _, e := range r.Expressions {
+			var v []json.RawMessage
+			if err := json.Unmarshal(e.Value, &v); err != nil {
+				return nil, errors.Errorf("%s must be a set of reasons", regoPolicyQuery)
+			}
+			reasons = append(reasons, v...)
+		}
+	}
+	if len(reasons) == 0 {
+		return []VerifyCheck{{Check: CheckPolicy, Status: VerifyPass}}, nil
+	}
+	checks := make([]VerifyCheck, 0, len(reasons))
+	for _, r := range reasons {
+		var msg string
+		if err := json.Unmarshal(r, &msg); err != nil {
+			msg = string(r)
+		}
+		checks = append(checks, VerifyCheck{Check: CheckPolicy, Status: VerifyFail, Message: msg})
+	}
+	return checks, nil
+}
+
+// runPolicyTool runs a policy engine command and returns its output.
+func runPolicyTool(ctx context.Context, name string, stdin []byte, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, errors.Errorf("%s must be installed to evaluate the policy", name)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,77 @@
+package imagetools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakePolicyTool installs a command in PATH printing output, after saving its
+// arguments and input next to it.
+func fakePolicyTool(t *testing.T, name, output string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake policy tools are shell scripts")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "args") + "\ncat > " + filepath.Join(dir, "input") + "\nprintf '%s' '" + output + "'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return dir
+}
+
+func TestLoadVerifyPolicyCUE(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "policy.cue")
+	require.NoError(t, os.WriteFile(fp, []byte("require: [\"sbom\"]\n"), 0600))
+
+	t.Setenv("PATH", t.TempDir())
+	_, err := LoadVerifyPolicy(context.TODO(), fp)
+	require.ErrorContains(t, err, "cue must be installed to evaluate the policy")
+
+	dir := fakePolicyTool(t, "cue", `{"require": ["sbom"], "platforms": ["linux/amd64"]}`)
+	p, err := LoadVerifyPolicy(context.TODO(), fp)
+	require.NoError(t, err)
+	require.Equal(t, &VerifyPolicy{Require: []string{"sbom"}, Platforms: []string{"linux/amd64"}}, p)
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	require.NoError(t, err)
+	require.Equal(t, "export --out json "+fp+"\n", string(args))
+}
+
+func TestEvalRegoPolicy(t *testing.T) {
+	report := &VerifyReport{
+		Name:   "docker.io/user/app:latest",
+		Checks: []VerifyCheck{{Platform: "linux/amd64", Check: CheckSBOM, Status: VerifySkip}},
+	}
+
+	dir := fakePolicyTool(t, "opa", `{"result": [{"expressions": [{"value": ["missing sbom for linux/amd64"], "text": "data.buildx.verify.deny"}]}]}`)
+	checks, err := evalRegoPolicy(context.TODO(), "policy.rego", report)
+	require.NoError(t, err)
+	require.Equal(t, []VerifyCheck{{Check: CheckPolicy, Status: VerifyFail, Message: "missing sbom for linux/amd64"}}, checks)
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	require.NoError(t, err)
+	require.Equal(t, "eval --format json --stdin-input --data policy.rego data.buildx.verify.deny\n", string(args))
+	input, err := os.ReadFile(filepath.Join(dir, "input"))
+	require.NoError(t, err)
+	require.Contains(t, string(input), `"check":"sbom","status":"skip"`)
+}
+
+func TestRegoPolicyChecks(t *testing.T) {
+	checks, err := regoPolicyChecks([]byte(`{"result": [{"expressions": [{"value": []}]}]}`))
+	require.NoError(t, err)
+	require.Equal(t, []VerifyCheck{{Check: CheckPolicy, Status: VerifyPass}}, checks)
+
+	// the deny rule is not defined
+	checks, err = regoPolicyChecks([]byte(`{}`))
+	require.NoError(t, err)
+	require.Equal(t, []VerifyCheck{{Check: CheckPolicy, Status: VerifyPass}}, checks)
+
+	checks, err = regoPolicyChecks([]byte(`{"result": [{"expressions": [{"value": [{"msg": "unsigned"}]}]}]}`))
+	require.NoError(t, err)
+	require.Equal(t, []VerifyCheck{{Check: CheckPolicy, Status: VerifyFail, Message: `{"msg": "unsigned"}`}}, checks)
+
+	_, err = regoPolicyChecks([]byte(`{"result": [{"expressions": [{"value": true}]}]}`))
+	require.ErrorContains(t, err, "data.buildx.verify.deny must be a set of reasons")
+}
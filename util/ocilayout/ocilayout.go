@@ -0,0 +1,192 @@
+package ocilayout
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/content/local"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/errdefs"
+	"github.com/moby/buildkit/client/ociindex"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Layout is a local OCI image layout directory that can be used as a named
+// context with the oci-layout:// scheme.
+type Layout struct {
+	path  string
+	store content.Store
+	index ociindex.StoreIndex
+}
+
+// Image is an image of the index of a layout.
+type Image struct {
+	Name       string
+	Descriptor ocispec.Descriptor
+}
+
+// PruneResult holds the blobs removed by Prune.
+type PruneResult struct {
+	Blobs []digest.Digest
+	Size  int64
+}
+
+// Open opens the layout at path. The directory is created by the first
+// image added to the layout.
+func Open(path string) (*Layout, error) {
+	store, err := local.NewStore(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid store at %s", path)
+	}
+	return &Layout{
+		path:  path,
+		store: store,
+		index: ociindex.NewStoreIndex(path),
+	}, nil
+}
+
+// Store returns the content store of the blobs of the layout.
+func (l *Layout) Store() content.Store {
+	return l.store
+}
+
+// Tag sets the image of name in the index of the layout to desc. The blobs
+// of the image must already be in the layout.
+func (l *Layout) Tag(ctx context.Context, name string, desc ocispec.Descriptor) error {
+	if strings.Contains(name, "@") {
+		return errors.Errorf("invalid image name %q, must not contain a digest", name)
+	}
+	if _, err := l.store.Info(ctx, desc.Digest); err != nil {
+		return errors.Wrapf(err, "image %s not found in %s", desc.Digest, l.path)
+	}
+	return l.index.Put(name, desc)
+}
+
+// Images returns the images of the index of the layout.
+func (l *Layout) Images() ([]Image, error) {
+	idx, err := l.index.Read()
+	if err != nil {
+		return nil, err
+	}
+	imgs := make([]Image, 0, len(idx.Manifests))
+	for _, desc := range idx.Manifests {
+		imgs = append(imgs, Image{
+			Name:       desc.Annotations[ocispec.AnnotationRefName],
+			Descriptor: desc,
+		})
+	}
+	return imgs, nil
+}
+
+// Verify checks that all the blobs referenced by the images of the layout
+// exist and match their digest and size.
+func (l *Layout) Verify(ctx context.Context) error {
+	descs, err := l.roots()
+	if err != nil {
+		return err
+	}
+	seen := map[digest.Digest]struct{}{}
+	handler := images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if _, ok := seen[desc.Digest]; ok {
+			return nil, images.ErrSkipDesc
+		}
+		seen[desc.Digest] = struct{}{}
+		if err := l.verifyBlob(ctx, desc); err != nil {
+			return nil, err
+		}
+		return images.Children(ctx, l.store, desc)
+	})
+	return images.Walk(ctx, handler, descs...)
+}
+
+func (l *Layout) verifyBlob(ctx context.Context, desc ocispec.Descriptor) error {
+	if err := desc.Digest.Validate(); err != nil {
+		return errors.Wrapf(err, "invalid digest %q", desc.Digest)
+	}
+	ra, err := l.store.ReaderAt(ctx, desc)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return errors.Errorf("blob %s is missing", desc.Digest)
+		}
+		return err
+	}
+	defer ra.Close()
+
+	if ra.Size() != desc.Size {
+		return errors.Errorf("blob %s has size %d, expected %d", desc.Digest, ra.Size(), desc.Size)
+	}
+	verifier := desc.Digest.Verifier()
+	if _, err := io.Copy(verifier, content.NewReader(ra)); err != nil {
+		return errors.Wrapf(err, "failed to read blob %s", desc.Digest)
+	}
+	if !verifier.Verified() {
+		return errors.Errorf("blob %s does not match its digest", desc.Digest)
+	}
+	return nil
+}
+
+// Prune removes the blobs of the layout that are not referenced by any of
+// its images.
+func (l *Layout) Prune(ctx context.Context) (*PruneResult, error) {
+	descs, err := l.roots()
+	if err != nil {
+		return nil, err
+	}
+	used := map[digest.Digest]struct{}{}
+	handler := images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if _, ok := used[desc.Digest]; ok {
+			return nil, images.ErrSkipDesc
+		}
+		used[desc.Digest] = struct{}{}
+		children, err := images.Children(ctx, l.store, desc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s, run verify to check the layout", desc.Digest)
+		}
+		return children, nil
+	})
+	if err := images.Walk(ctx, handler, descs...); err != nil {
+		return nil, err
+	}
+
+	var unused []content.Info
+	if err := l.store.Walk(ctx, func(info content.Info) error {
+		if _, ok := used[info.Digest]; !ok {
+			unused = append(unused, info)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	res := &PruneResult{}
+	for _, info := range unused {
+		if err := l.store.Delete(ctx, info.Digest); err != nil && !errdefs.IsNotFound(err) {
+			return res, errors.Wrapf(err, "failed to remove blob %s", info.Digest)
+		}
+		res.Blobs = append(res.Blobs, info.Digest)
+		res.Size += info.Size
+	}
+	return res, nil
+}
+
+func (l *Layout) roots() ([]ocispec.Descriptor, error) {
+	idx, err := l.index.Read()
+	if err != nil {
+		return nil, err
+	}
+	return idx.Manifests, nil
+}
+
+// Reference returns the oci-layout:// reference of the image of name in the
+// layout at path, pinned to dgst.
+func Reference(path, name string, dgst digest.Digest) string {
+	ref := "oci-layout://" + path
+	if name != "" {
+		ref += ":" + name
+	}
+	return ref + "@" + dgst.String()
+}
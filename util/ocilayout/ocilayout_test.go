@@ -0,0 +1,93 @@
+package ocilayout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func writeBlob(t *testing.T, l *Layout, mediaType string, dt []byte) ocispec.Descriptor {
+	desc := ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(dt),
+		Size:      int64(len(dt)),
+	}
+	require.NoError(t, content.WriteBlob(context.TODO(), l.Store(), desc.Digest.String(), bytes.NewReader(dt), desc))
+	return desc
+}
+
+func writeImage(t *testing.T, l *Layout, layer string) ocispec.Descriptor {
+	cfg := writeBlob(t, l, ocispec.MediaTypeImageConfig, []byte(`{"architecture":"amd64","os":"linux"}`))
+	lyr := writeBlob(t, l, ocispec.MediaTypeImageLayer, []byte(layer))
+	dt, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    cfg,
+		Layers:    []ocispec.Descriptor{lyr},
+	})
+	require.NoError(t, err)
+	return writeBlob(t, l, ocispec.MediaTypeImageManifest, dt)
+}
+
+func TestLayout(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+
+	l, err := Open(dir)
+	require.NoError(t, err)
+
+	app := writeImage(t, l, "app")
+	require.NoError(t, l.Tag(ctx, "app:v1", app))
+	base := writeImage(t, l, "base")
+	require.NoError(t, l.Tag(ctx, "base", base))
+
+	require.ErrorContains(t, l.Tag(ctx, "app@sha256:abc", app), "must not contain a digest")
+	require.ErrorContains(t, l.Tag(ctx, "missing", ocispec.Descriptor{Digest: digest.FromString("missing")}), "not found")
+
+	imgs, err := l.Images()
+	require.NoError(t, err)
+	require.Len(t, imgs, 2)
+	require.Equal(t, "app:v1", imgs[0].Name)
+	require.Equal(t, app.Digest, imgs[0].Descriptor.Digest)
+	require.Equal(t, "base", imgs[1].Name)
+	require.Equal(t, base.Digest, imgs[1].Descriptor.Digest)
+
+	require.NoError(t, l.Verify(ctx))
+
+	// retagging base leaves the blobs of the previous image unused
+	base2 := writeImage(t, l, "base2")
+	require.NoError(t, l.Tag(ctx, "base", base2))
+
+	res, err := l.Prune(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []digest.Digest{base.Digest, digest.FromString("base")}, res.Blobs)
+	require.Equal(t, base.Size+int64(len("base")), res.Size)
+	require.NoError(t, l.Verify(ctx))
+
+	res, err = l.Prune(ctx)
+	require.NoError(t, err)
+	require.Empty(t, res.Blobs)
+
+	// corrupt the layer of app
+	p := filepath.Join(dir, "blobs", "sha256", digest.FromString("app").Encoded())
+	require.NoError(t, os.WriteFile(p, []byte("ppa"), 0644))
+	require.ErrorContains(t, l.Verify(ctx), "does not match its digest")
+
+	require.NoError(t, os.Remove(p))
+	require.ErrorContains(t, l.Verify(ctx), "is missing")
+}
+
+func TestReference(t *testing.T) {
+	dgst := digest.FromString("app")
+	require.Equal(t, "oci-layout:///tmp/layout:app:v1@"+dgst.String(), Reference("/tmp/layout", "app:v1", dgst))
+	require.Equal(t, "oci-layout:///tmp/layout@"+dgst.String(), Reference("/tmp/layout", "", dgst))
+}
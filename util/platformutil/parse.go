@@ -1,33 +1,42 @@
 package platformutil
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/containerd/platforms"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
+// Parse parses a list of platforms, each of which may be a comma-separated
+// list itself. All invalid platforms are reported in the returned error.
 func Parse(platformsStr []string) ([]specs.Platform, error) {
 	if len(platformsStr) == 0 {
 		return nil, nil
 	}
 	out := make([]specs.Platform, 0, len(platformsStr))
+	var errs []error
 	for _, s := range platformsStr {
 		parts := strings.Split(s, ",")
 		if len(parts) > 1 {
 			p, err := Parse(parts)
 			if err != nil {
-				return nil, err
+				errs = append(errs, err)
+				continue
 			}
 			out = append(out, p...)
 			continue
 		}
 		p, err := parse(s)
 		if err != nil {
-			return nil, err
+			errs = append(errs, err)
+			continue
 		}
 		out = append(out, platforms.Normalize(p))
 	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
 	return out, nil
 }
 
@@ -35,7 +44,39 @@ func parse(in string) (specs.Platform, error) {
 	if strings.EqualFold(in, "local") {
 		return platforms.DefaultSpec(), nil
 	}
-	return platforms.Parse(in)
+	switch strings.ToLower(in) {
+	case "wasi", "wasm", "wasm32", "wasi/wasm", "wasi/wasm32":
+		return WasiPlatform(), nil
+	}
+	p, err := platforms.Parse(in)
+	if err != nil {
+		return p, err
+	}
+	if IsWasm(p) && p.Architecture == "wasm32" {
+		p.Architecture = "wasm"
+	}
+	return p, nil
+}
+
+// WasiPlatform returns the platform of WebAssembly System Interface images.
+func WasiPlatform() specs.Platform {
+	return specs.Platform{
+		OS:           "wasi",
+		Architecture: "wasm",
+	}
+}
+
+// IsWasm reports whether p is a WebAssembly platform.
+func IsWasm(p specs.Platform) bool {
+	switch p.OS {
+	case "wasi", "wasip1", "wasip2":
+		return true
+	}
+	switch p.Architecture {
+	case "wasm", "wasm32":
+		return true
+	}
+	return false
 }
 
 func Dedupe(in []specs.Platform) []specs.Platform {
@@ -0,0 +1,38 @@
+package platformutil
+
+import (
+	"testing"
+
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWasm(t *testing.T) {
+	wasi := specs.Platform{OS: "wasi", Architecture: "wasm"}
+	for _, in := range []string{"wasi", "wasm", "wasm32", "wasi/wasm", "wasi/wasm32", "WASI/WASM32"} {
+		t.Run(in, func(t *testing.T) {
+			pp, err := Parse([]string{in})
+			require.NoError(t, err)
+			require.Equal(t, []specs.Platform{wasi}, pp)
+			require.True(t, IsWasm(pp[0]))
+		})
+	}
+
+	pp, err := Parse([]string{"linux/amd64,wasi/wasm32"})
+	require.NoError(t, err)
+	require.Len(t, pp, 2)
+	require.False(t, IsWasm(pp[0]))
+	require.True(t, IsWasm(pp[1]))
+
+	pp, err = Parse([]string{"wasip1/wasm"})
+	require.NoError(t, err)
+	require.Equal(t, "wasip1", pp[0].OS)
+	require.True(t, IsWasm(pp[0]))
+}
+
+func TestParseReportsAllErrors(t *testing.T) {
+	_, err := Parse([]string{"linux/amd64,linux/", "linux/amd64/v2/x", "linux/arm64"})
+	require.Error(t, err)
+	require.ErrorContains(t, err, `"linux/"`)
+	require.ErrorContains(t, err, `"linux/amd64/v2/x"`)
+}
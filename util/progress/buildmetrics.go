@@ -0,0 +1,231 @@
+package progress
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var targetNameProperty = attribute.Key("target.name")
+
+// buildStats accumulates the progress of a build, or of one of its targets.
+type buildStats struct {
+	// Started and Completed hold the bounds of the steps of the build.
+	Started   time.Time
+	Completed time.Time
+
+	// Steps holds whether each completed step was cached.
+	Steps map[digest.Digest]bool
+
+	// Pulled and Pushed hold the bytes of each layer transferred by image
+	// sources and image exports, keyed by vertex and status id.
+	Pulled map[string]int64
+	Pushed map[string]int64
+
+	// Kind holds the kind of transfer of each step, if any.
+	Kind map[digest.Digest]transferKind
+}
+
+type transferKind int
+
+const (
+	transferNone transferKind = iota
+	transferPull
+	transferPush
+)
+
+func newBuildStats() *buildStats {
+	return &buildStats{
+		Steps:  make(map[digest.Digest]bool),
+		Pulled: make(map[string]int64),
+		Pushed: make(map[string]int64),
+		Kind:   make(map[digest.Digest]transferKind),
+	}
+}
+
+func (s *buildStats) Record(ss *client.SolveStatus) {
+	for _, v := range ss.Vertexes {
+		if _, ok := s.Kind[v.Digest]; !ok {
+			switch {
+			case detectImageSourceType(v.Name):
+				s.Kind[v.Digest] = transferPull
+			case detectExportImageType(trimTargetPrefix(v.Name)) != "":
+				s.Kind[v.Digest] = transferPush
+			default:
+				s.Kind[v.Digest] = transferNone
+			}
+		}
+		if v.Started != nil && (s.Started.IsZero() || v.Started.Before(s.Started)) {
+			s.Started = *v.Started
+		}
+		if v.Completed != nil {
+			if v.Completed.After(s.Completed) {
+				s.Completed = *v.Completed
+			}
+			s.Steps[v.Digest] = v.Cached
+		}
+	}
+
+	for _, status := range ss.Statuses {
+		if !strings.HasPrefix(status.ID, "sha256:") {
+			continue
+		}
+		var transfers map[string]int64
+		switch s.Kind[status.Vertex] {
+		case transferPull:
+			transfers = s.Pulled
+		case transferPush:
+			transfers = s.Pushed
+		default:
+			continue
+		}
+		key := status.Vertex.String() + "/" + status.ID
+		if n := max(status.Current, status.Total); n > transfers[key] {
+			transfers[key] = n
+		}
+	}
+}
+
+// CacheRatio returns the ratio of completed steps that were cached.
+func (s *buildStats) CacheRatio() float64 {
+	if len(s.Steps) == 0 {
+		return 0
+	}
+	var cached int
+	for _, v := range s.Steps {
+		if v {
+			cached++
+		}
+	}
+	return float64(cached) / float64(len(s.Steps))
+}
+
+func (s *buildStats) Duration() time.Duration {
+	if s.Started.IsZero() || s.Completed.Before(s.Started) {
+		return 0
+	}
+	return s.Completed.Sub(s.Started)
+}
+
+// trimTargetPrefix removes the target prefix added to the name of a vertex
+// that is not part of a stage by WithPrefix.
+func trimTargetPrefix(name string) string {
+	if strings.HasPrefix(name, "[") {
+		if _, rest, ok := strings.Cut(name, "] "); ok {
+			return rest
+		}
+	}
+	return name
+}
+
+func sumTransfers(m map[string]int64) (n int64) {
+	for _, v := range m {
+		n += v
+	}
+	return n
+}
+
+// buildMetrics holds the instruments recording the duration, cache hit ratio
+// and transferred bytes of a build or of its targets.
+type buildMetrics struct {
+	Duration   metric.Float64Histogram
+	CacheRatio metric.Float64Histogram
+	PulledSize metric.Int64Counter
+	PushedSize metric.Int64Counter
+}
+
+func newBuildMetrics(meter metric.Meter, prefix, subject string) *buildMetrics {
+	m := &buildMetrics{}
+	m.Duration, _ = meter.Float64Histogram(prefix+".time",
+		metric.WithDescription("Measures the duration of the "+subject+"."),
+		metric.WithUnit("ms"))
+	m.CacheRatio, _ = meter.Float64Histogram(prefix+".cache.ratio",
+		metric.WithDescription("Measures the ratio of steps of the "+subject+" that were cached."),
+		metric.WithUnit("1"))
+	m.PulledSize, _ = meter.Int64Counter(prefix+".pull.io",
+		metric.WithDescription("Measures the number of bytes of image content pulled by the "+subject+"."),
+		metric.WithUnit("By"))
+	m.PushedSize, _ = meter.Int64Counter(prefix+".push.io",
+		metric.WithDescription("Measures the number of bytes of image content pushed by the "+subject+"."),
+		metric.WithUnit("By"))
+	return m
+}
+
+func (m *buildMetrics) Record(s *buildStats, attrs attribute.Set, extra ...attribute.KeyValue) {
+	if len(s.Steps) == 0 {
+		return
+	}
+	ctx := context.Background()
+	m.Duration.Record(ctx, float64(s.Duration())/float64(time.Millisecond),
+		metric.WithAttributeSet(attrs),
+		metric.WithAttributes(extra...),
+	)
+	m.CacheRatio.Record(ctx, s.CacheRatio(),
+		metric.WithAttributeSet(attrs),
+		metric.WithAttributes(extra...),
+	)
+	m.PulledSize.Add(ctx, sumTransfers(s.Pulled),
+		metric.WithAttributeSet(attrs),
+		metric.WithAttributes(extra...),
+	)
+	m.PushedSize.Add(ctx, sumTransfers(s.Pushed),
+		metric.WithAttributeSet(attrs),
+		metric.WithAttributes(extra...),
+	)
+}
+
+// buildMetricRecorder records the metrics of the whole build, and of each of
+// its targets from the progress attributed to them with WriteTarget.
+type buildMetricRecorder struct {
+	// Attributes holds the set of base attributes for all metrics produced.
+	Attributes attribute.Set
+
+	Build   *buildMetrics
+	Targets *buildMetrics
+
+	// BuildStats holds the progress of the whole build.
+	BuildStats *buildStats
+
+	// TargetStats holds the progress of each target.
+	TargetStats map[string]*buildStats
+
+	// TargetOrder holds the targets in the order they were seen.
+	TargetOrder []string
+}
+
+func newBuildMetricRecorder(meter metric.Meter, attrs attribute.Set) *buildMetricRecorder {
+	return &buildMetricRecorder{
+		Attributes:  attrs,
+		Build:       newBuildMetrics(meter, "build", "build"),
+		Targets:     newBuildMetrics(meter, "target", "build target"),
+		BuildStats:  newBuildStats(),
+		TargetStats: make(map[string]*buildStats),
+	}
+}
+
+func (mr *buildMetricRecorder) Record(ss *client.SolveStatus) {
+	mr.BuildStats.Record(ss)
+}
+
+func (mr *buildMetricRecorder) RecordTarget(target string, ss *client.SolveStatus) {
+	st, ok := mr.TargetStats[target]
+	if !ok {
+		st = newBuildStats()
+		mr.TargetStats[target] = st
+		mr.TargetOrder = append(mr.TargetOrder, target)
+	}
+	st.Record(ss)
+}
+
+// Flush records the metrics accumulated for the build and its targets.
+func (mr *buildMetricRecorder) Flush() {
+	mr.Build.Record(mr.BuildStats, mr.Attributes)
+	for _, target := range mr.TargetOrder {
+		mr.Targets.Record(mr.TargetStats[target], mr.Attributes, targetNameProperty.String(target))
+	}
+}
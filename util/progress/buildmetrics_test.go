@@ -0,0 +1,119 @@
+package progress
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestBuildMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	mw := newMetrics(mp, attribute.NewSet(attribute.String("command.name", "bake")))
+
+	start := time.Unix(0, 0)
+	at := func(ms int) *time.Time {
+		tm := start.Add(time.Duration(ms) * time.Millisecond)
+		return &tm
+	}
+
+	from := digest.FromString("from")
+	run := digest.FromString("run")
+	export := digest.FromString("export")
+	copyd := digest.FromString("copy")
+
+	tw := &targetRecorder{mw: mw}
+	WithPrefix(tw, "app", true).Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: from, Name: "[1/3] FROM docker.io/library/alpine", Started: at(0), Completed: at(100)},
+			{Digest: run, Name: "[2/3] RUN make", Started: at(100), Completed: at(300)},
+			{Digest: copyd, Name: "[3/3] COPY . .", Started: at(300), Completed: at(300), Cached: true},
+			{Digest: export, Name: "exporting to image", Started: at(300), Completed: at(400)},
+		},
+		Statuses: []*client.VertexStatus{
+			{ID: "sha256:aaa", Vertex: from, Current: 50, Total: 100},
+			{ID: "sha256:aaa", Vertex: from, Current: 100, Total: 100, Started: at(0), Completed: at(100)},
+			{ID: "sha256:bbb", Vertex: export, Current: 30, Total: 30, Started: at(350), Completed: at(400)},
+			{ID: "exporting layers", Vertex: export, Started: at(300), Completed: at(350)},
+		},
+	})
+	WithPrefix(tw, "base", true).Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: from, Name: "[1/1] FROM docker.io/library/alpine", Started: at(0), Completed: at(0), Cached: true},
+		},
+	})
+	mw.Flush()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+
+	histogram := func(name string) map[string]metricdata.HistogramDataPoint[float64] {
+		t.Helper()
+		require.Contains(t, metrics, name)
+		points := map[string]metricdata.HistogramDataPoint[float64]{}
+		for _, dp := range metrics[name].(metricdata.Histogram[float64]).DataPoints {
+			target, _ := dp.Attributes.Value(targetNameProperty)
+			points[target.AsString()] = dp
+		}
+		return points
+	}
+	sum := func(name string) map[string]int64 {
+		t.Helper()
+		require.Contains(t, metrics, name)
+		points := map[string]int64{}
+		for _, dp := range metrics[name].(metricdata.Sum[int64]).DataPoints {
+			target, _ := dp.Attributes.Value(targetNameProperty)
+			points[target.AsString()] = dp.Value
+		}
+		return points
+	}
+
+	buildTime := histogram("build.time")
+	require.Equal(t, 400.0, buildTime[""].Sum)
+	targetTime := histogram("target.time")
+	require.Equal(t, 400.0, targetTime["app"].Sum)
+	require.Equal(t, 0.0, targetTime["base"].Sum)
+
+	targetCache := histogram("target.cache.ratio")
+	require.Equal(t, 0.25, targetCache["app"].Sum)
+	require.Equal(t, 1.0, targetCache["base"].Sum)
+
+	require.Equal(t, map[string]int64{"": 100}, sum("build.pull.io"))
+	require.Equal(t, map[string]int64{"": 30}, sum("build.push.io"))
+	require.Equal(t, map[string]int64{"app": 100, "base": 0}, sum("target.pull.io"))
+	require.Equal(t, map[string]int64{"app": 30, "base": 0}, sum("target.push.io"))
+
+	for _, dp := range buildTime {
+		v, ok := dp.Attributes.Value("command.name")
+		require.True(t, ok)
+		require.Equal(t, "bake", v.AsString())
+	}
+}
+
+// targetRecorder is a writer recording the metrics of each target like
+// Printer.
+type targetRecorder struct {
+	Writer
+	mw *metricWriter
+}
+
+func (w *targetRecorder) Write(s *client.SolveStatus) {
+	w.mw.Write(s)
+}
+
+func (w *targetRecorder) WriteTarget(target string, s *client.SolveStatus) {
+	w.mw.WriteTarget(target, s)
+	w.Write(s)
+}
@@ -0,0 +1,129 @@
+package progress
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+)
+
+const cacheImportPrefix = "importing cache manifest from "
+
+// Reasons for a step of the build to be rebuilt instead of being a cache hit.
+const (
+	// CacheMissInputChanged is set when an input of the step was rebuilt, so
+	// the cache key of the step changed.
+	CacheMissInputChanged = "input-changed"
+	// CacheMissNoMatch is set when the inputs of the step were cache hits,
+	// but no cache record matched the definition of the step.
+	CacheMissNoMatch = "no-match"
+	// CacheMissFailed is set when the step failed.
+	CacheMissFailed = "failed"
+)
+
+// CacheStep is the cache status of a completed step of the build.
+type CacheStep struct {
+	Vertex digest.Digest `json:"vertex"`
+	Name   string        `json:"name"`
+	Cached bool          `json:"cached"`
+	// Reason is the reason why the step was rebuilt.
+	Reason string `json:"reason,omitempty"`
+	// ChangedInputs are the names of the inputs of the step that were
+	// rebuilt.
+	ChangedInputs []string `json:"changedInputs,omitempty"`
+}
+
+// CacheReport is the cache status of the steps of a build.
+type CacheReport struct {
+	Steps []CacheStep `json:"steps"`
+	// Imports are the cache sources whose manifest was imported, as named
+	// by BuildKit.
+	Imports []string `json:"-"`
+}
+
+type cacheVertex struct {
+	name      string
+	inputs    []digest.Digest
+	cached    bool
+	completed bool
+	err       string
+}
+
+// cacheReportWriter records the cache status and the inputs of the steps of
+// a build.
+type cacheReportWriter struct {
+	mu       sync.Mutex
+	vertexes map[digest.Digest]*cacheVertex
+	order    []digest.Digest
+}
+
+func newCacheReportWriter() *cacheReportWriter {
+	return &cacheReportWriter{
+		vertexes: map[digest.Digest]*cacheVertex{},
+	}
+}
+
+func (w *cacheReportWriter) Write(ss *client.SolveStatus) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, v := range ss.Vertexes {
+		cv, ok := w.vertexes[v.Digest]
+		if !ok {
+			cv = &cacheVertex{}
+			w.vertexes[v.Digest] = cv
+			w.order = append(w.order, v.Digest)
+		}
+		cv.name = v.Name
+		if len(v.Inputs) > 0 {
+			cv.inputs = v.Inputs
+		}
+		cv.cached = v.Cached
+		cv.completed = v.Completed != nil
+		cv.err = v.Error
+	}
+}
+
+// Report returns the cache status of the completed steps in the order they
+// were started.
+func (w *cacheReportWriter) Report() CacheReport {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var report CacheReport
+	for _, dgst := range w.order {
+		v := w.vertexes[dgst]
+		if !v.completed {
+			continue
+		}
+		if strings.HasPrefix(v.name, cacheImportPrefix) {
+			if v.err == "" {
+				report.Imports = append(report.Imports, strings.TrimPrefix(v.name, cacheImportPrefix))
+			}
+			continue
+		}
+		step := CacheStep{
+			Vertex: dgst,
+			Name:   v.name,
+			Cached: v.cached,
+		}
+		if !v.cached {
+			for _, in := range v.inputs {
+				if iv, ok := w.vertexes[in]; ok && iv.completed && !iv.cached {
+					step.ChangedInputs = append(step.ChangedInputs, iv.name)
+				}
+			}
+			switch {
+			case v.err != "":
+				step.Reason = CacheMissFailed
+			case len(step.ChangedInputs) > 0:
+				step.Reason = CacheMissInputChanged
+			default:
+				step.Reason = CacheMissNoMatch
+			}
+		}
+		report.Steps = append(report.Steps, step)
+	}
+	return report
+}
@@ -0,0 +1,57 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheReportWriter(t *testing.T) {
+	w := newCacheReportWriter()
+
+	now := time.Now()
+	imp := digest.FromString("import")
+	base := digest.FromString("base")
+	src := digest.FromString("context")
+	deps := digest.FromString("deps")
+	build := digest.FromString("build")
+	pending := digest.FromString("pending")
+
+	w.Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: imp, Name: "importing cache manifest from docker.io/org/app:cache", Started: &now, Completed: &now},
+			{Digest: base, Name: "[1/4] FROM docker.io/library/golang", Started: &now, Completed: &now, Cached: true},
+			{Digest: src, Name: "[internal] load build context", Started: &now, Completed: &now},
+			{Digest: deps, Name: "[2/4] RUN go mod download", Inputs: []digest.Digest{base}, Started: &now},
+			{Digest: build, Name: "[3/4] RUN go build", Inputs: []digest.Digest{deps, src}},
+			{Digest: pending, Name: "[4/4] COPY --from=build"},
+		},
+	})
+	w.Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: deps, Name: "[2/4] RUN go mod download", Started: &now, Completed: &now},
+			{Digest: build, Name: "[3/4] RUN go build", Inputs: []digest.Digest{deps, src}, Started: &now, Completed: &now, Error: "exit code: 1"},
+		},
+	})
+
+	report := w.Report()
+	require.Equal(t, []string{"docker.io/org/app:cache"}, report.Imports)
+	require.Len(t, report.Steps, 4)
+
+	require.Equal(t, "[1/4] FROM docker.io/library/golang", report.Steps[0].Name)
+	require.True(t, report.Steps[0].Cached)
+	require.Empty(t, report.Steps[0].Reason)
+
+	require.Equal(t, "[internal] load build context", report.Steps[1].Name)
+	require.Equal(t, CacheMissNoMatch, report.Steps[1].Reason)
+
+	// the inputs of the first status are kept
+	require.Equal(t, "[2/4] RUN go mod download", report.Steps[2].Name)
+	require.Equal(t, CacheMissNoMatch, report.Steps[2].Reason)
+
+	require.Equal(t, CacheMissFailed, report.Steps[3].Reason)
+	require.Equal(t, []string{"[2/4] RUN go mod download", "[internal] load build context"}, report.Steps[3].ChangedInputs)
+}
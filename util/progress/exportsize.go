@@ -0,0 +1,32 @@
+package progress
+
+import (
+	"sync"
+
+	"github.com/moby/buildkit/client"
+)
+
+// exportSizeWriter records the size of the blobs written by the image exports
+// of a build.
+type exportSizeWriter struct {
+	mu    sync.Mutex
+	stats *buildStats
+}
+
+func newExportSizeWriter() *exportSizeWriter {
+	return &exportSizeWriter{
+		stats: newBuildStats(),
+	}
+}
+
+func (w *exportSizeWriter) Write(ss *client.SolveStatus) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stats.Record(ss)
+}
+
+func (w *exportSizeWriter) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return sumTransfers(w.stats.Pushed)
+}
@@ -0,0 +1,97 @@
+package progress
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/pkg/errors"
+)
+
+const (
+	// GroupByDefault keeps the progress groups set by BuildKit.
+	GroupByDefault = ""
+	// GroupByStage collapses the steps of each stage of a Dockerfile.
+	GroupByStage = "stage"
+	// GroupByTarget collapses the steps of each target of a bake build.
+	GroupByTarget = "target"
+	// GroupByNone displays every step, including the ones that BuildKit
+	// groups.
+	GroupByNone = "none"
+)
+
+// ParseGroupBy validates how the steps of a build are grouped in the progress
+// display.
+func ParseGroupBy(v string) (string, error) {
+	switch v {
+	case GroupByDefault, GroupByStage, GroupByTarget, GroupByNone:
+		return v, nil
+	}
+	return "", errors.Errorf("invalid progress group %q, expected stage, target or none", v)
+}
+
+// WithGroupBy sets how the steps of a build are grouped in the progress
+// display. Grouped steps are collapsed in a single line, and the logs of a
+// failing step are still printed with its error.
+func WithGroupBy(groupBy string) PrinterOpt {
+	return func(opt *printerOpts) {
+		opt.groupBy = groupBy
+	}
+}
+
+// stepRegexp matches the step counter of the name of a Dockerfile step.
+var stepRegexp = regexp.MustCompile(`^\d+/\d+$`)
+
+// groupVertexes returns the status with the progress groups of its vertexes
+// set by groupBy. The vertexes are copied so that the other writers receive
+// the status as it was written.
+func groupVertexes(groupBy, target string, s *client.SolveStatus) *client.SolveStatus {
+	if groupBy == GroupByDefault || len(s.Vertexes) == 0 {
+		return s
+	}
+	out := *s
+	out.Vertexes = make([]*client.Vertex, len(s.Vertexes))
+	for i, v := range s.Vertexes {
+		v2 := *v
+		switch groupBy {
+		case GroupByNone:
+			v2.ProgressGroup = nil
+		case GroupByStage:
+			if id, name, ok := stageGroup(target, v.Name); ok {
+				v2.ProgressGroup = &pb.ProgressGroup{Id: id, Name: name}
+			}
+		case GroupByTarget:
+			if target != "" {
+				v2.ProgressGroup = &pb.ProgressGroup{Id: "target:" + target, Name: "[" + target + "] target"}
+			}
+		}
+		out.Vertexes[i] = &v2
+	}
+	return &out
+}
+
+// stageGroup returns the progress group of a Dockerfile step, whose name is
+// like "[stage 2/5] RUN ...", or "[target stage 2/5] RUN ..." when prefixed by
+// the target. Steps of unnamed stages and other vertexes are not grouped.
+func stageGroup(target, name string) (string, string, bool) {
+	if !strings.HasPrefix(name, "[") {
+		return "", "", false
+	}
+	pfx, _, ok := strings.Cut(name[1:], "]")
+	if !ok {
+		return "", "", false
+	}
+	fields := strings.Fields(pfx)
+	if target != "" && len(fields) > 0 && fields[0] == target {
+		fields = fields[1:]
+	}
+	if len(fields) != 2 || !stepRegexp.MatchString(fields[1]) {
+		return "", "", false
+	}
+	groupName := "stage " + fields[0]
+	if target != "" {
+		groupName = addPrefix(target, groupName)
+	}
+	return "stage:" + target + ":" + fields[0], groupName, true
+}
@@ -0,0 +1,54 @@
+package progress
+
+import (
+	"testing"
+
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupVertexes(t *testing.T) {
+	status := func() *client.SolveStatus {
+		return &client.SolveStatus{
+			Vertexes: []*client.Vertex{
+				{Digest: digest.FromString("load"), Name: "[app internal] load build definition from Dockerfile"},
+				{Digest: digest.FromString("from"), Name: "[app build 1/3] FROM docker.io/library/golang:1.22"},
+				{Digest: digest.FromString("run"), Name: "[app build 2/3] RUN go build ./..."},
+				{Digest: digest.FromString("copy"), Name: "[app 1/1] COPY --from=build /out /"},
+				{Digest: digest.FromString("export"), Name: "exporting layers", ProgressGroup: &pb.ProgressGroup{Id: "export", Name: "exporting"}},
+			},
+		}
+	}
+	groups := func(s *client.SolveStatus) []string {
+		var out []string
+		for _, v := range s.Vertexes {
+			if v.ProgressGroup == nil {
+				out = append(out, "")
+				continue
+			}
+			out = append(out, v.ProgressGroup.Name)
+		}
+		return out
+	}
+
+	s := status()
+	require.Same(t, s, groupVertexes(GroupByDefault, "app", s))
+
+	s = status()
+	require.Equal(t, []string{"", "[app] stage build", "[app] stage build", "", "exporting"}, groups(groupVertexes(GroupByStage, "app", s)))
+	require.Equal(t, []string{"", "", "", "", "exporting"}, groups(s), "written status must not be modified")
+
+	s = status()
+	grouped := groupVertexes(GroupByStage, "app", s)
+	require.Equal(t, grouped.Vertexes[1].ProgressGroup.Id, grouped.Vertexes[2].ProgressGroup.Id)
+
+	require.Equal(t, []string{"[app] target", "[app] target", "[app] target", "[app] target", "[app] target"}, groups(groupVertexes(GroupByTarget, "app", status())))
+	require.Equal(t, []string{"", "", "", "", "exporting"}, groups(groupVertexes(GroupByTarget, "", status())))
+
+	require.Equal(t, []string{"", "", "", "", ""}, groups(groupVertexes(GroupByNone, "app", status())))
+
+	_, err := ParseGroupBy("layer")
+	require.ErrorContains(t, err, `invalid progress group "layer"`)
+}
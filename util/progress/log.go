@@ -0,0 +1,51 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/util/progress/progressui"
+)
+
+// logWriter records the statuses of the build so its full log can be
+// rendered once the build is complete.
+type logWriter struct {
+	mu       sync.Mutex
+	statuses []*client.SolveStatus
+}
+
+func newLogWriter() *logWriter {
+	return &logWriter{}
+}
+
+func (lw *logWriter) Write(s *client.SolveStatus) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	lw.statuses = append(lw.statuses, s)
+}
+
+// Log renders the recorded statuses with the plain display.
+func (lw *logWriter) Log(ctx context.Context) ([]byte, error) {
+	lw.mu.Lock()
+	statuses := lw.statuses
+	lw.mu.Unlock()
+
+	buf := &bytes.Buffer{}
+	d, err := progressui.NewDisplay(buf, progressui.PlainMode)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan *client.SolveStatus)
+	go func() {
+		defer close(ch)
+		for _, s := range statuses {
+			ch <- s
+		}
+	}()
+	if _, err := d.UpdateFrom(ctx, ch); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
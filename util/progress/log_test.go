@@ -0,0 +1,41 @@
+package progress
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogWriter(t *testing.T) {
+	lw := newLogWriter()
+
+	now := time.Now()
+	later := now.Add(time.Second)
+	dgst := digest.FromString("run")
+
+	lw.Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: dgst, Name: "[1/1] RUN make", Started: &now},
+		},
+	})
+	lw.Write(&client.SolveStatus{
+		Logs: []*client.VertexLog{
+			{Vertex: dgst, Data: []byte("compiling\n"), Timestamp: now},
+		},
+	})
+	lw.Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: dgst, Name: "[1/1] RUN make", Started: &now, Completed: &later},
+		},
+	})
+
+	dt, err := lw.Log(context.TODO())
+	require.NoError(t, err)
+	require.Contains(t, string(dt), "[1/1] RUN make")
+	require.Contains(t, string(dt), "compiling")
+	require.Contains(t, string(dt), "DONE 1.0s")
+}
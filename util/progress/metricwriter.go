@@ -44,12 +44,15 @@ var re = sync.OnceValue(func() *rePatterns {
 
 type metricWriter struct {
 	recorders []metricRecorder
+	build     *buildMetricRecorder
 	attrs     attribute.Set
 	mu        sync.Mutex
+	flushOnce sync.Once
 }
 
 func newMetrics(mp metric.MeterProvider, attrs attribute.Set) *metricWriter {
 	meter := metricutil.Meter(mp)
+	build := newBuildMetricRecorder(meter, attrs)
 	return &metricWriter{
 		recorders: []metricRecorder{
 			newLocalSourceTransferMetricRecorder(meter, attrs),
@@ -58,7 +61,9 @@ func newMetrics(mp metric.MeterProvider, attrs attribute.Set) *metricWriter {
 			newExportImageMetricRecorder(meter, attrs),
 			newIdleMetricRecorder(meter, attrs),
 			newLintMetricRecorder(meter, attrs),
+			build,
 		},
+		build: build,
 		attrs: attrs,
 	}
 }
@@ -72,6 +77,25 @@ func (mw *metricWriter) Write(ss *client.SolveStatus) {
 	}
 }
 
+// WriteTarget attributes the progress of a build to one of its targets. The
+// status still needs to be written with Write.
+func (mw *metricWriter) WriteTarget(target string, ss *client.SolveStatus) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	mw.build.RecordTarget(target, ss)
+}
+
+// Flush records the metrics that are only known once the build completed.
+func (mw *metricWriter) Flush() {
+	mw.flushOnce.Do(func() {
+		mw.mu.Lock()
+		defer mw.mu.Unlock()
+
+		mw.build.Flush()
+	})
+}
+
 type metricRecorder interface {
 	Record(ss *client.SolveStatus)
 }
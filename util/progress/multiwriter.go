@@ -29,9 +29,19 @@ func (p *prefixed) Write(v *client.SolveStatus) {
 			}
 		}
 	}
+	if tw, ok := p.Writer.(targetWriter); ok {
+		tw.WriteTarget(p.pfx, v)
+		return
+	}
 	p.Writer.Write(v)
 }
 
+// targetWriter is implemented by writers recording the progress of each
+// target of a build.
+type targetWriter interface {
+	WriteTarget(target string, s *client.SolveStatus)
+}
+
 func addPrefix(pfx, name string) string {
 	if strings.HasPrefix(name, "[") {
 		return "[" + pfx + " " + name[1:]
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/containerd/console"
 	"github.com/docker/buildx/util/logutil"
@@ -29,6 +30,13 @@ type Printer struct {
 	logMu        sync.Mutex
 	logSourceMap map[digest.Digest]interface{}
 	metrics      *metricWriter
+	summary      *summaryWriter
+	exportSize   *exportSizeWriter
+	log          *logWriter
+	report       *reportWriter
+	cacheReport  *cacheReportWriter
+	rawJSON      *rawJSONWriter
+	groupBy      string
 
 	// TODO: remove once we can use result context to pass build ref
 	//  see https://github.com/docker/buildx/pull/1861
@@ -64,16 +72,122 @@ func (p *Printer) Unpause() {
 }
 
 func (p *Printer) Write(s *client.SolveStatus) {
-	p.status <- s
+	p.write("", s)
+}
+
+func (p *Printer) write(target string, s *client.SolveStatus) {
+	if p.rawJSON != nil {
+		p.rawJSON.write(target, s)
+	}
+	p.status <- groupVertexes(p.groupBy, target, s)
 	if p.metrics != nil {
 		p.metrics.Write(s)
 	}
+	if p.summary != nil {
+		p.summary.Write(s)
+	}
+	if p.exportSize != nil {
+		p.exportSize.Write(s)
+	}
+	if p.log != nil {
+		p.log.Write(s)
+	}
+	if p.cacheReport != nil {
+		p.cacheReport.Write(s)
+	}
+}
+
+// WriteTarget writes the progress of a target of the build, so its metrics
+// can be recorded separately.
+func (p *Printer) WriteTarget(target string, s *client.SolveStatus) {
+	if p.metrics != nil {
+		p.metrics.WriteTarget(target, s)
+	}
+	if p.report != nil {
+		p.report.WriteTarget(target, s)
+	}
+	p.write(target, s)
+}
+
+// StartTarget reports that the build of a target started. It is only written
+// by the rawjson display of a printer created with WithTargetEvents, and
+// recorded by a printer created with WithTargetReport.
+func (p *Printer) StartTarget(target string) {
+	if p.report != nil {
+		p.report.start(target)
+	}
+	if p.rawJSON != nil {
+		p.rawJSON.writeEvent(TargetEvent{Target: target, Event: TargetEventStart, Time: time.Now()})
+	}
+}
+
+// FinishTarget reports that the build of a target finished, with err if it
+// failed. It is only written by the rawjson display of a printer created with
+// WithTargetEvents, and recorded by a printer created with WithTargetReport.
+func (p *Printer) FinishTarget(target string, err error) {
+	if p.report != nil {
+		p.report.finish(target, err)
+	}
+	if p.rawJSON != nil {
+		ev := TargetEvent{Target: target, Event: TargetEventFinish, Time: time.Now()}
+		if err != nil {
+			ev.Error = err.Error()
+		}
+		p.rawJSON.writeEvent(ev)
+	}
 }
 
 func (p *Printer) Warnings() []client.VertexWarning {
 	return dedupWarnings(p.warnings)
 }
 
+// ExportedSize returns the number of bytes of the image blobs written by the
+// exports of the build. It returns 0 unless the printer was created with
+// WithExportSize.
+func (p *Printer) ExportedSize() int64 {
+	if p.exportSize == nil {
+		return 0
+	}
+	return p.exportSize.Size()
+}
+
+// Summary returns the steps of the build sorted by decreasing duration. It
+// returns nil unless the printer was created with WithSummary.
+func (p *Printer) Summary() []StepSummary {
+	if p.summary == nil {
+		return nil
+	}
+	return p.summary.Summary()
+}
+
+// TargetReports returns the reports of the targets of the build sorted by
+// name. It returns nil unless the printer was created with WithTargetReport.
+func (p *Printer) TargetReports() []TargetReport {
+	if p.report == nil {
+		return nil
+	}
+	return p.report.Reports()
+}
+
+// CacheReport returns the cache status of the completed steps of the build.
+// It returns an empty report unless the printer was created with
+// WithCacheReport.
+func (p *Printer) CacheReport() CacheReport {
+	if p.cacheReport == nil {
+		return CacheReport{}
+	}
+	return p.cacheReport.Report()
+}
+
+// Log returns the full log of the build rendered as plain text. It returns nil
+// unless the printer was created with WithLog.
+func (p *Printer) Log(ctx context.Context) ([]byte, error) {
+	if p.log == nil {
+		return nil, nil
+	}
+	return p.log.Log(ctx)
+}
+
 func (p *Printer) ValidateLogSource(dgst digest.Digest, v interface{}) bool {
 	p.logMu.Lock()
 	defer p.logMu.Unlock()
@@ -109,14 +223,29 @@ func NewPrinter(ctx context.Context, out console.File, mode progressui.DisplayMo
 		mode = progressui.DisplayMode(v)
 	}
 
+	var rawJSON *rawJSONWriter
+	if opt.targetEvents && mode == progressui.RawJSONMode {
+		// the statuses are written by the printer itself to tag them with
+		// their target, the display only collects the warnings
+		rawJSON = newRawJSONWriter(out)
+		mode = progressui.QuietMode
+	}
+
 	d, err := progressui.NewDisplay(out, mode, opt.displayOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	pw := &Printer{
-		ready:   make(chan struct{}),
-		metrics: opt.mw,
+		ready:       make(chan struct{}),
+		metrics:     opt.mw,
+		summary:     opt.sw,
+		exportSize:  opt.esw,
+		log:         opt.lw,
+		report:      opt.rw,
+		cacheReport: opt.crw,
+		rawJSON:     rawJSON,
+		groupBy:     opt.groupBy,
 	}
 	go func() {
 		for {
@@ -132,6 +261,10 @@ func NewPrinter(ctx context.Context, out console.File, mode progressui.DisplayMo
 			// not using shared context to not disrupt display but let is finish reporting errors
 			pw.warnings, pw.err = d.UpdateFrom(ctx, pw.status)
 			resumeLogs()
+			if pw.paused == nil && pw.metrics != nil {
+				// the build is complete unless the printer is only paused
+				pw.metrics.Flush()
+			}
 			close(pw.done)
 
 			if opt.onclose != nil {
@@ -168,8 +301,15 @@ func (p *Printer) BuildRefs() map[string]string {
 type printerOpts struct {
 	displayOpts []progressui.DisplayOpt
 	mw          *metricWriter
-
-	onclose func()
+	sw          *summaryWriter
+	esw         *exportSizeWriter
+	lw          *logWriter
+	rw          *reportWriter
+	crw         *cacheReportWriter
+
+	targetEvents bool
+	groupBy      string
+	onclose      func()
 }
 
 type PrinterOpt func(b *printerOpts)
@@ -192,6 +332,55 @@ func WithMetrics(mp metric.MeterProvider, attrs attribute.Set) PrinterOpt {
 	}
 }
 
+// WithSummary records the duration, cache status and transfer sizes of each
+// step so they can be retrieved with Printer.Summary.
+func WithSummary() PrinterOpt {
+	return func(opt *printerOpts) {
+		opt.sw = newSummaryWriter()
+	}
+}
+
+// WithExportSize records the size of the image blobs written by the exports
+// so it can be retrieved with Printer.ExportedSize.
+func WithExportSize() PrinterOpt {
+	return func(opt *printerOpts) {
+		opt.esw = newExportSizeWriter()
+	}
+}
+
+// WithLog records the statuses of the build so its full log can be retrieved
+// with Printer.Log, whatever the display mode.
+func WithLog() PrinterOpt {
+	return func(opt *printerOpts) {
+		opt.lw = newLogWriter()
+	}
+}
+
+// WithTargetReport records the status, steps and warnings of each target so
+// they can be retrieved with Printer.TargetReports.
+func WithTargetReport() PrinterOpt {
+	return func(opt *printerOpts) {
+		opt.rw = newReportWriter()
+	}
+}
+
+// WithCacheReport records the cache status and the inputs of each step so the
+// cache report of the build can be retrieved with Printer.CacheReport.
+func WithCacheReport() PrinterOpt {
+	return func(opt *printerOpts) {
+		opt.crw = newCacheReportWriter()
+	}
+}
+
+// WithTargetEvents tags the statuses written by the rawjson display with the
+// name of their target, and writes an event when the build of each target
+// starts and finishes.
+func WithTargetEvents() PrinterOpt {
+	return func(opt *printerOpts) {
+		opt.targetEvents = true
+	}
+}
+
 func WithOnClose(onclose func()) PrinterOpt {
 	return func(opt *printerOpts) {
 		opt.onclose = onclose
@@ -0,0 +1,74 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/moby/buildkit/client"
+)
+
+const (
+	TargetEventStart  = "start"
+	TargetEventFinish = "finish"
+)
+
+// TargetEvent is written by the rawjson display of a printer created with
+// WithTargetEvents when the build of a target starts or finishes.
+type TargetEvent struct {
+	Target string    `json:"target"`
+	Event  string    `json:"event"`
+	Time   time.Time `json:"time"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// targetStatus is a solve status tagged with the target it belongs to.
+type targetStatus struct {
+	Target string `json:"target,omitempty"`
+	*client.SolveStatus
+}
+
+// rawJSONWriter is the rawjson display of a printer created with
+// WithTargetEvents.
+type rawJSONWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newRawJSONWriter(w io.Writer) *rawJSONWriter {
+	return &rawJSONWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *rawJSONWriter) write(target string, s *client.SolveStatus) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.enc.Encode(targetStatus{Target: target, SolveStatus: s})
+}
+
+func (w *rawJSONWriter) writeEvent(ev TargetEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.enc.Encode(ev)
+}
+
+// targetEventWriter is implemented by writers reporting when the build of
+// each target starts and finishes.
+type targetEventWriter interface {
+	StartTarget(target string)
+	FinishTarget(target string, err error)
+}
+
+// StartTarget reports that the build of target started if w supports it.
+func StartTarget(w Writer, target string) {
+	if tw, ok := w.(targetEventWriter); ok {
+		tw.StartTarget(target)
+	}
+}
+
+// FinishTarget reports that the build of target finished if w supports it.
+func FinishTarget(w Writer, target string, err error) {
+	if tw, ok := w.(targetEventWriter); ok {
+		tw.FinishTarget(target, err)
+	}
+}
@@ -0,0 +1,89 @@
+package progress
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/util/progress/progressui"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrinterTargetEvents(t *testing.T) {
+	t.Setenv("BUILDKIT_PROGRESS", "")
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "progress.json"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	p, err := NewPrinter(context.TODO(), f, progressui.RawJSONMode, WithTargetEvents())
+	require.NoError(t, err)
+
+	dgst := digest.FromString("run")
+	StartTarget(p, "app")
+	WithPrefix(p, "app", true).Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{{Digest: dgst, Name: "[1/1] RUN make"}},
+	})
+	p.Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{{Digest: digest.FromString("load"), Name: "loading definitions"}},
+	})
+	FinishTarget(p, "app", errors.New("exit code 1"))
+	require.NoError(t, p.Wait())
+
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &m))
+		lines = append(lines, m)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, lines, 4)
+
+	require.Equal(t, "app", lines[0]["target"])
+	require.Equal(t, TargetEventStart, lines[0]["event"])
+
+	require.Equal(t, "app", lines[1]["target"])
+	vertexes := lines[1]["vertexes"].([]interface{})
+	require.Equal(t, "[app 1/1] RUN make", vertexes[0].(map[string]interface{})["name"])
+
+	require.NotContains(t, lines[2], "target")
+	require.Contains(t, lines[2], "vertexes")
+
+	require.Equal(t, "app", lines[3]["target"])
+	require.Equal(t, TargetEventFinish, lines[3]["event"])
+	require.Equal(t, "exit code 1", lines[3]["error"])
+}
+
+func TestPrinterRawJSONWithoutTargetEvents(t *testing.T) {
+	t.Setenv("BUILDKIT_PROGRESS", "")
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "progress.json"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	p, err := NewPrinter(context.TODO(), f, progressui.RawJSONMode)
+	require.NoError(t, err)
+
+	StartTarget(p, "default")
+	WithPrefix(p, "default", false).Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{{Digest: digest.FromString("run"), Name: "[1/1] RUN make"}},
+	})
+	FinishTarget(p, "default", nil)
+	require.NoError(t, p.Wait())
+
+	dt, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(dt, &m))
+	require.NotContains(t, m, "target")
+	require.Contains(t, m, "vertexes")
+}
@@ -0,0 +1,136 @@
+package progress
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+)
+
+// TargetReport is the report of the build of a target.
+type TargetReport struct {
+	Name     string
+	Started  time.Time
+	Finished time.Time
+	Error    string
+	// Steps is the number of completed steps, of which Cached were cached.
+	Steps    int
+	Cached   int
+	Warnings []string
+}
+
+// Duration returns the time spent building the target, or 0 if the build of
+// the target did not finish.
+func (r TargetReport) Duration() time.Duration {
+	if r.Started.IsZero() || r.Finished.IsZero() {
+		return 0
+	}
+	return r.Finished.Sub(r.Started)
+}
+
+// CacheRatio returns the ratio of the completed steps that were cached.
+func (r TargetReport) CacheRatio() float64 {
+	if r.Steps == 0 {
+		return 0
+	}
+	return float64(r.Cached) / float64(r.Steps)
+}
+
+type reportTarget struct {
+	started  time.Time
+	finished time.Time
+	err      string
+	steps    map[digest.Digest]bool
+	warnings []string
+}
+
+// reportWriter records the status, steps and warnings of each target of a
+// build.
+type reportWriter struct {
+	mu      sync.Mutex
+	targets map[string]*reportTarget
+}
+
+func newReportWriter() *reportWriter {
+	return &reportWriter{
+		targets: map[string]*reportTarget{},
+	}
+}
+
+func (w *reportWriter) target(name string) *reportTarget {
+	t, ok := w.targets[name]
+	if !ok {
+		t = &reportTarget{steps: map[digest.Digest]bool{}}
+		w.targets[name] = t
+	}
+	return t
+}
+
+func (w *reportWriter) WriteTarget(target string, ss *client.SolveStatus) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	t := w.target(target)
+	for _, v := range ss.Vertexes {
+		if v.Completed != nil {
+			t.steps[v.Digest] = v.Cached
+		}
+	}
+	for _, warn := range ss.Warnings {
+		msg := string(warn.Short)
+		if !slices.Contains(t.warnings, msg) {
+			t.warnings = append(t.warnings, msg)
+		}
+	}
+}
+
+func (w *reportWriter) start(target string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.target(target).started = time.Now()
+}
+
+func (w *reportWriter) finish(target string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	t := w.target(target)
+	t.finished = time.Now()
+	if err != nil {
+		t.err = err.Error()
+	}
+}
+
+// Reports returns the reports of the targets sorted by name.
+func (w *reportWriter) Reports() []TargetReport {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	reports := make([]TargetReport, 0, len(w.targets))
+	for name, t := range w.targets {
+		r := TargetReport{
+			Name:     name,
+			Started:  t.started,
+			Finished: t.finished,
+			Error:    t.err,
+			Steps:    len(t.steps),
+			Warnings: slices.Clone(t.warnings),
+		}
+		for _, cached := range t.steps {
+			if cached {
+				r.Cached++
+			}
+		}
+		reports = append(reports, r)
+	}
+	slices.SortFunc(reports, func(a, b TargetReport) int {
+		if a.Name < b.Name {
+			return -1
+		} else if a.Name > b.Name {
+			return 1
+		}
+		return 0
+	})
+	return reports
+}
@@ -0,0 +1,61 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportWriter(t *testing.T) {
+	rw := newReportWriter()
+
+	now := time.Now()
+	build := digest.FromString("build")
+	cp := digest.FromString("copy")
+	pending := digest.FromString("pending")
+
+	rw.start("app")
+	rw.start("db")
+	rw.WriteTarget("app", &client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: cp, Started: &now, Completed: &now, Cached: true},
+			{Digest: build, Started: &now},
+			{Digest: pending},
+		},
+		Warnings: []*client.VertexWarning{
+			{Vertex: build, Short: []byte("FromAsCasing")},
+		},
+	})
+	rw.WriteTarget("app", &client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: build, Started: &now, Completed: &now},
+		},
+		Warnings: []*client.VertexWarning{
+			{Vertex: build, Short: []byte("FromAsCasing")},
+		},
+	})
+	rw.finish("app", nil)
+	rw.finish("db", errors.New("failed to solve"))
+
+	reports := rw.Reports()
+	require.Len(t, reports, 2)
+
+	app := reports[0]
+	require.Equal(t, "app", app.Name)
+	require.Equal(t, 2, app.Steps)
+	require.Equal(t, 1, app.Cached)
+	require.InDelta(t, 0.5, app.CacheRatio(), 0.001)
+	require.Equal(t, []string{"FromAsCasing"}, app.Warnings)
+	require.Empty(t, app.Error)
+	require.False(t, app.Finished.IsZero())
+
+	db := reports[1]
+	require.Equal(t, "db", db.Name)
+	require.Equal(t, "failed to solve", db.Error)
+	require.Equal(t, 0, db.Steps)
+	require.Zero(t, db.CacheRatio())
+}
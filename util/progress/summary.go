@@ -0,0 +1,116 @@
+package progress
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+)
+
+// StepSummary is the summary of a completed build step.
+type StepSummary struct {
+	Name string `json:"name"`
+	// Duration is the time spent running the step, in nanoseconds.
+	Duration time.Duration `json:"duration"`
+	Cached   bool          `json:"cached"`
+	// Transferred is the number of bytes transferred by the step, e.g. when
+	// pulling layers or sending the build context.
+	Transferred int64  `json:"transferred,omitempty"`
+	Error       string `json:"error,omitempty"`
+	// CPU is the CPU time used by the step, if sampled by the builder.
+	CPU time.Duration `json:"cpu,omitempty"`
+	// MemoryPeak is the peak memory usage of the step in bytes, if sampled
+	// by the builder.
+	MemoryPeak int64 `json:"memoryPeak,omitempty"`
+
+	Vertex digest.Digest `json:"vertex"`
+}
+
+type summaryStep struct {
+	name      string
+	started   *time.Time
+	completed *time.Time
+	cached    bool
+	err       string
+	transfers map[string]int64
+}
+
+type summaryWriter struct {
+	mu    sync.Mutex
+	steps map[digest.Digest]*summaryStep
+	order []digest.Digest
+}
+
+func newSummaryWriter() *summaryWriter {
+	return &summaryWriter{
+		steps: map[digest.Digest]*summaryStep{},
+	}
+}
+
+func (sw *summaryWriter) step(dgst digest.Digest) *summaryStep {
+	st, ok := sw.steps[dgst]
+	if !ok {
+		st = &summaryStep{transfers: map[string]int64{}}
+		sw.steps[dgst] = st
+		sw.order = append(sw.order, dgst)
+	}
+	return st
+}
+
+func (sw *summaryWriter) Write(ss *client.SolveStatus) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	for _, v := range ss.Vertexes {
+		st := sw.step(v.Digest)
+		st.name = v.Name
+		if v.Started != nil && (st.started == nil || v.Started.Before(*st.started)) {
+			st.started = v.Started
+		}
+		if v.Completed != nil && (st.completed == nil || v.Completed.After(*st.completed)) {
+			st.completed = v.Completed
+		}
+		st.cached = v.Cached
+		st.err = v.Error
+	}
+	for _, s := range ss.Statuses {
+		st := sw.step(s.Vertex)
+		n := max(s.Current, s.Total)
+		if n > st.transfers[s.ID] {
+			st.transfers[s.ID] = n
+		}
+	}
+}
+
+// Summary returns the completed steps, sorted by decreasing duration.
+func (sw *summaryWriter) Summary() []StepSummary {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	res := make([]StepSummary, 0, len(sw.steps))
+	for _, dgst := range sw.order {
+		st := sw.steps[dgst]
+		if st.completed == nil || st.name == "" {
+			continue
+		}
+		s := StepSummary{
+			Name:   st.name,
+			Cached: st.cached,
+			Error:  st.err,
+			Vertex: dgst,
+		}
+		if st.started != nil {
+			s.Duration = st.completed.Sub(*st.started)
+		}
+		for _, n := range st.transfers {
+			s.Transferred += n
+		}
+		res = append(res, s)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Duration > res[j].Duration
+	})
+	return res
+}
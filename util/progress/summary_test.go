@@ -0,0 +1,53 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryWriter(t *testing.T) {
+	sw := newSummaryWriter()
+
+	now := time.Now()
+	ts := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	pull := digest.FromString("pull")
+	run := digest.FromString("run")
+	cached := digest.FromString("cached")
+	pending := digest.FromString("pending")
+
+	sw.Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: pull, Name: "[1/3] FROM docker.io/library/alpine", Started: ts(0)},
+			{Digest: cached, Name: "[2/3] COPY . .", Started: ts(0), Completed: ts(0), Cached: true},
+			{Digest: pending, Name: "[3/3] RUN make", Started: ts(0)},
+		},
+		Statuses: []*client.VertexStatus{
+			{ID: "sha256:aaa", Vertex: pull, Current: 512, Total: 1024},
+			{ID: "sha256:bbb", Vertex: pull, Current: 100},
+		},
+	})
+	sw.Write(&client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: pull, Name: "[1/3] FROM docker.io/library/alpine", Started: ts(0), Completed: ts(2 * time.Second)},
+			{Digest: run, Name: "[internal] load build context", Started: ts(time.Second), Completed: ts(6 * time.Second)},
+		},
+		Statuses: []*client.VertexStatus{
+			{ID: "sha256:aaa", Vertex: pull, Current: 1024, Total: 1024},
+			{ID: "sha256:bbb", Vertex: pull, Current: 200},
+		},
+	})
+
+	require.Equal(t, []StepSummary{
+		{Name: "[internal] load build context", Duration: 5 * time.Second, Vertex: run},
+		{Name: "[1/3] FROM docker.io/library/alpine", Duration: 2 * time.Second, Transferred: 1224, Vertex: pull},
+		{Name: "[2/3] COPY . .", Cached: true, Vertex: cached},
+	}, sw.Summary())
+}
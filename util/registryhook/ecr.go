@@ -0,0 +1,110 @@
+package registryhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/pkg/errors"
+)
+
+// ecrHostPattern matches private Amazon ECR registry hosts such as
+// 123456789012.dkr.ecr.us-east-1.amazonaws.com
+var ecrHostPattern = regexp.MustCompile(`^([0-9]{12})\.dkr\.ecr(-fips)?\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+
+// ecrHook creates missing repositories on Amazon ECR using the AWS
+// credentials resolved from the environment and shared config files. The
+// registry credentials can't be used: they are an authorization token derived
+// from the IAM credentials, which the ECR API doesn't accept to sign requests.
+// The ECR credential helper resolves the IAM credentials the same way.
+type ecrHook struct {
+	// endpoint overrides the ECR API endpoint
+	endpoint string
+	client   *http.Client
+}
+
+func (h *ecrHook) Name() string {
+	return "Amazon ECR"
+}
+
+func (h *ecrHook) Match(host string) bool {
+	return ecrHostPattern.MatchString(host)
+}
+
+func (h *ecrHook) CreateRepository(ctx context.Context, ref reference.Named, _ imagetools.Auth) error {
+	m := ecrHostPattern.FindStringSubmatch(reference.Domain(ref))
+	if m == nil {
+		return errors.Errorf("invalid ECR registry %s", reference.Domain(ref))
+	}
+	registryID, fips, region, cn := m[1], m[2], m[3], m[4]
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return errors.Wrap(err, "failed to load AWS config")
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to retrieve AWS credentials, registry credentials from docker login can't be used to create repositories")
+	}
+
+	endpoint := h.endpoint
+	if endpoint == "" {
+		endpoint = "https://api.ecr" + fips + "." + region + ".amazonaws.com" + cn
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"registryId":     registryID,
+		"repositoryName": reference.Path(ref),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921.CreateRepository")
+
+	sum := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "ecr", region, time.Now()); err != nil {
+		return errors.Wrap(err, "failed to sign request")
+	}
+
+	client := h.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	dt, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var apiErr struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(dt, &apiErr); err != nil || apiErr.Type == "" {
+		return errors.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(dt)))
+	}
+	if strings.HasSuffix(apiErr.Type, "RepositoryAlreadyExistsException") {
+		return nil
+	}
+	return errors.Errorf("%s: %s", apiErr.Type, apiErr.Message)
+}
@@ -0,0 +1,203 @@
+package registryhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/pkg/errors"
+)
+
+const (
+	garPollInterval     = time.Second
+	garOperationTimeout = 2 * time.Minute
+)
+
+// garHostPattern matches Google Artifact Registry hosts such as
+// us-central1-docker.pkg.dev
+var garHostPattern = regexp.MustCompile(`^([a-z0-9-]+)-docker\.pkg\.dev$`)
+
+// garHook creates missing Docker repositories on Google Artifact Registry
+// using the access token resolved by the credential helper for the registry.
+type garHook struct {
+	// endpoint overrides the Artifact Registry API endpoint
+	endpoint string
+	client   *http.Client
+	// pollInterval overrides the interval between checks of the repository
+	// creation operation
+	pollInterval time.Duration
+}
+
+func (h *garHook) Name() string {
+	return "Google Artifact Registry"
+}
+
+func (h *garHook) Match(host string) bool {
+	return garHostPattern.MatchString(host)
+}
+
+func (h *garHook) CreateRepository(ctx context.Context, ref reference.Named, auth imagetools.Auth) error {
+	host := reference.Domain(ref)
+	m := garHostPattern.FindStringSubmatch(host)
+	if m == nil {
+		return errors.Errorf("invalid Artifact Registry host %s", host)
+	}
+	location := m[1]
+
+	// image names are in the form LOCATION-docker.pkg.dev/PROJECT/REPOSITORY/IMAGE
+	parts := strings.SplitN(reference.Path(ref), "/", 3)
+	if len(parts) < 3 {
+		return errors.Errorf("image name must be in the form %s/PROJECT/REPOSITORY/IMAGE", host)
+	}
+	project, repo := parts[0], parts[1]
+
+	token, err := garToken(auth, host)
+	if err != nil {
+		return err
+	}
+
+	endpoint := h.endpoint
+	if endpoint == "" {
+		endpoint = "https://artifactregistry.googleapis.com"
+	}
+	parent := endpoint + "/v1/projects/" + url.PathEscape(project) + "/locations/" + url.PathEscape(location) + "/repositories"
+
+	client := h.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	status, dt, err := garDo(ctx, client, http.MethodGet, parent+"/"+url.PathEscape(repo), token, nil)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+	default:
+		return garError(status, dt)
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"format": "DOCKER",
+	})
+	if err != nil {
+		return err
+	}
+	status, dt, err = garDo(ctx, client, http.MethodPost, parent+"?repositoryId="+url.QueryEscape(repo), token, body)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusConflict:
+		return nil
+	default:
+		return garError(status, dt)
+	}
+
+	// the repository is created by a long-running operation that must be
+	// complete before pushing to it
+	var op garOperation
+	if err := json.Unmarshal(dt, &op); err != nil {
+		return errors.Wrap(err, "failed to decode repository creation operation")
+	}
+	pollInterval := h.pollInterval
+	if pollInterval == 0 {
+		pollInterval = garPollInterval
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, garOperationTimeout, errors.Errorf("timed out waiting for the creation of repository %s", repo))
+	defer cancel()
+	for !op.Done && op.Name != "" {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(pollInterval):
+		}
+		status, dt, err := garDo(ctx, client, http.MethodGet, endpoint+"/v1/"+op.Name, token, nil)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK {
+			return garError(status, dt)
+		}
+		op = garOperation{}
+		if err := json.Unmarshal(dt, &op); err != nil {
+			return errors.Wrap(err, "failed to decode repository creation operation")
+		}
+	}
+	if op.Error != nil {
+		return errors.Errorf("failed to create repository %s: %s", repo, op.Error.Message)
+	}
+	return nil
+}
+
+// garOperation is the long-running operation returned by the Artifact
+// Registry API for the creation of a repository.
+type garOperation struct {
+	Name  string `json:"name"`
+	Done  bool   `json:"done"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func garToken(auth imagetools.Auth, host string) (string, error) {
+	if auth == nil {
+		return "", errors.Errorf("no credentials found for %s", host)
+	}
+	ac, err := auth.GetAuthConfig(host)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case ac.RegistryToken != "":
+		return ac.RegistryToken, nil
+	case ac.Username == "oauth2accesstoken" && ac.Password != "":
+		return ac.Password, nil
+	}
+	return "", errors.Errorf("no access token found for %s, configure the gcloud credential helper", host)
+}
+
+func garDo(ctx context.Context, client *http.Client, method, u, token string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	dt, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, dt, nil
+}
+
+func garError(status int, dt []byte) error {
+	var apiErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(dt, &apiErr); err == nil && apiErr.Error.Message != "" {
+		return errors.Errorf("%s: %s", http.StatusText(status), apiErr.Error.Message)
+	}
+	return errors.Errorf("unexpected status %d: %s", status, strings.TrimSpace(string(dt)))
+}
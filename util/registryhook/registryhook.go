@@ -0,0 +1,76 @@
+// Package registryhook provides hooks that prepare a registry before images
+// are pushed to it, such as creating missing repositories on registries that
+// don't create them implicitly.
+package registryhook
+
+import (
+	"context"
+	"sync"
+
+	"github.com/distribution/reference"
+	"github.com/docker/buildx/util/imagetools"
+	"github.com/pkg/errors"
+)
+
+// Hook is called before an image is pushed to a registry it handles.
+type Hook interface {
+	// Name returns the name of the registry provider.
+	Name() string
+	// Match reports whether the hook handles the registry host.
+	Match(host string) bool
+	// CreateRepository creates the repository of ref if it does not exist.
+	CreateRepository(ctx context.Context, ref reference.Named, auth imagetools.Auth) error
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   = []Hook{
+		&ecrHook{},
+		&garHook{},
+	}
+)
+
+// Register adds a hook. Hooks registered later take precedence.
+func Register(h Hook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append([]Hook{h}, hooks...)
+}
+
+func lookup(host string) Hook {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	for _, h := range hooks {
+		if h.Match(host) {
+			return h
+		}
+	}
+	return nil
+}
+
+// CreateRepositories creates the repositories of the given image names on
+// registries that have a hook registered. Names on other registries are
+// ignored.
+func CreateRepositories(ctx context.Context, names []string, auth imagetools.Auth) error {
+	seen := map[string]struct{}{}
+	for _, name := range names {
+		ref, err := reference.ParseNormalizedNamed(name)
+		if err != nil {
+			return errors.Wrapf(err, "invalid image name %q", name)
+		}
+		repo := ref.Name()
+		if _, ok := seen[repo]; ok {
+			continue
+		}
+		seen[repo] = struct{}{}
+
+		h := lookup(reference.Domain(ref))
+		if h == nil {
+			continue
+		}
+		if err := h.CreateRepository(ctx, reference.TrimNamed(ref), auth); err != nil {
+			return errors.Wrapf(err, "failed to create %s repository %s", h.Name(), repo)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package registryhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/distribution/reference"
+	clitypes "github.com/docker/cli/cli/config/types"
+	"github.com/stretchr/testify/require"
+)
+
+type staticAuth map[string]clitypes.AuthConfig
+
+func (a staticAuth) GetAuthConfig(host string) (clitypes.AuthConfig, error) {
+	return a[host], nil
+}
+
+func TestMatch(t *testing.T) {
+	tcases := []struct {
+		host string
+		hook Hook
+	}{
+		{host: "123456789012.dkr.ecr.us-east-1.amazonaws.com", hook: &ecrHook{}},
+		{host: "123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com", hook: &ecrHook{}},
+		{host: "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn", hook: &ecrHook{}},
+		{host: "public.ecr.aws"},
+		{host: "europe-west1-docker.pkg.dev", hook: &garHook{}},
+		{host: "gcr.io"},
+		{host: "docker.io"},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.host, func(t *testing.T) {
+			require.IsType(t, tc.hook, lookup(tc.host))
+		})
+	}
+}
+
+func TestECRCreateRepository(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	var exists bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "AmazonEC2ContainerRegistry_V20150921.CreateRepository", r.Header.Get("X-Amz-Target"))
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		require.Contains(t, r.Header.Get("Authorization"), "/us-west-2/ecr/aws4_request")
+
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, map[string]string{
+			"registryId":     "123456789012",
+			"repositoryName": "team/app",
+		}, req)
+
+		if exists {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"__type":"RepositoryAlreadyExistsException","message":"already exists"}`)
+			return
+		}
+		exists = true
+		io.WriteString(w, `{}`)
+	}))
+	defer srv.Close()
+
+	h := &ecrHook{endpoint: srv.URL}
+	ref, err := reference.ParseNormalizedNamed("123456789012.dkr.ecr.us-west-2.amazonaws.com/team/app")
+	require.NoError(t, err)
+
+	require.NoError(t, h.CreateRepository(context.TODO(), ref, nil))
+	require.NoError(t, h.CreateRepository(context.TODO(), ref, nil))
+}
+
+func TestGARCreateRepository(t *testing.T) {
+	var created bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch r.Method {
+		case http.MethodGet:
+			require.Equal(t, "/v1/projects/my-project/locations/europe-west1/repositories/images", r.URL.Path)
+			if !created {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, `{}`)
+		case http.MethodPost:
+			require.Equal(t, "/v1/projects/my-project/locations/europe-west1/repositories", r.URL.Path)
+			require.Equal(t, "images", r.URL.Query().Get("repositoryId"))
+			created = true
+			io.WriteString(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+
+	h := &garHook{endpoint: srv.URL}
+	ref, err := reference.ParseNormalizedNamed("europe-west1-docker.pkg.dev/my-project/images/app")
+	require.NoError(t, err)
+
+	auth := staticAuth{
+		"europe-west1-docker.pkg.dev": {Username: "oauth2accesstoken", Password: "token"},
+	}
+	require.NoError(t, h.CreateRepository(context.TODO(), ref, auth))
+	require.True(t, created)
+	require.NoError(t, h.CreateRepository(context.TODO(), ref, auth))
+
+	err = h.CreateRepository(context.TODO(), ref, staticAuth{})
+	require.ErrorContains(t, err, "no access token found")
+
+	ref, err = reference.ParseNormalizedNamed("europe-west1-docker.pkg.dev/my-project/app")
+	require.NoError(t, err)
+	err = h.CreateRepository(context.TODO(), ref, auth)
+	require.ErrorContains(t, err, "image name must be in the form")
+}
+
+func TestGARCreateRepositoryOperation(t *testing.T) {
+	const opPath = "/v1/projects/my-project/locations/europe-west1/operations/op1"
+	var polls int
+	var opErr bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == opPath:
+			polls++
+			switch {
+			case polls < 2:
+				io.WriteString(w, `{"name":"projects/my-project/locations/europe-west1/operations/op1"}`)
+			case opErr:
+				io.WriteString(w, `{"name":"projects/my-project/locations/europe-west1/operations/op1","done":true,"error":{"code":7,"message":"permission denied"}}`)
+			default:
+				io.WriteString(w, `{"name":"projects/my-project/locations/europe-west1/operations/op1","done":true}`)
+			}
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost:
+			io.WriteString(w, `{"name":"projects/my-project/locations/europe-west1/operations/op1"}`)
+		}
+	}))
+	defer srv.Close()
+
+	h := &garHook{endpoint: srv.URL, pollInterval: time.Millisecond}
+	ref, err := reference.ParseNormalizedNamed("europe-west1-docker.pkg.dev/my-project/images/app")
+	require.NoError(t, err)
+	auth := staticAuth{
+		"europe-west1-docker.pkg.dev": {Username: "oauth2accesstoken", Password: "token"},
+	}
+
+	// the creation returns once the operation is done
+	require.NoError(t, h.CreateRepository(context.TODO(), ref, auth))
+	require.Equal(t, 2, polls)
+
+	polls, opErr = 0, true
+	err = h.CreateRepository(context.TODO(), ref, auth)
+	require.EqualError(t, err, "failed to create repository images: permission denied")
+}
@@ -0,0 +1,324 @@
+// Package selfupdate updates the buildx binary to a release published on
+// GitHub, after verifying the signature of its checksums file and the
+// checksum of the binary.
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	// ChannelStable selects the latest stable release.
+	ChannelStable = "stable"
+	// ChannelRC selects the latest release, including release candidates.
+	ChannelRC = "rc"
+
+	// DefaultAPIURL is the GitHub API URL of the buildx repository.
+	DefaultAPIURL = "https://api.github.com/repos/docker/buildx"
+
+	checksumsAsset  = "checksums.txt"
+	signatureSuffix = ".sig"
+)
+
+// PublicKey is the base64 encoded DER public key, ECDSA or Ed25519, the
+// checksums files of the releases are signed with. It is filled at linking
+// time, and self-update is refused by builds without it.
+var PublicKey = ""
+
+// Release is a GitHub release of buildx.
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Draft   bool    `json:"draft"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Updater looks up releases with the GitHub API and installs them.
+type Updater struct {
+	// APIURL is the GitHub API URL of the repository.
+	APIURL string
+	// Token authenticates the requests to the GitHub API, to avoid its rate
+	// limit for anonymous requests.
+	Token string
+	// PublicKey is the base64 encoded DER public key verifying the
+	// signature of the checksums file of a release.
+	PublicKey string
+	Client    *http.Client
+}
+
+// New returns an updater for the buildx releases, authenticated with the
+// GITHUB_TOKEN environment variable if set.
+func New() *Updater {
+	return &Updater{
+		APIURL:    DefaultAPIURL,
+		Token:     os.Getenv("GITHUB_TOKEN"),
+		PublicKey: PublicKey,
+		Client:    http.DefaultClient,
+	}
+}
+
+// Latest returns the latest release of the channel.
+func (u *Updater) Latest(ctx context.Context, channel string) (*Release, error) {
+	switch channel {
+	case ChannelStable:
+		var rel Release
+		if err := u.getJSON(ctx, u.APIURL+"/releases/latest", &rel); err != nil {
+			return nil, errors.Wrap(err, "failed to get latest release")
+		}
+		return &rel, nil
+	case ChannelRC:
+		var rels []Release
+		if err := u.getJSON(ctx, u.APIURL+"/releases", &rels); err != nil {
+			return nil, errors.Wrap(err, "failed to list releases")
+		}
+		var latest *Release
+		for i, rel := range rels {
+			if rel.Draft || !semver.IsValid(rel.TagName) {
+				continue
+			}
+			if latest == nil || semver.Compare(rel.TagName, latest.TagName) > 0 {
+				latest = &rels[i]
+			}
+		}
+		if latest == nil {
+			return nil, errors.New("no release found")
+		}
+		return latest, nil
+	default:
+		return nil, errors.Errorf("invalid channel %q, expecting %s or %s", channel, ChannelStable, ChannelRC)
+	}
+}
+
+// IsNewer returns whether the release tag is newer than the current version.
+// Development builds without a valid version are always updated.
+func IsNewer(tag, current string) bool {
+	if !semver.IsValid(current) || semver.Compare(current, "v0.0.0") == 0 {
+		return true
+	}
+	return semver.Compare(tag, current) > 0
+}
+
+// AssetName returns the name of the binary of the release tag for the
+// platform p.
+func AssetName(tag string, p ocispecs.Platform) string {
+	arch := p.Architecture
+	if p.Variant != "" && p.Architecture == "arm" {
+		arch += "-" + p.Variant
+	}
+	name := fmt.Sprintf("buildx-%s.%s-%s", tag, p.OS, arch)
+	if p.OS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Install downloads the asset name of the release, verifies the signature of
+// the checksums file of the release and the checksum of the asset, and
+// replaces the file at path with it.
+func (u *Updater) Install(ctx context.Context, rel *Release, name, path string) error {
+	bin, ok := rel.asset(name)
+	if !ok {
+		return errors.Errorf("release %s has no binary %s", rel.TagName, name)
+	}
+	sums, err := u.checksums(ctx, rel)
+	if err != nil {
+		return err
+	}
+	expected, err := checksum(sums, name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get checksum of %s", name)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".buildx-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	h := sha256.New()
+	if err := u.download(ctx, bin.URL, io.MultiWriter(f, h)); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "failed to download %s", name)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		return errors.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)
+	}
+	if err := os.Chmod(f.Name(), 0755); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		// a running executable can't be replaced on Windows, but it can be
+		// renamed
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (rel *Release) asset(name string) (Asset, bool) {
+	for _, a := range rel.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// checksums returns the checksums file of the release once its signature is
+// verified with the public key of the updater.
+func (u *Updater) checksums(ctx context.Context, rel *Release) ([]byte, error) {
+	if u.PublicKey == "" {
+		return nil, errors.New("this build of buildx has no release public key to verify the signature of releases, update it with the package manager it was installed with")
+	}
+	pub, err := parsePublicKey(u.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	sums, ok := rel.asset(checksumsAsset)
+	if !ok {
+		return nil, errors.Errorf("release %s has no %s", rel.TagName, checksumsAsset)
+	}
+	sig, ok := rel.asset(checksumsAsset + signatureSuffix)
+	if !ok {
+		return nil, errors.Errorf("release %s has no %s", rel.TagName, checksumsAsset+signatureSuffix)
+	}
+	var dt, sigdt bytes.Buffer
+	if err := u.download(ctx, sums.URL, &dt); err != nil {
+		return nil, errors.Wrapf(err, "failed to download %s", checksumsAsset)
+	}
+	if err := u.download(ctx, sig.URL, &sigdt); err != nil {
+		return nil, errors.Wrapf(err, "failed to download %s", sig.Name)
+	}
+	if err := verifySignature(pub, dt.Bytes(), sigdt.Bytes()); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify signature of %s of release %s", checksumsAsset, rel.TagName)
+	}
+	return dt.Bytes(), nil
+}
+
+func parsePublicKey(s string) (crypto.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid release public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid release public key")
+	}
+	switch pub.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return pub, nil
+	}
+	return nil, errors.Errorf("unsupported release public key type %T", pub)
+}
+
+// verifySignature verifies the base64 encoded signature of dt, as created by
+// cosign sign-blob.
+func verifySignature(pub crypto.PublicKey, dt, sig []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return errors.Wrap(err, "invalid signature")
+	}
+	var ok bool
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		h := sha256.Sum256(dt)
+		ok = ecdsa.VerifyASN1(pub, h[:], sig)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, dt, sig)
+	}
+	if !ok {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// checksum returns the sha256 checksum of the asset name from the checksums
+// file of a release.
+func checksum(sums []byte, name string) (string, error) {
+	s := bufio.NewScanner(bytes.NewReader(sums))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.Errorf("%s not found in %s", name, checksumsAsset)
+}
+
+func (u *Updater) getJSON(ctx context.Context, url string, v any) error {
+	rc, err := u.get(ctx, url, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(rc).Decode(v)
+}
+
+func (u *Updater) download(ctx context.Context, url string, w io.Writer) error {
+	rc, err := u.get(ctx, url, "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+func (u *Updater) get(ctx context.Context, url, accept string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if u.Token != "" && strings.HasPrefix(url, u.APIURL) {
+		req.Header.Set("Authorization", "Bearer "+u.Token)
+	}
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return resp.Body, nil
+}
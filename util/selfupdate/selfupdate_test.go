@@ -0,0 +1,190 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestKey returns a signing key and its public key as expected by the
+// updater.
+func newTestKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return key, base64.StdEncoding.EncodeToString(der)
+}
+
+func newTestServer(t *testing.T, bin []byte, checksum string, key *ecdsa.PrivateKey) *httptest.Server {
+	sums := fmt.Sprintf("%s *buildx-v0.19.0.darwin-arm64\n%s *buildx-v0.19.0.linux-amd64\n", checksum, checksum)
+	var sig []byte
+	if key != nil {
+		h := sha256.Sum256([]byte(sums))
+		var err error
+		sig, err = ecdsa.SignASN1(rand.Reader, key, h[:])
+		require.NoError(t, err)
+	}
+
+	var srv *httptest.Server
+	release := func(tag string, draft bool) Release {
+		name := "buildx-" + tag + ".linux-amd64"
+		return Release{
+			TagName: tag,
+			Draft:   draft,
+			Assets: []Asset{
+				{Name: name, URL: srv.URL + "/download/" + tag + "/" + name},
+				{Name: checksumsAsset, URL: srv.URL + "/download/" + tag + "/" + checksumsAsset},
+				{Name: checksumsAsset + signatureSuffix, URL: srv.URL + "/download/" + tag + "/" + checksumsAsset + signatureSuffix},
+			},
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/docker/buildx/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(release("v0.19.0", false))
+	})
+	mux.HandleFunc("/repos/docker/buildx/releases", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]Release{
+			release("v0.21.0", true),
+			release("v0.20.0-rc1", false),
+			release("v0.19.0", false),
+		})
+	})
+	mux.HandleFunc("/download/v0.19.0/buildx-v0.19.0.linux-amd64", func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.Header.Get("Authorization"))
+		w.Write(bin)
+	})
+	mux.HandleFunc("/download/v0.19.0/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sums))
+	})
+	mux.HandleFunc("/download/v0.19.0/checksums.txt.sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(base64.StdEncoding.EncodeToString(sig) + "\n"))
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestLatest(t *testing.T) {
+	srv := newTestServer(t, nil, "", nil)
+	u := &Updater{APIURL: srv.URL + "/repos/docker/buildx", Token: "token"}
+
+	rel, err := u.Latest(context.TODO(), ChannelStable)
+	require.NoError(t, err)
+	require.Equal(t, "v0.19.0", rel.TagName)
+
+	rel, err = u.Latest(context.TODO(), ChannelRC)
+	require.NoError(t, err)
+	require.Equal(t, "v0.20.0-rc1", rel.TagName)
+
+	_, err = u.Latest(context.TODO(), "nightly")
+	require.ErrorContains(t, err, `invalid channel "nightly"`)
+}
+
+func TestInstall(t *testing.T) {
+	bin := []byte("new buildx")
+	sum := sha256.Sum256(bin)
+	key, pub := newTestKey(t)
+	srv := newTestServer(t, bin, hex.EncodeToString(sum[:]), key)
+	u := &Updater{APIURL: srv.URL + "/repos/docker/buildx", Token: "token", PublicKey: pub}
+
+	rel, err := u.Latest(context.TODO(), ChannelStable)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "docker-buildx")
+	require.NoError(t, os.WriteFile(path, []byte("old buildx"), 0755))
+
+	err = u.Install(context.TODO(), rel, "buildx-v0.19.0.linux-arm64", path)
+	require.ErrorContains(t, err, "release v0.19.0 has no binary buildx-v0.19.0.linux-arm64")
+
+	require.NoError(t, u.Install(context.TODO(), rel, "buildx-v0.19.0.linux-amd64", path))
+	dt, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, bin, dt)
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestInstallChecksumMismatch(t *testing.T) {
+	key, pub := newTestKey(t)
+	srv := newTestServer(t, []byte("tampered buildx"), "4e1f9c1f7d1bbcf7e2b4f2a4a9c4bd5ef4f2f6b0c5d0e1f2a3b4c5d6e7f8a9b0", key)
+	u := &Updater{APIURL: srv.URL + "/repos/docker/buildx", Token: "token", PublicKey: pub}
+
+	rel, err := u.Latest(context.TODO(), ChannelStable)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "docker-buildx")
+	require.NoError(t, os.WriteFile(path, []byte("old buildx"), 0755))
+
+	err = u.Install(context.TODO(), rel, "buildx-v0.19.0.linux-amd64", path)
+	require.ErrorContains(t, err, "checksum mismatch for buildx-v0.19.0.linux-amd64")
+	dt, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "old buildx", string(dt))
+}
+
+func TestInstallSignature(t *testing.T) {
+	bin := []byte("new buildx")
+	sum := sha256.Sum256(bin)
+	key, _ := newTestKey(t)
+	_, otherPub := newTestKey(t)
+	srv := newTestServer(t, bin, hex.EncodeToString(sum[:]), key)
+
+	path := filepath.Join(t.TempDir(), "docker-buildx")
+	require.NoError(t, os.WriteFile(path, []byte("old buildx"), 0755))
+
+	// signed with another key
+	u := &Updater{APIURL: srv.URL + "/repos/docker/buildx", Token: "token", PublicKey: otherPub}
+	rel, err := u.Latest(context.TODO(), ChannelStable)
+	require.NoError(t, err)
+	err = u.Install(context.TODO(), rel, "buildx-v0.19.0.linux-amd64", path)
+	require.ErrorContains(t, err, "failed to verify signature of checksums.txt of release v0.19.0")
+
+	// no public key to verify the release with
+	u.PublicKey = ""
+	err = u.Install(context.TODO(), rel, "buildx-v0.19.0.linux-amd64", path)
+	require.ErrorContains(t, err, "no release public key")
+
+	// unsigned release
+	u.PublicKey = otherPub
+	unsigned := *rel
+	unsigned.Assets = unsigned.Assets[:2]
+	err = u.Install(context.TODO(), &unsigned, "buildx-v0.19.0.linux-amd64", path)
+	require.ErrorContains(t, err, "release v0.19.0 has no checksums.txt.sig")
+
+	dt, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "old buildx", string(dt))
+}
+
+func TestIsNewer(t *testing.T) {
+	require.True(t, IsNewer("v0.19.0", "v0.18.0"))
+	require.False(t, IsNewer("v0.19.0", "v0.19.0"))
+	require.False(t, IsNewer("v0.19.0-rc1", "v0.19.0"))
+	require.True(t, IsNewer("v0.19.0", "v0.19.0-rc1"))
+	require.True(t, IsNewer("v0.19.0", "v0.0.0+unknown"))
+}
+
+func TestAssetName(t *testing.T) {
+	require.Equal(t, "buildx-v0.19.0.linux-amd64", AssetName("v0.19.0", ocispecs.Platform{OS: "linux", Architecture: "amd64"}))
+	require.Equal(t, "buildx-v0.19.0.linux-arm-v7", AssetName("v0.19.0", ocispecs.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}))
+	require.Equal(t, "buildx-v0.19.0.darwin-arm64", AssetName("v0.19.0", ocispecs.Platform{OS: "darwin", Architecture: "arm64", Variant: "v8"}))
+	require.Equal(t, "buildx-v0.19.0.windows-amd64.exe", AssetName("v0.19.0", ocispecs.Platform{OS: "windows", Architecture: "amd64"}))
+}